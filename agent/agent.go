@@ -3,8 +3,9 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -17,26 +18,61 @@ import (
 type Agent struct {
 	name         string
 	description  string
-	client       *anthropic.Client
+	provider     Provider
 	registry     *tools.ToolRegistry
 	model        anthropic.Model
 	maxTokens    int64
 	systemPrompt string
 	logger       *Logger
 	eventEmitter events.EventEmitter
+	streaming    bool
+
+	maxParallelTools int
+	historyStrategy  HistoryStrategy
+	promptCaching    bool
+	budget           *BudgetTracker
+	retryPolicy      RetryPolicy
+	fallbackModels   []anthropic.Model
+	thinkingBudget   int64
+	checkpointer     Checkpointer
+	newRunID         string
+	parentRunID      string
 }
 
 // Config holds the configuration for creating an agent
 type Config struct {
 	Name         string
 	Description  string
-	Client       *anthropic.Client
+	Provider     Provider // LLM backend (see NewAnthropicProvider, NewOpenAIProvider)
 	Model        anthropic.Model
 	MaxTokens    int64
 	SystemPrompt string
 	LogLevel     config.LogLevel
 	EventEmitter events.EventEmitter
 	Tools        []tools.Tool
+	Streaming    bool // Use the streaming Messages API and emit delta events
+
+	// MaxParallelTools caps concurrent tool calls per turn (default 4, 1 disables)
+	MaxParallelTools int
+
+	// HistoryStrategy compacts the conversation before each API call (nil keeps full history)
+	HistoryStrategy HistoryStrategy
+
+	// DisablePromptCaching turns off cache breakpoints on the system prompt, tools and conversation
+	DisablePromptCaching bool
+
+	// Budget limits tokens, spend, time and iterations per run (nil is unlimited apart from 50 iterations)
+	Budget *Budget
+
+	// Retry controls retries of transient API errors (nil uses DefaultRetryPolicy)
+	Retry *RetryPolicy
+
+	// FallbackModels are tried in order once retries on Model are exhausted
+	FallbackModels []anthropic.Model
+
+	// ThinkingBudget enables extended thinking with this many tokens (0 disables,
+	// minimum 1024). MaxTokens is raised above the budget if needed.
+	ThinkingBudget int64
 }
 
 // NewAgent creates a new agent with the given configuration
@@ -47,6 +83,26 @@ func NewAgent(cfg Config) *Agent {
 	if cfg.MaxTokens == 0 {
 		cfg.MaxTokens = 4096
 	}
+	if cfg.MaxParallelTools == 0 {
+		cfg.MaxParallelTools = 4
+	}
+	if cfg.ThinkingBudget > 0 {
+		cfg.ThinkingBudget = max(cfg.ThinkingBudget, 1024)
+		// max_tokens includes thinking, so leave room for the response itself
+		if cfg.MaxTokens <= cfg.ThinkingBudget {
+			cfg.MaxTokens = cfg.ThinkingBudget + 4096
+		}
+	}
+	retryPolicy := DefaultRetryPolicy
+	if cfg.Retry != nil {
+		retryPolicy = *cfg.Retry
+		if retryPolicy.BaseDelay <= 0 {
+			retryPolicy.BaseDelay = DefaultRetryPolicy.BaseDelay
+		}
+		if retryPolicy.MaxDelay <= 0 {
+			retryPolicy.MaxDelay = DefaultRetryPolicy.MaxDelay
+		}
+	}
 
 	// Use NoOpEmitter if no emitter is provided
 	emitter := cfg.EventEmitter
@@ -57,13 +113,30 @@ func NewAgent(cfg Config) *Agent {
 	agent := &Agent{
 		name:         cfg.Name,
 		description:  cfg.Description,
-		client:       cfg.Client,
+		provider:     cfg.Provider,
 		registry:     tools.NewRegistry(),
 		model:        cfg.Model,
 		maxTokens:    cfg.MaxTokens,
 		systemPrompt: cfg.SystemPrompt,
 		logger:       NewLogger(cfg.LogLevel),
 		eventEmitter: emitter,
+		streaming:    cfg.Streaming,
+
+		maxParallelTools: cfg.MaxParallelTools,
+		historyStrategy:  cfg.HistoryStrategy,
+		promptCaching:    !cfg.DisablePromptCaching,
+		retryPolicy:      retryPolicy,
+		fallbackModels:   cfg.FallbackModels,
+		thinkingBudget:   cfg.ThinkingBudget,
+	}
+
+	if cfg.Budget != nil {
+		agent.budget = NewBudgetTracker(*cfg.Budget)
+	}
+
+	// Cassettes record or stub tool results along with API responses
+	if cassette, ok := cfg.Provider.(*CassetteProvider); ok {
+		agent.registry.SetInterceptor(cassette.InterceptTool)
 	}
 
 	// Register tools if provided
@@ -149,6 +222,8 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 	var runID string
 	if existingRunID != "" {
 		runID = existingRunID
+	} else if a.newRunID != "" {
+		runID = a.newRunID
 	} else {
 		runID = fmt.Sprintf("run-%d", time.Now().UnixNano())
 	}
@@ -162,25 +237,53 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 			Model:        string(a.model),
 			MaxTokens:    a.maxTokens,
 			SystemPrompt: a.systemPrompt,
+			ParentRunID:  a.parentRunID,
 		}); err == nil {
 			a.eventEmitter.Emit(evt)
 		}
 	}
 
 	maxIterations := 50 // Prevent infinite loops
+	if a.budget != nil {
+		if n := a.budget.MaxIterations(); n > 0 {
+			maxIterations = n
+		}
+
+		// Interrupt in-flight API calls and tools when the time budget runs out
+		if deadline, ok := a.budget.Deadline(); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+	}
+
+	// Message count of the previous request, used to keep its prefix cached
+	previousRequestLen := 0
 
 	for result.Iterations = 0; result.Iterations < maxIterations; result.Iterations++ {
 		// Check if context is cancelled
 		select {
 		case <-ctx.Done():
-			a.logger.Info("Run cancelled by user")
+			if a.endOnBudgetExceeded(result, runID, startTime) {
+				return result
+			}
+
+			reason := events.RunEndReasonCancelled
+			message := "Run was cancelled by user"
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				reason = events.RunEndReasonTimeout
+				message = "Run timed out"
+			}
+
+			a.logger.Info("%s", message)
 			result.Error = ctx.Err()
 			result.Success = false
 
 			// Emit cancelled event
 			if evt, err := events.NewRunEndEvent(runID, a.name, a.name, events.RunEndData{
 				Success:         false,
-				Error:           "Run was cancelled by user",
+				Reason:          reason,
+				Error:           message,
 				TotalToolCalls:  result.ToolCalls,
 				TotalIterations: result.Iterations,
 				Duration:        time.Since(startTime).String(),
@@ -192,14 +295,18 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 		default:
 		}
 
+		// Stop before the next API call if this run or its sub-runs used up the budget
+		if a.endOnBudgetExceeded(result, runID, startTime) {
+			return result
+		}
+
 		// Check for pending messages if channel is provided
 		if pendingMessages != nil {
 			select {
 			case pendingMsg := <-pendingMessages:
 				a.logger.Info("Received pending message from channel")
 				// Add the pending message to the conversation
-				messages = append(messages, anthropic.NewUserMessage(anthropic.NewTextBlock(pendingMsg)))
-				result.Messages = messages
+				result.Messages = append(result.Messages, anthropic.NewUserMessage(anthropic.NewTextBlock(pendingMsg)))
 
 				// Emit message event for the pending message
 				if evt, err := events.NewMessageEvent(runID, a.name, a.name, events.MessageData{
@@ -226,6 +333,11 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 			a.eventEmitter.Emit(evt)
 		}
 
+		// Compact history if a strategy is configured
+		if a.historyStrategy != nil {
+			result.Messages = a.compactHistory(ctx, result.Messages, runID)
+		}
+
 		// Create message params
 		params := anthropic.MessageNewParams{
 			Model:     a.model,
@@ -241,21 +353,36 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 			}
 		}
 
+		if a.thinkingBudget > 0 {
+			params.Thinking = anthropic.ThinkingConfigParamOfEnabled(a.thinkingBudget)
+		}
+
+		// Mark cache breakpoints so repeated prefixes are billed as cache reads
+		if a.promptCaching {
+			applyPromptCaching(&params, previousRequestLen)
+		}
+		previousRequestLen = len(result.Messages)
+
 		// Log API request
 		a.logger.LogAPIRequest(a.model, a.maxTokens, len(a.registry.ListTools()))
 
 		// Emit API request event
 		if evt, err := events.NewAPIRequestEvent(runID, a.name, a.name, events.APIRequestData{
-			Model:     string(a.model),
-			MaxTokens: a.maxTokens,
-			ToolCount: len(a.registry.ListTools()),
+			Model:          string(a.model),
+			MaxTokens:      a.maxTokens,
+			ToolCount:      len(a.registry.ListTools()),
+			ThinkingBudget: a.thinkingBudget,
 		}); err == nil {
 			a.eventEmitter.Emit(evt)
 		}
 
 		// Call Claude API
-		message, err := a.client.Messages.New(ctx, params)
+		message, err := a.createMessage(ctx, params, runID)
 		if err != nil {
+			if a.endOnBudgetExceeded(result, runID, startTime) {
+				return result
+			}
+
 			a.logger.Error("API call failed: %v", err)
 			result.Error = fmt.Errorf("API call failed: %w", err)
 
@@ -272,6 +399,7 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 			// Emit run end event
 			if evt, err := events.NewRunEndEvent(runID, a.name, a.name, events.RunEndData{
 				Success:         false,
+				Reason:          events.RunEndReasonError,
 				Error:           result.Error.Error(),
 				TotalToolCalls:  result.ToolCalls,
 				TotalIterations: result.Iterations + 1,
@@ -286,14 +414,15 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 		// Log API response
 		a.logger.LogAPIResponse(message)
 
+		if a.budget != nil {
+			a.budget.Record(message.Model, message.Usage)
+		}
+
 		// Emit API response event
 		if evt, err := events.NewAPIResponseEvent(runID, a.name, a.name, events.APIResponseData{
-			StopReason: string(message.StopReason),
-			Model:      string(message.Model),
-			Usage: &events.UsageInfo{
-				InputTokens:  int(message.Usage.InputTokens),
-				OutputTokens: int(message.Usage.OutputTokens),
-			},
+			StopReason:   string(message.StopReason),
+			Model:        string(message.Model),
+			Usage:        events.UsageInfoFromAnthropic(message.Usage),
 			ContentCount: len(message.Content),
 		}); err == nil {
 			a.eventEmitter.Emit(evt)
@@ -306,6 +435,12 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 
 		result.FinalMessage = message
 
+		// Checkpoint the conversation including this response. Tool calls stay
+		// unanswered until their results are checkpointed below; a resume in
+		// between repairs them (see RepairToolUse).
+		assistantMessage := message.ToParam()
+		a.saveCheckpoint(runID, result.Iterations+1, append(result.Messages[:len(result.Messages):len(result.Messages)], assistantMessage))
+
 		// Check stop reason
 		a.logger.LogStopReason(string(message.StopReason))
 
@@ -318,6 +453,7 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 			// Emit run end event
 			if evt, err := events.NewRunEndEvent(runID, a.name, a.name, events.RunEndData{
 				Success:         true,
+				Reason:          events.RunEndReasonCompleted,
 				TotalToolCalls:  result.ToolCalls,
 				TotalIterations: result.Iterations + 1,
 				Duration:        time.Since(startTime).String(),
@@ -329,7 +465,7 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 
 		case "tool_use":
 			// Execute tools
-			toolResults, err := a.executeTools(message, runID)
+			toolResults, err := a.executeTools(ctx, message, runID)
 			if err != nil {
 				a.logger.Error("Tool execution failed: %v", err)
 				result.Error = fmt.Errorf("tool execution failed: %w", err)
@@ -345,6 +481,7 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 				// Emit run end event
 				if evt, err := events.NewRunEndEvent(runID, a.name, a.name, events.RunEndData{
 					Success:         false,
+					Reason:          events.RunEndReasonError,
 					Error:           result.Error.Error(),
 					TotalToolCalls:  result.ToolCalls,
 					TotalIterations: result.Iterations + 1,
@@ -358,28 +495,14 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 
 			result.ToolCalls += len(toolResults)
 
-			// Add assistant message with tool use, then tool results
-			assistantContent := make([]anthropic.ContentBlockParamUnion, 0, len(message.Content))
-			for _, block := range message.Content {
-				blockJSON, err := json.Marshal(block)
-				if err != nil {
-					result.Error = fmt.Errorf("failed to marshal content block: %w", err)
-					return result
-				}
-
-				var paramBlock anthropic.ContentBlockParamUnion
-				if err := json.Unmarshal(blockJSON, &paramBlock); err != nil {
-					result.Error = fmt.Errorf("failed to unmarshal content block: %w", err)
-					return result
-				}
-
-				assistantContent = append(assistantContent, paramBlock)
-			}
-
-			result.Messages = append(result.Messages, anthropic.NewAssistantMessage(assistantContent...))
+			// Add assistant message with tool use, then tool results. ToParam keeps
+			// thinking blocks and their signatures intact, which the API requires
+			// when extended thinking is combined with tool use.
+			result.Messages = append(result.Messages, assistantMessage)
 
 			// Add tool results
 			result.Messages = append(result.Messages, toolResults...)
+			a.saveCheckpoint(runID, result.Iterations+1, result.Messages)
 
 		case "max_tokens":
 			result.Error = fmt.Errorf("reached max tokens")
@@ -387,6 +510,26 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 			// Emit run end event
 			if evt, err := events.NewRunEndEvent(runID, a.name, a.name, events.RunEndData{
 				Success:         false,
+				Reason:          events.RunEndReasonMaxTokens,
+				Error:           result.Error.Error(),
+				TotalToolCalls:  result.ToolCalls,
+				TotalIterations: result.Iterations + 1,
+				Duration:        time.Since(startTime).String(),
+			}); err == nil {
+				a.eventEmitter.Emit(evt)
+			}
+
+			return result
+
+		case "refusal":
+			// The model declined to continue, or an OpenAI-compatible provider
+			// filtered the response (finish_reason content_filter)
+			result.Error = fmt.Errorf("model refused to continue")
+
+			// Emit run end event
+			if evt, err := events.NewRunEndEvent(runID, a.name, a.name, events.RunEndData{
+				Success:         false,
+				Reason:          events.RunEndReasonRefusal,
 				Error:           result.Error.Error(),
 				TotalToolCalls:  result.ToolCalls,
 				TotalIterations: result.Iterations + 1,
@@ -403,6 +546,7 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 			// Emit run end event
 			if evt, err := events.NewRunEndEvent(runID, a.name, a.name, events.RunEndData{
 				Success:         false,
+				Reason:          events.RunEndReasonError,
 				Error:           result.Error.Error(),
 				TotalToolCalls:  result.ToolCalls,
 				TotalIterations: result.Iterations + 1,
@@ -420,6 +564,7 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 	// Emit run end event
 	if evt, err := events.NewRunEndEvent(runID, a.name, a.name, events.RunEndData{
 		Success:         false,
+		Reason:          events.RunEndReasonMaxIterations,
 		Error:           result.Error.Error(),
 		TotalToolCalls:  result.ToolCalls,
 		TotalIterations: result.Iterations,
@@ -431,110 +576,209 @@ func (a *Agent) runInternal(ctx context.Context, prompt string, existingRunID st
 	return result
 }
 
-// executeTools processes all tool use blocks in a message
-func (a *Agent) executeTools(message *anthropic.Message, runID string) ([]anthropic.MessageParam, error) {
-	var toolResultBlocks []anthropic.ContentBlockParamUnion
+// endOnBudgetExceeded ends the run with a budget_exceeded reason if any budget
+// limit is exhausted, reporting whether it did
+func (a *Agent) endOnBudgetExceeded(result *RunResult, runID string, startTime time.Time) bool {
+	if a.budget == nil {
+		return false
+	}
+	limit, usage := a.budget.Exceeded()
+	if limit == "" {
+		return false
+	}
 
+	a.logger.Info("Stopping run: %s", usage)
+	result.Error = fmt.Errorf("%w: %s", ErrBudgetExceeded, usage)
+	result.Success = false
+
+	if evt, err := events.NewRunEndEvent(runID, a.name, a.name, events.RunEndData{
+		Success:         false,
+		Reason:          events.RunEndReasonBudgetExceeded,
+		BudgetLimit:     limit,
+		Error:           result.Error.Error(),
+		TotalToolCalls:  result.ToolCalls,
+		TotalIterations: result.Iterations,
+		Duration:        time.Since(startTime).String(),
+	}); err == nil {
+		a.eventEmitter.Emit(evt)
+	}
+
+	return true
+}
+
+// compactHistory applies the history strategy and emits a compaction event
+// when the history changed. Compaction failures are logged and the original
+// history is kept, so a failing summary call never ends the run.
+func (a *Agent) compactHistory(ctx context.Context, messages []anthropic.MessageParam, runID string) []anthropic.MessageParam {
+	compaction, err := a.historyStrategy.Compact(ctx, messages)
+	if err != nil {
+		a.logger.Error("History compaction failed: %v", err)
+	}
+	if compaction == nil || err != nil {
+		return messages
+	}
+
+	tokensBefore := EstimateTokens(messages)
+	tokensAfter := EstimateTokens(compaction.Messages)
+	a.logger.Info("Compacted history (%s): %d -> %d messages, ~%d -> ~%d tokens",
+		compaction.Strategy, len(messages), len(compaction.Messages), tokensBefore, tokensAfter)
+
+	if evt, err := events.NewCompactionEvent(runID, a.name, a.name, events.CompactionData{
+		Strategy:              compaction.Strategy,
+		MessagesBefore:        len(messages),
+		MessagesAfter:         len(compaction.Messages),
+		EstimatedTokensBefore: tokensBefore,
+		EstimatedTokensAfter:  tokensAfter,
+		TruncatedResults:      compaction.TruncatedResults,
+		SummarizedMessages:    compaction.SummarizedMessages,
+	}); err == nil {
+		a.eventEmitter.Emit(evt)
+	}
+
+	return compaction.Messages
+}
+
+// executeTools processes all tool use blocks in a message
+// Consecutive tools that are not marked serial run concurrently, bounded by
+// maxParallelTools. Serial tools act as barriers and run alone. Results keep
+// the order of the tool use blocks in the message. Tool handlers receive ctx,
+// so cancelling the run interrupts in-flight tools.
+func (a *Agent) executeTools(ctx context.Context, message *anthropic.Message, runID string) ([]anthropic.MessageParam, error) {
+	var toolUses []anthropic.ToolUseBlock
 	for _, block := range message.Content {
-		switch variant := block.AsAny().(type) {
-		case anthropic.ToolUseBlock:
-			// Log tool execution start
-			a.logger.LogToolExecution(variant.Name, variant.Input)
-
-			// Emit tool call event
-			if evt, err := events.NewToolCallEvent(runID, a.name, a.name, events.ToolCallData{
-				ToolUseID: variant.ID,
-				ToolName:  variant.Name,
-				Input:     variant.Input,
-			}); err == nil {
-				a.eventEmitter.Emit(evt)
-			}
+		if variant, ok := block.AsAny().(anthropic.ToolUseBlock); ok {
+			toolUses = append(toolUses, variant)
+		}
+	}
 
-			// Execute the tool using the registry
-			toolStartTime := time.Now()
-			result, err := a.registry.Execute(variant.Name, variant.Input)
-			toolDuration := time.Since(toolStartTime)
+	toolResultBlocks := make([]anthropic.ContentBlockParamUnion, len(toolUses))
 
-			// Log tool result
-			a.logger.LogToolResult(variant.Name, result, err)
+	for start := 0; start < len(toolUses); {
+		if a.maxParallelTools <= 1 || a.registry.IsSerial(toolUses[start].Name) {
+			toolResultBlocks[start] = a.executeTool(ctx, toolUses[start], runID)
+			start++
+			continue
+		}
 
-			// Emit tool result event
-			var resultJSON json.RawMessage
-			if result != nil {
-				resultJSON, _ = json.Marshal(result)
-			}
+		end := start
+		for end < len(toolUses) && !a.registry.IsSerial(toolUses[end].Name) {
+			end++
+		}
 
-			if evt, toolErr := events.NewToolResultEvent(runID, a.name, a.name, events.ToolResultData{
-				ToolUseID: variant.ID,
-				ToolName:  variant.Name,
-				Result:    resultJSON,
-				Error: func() string {
-					if err != nil {
-						return err.Error()
-					}
-					return ""
-				}(),
-				IsError:  err != nil,
-				Duration: toolDuration.String(),
-			}); toolErr == nil {
-				a.eventEmitter.Emit(evt)
-			}
+		a.executeToolBatch(ctx, toolUses[start:end], toolResultBlocks[start:end], runID)
+		start = end
+	}
 
+	return []anthropic.MessageParam{anthropic.NewUserMessage(toolResultBlocks...)}, nil
+}
+
+// executeToolBatch runs independent tool calls concurrently, writing each
+// result to the matching index of results
+func (a *Agent) executeToolBatch(ctx context.Context, toolUses []anthropic.ToolUseBlock, results []anthropic.ContentBlockParamUnion, runID string) {
+	if len(toolUses) == 1 {
+		results[0] = a.executeTool(ctx, toolUses[0], runID)
+		return
+	}
+
+	sem := make(chan struct{}, a.maxParallelTools)
+	var wg sync.WaitGroup
+
+	for i, toolUse := range toolUses {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, toolUse anthropic.ToolUseBlock) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = a.executeTool(ctx, toolUse, runID)
+		}(i, toolUse)
+	}
+
+	wg.Wait()
+}
+
+// executeTool runs a single tool use block and returns its tool result block
+func (a *Agent) executeTool(ctx context.Context, variant anthropic.ToolUseBlock, runID string) anthropic.ContentBlockParamUnion {
+	// Log tool execution start
+	a.logger.LogToolExecution(variant.Name, variant.Input)
+
+	// Emit tool call event
+	if evt, err := events.NewToolCallEvent(runID, a.name, a.name, events.ToolCallData{
+		ToolUseID: variant.ID,
+		ToolName:  variant.Name,
+		Input:     variant.Input,
+	}); err == nil {
+		a.eventEmitter.Emit(evt)
+	}
+
+	// Execute the tool using the registry
+	toolStartTime := time.Now()
+	toolCtx := tools.WithRunID(tools.WithToolUseID(ctx, variant.ID), runID)
+	result, err := a.registry.ExecuteContext(toolCtx, variant.Name, variant.Input)
+	toolDuration := time.Since(toolStartTime)
+
+	// Log tool result
+	a.logger.LogToolResult(variant.Name, result, err)
+
+	// Emit tool result event
+	var resultJSON json.RawMessage
+	if result != nil {
+		resultJSON, _ = json.Marshal(result)
+	}
+
+	if evt, toolErr := events.NewToolResultEvent(runID, a.name, a.name, events.ToolResultData{
+		ToolUseID: variant.ID,
+		ToolName:  variant.Name,
+		Result:    resultJSON,
+		Error: func() string {
 			if err != nil {
-				// Return error result
-				toolResultBlocks = append(toolResultBlocks, anthropic.NewToolResultBlock(
-					variant.ID,
-					fmt.Sprintf("Error: %v", err),
-					true, // is_error
-				))
-			} else {
-				// Marshal result to JSON
-				resultJSON, err := json.Marshal(result)
-				if err != nil {
-					toolResultBlocks = append(toolResultBlocks, anthropic.NewToolResultBlock(
-						variant.ID,
-						fmt.Sprintf("Error marshaling result: %v", err),
-						true,
-					))
-				} else {
-					toolResultBlocks = append(toolResultBlocks, anthropic.NewToolResultBlock(
-						variant.ID,
-						string(resultJSON),
-						false,
-					))
-				}
+				return err.Error()
 			}
-		}
+			return ""
+		}(),
+		IsError:  err != nil,
+		Duration: toolDuration.String(),
+	}); toolErr == nil {
+		a.eventEmitter.Emit(evt)
 	}
 
-	return []anthropic.MessageParam{anthropic.NewUserMessage(toolResultBlocks...)}, nil
+	if err != nil {
+		// Return error result; invalid inputs name the offending fields
+		return anthropic.NewToolResultBlock(
+			variant.ID,
+			tools.ErrorContent(err),
+			true, // is_error
+		)
+	}
+
+	// Marshal result to JSON
+	resultJSON, err = json.Marshal(result)
+	if err != nil {
+		return anthropic.NewToolResultBlock(
+			variant.ID,
+			fmt.Sprintf("Error marshaling result: %v", err),
+			true,
+		)
+	}
+
+	return anthropic.NewToolResultBlock(
+		variant.ID,
+		string(resultJSON),
+		false,
+	)
 }
 
-// parseAPIError parses Anthropic API errors to extract meaningful information
+// parseAPIError formats an API error for display
 func (a *Agent) parseAPIError(err error) string {
-	errStr := err.Error()
-	
-	// Check if this is a structured error (likely from Anthropic SDK)
-	// The error format from the SDK often includes detailed information
-	
-	// Rate limit errors
-	if strings.Contains(errStr, "429") || strings.Contains(errStr, "rate_limit") {
-		return errStr
-	}
-	
-	// Authentication errors
-	if strings.Contains(errStr, "401") || strings.Contains(errStr, "authentication") {
+	apiErr := ClassifyAPIError(err)
+
+	switch apiErr.Kind {
+	case APIErrorAuthentication:
 		return "API authentication failed. Please check your API key."
+	case APIErrorRateLimit, APIErrorOverloaded, APIErrorServer, APIErrorTimeout, APIErrorConnection:
+		return fmt.Sprintf("API call failed after retries: %v", apiErr)
 	}
-	
-	// API error format from Anthropic SDK
-	if strings.Contains(errStr, "API call failed:") {
-		// Return the full error as it contains structured information
-		return errStr
-	}
-	
-	// Default: return the full error
-	return fmt.Sprintf("API call failed: %v", err)
+
+	return fmt.Sprintf("API call failed: %v", apiErr)
 }
 
 // GetName returns the agent's name
@@ -552,6 +796,44 @@ func (a *Agent) GetRegistry() *tools.ToolRegistry {
 	return a.registry
 }
 
+// SetBudget sets the budget tracker for subsequent runs. Passing a tracker
+// shared with another run charges both runs against the same budget.
+func (a *Agent) SetBudget(tracker *BudgetTracker) {
+	a.budget = tracker
+}
+
+// SetCheckpointer persists the conversation after every response and batch of
+// tool results so the run can be resumed losslessly
+func (a *Agent) SetCheckpointer(checkpointer Checkpointer) {
+	a.checkpointer = checkpointer
+}
+
+// saveCheckpoint saves a checkpoint if a checkpointer is set. Failures are
+// logged rather than ending the run.
+func (a *Agent) saveCheckpoint(runID string, iteration int, messages []anthropic.MessageParam) {
+	if a.checkpointer == nil {
+		return
+	}
+	if err := a.checkpointer.SaveCheckpoint(runID, iteration, messages, a.budget); err != nil {
+		a.logger.Error("Failed to save checkpoint: %v", err)
+	}
+}
+
+// SetRunID sets the ID of the agent's next new run instead of generating one
+func (a *Agent) SetRunID(runID string) {
+	a.newRunID = runID
+}
+
+// SetParentRunID marks the agent's runs as handoff sub-runs of a parent run
+func (a *Agent) SetParentRunID(parentRunID string) {
+	a.parentRunID = parentRunID
+}
+
+// SetStreaming enables or disables streaming of API responses
+func (a *Agent) SetStreaming(enabled bool) {
+	a.streaming = enabled
+}
+
 // ListTools returns all tools available to this agent
 func (a *Agent) ListTools() []string {
 	return a.registry.ListTools()
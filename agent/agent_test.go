@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+	"github.com/mottibechhofer/otel-ai-engineer/config"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+// recordingProvider returns canned responses in order and records each request
+type recordingProvider struct {
+	scriptedProvider
+	params []anthropic.MessageNewParams
+}
+
+func (p *recordingProvider) CreateMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	p.params = append(p.params, params)
+	return p.scriptedProvider.CreateMessage(ctx, params)
+}
+
+var thinkingTestResponses = []string{
+	`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"tool_use",
+	  "content":[
+	    {"type":"thinking","thinking":"I should look up the service.","signature":"sig_1"},
+	    {"type":"redacted_thinking","data":"opaque_1"},
+	    {"type":"tool_use","id":"toolu_1","name":"lookup","input":{"key":"service"}}],
+	  "usage":{"input_tokens":10,"output_tokens":5}}`,
+	`{"id":"msg_2","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"end_turn",
+	  "content":[{"type":"text","text":"The service is checkout."}],
+	  "usage":{"input_tokens":20,"output_tokens":6}}`,
+}
+
+func TestRunWithThinking(t *testing.T) {
+	provider := &recordingProvider{scriptedProvider: scriptedProvider{responses: thinkingTestResponses}}
+	emitter := events.NewEmitter()
+	received, unsubscribe := emitter.SubscribeAll()
+	defer unsubscribe()
+
+	agent := NewAgent(Config{
+		Name:           "thinking-test",
+		Provider:       provider,
+		LogLevel:       config.LogLevelSilent,
+		EventEmitter:   emitter,
+		MaxTokens:      2000,
+		ThinkingBudget: 2000,
+		Tools: []tools.Tool{{
+			Name:        "lookup",
+			Description: "Look up a value",
+			Schema:      anthropic.ToolInputSchemaParam{},
+			Handler: func(json.RawMessage) (interface{}, error) {
+				return map[string]string{"value": "checkout"}, nil
+			},
+		}},
+	})
+
+	result := agent.Run(context.Background(), "Which service?")
+	if !result.Success {
+		t.Fatalf("Run() failed: %v", result.Error)
+	}
+	if len(provider.params) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(provider.params))
+	}
+
+	first := provider.params[0]
+	if first.Thinking.OfEnabled == nil || first.Thinking.OfEnabled.BudgetTokens != 2000 {
+		t.Fatalf("thinking config = %+v, want enabled with a 2000 token budget", first.Thinking)
+	}
+	if first.MaxTokens <= 2000 {
+		t.Errorf("MaxTokens = %d, want more than the thinking budget", first.MaxTokens)
+	}
+
+	// The assistant turn is sent back with its thinking blocks unchanged
+	history := provider.params[1].Messages
+	if len(history) != 3 {
+		t.Fatalf("second request has %d messages, want 3", len(history))
+	}
+	content := history[1].Content
+	if len(content) != 3 {
+		t.Fatalf("assistant message has %d blocks, want 3", len(content))
+	}
+	if thinking := content[0].OfThinking; thinking == nil || thinking.Signature != "sig_1" || thinking.Thinking != "I should look up the service." {
+		t.Errorf("thinking block = %+v, want the original thinking and signature", content[0])
+	}
+	if redacted := content[1].OfRedactedThinking; redacted == nil || redacted.Data != "opaque_1" {
+		t.Errorf("redacted thinking block = %+v, want the original data", content[1])
+	}
+	if content[2].OfToolUse == nil || content[2].OfToolUse.ID != "toolu_1" {
+		t.Errorf("tool_use block = %+v", content[2])
+	}
+
+	var apiRequest *events.APIRequestData
+	var message *events.MessageData
+	for len(received) > 0 {
+		event := <-received
+		switch {
+		case event.Type == events.EventAPIRequest && apiRequest == nil:
+			apiRequest = &events.APIRequestData{}
+			if err := json.Unmarshal(event.Data, apiRequest); err != nil {
+				t.Fatalf("failed to decode api_request event: %v", err)
+			}
+		case event.Type == events.EventMessage && message == nil:
+			message = &events.MessageData{}
+			if err := json.Unmarshal(event.Data, message); err != nil {
+				t.Fatalf("failed to decode message event: %v", err)
+			}
+		}
+	}
+	if apiRequest == nil || apiRequest.ThinkingBudget != 2000 {
+		t.Errorf("api_request event = %+v, want a 2000 token thinking budget", apiRequest)
+	}
+	if message == nil || len(message.Content) != 3 {
+		t.Fatalf("message event = %+v, want 3 content blocks", message)
+	}
+	if block := message.Content[0]; block.Type != "thinking" || block.Thinking == nil || block.Thinking.Signature != "sig_1" {
+		t.Errorf("thinking content = %+v", block)
+	}
+	if block := message.Content[1]; block.Type != "redacted_thinking" || block.Thinking == nil || block.Thinking.Data != "opaque_1" {
+		t.Errorf("redacted thinking content = %+v", block)
+	}
+}
@@ -16,7 +16,7 @@ type BackendAgent struct {
 }
 
 // NewBackendAgent creates a new backend agent with backend management tools
-func NewBackendAgent(client *anthropic.Client, logLevel config.LogLevel) (*BackendAgent, error) {
+func NewBackendAgent(provider Provider, logLevel config.LogLevel) (*BackendAgent, error) {
 	systemPrompt := `You are an expert observability backend connectivity assistant.
 
 Your capabilities:
@@ -95,7 +95,7 @@ Best Practices:
 	agent := NewAgent(Config{
 		Name:         "BackendAgent",
 		Description:  "An AI agent specialized for observability backend connectivity and validation",
-		Client:       client,
+		Provider:     provider,
 		Model:        anthropic.ModelClaudeSonnet4_5_20250929,
 		MaxTokens:    4096,
 		SystemPrompt: systemPrompt,
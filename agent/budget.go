@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+// ErrBudgetExceeded is wrapped by the run error when a budget limit ends a run
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Budget limits what a run may consume. Zero values are unlimited.
+type Budget struct {
+	MaxInputTokens  int           // Input tokens including cache writes and reads
+	MaxOutputTokens int           // Output tokens
+	MaxCostUSD      float64       // Spend computed from the model price table
+	MaxDuration     time.Duration // Wall-clock time since the run started
+	MaxIterations   int           // Agent loop iterations (default 50)
+}
+
+// BudgetFromRunBudget converts a stored or requested budget to a Budget
+func BudgetFromRunBudget(b *storage.RunBudget) (*Budget, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	budget := &Budget{
+		MaxInputTokens:  b.MaxInputTokens,
+		MaxOutputTokens: b.MaxOutputTokens,
+		MaxCostUSD:      b.MaxCostUSD,
+		MaxIterations:   b.MaxIterations,
+	}
+	if b.MaxDuration != "" {
+		d, err := time.ParseDuration(b.MaxDuration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_duration: %w", err)
+		}
+		budget.MaxDuration = d
+	}
+
+	return budget, nil
+}
+
+// RunBudget converts b to its stored form
+func (b Budget) RunBudget() *storage.RunBudget {
+	budget := &storage.RunBudget{
+		MaxInputTokens:  b.MaxInputTokens,
+		MaxOutputTokens: b.MaxOutputTokens,
+		MaxCostUSD:      b.MaxCostUSD,
+		MaxIterations:   b.MaxIterations,
+	}
+	if b.MaxDuration > 0 {
+		budget.MaxDuration = b.MaxDuration.String()
+	}
+	return budget
+}
+
+// Budget limit names reported in RunEndData.BudgetLimit
+const (
+	BudgetLimitInputTokens  = "input_tokens"
+	BudgetLimitOutputTokens = "output_tokens"
+	BudgetLimitCost         = "cost_usd"
+	BudgetLimitDuration     = "duration"
+)
+
+// BudgetTracker accumulates usage against a Budget. A single tracker is
+// shared by a run and all sub-runs started through handoff_task, so
+// delegated work is charged to the parent budget.
+type BudgetTracker struct {
+	mu           sync.Mutex
+	budget       Budget
+	startTime    time.Time
+	inputTokens  int
+	outputTokens int
+	costUSD      float64
+}
+
+// NewBudgetTracker creates a tracker whose duration limit counts from now
+func NewBudgetTracker(budget Budget) *BudgetTracker {
+	return &BudgetTracker{
+		budget:    budget,
+		startTime: time.Now(),
+	}
+}
+
+// RestoreBudgetTracker creates a tracker that continues from usage a run
+// already charged before it stopped. Elapsed time counts against the
+// duration limit, so the deadline moves back by that much.
+func RestoreBudgetTracker(budget Budget, usage storage.BudgetUsage) (*BudgetTracker, error) {
+	tracker := NewBudgetTracker(budget)
+	tracker.inputTokens = usage.InputTokens
+	tracker.outputTokens = usage.OutputTokens
+	tracker.costUSD = usage.CostUSD
+
+	if usage.Elapsed != "" {
+		elapsed, err := time.ParseDuration(usage.Elapsed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid elapsed time: %w", err)
+		}
+		tracker.startTime = tracker.startTime.Add(-elapsed)
+	}
+
+	return tracker, nil
+}
+
+// BudgetTrackerFromCheckpoint returns a tracker that continues the budget
+// saved with a checkpoint, or nil if the checkpoint has no budget
+func BudgetTrackerFromCheckpoint(checkpoint *storage.RunCheckpoint) (*BudgetTracker, error) {
+	if checkpoint == nil || checkpoint.Budget == nil {
+		return nil, nil
+	}
+
+	budget, err := BudgetFromRunBudget(checkpoint.Budget)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage storage.BudgetUsage
+	if checkpoint.BudgetUsage != nil {
+		usage = *checkpoint.BudgetUsage
+	}
+	return RestoreBudgetTracker(*budget, usage)
+}
+
+// Record adds the usage of one API response
+func (t *BudgetTracker) Record(model anthropic.Model, usage anthropic.Usage) {
+	pricing, _ := PricingForModel(string(model))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inputTokens += int(usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens)
+	t.outputTokens += int(usage.OutputTokens)
+	t.costUSD += pricing.CostUSD(usage)
+}
+
+// Exceeded returns the name of the first exhausted limit and a description of
+// the usage, or an empty limit if the budget still has room
+func (t *BudgetTracker) Exceeded() (limit string, message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.budget
+	switch {
+	case b.MaxInputTokens > 0 && t.inputTokens >= b.MaxInputTokens:
+		return BudgetLimitInputTokens, fmt.Sprintf("input tokens %d/%d", t.inputTokens, b.MaxInputTokens)
+	case b.MaxOutputTokens > 0 && t.outputTokens >= b.MaxOutputTokens:
+		return BudgetLimitOutputTokens, fmt.Sprintf("output tokens %d/%d", t.outputTokens, b.MaxOutputTokens)
+	case b.MaxCostUSD > 0 && t.costUSD >= b.MaxCostUSD:
+		return BudgetLimitCost, fmt.Sprintf("cost $%.4f/$%.4f", t.costUSD, b.MaxCostUSD)
+	case b.MaxDuration > 0 && time.Since(t.startTime) >= b.MaxDuration:
+		return BudgetLimitDuration, fmt.Sprintf("duration limit %s reached", b.MaxDuration)
+	}
+
+	return "", ""
+}
+
+// Deadline returns the wall-clock deadline, if the budget has one
+func (t *BudgetTracker) Deadline() (time.Time, bool) {
+	if t.budget.MaxDuration <= 0 {
+		return time.Time{}, false
+	}
+	return t.startTime.Add(t.budget.MaxDuration), true
+}
+
+// MaxIterations returns the iteration limit, or 0 if the budget doesn't set one
+func (t *BudgetTracker) MaxIterations() int {
+	return t.budget.MaxIterations
+}
+
+// CostUSD returns the spend recorded so far
+func (t *BudgetTracker) CostUSD() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.costUSD
+}
+
+// Usage returns the usage recorded so far in its stored form
+func (t *BudgetTracker) Usage() *storage.BudgetUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return &storage.BudgetUsage{
+		InputTokens:  t.inputTokens,
+		OutputTokens: t.outputTokens,
+		CostUSD:      t.costUSD,
+		Elapsed:      time.Since(t.startTime).Round(time.Second).String(),
+	}
+}
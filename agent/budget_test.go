@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+func TestBudgetTrackerExceeded(t *testing.T) {
+	usage := anthropic.Usage{InputTokens: 600, OutputTokens: 100, CacheCreationInputTokens: 300, CacheReadInputTokens: 100}
+
+	tests := []struct {
+		name      string
+		budget    Budget
+		wantLimit string
+		wantUsage string
+	}{
+		{name: "unlimited", budget: Budget{}},
+		{name: "room left", budget: Budget{MaxInputTokens: 2000, MaxOutputTokens: 500, MaxCostUSD: 1}},
+		{name: "input tokens include cache", budget: Budget{MaxInputTokens: 1000}, wantLimit: BudgetLimitInputTokens, wantUsage: "input tokens 1000/1000"},
+		{name: "output tokens", budget: Budget{MaxOutputTokens: 100}, wantLimit: BudgetLimitOutputTokens, wantUsage: "output tokens 100/100"},
+		{name: "cost", budget: Budget{MaxCostUSD: 0.001}, wantLimit: BudgetLimitCost},
+		{name: "input checked first", budget: Budget{MaxInputTokens: 10, MaxOutputTokens: 10}, wantLimit: BudgetLimitInputTokens},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewBudgetTracker(tt.budget)
+			tracker.Record(anthropic.ModelClaudeSonnet4_5, usage)
+
+			limit, message := tracker.Exceeded()
+			if limit != tt.wantLimit {
+				t.Errorf("Exceeded() limit = %q, want %q", limit, tt.wantLimit)
+			}
+			if tt.wantUsage != "" && message != tt.wantUsage {
+				t.Errorf("Exceeded() usage = %q, want %q", message, tt.wantUsage)
+			}
+		})
+	}
+}
+
+func TestBudgetTrackerDuration(t *testing.T) {
+	tracker := NewBudgetTracker(Budget{MaxDuration: time.Hour})
+	deadline, ok := tracker.Deadline()
+	if !ok || time.Until(deadline) < 59*time.Minute {
+		t.Errorf("Deadline() = %v, %v, want about an hour from now", deadline, ok)
+	}
+	if limit, _ := tracker.Exceeded(); limit != "" {
+		t.Errorf("Exceeded() = %q before the deadline", limit)
+	}
+
+	if _, ok := NewBudgetTracker(Budget{}).Deadline(); ok {
+		t.Error("Deadline() set without a duration limit")
+	}
+
+	expired := NewBudgetTracker(Budget{MaxDuration: time.Nanosecond})
+	time.Sleep(time.Millisecond)
+	if limit, _ := expired.Exceeded(); limit != BudgetLimitDuration {
+		t.Errorf("Exceeded() = %q, want %q", limit, BudgetLimitDuration)
+	}
+}
+
+func TestBudgetTrackerCostAccumulates(t *testing.T) {
+	tracker := NewBudgetTracker(Budget{})
+	usage := anthropic.Usage{InputTokens: 1_000_000}
+
+	tracker.Record(anthropic.ModelClaudeSonnet4_5, usage)
+	tracker.Record(anthropic.ModelClaudeHaiku4_5, usage)
+
+	if got := tracker.CostUSD(); math.Abs(got-4) > 1e-9 {
+		t.Errorf("CostUSD() = %v, want 4", got)
+	}
+}
+
+func TestBudgetFromRunBudget(t *testing.T) {
+	budget, err := BudgetFromRunBudget(&storage.RunBudget{MaxInputTokens: 10, MaxCostUSD: 2.5, MaxDuration: "30m", MaxIterations: 5})
+	if err != nil {
+		t.Fatalf("BudgetFromRunBudget() error = %v", err)
+	}
+	want := Budget{MaxInputTokens: 10, MaxCostUSD: 2.5, MaxDuration: 30 * time.Minute, MaxIterations: 5}
+	if *budget != want {
+		t.Errorf("BudgetFromRunBudget() = %+v, want %+v", *budget, want)
+	}
+	if got := budget.RunBudget(); *got != (storage.RunBudget{MaxInputTokens: 10, MaxCostUSD: 2.5, MaxDuration: "30m0s", MaxIterations: 5}) {
+		t.Errorf("RunBudget() = %+v", *got)
+	}
+
+	if budget, err := BudgetFromRunBudget(nil); budget != nil || err != nil {
+		t.Errorf("BudgetFromRunBudget(nil) = %v, %v, want nil", budget, err)
+	}
+	if _, err := BudgetFromRunBudget(&storage.RunBudget{MaxDuration: "soon"}); err == nil {
+		t.Error("BudgetFromRunBudget() accepted an invalid duration")
+	}
+}
+
+func TestBudgetTrackerFromCheckpoint(t *testing.T) {
+	tracker, err := BudgetTrackerFromCheckpoint(&storage.RunCheckpoint{
+		Budget:      &storage.RunBudget{MaxOutputTokens: 1000, MaxDuration: "1h"},
+		BudgetUsage: &storage.BudgetUsage{InputTokens: 500, OutputTokens: 900, CostUSD: 0.25, Elapsed: "50m"},
+	})
+	if err != nil {
+		t.Fatalf("BudgetTrackerFromCheckpoint() error = %v", err)
+	}
+
+	usage := tracker.Usage()
+	if usage.InputTokens != 500 || usage.OutputTokens != 900 || usage.CostUSD != 0.25 || usage.Elapsed != "50m0s" {
+		t.Errorf("restored usage = %+v", *usage)
+	}
+	if deadline, _ := tracker.Deadline(); time.Until(deadline) > 11*time.Minute {
+		t.Errorf("restored deadline is %v away, want about 10m", time.Until(deadline))
+	}
+
+	// The restored usage counts towards the limits
+	tracker.Record(anthropic.ModelClaudeSonnet4_5, anthropic.Usage{OutputTokens: 100})
+	if limit, _ := tracker.Exceeded(); limit != BudgetLimitOutputTokens {
+		t.Errorf("Exceeded() = %q, want %q", limit, BudgetLimitOutputTokens)
+	}
+
+	for _, checkpoint := range []*storage.RunCheckpoint{nil, {}} {
+		if tracker, err := BudgetTrackerFromCheckpoint(checkpoint); tracker != nil || err != nil {
+			t.Errorf("BudgetTrackerFromCheckpoint(%+v) = %v, %v, want nil", checkpoint, tracker, err)
+		}
+	}
+
+	_, err = BudgetTrackerFromCheckpoint(&storage.RunCheckpoint{
+		Budget:      &storage.RunBudget{MaxIterations: 3},
+		BudgetUsage: &storage.BudgetUsage{Elapsed: "a while"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "elapsed") {
+		t.Errorf("BudgetTrackerFromCheckpoint() error = %v, want invalid elapsed time", err)
+	}
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// Prompt caching places cache_control breakpoints on the stable parts of each
+// request so repeated iterations read them from the cache instead of paying
+// for them as fresh input tokens. The API allows at most four breakpoints per
+// request: one on the system prompt, one on the last tool definition, and two
+// on the conversation (the latest message and the last message of the
+// previous request), so the prefix written by the previous iteration is
+// always within lookback range.
+
+// applyPromptCaching marks cache breakpoints on params. Messages and their
+// content blocks are copied before being marked, so the run history is never
+// modified and breakpoints don't accumulate across iterations.
+func applyPromptCaching(params *anthropic.MessageNewParams, previousLen int) {
+	if len(params.System) > 0 {
+		system := make([]anthropic.TextBlockParam, len(params.System))
+		copy(system, params.System)
+		system[len(system)-1].CacheControl = anthropic.NewCacheControlEphemeralParam()
+		params.System = system
+	}
+
+	if len(params.Tools) > 0 {
+		toolUnions := make([]anthropic.ToolUnionParam, len(params.Tools))
+		copy(toolUnions, params.Tools)
+		last := &toolUnions[len(toolUnions)-1]
+		if last.OfTool != nil {
+			tool := *last.OfTool
+			tool.CacheControl = anthropic.NewCacheControlEphemeralParam()
+			last.OfTool = &tool
+		}
+		params.Tools = toolUnions
+	}
+
+	if len(params.Messages) == 0 {
+		return
+	}
+
+	messages := make([]anthropic.MessageParam, len(params.Messages))
+	copy(messages, params.Messages)
+
+	markMessageForCaching(messages, len(messages)-1)
+	if previousLen > 0 && previousLen < len(messages) {
+		markMessageForCaching(messages, previousLen-1)
+	}
+
+	params.Messages = messages
+}
+
+// markMessageForCaching sets a cache breakpoint on the last cacheable content
+// block of messages[i], replacing the message with a marked copy
+func markMessageForCaching(messages []anthropic.MessageParam, i int) {
+	msg := messages[i]
+	for j := len(msg.Content) - 1; j >= 0; j-- {
+		marked, ok := withCacheControl(msg.Content[j])
+		if !ok {
+			continue
+		}
+
+		content := make([]anthropic.ContentBlockParamUnion, len(msg.Content))
+		copy(content, msg.Content)
+		content[j] = marked
+		messages[i] = anthropic.MessageParam{Role: msg.Role, Content: content}
+		return
+	}
+}
+
+// withCacheControl returns a copy of block with a cache breakpoint set, or
+// false if the block type can't carry one (e.g. thinking blocks)
+func withCacheControl(block anthropic.ContentBlockParamUnion) (anthropic.ContentBlockParamUnion, bool) {
+	cacheControl := anthropic.NewCacheControlEphemeralParam()
+
+	switch {
+	case block.OfText != nil:
+		b := *block.OfText
+		b.CacheControl = cacheControl
+		return anthropic.ContentBlockParamUnion{OfText: &b}, true
+	case block.OfToolResult != nil:
+		b := *block.OfToolResult
+		b.CacheControl = cacheControl
+		return anthropic.ContentBlockParamUnion{OfToolResult: &b}, true
+	case block.OfToolUse != nil:
+		b := *block.OfToolUse
+		b.CacheControl = cacheControl
+		return anthropic.ContentBlockParamUnion{OfToolUse: &b}, true
+	case block.OfImage != nil:
+		b := *block.OfImage
+		b.CacheControl = cacheControl
+		return anthropic.ContentBlockParamUnion{OfImage: &b}, true
+	case block.OfDocument != nil:
+		b := *block.OfDocument
+		b.CacheControl = cacheControl
+		return anthropic.ContentBlockParamUnion{OfDocument: &b}, true
+	}
+
+	return block, false
+}
@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// countBreakpoints counts the cache_control markers sent with value
+func countBreakpoints(t *testing.T, value interface{}) int {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return strings.Count(string(data), `"cache_control"`)
+}
+
+func cachingTestParams(messages []anthropic.MessageParam) anthropic.MessageNewParams {
+	return anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaudeSonnet4_5,
+		MaxTokens: 1024,
+		System: []anthropic.TextBlockParam{
+			{Text: "You are a test agent."},
+			{Text: "Use the lookup tool."},
+		},
+		Tools: []anthropic.ToolUnionParam{
+			{OfTool: &anthropic.ToolParam{Name: "first", InputSchema: anthropic.ToolInputSchemaParam{}}},
+			{OfTool: &anthropic.ToolParam{Name: "lookup", InputSchema: anthropic.ToolInputSchemaParam{}}},
+		},
+		Messages: messages,
+	}
+}
+
+func TestApplyPromptCachingBreakpoints(t *testing.T) {
+	messages := toolConversation(3, 10)
+	params := cachingTestParams(messages)
+
+	applyPromptCaching(&params, 5)
+
+	if params.System[0].CacheControl.Type != "" || params.System[1].CacheControl.Type == "" {
+		t.Error("want a breakpoint on the last system block only")
+	}
+	if params.Tools[0].OfTool.CacheControl.Type != "" || params.Tools[1].OfTool.CacheControl.Type == "" {
+		t.Error("want a breakpoint on the last tool only")
+	}
+
+	tests := []struct {
+		index int
+		want  int
+	}{
+		{index: 0, want: 0},
+		{index: 3, want: 0},
+		{index: 4, want: 1}, // last message of the previous request
+		{index: 5, want: 0},
+		{index: 6, want: 1}, // latest message
+	}
+	for _, tt := range tests {
+		if got := countBreakpoints(t, params.Messages[tt.index]); got != tt.want {
+			t.Errorf("message %d has %d breakpoints, want %d", tt.index, got, tt.want)
+		}
+	}
+
+	if got := countBreakpoints(t, params); got != 4 {
+		t.Errorf("request has %d breakpoints, want at most 4", got)
+	}
+}
+
+func TestApplyPromptCachingDoesNotModifyHistory(t *testing.T) {
+	messages := toolConversation(2, 10)
+	params := cachingTestParams(messages)
+	system := params.System
+	toolUnions := params.Tools
+
+	applyPromptCaching(&params, 3)
+
+	if got := countBreakpoints(t, messages); got != 0 {
+		t.Errorf("history has %d breakpoints after caching, want 0", got)
+	}
+	if system[1].CacheControl.Type != "" || toolUnions[1].OfTool.CacheControl.Type != "" {
+		t.Error("applyPromptCaching() modified the caller's system prompt or tools")
+	}
+
+	// Breakpoints don't accumulate when the history is reused for the next request
+	next := cachingTestParams(append(messages,
+		anthropic.NewAssistantMessage(anthropic.NewTextBlock("done")),
+	))
+	applyPromptCaching(&next, len(messages))
+	if got := countBreakpoints(t, next.Messages); got != 2 {
+		t.Errorf("next request messages have %d breakpoints, want 2", got)
+	}
+}
+
+func TestApplyPromptCachingPreviousLength(t *testing.T) {
+	tests := []struct {
+		name        string
+		previousLen int
+		want        int
+	}{
+		{name: "first request", previousLen: 0, want: 1},
+		{name: "nothing new", previousLen: 5, want: 1},
+		{name: "beyond history", previousLen: 9, want: 1},
+		{name: "one new message", previousLen: 4, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := cachingTestParams(toolConversation(2, 10))
+			applyPromptCaching(&params, tt.previousLen)
+			if got := countBreakpoints(t, params.Messages); got != tt.want {
+				t.Errorf("messages have %d breakpoints, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkMessageForCachingSkipsThinking(t *testing.T) {
+	messages := []anthropic.MessageParam{
+		anthropic.NewAssistantMessage(
+			anthropic.NewTextBlock("checking"),
+			anthropic.NewThinkingBlock("sig", "let me think"),
+		),
+	}
+
+	markMessageForCaching(messages, 0)
+
+	content := messages[0].Content
+	if content[0].OfText == nil || content[0].OfText.CacheControl.Type == "" {
+		t.Error("want the breakpoint on the text block before the thinking block")
+	}
+	if countBreakpoints(t, content[1]) != 0 {
+		t.Error("thinking block was marked for caching")
+	}
+
+	onlyThinking := []anthropic.MessageParam{
+		anthropic.NewAssistantMessage(anthropic.NewThinkingBlock("sig", "let me think")),
+	}
+	markMessageForCaching(onlyThinking, 0)
+	if countBreakpoints(t, onlyThinking) != 0 {
+		t.Error("message with only a thinking block was marked for caching")
+	}
+}
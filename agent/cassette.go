@@ -0,0 +1,272 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+// Cassettes make agent runs reproducible without network access. In record
+// mode every Messages request/response pair and every tool result of a run is
+// written to a JSON file; in replay mode responses and tool results are served
+// from that file, so the same agent code produces the same run offline.
+//
+// Replay is strict: each request must hash to the recorded request, so a
+// changed prompt, tool schema or tool result fails with ErrCassetteMismatch
+// instead of silently diverging. Only successful responses are recorded;
+// retried errors are not part of the cassette.
+
+// Cassette modes
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// ErrCassetteMismatch is returned when a replayed run diverges from the recording
+var ErrCassetteMismatch = errors.New("cassette mismatch")
+
+// cassetteVersion is bumped when the file format changes incompatibly
+const cassetteVersion = 1
+
+// Cassette is the on-disk recording of a run
+type Cassette struct {
+	Version      int                   `json:"version"`
+	Interactions []CassetteInteraction `json:"interactions"`
+	ToolCalls    []CassetteToolCall    `json:"tool_calls,omitempty"`
+}
+
+// CassetteInteraction is one Messages request and its response
+type CassetteInteraction struct {
+	RequestHash string          `json:"request_hash"`
+	Request     json.RawMessage `json:"request"`
+	Response    json.RawMessage `json:"response"`
+}
+
+// CassetteToolCall is one tool execution
+type CassetteToolCall struct {
+	ToolUseID string          `json:"tool_use_id"`
+	ToolName  string          `json:"tool_name"`
+	Input     json.RawMessage `json:"input"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// CassetteConfig configures a CassetteProvider
+type CassetteConfig struct {
+	Path  string   // Cassette file
+	Mode  string   // CassetteRecord or CassetteReplay
+	Inner Provider // Provider whose responses are recorded (record mode only)
+}
+
+// CassetteProvider records or replays Messages calls. Agents created with it
+// also record or stub their tool results (see NewAgent). It does not stream;
+// agents with streaming enabled use CreateMessage instead.
+type CassetteProvider struct {
+	mu       sync.Mutex
+	path     string
+	mode     string
+	inner    Provider
+	cassette *Cassette
+	next     int // Next interaction to replay
+}
+
+// NewCassetteProvider creates a cassette provider. Replay mode loads the
+// cassette file; record mode starts a new one, overwriting the file on the
+// first response.
+func NewCassetteProvider(cfg CassetteConfig) (*CassetteProvider, error) {
+	p := &CassetteProvider{
+		path:     cfg.Path,
+		mode:     cfg.Mode,
+		inner:    cfg.Inner,
+		cassette: &Cassette{Version: cassetteVersion},
+	}
+
+	switch cfg.Mode {
+	case CassetteRecord:
+		if cfg.Inner == nil {
+			return nil, fmt.Errorf("cassette record mode requires an inner provider")
+		}
+	case CassetteReplay:
+		cassette, err := LoadCassette(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+		p.cassette = cassette
+	default:
+		return nil, fmt.Errorf("unknown cassette mode: %q", cfg.Mode)
+	}
+
+	return p, nil
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if cassette.Version != cassetteVersion {
+		return nil, fmt.Errorf("cassette %s has version %d, expected %d", path, cassette.Version, cassetteVersion)
+	}
+	return &cassette, nil
+}
+
+// Name returns the inner provider's name when recording, or "cassette"
+func (p *CassetteProvider) Name() string {
+	if p.inner != nil {
+		return p.inner.Name()
+	}
+	return "cassette"
+}
+
+// CreateMessage returns the next recorded response, or calls the inner
+// provider and records the exchange
+func (p *CassetteProvider) CreateMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	request, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	hash := sha256.Sum256(request)
+	requestHash := hex.EncodeToString(hash[:])
+
+	if p.mode == CassetteReplay {
+		return p.replayMessage(requestHash)
+	}
+
+	message, err := p.inner.CreateMessage(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	response := json.RawMessage(message.RawJSON())
+	if len(response) == 0 {
+		if response, err = json.Marshal(message); err != nil {
+			return nil, fmt.Errorf("failed to marshal response: %w", err)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cassette.Interactions = append(p.cassette.Interactions, CassetteInteraction{
+		RequestHash: requestHash,
+		Request:     request,
+		Response:    response,
+	})
+	if err := p.save(); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// replayMessage returns the next recorded response if its request matches
+func (p *CassetteProvider) replayMessage(requestHash string) (*anthropic.Message, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next >= len(p.cassette.Interactions) {
+		return nil, fmt.Errorf("%w: request %d was not recorded (cassette has %d)", ErrCassetteMismatch, p.next+1, len(p.cassette.Interactions))
+	}
+
+	interaction := p.cassette.Interactions[p.next]
+	if interaction.RequestHash != requestHash {
+		return nil, fmt.Errorf("%w: request %d differs from the recording", ErrCassetteMismatch, p.next+1)
+	}
+	p.next++
+
+	var message anthropic.Message
+	if err := json.Unmarshal(interaction.Response, &message); err != nil {
+		return nil, fmt.Errorf("failed to parse recorded response %d: %w", p.next, err)
+	}
+	return &message, nil
+}
+
+// InterceptTool is a tools.ToolInterceptor that records tool results, or
+// returns the recorded result for the same tool_use ID when replaying
+func (p *CassetteProvider) InterceptTool(ctx context.Context, toolName string, inputJSON json.RawMessage, next tools.ContextToolHandler) (interface{}, error) {
+	toolUseID := tools.ToolUseIDFromContext(ctx)
+
+	if p.mode == CassetteReplay {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		for _, call := range p.cassette.ToolCalls {
+			if call.ToolUseID != toolUseID || call.ToolName != toolName {
+				continue
+			}
+			if call.Error != "" {
+				return nil, errors.New(call.Error)
+			}
+			return call.Result, nil
+		}
+		return nil, fmt.Errorf("%w: no recorded result for tool %s (%s)", ErrCassetteMismatch, toolName, toolUseID)
+	}
+
+	result, err := next(ctx, inputJSON)
+
+	call := CassetteToolCall{
+		ToolUseID: toolUseID,
+		ToolName:  toolName,
+		Input:     inputJSON,
+	}
+	if err != nil {
+		call.Error = err.Error()
+	} else if result != nil {
+		resultJSON, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			return result, err
+		}
+		call.Result = resultJSON
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cassette.ToolCalls = append(p.cassette.ToolCalls, call)
+	if saveErr := p.save(); saveErr != nil {
+		return nil, saveErr
+	}
+
+	return result, err
+}
+
+// Remaining returns the number of recorded responses not yet replayed. A
+// replayed run that ends early leaves responses behind.
+func (p *CassetteProvider) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.cassette.Interactions) - p.next
+}
+
+// save writes the cassette so a crashed recording keeps what it captured.
+// Callers must hold p.mu.
+func (p *CassetteProvider) save() error {
+	data, err := json.MarshalIndent(p.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if dir := filepath.Dir(p.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(p.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/config"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+// scriptedProvider returns canned responses in order
+type scriptedProvider struct {
+	responses []string
+	calls     int
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) CreateMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	var message anthropic.Message
+	if err := json.Unmarshal([]byte(p.responses[p.calls]), &message); err != nil {
+		return nil, err
+	}
+	p.calls++
+	return &message, nil
+}
+
+var cassetteTestResponses = []string{
+	`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"tool_use",
+	  "content":[{"type":"tool_use","id":"toolu_1","name":"lookup","input":{"key":"service"}}],
+	  "usage":{"input_tokens":10,"output_tokens":5}}`,
+	`{"id":"msg_2","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"end_turn",
+	  "content":[{"type":"text","text":"The service is checkout."}],
+	  "usage":{"input_tokens":20,"output_tokens":6}}`,
+}
+
+// newCassetteTestAgent creates an agent with a single lookup tool that counts its calls
+func newCassetteTestAgent(provider Provider, toolCalls *int) *Agent {
+	return NewAgent(Config{
+		Name:     "cassette-test",
+		Provider: provider,
+		LogLevel: config.LogLevelSilent,
+		Tools: []tools.Tool{{
+			Name:        "lookup",
+			Description: "Look up a value",
+			Schema:      anthropic.ToolInputSchemaParam{Properties: map[string]interface{}{"key": map[string]interface{}{"type": "string"}}},
+			Handler: func(json.RawMessage) (interface{}, error) {
+				*toolCalls++
+				return map[string]string{"value": "checkout"}, nil
+			},
+		}},
+	})
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+
+	recorder, err := NewCassetteProvider(CassetteConfig{
+		Path:  path,
+		Mode:  CassetteRecord,
+		Inner: &scriptedProvider{responses: cassetteTestResponses},
+	})
+	if err != nil {
+		t.Fatalf("NewCassetteProvider(record) error = %v", err)
+	}
+
+	var recordedToolCalls int
+	recorded := newCassetteTestAgent(recorder, &recordedToolCalls).Run(context.Background(), "Which service?")
+	if !recorded.Success {
+		t.Fatalf("recording run failed: %v", recorded.Error)
+	}
+	if recordedToolCalls != 1 {
+		t.Fatalf("recording ran tool %d times, want 1", recordedToolCalls)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette() error = %v", err)
+	}
+	if len(cassette.Interactions) != 2 || len(cassette.ToolCalls) != 1 {
+		t.Fatalf("cassette has %d interactions and %d tool calls, want 2 and 1", len(cassette.Interactions), len(cassette.ToolCalls))
+	}
+
+	replayer, err := NewCassetteProvider(CassetteConfig{Path: path, Mode: CassetteReplay})
+	if err != nil {
+		t.Fatalf("NewCassetteProvider(replay) error = %v", err)
+	}
+
+	var replayedToolCalls int
+	replayed := newCassetteTestAgent(replayer, &replayedToolCalls).Run(context.Background(), "Which service?")
+	if !replayed.Success {
+		t.Fatalf("replay run failed: %v", replayed.Error)
+	}
+	if replayedToolCalls != 0 {
+		t.Errorf("replay ran tool %d times, want stubbed result", replayedToolCalls)
+	}
+	if replayer.Remaining() != 0 {
+		t.Errorf("replay left %d responses unused", replayer.Remaining())
+	}
+
+	text := replayed.FinalMessage.Content[0].AsText().Text
+	if text != "The service is checkout." {
+		t.Errorf("final text = %q", text)
+	}
+}
+
+func TestCassetteReplayMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+
+	recorder, err := NewCassetteProvider(CassetteConfig{
+		Path:  path,
+		Mode:  CassetteRecord,
+		Inner: &scriptedProvider{responses: cassetteTestResponses},
+	})
+	if err != nil {
+		t.Fatalf("NewCassetteProvider(record) error = %v", err)
+	}
+	var toolCalls int
+	if result := newCassetteTestAgent(recorder, &toolCalls).Run(context.Background(), "Which service?"); !result.Success {
+		t.Fatalf("recording run failed: %v", result.Error)
+	}
+
+	replayer, err := NewCassetteProvider(CassetteConfig{Path: path, Mode: CassetteReplay})
+	if err != nil {
+		t.Fatalf("NewCassetteProvider(replay) error = %v", err)
+	}
+
+	result := newCassetteTestAgent(replayer, &toolCalls).Run(context.Background(), "Which region?")
+	if result.Success {
+		t.Fatal("replay with a different prompt succeeded, want mismatch")
+	}
+	if result.Error == nil || !strings.Contains(result.Error.Error(), ErrCassetteMismatch.Error()) {
+		t.Errorf("error = %v, want cassette mismatch", result.Error)
+	}
+}
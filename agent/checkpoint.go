@@ -0,0 +1,171 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+// Checkpoints persist the exact conversation of a run after every model
+// response and every batch of tool results. Resuming a run restores the latest
+// checkpoint, so the agent keeps its assistant turns, tool calls and tool
+// results instead of starting over from the user messages alone.
+
+// interruptedToolResult is the tool result given to a tool call whose result
+// was never recorded, e.g. because the run stopped while the tool was running
+const interruptedToolResult = "Tool call was interrupted before it returned a result. Run it again if it is still needed."
+
+// Checkpointer persists a run's conversation and, when the run has a budget,
+// the usage charged against it
+type Checkpointer interface {
+	SaveCheckpoint(runID string, iteration int, messages []anthropic.MessageParam, budget *BudgetTracker) error
+}
+
+// StorageCheckpointer saves checkpoints to run storage
+type StorageCheckpointer struct {
+	storage storage.Storage
+}
+
+// NewStorageCheckpointer creates a checkpointer backed by storage
+func NewStorageCheckpointer(stor storage.Storage) *StorageCheckpointer {
+	return &StorageCheckpointer{storage: stor}
+}
+
+// SaveCheckpoint stores messages and the budget state as the run's latest checkpoint
+func (c *StorageCheckpointer) SaveCheckpoint(runID string, iteration int, messages []anthropic.MessageParam, budget *BudgetTracker) error {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	checkpoint := &storage.RunCheckpoint{
+		RunID:     runID,
+		Iteration: iteration,
+		Messages:  data,
+		CreatedAt: time.Now(),
+	}
+	if budget != nil {
+		checkpoint.Budget = budget.budget.RunBudget()
+		checkpoint.BudgetUsage = budget.Usage()
+	}
+
+	return c.storage.SaveCheckpoint(checkpoint)
+}
+
+// LoadCheckpoint returns the conversation from the run's latest checkpoint that
+// decodes, with dangling tool calls repaired. The checkpoint is nil when the
+// run has none.
+func LoadCheckpoint(stor storage.Storage, runID string) ([]anthropic.MessageParam, *storage.RunCheckpoint, error) {
+	checkpoints, err := stor.GetLatestCheckpoints(runID, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, checkpoint := range checkpoints {
+		var messages []anthropic.MessageParam
+		if err := json.Unmarshal(checkpoint.Messages, &messages); err != nil || len(messages) == 0 {
+			continue
+		}
+		return RepairToolUse(messages), checkpoint, nil
+	}
+
+	return nil, nil, nil
+}
+
+// RepairToolUse gives every tool_use block without a matching tool_result an
+// error result, since the API rejects conversations with unanswered tool calls.
+// Missing results are added to the following user message, or to a new one.
+func RepairToolUse(messages []anthropic.MessageParam) []anthropic.MessageParam {
+	repaired := make([]anthropic.MessageParam, 0, len(messages))
+
+	for i := 0; i < len(messages); i++ {
+		msg := messages[i]
+		repaired = append(repaired, msg)
+		if msg.Role != anthropic.MessageParamRoleAssistant {
+			continue
+		}
+
+		var toolUseIDs []string
+		for _, block := range msg.Content {
+			if block.OfToolUse != nil {
+				toolUseIDs = append(toolUseIDs, block.OfToolUse.ID)
+			}
+		}
+		if len(toolUseIDs) == 0 {
+			continue
+		}
+
+		var next *anthropic.MessageParam
+		if i+1 < len(messages) && messages[i+1].Role == anthropic.MessageParamRoleUser {
+			next = &messages[i+1]
+		}
+
+		answered := make(map[string]bool)
+		if next != nil {
+			for _, block := range next.Content {
+				if block.OfToolResult != nil {
+					answered[block.OfToolResult.ToolUseID] = true
+				}
+			}
+		}
+
+		var missing []anthropic.ContentBlockParamUnion
+		for _, id := range toolUseIDs {
+			if !answered[id] {
+				missing = append(missing, anthropic.NewToolResultBlock(id, interruptedToolResult, true))
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		if next == nil {
+			repaired = append(repaired, anthropic.NewUserMessage(missing...))
+			continue
+		}
+
+		// Tool results must come before any other content in the user message
+		content := make([]anthropic.ContentBlockParamUnion, 0, len(missing)+len(next.Content))
+		content = append(content, missing...)
+		content = append(content, next.Content...)
+		repaired = append(repaired, anthropic.NewUserMessage(content...))
+		i++
+	}
+
+	return repaired
+}
+
+// SetToolResults replaces the results of the given tool calls, keyed by
+// tool_use ID. Resuming a run uses it to answer a call that was still waiting
+// (e.g. for human input) when the run stopped.
+func SetToolResults(messages []anthropic.MessageParam, results map[string]string) []anthropic.MessageParam {
+	updated := make([]anthropic.MessageParam, len(messages))
+	for i, msg := range messages {
+		updated[i] = msg
+		if msg.Role != anthropic.MessageParamRoleUser {
+			continue
+		}
+
+		var content []anthropic.ContentBlockParamUnion
+		for j, block := range msg.Content {
+			if block.OfToolResult == nil {
+				continue
+			}
+			result, ok := results[block.OfToolResult.ToolUseID]
+			if !ok {
+				continue
+			}
+			if content == nil {
+				content = append([]anthropic.ContentBlockParamUnion(nil), msg.Content...)
+			}
+			content[j] = anthropic.NewToolResultBlock(block.OfToolResult.ToolUseID, result, false)
+		}
+		if content != nil {
+			updated[i] = anthropic.NewUserMessage(content...)
+		}
+	}
+	return updated
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// memoryCheckpointer keeps checkpoints as JSON, as storage does
+type memoryCheckpointer struct {
+	checkpoints []json.RawMessage
+}
+
+func (c *memoryCheckpointer) SaveCheckpoint(runID string, iteration int, messages []anthropic.MessageParam, budget *BudgetTracker) error {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+	c.checkpoints = append(c.checkpoints, data)
+	return nil
+}
+
+func TestCheckpointKeepsToolHistory(t *testing.T) {
+	checkpointer := &memoryCheckpointer{}
+	var toolCalls int
+	a := newCassetteTestAgent(&scriptedProvider{responses: cassetteTestResponses}, &toolCalls)
+	a.SetCheckpointer(checkpointer)
+
+	if result := a.Run(context.Background(), "Which service?"); !result.Success {
+		t.Fatalf("run failed: %v", result.Error)
+	}
+
+	// Response with tool_use, tool results, final response
+	if len(checkpointer.checkpoints) != 3 {
+		t.Fatalf("saved %d checkpoints, want 3", len(checkpointer.checkpoints))
+	}
+
+	var messages []anthropic.MessageParam
+	if err := json.Unmarshal(checkpointer.checkpoints[2], &messages); err != nil {
+		t.Fatalf("failed to decode checkpoint: %v", err)
+	}
+	if len(messages) != 4 {
+		t.Fatalf("checkpoint has %d messages, want 4", len(messages))
+	}
+	if messages[1].Content[0].OfToolUse == nil || messages[1].Content[0].OfToolUse.ID != "toolu_1" {
+		t.Errorf("message 1 = %+v, want tool_use toolu_1", messages[1].Content[0])
+	}
+	if messages[2].Content[0].OfToolResult == nil || messages[2].Content[0].OfToolResult.ToolUseID != "toolu_1" {
+		t.Errorf("message 2 = %+v, want tool_result for toolu_1", messages[2].Content[0])
+	}
+	if messages[3].Role != anthropic.MessageParamRoleAssistant {
+		t.Errorf("last message role = %s, want assistant", messages[3].Role)
+	}
+}
+
+func TestRepairToolUse(t *testing.T) {
+	messages := []anthropic.MessageParam{
+		anthropic.NewUserMessage(anthropic.NewTextBlock("Check both services")),
+		anthropic.NewAssistantMessage(
+			anthropic.NewToolUseBlock("toolu_1", map[string]string{"key": "a"}, "lookup"),
+			anthropic.NewToolUseBlock("toolu_2", map[string]string{"key": "b"}, "lookup"),
+		),
+		anthropic.NewUserMessage(
+			anthropic.NewToolResultBlock("toolu_1", "ok", false),
+			anthropic.NewTextBlock("Also check c"),
+		),
+		anthropic.NewAssistantMessage(
+			anthropic.NewToolUseBlock("toolu_3", map[string]string{"key": "c"}, "lookup"),
+		),
+	}
+
+	repaired := RepairToolUse(messages)
+	if len(repaired) != 5 {
+		t.Fatalf("repaired history has %d messages, want 5", len(repaired))
+	}
+
+	partial := repaired[2].Content
+	if len(partial) != 3 || partial[0].OfToolResult == nil || partial[0].OfToolResult.ToolUseID != "toolu_2" || !partial[0].OfToolResult.IsError.Value {
+		t.Errorf("partial results = %+v, want error result for toolu_2 first", partial)
+	}
+
+	last := repaired[4]
+	if last.Role != anthropic.MessageParamRoleUser || len(last.Content) != 1 || last.Content[0].OfToolResult == nil || last.Content[0].OfToolResult.ToolUseID != "toolu_3" {
+		t.Errorf("last message = %+v, want tool_result for toolu_3", last)
+	}
+
+	if complete := RepairToolUse(repaired); len(complete) != len(repaired) {
+		t.Errorf("repairing a consistent history changed it to %d messages", len(complete))
+	}
+}
@@ -15,7 +15,7 @@ type CodingAgent struct {
 }
 
 // NewCodingAgent creates a new coding agent with file system tools
-func NewCodingAgent(client *anthropic.Client, logLevel config.LogLevel) (*CodingAgent, error) {
+func NewCodingAgent(provider Provider, logLevel config.LogLevel) (*CodingAgent, error) {
 	systemPrompt := `You are an expert coding assistant with access to file system tools.
 
 Your capabilities:
@@ -43,7 +43,7 @@ Always provide clear explanations of what you're doing and why.`
 	agent := NewAgent(Config{
 		Name:         "CodingAgent",
 		Description:  "An AI agent specialized for coding tasks with file system access",
-		Client:       client,
+		Provider:     provider,
 		Model:        anthropic.ModelClaudeSonnet4_5_20250929,
 		MaxTokens:    4096,
 		SystemPrompt: systemPrompt,
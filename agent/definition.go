@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+	"gopkg.in/yaml.v2"
+)
+
+// AgentDefinition is an agent declared in a file, so its prompt and tools can
+// be versioned next to the infrastructure it manages.
+//
+// YAML files (.yaml, .yml) hold all fields. Markdown files (.md) hold the
+// fields as YAML front matter between "---" lines, and the body is the
+// system prompt:
+//
+//	---
+//	id: postgres-tuner
+//	name: Postgres Tuner
+//	tools: [read_file, otel_update_config]
+//	handoff_targets: [pipeline]
+//	---
+//	You tune the OpenTelemetry postgres receiver...
+type AgentDefinition struct {
+	ID             string             `yaml:"id"`
+	Name           string             `yaml:"name"`
+	Description    string             `yaml:"description"`
+	Model          string             `yaml:"model"`
+	SystemPrompt   string             `yaml:"system_prompt"`
+	Tools          []string           `yaml:"tools"`
+	HandoffTargets []string           `yaml:"handoff_targets"` // Agents it may hand off to (empty allows any)
+	MaxTokens      int64              `yaml:"max_tokens"`
+	ThinkingBudget int64              `yaml:"thinking_budget"`
+	Budget         *storage.RunBudget `yaml:"budget"`
+
+	// Path is the file the definition was loaded from
+	Path string `yaml:"-"`
+}
+
+// ParseAgentDefinition parses a YAML or Markdown agent definition. The file
+// name selects the format.
+func ParseAgentDefinition(path string, data []byte) (*AgentDefinition, error) {
+	var def AgentDefinition
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.UnmarshalStrict(data, &def); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	case ".md":
+		frontMatter, body, err := splitFrontMatter(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if err := yaml.UnmarshalStrict(frontMatter, &def); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if prompt := strings.TrimSpace(string(body)); prompt != "" {
+			def.SystemPrompt = prompt
+		}
+	default:
+		return nil, fmt.Errorf("unsupported agent definition file: %s", path)
+	}
+
+	def.Path = path
+	if def.Name == "" {
+		def.Name = def.ID
+	}
+
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid agent definition %s: %w", path, err)
+	}
+	return &def, nil
+}
+
+// Validate checks the fields that cannot be defaulted
+func (d *AgentDefinition) Validate() error {
+	if d.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if strings.ContainsAny(d.ID, " /\\") {
+		return fmt.Errorf("id %q must not contain spaces or slashes", d.ID)
+	}
+	if d.SystemPrompt == "" {
+		return fmt.Errorf("system_prompt is required")
+	}
+	if d.ThinkingBudget != 0 && d.ThinkingBudget < 1024 {
+		return fmt.Errorf("thinking_budget must be 0 or at least 1024 tokens")
+	}
+	if _, err := BudgetFromRunBudget(d.Budget); err != nil {
+		return fmt.Errorf("invalid budget: %w", err)
+	}
+	return nil
+}
+
+// splitFrontMatter splits Markdown into its YAML front matter and body
+func splitFrontMatter(data []byte) ([]byte, []byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return nil, nil, fmt.Errorf("missing front matter: file must start with ---")
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			return []byte(strings.Join(lines[1:i], "")), []byte(strings.Join(lines[i+1:], "")), nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unterminated front matter: missing closing ---")
+}
+
+// isAgentDefinitionFile reports whether a file name has a definition extension
+func isAgentDefinitionFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".md":
+		return true
+	}
+	return false
+}
+
+// LoadAgentDefinitions loads every definition in dir, sorted by file name.
+// Files that fail to parse, or reuse an ID from an earlier file, are skipped
+// and reported in the returned error; the other definitions are still returned.
+// The definitions are nil only if dir itself can't be read.
+func LoadAgentDefinitions(dir string) ([]*AgentDefinition, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent definitions: %w", err)
+	}
+
+	defs := []*AgentDefinition{}
+	var errs []error
+	seen := make(map[string]string)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !isAgentDefinitionFile(entry.Name()) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", path, err))
+			continue
+		}
+
+		def, err := ParseAgentDefinition(path, data)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if first, ok := seen[def.ID]; ok {
+			errs = append(errs, fmt.Errorf("agent %s in %s is already defined in %s", def.ID, path, first))
+			continue
+		}
+		seen[def.ID] = path
+		defs = append(defs, def)
+	}
+
+	return defs, errors.Join(errs...)
+}
+
+// AgentDefinitionsFingerprint summarizes the names, sizes and modification
+// times of the definition files in dir, so callers can poll for changes
+func AgentDefinitionsFingerprint(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var parts []string
+	for _, entry := range entries {
+		if entry.IsDir() || !isAgentDefinitionFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d:%s", entry.Name(), info.Size(), info.ModTime().Format(time.RFC3339Nano)))
+	}
+	sort.Strings(parts)
+
+	return strings.Join(parts, "\n"), nil
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAgentDefinition(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		data    string
+		want    AgentDefinition
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			path: "tuner.yaml",
+			data: `id: tuner
+name: Tuner
+system_prompt: You tune collectors.
+tools: [read_file]
+handoff_targets: [pipeline]
+budget:
+  max_iterations: 5
+  max_duration: 10m
+`,
+			want: AgentDefinition{ID: "tuner", Name: "Tuner", SystemPrompt: "You tune collectors.", Tools: []string{"read_file"}, HandoffTargets: []string{"pipeline"}},
+		},
+		{
+			name: "markdown body is the system prompt",
+			path: "tuner.md",
+			data: "---\nid: tuner\ntools: [read_file]\n---\n\nYou tune collectors.\n",
+			want: AgentDefinition{ID: "tuner", Name: "tuner", SystemPrompt: "You tune collectors.", Tools: []string{"read_file"}},
+		},
+		{
+			name:    "unknown field",
+			path:    "tuner.yaml",
+			data:    "id: tuner\nsystem_prompt: x\nsytem_prompt: y\n",
+			wantErr: "sytem_prompt",
+		},
+		{
+			name:    "missing id",
+			path:    "tuner.yaml",
+			data:    "system_prompt: x\n",
+			wantErr: "id is required",
+		},
+		{
+			name:    "markdown without front matter",
+			path:    "tuner.md",
+			data:    "You tune collectors.\n",
+			wantErr: "missing front matter",
+		},
+		{
+			name:    "invalid budget",
+			path:    "tuner.yaml",
+			data:    "id: tuner\nsystem_prompt: x\nbudget:\n  max_duration: soon\n",
+			wantErr: "invalid budget",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, err := ParseAgentDefinition(tt.path, []byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseAgentDefinition() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAgentDefinition() error = %v", err)
+			}
+			if def.ID != tt.want.ID || def.Name != tt.want.Name || def.SystemPrompt != tt.want.SystemPrompt ||
+				strings.Join(def.Tools, ",") != strings.Join(tt.want.Tools, ",") ||
+				strings.Join(def.HandoffTargets, ",") != strings.Join(tt.want.HandoffTargets, ",") {
+				t.Errorf("ParseAgentDefinition() = %+v, want %+v", def, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAgentDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml":     "id: tuner\nsystem_prompt: first\n",
+		"b.md":       "---\nid: tuner\n---\nsecond\n",
+		"c.yml":      "id: reviewer\nsystem_prompt: review\n",
+		"notes.txt":  "not an agent",
+		"broken.yml": "id: [\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defs, err := LoadAgentDefinitions(dir)
+	if err == nil || !strings.Contains(err.Error(), "already defined") || !strings.Contains(err.Error(), "broken.yml") {
+		t.Errorf("LoadAgentDefinitions() error = %v, want duplicate and parse errors", err)
+	}
+	if len(defs) != 2 || defs[0].SystemPrompt != "first" || defs[1].ID != "reviewer" {
+		t.Fatalf("LoadAgentDefinitions() = %+v, want tuner from a.yaml and reviewer", defs)
+	}
+
+	before, _ := AgentDefinitionsFingerprint(dir)
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(filepath.Join(dir, "c.yml"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := AgentDefinitionsFingerprint(dir); after == before {
+		t.Error("AgentDefinitionsFingerprint() did not change after a file was modified")
+	}
+}
@@ -23,8 +23,19 @@ const (
 	EventAPIResponse         EventType = "api_response"
 	EventAgentHandoff        EventType = "agent_handoff"
 	EventAgentHandoffComplete EventType = "agent_handoff_complete"
+	EventTextDelta           EventType = "text_delta"
+	EventToolInputDelta      EventType = "tool_input_delta"
+	EventCompaction          EventType = "compaction"
+	EventAPIRetry            EventType = "api_retry"
 )
 
+// IsStreamingEvent reports whether events of this type are incremental
+// streaming updates. These are forwarded to live clients but not persisted,
+// since the complete message event carries the same content.
+func IsStreamingEvent(t EventType) bool {
+	return t == EventTextDelta || t == EventToolInputDelta
+}
+
 // AgentEvent represents a single event in the agent's execution
 type AgentEvent struct {
 	ID        string          `json:"id"`
@@ -42,15 +53,32 @@ type RunStartData struct {
 	Model        string `json:"model"`
 	MaxTokens    int64  `json:"max_tokens"`
 	SystemPrompt string `json:"system_prompt,omitempty"`
+	ParentRunID  string `json:"parent_run_id,omitempty"` // Set for handoff sub-runs
 }
 
+// RunEndReason describes why a run ended
+type RunEndReason string
+
+const (
+	RunEndReasonCompleted      RunEndReason = "completed"
+	RunEndReasonCancelled      RunEndReason = "cancelled"
+	RunEndReasonTimeout        RunEndReason = "timeout"
+	RunEndReasonError          RunEndReason = "error"
+	RunEndReasonMaxTokens      RunEndReason = "max_tokens"
+	RunEndReasonMaxIterations  RunEndReason = "max_iterations"
+	RunEndReasonBudgetExceeded RunEndReason = "budget_exceeded"
+	RunEndReasonRefusal        RunEndReason = "refusal"
+)
+
 // RunEndData contains data for run end events
 type RunEndData struct {
-	Success         bool   `json:"success"`
-	Error           string `json:"error,omitempty"`
-	TotalToolCalls  int    `json:"total_tool_calls"`
-	TotalIterations int    `json:"total_iterations"`
-	Duration        string `json:"duration"`
+	Success         bool         `json:"success"`
+	Reason          RunEndReason `json:"reason,omitempty"`
+	BudgetLimit     string       `json:"budget_limit,omitempty"` // Set when Reason is budget_exceeded
+	Error           string       `json:"error,omitempty"`
+	TotalToolCalls  int          `json:"total_tool_calls"`
+	TotalIterations int          `json:"total_iterations"`
+	Duration        string       `json:"duration"`
 }
 
 // IterationData contains data for iteration events
@@ -70,10 +98,11 @@ type MessageData struct {
 
 // ContentBlock represents a content block in a message
 type ContentBlock struct {
-	Type       string          `json:"type"` // text, tool_use, tool_result
+	Type       string          `json:"type"` // text, tool_use, tool_result, thinking, redacted_thinking
 	Text       string          `json:"text,omitempty"`
 	ToolUse    *ToolUseInfo    `json:"tool_use,omitempty"`
 	ToolResult *ToolResultInfo `json:"tool_result,omitempty"`
+	Thinking   *ThinkingInfo   `json:"thinking,omitempty"`
 }
 
 // ToolUseInfo contains information about a tool use
@@ -83,6 +112,14 @@ type ToolUseInfo struct {
 	Input json.RawMessage `json:"input"`
 }
 
+// ThinkingInfo contains an extended thinking block. Redacted blocks carry only
+// encrypted Data. The signature is kept so the block can be sent back to the API.
+type ThinkingInfo struct {
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+}
+
 // ToolResultInfo contains information about a tool result
 type ToolResultInfo struct {
 	ToolUseID string `json:"tool_use_id"`
@@ -92,8 +129,20 @@ type ToolResultInfo struct {
 
 // UsageInfo contains token usage information
 type UsageInfo struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// UsageInfoFromAnthropic converts Anthropic usage to UsageInfo
+func UsageInfoFromAnthropic(usage anthropic.Usage) *UsageInfo {
+	return &UsageInfo{
+		InputTokens:              int(usage.InputTokens),
+		OutputTokens:             int(usage.OutputTokens),
+		CacheCreationInputTokens: int(usage.CacheCreationInputTokens),
+		CacheReadInputTokens:     int(usage.CacheReadInputTokens),
+	}
 }
 
 // ToolCallData contains data for tool call events
@@ -129,9 +178,10 @@ type ErrorData struct {
 
 // APIRequestData contains data for API request events
 type APIRequestData struct {
-	Model     string `json:"model"`
-	MaxTokens int64  `json:"max_tokens"`
-	ToolCount int    `json:"tool_count"`
+	Model          string `json:"model"`
+	MaxTokens      int64  `json:"max_tokens"`
+	ToolCount      int    `json:"tool_count"`
+	ThinkingBudget int64  `json:"thinking_budget,omitempty"`
 }
 
 // APIResponseData contains data for API response events
@@ -161,6 +211,70 @@ type HandoffCompleteData struct {
 	Summary     string `json:"summary"`
 	Error       string `json:"error,omitempty"`
 	Duration    string `json:"duration"`
+
+	// Report is the structured result the sub-agent submitted, if any
+	Report *HandoffReport `json:"report,omitempty"`
+}
+
+// HandoffReport is the structured result a sub-agent finishes a handoff with
+type HandoffReport struct {
+	Status     string             `json:"status"` // "completed", "partial" or "failed"
+	Summary    string             `json:"summary"`
+	Artifacts  []HandoffArtifact  `json:"artifacts,omitempty"`
+	Components []HandoffComponent `json:"components,omitempty"`
+	FollowUps  []string           `json:"follow_ups,omitempty"`
+}
+
+// HandoffArtifact is something a sub-agent created or changed
+type HandoffArtifact struct {
+	Type        string `json:"type"` // "collector", "dashboard", "datasource", "file" or "other"
+	ID          string `json:"id"`   // Collector ID, dashboard UID, file path, ...
+	Description string `json:"description,omitempty"`
+}
+
+// HandoffComponent is the outcome for one plan component handled by a sub-agent
+type HandoffComponent struct {
+	Name    string `json:"name"`
+	Type    string `json:"type,omitempty"` // "service", "infrastructure", "pipeline" or "backend"
+	Status  string `json:"status"`         // "success", "failed" or "skipped"
+	Details string `json:"details,omitempty"`
+}
+
+// TextDeltaData contains data for streamed text delta events
+type TextDeltaData struct {
+	Index int    `json:"index"` // Content block index within the message
+	Text  string `json:"text"`
+}
+
+// ToolInputDeltaData contains data for streamed partial tool input events
+type ToolInputDeltaData struct {
+	Index       int    `json:"index"` // Content block index within the message
+	ToolUseID   string `json:"tool_use_id"`
+	ToolName    string `json:"tool_name"`
+	PartialJSON string `json:"partial_json"`
+}
+
+// APIRetryData contains data for API retry and model fallback events
+type APIRetryData struct {
+	Attempt       int    `json:"attempt"`
+	MaxRetries    int    `json:"max_retries"`
+	Model         string `json:"model"`
+	FallbackModel string `json:"fallback_model,omitempty"` // Set when switching to the next model
+	ErrorKind     string `json:"error_kind"`
+	StatusCode    int    `json:"status_code,omitempty"`
+	Error         string `json:"error"`
+	Delay         string `json:"delay,omitempty"`
+}
+
+// CompactionData contains data for history compaction events
+type CompactionData struct {
+	Strategy              string `json:"strategy"`
+	MessagesBefore        int    `json:"messages_before"`
+	MessagesAfter         int    `json:"messages_after"`
+	EstimatedTokensBefore int    `json:"estimated_tokens_before"`
+	EstimatedTokensAfter  int    `json:"estimated_tokens_after"`
+	TruncatedResults      int    `json:"truncated_results,omitempty"`
+	SummarizedMessages    int    `json:"summarized_messages,omitempty"`
 }
 
 // Helper functions to create events with typed data
@@ -347,6 +461,21 @@ func MessageDataFromAnthropic(msg *anthropic.Message) MessageData {
 					Input: v.Input,
 				},
 			})
+		case anthropic.ThinkingBlock:
+			content = append(content, ContentBlock{
+				Type: "thinking",
+				Thinking: &ThinkingInfo{
+					Thinking:  v.Thinking,
+					Signature: v.Signature,
+				},
+			})
+		case anthropic.RedactedThinkingBlock:
+			content = append(content, ContentBlock{
+				Type: "redacted_thinking",
+				Thinking: &ThinkingInfo{
+					Data: v.Data,
+				},
+			})
 		}
 	}
 
@@ -355,10 +484,7 @@ func MessageDataFromAnthropic(msg *anthropic.Message) MessageData {
 		Content:    content,
 		StopReason: string(msg.StopReason),
 		Model:      string(msg.Model),
-		Usage: &UsageInfo{
-			InputTokens:  int(msg.Usage.InputTokens),
-			OutputTokens: int(msg.Usage.OutputTokens),
-		},
+		Usage:      UsageInfoFromAnthropic(msg.Usage),
 	}
 }
 
@@ -402,3 +528,75 @@ func NewAgentHandoffCompleteEvent(runID, agentID, agentName string, data Handoff
 		Data:      dataJSON,
 	}, nil
 }
+
+// NewTextDeltaEvent creates a streamed text delta event
+func NewTextDeltaEvent(runID, agentID, agentName string, data TextDeltaData) (*AgentEvent, error) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AgentEvent{
+		ID:        generateEventID(),
+		Timestamp: time.Now(),
+		AgentID:   agentID,
+		AgentName: agentName,
+		RunID:     runID,
+		Type:      EventTextDelta,
+		Data:      dataJSON,
+	}, nil
+}
+
+// NewToolInputDeltaEvent creates a streamed partial tool input event
+func NewToolInputDeltaEvent(runID, agentID, agentName string, data ToolInputDeltaData) (*AgentEvent, error) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AgentEvent{
+		ID:        generateEventID(),
+		Timestamp: time.Now(),
+		AgentID:   agentID,
+		AgentName: agentName,
+		RunID:     runID,
+		Type:      EventToolInputDelta,
+		Data:      dataJSON,
+	}, nil
+}
+
+// NewCompactionEvent creates a history compaction event
+func NewCompactionEvent(runID, agentID, agentName string, data CompactionData) (*AgentEvent, error) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AgentEvent{
+		ID:        generateEventID(),
+		Timestamp: time.Now(),
+		AgentID:   agentID,
+		AgentName: agentName,
+		RunID:     runID,
+		Type:      EventCompaction,
+		Data:      dataJSON,
+	}, nil
+}
+
+// NewAPIRetryEvent creates an API retry event
+func NewAPIRetryEvent(runID, agentID, agentName string, data APIRetryData) (*AgentEvent, error) {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AgentEvent{
+		ID:        generateEventID(),
+		Timestamp: time.Now(),
+		AgentID:   agentID,
+		AgentName: agentName,
+		RunID:     runID,
+		Type:      EventAPIRetry,
+		Data:      dataJSON,
+	}, nil
+}
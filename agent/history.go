@@ -0,0 +1,393 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// HistoryStrategy manages the conversation history between iterations so long
+// runs stay within the model's context window. The system prompt is sent
+// separately on every request and is never part of the history.
+type HistoryStrategy interface {
+	// Compact returns a compacted copy of messages, or nil if no compaction was needed.
+	// The input slice must not be modified.
+	Compact(ctx context.Context, messages []anthropic.MessageParam) (*Compaction, error)
+}
+
+// Compaction describes the result of a history strategy pass
+type Compaction struct {
+	Messages           []anthropic.MessageParam
+	Strategy           string
+	TruncatedResults   int // Tool results that were truncated
+	SummarizedMessages int // Messages replaced by a summary
+}
+
+const (
+	// truncationMarker prefixes the note appended to truncated tool results.
+	// It is also used to skip results that were already truncated.
+	truncationMarker = "[truncated by history compaction"
+
+	// summaryMarker prefixes the summary block injected into the first user message
+	summaryMarker = "[Summary of earlier conversation]"
+
+	// transcriptResultLimit caps each tool result when rendering a transcript for summarization
+	transcriptResultLimit = 2000
+)
+
+// NewDefaultHistoryStrategy truncates large tool results outside the latest
+// turns and summarizes older turns with a small model once the history grows
+// past roughly 100k tokens.
+func NewDefaultHistoryStrategy(provider Provider) HistoryStrategy {
+	return CompositeStrategy{
+		&ToolResultTruncation{
+			MaxResultChars:  4000,
+			KeepRecentTurns: 4,
+		},
+		&Summarization{
+			Provider:        provider,
+			Model:           anthropic.ModelClaudeHaiku4_5,
+			KeepRecentTurns: 6,
+			TriggerTokens:   100000,
+		},
+	}
+}
+
+// CompositeStrategy applies several strategies in order, each one seeing the
+// output of the previous one
+type CompositeStrategy []HistoryStrategy
+
+// Compact runs every strategy in order and merges their results
+func (c CompositeStrategy) Compact(ctx context.Context, messages []anthropic.MessageParam) (*Compaction, error) {
+	var merged *Compaction
+	var names []string
+
+	for _, strategy := range c {
+		compaction, err := strategy.Compact(ctx, messages)
+		if err != nil {
+			return merged, err
+		}
+		if compaction == nil {
+			continue
+		}
+
+		if merged == nil {
+			merged = &Compaction{}
+		}
+		merged.Messages = compaction.Messages
+		merged.TruncatedResults += compaction.TruncatedResults
+		merged.SummarizedMessages += compaction.SummarizedMessages
+		names = append(names, compaction.Strategy)
+		messages = compaction.Messages
+	}
+
+	if merged != nil {
+		merged.Strategy = strings.Join(names, "+")
+	}
+	return merged, nil
+}
+
+// ToolResultTruncation shortens large tool results that are older than the
+// latest KeepRecentTurns assistant turns
+type ToolResultTruncation struct {
+	MaxResultChars  int // Results longer than this are truncated (default 4000)
+	KeepRecentTurns int // Latest assistant turns left untouched (default 4)
+}
+
+// Compact truncates old tool results that exceed MaxResultChars
+func (t *ToolResultTruncation) Compact(ctx context.Context, messages []anthropic.MessageParam) (*Compaction, error) {
+	maxChars := t.MaxResultChars
+	if maxChars <= 0 {
+		maxChars = 4000
+	}
+	keep := t.KeepRecentTurns
+	if keep <= 0 {
+		keep = 4
+	}
+
+	boundary := recentTurnsBoundary(messages, keep)
+	if boundary == 0 {
+		return nil, nil
+	}
+
+	var compacted []anthropic.MessageParam
+	truncated := 0
+
+	for i := 0; i < boundary; i++ {
+		msg := messages[i]
+		var newContent []anthropic.ContentBlockParamUnion
+
+		for j, block := range msg.Content {
+			if block.OfToolResult == nil {
+				continue
+			}
+			newResult, ok := truncateToolResult(*block.OfToolResult, maxChars)
+			if !ok {
+				continue
+			}
+
+			if newContent == nil {
+				newContent = make([]anthropic.ContentBlockParamUnion, len(msg.Content))
+				copy(newContent, msg.Content)
+			}
+			newContent[j] = anthropic.ContentBlockParamUnion{OfToolResult: &newResult}
+			truncated++
+		}
+
+		if newContent == nil {
+			continue
+		}
+		if compacted == nil {
+			compacted = make([]anthropic.MessageParam, len(messages))
+			copy(compacted, messages)
+		}
+		compacted[i] = anthropic.MessageParam{Role: msg.Role, Content: newContent}
+	}
+
+	if truncated == 0 {
+		return nil, nil
+	}
+
+	return &Compaction{
+		Messages:         compacted,
+		Strategy:         "truncate_tool_results",
+		TruncatedResults: truncated,
+	}, nil
+}
+
+// truncateToolResult returns a copy of result with its text content cut to
+// maxChars, and whether anything was truncated
+func truncateToolResult(result anthropic.ToolResultBlockParam, maxChars int) (anthropic.ToolResultBlockParam, bool) {
+	var content []anthropic.ToolResultBlockParamContentUnion
+	changed := false
+
+	for i, part := range result.Content {
+		if part.OfText == nil {
+			continue
+		}
+		text := part.OfText.Text
+		if len(text) <= maxChars || strings.Contains(text, truncationMarker) {
+			continue
+		}
+
+		if content == nil {
+			content = make([]anthropic.ToolResultBlockParamContentUnion, len(result.Content))
+			copy(content, result.Content)
+		}
+		head := truncateUTF8(text, maxChars)
+		content[i] = anthropic.ToolResultBlockParamContentUnion{
+			OfText: &anthropic.TextBlockParam{
+				Text: fmt.Sprintf("%s\n... %s: %d characters omitted]", head, truncationMarker, len(text)-len(head)),
+			},
+		}
+		changed = true
+	}
+
+	if !changed {
+		return result, false
+	}
+	result.Content = content
+	return result, true
+}
+
+// Summarization replaces turns older than the latest KeepRecentTurns with a
+// summary written by a (typically cheaper) model. The first user message is
+// kept verbatim so the original task is never lost.
+type Summarization struct {
+	Provider         Provider
+	Model            anthropic.Model // Model used to write the summary (default Claude Haiku 4.5)
+	KeepRecentTurns  int             // Latest assistant turns kept verbatim (default 6)
+	TriggerTokens    int             // Estimated history size that triggers summarization (default 100000)
+	MaxSummaryTokens int64           // Max tokens for the summary response (default 2048)
+}
+
+// Compact summarizes older turns once the history exceeds TriggerTokens
+func (s *Summarization) Compact(ctx context.Context, messages []anthropic.MessageParam) (*Compaction, error) {
+	if s.Provider == nil {
+		return nil, fmt.Errorf("summarization strategy requires a provider")
+	}
+
+	trigger := s.TriggerTokens
+	if trigger <= 0 {
+		trigger = 100000
+	}
+	if EstimateTokens(messages) < trigger {
+		return nil, nil
+	}
+
+	keep := s.KeepRecentTurns
+	if keep <= 0 {
+		keep = 6
+	}
+
+	// The boundary is an assistant message, so the kept tail always starts with
+	// a complete tool_use/tool_result exchange. Index 0 is the original prompt.
+	boundary := recentTurnsBoundary(messages, keep)
+	if boundary <= 1 {
+		return nil, nil
+	}
+
+	prompt, previousSummary := splitSummary(messages[0])
+
+	summary, err := s.summarize(ctx, previousSummary, messages[1:boundary])
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize history: %w", err)
+	}
+
+	firstContent := make([]anthropic.ContentBlockParamUnion, 0, len(prompt)+1)
+	firstContent = append(firstContent, prompt...)
+	firstContent = append(firstContent, anthropic.NewTextBlock(summaryMarker+"\n"+summary))
+
+	compacted := make([]anthropic.MessageParam, 0, len(messages)-boundary+1)
+	compacted = append(compacted, anthropic.NewUserMessage(firstContent...))
+	compacted = append(compacted, messages[boundary:]...)
+
+	return &Compaction{
+		Messages:           compacted,
+		Strategy:           "summarize",
+		SummarizedMessages: boundary - 1,
+	}, nil
+}
+
+// summarize asks the summary model to condense the given messages
+func (s *Summarization) summarize(ctx context.Context, previousSummary string, messages []anthropic.MessageParam) (string, error) {
+	model := s.Model
+	if model == "" {
+		model = anthropic.ModelClaudeHaiku4_5
+	}
+	maxTokens := s.MaxSummaryTokens
+	if maxTokens == 0 {
+		maxTokens = 2048
+	}
+
+	var input strings.Builder
+	if previousSummary != "" {
+		input.WriteString("Summary of the conversation before this excerpt:\n")
+		input.WriteString(previousSummary)
+		input.WriteString("\n\n")
+	}
+	input.WriteString("Conversation excerpt:\n")
+	input.WriteString(renderTranscript(messages))
+
+	response, err := s.Provider.CreateMessage(ctx, anthropic.MessageNewParams{
+		Model:     model,
+		MaxTokens: maxTokens,
+		System: []anthropic.TextBlockParam{
+			{Text: `You compress the working history of an AI agent so it can continue its task with less context.
+Summarize the conversation excerpt into concise notes covering: what has been done, which tools were called and their key results (paths, IDs, URLs, config values, errors), decisions made, and what remains to do.
+Preserve exact identifiers. Omit pleasantries and raw output that is no longer needed. Respond with the notes only.`},
+		},
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(input.String())),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var summary strings.Builder
+	for _, block := range response.Content {
+		if text, ok := block.AsAny().(anthropic.TextBlock); ok {
+			summary.WriteString(text.Text)
+		}
+	}
+	if summary.Len() == 0 {
+		return "", fmt.Errorf("summary model returned no text")
+	}
+
+	return summary.String(), nil
+}
+
+// splitSummary separates a previous compaction summary from the rest of the
+// first user message
+func splitSummary(msg anthropic.MessageParam) ([]anthropic.ContentBlockParamUnion, string) {
+	content := make([]anthropic.ContentBlockParamUnion, 0, len(msg.Content))
+	summary := ""
+
+	for _, block := range msg.Content {
+		if block.OfText != nil && strings.HasPrefix(block.OfText.Text, summaryMarker) {
+			summary = strings.TrimSpace(strings.TrimPrefix(block.OfText.Text, summaryMarker))
+			continue
+		}
+		content = append(content, block)
+	}
+
+	return content, summary
+}
+
+// recentTurnsBoundary returns the index of the assistant message that starts
+// the latest n turns, or 0 if the history has n turns or fewer
+func recentTurnsBoundary(messages []anthropic.MessageParam, n int) int {
+	turns := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != anthropic.MessageParamRoleAssistant {
+			continue
+		}
+		turns++
+		if turns == n {
+			return i
+		}
+	}
+	return 0
+}
+
+// renderTranscript renders messages as plain text for the summary model
+func renderTranscript(messages []anthropic.MessageParam) string {
+	var b strings.Builder
+
+	for _, msg := range messages {
+		fmt.Fprintf(&b, "[%s]\n", msg.Role)
+		for _, block := range msg.Content {
+			switch {
+			case block.OfText != nil:
+				b.WriteString(block.OfText.Text)
+				b.WriteString("\n")
+			case block.OfToolUse != nil:
+				input, _ := json.Marshal(block.OfToolUse.Input)
+				fmt.Fprintf(&b, "tool_use %s: %s\n", block.OfToolUse.Name, input)
+			case block.OfToolResult != nil:
+				status := "tool_result"
+				if block.OfToolResult.IsError.Value {
+					status = "tool_result (error)"
+				}
+				for _, part := range block.OfToolResult.Content {
+					if part.OfText != nil {
+						fmt.Fprintf(&b, "%s: %s\n", status, truncateUTF8(part.OfText.Text, transcriptResultLimit))
+					}
+				}
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// EstimateTokens roughly estimates the token count of messages from their
+// serialized size (about four bytes per token)
+func EstimateTokens(messages []anthropic.MessageParam) int {
+	size := 0
+	for _, msg := range messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+		size += len(data)
+	}
+	return size / 4
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,266 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// summaryProvider records the summary request and answers with a fixed summary
+type summaryProvider struct {
+	summary string
+	params  []anthropic.MessageNewParams
+}
+
+func (p *summaryProvider) Name() string { return "summary" }
+
+func (p *summaryProvider) CreateMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	p.params = append(p.params, params)
+
+	response, err := json.Marshal(map[string]interface{}{
+		"id":          "msg_summary",
+		"type":        "message",
+		"role":        "assistant",
+		"model":       string(params.Model),
+		"stop_reason": "end_turn",
+		"content":     []map[string]string{{"type": "text", "text": p.summary}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var message anthropic.Message
+	if err := json.Unmarshal(response, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// toolConversation builds a prompt followed by the given number of
+// tool_use/tool_result turns whose results are resultSize characters long
+func toolConversation(turns, resultSize int) []anthropic.MessageParam {
+	messages := []anthropic.MessageParam{
+		anthropic.NewUserMessage(anthropic.NewTextBlock("Investigate the checkout service")),
+	}
+	for i := 0; i < turns; i++ {
+		id := fmt.Sprintf("toolu_%d", i)
+		messages = append(messages,
+			anthropic.NewAssistantMessage(anthropic.NewToolUseBlock(id, map[string]interface{}{"n": i}, "lookup")),
+			anthropic.NewUserMessage(anthropic.NewToolResultBlock(id, strings.Repeat("x", resultSize), false)),
+		)
+	}
+	return messages
+}
+
+// resultText returns the text of the first tool result in msg
+func resultText(msg anthropic.MessageParam) string {
+	for _, block := range msg.Content {
+		if block.OfToolResult != nil && len(block.OfToolResult.Content) > 0 && block.OfToolResult.Content[0].OfText != nil {
+			return block.OfToolResult.Content[0].OfText.Text
+		}
+	}
+	return ""
+}
+
+// assertToolPairs fails if a tool_result has no matching tool_use in the preceding message
+func assertToolPairs(t *testing.T, messages []anthropic.MessageParam) {
+	t.Helper()
+	for i, msg := range messages {
+		for _, block := range msg.Content {
+			if block.OfToolResult == nil {
+				continue
+			}
+			found := false
+			if i > 0 {
+				for _, prev := range messages[i-1].Content {
+					if prev.OfToolUse != nil && prev.OfToolUse.ID == block.OfToolResult.ToolUseID {
+						found = true
+					}
+				}
+			}
+			if !found {
+				t.Errorf("message %d: tool_result %s has no matching tool_use", i, block.OfToolResult.ToolUseID)
+			}
+		}
+	}
+}
+
+func TestRecentTurnsBoundary(t *testing.T) {
+	messages := toolConversation(3, 1) // user, (assistant, user) x3
+
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{n: 1, want: 5},
+		{n: 2, want: 3},
+		{n: 3, want: 1},
+		{n: 4, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := recentTurnsBoundary(messages, tt.n); got != tt.want {
+			t.Errorf("recentTurnsBoundary(n=%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestToolResultTruncation(t *testing.T) {
+	messages := toolConversation(4, 100)
+	strategy := &ToolResultTruncation{MaxResultChars: 10, KeepRecentTurns: 2}
+
+	compaction, err := strategy.Compact(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if compaction == nil {
+		t.Fatal("Compact() = nil, want truncation")
+	}
+	if compaction.TruncatedResults != 2 {
+		t.Errorf("TruncatedResults = %d, want 2", compaction.TruncatedResults)
+	}
+	if len(compaction.Messages) != len(messages) {
+		t.Fatalf("compacted history has %d messages, want %d", len(compaction.Messages), len(messages))
+	}
+
+	for i, want := range map[int]bool{2: true, 4: true, 6: false, 8: false} {
+		text := resultText(compaction.Messages[i])
+		if truncated := strings.Contains(text, truncationMarker); truncated != want {
+			t.Errorf("message %d truncated = %v, want %v", i, truncated, want)
+		}
+	}
+	if !strings.HasPrefix(resultText(compaction.Messages[2]), strings.Repeat("x", 10)+"\n") {
+		t.Errorf("truncated result does not keep the first MaxResultChars characters: %q", resultText(compaction.Messages[2]))
+	}
+	if resultText(messages[2]) != strings.Repeat("x", 100) {
+		t.Error("Compact() modified the input history")
+	}
+	assertToolPairs(t, compaction.Messages)
+
+	// A second pass finds nothing new to truncate
+	again, err := strategy.Compact(context.Background(), compaction.Messages)
+	if err != nil {
+		t.Fatalf("second Compact() error = %v", err)
+	}
+	if again != nil {
+		t.Errorf("second Compact() truncated %d results, want none", again.TruncatedResults)
+	}
+}
+
+func TestToolResultTruncationShortHistory(t *testing.T) {
+	strategy := &ToolResultTruncation{MaxResultChars: 10, KeepRecentTurns: 4}
+
+	compaction, err := strategy.Compact(context.Background(), toolConversation(3, 100))
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if compaction != nil {
+		t.Errorf("Compact() truncated %d results within the recent turns", compaction.TruncatedResults)
+	}
+}
+
+func TestSummarization(t *testing.T) {
+	messages := toolConversation(4, 400)
+	provider := &summaryProvider{summary: "looked up 0 and 1"}
+	strategy := &Summarization{Provider: provider, KeepRecentTurns: 2, TriggerTokens: 10}
+
+	compaction, err := strategy.Compact(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if compaction == nil {
+		t.Fatal("Compact() = nil, want summary")
+	}
+	if compaction.SummarizedMessages != 4 {
+		t.Errorf("SummarizedMessages = %d, want 4", compaction.SummarizedMessages)
+	}
+
+	// The prompt plus summary, followed by the two kept turns starting at an assistant message
+	if len(compaction.Messages) != 5 {
+		t.Fatalf("compacted history has %d messages, want 5", len(compaction.Messages))
+	}
+	first := compaction.Messages[0]
+	if len(first.Content) != 2 || first.Content[0].OfText.Text != "Investigate the checkout service" {
+		t.Fatalf("first message does not keep the original prompt: %+v", first.Content)
+	}
+	if got := first.Content[1].OfText.Text; got != summaryMarker+"\nlooked up 0 and 1" {
+		t.Errorf("summary block = %q", got)
+	}
+	if compaction.Messages[1].Role != anthropic.MessageParamRoleAssistant {
+		t.Errorf("kept tail starts with %s, want assistant", compaction.Messages[1].Role)
+	}
+	assertToolPairs(t, compaction.Messages)
+
+	// A second pass carries the previous summary forward instead of nesting it
+	provider.summary = "looked up 0 to 3"
+	messages = append(compaction.Messages, toolConversation(2, 400)[1:]...)
+	compaction, err = strategy.Compact(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("second Compact() error = %v", err)
+	}
+	if len(compaction.Messages[0].Content) != 2 {
+		t.Fatalf("first message has %d blocks after second summary, want 2", len(compaction.Messages[0].Content))
+	}
+	request := provider.params[1].Messages[0].Content[0].OfText.Text
+	if !strings.Contains(request, "looked up 0 and 1") {
+		t.Errorf("second summary request does not include the previous summary: %q", request)
+	}
+	assertToolPairs(t, compaction.Messages)
+}
+
+func TestSummarizationBelowTrigger(t *testing.T) {
+	provider := &summaryProvider{summary: "unused"}
+	strategy := &Summarization{Provider: provider, KeepRecentTurns: 2, TriggerTokens: 1000000}
+
+	compaction, err := strategy.Compact(context.Background(), toolConversation(4, 400))
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if compaction != nil || len(provider.params) != 0 {
+		t.Error("Compact() summarized a history below the trigger")
+	}
+}
+
+func TestCompositeStrategy(t *testing.T) {
+	strategy := CompositeStrategy{
+		&ToolResultTruncation{MaxResultChars: 10, KeepRecentTurns: 1},
+		&Summarization{Provider: &summaryProvider{summary: "done"}, KeepRecentTurns: 2, TriggerTokens: 10},
+	}
+
+	compaction, err := strategy.Compact(context.Background(), toolConversation(4, 400))
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if compaction.Strategy != "truncate_tool_results+summarize" {
+		t.Errorf("Strategy = %q", compaction.Strategy)
+	}
+	if compaction.TruncatedResults != 3 || compaction.SummarizedMessages != 4 {
+		t.Errorf("TruncatedResults = %d, SummarizedMessages = %d, want 3 and 4", compaction.TruncatedResults, compaction.SummarizedMessages)
+	}
+	// The kept turn before the latest one was truncated by the first strategy
+	if !strings.Contains(resultText(compaction.Messages[2]), truncationMarker) {
+		t.Error("summarization did not see the truncated history")
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{in: "hello", n: 10, want: "hello"},
+		{in: "hello", n: 3, want: "hel"},
+		{in: "héllo", n: 2, want: "h"},
+		{in: "héllo", n: 3, want: "hé"},
+	}
+
+	for _, tt := range tests {
+		if got := truncateUTF8(tt.in, tt.n); got != tt.want {
+			t.Errorf("truncateUTF8(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
@@ -16,7 +16,7 @@ type InfrastructureAgent struct {
 }
 
 // NewInfrastructureAgent creates a new infrastructure agent with collector deployment tools
-func NewInfrastructureAgent(client *anthropic.Client, logLevel config.LogLevel) (*InfrastructureAgent, error) {
+func NewInfrastructureAgent(provider Provider, logLevel config.LogLevel) (*InfrastructureAgent, error) {
 	systemPrompt := `You are an expert infrastructure monitoring assistant specializing in OpenTelemetry collector configuration and deployment.
 
 Your capabilities:
@@ -79,7 +79,7 @@ Best Practices:
 	agent := NewAgent(Config{
 		Name:         "InfrastructureAgent",
 		Description:  "An AI agent specialized for infrastructure monitoring with OpenTelemetry collectors",
-		Client:       client,
+		Provider:     provider,
 		Model:        anthropic.ModelClaudeSonnet4_5_20250929,
 		MaxTokens:    4096,
 		SystemPrompt: systemPrompt,
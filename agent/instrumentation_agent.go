@@ -15,7 +15,7 @@ type InstrumentationAgent struct {
 }
 
 // NewInstrumentationAgent creates a new instrumentation agent with filesystem tools
-func NewInstrumentationAgent(client *anthropic.Client, logLevel config.LogLevel) (*InstrumentationAgent, error) {
+func NewInstrumentationAgent(provider Provider, logLevel config.LogLevel) (*InstrumentationAgent, error) {
 	systemPrompt := `You are an expert service instrumentation assistant specializing in OpenTelemetry SDK integration.
 
 Your capabilities:
@@ -70,7 +70,7 @@ Best Practices:
 	agent := NewAgent(Config{
 		Name:         "InstrumentationAgent",
 		Description:  "An AI agent specialized for instrumenting services with OpenTelemetry",
-		Client:       client,
+		Provider:     provider,
 		Model:        anthropic.ModelClaudeSonnet4_5_20250929,
 		MaxTokens:    4096,
 		SystemPrompt: systemPrompt,
@@ -84,8 +84,9 @@ func (l *Logger) LogAPIResponse(message *anthropic.Message) {
 	fmt.Printf("\n%s📥 API Response:%s\n", colorGreen, colorReset)
 	fmt.Printf("  Stop Reason: %s%s%s\n", colorBold, message.StopReason, colorReset)
 	fmt.Printf("  Model: %s\n", message.Model)
-	fmt.Printf("  Usage: Input=%d tokens, Output=%d tokens\n",
-		message.Usage.InputTokens, message.Usage.OutputTokens)
+	fmt.Printf("  Usage: Input=%d tokens, Output=%d tokens, Cache write=%d tokens, Cache read=%d tokens\n",
+		message.Usage.InputTokens, message.Usage.OutputTokens,
+		message.Usage.CacheCreationInputTokens, message.Usage.CacheReadInputTokens)
 
 	fmt.Printf("\n%s  Content Blocks:%s\n", colorCyan, colorReset)
 	for i, block := range message.Content {
@@ -106,6 +107,17 @@ func (l *Logger) LogAPIResponse(message *anthropic.Message) {
 			fmt.Printf("      ID: %s\n", v.ID)
 			fmt.Printf("      Name: %s%s%s\n", colorBold, v.Name, colorReset)
 			fmt.Printf("      Input: %s\n", formatJSON(v.Input))
+		case anthropic.ThinkingBlock:
+			fmt.Printf("    %s[%d] Thinking:%s\n", colorCyan, i, colorReset)
+			text := v.Thinking
+			if len(text) > 200 {
+				text = text[:200] + "..."
+			}
+			for _, line := range strings.Split(text, "\n") {
+				fmt.Printf("      %s\n", line)
+			}
+		case anthropic.RedactedThinkingBlock:
+			fmt.Printf("    %s[%d] Redacted Thinking%s (%d bytes)\n", colorCyan, i, colorReset, len(v.Data))
 		}
 	}
 }
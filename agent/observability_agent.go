@@ -30,7 +30,7 @@ type ObservabilityAgent struct {
 }
 
 // NewObservabilityAgent creates a new observability agent with file system, OTEL, and Grafana tools
-func NewObservabilityAgent(client *anthropic.Client, logLevel config.LogLevel) (*ObservabilityAgent, error) {
+func NewObservabilityAgent(provider Provider, logLevel config.LogLevel) (*ObservabilityAgent, error) {
 	systemPrompt := `You are an expert observability infrastructure assistant with access to file system operations, OpenTelemetry collector management, and Grafana visualization tools.
 
 Your capabilities:
@@ -69,6 +69,8 @@ Your capabilities:
   - Delegate backend connectivity to 'backend' agent
   - Delegate coding tasks to 'coding' agent
   - The handoff is blocking - wait for completion before proceeding
+  - Use the 'handoff_parallel' tool to run independent instrumentation, infrastructure, pipeline or backend tasks at the same time
+  - Handoff results include the sub-agent's report: artifacts created, component statuses and follow-ups
 
 When setting up complete observability:
 
@@ -137,12 +139,15 @@ Best Practices:
 	agent := NewAgent(Config{
 		Name:         "ObservabilityAgent",
 		Description:  "An AI agent specialized for complete observability infrastructure setup with OTEL collectors, Grafana visualization, and code analysis",
-		Client:       client,
+		Provider:     provider,
 		Model:        anthropic.ModelClaudeSonnet4_5_20250929,
 		MaxTokens:    4096,
 		SystemPrompt: systemPrompt,
 		LogLevel:     logLevel,
 		Tools:        allTools,
+
+		// Large read_file and sandbox log outputs quickly fill the context window
+		HistoryStrategy: NewDefaultHistoryStrategy(provider),
 	})
 
 	return &ObservabilityAgent{
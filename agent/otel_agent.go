@@ -21,7 +21,7 @@ type OtelAgent struct {
 }
 
 // NewOtelAgent creates a new OTEL management agent with file system and OTEL tools
-func NewOtelAgent(client *anthropic.Client, logLevel config.LogLevel) (*OtelAgent, error) {
+func NewOtelAgent(provider Provider, logLevel config.LogLevel) (*OtelAgent, error) {
 	systemPrompt := `You are an expert OpenTelemetry collector management assistant with access to both file system operations and OTEL agent management tools.
 
 Your capabilities:
@@ -111,7 +111,7 @@ Best Practices:
 	agent := NewAgent(Config{
 		Name:         "OtelAgent",
 		Description:  "An AI agent specialized for OpenTelemetry collector management with file system and OTEL tools",
-		Client:       client,
+		Provider:     provider,
 		Model:        anthropic.ModelClaudeSonnet4_5_20250929,
 		MaxTokens:    4096,
 		SystemPrompt: systemPrompt,
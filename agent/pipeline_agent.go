@@ -8,6 +8,7 @@ import (
 	"github.com/mottibechhofer/otel-ai-engineer/config"
 	"github.com/mottibechhofer/otel-ai-engineer/tools"
 	otelTools "github.com/mottibechhofer/otel-ai-engineer/tools/otel"
+	sandboxTools "github.com/mottibechhofer/otel-ai-engineer/tools/sandbox"
 )
 
 // PipelineAgent is specialized for collector pipeline management
@@ -16,7 +17,7 @@ type PipelineAgent struct {
 }
 
 // NewPipelineAgent creates a new pipeline agent with collector configuration tools
-func NewPipelineAgent(client *anthropic.Client, logLevel config.LogLevel) (*PipelineAgent, error) {
+func NewPipelineAgent(provider Provider, logLevel config.LogLevel) (*PipelineAgent, error) {
 	systemPrompt := `You are an expert OpenTelemetry collector pipeline configuration assistant.
 
 Your capabilities:
@@ -25,6 +26,7 @@ Your capabilities:
 - Configure filtering and transformation
 - Manage batch and aggregation settings
 - Update collector configurations remotely via OpAMP
+- Compare a changed configuration against the current one on identical telemetry before applying it
 
 - **Task Delegation**:
   - Use the 'handoff_task' tool when a task is better suited for another agent
@@ -57,8 +59,15 @@ Configuration tasks:
 2. Determine appropriate sampling strategy
 3. Configure processors for data quality
 4. Set up filters for cost reduction
-5. Update collector config via OpAMP
-6. Monitor pipeline health and metrics
+5. Compare the new config against the current one
+6. Update collector config via OpAMP
+7. Monitor pipeline health and metrics
+
+Before every 'update_otel_agent_config' call:
+- Fetch the current config with 'get_otel_agent_config'
+- Call 'compare_collector_configs' with the current config as baseline_config and your change as candidate_config
+- Check the differences are the ones you intended: the volume dropped by sampling or filters, the attributes added, removed or reduced in cardinality, and no new refused or failed items
+- Don't apply a change whose report shows unintended data loss, new errors or a large rise in memory or CPU; fix the config and compare again
 
 Best Practices:
 - Start with no sampling to understand data volume
@@ -70,11 +79,12 @@ Best Practices:
 	// Get OTEL tools
 	allTools := tools.GetFileSystemTools()
 	allTools = append(allTools, otelTools.GetOtelTools(nil)...) // Will need proper OTEL client
+	allTools = append(allTools, sandboxTools.GetCompareTools()...)
 
 	agent := NewAgent(Config{
 		Name:         "PipelineAgent",
 		Description:  "An AI agent specialized for OpenTelemetry collector pipeline configuration",
-		Client:       client,
+		Provider:     provider,
 		Model:        anthropic.ModelClaudeSonnet4_5_20250929,
 		MaxTokens:    4096,
 		SystemPrompt: systemPrompt,
@@ -99,9 +109,10 @@ Tasks:
 1. Review the existing collector configuration for %s
 2. Apply the processing rules to the pipeline
 3. Configure sampling, filtering, and batching as needed
-4. Update collector configuration via OpAMP
-5. Verify the configuration is applied
-6. Monitor pipeline performance
+4. Compare the updated configuration against the current one
+5. Update collector configuration via OpAMP
+6. Verify the configuration is applied
+7. Monitor pipeline performance
 
 Start by reading the current collector configuration and then apply updates.`, pipelineName, collectorID, configYAML, rulesJSON, collectorID)
 
@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// ModelPricing holds USD prices per million tokens for a model
+type ModelPricing struct {
+	InputPerMTok      float64
+	OutputPerMTok     float64
+	CacheWritePerMTok float64
+	CacheReadPerMTok  float64
+}
+
+// modelPrices maps model name prefixes to their prices. Dated model IDs
+// (e.g. claude-sonnet-4-5-20250929) match the longest prefix.
+var modelPrices = map[string]ModelPricing{
+	"claude-opus-4-5":   {InputPerMTok: 5, OutputPerMTok: 25, CacheWritePerMTok: 6.25, CacheReadPerMTok: 0.50},
+	"claude-opus-4":     {InputPerMTok: 15, OutputPerMTok: 75, CacheWritePerMTok: 18.75, CacheReadPerMTok: 1.50},
+	"claude-3-opus":     {InputPerMTok: 15, OutputPerMTok: 75, CacheWritePerMTok: 18.75, CacheReadPerMTok: 1.50},
+	"claude-sonnet-4":   {InputPerMTok: 3, OutputPerMTok: 15, CacheWritePerMTok: 3.75, CacheReadPerMTok: 0.30},
+	"claude-3-7-sonnet": {InputPerMTok: 3, OutputPerMTok: 15, CacheWritePerMTok: 3.75, CacheReadPerMTok: 0.30},
+	"claude-3-5-sonnet": {InputPerMTok: 3, OutputPerMTok: 15, CacheWritePerMTok: 3.75, CacheReadPerMTok: 0.30},
+	"claude-haiku-4-5":  {InputPerMTok: 1, OutputPerMTok: 5, CacheWritePerMTok: 1.25, CacheReadPerMTok: 0.10},
+	"claude-3-5-haiku":  {InputPerMTok: 0.80, OutputPerMTok: 4, CacheWritePerMTok: 1, CacheReadPerMTok: 0.08},
+	"claude-3-haiku":    {InputPerMTok: 0.25, OutputPerMTok: 1.25, CacheWritePerMTok: 0.30, CacheReadPerMTok: 0.03},
+}
+
+// defaultPricing is used for models missing from the table. It errs on the
+// side of Sonnet pricing so cost budgets still apply.
+var defaultPricing = modelPrices["claude-sonnet-4"]
+
+// PricingForModel returns the prices for a model, and whether the model was found
+func PricingForModel(model string) (ModelPricing, bool) {
+	prefixes := make([]string, 0, len(modelPrices))
+	for prefix := range modelPrices {
+		prefixes = append(prefixes, prefix)
+	}
+	// Longest prefix first so claude-opus-4-5 wins over claude-opus-4
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return modelPrices[prefix], true
+		}
+	}
+	return defaultPricing, false
+}
+
+// CostUSD returns the cost of a single API response's usage
+func (p ModelPricing) CostUSD(usage anthropic.Usage) float64 {
+	return (float64(usage.InputTokens)*p.InputPerMTok +
+		float64(usage.OutputTokens)*p.OutputPerMTok +
+		float64(usage.CacheCreationInputTokens)*p.CacheWritePerMTok +
+		float64(usage.CacheReadInputTokens)*p.CacheReadPerMTok) / 1_000_000
+}
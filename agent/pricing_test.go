@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"math"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestPricingForModel(t *testing.T) {
+	tests := []struct {
+		model     string
+		wantInput float64
+		wantFound bool
+	}{
+		{model: "claude-opus-4-5", wantInput: 5, wantFound: true},
+		{model: "claude-opus-4-5-20251101", wantInput: 5, wantFound: true},
+		{model: "claude-opus-4-1-20250805", wantInput: 15, wantFound: true},
+		{model: "claude-sonnet-4-5-20250929", wantInput: 3, wantFound: true},
+		{model: "claude-haiku-4-5", wantInput: 1, wantFound: true},
+		{model: "claude-3-5-haiku-latest", wantInput: 0.80, wantFound: true},
+		{model: "claude-3-haiku-20240307", wantInput: 0.25, wantFound: true},
+		{model: "gpt-4o", wantInput: 3, wantFound: false},
+		{model: "", wantInput: 3, wantFound: false},
+	}
+
+	for _, tt := range tests {
+		pricing, found := PricingForModel(tt.model)
+		if found != tt.wantFound {
+			t.Errorf("PricingForModel(%q) found = %v, want %v", tt.model, found, tt.wantFound)
+		}
+		if pricing.InputPerMTok != tt.wantInput {
+			t.Errorf("PricingForModel(%q) input price = %v, want %v", tt.model, pricing.InputPerMTok, tt.wantInput)
+		}
+	}
+}
+
+func TestModelPricingCostUSD(t *testing.T) {
+	pricing := ModelPricing{InputPerMTok: 3, OutputPerMTok: 15, CacheWritePerMTok: 3.75, CacheReadPerMTok: 0.30}
+
+	tests := []struct {
+		name  string
+		usage anthropic.Usage
+		want  float64
+	}{
+		{name: "empty", usage: anthropic.Usage{}, want: 0},
+		{name: "input", usage: anthropic.Usage{InputTokens: 1_000_000}, want: 3},
+		{name: "output", usage: anthropic.Usage{OutputTokens: 2000}, want: 0.03},
+		{name: "cache write", usage: anthropic.Usage{CacheCreationInputTokens: 1_000_000}, want: 3.75},
+		{name: "cache read", usage: anthropic.Usage{CacheReadInputTokens: 1_000_000}, want: 0.30},
+		{
+			name:  "combined",
+			usage: anthropic.Usage{InputTokens: 1000, OutputTokens: 500, CacheCreationInputTokens: 2000, CacheReadInputTokens: 10000},
+			want:  0.003 + 0.0075 + 0.0075 + 0.003,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := pricing.CostUSD(tt.usage); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: CostUSD() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/mottibechhofer/otel-ai-engineer/config"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+// Provider abstracts the LLM backend behind an agent. Requests and responses
+// use the Anthropic message types as the common format: providers translate
+// messages and tool schemas to their own wire format, and report usage in
+// anthropic.Usage so budgets and token accounting work for every provider.
+//
+// Providers must not retry internally; the agent retries classified errors
+// itself. Errors should be returned as *APIError where possible.
+type Provider interface {
+	// Name identifies the provider, e.g. "anthropic" or "openai"
+	Name() string
+
+	// CreateMessage sends a request and returns the complete response
+	CreateMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error)
+}
+
+// StreamingProvider is implemented by providers that can stream responses.
+// Agents with streaming enabled fall back to CreateMessage for providers
+// that don't implement it.
+type StreamingProvider interface {
+	Provider
+
+	// StreamMessage sends a request, calling onEvent for each stream event,
+	// and returns the accumulated response
+	StreamMessage(ctx context.Context, params anthropic.MessageNewParams, onEvent func(anthropic.MessageStreamEventUnion)) (*anthropic.Message, error)
+}
+
+// Provider types accepted in config.NamedProvider
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+)
+
+// AnthropicProvider is the default provider, backed by the Anthropic SDK
+type AnthropicProvider struct {
+	client *anthropic.Client
+}
+
+// NewAnthropicProvider creates a provider that uses the given client
+func NewAnthropicProvider(client *anthropic.Client) *AnthropicProvider {
+	return &AnthropicProvider{client: client}
+}
+
+// Name returns "anthropic"
+func (p *AnthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+// CreateMessage calls the Messages API. SDK retries are disabled so the agent
+// controls retry timing and emits retry events.
+func (p *AnthropicProvider) CreateMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	return p.client.Messages.New(ctx, params, option.WithMaxRetries(0))
+}
+
+// StreamMessage calls the streaming Messages API and accumulates the response
+func (p *AnthropicProvider) StreamMessage(ctx context.Context, params anthropic.MessageNewParams, onEvent func(anthropic.MessageStreamEventUnion)) (*anthropic.Message, error) {
+	stream := p.client.Messages.NewStreaming(ctx, params, option.WithMaxRetries(0))
+	defer stream.Close()
+
+	message := anthropic.Message{}
+	for stream.Next() {
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, fmt.Errorf("failed to accumulate stream event: %w", err)
+		}
+		onEvent(event)
+	}
+
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// ProviderSet holds the named providers the operator configured. Custom
+// agents select one by name only, so an API caller can never point an agent
+// at another endpoint or have it send another server secret as its API key.
+type ProviderSet map[string]config.NamedProvider
+
+// Validate checks that cfg selects a configured provider. A nil or empty
+// config is valid and selects the default provider.
+func (s ProviderSet) Validate(cfg *storage.ProviderConfig) error {
+	if cfg == nil || *cfg == (storage.ProviderConfig{}) {
+		return nil
+	}
+	if cfg.Name == "" {
+		return fmt.Errorf("provider name is required")
+	}
+	if _, ok := s[cfg.Name]; !ok {
+		return fmt.Errorf("unknown provider %q", cfg.Name)
+	}
+	return nil
+}
+
+// NewProvider creates the provider selected by cfg. A nil or empty config
+// returns defaultProvider, as does any config while a cassette is recording or
+// replaying, so cassettes capture every call. API keys are read from the
+// environment variable the operator named, so they are never stored.
+func (s ProviderSet) NewProvider(cfg *storage.ProviderConfig, defaultProvider Provider) (Provider, error) {
+	if cfg == nil || *cfg == (storage.ProviderConfig{}) {
+		return defaultProvider, nil
+	}
+	if _, ok := defaultProvider.(*CassetteProvider); ok {
+		return defaultProvider, nil
+	}
+	if err := s.Validate(cfg); err != nil {
+		return nil, err
+	}
+	named := s[cfg.Name]
+
+	apiKey := ""
+	if named.APIKeyEnv != "" {
+		apiKey = os.Getenv(named.APIKeyEnv)
+		if apiKey == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", named.APIKeyEnv)
+		}
+	}
+
+	switch named.Type {
+	case ProviderAnthropic:
+		if named.BaseURL == "" && apiKey == "" {
+			return defaultProvider, nil
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("provider %q needs api_key_env to use base_url", cfg.Name)
+		}
+		// Set the key explicitly and drop any bearer token the SDK read from
+		// the environment, so the server's own credentials never reach BaseURL
+		opts := []option.RequestOption{
+			option.WithAPIKey(apiKey),
+			option.WithHeaderDel("authorization"),
+		}
+		if named.BaseURL != "" {
+			opts = append(opts, option.WithBaseURL(named.BaseURL))
+		}
+		client := anthropic.NewClient(opts...)
+		return NewAnthropicProvider(&client), nil
+
+	case ProviderOpenAI:
+		model := named.Model
+		if cfg.Model != "" {
+			model = cfg.Model
+		}
+		return NewOpenAIProvider(OpenAIConfig{
+			BaseURL: named.BaseURL,
+			APIKey:  apiKey,
+			Model:   model,
+		}), nil
+	}
+
+	return nil, fmt.Errorf("provider %q has unknown type %q", cfg.Name, named.Type)
+}
@@ -0,0 +1,350 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// OpenAIConfig configures an OpenAI-compatible chat completions provider
+type OpenAIConfig struct {
+	BaseURL    string       // e.g. https://gateway.internal/v1
+	APIKey     string       // Sent as a bearer token if set
+	Model      string       // Replaces the model in every request if set
+	HTTPClient *http.Client // Optional (default has a 10 minute timeout)
+}
+
+// OpenAIProvider talks to any server implementing the OpenAI chat completions
+// API, such as self-hosted model gateways. Text, tool definitions, tool calls
+// and tool results are translated; other content blocks (images, documents,
+// thinking) are dropped, and cache breakpoints are ignored.
+type OpenAIProvider struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider creates an OpenAI-compatible provider
+func NewOpenAIProvider(cfg OpenAIConfig) *OpenAIProvider {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Minute}
+	}
+
+	return &OpenAIProvider{
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		apiKey:     cfg.APIKey,
+		model:      cfg.Model,
+		httpClient: httpClient,
+	}
+}
+
+// Name returns "openai"
+func (p *OpenAIProvider) Name() string {
+	return ProviderOpenAI
+}
+
+// openAIMessage is a chat completions message. Content is a pointer because
+// assistant messages carrying only tool calls send null content.
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+type openAIChatRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Tools       []openAITool    `json:"tools,omitempty"`
+	MaxTokens   int64           `json:"max_tokens,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"`
+}
+
+type openAIChatResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens        int64 `json:"prompt_tokens"`
+		CompletionTokens    int64 `json:"completion_tokens"`
+		PromptTokensDetails struct {
+			CachedTokens int64 `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+	} `json:"usage"`
+}
+
+// openAIErrorBody is the JSON error body returned by OpenAI-compatible servers
+type openAIErrorBody struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// CreateMessage translates the request to a chat completion and the response
+// back to an Anthropic message
+func (p *OpenAIProvider) CreateMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	request, err := p.buildRequest(params)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal chat completion request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chat completion response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{
+			Kind:       kindForStatus(resp.StatusCode),
+			StatusCode: resp.StatusCode,
+			RequestID:  resp.Header.Get("X-Request-Id"),
+			RetryAfter: parseRetryAfter(resp.Header),
+			Err:        fmt.Errorf("%s %s: %s", req.Method, req.URL, resp.Status),
+		}
+		var errBody openAIErrorBody
+		if json.Unmarshal(respBody, &errBody) == nil {
+			apiErr.Type = errBody.Error.Type
+			apiErr.Message = errBody.Error.Message
+		}
+		return nil, apiErr
+	}
+
+	var completion openAIChatResponse
+	if err := json.Unmarshal(respBody, &completion); err != nil {
+		return nil, fmt.Errorf("failed to parse chat completion response: %w", err)
+	}
+
+	return completion.toMessage(request.Model)
+}
+
+// buildRequest converts Anthropic message params to a chat completion request
+func (p *OpenAIProvider) buildRequest(params anthropic.MessageNewParams) (*openAIChatRequest, error) {
+	request := &openAIChatRequest{
+		Model:     string(params.Model),
+		MaxTokens: params.MaxTokens,
+	}
+	if p.model != "" {
+		request.Model = p.model
+	}
+	if params.Temperature.Valid() {
+		temperature := params.Temperature.Value
+		request.Temperature = &temperature
+	}
+
+	if len(params.System) > 0 {
+		parts := make([]string, 0, len(params.System))
+		for _, block := range params.System {
+			parts = append(parts, block.Text)
+		}
+		system := strings.Join(parts, "\n\n")
+		request.Messages = append(request.Messages, openAIMessage{Role: "system", Content: &system})
+	}
+
+	for _, msg := range params.Messages {
+		converted, err := convertMessageToOpenAI(msg)
+		if err != nil {
+			return nil, err
+		}
+		request.Messages = append(request.Messages, converted...)
+	}
+
+	for _, toolUnion := range params.Tools {
+		tool := toolUnion.OfTool
+		if tool == nil {
+			continue
+		}
+		schema, err := json.Marshal(tool.InputSchema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal schema for tool %s: %w", tool.Name, err)
+		}
+		request.Tools = append(request.Tools, openAITool{
+			Type: "function",
+			Function: openAIFunction{
+				Name:        tool.Name,
+				Description: tool.Description.Value,
+				Parameters:  schema,
+			},
+		})
+	}
+
+	return request, nil
+}
+
+// convertMessageToOpenAI converts one Anthropic message to chat completion
+// messages. Tool results become separate "tool" messages, which must directly
+// follow the assistant message that made the calls, so they come before any
+// user text in the same message.
+func convertMessageToOpenAI(msg anthropic.MessageParam) ([]openAIMessage, error) {
+	var result []openAIMessage
+	var text []string
+	var toolCalls []openAIToolCall
+
+	for _, block := range msg.Content {
+		switch {
+		case block.OfText != nil:
+			text = append(text, block.OfText.Text)
+
+		case block.OfToolUse != nil:
+			arguments, err := json.Marshal(block.OfToolUse.Input)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal input for tool call %s: %w", block.OfToolUse.ID, err)
+			}
+			call := openAIToolCall{ID: block.OfToolUse.ID, Type: "function"}
+			call.Function.Name = block.OfToolUse.Name
+			call.Function.Arguments = string(arguments)
+			toolCalls = append(toolCalls, call)
+
+		case block.OfToolResult != nil:
+			var parts []string
+			for _, c := range block.OfToolResult.Content {
+				if c.OfText != nil {
+					parts = append(parts, c.OfText.Text)
+				}
+			}
+			content := strings.Join(parts, "\n")
+			if block.OfToolResult.IsError.Value {
+				content = "Error: " + content
+			}
+			result = append(result, openAIMessage{
+				Role:       "tool",
+				Content:    &content,
+				ToolCallID: block.OfToolResult.ToolUseID,
+			})
+		}
+	}
+
+	if len(text) == 0 && len(toolCalls) == 0 {
+		return result, nil
+	}
+
+	converted := openAIMessage{Role: string(msg.Role), ToolCalls: toolCalls}
+	if len(text) > 0 {
+		content := strings.Join(text, "\n")
+		converted.Content = &content
+	}
+	return append(result, converted), nil
+}
+
+// openAIStopReasons maps chat completion finish reasons to Anthropic stop reasons
+var openAIStopReasons = map[string]anthropic.StopReason{
+	"stop":           anthropic.StopReasonEndTurn,
+	"tool_calls":     anthropic.StopReasonToolUse,
+	"function_call":  anthropic.StopReasonToolUse,
+	"length":         anthropic.StopReasonMaxTokens,
+	"content_filter": anthropic.StopReasonRefusal,
+}
+
+// toMessage converts a chat completion to an Anthropic message. The message is
+// built as JSON and decoded so the SDK's union types are populated exactly as
+// for a native response. Cached prompt tokens are reported as cache reads.
+func (r *openAIChatResponse) toMessage(requestModel string) (*anthropic.Message, error) {
+	if len(r.Choices) == 0 {
+		return nil, fmt.Errorf("chat completion returned no choices")
+	}
+	choice := r.Choices[0]
+
+	content := []map[string]any{}
+	if choice.Message.Content != nil && *choice.Message.Content != "" {
+		content = append(content, map[string]any{"type": "text", "text": *choice.Message.Content})
+	}
+	for _, call := range choice.Message.ToolCalls {
+		input := json.RawMessage(call.Function.Arguments)
+		if !json.Valid(input) {
+			input = json.RawMessage("{}")
+		}
+		content = append(content, map[string]any{
+			"type":  "tool_use",
+			"id":    call.ID,
+			"name":  call.Function.Name,
+			"input": input,
+		})
+	}
+
+	stopReason, ok := openAIStopReasons[choice.FinishReason]
+	if !ok {
+		stopReason = anthropic.StopReasonEndTurn
+	}
+
+	model := r.Model
+	if model == "" {
+		model = requestModel
+	}
+
+	cached := r.Usage.PromptTokensDetails.CachedTokens
+	data, err := json.Marshal(map[string]any{
+		"id":          r.ID,
+		"type":        "message",
+		"role":        "assistant",
+		"model":       model,
+		"content":     content,
+		"stop_reason": stopReason,
+		"usage": map[string]any{
+			"input_tokens":                r.Usage.PromptTokens - cached,
+			"output_tokens":               r.Usage.CompletionTokens,
+			"cache_read_input_tokens":     cached,
+			"cache_creation_input_tokens": 0,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert chat completion: %w", err)
+	}
+
+	var message anthropic.Message
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, fmt.Errorf("failed to convert chat completion: %w", err)
+	}
+	return &message, nil
+}
@@ -0,0 +1,249 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/config"
+)
+
+// openAITestServer answers chat completion requests with response and records the last request
+func openAITestServer(t *testing.T, status int, response string) (*httptest.Server, *http.Request, *openAIChatRequest) {
+	t.Helper()
+	var recorded http.Request
+	var request openAIChatRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorded = *r.Clone(context.Background())
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req_abc")
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "3")
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &recorded, &request
+}
+
+const openAIToolCallResponse = `{
+	"id": "chatcmpl-1",
+	"model": "gateway-model",
+	"choices": [{
+		"finish_reason": "tool_calls",
+		"message": {
+			"role": "assistant",
+			"content": "Looking it up.",
+			"tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "lookup", "arguments": "{\"key\":\"service\"}"}},
+				{"id": "call_2", "type": "function", "function": {"name": "lookup", "arguments": "not json"}}
+			]
+		}
+	}],
+	"usage": {"prompt_tokens": 120, "completion_tokens": 30, "prompt_tokens_details": {"cached_tokens": 100}}
+}`
+
+func TestOpenAIProviderBuildsRequest(t *testing.T) {
+	server, recorded, request := openAITestServer(t, http.StatusOK, openAIToolCallResponse)
+	provider := NewOpenAIProvider(OpenAIConfig{BaseURL: server.URL + "/", APIKey: "secret", Model: "gateway-model"})
+
+	_, err := provider.CreateMessage(context.Background(), anthropic.MessageNewParams{
+		Model:       anthropic.ModelClaudeSonnet4_5,
+		MaxTokens:   512,
+		Temperature: anthropic.Float(0.2),
+		System:      []anthropic.TextBlockParam{{Text: "Be brief."}, {Text: "Use tools."}},
+		Tools: []anthropic.ToolUnionParam{{OfTool: &anthropic.ToolParam{
+			Name:        "lookup",
+			Description: anthropic.String("Look up a value"),
+			InputSchema: anthropic.ToolInputSchemaParam{Properties: map[string]interface{}{"key": map[string]interface{}{"type": "string"}}},
+		}}},
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock("Which service?")),
+			anthropic.NewAssistantMessage(
+				anthropic.NewTextBlock("Checking."),
+				anthropic.NewToolUseBlock("call_0", map[string]string{"key": "service"}, "lookup"),
+			),
+			anthropic.NewUserMessage(
+				anthropic.NewTextBlock("Also check the region."),
+				anthropic.NewToolResultBlock("call_0", "not found", true),
+			),
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+
+	if recorded.URL.Path != "/chat/completions" {
+		t.Errorf("request path = %s, want /chat/completions", recorded.URL.Path)
+	}
+	if got := recorded.Header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if request.Model != "gateway-model" || request.MaxTokens != 512 || request.Temperature == nil || *request.Temperature != 0.2 {
+		t.Errorf("request model = %q, max_tokens = %d, temperature = %v", request.Model, request.MaxTokens, request.Temperature)
+	}
+
+	roles := make([]string, len(request.Messages))
+	for i, msg := range request.Messages {
+		roles[i] = msg.Role
+	}
+	// Tool results come straight after the assistant's tool calls, before the user's text
+	if got := strings.Join(roles, ","); got != "system,user,assistant,tool,user" {
+		t.Fatalf("message roles = %s", got)
+	}
+	if *request.Messages[0].Content != "Be brief.\n\nUse tools." {
+		t.Errorf("system content = %q", *request.Messages[0].Content)
+	}
+	assistant := request.Messages[2]
+	if len(assistant.ToolCalls) != 1 || assistant.ToolCalls[0].ID != "call_0" || assistant.ToolCalls[0].Function.Arguments != `{"key":"service"}` {
+		t.Errorf("assistant tool calls = %+v", assistant.ToolCalls)
+	}
+	if tool := request.Messages[3]; tool.ToolCallID != "call_0" || *tool.Content != "Error: not found" {
+		t.Errorf("tool message = %+v", tool)
+	}
+
+	if len(request.Tools) != 1 || request.Tools[0].Function.Name != "lookup" || request.Tools[0].Function.Description != "Look up a value" {
+		t.Fatalf("tools = %+v", request.Tools)
+	}
+	if !strings.Contains(string(request.Tools[0].Function.Parameters), `"key"`) {
+		t.Errorf("tool parameters = %s", request.Tools[0].Function.Parameters)
+	}
+}
+
+func TestOpenAIProviderConvertsResponse(t *testing.T) {
+	server, _, _ := openAITestServer(t, http.StatusOK, openAIToolCallResponse)
+	provider := NewOpenAIProvider(OpenAIConfig{BaseURL: server.URL})
+
+	message, err := provider.CreateMessage(context.Background(), anthropic.MessageNewParams{
+		Model:    anthropic.ModelClaudeSonnet4_5,
+		Messages: []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("Which service?"))},
+	})
+	if err != nil {
+		t.Fatalf("CreateMessage() error = %v", err)
+	}
+
+	if message.StopReason != anthropic.StopReasonToolUse || message.Model != "gateway-model" {
+		t.Errorf("stop reason = %s, model = %s", message.StopReason, message.Model)
+	}
+	if len(message.Content) != 3 {
+		t.Fatalf("got %d content blocks, want text and two tool calls", len(message.Content))
+	}
+	if text := message.Content[0].AsText().Text; text != "Looking it up." {
+		t.Errorf("text = %q", text)
+	}
+	toolUse := message.Content[1].AsToolUse()
+	if toolUse.ID != "call_1" || toolUse.Name != "lookup" || string(toolUse.Input) != `{"key":"service"}` {
+		t.Errorf("tool use = %+v", toolUse)
+	}
+	if input := string(message.Content[2].AsToolUse().Input); input != "{}" {
+		t.Errorf("invalid arguments decoded as %s, want {}", input)
+	}
+
+	// Cached prompt tokens are reported as cache reads, not as input
+	if message.Usage.InputTokens != 20 || message.Usage.CacheReadInputTokens != 100 || message.Usage.OutputTokens != 30 {
+		t.Errorf("usage = %+v", message.Usage)
+	}
+}
+
+func TestOpenAIStopReasons(t *testing.T) {
+	tests := []struct {
+		finishReason string
+		want         anthropic.StopReason
+	}{
+		{finishReason: "stop", want: anthropic.StopReasonEndTurn},
+		{finishReason: "tool_calls", want: anthropic.StopReasonToolUse},
+		{finishReason: "function_call", want: anthropic.StopReasonToolUse},
+		{finishReason: "length", want: anthropic.StopReasonMaxTokens},
+		{finishReason: "content_filter", want: anthropic.StopReasonRefusal},
+		{finishReason: "something_new", want: anthropic.StopReasonEndTurn},
+	}
+
+	for _, tt := range tests {
+		response := openAIChatResponse{ID: "chatcmpl-1"}
+		response.Choices = append(response.Choices, struct {
+			Message      openAIMessage `json:"message"`
+			FinishReason string        `json:"finish_reason"`
+		}{FinishReason: tt.finishReason})
+
+		message, err := response.toMessage("model")
+		if err != nil {
+			t.Fatalf("toMessage(%s) error = %v", tt.finishReason, err)
+		}
+		if message.StopReason != tt.want {
+			t.Errorf("finish_reason %s: stop reason = %s, want %s", tt.finishReason, message.StopReason, tt.want)
+		}
+		if message.Model != "model" {
+			t.Errorf("finish_reason %s: model = %s, want the request model", tt.finishReason, message.Model)
+		}
+	}
+
+	if _, err := (&openAIChatResponse{}).toMessage("model"); err == nil {
+		t.Error("toMessage() accepted a response without choices")
+	}
+}
+
+func TestOpenAIProviderErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantKind   APIErrorKind
+		wantType   string
+		retryAfter time.Duration
+	}{
+		{name: "rate limit", status: 429, body: `{"error":{"type":"rate_limit_exceeded","message":"slow down"}}`, wantKind: APIErrorRateLimit, wantType: "rate_limit_exceeded", retryAfter: 3 * time.Second},
+		{name: "unauthorized", status: 401, body: `{"error":{"type":"invalid_api_key","message":"bad key"}}`, wantKind: APIErrorAuthentication, wantType: "invalid_api_key"},
+		{name: "server error", status: 502, body: `<html>bad gateway</html>`, wantKind: APIErrorServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _, _ := openAITestServer(t, tt.status, tt.body)
+			provider := NewOpenAIProvider(OpenAIConfig{BaseURL: server.URL})
+
+			_, err := provider.CreateMessage(context.Background(), anthropic.MessageNewParams{Model: "m"})
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("CreateMessage() error = %v, want *APIError", err)
+			}
+			if apiErr.Kind != tt.wantKind || apiErr.Type != tt.wantType || apiErr.StatusCode != tt.status {
+				t.Errorf("error = %+v", apiErr)
+			}
+			if apiErr.RetryAfter != tt.retryAfter || apiErr.RequestID != "req_abc" {
+				t.Errorf("RetryAfter = %s, RequestID = %q", apiErr.RetryAfter, apiErr.RequestID)
+			}
+		})
+	}
+}
+
+func TestContentFilterEndsRunAsRefusal(t *testing.T) {
+	server, _, _ := openAITestServer(t, http.StatusOK, `{"id":"chatcmpl-1","choices":[{"finish_reason":"content_filter","message":{"role":"assistant","content":null}}]}`)
+	a := NewAgent(Config{
+		Name:     "refusal-test",
+		Provider: NewOpenAIProvider(OpenAIConfig{BaseURL: server.URL}),
+		LogLevel: config.LogLevelSilent,
+	})
+
+	result := a.Run(context.Background(), "Say something filtered")
+	if result.Success {
+		t.Fatal("run succeeded, want refusal")
+	}
+	if result.Error == nil || strings.Contains(result.Error.Error(), "unexpected stop reason") {
+		t.Errorf("run error = %v, want a refusal", result.Error)
+	}
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+func TestProviderSetValidate(t *testing.T) {
+	providers := ProviderSet{
+		"gateway": {Type: ProviderOpenAI, BaseURL: "https://gateway.internal/v1"},
+	}
+
+	tests := []struct {
+		name    string
+		cfg     *storage.ProviderConfig
+		wantErr bool
+	}{
+		{name: "nil selects default", cfg: nil},
+		{name: "empty selects default", cfg: &storage.ProviderConfig{}},
+		{name: "configured name", cfg: &storage.ProviderConfig{Name: "gateway", Model: "llama"}},
+		{name: "unknown name", cfg: &storage.ProviderConfig{Name: "attacker"}, wantErr: true},
+		{name: "model without name", cfg: &storage.ProviderConfig{Model: "llama"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := providers.Validate(tt.cfg); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	var none ProviderSet
+	if err := none.Validate(&storage.ProviderConfig{Name: "gateway"}); err == nil {
+		t.Error("Validate() accepted a name with no providers configured")
+	}
+}
+
+func TestProviderSetNewProvider(t *testing.T) {
+	t.Setenv("TEST_GATEWAY_KEY", "gateway-secret")
+	defaultProvider := &scriptedProvider{}
+	providers := ProviderSet{
+		"gateway":   {Type: ProviderOpenAI, BaseURL: "https://gateway.internal/v1/", APIKeyEnv: "TEST_GATEWAY_KEY", Model: "default-model"},
+		"anthropic": {Type: ProviderAnthropic},
+		"proxy":     {Type: ProviderAnthropic, BaseURL: "https://proxy.internal", APIKeyEnv: "TEST_GATEWAY_KEY"},
+		"unset":     {Type: ProviderOpenAI, BaseURL: "https://gateway.internal/v1", APIKeyEnv: "TEST_UNSET_KEY"},
+	}
+
+	provider, err := providers.NewProvider(&storage.ProviderConfig{Name: "gateway", Model: "llama"}, defaultProvider)
+	if err != nil {
+		t.Fatalf("NewProvider(gateway) error = %v", err)
+	}
+	openAI, ok := provider.(*OpenAIProvider)
+	if !ok {
+		t.Fatalf("NewProvider(gateway) = %T, want *OpenAIProvider", provider)
+	}
+	if openAI.baseURL != "https://gateway.internal/v1" || openAI.apiKey != "gateway-secret" || openAI.model != "llama" {
+		t.Errorf("openai provider = %+v", openAI)
+	}
+
+	if provider, err := providers.NewProvider(&storage.ProviderConfig{Name: "anthropic"}, defaultProvider); err != nil || provider != defaultProvider {
+		t.Errorf("NewProvider(anthropic) = %v, %v, want the default provider", provider, err)
+	}
+	if provider, err := providers.NewProvider(&storage.ProviderConfig{Name: "proxy"}, defaultProvider); err != nil || provider.Name() != ProviderAnthropic || provider == Provider(defaultProvider) {
+		t.Errorf("NewProvider(proxy) = %v, %v, want a new anthropic provider", provider, err)
+	}
+	if provider, err := providers.NewProvider(nil, defaultProvider); err != nil || provider != defaultProvider {
+		t.Errorf("NewProvider(nil) = %v, %v, want the default provider", provider, err)
+	}
+	if _, err := providers.NewProvider(&storage.ProviderConfig{Name: "unset"}, defaultProvider); err == nil {
+		t.Error("NewProvider() succeeded without the API key variable set")
+	}
+	if _, err := providers.NewProvider(&storage.ProviderConfig{Name: "elsewhere"}, defaultProvider); err == nil {
+		t.Error("NewProvider() accepted an unknown provider name")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
@@ -18,16 +19,20 @@ type AgentInfo struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Model       string `json:"model"`
+
+	// HandoffTargets limits which agents this agent may hand off to (empty allows any)
+	HandoffTargets []string `json:"handoff_targets,omitempty"`
 }
 
 // AgentFactory is a function that creates an agent instance
-type AgentFactory func(client *anthropic.Client, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error)
+type AgentFactory func(provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error)
 
 // Registry manages available agent types
 type Registry struct {
 	mu        sync.RWMutex
 	agents    map[string]AgentInfo
 	factories map[string]AgentFactory
+	providers ProviderSet
 }
 
 // NewRegistry creates a new agent registry
@@ -51,8 +56,8 @@ func (r *Registry) registerBuiltInAgents() {
 		Name:        "Coding Agent",
 		Description: "An AI agent specialized for coding tasks with file system access",
 		Model:       string(anthropic.ModelClaudeSonnet4_5_20250929),
-	}, func(client *anthropic.Client, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
-		codingAgent, err := NewCodingAgent(client, logLevel)
+	}, func(provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+		codingAgent, err := NewCodingAgent(provider, logLevel)
 		if err != nil {
 			return nil, err
 		}
@@ -67,8 +72,8 @@ func (r *Registry) registerBuiltInAgents() {
 		Name:        "OTEL Management Agent",
 		Description: "An AI agent specialized for OpenTelemetry collector management with file system and OTEL tools",
 		Model:       string(anthropic.ModelClaudeSonnet4_5_20250929),
-	}, func(client *anthropic.Client, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
-		otelAgent, err := NewOtelAgent(client, logLevel)
+	}, func(provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+		otelAgent, err := NewOtelAgent(provider, logLevel)
 		if err != nil {
 			return nil, err
 		}
@@ -83,8 +88,8 @@ func (r *Registry) registerBuiltInAgents() {
 		Name:        "Observability Agent",
 		Description: "An AI agent specialized for complete observability infrastructure setup with OTEL collectors, Grafana visualization, and code analysis",
 		Model:       string(anthropic.ModelClaudeSonnet4_5_20250929),
-	}, func(client *anthropic.Client, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
-		observabilityAgent, err := NewObservabilityAgent(client, logLevel)
+	}, func(provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+		observabilityAgent, err := NewObservabilityAgent(provider, logLevel)
 		if err != nil {
 			return nil, err
 		}
@@ -99,8 +104,8 @@ func (r *Registry) registerBuiltInAgents() {
 		Name:        "Instrumentation Agent",
 		Description: "Specialized agent for instrumenting services with OpenTelemetry",
 		Model:       string(anthropic.ModelClaudeSonnet4_5_20250929),
-	}, func(client *anthropic.Client, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
-		instrumentationAgent, err := NewInstrumentationAgent(client, logLevel)
+	}, func(provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+		instrumentationAgent, err := NewInstrumentationAgent(provider, logLevel)
 		if err != nil {
 			return nil, err
 		}
@@ -113,8 +118,8 @@ func (r *Registry) registerBuiltInAgents() {
 		Name:        "Infrastructure Agent",
 		Description: "Specialized agent for infrastructure monitoring setup",
 		Model:       string(anthropic.ModelClaudeSonnet4_5_20250929),
-	}, func(client *anthropic.Client, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
-		infrastructureAgent, err := NewInfrastructureAgent(client, logLevel)
+	}, func(provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+		infrastructureAgent, err := NewInfrastructureAgent(provider, logLevel)
 		if err != nil {
 			return nil, err
 		}
@@ -127,8 +132,8 @@ func (r *Registry) registerBuiltInAgents() {
 		Name:        "Pipeline Agent",
 		Description: "Specialized agent for collector pipeline configuration",
 		Model:       string(anthropic.ModelClaudeSonnet4_5_20250929),
-	}, func(client *anthropic.Client, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
-		pipelineAgent, err := NewPipelineAgent(client, logLevel)
+	}, func(provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+		pipelineAgent, err := NewPipelineAgent(provider, logLevel)
 		if err != nil {
 			return nil, err
 		}
@@ -141,8 +146,8 @@ func (r *Registry) registerBuiltInAgents() {
 		Name:        "Backend Agent",
 		Description: "Specialized agent for backend connectivity and validation",
 		Model:       string(anthropic.ModelClaudeSonnet4_5_20250929),
-	}, func(client *anthropic.Client, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
-		backendAgent, err := NewBackendAgent(client, logLevel)
+	}, func(provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+		backendAgent, err := NewBackendAgent(provider, logLevel)
 		if err != nil {
 			return nil, err
 		}
@@ -156,8 +161,8 @@ func (r *Registry) registerBuiltInAgents() {
 		Name:        "Sandbox Agent",
 		Description: "Specialized agent for testing OpenTelemetry collector configurations in isolated sandbox environments",
 		Model:       string(anthropic.ModelClaudeSonnet4_5_20250929),
-	}, func(client *anthropic.Client, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
-		sandboxAgent, err := NewSandboxAgent(client, logLevel)
+	}, func(provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+		sandboxAgent, err := NewSandboxAgent(provider, logLevel)
 		if err != nil {
 			return nil, err
 		}
@@ -175,20 +180,58 @@ func (r *Registry) Register(info AgentInfo, factory AgentFactory) {
 	r.factories[info.ID] = factory
 }
 
+// Unregister removes an agent type from the registry
+func (r *Registry) Unregister(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.agents, id)
+	delete(r.factories, id)
+}
+
+// SetProviders sets the named providers custom agents may select
+func (r *Registry) SetProviders(providers ProviderSet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.providers = providers
+}
+
+// ValidateProviderConfig checks that cfg selects a provider the operator configured
+func (r *Registry) ValidateProviderConfig(cfg *storage.ProviderConfig) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.providers.Validate(cfg)
+}
+
 // RegisterCustomAgent registers a custom agent with tool names
-// This creates a factory that will load tools by name at agent creation time
+// This creates a factory that will load tools by name at agent creation time.
+// A non-nil providerConfig replaces the provider passed to the factory.
 func (r *Registry) RegisterCustomAgent(
 	info AgentInfo,
 	toolNames []string,
 	systemPrompt string,
 	maxTokens int64,
+	thinkingBudget int64,
+	budget *Budget,
+	providerConfig *storage.ProviderConfig,
 	getToolsByName func(toolNames []string) []tools.Tool,
 ) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	// Create factory that loads tools by name
-	factory := func(client *anthropic.Client, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+	factory := func(provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+		r.mu.RLock()
+		providers := r.providers
+		r.mu.RUnlock()
+
+		provider, err := providers.NewProvider(providerConfig, provider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create provider for agent %s: %w", info.ID, err)
+		}
+
 		// Get tools by name
 		agentTools := getToolsByName(toolNames)
 
@@ -210,13 +253,16 @@ func (r *Registry) RegisterCustomAgent(
 		agent := NewAgent(Config{
 			Name:         info.Name,
 			Description:  info.Description,
-			Client:       client,
+			Provider:     provider,
 			Model:        model,
 			MaxTokens:    tokenLimit,
 			SystemPrompt: systemPrompt,
 			LogLevel:     logLevel,
 			EventEmitter: emitter,
 			Tools:        agentTools,
+			Budget:       budget,
+
+			ThinkingBudget: thinkingBudget,
 		})
 
 		return agent, nil
@@ -248,7 +294,7 @@ func (r *Registry) Get(id string) (AgentInfo, bool) {
 }
 
 // Create creates a new agent instance by ID
-func (r *Registry) Create(id string, client *anthropic.Client, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+func (r *Registry) Create(id string, provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
 	r.mu.RLock()
 	factory, ok := r.factories[id]
 	r.mu.RUnlock()
@@ -257,7 +303,7 @@ func (r *Registry) Create(id string, client *anthropic.Client, logLevel config.L
 		return nil, fmt.Errorf("agent type not found: %s", id)
 	}
 
-	return factory(client, logLevel, emitter)
+	return factory(provider, logLevel, emitter)
 }
 
 // Has checks if an agent type exists
@@ -269,43 +315,108 @@ func (r *Registry) Has(id string) bool {
 	return ok
 }
 
+// RunTracker registers running runs so they can be found and cancelled by ID.
+// The server's active run manager implements it.
+type RunTracker interface {
+	Add(runID string, ctx context.Context, cancel context.CancelFunc)
+	Remove(runID string)
+}
+
 // RunnerConfig holds configuration for running an agent
 type RunnerConfig struct {
 	AgentID         string
 	Prompt          string
-	Client          *anthropic.Client
+	Provider        Provider
 	LogLevel        config.LogLevel
 	EventEmitter    events.EventEmitter
 	PendingMessages chan string
 	History         []anthropic.MessageParam
 	RunID           string          // Optional: existing run ID for resuming
+	NewRunID        string          // Optional: ID for a new run, so callers can track it before it starts
+	ParentRunID     string          // Optional: parent of a handoff sub-run
 	Storage         storage.Storage // Optional: storage for handoff tracking
+	Streaming       bool            // Optional: stream API responses as delta events
+	Budget          *Budget         // Optional: limits for this run, overriding the agent's own budget
+
+	// HumanInputTimeout is how long request_human_input waits for a response
+	// (0 uses DefaultHumanInputTimeout)
+	HumanInputTimeout time.Duration
+
+	// HandoffTimeout limits each handoff sub-run (0 is unlimited)
+	HandoffTimeout time.Duration
+
+	// RunTracker registers handoff sub-runs so they can be stopped by ID
+	RunTracker RunTracker
+
+	// HandoffReporter is set on handoff sub-runs. It gives the agent the
+	// complete_handoff tool and collects the structured result it submits.
+	HandoffReporter *HandoffReporter
+
+	// BudgetTracker charges the run against an existing budget. Handoffs set
+	// it so sub-runs count against the parent budget; takes precedence over Budget.
+	BudgetTracker *BudgetTracker
 }
 
 // RunAgent is a helper function to create and run an agent in one call
 func (r *Registry) RunAgent(ctx context.Context, cfg RunnerConfig) (*RunResult, error) {
-	agent, err := r.Create(cfg.AgentID, cfg.Client, cfg.LogLevel, cfg.EventEmitter)
+	agent, err := r.Create(cfg.AgentID, cfg.Provider, cfg.LogLevel, cfg.EventEmitter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %w", err)
 	}
 
+	if cfg.Streaming {
+		agent.SetStreaming(true)
+	}
+
+	if cfg.BudgetTracker != nil {
+		agent.SetBudget(cfg.BudgetTracker)
+	} else if cfg.Budget != nil {
+		agent.SetBudget(NewBudgetTracker(*cfg.Budget))
+	}
+
+	// Checkpoint the conversation so the run can be resumed with its full history
+	if cfg.Storage != nil {
+		agent.SetCheckpointer(NewStorageCheckpointer(cfg.Storage))
+	}
+
+	// The run's ID, known up front when resuming or when the caller assigned one
+	runID := cfg.RunID
+	if runID == "" && cfg.NewRunID != "" {
+		runID = cfg.NewRunID
+		agent.SetRunID(runID)
+	}
+	if cfg.ParentRunID != "" {
+		agent.SetParentRunID(cfg.ParentRunID)
+	}
+
 	// Enable handoff if storage is provided
-	if cfg.Storage != nil && cfg.RunID != "" {
+	if cfg.Storage != nil && runID != "" {
 		handoffCtx := &HandoffContext{
-			ParentRunID:   cfg.RunID,
+			ParentRunID:   runID,
 			ParentAgentID: cfg.AgentID,
 			Registry:      r,
-			Client:        cfg.Client,
+			Provider:      cfg.Provider,
 			LogLevel:      cfg.LogLevel,
 			EventEmitter:  cfg.EventEmitter,
 			Storage:       cfg.Storage,
+			Streaming:     cfg.Streaming,
+			Budget:        agent.budget,
+			Timeout:       cfg.HandoffTimeout,
+			RunTracker:    cfg.RunTracker,
+
+			HumanInputTimeout: cfg.HumanInputTimeout,
 		}
 		handoffTool := CreateHandoffTool(handoffCtx)
 		agent.registry.RegisterTool(handoffTool)
+		agent.registry.RegisterTool(CreateParallelHandoffTool(handoffCtx))
+	}
+
+	if cfg.HandoffReporter != nil {
+		agent.registry.RegisterTool(CreateCompleteHandoffTool(cfg.HandoffReporter))
 	}
 
 	// Enable human input tool if storage is provided
-	if cfg.Storage != nil && cfg.RunID != "" {
+	if cfg.Storage != nil && runID != "" {
 		// Extract resource context from agent work if available
 		var resourceType *storage.ResourceType
 		var resourceID *string
@@ -318,7 +429,7 @@ func (r *Registry) RunAgent(ctx context.Context, cfg RunnerConfig) (*RunResult,
 		})
 		if err == nil {
 			for _, work := range works {
-				if work.RunID == cfg.RunID {
+				if work.RunID == runID {
 					resourceType = &work.ResourceType
 					resourceID = &work.ResourceID
 					workID := work.ID
@@ -329,7 +440,7 @@ func (r *Registry) RunAgent(ctx context.Context, cfg RunnerConfig) (*RunResult,
 		}
 
 		humanInputCtx := &HumanInputContext{
-			RunID:        cfg.RunID,
+			RunID:        runID,
 			AgentID:      cfg.AgentID,
 			AgentName:    agent.GetName(),
 			Storage:      cfg.Storage,
@@ -337,6 +448,7 @@ func (r *Registry) RunAgent(ctx context.Context, cfg RunnerConfig) (*RunResult,
 			ResourceType: resourceType,
 			ResourceID:   resourceID,
 			AgentWorkID:  agentWorkID,
+			Timeout:      cfg.HumanInputTimeout,
 		}
 		humanInputTool := CreateHumanInputTool(humanInputCtx)
 		agent.registry.RegisterTool(humanInputTool)
@@ -0,0 +1,300 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+)
+
+// APIErrorKind classifies errors returned by the Messages API
+type APIErrorKind string
+
+const (
+	APIErrorRateLimit      APIErrorKind = "rate_limit"      // 429
+	APIErrorOverloaded     APIErrorKind = "overloaded"      // 529
+	APIErrorServer         APIErrorKind = "server_error"    // 5xx
+	APIErrorTimeout        APIErrorKind = "timeout"         // 408 or network timeout
+	APIErrorConnection     APIErrorKind = "connection"      // Network failure before a response
+	APIErrorAuthentication APIErrorKind = "authentication"  // 401, 403
+	APIErrorInvalidRequest APIErrorKind = "invalid_request" // Other 4xx
+	APIErrorCancelled      APIErrorKind = "cancelled"       // Context cancelled or deadline exceeded
+	APIErrorUnknown        APIErrorKind = "unknown"
+)
+
+// APIError is a classified Messages API error
+type APIError struct {
+	Kind       APIErrorKind
+	StatusCode int           // HTTP status, 0 if no response was received
+	Type       string        // Error type from the response body, e.g. overloaded_error
+	Message    string        // Error message from the response body
+	RequestID  string        // Request ID for support requests
+	RetryAfter time.Duration // Server-requested delay before retrying, 0 if not given
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString(string(e.Kind))
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (%d)", e.StatusCode)
+	}
+	if e.Message != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Message)
+	} else if e.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request ID: %s)", e.RequestID)
+	}
+	return b.String()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the request may succeed if sent again
+func (e *APIError) Retryable() bool {
+	switch e.Kind {
+	case APIErrorRateLimit, APIErrorOverloaded, APIErrorServer, APIErrorTimeout, APIErrorConnection:
+		return true
+	}
+	return false
+}
+
+// apiErrorBody is the JSON error body returned by the API
+type apiErrorBody struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// streamErrorPrefix prefixes errors for error events received mid-stream
+const streamErrorPrefix = "received error while streaming: "
+
+// ClassifyAPIError converts an error from the Messages API into an APIError
+func ClassifyAPIError(err error) *APIError {
+	var classified *APIError
+	if errors.As(err, &classified) {
+		return classified
+	}
+
+	apiErr := &APIError{Kind: APIErrorUnknown, Err: err}
+
+	var sdkErr *anthropic.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		apiErr.Kind = APIErrorCancelled
+
+	case errors.As(err, &sdkErr):
+		apiErr.StatusCode = sdkErr.StatusCode
+		apiErr.RequestID = sdkErr.RequestID
+		apiErr.Kind = kindForStatus(sdkErr.StatusCode)
+		if sdkErr.Response != nil {
+			apiErr.RetryAfter = parseRetryAfter(sdkErr.Response.Header)
+		}
+		var body apiErrorBody
+		if json.Unmarshal([]byte(sdkErr.RawJSON()), &body) == nil {
+			apiErr.Type = body.Error.Type
+			apiErr.Message = body.Error.Message
+		}
+
+	case strings.HasPrefix(err.Error(), streamErrorPrefix):
+		// Error events arrive after a 200 response, so only the body type is known
+		var body apiErrorBody
+		if json.Unmarshal([]byte(strings.TrimPrefix(err.Error(), streamErrorPrefix)), &body) == nil {
+			apiErr.Type = body.Error.Type
+			apiErr.Message = body.Error.Message
+			apiErr.Kind = kindForErrorType(body.Error.Type)
+		}
+
+	case errors.As(err, &netErr):
+		apiErr.Kind = APIErrorConnection
+		if netErr.Timeout() {
+			apiErr.Kind = APIErrorTimeout
+		}
+	}
+
+	return apiErr
+}
+
+// kindForStatus maps an HTTP status code to an error kind
+func kindForStatus(status int) APIErrorKind {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return APIErrorRateLimit
+	case status == 529:
+		return APIErrorOverloaded
+	case status == http.StatusRequestTimeout:
+		return APIErrorTimeout
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return APIErrorAuthentication
+	case status >= 500:
+		return APIErrorServer
+	case status >= 400:
+		return APIErrorInvalidRequest
+	}
+	return APIErrorUnknown
+}
+
+// kindForErrorType maps an API error type (from a streamed error event) to an error kind
+func kindForErrorType(errorType string) APIErrorKind {
+	switch errorType {
+	case "rate_limit_error":
+		return APIErrorRateLimit
+	case "overloaded_error":
+		return APIErrorOverloaded
+	case "api_error":
+		return APIErrorServer
+	case "timeout_error":
+		return APIErrorTimeout
+	case "authentication_error", "permission_error":
+		return APIErrorAuthentication
+	case "invalid_request_error", "not_found_error", "request_too_large":
+		return APIErrorInvalidRequest
+	}
+	return APIErrorUnknown
+}
+
+// parseRetryAfter reads the retry-after-ms or retry-after response headers
+func parseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// RetryPolicy controls retries of failed Messages API calls
+type RetryPolicy struct {
+	MaxRetries int           // Retries per model after the first attempt (0 disables retries)
+	BaseDelay  time.Duration // Delay before the first retry, doubled on each attempt
+	MaxDelay   time.Duration // Upper bound for the computed backoff and for retry-after
+}
+
+// DefaultRetryPolicy is used when Config.Retry is nil
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 4,
+	BaseDelay:  time.Second,
+	MaxDelay:   time.Minute,
+}
+
+// backoff returns the delay before retry number attempt (0-based). A
+// server-provided retry-after wins; otherwise exponential backoff with
+// jitter spreads concurrent runs apart.
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, p.MaxDelay)
+	}
+
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// Jitter in [delay/2, delay]
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// createMessage sends a Messages API request, streaming the response if
+// enabled. Retryable errors are retried with backoff; once retries for a
+// model are exhausted the next fallback model is tried. Every retry and
+// fallback emits an api_retry event.
+func (a *Agent) createMessage(ctx context.Context, params anthropic.MessageNewParams, runID string) (*anthropic.Message, error) {
+	models := append([]anthropic.Model{params.Model}, a.fallbackModels...)
+
+	var lastErr *APIError
+	for i, model := range models {
+		params.Model = model
+
+		for attempt := 0; ; attempt++ {
+			message, err := a.sendMessage(ctx, params, runID)
+			if err == nil {
+				return message, nil
+			}
+
+			lastErr = ClassifyAPIError(err)
+			if !lastErr.Retryable() || ctx.Err() != nil {
+				return nil, lastErr
+			}
+			if attempt >= a.retryPolicy.MaxRetries {
+				break
+			}
+
+			delay := a.retryPolicy.backoff(attempt, lastErr.RetryAfter)
+			a.logger.Info("API call failed (%s), retrying in %s (attempt %d/%d)", lastErr.Kind, delay, attempt+1, a.retryPolicy.MaxRetries)
+			a.emitRetry(runID, events.APIRetryData{
+				Attempt:    attempt + 1,
+				MaxRetries: a.retryPolicy.MaxRetries,
+				Model:      string(model),
+				ErrorKind:  string(lastErr.Kind),
+				StatusCode: lastErr.StatusCode,
+				Error:      lastErr.Error(),
+				Delay:      delay.String(),
+			})
+
+			select {
+			case <-ctx.Done():
+				return nil, ClassifyAPIError(ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+
+		if i+1 < len(models) {
+			a.logger.Info("Falling back from model %s to %s after %s", model, models[i+1], lastErr.Kind)
+			a.emitRetry(runID, events.APIRetryData{
+				Attempt:       a.retryPolicy.MaxRetries + 1,
+				MaxRetries:    a.retryPolicy.MaxRetries,
+				Model:         string(model),
+				FallbackModel: string(models[i+1]),
+				ErrorKind:     string(lastErr.Kind),
+				StatusCode:    lastErr.StatusCode,
+				Error:         lastErr.Error(),
+			})
+		}
+	}
+
+	return nil, lastErr
+}
+
+// sendMessage makes a single provider call. Providers don't retry, so
+// createMessage controls retry timing and emits events.
+func (a *Agent) sendMessage(ctx context.Context, params anthropic.MessageNewParams, runID string) (*anthropic.Message, error) {
+	if a.streaming {
+		return a.streamMessage(ctx, params, runID)
+	}
+	return a.provider.CreateMessage(ctx, params)
+}
+
+// emitRetry emits an api_retry event
+func (a *Agent) emitRetry(runID string, data events.APIRetryData) {
+	if evt, err := events.NewAPIRetryEvent(runID, a.name, a.name, data); err == nil {
+		a.eventEmitter.Emit(evt)
+	}
+}
@@ -0,0 +1,280 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+	"github.com/mottibechhofer/otel-ai-engineer/config"
+)
+
+// sdkError builds the error the SDK returns for an HTTP error response
+func sdkError(t *testing.T, status int, errorType string, header http.Header) error {
+	t.Helper()
+	err := &anthropic.Error{
+		StatusCode: status,
+		RequestID:  "req_123",
+		Request:    httptest.NewRequest(http.MethodPost, "https://api.anthropic.com/v1/messages", nil),
+		Response:   &http.Response{StatusCode: status, Header: header},
+	}
+	body := fmt.Sprintf(`{"type":"error","error":{"type":%q,"message":"something went wrong"}}`, errorType)
+	if e := json.Unmarshal([]byte(body), err); e != nil {
+		t.Fatalf("failed to build SDK error: %v", e)
+	}
+	return err
+}
+
+// timeoutError is a net.Error that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyAPIError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantKind      APIErrorKind
+		wantStatus    int
+		wantType      string
+		wantRetryable bool
+	}{
+		{name: "rate limit", err: sdkError(t, 429, "rate_limit_error", nil), wantKind: APIErrorRateLimit, wantStatus: 429, wantType: "rate_limit_error", wantRetryable: true},
+		{name: "overloaded", err: sdkError(t, 529, "overloaded_error", nil), wantKind: APIErrorOverloaded, wantStatus: 529, wantType: "overloaded_error", wantRetryable: true},
+		{name: "server error", err: sdkError(t, 500, "api_error", nil), wantKind: APIErrorServer, wantStatus: 500, wantType: "api_error", wantRetryable: true},
+		{name: "request timeout", err: sdkError(t, 408, "timeout_error", nil), wantKind: APIErrorTimeout, wantStatus: 408, wantType: "timeout_error", wantRetryable: true},
+		{name: "unauthorized", err: sdkError(t, 401, "authentication_error", nil), wantKind: APIErrorAuthentication, wantStatus: 401, wantType: "authentication_error"},
+		{name: "forbidden", err: sdkError(t, 403, "permission_error", nil), wantKind: APIErrorAuthentication, wantStatus: 403, wantType: "permission_error"},
+		{name: "bad request", err: sdkError(t, 400, "invalid_request_error", nil), wantKind: APIErrorInvalidRequest, wantStatus: 400, wantType: "invalid_request_error"},
+		{name: "streamed overload", err: errors.New(streamErrorPrefix + `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`), wantKind: APIErrorOverloaded, wantType: "overloaded_error", wantRetryable: true},
+		{name: "streamed invalid request", err: errors.New(streamErrorPrefix + `{"type":"error","error":{"type":"request_too_large","message":"too large"}}`), wantKind: APIErrorInvalidRequest, wantType: "request_too_large"},
+		{name: "network timeout", err: &net.OpError{Op: "read", Err: timeoutError{}}, wantKind: APIErrorTimeout, wantRetryable: true},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, wantKind: APIErrorConnection, wantRetryable: true},
+		{name: "cancelled", err: fmt.Errorf("request failed: %w", context.Canceled), wantKind: APIErrorCancelled},
+		{name: "deadline", err: context.DeadlineExceeded, wantKind: APIErrorCancelled},
+		{name: "unknown", err: errors.New("boom"), wantKind: APIErrorUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := ClassifyAPIError(tt.err)
+			if apiErr.Kind != tt.wantKind {
+				t.Errorf("Kind = %s, want %s", apiErr.Kind, tt.wantKind)
+			}
+			if apiErr.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, tt.wantStatus)
+			}
+			if apiErr.Type != tt.wantType {
+				t.Errorf("Type = %q, want %q", apiErr.Type, tt.wantType)
+			}
+			if apiErr.Retryable() != tt.wantRetryable {
+				t.Errorf("Retryable() = %v, want %v", apiErr.Retryable(), tt.wantRetryable)
+			}
+			if !errors.Is(apiErr, tt.err) {
+				t.Error("classified error does not wrap the original error")
+			}
+			if ClassifyAPIError(apiErr) != apiErr {
+				t.Error("classifying an APIError again did not return it unchanged")
+			}
+		})
+	}
+}
+
+func TestClassifyAPIErrorDetails(t *testing.T) {
+	apiErr := ClassifyAPIError(sdkError(t, 429, "rate_limit_error", http.Header{"Retry-After": []string{"7"}}))
+
+	if apiErr.RetryAfter != 7*time.Second {
+		t.Errorf("RetryAfter = %s, want 7s", apiErr.RetryAfter)
+	}
+	if apiErr.RequestID != "req_123" || apiErr.Message != "something went wrong" {
+		t.Errorf("RequestID = %q, Message = %q", apiErr.RequestID, apiErr.Message)
+	}
+	if got, want := apiErr.Error(), "rate_limit (429): something went wrong (request ID: req_123)"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{name: "none", header: http.Header{}, want: 0},
+		{name: "milliseconds", header: http.Header{"Retry-After-Ms": []string{"1500"}}, want: 1500 * time.Millisecond},
+		{name: "milliseconds win", header: http.Header{"Retry-After-Ms": []string{"200"}, "Retry-After": []string{"9"}}, want: 200 * time.Millisecond},
+		{name: "seconds", header: http.Header{"Retry-After": []string{"2.5"}}, want: 2500 * time.Millisecond},
+		{name: "negative", header: http.Header{"Retry-After": []string{"-1"}}, want: 0},
+		{name: "past date", header: http.Header{"Retry-After": []string{"Mon, 02 Jan 2006 15:04:05 GMT"}}, want: 0},
+		{name: "garbage", header: http.Header{"Retry-After": []string{"later"}}, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header); got != tt.want {
+			t.Errorf("%s: parseRetryAfter() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	future := http.Header{"Retry-After": []string{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}}
+	if got := parseRetryAfter(future); got <= 55*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(date) = %s, want about 1m", got)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		attempt    int
+		retryAfter time.Duration
+		min, max   time.Duration
+	}{
+		{attempt: 0, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{attempt: 1, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{attempt: 3, min: 400 * time.Millisecond, max: 800 * time.Millisecond},
+		{attempt: 4, min: 500 * time.Millisecond, max: time.Second},   // capped at MaxDelay
+		{attempt: 100, min: 500 * time.Millisecond, max: time.Second}, // overflow is capped too
+		{attempt: 0, retryAfter: 300 * time.Millisecond, min: 300 * time.Millisecond, max: 300 * time.Millisecond},
+		{attempt: 0, retryAfter: time.Hour, min: time.Second, max: time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := policy.backoff(tt.attempt, tt.retryAfter); got < tt.min || got > tt.max {
+				t.Errorf("backoff(%d, %s) = %s, want within [%s, %s]", tt.attempt, tt.retryAfter, got, tt.min, tt.max)
+				break
+			}
+		}
+	}
+}
+
+// failingProvider returns the scripted errors in order, then succeeds
+type failingProvider struct {
+	errs   []error
+	models []anthropic.Model
+}
+
+func (p *failingProvider) Name() string { return "failing" }
+
+func (p *failingProvider) CreateMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	p.models = append(p.models, params.Model)
+	if len(p.models) <= len(p.errs) {
+		return nil, p.errs[len(p.models)-1]
+	}
+	return &anthropic.Message{Model: params.Model}, nil
+}
+
+// newRetryTestAgent creates an agent with millisecond backoff and the given fallback models
+func newRetryTestAgent(provider Provider, emitter events.EventEmitter, fallbacks ...anthropic.Model) *Agent {
+	return NewAgent(Config{
+		Name:           "retry-test",
+		Provider:       provider,
+		Model:          "primary",
+		LogLevel:       config.LogLevelSilent,
+		EventEmitter:   emitter,
+		Retry:          &RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		FallbackModels: fallbacks,
+	})
+}
+
+func TestCreateMessageRetriesAndFallsBack(t *testing.T) {
+	overloaded := sdkError(t, 529, "overloaded_error", nil)
+	provider := &failingProvider{errs: []error{overloaded, overloaded, overloaded, overloaded}}
+	emitter := events.NewEmitter()
+	retries, unsubscribe := emitter.SubscribeAll()
+	defer unsubscribe()
+
+	message, err := newRetryTestAgent(provider, emitter, "fallback").createMessage(context.Background(), anthropic.MessageNewParams{Model: "primary"}, "run-1")
+	if err != nil {
+		t.Fatalf("createMessage() error = %v", err)
+	}
+	if message.Model != "fallback" {
+		t.Errorf("answered by %s, want fallback", message.Model)
+	}
+
+	// Three attempts on the primary model, then the fallback
+	want := []anthropic.Model{"primary", "primary", "primary", "fallback", "fallback"}
+	if fmt.Sprint(provider.models) != fmt.Sprint(want) {
+		t.Errorf("models called = %v, want %v", provider.models, want)
+	}
+
+	var data []events.APIRetryData
+	for len(retries) > 0 {
+		var retry events.APIRetryData
+		if err := json.Unmarshal((<-retries).Data, &retry); err != nil {
+			t.Fatalf("failed to decode retry event: %v", err)
+		}
+		data = append(data, retry)
+	}
+	if len(data) != 4 {
+		t.Fatalf("emitted %d retry events, want 4", len(data))
+	}
+	if data[2].FallbackModel != "fallback" || data[2].Attempt != 3 || data[2].ErrorKind != string(APIErrorOverloaded) {
+		t.Errorf("fallback event = %+v", data[2])
+	}
+	if data[3].Model != "fallback" || data[3].Attempt != 1 {
+		t.Errorf("retry on fallback model event = %+v", data[3])
+	}
+}
+
+func TestCreateMessageStopsOnPermanentError(t *testing.T) {
+	provider := &failingProvider{errs: []error{sdkError(t, 400, "invalid_request_error", nil)}}
+
+	_, err := newRetryTestAgent(provider, nil, "fallback").createMessage(context.Background(), anthropic.MessageNewParams{Model: "primary"}, "run-1")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Kind != APIErrorInvalidRequest {
+		t.Fatalf("createMessage() error = %v, want invalid_request", err)
+	}
+	if len(provider.models) != 1 {
+		t.Errorf("provider called %d times, want 1 (no retry or fallback)", len(provider.models))
+	}
+}
+
+func TestCreateMessageExhaustsRetries(t *testing.T) {
+	serverErr := sdkError(t, 500, "api_error", nil)
+	provider := &failingProvider{errs: []error{serverErr, serverErr, serverErr, serverErr}}
+
+	_, err := newRetryTestAgent(provider, nil).createMessage(context.Background(), anthropic.MessageNewParams{Model: "primary"}, "run-1")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Kind != APIErrorServer {
+		t.Fatalf("createMessage() error = %v, want server_error", err)
+	}
+	if len(provider.models) != 3 {
+		t.Errorf("provider called %d times, want 3", len(provider.models))
+	}
+}
+
+func TestCreateMessageCancelledDuringBackoff(t *testing.T) {
+	provider := &failingProvider{errs: []error{sdkError(t, 429, "rate_limit_error", http.Header{"Retry-After": []string{"60"}})}}
+	a := NewAgent(Config{
+		Name:     "retry-test",
+		Provider: provider,
+		LogLevel: config.LogLevelSilent,
+		Retry:    &RetryPolicy{MaxRetries: 2, MaxDelay: time.Minute},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := a.createMessage(ctx, anthropic.MessageNewParams{Model: "primary"}, "run-1")
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("createMessage() waited out the retry-after delay despite cancellation")
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Kind != APIErrorCancelled {
+		t.Errorf("createMessage() error = %v, want cancelled", err)
+	}
+}
@@ -16,7 +16,7 @@ type SandboxAgent struct {
 }
 
 // NewSandboxAgent creates a new sandbox testing agent
-func NewSandboxAgent(client *anthropic.Client, logLevel config.LogLevel) (*SandboxAgent, error) {
+func NewSandboxAgent(provider Provider, logLevel config.LogLevel) (*SandboxAgent, error) {
 	systemPrompt := `You are an expert OpenTelemetry collector testing and validation assistant. You help users test OpenTelemetry collector configurations in isolated sandbox environments before deploying to production.
 
 Your capabilities:
@@ -25,13 +25,16 @@ Your capabilities:
 - Create isolated test environments (sandboxes) with OpenTelemetry collectors
 - Each sandbox runs in its own Docker network with:
   - An OpenTelemetry Collector instance with custom configuration
-  - Telemetrygen containers for generating synthetic telemetry (traces, metrics, logs)
+  - A built-in generator sending synthetic telemetry (traces, metrics, logs) to it
   - Log and metric collection for validation
 - List all active sandboxes and their status
 - Get detailed information about specific sandboxes
 
 **Telemetry Generation**:
-- Generate synthetic traces, metrics, and logs using telemetrygen
+- Generate synthetic traces, metrics, and logs from a scenario: multi-service trace trees,
+  counters, gauges, histograms, exponential histograms and summaries, and structured logs
+- Set error ratios to exercise tail sampling, and high cardinality to exercise attribute processors
+- Replay telemetry recorded by a production collector's file exporter to test against real payloads
 - Configure generation rates (traces/metrics/logs per second)
 - Set custom duration for telemetry generation
 - Auto-validate after generation completes
@@ -49,6 +52,8 @@ Your capabilities:
 - Analyze collector logs for errors and warnings
 - Collect internal collector metrics (queue sizes, throughput, resource usage)
 - Provide AI-powered recommendations for improvements
+- Compare a candidate configuration against a baseline on identical telemetry: throughput,
+  refused/dropped/failed counts, exported attributes and their cardinality, and CPU/memory use
 
 **File System Operations**:
 - Read, write, and edit collector configuration files
@@ -64,9 +69,9 @@ Your capabilities:
    - Sandbox is deployed with isolated network
 
 2. **Generate Telemetry**:
-   - Start telemetrygen to send synthetic data
+   - Start the telemetry generator to send synthetic data
    - Configure what types to generate (traces/metrics/logs)
-   - Set generation rates and duration
+   - Set generation rates and duration, or replay recorded telemetry
    - Optionally auto-validate after completion
 
 3. **Validate & Diagnose**:
@@ -79,7 +84,7 @@ Your capabilities:
 4. **Iterate**:
    - Update configurations based on findings
    - Re-run validation
-   - Compare results
+   - Compare a changed configuration against the previous one with compare_collector_configs
 
 5. **Cleanup**:
    - Stop sandbox when done testing
@@ -127,9 +132,12 @@ Your capabilities:
 
 Remember: Sandboxes are ephemeral test environments. They're perfect for experimentation and validation before production deployment.`
 
-	// Initialize sandbox tools
-	if err := sandboxTools.InitializeSandboxTools(); err != nil {
-		return nil, fmt.Errorf("failed to initialize sandbox tools: %w", err)
+	// Initialize sandbox tools, keeping the manager's sandboxes if the server
+	// already initialized it
+	if sandboxTools.GetSandboxManager() == nil {
+		if err := sandboxTools.InitializeSandboxTools(); err != nil {
+			return nil, fmt.Errorf("failed to initialize sandbox tools: %w", err)
+		}
 	}
 
 	// Get tools directly (sandbox tools + file system tools)
@@ -140,7 +148,7 @@ Remember: Sandboxes are ephemeral test environments. They're perfect for experim
 	agent := NewAgent(Config{
 		Name:         "SandboxAgent",
 		Description:  "An AI agent specialized for OpenTelemetry collector testing and validation in sandbox environments",
-		Client:       client,
+		Provider:     provider,
 		Model:        anthropic.ModelClaudeSonnet4_5_20250929,
 		MaxTokens:    4096,
 		SystemPrompt: systemPrompt,
@@ -208,7 +216,7 @@ Please:
 	return sa.Run(ctx, prompt)
 }
 
-// CompareConfigurations compares two configurations by testing them in separate sandboxes
+// CompareConfigurations compares two configurations on identical telemetry
 func (sa *SandboxAgent) CompareConfigurations(ctx context.Context, config1 string, config2 string, description string) *RunResult {
 	prompt := fmt.Sprintf(`I need to compare two OpenTelemetry collector configurations.
 
@@ -225,15 +233,12 @@ Configuration B:
 ---
 
 Please:
-1. Create sandbox "comparison-a" with Configuration A
-2. Create sandbox "comparison-b" with Configuration B
-3. Generate the same telemetry to both (30 seconds, auto-validate)
-4. Compare validation results side by side
-5. Provide a detailed comparison highlighting:
+1. Compare them with compare_collector_configs, Configuration A as the baseline and Configuration B as the candidate (30 seconds of traces, metrics and logs)
+2. Review the report's differences, signals and resource use
+3. Provide a detailed comparison highlighting:
    - Which configuration performs better
-   - Any issues specific to each
-   - Recommendations on which to use
-6. Keep both sandboxes running for further inspection`, description, config1, config2)
+   - What data each drops, adds or changes
+   - Recommendations on which to use`, description, config1, config2)
 
 	return sa.Run(ctx, prompt)
 }
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+)
+
+// streamFlushInterval bounds how often buffered deltas are emitted.
+// Coalescing keeps the event volume low enough that subscribers with
+// bounded buffers (the event bridge, WebSocket clients) don't drop events.
+const streamFlushInterval = 100 * time.Millisecond
+
+// streamFlushSize flushes a delta buffer early once it grows past this many bytes
+const streamFlushSize = 512
+
+// deltaBuffer accumulates streamed deltas for a single content block
+type deltaBuffer struct {
+	index     int
+	blockType string // "text" or "tool_use"
+	toolUseID string
+	toolName  string
+	pending   string
+	lastFlush time.Time
+}
+
+// streamMessage calls the provider's streaming endpoint, emitting delta events
+// as content arrives, and returns the fully accumulated message. Providers
+// that can't stream fall back to a single CreateMessage call.
+func (a *Agent) streamMessage(ctx context.Context, params anthropic.MessageNewParams, runID string) (*anthropic.Message, error) {
+	streamer, ok := a.provider.(StreamingProvider)
+	if !ok {
+		return a.provider.CreateMessage(ctx, params)
+	}
+
+	var current *deltaBuffer
+	return streamer.StreamMessage(ctx, params, func(event anthropic.MessageStreamEventUnion) {
+		switch variant := event.AsAny().(type) {
+		case anthropic.ContentBlockStartEvent:
+			current = &deltaBuffer{
+				index:     int(variant.Index),
+				blockType: variant.ContentBlock.Type,
+				lastFlush: time.Now(),
+			}
+			if variant.ContentBlock.Type == "tool_use" {
+				current.toolUseID = variant.ContentBlock.ID
+				current.toolName = variant.ContentBlock.Name
+			}
+
+		case anthropic.ContentBlockDeltaEvent:
+			if current == nil {
+				return
+			}
+			switch delta := variant.Delta.AsAny().(type) {
+			case anthropic.TextDelta:
+				current.pending += delta.Text
+			case anthropic.InputJSONDelta:
+				current.pending += delta.PartialJSON
+			default:
+				return
+			}
+			if len(current.pending) >= streamFlushSize || time.Since(current.lastFlush) >= streamFlushInterval {
+				a.flushDelta(runID, current)
+			}
+
+		case anthropic.ContentBlockStopEvent:
+			if current != nil {
+				a.flushDelta(runID, current)
+				current = nil
+			}
+		}
+	})
+}
+
+// flushDelta emits any buffered content for a block as a delta event
+func (a *Agent) flushDelta(runID string, buf *deltaBuffer) {
+	buf.lastFlush = time.Now()
+	if buf.pending == "" {
+		return
+	}
+
+	var evt *events.AgentEvent
+	var err error
+	switch buf.blockType {
+	case "text":
+		evt, err = events.NewTextDeltaEvent(runID, a.name, a.name, events.TextDeltaData{
+			Index: buf.index,
+			Text:  buf.pending,
+		})
+	case "tool_use":
+		evt, err = events.NewToolInputDeltaEvent(runID, a.name, a.name, events.ToolInputDeltaData{
+			Index:       buf.index,
+			ToolUseID:   buf.toolUseID,
+			ToolName:    buf.toolName,
+			PartialJSON: buf.pending,
+		})
+	default:
+		buf.pending = ""
+		return
+	}
+
+	buf.pending = ""
+	if err == nil {
+		a.eventEmitter.Emit(evt)
+	}
+}
@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+// Allowed values of the complete_handoff report fields
+var (
+	handoffReportStatuses    = []string{"completed", "partial", "failed"}
+	handoffArtifactTypes     = []string{"collector", "dashboard", "datasource", "file", "other"}
+	handoffComponentTypes    = []string{"service", "infrastructure", "pipeline", "backend"}
+	handoffComponentStatuses = []string{"success", "failed", "skipped"}
+)
+
+// handoffReportInstructions is appended to a sub-run's task so the sub-agent
+// knows to finish with complete_handoff
+const handoffReportInstructions = `
+
+When you are done, call the complete_handoff tool once with your result: the artifacts you created or changed (collector IDs, dashboard UIDs, files edited), the status of each plan component you worked on, and any follow-ups. Then end your turn.`
+
+// HandoffReporter collects the report a sub-agent submits with complete_handoff
+type HandoffReporter struct {
+	mu     sync.Mutex
+	report *events.HandoffReport
+}
+
+// NewHandoffReporter creates an empty reporter
+func NewHandoffReporter() *HandoffReporter {
+	return &HandoffReporter{}
+}
+
+// Report returns the last submitted report, or nil if none was submitted
+func (r *HandoffReporter) Report() *events.HandoffReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.report
+}
+
+func (r *HandoffReporter) set(report *events.HandoffReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report = report
+}
+
+// CreateCompleteHandoffTool creates the complete_handoff tool, which sub-agents
+// use to return a structured result to the agent that delegated to them
+func CreateCompleteHandoffTool(reporter *HandoffReporter) tools.Tool {
+	schema := anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"status": map[string]interface{}{
+				"type":        "string",
+				"description": "Overall outcome: 'completed' (everything done), 'partial' (some parts done) or 'failed'",
+				"enum":        handoffReportStatuses,
+			},
+			"summary": map[string]interface{}{
+				"type":        "string",
+				"description": "Short summary of what was done",
+			},
+			"artifacts": map[string]interface{}{
+				"type":        "array",
+				"description": "Everything created or changed",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"type": map[string]interface{}{
+							"type": "string",
+							"enum": handoffArtifactTypes,
+						},
+						"id": map[string]interface{}{
+							"type":        "string",
+							"description": "Collector ID, dashboard UID, datasource UID or file path",
+						},
+						"description": map[string]interface{}{
+							"type": "string",
+						},
+					},
+					"required": []string{"type", "id"},
+				},
+			},
+			"components": map[string]interface{}{
+				"type":        "array",
+				"description": "Status of each plan component worked on",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"name": map[string]interface{}{
+							"type":        "string",
+							"description": "Component name, e.g. the service name",
+						},
+						"type": map[string]interface{}{
+							"type": "string",
+							"enum": handoffComponentTypes,
+						},
+						"status": map[string]interface{}{
+							"type": "string",
+							"enum": handoffComponentStatuses,
+						},
+						"details": map[string]interface{}{
+							"type": "string",
+						},
+					},
+					"required": []string{"name", "status"},
+				},
+			},
+			"follow_ups": map[string]interface{}{
+				"type":        "array",
+				"description": "Remaining work or manual steps for the delegating agent or a human",
+				"items":       map[string]interface{}{"type": "string"},
+			},
+		},
+		Required: []string{"status", "summary"},
+	}
+
+	handler := func(inputJSON json.RawMessage) (interface{}, error) {
+		var report events.HandoffReport
+		if err := json.Unmarshal(inputJSON, &report); err != nil {
+			return nil, fmt.Errorf("failed to parse handoff report: %w", err)
+		}
+		if err := ValidateHandoffReport(&report); err != nil {
+			return nil, err
+		}
+
+		reporter.set(&report)
+		return "Result recorded. End your turn now.", nil
+	}
+
+	return tools.Tool{
+		Name:        "complete_handoff",
+		Description: "Return the structured result of the delegated task to the agent that delegated it. Call this once when you are done.",
+		Schema:      schema,
+		Handler:     handler,
+	}
+}
+
+// ValidateHandoffReport checks a report against the complete_handoff schema
+func ValidateHandoffReport(report *events.HandoffReport) error {
+	var problems []string
+
+	if !containsString(handoffReportStatuses, report.Status) {
+		problems = append(problems, fmt.Sprintf("status must be one of %v", handoffReportStatuses))
+	}
+	if strings.TrimSpace(report.Summary) == "" {
+		problems = append(problems, "summary is required")
+	}
+	for i, artifact := range report.Artifacts {
+		if !containsString(handoffArtifactTypes, artifact.Type) {
+			problems = append(problems, fmt.Sprintf("artifacts[%d].type must be one of %v", i, handoffArtifactTypes))
+		}
+		if strings.TrimSpace(artifact.ID) == "" {
+			problems = append(problems, fmt.Sprintf("artifacts[%d].id is required", i))
+		}
+	}
+	for i, component := range report.Components {
+		if strings.TrimSpace(component.Name) == "" {
+			problems = append(problems, fmt.Sprintf("components[%d].name is required", i))
+		}
+		if component.Type != "" && !containsString(handoffComponentTypes, component.Type) {
+			problems = append(problems, fmt.Sprintf("components[%d].type must be one of %v", i, handoffComponentTypes))
+		}
+		if !containsString(handoffComponentStatuses, component.Status) {
+			problems = append(problems, fmt.Sprintf("components[%d].status must be one of %v", i, handoffComponentStatuses))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid handoff report: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+var completeHandoffTestResponses = []string{
+	`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"tool_use",
+	  "content":[{"type":"tool_use","id":"toolu_1","name":"complete_handoff","input":{
+	    "status":"partial","summary":"Deployed the collector",
+	    "artifacts":[{"type":"collector","id":"col-1"}],
+	    "components":[{"name":"user-service","type":"service","status":"success"},{"name":"postgres","type":"infrastructure","status":"skipped"}],
+	    "follow_ups":["Add a postgres receiver"]}}],
+	  "usage":{"input_tokens":10,"output_tokens":5}}`,
+	`{"id":"msg_2","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"end_turn",
+	  "content":[{"type":"text","text":"Done."}],
+	  "usage":{"input_tokens":20,"output_tokens":5}}`,
+}
+
+func TestHandoffReturnsReport(t *testing.T) {
+	stor, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { stor.Close() })
+
+	for _, id := range []string{"run-parent", "run-sub"} {
+		if err := stor.CreateRun(&storage.Run{ID: id, AgentID: "agent", AgentName: "agent", Status: storage.RunStatusRunning, StartTime: time.Now()}); err != nil {
+			t.Fatalf("CreateRun(%s) error = %v", id, err)
+		}
+	}
+
+	hctx := newHandoffTestContext(nil)
+	hctx.Provider = &scriptedProvider{responses: completeHandoffTestResponses}
+	hctx.Storage = stor
+
+	result := runHandoff(context.Background(), hctx, HandoffInput{ToAgentID: "slow", TaskDescription: "deploy"}, "run-sub")
+	if !result.Success || result.Summary != "Deployed the collector" {
+		t.Fatalf("result = %+v, want successful handoff with the report's summary", result)
+	}
+	if result.Report == nil || len(result.Report.Artifacts) != 1 || result.Report.Artifacts[0].ID != "col-1" {
+		t.Fatalf("result.Report = %+v, want the submitted report", result.Report)
+	}
+
+	sub, err := stor.GetRun("run-sub")
+	if err != nil {
+		t.Fatalf("GetRun() error = %v", err)
+	}
+	if sub.HandoffReport == nil || sub.HandoffReport.Status != "partial" || len(sub.HandoffReport.Components) != 2 {
+		t.Errorf("sub-run HandoffReport = %+v, want the submitted report", sub.HandoffReport)
+	}
+}
+
+func TestValidateHandoffReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  events.HandoffReport
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			report: events.HandoffReport{Status: "completed", Summary: "ok", Artifacts: []events.HandoffArtifact{{Type: "dashboard", ID: "abc"}}},
+		},
+		{
+			name:    "unknown status",
+			report:  events.HandoffReport{Status: "done", Summary: "ok"},
+			wantErr: "status must be one of",
+		},
+		{
+			name:    "artifact without id",
+			report:  events.HandoffReport{Status: "completed", Summary: "ok", Artifacts: []events.HandoffArtifact{{Type: "file"}}},
+			wantErr: "artifacts[0].id is required",
+		},
+		{
+			name:    "unknown component status",
+			report:  events.HandoffReport{Status: "failed", Summary: "ok", Components: []events.HandoffComponent{{Name: "api", Status: "pending"}}},
+			wantErr: "components[0].status must be one of",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHandoffReport(&tt.report)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateHandoffReport() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateHandoffReport() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -17,6 +19,7 @@ import (
 type HandoffInput struct {
 	ToAgentID       string `json:"to_agent_id"`
 	TaskDescription string `json:"task_description"`
+	TimeoutSeconds  int    `json:"timeout_seconds,omitempty"` // Optional: overrides the default sub-run timeout
 }
 
 // HandoffContext holds dependencies for handoff execution
@@ -24,10 +27,16 @@ type HandoffContext struct {
 	ParentRunID   string
 	ParentAgentID string
 	Registry      *Registry
-	Client        *anthropic.Client
+	Provider      Provider
 	LogLevel      config.LogLevel
 	EventEmitter  events.EventEmitter
 	Storage       storage.Storage
+	Streaming     bool
+	Budget        *BudgetTracker // Shared with sub-runs so they count against the parent budget
+	Timeout       time.Duration  // Default limit per sub-run (0 is unlimited)
+	RunTracker    RunTracker     // Optional: registers sub-runs so they can be stopped by ID
+
+	HumanInputTimeout time.Duration
 }
 
 // CreateHandoffTool creates the handoff tool with context
@@ -42,38 +51,75 @@ func CreateHandoffTool(ctx *HandoffContext) tools.Tool {
 				"type":        "string",
 				"description": "Clear description of the task to delegate to the sub-agent",
 			},
+			"timeout_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional: Stop the sub-agent if it has not finished within this many seconds",
+			},
 		},
 		Required: []string{"to_agent_id", "task_description"},
 	}
 
-	handler := func(inputJSON json.RawMessage) (interface{}, error) {
+	handler := func(toolCtx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 		var input HandoffInput
 		if err := json.Unmarshal(inputJSON, &input); err != nil {
 			return nil, fmt.Errorf("failed to parse handoff input: %w", err)
 		}
 
-		return executeHandoff(ctx, input)
+		return executeHandoff(toolCtx, ctx, input)
 	}
 
 	return tools.Tool{
-		Name:        "handoff_task",
-		Description: "Delegate a task to another specialized agent. The current agent will pause until the sub-agent completes. Use this when a task requires expertise from a different agent type.",
-		Schema:      schema,
-		Handler:     handler,
+		Name:           "handoff_task",
+		Serial:         true,
+		Description:    "Delegate a task to another specialized agent. The current agent will pause until the sub-agent completes. Use this when a task requires expertise from a different agent type.",
+		Schema:         schema,
+		ContextHandler: handler,
 	}
 }
 
+// HandoffResult is the outcome of one sub-run, returned to the parent agent
+type HandoffResult struct {
+	Success  bool   `json:"success"`
+	Summary  string `json:"summary"`
+	SubRunID string `json:"sub_run_id"`
+	AgentID  string `json:"agent_id"`
+	Duration string `json:"duration"`
+	Error    string `json:"error"`
+
+	// Report is the sub-agent's structured result. It is nil if the sub-agent
+	// finished without calling complete_handoff.
+	Report *events.HandoffReport `json:"report,omitempty"`
+}
+
 // executeHandoff runs the sub-agent and returns results
-func executeHandoff(ctx *HandoffContext, input HandoffInput) (interface{}, error) {
-	// 1. Validate target agent exists
-	if !ctx.Registry.Has(input.ToAgentID) {
-		return nil, fmt.Errorf("agent not found: %s", input.ToAgentID)
+func executeHandoff(parentCtx context.Context, ctx *HandoffContext, input HandoffInput) (interface{}, error) {
+	if err := ctx.checkTarget(input.ToAgentID); err != nil {
+		return nil, err
 	}
 
-	// 2. Generate sub-run ID
 	subRunID := fmt.Sprintf("run-%d", time.Now().UnixNano())
+	return runHandoff(parentCtx, ctx, input, subRunID), nil
+}
+
+// checkTarget validates that the target agent exists and that the parent
+// agent's HandoffTargets, if any, allow it
+func (ctx *HandoffContext) checkTarget(agentID string) error {
+	if !ctx.Registry.Has(agentID) {
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
 
-	// 3. Emit handoff start event
+	if parent, ok := ctx.Registry.Get(ctx.ParentAgentID); ok && len(parent.HandoffTargets) > 0 {
+		if !containsString(parent.HandoffTargets, agentID) {
+			return fmt.Errorf("agent %s may only hand off to %v", ctx.ParentAgentID, parent.HandoffTargets)
+		}
+	}
+	return nil
+}
+
+// runHandoff runs one sub-agent to completion. The sub-run's context derives
+// from the parent's, so stopping the parent stops the whole tree.
+func runHandoff(parentCtx context.Context, ctx *HandoffContext, input HandoffInput, subRunID string) *HandoffResult {
+	// 1. Emit handoff start event
 	if evt, err := events.NewAgentHandoffEvent(ctx.ParentRunID, ctx.ParentAgentID,
 		ctx.ParentAgentID, events.HandoffData{
 		ParentRunID:     ctx.ParentRunID,
@@ -85,30 +131,75 @@ func executeHandoff(ctx *HandoffContext, input HandoffInput) (interface{}, error
 		ctx.EventEmitter.Emit(evt)
 	}
 
-	// 4. Create and run sub-agent (blocking)
+	// 2. Create and run sub-agent (blocking) under the parent's context
+	timeout := ctx.Timeout
+	if input.TimeoutSeconds > 0 {
+		timeout = time.Duration(input.TimeoutSeconds) * time.Second
+	}
+
+	var subCtx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		subCtx, cancel = context.WithTimeout(parentCtx, timeout)
+	} else {
+		subCtx, cancel = context.WithCancel(parentCtx)
+	}
+	defer cancel()
+
+	if ctx.RunTracker != nil {
+		ctx.RunTracker.Add(subRunID, subCtx, cancel)
+		defer ctx.RunTracker.Remove(subRunID)
+	}
+
+	// Reference the sub-run from the parent while it runs, so in-flight
+	// children are listed and a crash leaves none unreferenced
+	if ctx.Storage != nil {
+		updateParentWithSubRun(ctx.Storage, ctx.ParentRunID, subRunID)
+	}
+
+	reporter := NewHandoffReporter()
+
 	startTime := time.Now()
-	result, err := ctx.Registry.RunAgent(context.Background(), RunnerConfig{
+	result, err := ctx.Registry.RunAgent(subCtx, RunnerConfig{
 		AgentID:      input.ToAgentID,
-		Prompt:       input.TaskDescription,
-		Client:       ctx.Client,
+		Prompt:       input.TaskDescription + handoffReportInstructions,
+		Provider:     ctx.Provider,
 		LogLevel:     ctx.LogLevel,
 		EventEmitter: ctx.EventEmitter,
-		RunID:        subRunID,
+		NewRunID:     subRunID,
+		ParentRunID:  ctx.ParentRunID,
 		Storage:      ctx.Storage,
+		Streaming:    ctx.Streaming,
+
+		BudgetTracker:     ctx.Budget,
+		HandoffTimeout:    ctx.Timeout,
+		RunTracker:        ctx.RunTracker,
+		HumanInputTimeout: ctx.HumanInputTimeout,
+		HandoffReporter:   reporter,
 	})
 
 	duration := time.Since(startTime)
 
-	// 5. Extract summary from result
+	// A sub-run that hit its own deadline reports it rather than a bare context error
+	if errors.Is(subCtx.Err(), context.DeadlineExceeded) && parentCtx.Err() == nil {
+		err = fmt.Errorf("sub-run timed out after %s", timeout)
+	}
+
+	// 3. Take the summary from the sub-agent's report, or its final message
+	report := reporter.Report()
 	summary := extractSummary(result)
-	success := result != nil && result.Success
+	success := result != nil && result.Success && err == nil
+	if report != nil {
+		summary = report.Summary
+		success = success && report.Status != "failed"
+	}
 
-	// 6. Update parent run with sub-run reference
-	if ctx.Storage != nil {
-		updateParentWithSubRun(ctx.Storage, ctx.ParentRunID, subRunID)
+	// 4. Store the report on the sub-run
+	if ctx.Storage != nil && report != nil {
+		ctx.Storage.UpdateRun(subRunID, &storage.RunUpdate{HandoffReport: report})
 	}
 
-	// 7. Emit handoff complete event
+	// 5. Emit handoff complete event
 	if evt, e := events.NewAgentHandoffCompleteEvent(ctx.ParentRunID, ctx.ParentAgentID,
 		ctx.ParentAgentID, events.HandoffCompleteData{
 		ParentRunID: ctx.ParentRunID,
@@ -119,19 +210,21 @@ func executeHandoff(ctx *HandoffContext, input HandoffInput) (interface{}, error
 		Summary:     summary,
 		Error:       getErrorString(result, err),
 		Duration:    duration.String(),
+		Report:      report,
 	}); e == nil {
 		ctx.EventEmitter.Emit(evt)
 	}
 
-	// 8. Return summary to parent agent
-	return map[string]interface{}{
-		"success":    success,
-		"summary":    summary,
-		"sub_run_id": subRunID,
-		"agent_id":   input.ToAgentID,
-		"duration":   duration.String(),
-		"error":      getErrorString(result, err),
-	}, nil
+	// 6. Return summary to parent agent
+	return &HandoffResult{
+		Success:  success,
+		Summary:  summary,
+		SubRunID: subRunID,
+		AgentID:  input.ToAgentID,
+		Duration: duration.String(),
+		Error:    getErrorString(result, err),
+		Report:   report,
+	}
 }
 
 // extractSummary extracts a summary from the sub-agent's final result
@@ -161,8 +254,15 @@ func getErrorString(result *RunResult, err error) string {
 	return ""
 }
 
+// subRunIDsMu serializes read-modify-write updates of parent SubRunIDs, which
+// parallel handoffs make concurrently
+var subRunIDsMu sync.Mutex
+
 // updateParentWithSubRun updates the parent run to include the sub-run ID
 func updateParentWithSubRun(stor storage.Storage, parentRunID, subRunID string) {
+	subRunIDsMu.Lock()
+	defer subRunIDsMu.Unlock()
+
 	// Fetch parent, update sub_run_ids, save
 	parent, err := stor.GetRun(parentRunID)
 	if err != nil {
@@ -170,6 +270,9 @@ func updateParentWithSubRun(stor storage.Storage, parentRunID, subRunID string)
 	}
 
 	// Append sub_run_id if not already in list
+	if containsString(parent.SubRunIDs, subRunID) {
+		return
+	}
 	subRunIDs := make([]string, len(parent.SubRunIDs))
 	copy(subRunIDs, parent.SubRunIDs)
 	subRunIDs = append(subRunIDs, subRunID)
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+// ParallelHandoffAgents are the agents handoff_parallel may fan out to. Their
+// tasks are independent per component, unlike the coordinating agents.
+var ParallelHandoffAgents = []string{"instrumentation", "infrastructure", "pipeline", "backend"}
+
+const (
+	defaultHandoffConcurrency = 4  // Sub-runs at once when max_concurrency is not set
+	maxHandoffConcurrency     = 10 // Upper bound for max_concurrency
+)
+
+// ParallelHandoffInput defines input for the handoff_parallel tool
+type ParallelHandoffInput struct {
+	Tasks          []HandoffInput `json:"tasks"`
+	MaxConcurrency int            `json:"max_concurrency,omitempty"`
+}
+
+// ParallelHandoffResult aggregates the sub-runs of a handoff_parallel call.
+// Results are in task order.
+type ParallelHandoffResult struct {
+	Results   []*HandoffResult `json:"results"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Duration  string           `json:"duration"`
+}
+
+// CreateParallelHandoffTool creates the handoff_parallel tool with context
+func CreateParallelHandoffTool(ctx *HandoffContext) tools.Tool {
+	schema := anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"tasks": map[string]interface{}{
+				"type":        "array",
+				"description": "Independent tasks to run at the same time, one sub-agent each",
+				"minItems":    1,
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"to_agent_id": map[string]interface{}{
+							"type":        "string",
+							"description": "ID of the agent to delegate this task to",
+							"enum":        ParallelHandoffAgents,
+						},
+						"task_description": map[string]interface{}{
+							"type":        "string",
+							"description": "Clear description of the task to delegate to the sub-agent",
+						},
+						"timeout_seconds": map[string]interface{}{
+							"type":        "integer",
+							"description": "Optional: Stop this sub-agent if it has not finished within this many seconds",
+						},
+					},
+					"required": []string{"to_agent_id", "task_description"},
+				},
+			},
+			"max_concurrency": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Optional: How many sub-agents run at once (default %d, max %d)", defaultHandoffConcurrency, maxHandoffConcurrency),
+			},
+		},
+		Required: []string{"tasks"},
+	}
+
+	handler := func(toolCtx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+		var input ParallelHandoffInput
+		if err := json.Unmarshal(inputJSON, &input); err != nil {
+			return nil, fmt.Errorf("failed to parse parallel handoff input: %w", err)
+		}
+
+		return executeParallelHandoff(toolCtx, ctx, input)
+	}
+
+	return tools.Tool{
+		Name:           "handoff_parallel",
+		Serial:         true,
+		Description:    "Delegate several independent tasks to the instrumentation, infrastructure, pipeline or backend agents at once. The current agent pauses until all sub-agents finish and gets one result per task. Use this instead of repeated handoff_task calls when tasks do not depend on each other, e.g. instrumenting several services.",
+		Schema:         schema,
+		ContextHandler: handler,
+	}
+}
+
+// executeParallelHandoff runs the tasks' sub-agents with a concurrency cap
+func executeParallelHandoff(parentCtx context.Context, ctx *HandoffContext, input ParallelHandoffInput) (interface{}, error) {
+	if len(input.Tasks) == 0 {
+		return nil, fmt.Errorf("at least one task is required")
+	}
+
+	// Validate every task before starting any sub-run
+	for i, task := range input.Tasks {
+		if !isParallelHandoffAgent(task.ToAgentID) {
+			return nil, fmt.Errorf("task %d: agent %q cannot be used with handoff_parallel (allowed: %v)", i+1, task.ToAgentID, ParallelHandoffAgents)
+		}
+		if err := ctx.checkTarget(task.ToAgentID); err != nil {
+			return nil, fmt.Errorf("task %d: %w", i+1, err)
+		}
+		if task.TaskDescription == "" {
+			return nil, fmt.Errorf("task %d: task_description is required", i+1)
+		}
+	}
+
+	concurrency := input.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultHandoffConcurrency
+	}
+	if concurrency > maxHandoffConcurrency {
+		concurrency = maxHandoffConcurrency
+	}
+
+	startTime := time.Now()
+	results := make([]*HandoffResult, len(input.Tasks))
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, task := range input.Tasks {
+		// IDs are generated up front so tasks started together cannot collide
+		subRunID := fmt.Sprintf("run-%d-%d", startTime.UnixNano(), i+1)
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, task HandoffInput) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if parentCtx.Err() != nil {
+				results[i] = &HandoffResult{AgentID: task.ToAgentID, Summary: "Not started", Error: "parent run was stopped"}
+				return
+			}
+			results[i] = runHandoff(parentCtx, ctx, task, subRunID)
+		}(i, task)
+	}
+
+	wg.Wait()
+
+	result := &ParallelHandoffResult{
+		Results:  results,
+		Duration: time.Since(startTime).String(),
+	}
+	for _, r := range results {
+		if r.Success {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+	}
+
+	return result, nil
+}
+
+// isParallelHandoffAgent reports whether handoff_parallel may target an agent
+func isParallelHandoffAgent(agentID string) bool {
+	return containsString(ParallelHandoffAgents, agentID)
+}
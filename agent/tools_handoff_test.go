@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+	"github.com/mottibechhofer/otel-ai-engineer/config"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+// blockingProvider never answers; it returns once the request context is done
+type blockingProvider struct{}
+
+func (blockingProvider) Name() string { return "blocking" }
+
+func (blockingProvider) CreateMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// recordingTracker records which runs were registered and are still active
+type recordingTracker struct {
+	mu     sync.Mutex
+	added  []string
+	active map[string]context.CancelFunc
+}
+
+func (t *recordingTracker) Add(runID string, ctx context.Context, cancel context.CancelFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.added = append(t.added, runID)
+	t.active[runID] = cancel
+}
+
+func (t *recordingTracker) Remove(runID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.active, runID)
+}
+
+// newHandoffTestContext creates a handoff context whose only target agent blocks
+func newHandoffTestContext(tracker RunTracker) *HandoffContext {
+	registry := &Registry{agents: make(map[string]AgentInfo), factories: make(map[string]AgentFactory)}
+	registry.Register(AgentInfo{ID: "slow", Name: "Slow"}, func(provider Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*Agent, error) {
+		return NewAgent(Config{Name: "slow", Provider: provider, LogLevel: logLevel, EventEmitter: emitter, Retry: &RetryPolicy{}}), nil
+	})
+
+	return &HandoffContext{
+		ParentRunID:   "run-parent",
+		ParentAgentID: "parent",
+		Registry:      registry,
+		Provider:      blockingProvider{},
+		LogLevel:      config.LogLevelSilent,
+		EventEmitter:  events.NewEmitter(),
+		RunTracker:    tracker,
+	}
+}
+
+func TestHandoffCancelledWithParent(t *testing.T) {
+	tracker := &recordingTracker{active: make(map[string]context.CancelFunc)}
+	hctx := newHandoffTestContext(tracker)
+
+	parentCtx, cancelParent := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancelParent)
+
+	done := make(chan *HandoffResult, 1)
+	go func() {
+		result, _ := executeHandoff(parentCtx, hctx, HandoffInput{ToAgentID: "slow", TaskDescription: "wait"})
+		done <- result.(*HandoffResult)
+	}()
+
+	select {
+	case result := <-done:
+		if result.Success {
+			t.Errorf("result = %v, want unsuccessful sub-run", result)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("sub-run kept running after the parent was cancelled")
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if len(tracker.added) != 1 || len(tracker.active) != 0 {
+		t.Errorf("tracker added %v with %d still active, want one sub-run registered and removed", tracker.added, len(tracker.active))
+	}
+}
+
+func TestHandoffListsRunningSubRun(t *testing.T) {
+	tracker := &recordingTracker{active: make(map[string]context.CancelFunc)}
+	hctx := newHandoffTestContext(tracker)
+	stor, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { stor.Close() })
+	if err := stor.CreateRun(&storage.Run{ID: "run-parent", AgentID: "parent", AgentName: "parent", Status: storage.RunStatusRunning, StartTime: time.Now()}); err != nil {
+		t.Fatalf("CreateRun() error = %v", err)
+	}
+	hctx.Storage = stor
+
+	parentCtx, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+	done := make(chan *HandoffResult, 1)
+	go func() {
+		result, _ := executeHandoff(parentCtx, hctx, HandoffInput{ToAgentID: "slow", TaskDescription: "wait"})
+		done <- result.(*HandoffResult)
+	}()
+
+	// The parent lists the sub-run while it is still running
+	var subRunID string
+	for deadline := time.Now().Add(2 * time.Second); subRunID == "" && time.Now().Before(deadline); {
+		tracker.mu.Lock()
+		for id := range tracker.active {
+			subRunID = id
+		}
+		tracker.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if subRunID == "" {
+		t.Fatal("the sub-run never started")
+	}
+	parent, err := stor.GetRun("run-parent")
+	if err != nil {
+		t.Fatalf("GetRun() error = %v", err)
+	}
+	if len(parent.SubRunIDs) != 1 || parent.SubRunIDs[0] != subRunID {
+		t.Errorf("parent sub-runs = %v while %s runs, want it listed", parent.SubRunIDs, subRunID)
+	}
+
+	cancelParent()
+	result := <-done
+	parent, _ = stor.GetRun("run-parent")
+	if len(parent.SubRunIDs) != 1 || parent.SubRunIDs[0] != result.SubRunID {
+		t.Errorf("parent sub-runs = %v after the sub-run ended, want only %s", parent.SubRunIDs, result.SubRunID)
+	}
+}
+
+func TestHandoffTimeout(t *testing.T) {
+	hctx := newHandoffTestContext(nil)
+	hctx.Timeout = 20 * time.Millisecond
+
+	result, err := executeHandoff(context.Background(), hctx, HandoffInput{ToAgentID: "slow", TaskDescription: "wait"})
+	if err != nil {
+		t.Fatalf("executeHandoff() error = %v", err)
+	}
+
+	got := result.(*HandoffResult)
+	if got.Success || !strings.Contains(got.Error, "timed out") {
+		t.Errorf("result = %v, want timed out sub-run", got)
+	}
+}
+
+func TestParallelHandoff(t *testing.T) {
+	tracker := &recordingTracker{active: make(map[string]context.CancelFunc)}
+	hctx := newHandoffTestContext(tracker)
+	hctx.Timeout = 20 * time.Millisecond
+	hctx.Registry.Register(AgentInfo{ID: "instrumentation", Name: "Instrumentation"}, hctx.Registry.factories["slow"])
+
+	if _, err := executeParallelHandoff(context.Background(), hctx, ParallelHandoffInput{
+		Tasks: []HandoffInput{{ToAgentID: "slow", TaskDescription: "wait"}},
+	}); err == nil {
+		t.Error("executeParallelHandoff() accepted an agent outside ParallelHandoffAgents")
+	}
+	if len(tracker.added) != 0 {
+		t.Fatalf("tracker added %v, want no sub-run started for invalid tasks", tracker.added)
+	}
+
+	result, err := executeParallelHandoff(context.Background(), hctx, ParallelHandoffInput{
+		Tasks: []HandoffInput{
+			{ToAgentID: "instrumentation", TaskDescription: "a"},
+			{ToAgentID: "instrumentation", TaskDescription: "b"},
+			{ToAgentID: "instrumentation", TaskDescription: "c"},
+		},
+		MaxConcurrency: 2,
+	})
+	if err != nil {
+		t.Fatalf("executeParallelHandoff() error = %v", err)
+	}
+
+	got := result.(*ParallelHandoffResult)
+	if len(got.Results) != 3 || got.Failed != 3 || got.Succeeded != 0 {
+		t.Fatalf("result = %+v, want 3 failed sub-runs", got)
+	}
+	for i, r := range got.Results {
+		if want := "-" + string(rune('1'+i)); !strings.HasSuffix(r.SubRunID, want) {
+			t.Errorf("Results[%d].SubRunID = %q, want suffix %q", i, r.SubRunID, want)
+		}
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if len(tracker.added) != 3 || len(tracker.active) != 0 {
+		t.Errorf("tracker added %v with %d still active, want three sub-runs registered and removed", tracker.added, len(tracker.active))
+	}
+}
+
+func TestHandoffTargetsRestrictHandoff(t *testing.T) {
+	hctx := newHandoffTestContext(nil)
+	hctx.Registry.Register(AgentInfo{ID: "parent", Name: "Parent", HandoffTargets: []string{"pipeline"}}, hctx.Registry.factories["slow"])
+
+	_, err := executeHandoff(context.Background(), hctx, HandoffInput{ToAgentID: "slow", TaskDescription: "wait"})
+	if err == nil || !strings.Contains(err.Error(), "may only hand off to") {
+		t.Errorf("executeHandoff() error = %v, want handoff target error", err)
+	}
+}
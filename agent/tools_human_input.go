@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -11,24 +12,34 @@ import (
 	"github.com/mottibechhofer/otel-ai-engineer/tools"
 )
 
+// DefaultHumanInputTimeout is how long request_human_input waits for a
+// response when neither the run nor the request sets a timeout
+const DefaultHumanInputTimeout = 30 * time.Minute
+
+// humanInputPollInterval is how often a waiting request checks storage for a response
+var humanInputPollInterval = 2 * time.Second
+
 // HumanInputContext holds dependencies for human input requests
 type HumanInputContext struct {
-	RunID       string
-	AgentID     string
-	AgentName   string
-	Storage     storage.Storage
+	RunID        string
+	AgentID      string
+	AgentName    string
+	Storage      storage.Storage
 	EventEmitter events.EventEmitter
 	ResourceType *storage.ResourceType
 	ResourceID   *string
 	AgentWorkID  *string
+	Timeout      time.Duration // Default wait for a response (0 uses DefaultHumanInputTimeout)
 }
 
 // HumanInputRequest defines input for the request_human_input tool
 type HumanInputRequest struct {
-	RequestType string   `json:"request_type"` // "approval", "input", "decision", "information"
-	Question    string   `json:"question"`    // The question or request
-	Context     string   `json:"context"`     // Additional context
-	Options     []string `json:"options,omitempty"` // Optional: predefined choices
+	RequestType     string   `json:"request_type"`               // "approval", "input", "decision", "information"
+	Question        string   `json:"question"`                   // The question or request
+	Context         string   `json:"context"`                    // Additional context
+	Options         []string `json:"options,omitempty"`          // Optional: predefined choices
+	TimeoutSeconds  int      `json:"timeout_seconds,omitempty"`  // Optional: overrides the default wait
+	DefaultResponse string   `json:"default_response,omitempty"` // Optional: answer to use if nobody responds in time
 }
 
 // CreateHumanInputTool creates the request_human_input tool with context
@@ -53,46 +64,83 @@ func CreateHumanInputTool(ctx *HumanInputContext) tools.Tool {
 				"items":       map[string]interface{}{"type": "string"},
 				"description": "Optional: Predefined options for 'decision' type requests",
 			},
+			"timeout_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional: How long to wait for a response before giving up",
+			},
+			"default_response": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional: Answer to use if nobody responds before the timeout",
+			},
 		},
 		Required: []string{"request_type", "question", "context"},
 	}
 
-	handler := func(inputJSON json.RawMessage) (interface{}, error) {
+	handler := func(toolCtx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 		var input HumanInputRequest
 		if err := json.Unmarshal(inputJSON, &input); err != nil {
 			return nil, fmt.Errorf("failed to parse human input request: %w", err)
 		}
 
-		return executeHumanInputRequest(ctx, input)
+		return executeHumanInputRequest(toolCtx, ctx, input)
 	}
 
 	return tools.Tool{
-		Name:        "request_human_input",
-		Description: "Request manual intervention or input from a human. The agent will pause until a human responds. Use this when you need approval, additional information, or a decision that requires human judgment.",
-		Schema:      schema,
-		Handler:     handler,
+		Name:           "request_human_input",
+		Serial:         true,
+		Description:    "Request manual intervention or input from a human. The agent will pause until a human responds or the request times out, and the response is returned as the result. Use this when you need approval, additional information, or a decision that requires human judgment.",
+		Schema:         schema,
+		ContextHandler: handler,
+	}
+}
+
+// RequestHumanInput asks a human for input on behalf of a tool other than
+// request_human_input, e.g. to approve what the tool is about to do. It
+// blocks like request_human_input and returns the same result.
+func RequestHumanInput(toolCtx context.Context, ctx *HumanInputContext, input HumanInputRequest) (map[string]interface{}, error) {
+	result, err := executeHumanInputRequest(toolCtx, ctx, input)
+	if err != nil {
+		return nil, err
 	}
+	return result.(map[string]interface{}), nil
 }
 
-// executeHumanInputRequest creates a human action and pauses the agent
-func executeHumanInputRequest(ctx *HumanInputContext, input HumanInputRequest) (interface{}, error) {
+// executeHumanInputRequest creates a human action and waits for its response.
+// The action records the tool_use ID, so if the run stops while waiting (e.g.
+// the server restarts) a later response resumes the run from its checkpoint
+// with the response as this call's result.
+func executeHumanInputRequest(toolCtx context.Context, ctx *HumanInputContext, input HumanInputRequest) (interface{}, error) {
 	// Generate action ID
 	actionID := fmt.Sprintf("action-%d", time.Now().UnixNano())
 
+	timeout := ctx.Timeout
+	if input.TimeoutSeconds > 0 {
+		timeout = time.Duration(input.TimeoutSeconds) * time.Second
+	}
+	if timeout <= 0 {
+		timeout = DefaultHumanInputTimeout
+	}
+	expiresAt := time.Now().Add(timeout)
+
 	// Create human action entry
 	action := &storage.HumanAction{
 		ID:           actionID,
 		RunID:        ctx.RunID,
-		AgentID:     ctx.AgentID,
-		AgentName:   ctx.AgentName,
+		AgentID:      ctx.AgentID,
+		AgentName:    ctx.AgentName,
 		ResourceType: ctx.ResourceType,
-		ResourceID:  ctx.ResourceID,
-		AgentWorkID: ctx.AgentWorkID,
-		RequestType: input.RequestType,
-		Question:    input.Question,
-		Context:     input.Context,
-		Options:     input.Options,
-		Status:      storage.HumanActionStatusPending,
+		ResourceID:   ctx.ResourceID,
+		AgentWorkID:  ctx.AgentWorkID,
+		RequestType:  input.RequestType,
+		Question:     input.Question,
+		Context:      input.Context,
+		Options:      input.Options,
+		ToolUseID:    tools.ToolUseIDFromContext(toolCtx),
+		ExpiresAt:    &expiresAt,
+		Status:       storage.HumanActionStatusPending,
+	}
+	if input.DefaultResponse != "" {
+		action.DefaultResponse = &input.DefaultResponse
 	}
 
 	if err := ctx.Storage.CreateHumanAction(action); err != nil {
@@ -112,12 +160,85 @@ func executeHumanInputRequest(ctx *HumanInputContext, input HumanInputRequest) (
 		ctx.EventEmitter.Emit(evt)
 	}
 
-	// Return information about the pending request
-	// The agent will need to wait (via resume) for the response
-	return map[string]interface{}{
-		"action_id":    actionID,
-		"status":       "pending",
-		"message":      "Human input requested. The agent will pause and wait for a human response. Use the resume endpoint with the response to continue.",
-	}, nil
+	return waitForHumanResponse(toolCtx, ctx.Storage, action)
+}
+
+// waitForHumanResponse polls storage until the action is responded to, expires
+// or is cancelled, or the run stops
+func waitForHumanResponse(toolCtx context.Context, stor storage.Storage, action *storage.HumanAction) (interface{}, error) {
+	ticker := time.NewTicker(humanInputPollInterval)
+	defer ticker.Stop()
+
+	timer := time.NewTimer(time.Until(*action.ExpiresAt))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-toolCtx.Done():
+			return nil, fmt.Errorf("run stopped while waiting for human input; responding to action %s resumes the run", action.ID)
+
+		case <-timer.C:
+			// Expire only if still pending, so a response that lands at the
+			// deadline isn't overwritten by the default
+			status := storage.HumanActionStatusExpired
+			pending := storage.HumanActionStatusPending
+			update := &storage.HumanActionUpdate{Status: &status, Response: action.DefaultResponse, IfStatus: &pending}
+			if err := stor.UpdateHumanAction(action.ID, update); err != nil {
+				return nil, fmt.Errorf("failed to expire human action: %w", err)
+			}
+
+			current, err := stor.GetHumanAction(action.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get human action: %w", err)
+			}
+			return finishHumanInput(stor, current)
+
+		case <-ticker.C:
+			current, err := stor.GetHumanAction(action.ID)
+			if err != nil || current.Status == storage.HumanActionStatusPending {
+				continue
+			}
+			return finishHumanInput(stor, current)
+		}
+	}
+}
+
+// finishHumanInput returns the result for an action that is no longer
+// pending, marking responded actions as resumed
+func finishHumanInput(stor storage.Storage, action *storage.HumanAction) (interface{}, error) {
+	switch action.Status {
+	case storage.HumanActionStatusCancelled:
+		return nil, fmt.Errorf("human input request %s was cancelled", action.ID)
+	case storage.HumanActionStatusResponded:
+		status := storage.HumanActionStatusResumed
+		if err := stor.UpdateHumanAction(action.ID, &storage.HumanActionUpdate{Status: &status}); err != nil {
+			return nil, fmt.Errorf("failed to update human action: %w", err)
+		}
+	}
+	return HumanInputResult(action), nil
 }
 
+// HumanInputResult is the request_human_input result for an answered or
+// expired action. Resuming a stopped run uses it as the tool result too.
+func HumanInputResult(action *storage.HumanAction) map[string]interface{} {
+	result := map[string]interface{}{
+		"action_id": action.ID,
+	}
+
+	if action.Status == storage.HumanActionStatusExpired {
+		result["status"] = "expired"
+		if action.Response != nil {
+			result["response"] = *action.Response
+			result["message"] = "No human responded before the timeout; using the default response."
+		} else {
+			result["message"] = "No human responded before the timeout. Proceed without the input or ask again."
+		}
+		return result
+	}
+
+	result["status"] = "responded"
+	if action.Response != nil {
+		result["response"] = *action.Response
+	}
+	return result
+}
@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+// newHumanInputTestContext creates a human input context backed by a fresh
+// SQLite database with one run
+func newHumanInputTestContext(t *testing.T, timeout time.Duration) *HumanInputContext {
+	t.Helper()
+
+	stor, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { stor.Close() })
+
+	if err := stor.CreateRun(&storage.Run{ID: "run-1", AgentID: "agent", AgentName: "agent", Status: storage.RunStatusRunning, StartTime: time.Now()}); err != nil {
+		t.Fatalf("CreateRun() error = %v", err)
+	}
+
+	previous := humanInputPollInterval
+	humanInputPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { humanInputPollInterval = previous })
+
+	return &HumanInputContext{
+		RunID:        "run-1",
+		AgentID:      "agent",
+		AgentName:    "agent",
+		Storage:      stor,
+		EventEmitter: events.NewEmitter(),
+		Timeout:      timeout,
+	}
+}
+
+func TestHumanInputWaitsForResponse(t *testing.T) {
+	hctx := newHumanInputTestContext(t, time.Minute)
+
+	done := make(chan map[string]interface{}, 1)
+	go func() {
+		result, err := executeHumanInputRequest(tools.WithToolUseID(context.Background(), "toolu_1"), hctx, HumanInputRequest{
+			RequestType: "approval",
+			Question:    "Deploy?",
+			Context:     "Staging is green",
+		})
+		if err != nil {
+			t.Errorf("executeHumanInputRequest() error = %v", err)
+		}
+		got, _ := result.(map[string]interface{})
+		done <- got
+	}()
+
+	var action *storage.HumanAction
+	for deadline := time.Now().Add(2 * time.Second); action == nil && time.Now().Before(deadline); {
+		if pending, _ := hctx.Storage.GetPendingHumanActions(); len(pending) == 1 {
+			action = pending[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if action == nil {
+		t.Fatal("no pending human action was created")
+	}
+	if action.ToolUseID != "toolu_1" {
+		t.Errorf("action tool_use_id = %q, want toolu_1", action.ToolUseID)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("request returned before a response")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	status := storage.HumanActionStatusResponded
+	response := "yes"
+	if err := hctx.Storage.UpdateHumanAction(action.ID, &storage.HumanActionUpdate{Status: &status, Response: &response}); err != nil {
+		t.Fatalf("UpdateHumanAction() error = %v", err)
+	}
+
+	select {
+	case result := <-done:
+		if result["status"] != "responded" || result["response"] != "yes" {
+			t.Errorf("result = %v, want responded with yes", result)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request did not return after the response")
+	}
+
+	updated, err := hctx.Storage.GetHumanAction(action.ID)
+	if err != nil {
+		t.Fatalf("GetHumanAction() error = %v", err)
+	}
+	if updated.Status != storage.HumanActionStatusResumed {
+		t.Errorf("action status = %s, want resumed", updated.Status)
+	}
+}
+
+func TestHumanInputDefaultOnTimeout(t *testing.T) {
+	hctx := newHumanInputTestContext(t, 30*time.Millisecond)
+
+	result, err := executeHumanInputRequest(context.Background(), hctx, HumanInputRequest{
+		RequestType:     "decision",
+		Question:        "Which backend?",
+		Context:         "Both are configured",
+		DefaultResponse: "jaeger",
+	})
+	if err != nil {
+		t.Fatalf("executeHumanInputRequest() error = %v", err)
+	}
+
+	got := result.(map[string]interface{})
+	if got["status"] != "expired" || got["response"] != "jaeger" {
+		t.Errorf("result = %v, want expired with default response", got)
+	}
+
+	actions, err := hctx.Storage.GetHumanActionsByRun("run-1")
+	if err != nil || len(actions) != 1 {
+		t.Fatalf("GetHumanActionsByRun() = %v, %v", actions, err)
+	}
+	if actions[0].Status != storage.HumanActionStatusExpired {
+		t.Errorf("action status = %s, want expired", actions[0].Status)
+	}
+}
+
+func TestHumanInputResponseAtTimeout(t *testing.T) {
+	hctx := newHumanInputTestContext(t, time.Minute)
+	humanInputPollInterval = time.Hour // only the timeout sees the response
+
+	expiresAt := time.Now().Add(20 * time.Millisecond)
+	fallback := "jaeger"
+	action := &storage.HumanAction{
+		ID:              "action-1",
+		RunID:           "run-1",
+		AgentID:         "agent",
+		AgentName:       "agent",
+		RequestType:     "decision",
+		Question:        "Which backend?",
+		DefaultResponse: &fallback,
+		ExpiresAt:       &expiresAt,
+		Status:          storage.HumanActionStatusPending,
+	}
+	if err := hctx.Storage.CreateHumanAction(action); err != nil {
+		t.Fatalf("CreateHumanAction() error = %v", err)
+	}
+
+	status := storage.HumanActionStatusResponded
+	response := "tempo"
+	if err := hctx.Storage.UpdateHumanAction(action.ID, &storage.HumanActionUpdate{Status: &status, Response: &response}); err != nil {
+		t.Fatalf("UpdateHumanAction() error = %v", err)
+	}
+
+	result, err := waitForHumanResponse(context.Background(), hctx.Storage, action)
+	if err != nil {
+		t.Fatalf("waitForHumanResponse() error = %v", err)
+	}
+	got := result.(map[string]interface{})
+	if got["status"] != "responded" || got["response"] != "tempo" {
+		t.Errorf("result = %v, want the human response", got)
+	}
+
+	stored, err := hctx.Storage.GetHumanAction(action.ID)
+	if err != nil {
+		t.Fatalf("GetHumanAction() error = %v", err)
+	}
+	if stored.Status != storage.HumanActionStatusResumed || stored.Response == nil || *stored.Response != "tempo" {
+		t.Errorf("stored action = %s %v, want resumed with the human response", stored.Status, stored.Response)
+	}
+}
@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strings"
+	"time"
 )
 
 // LogLevel represents the logging level
@@ -50,9 +52,29 @@ func ParseLogLevel(s string) LogLevel {
 
 // Config holds the application configuration
 type Config struct {
-	// Anthropic API key
+	// Anthropic API key (required when Provider is "anthropic")
 	AnthropicAPIKey string
 
+	// LLM provider for agents: "anthropic" (default) or "openai" for an
+	// OpenAI-compatible chat completions endpoint such as a model gateway
+	Provider string
+
+	// OpenAI-compatible endpoint, API key and model override (Provider "openai")
+	OpenAIBaseURL string
+	OpenAIAPIKey  string
+	OpenAIModel   string
+
+	// Named providers custom agents may select, from the JSON file at
+	// LLM_PROVIDERS_CONFIG (empty allows only the default provider)
+	LLMProviders map[string]NamedProvider
+
+	// Cassette file for recording or replaying LLM calls and tool results
+	// (empty disables cassettes)
+	CassettePath string
+
+	// Cassette mode: "record" or "replay" (default "replay")
+	CassetteMode string
+
 	// Model to use (default: claude-3-5-sonnet-20241022)
 	Model string
 
@@ -61,13 +83,89 @@ type Config struct {
 
 	// Log level for debugging
 	LogLevel LogLevel
+
+	// Stream API responses so clients see text as it is generated
+	Streaming bool
+
+	// How long request_human_input waits for a response (0 uses the agent default)
+	HumanInputTimeout time.Duration
+
+	// Limit for each handoff sub-run (0 is unlimited)
+	HandoffTimeout time.Duration
+
+	// Directory of YAML/Markdown agent definitions, reloaded on change
+	// (empty disables file agents)
+	AgentsDir string
+
+	// JSON file listing MCP servers whose tools are imported (empty disables MCP)
+	MCPConfigPath string
+
+	// Tools published by the MCP server, as category -> tool names ("*" for
+	// all tools in the category, or the category "*" for every category).
+	// Nil publishes nothing.
+	MCPServerAllow map[string][]string
+
+	// Bearer token MCP clients must send over HTTP (empty disables the HTTP
+	// transport)
+	MCPServerToken string
+
+	// Origins allowed to call the MCP HTTP endpoint from a browser
+	MCPServerOrigins []string
+
+	// Address of the OTLP sink capturing what sandbox collectors export
+	// (empty disables capture)
+	CaptureSinkAddr string
+
+	// OTLP/HTTP URL sandbox collectors use to reach the capture sink
+	CaptureSinkEndpoint string
 }
 
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
+	provider := strings.ToLower(os.Getenv("LLM_PROVIDER"))
+	if provider == "" {
+		provider = "anthropic"
+	}
+
+	cassettePath := os.Getenv("LLM_CASSETTE")
+	cassetteMode := strings.ToLower(os.Getenv("LLM_CASSETTE_MODE"))
+	if cassetteMode == "" {
+		cassetteMode = "replay"
+	}
+	if cassettePath != "" && cassetteMode != "record" && cassetteMode != "replay" {
+		return nil, fmt.Errorf("unknown LLM_CASSETTE_MODE %q (expected record or replay)", cassetteMode)
+	}
+	// Replaying a cassette makes no API calls, so no credentials are needed
+	replaying := cassettePath != "" && cassetteMode == "replay"
+
 	apiKey := os.Getenv("ANTHROPIC_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is required")
+	openAIBaseURL := os.Getenv("OPENAI_BASE_URL")
+	switch provider {
+	case "anthropic":
+		if replaying {
+			break
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is required")
+		}
+	case "openai":
+		if replaying {
+			break
+		}
+		if openAIBaseURL == "" {
+			return nil, fmt.Errorf("OPENAI_BASE_URL environment variable is required when LLM_PROVIDER=openai")
+		}
+	default:
+		return nil, fmt.Errorf("unknown LLM_PROVIDER %q (expected anthropic or openai)", provider)
+	}
+
+	var llmProviders map[string]NamedProvider
+	if path := os.Getenv("LLM_PROVIDERS_CONFIG"); path != "" {
+		providers, err := LoadProviders(path)
+		if err != nil {
+			return nil, err
+		}
+		llmProviders = providers
 	}
 
 	model := os.Getenv("ANTHROPIC_MODEL")
@@ -82,10 +180,107 @@ func Load() (*Config, error) {
 
 	logLevel := ParseLogLevel(os.Getenv("LOG_LEVEL"))
 
+	streaming := true // Default
+	if s := os.Getenv("ANTHROPIC_STREAMING"); s != "" {
+		streaming = strings.ToLower(s) != "false"
+	}
+
+	var humanInputTimeout time.Duration
+	if t := os.Getenv("HUMAN_INPUT_TIMEOUT"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid HUMAN_INPUT_TIMEOUT %q (expected a duration such as 30m)", t)
+		}
+		humanInputTimeout = d
+	}
+
+	var handoffTimeout time.Duration
+	if t := os.Getenv("HANDOFF_TIMEOUT"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid HANDOFF_TIMEOUT %q (expected a duration such as 10m)", t)
+		}
+		handoffTimeout = d
+	}
+
+	mcpServerAllow, err := ParseAllowList(os.Getenv("MCP_SERVER_ALLOW"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MCP_SERVER_ALLOW: %w", err)
+	}
+
+	var mcpServerOrigins []string
+	for _, origin := range strings.Split(os.Getenv("MCP_SERVER_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			mcpServerOrigins = append(mcpServerOrigins, origin)
+		}
+	}
+
+	captureSinkAddr := os.Getenv("CAPTURE_SINK_ADDR")
+	switch strings.ToLower(captureSinkAddr) {
+	case "off", "false":
+		captureSinkAddr = ""
+	}
+	captureSinkEndpoint := os.Getenv("CAPTURE_SINK_ENDPOINT")
+	if captureSinkAddr != "" && captureSinkEndpoint == "" {
+		_, port, err := net.SplitHostPort(captureSinkAddr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CAPTURE_SINK_ADDR %q (expected host:port or :port)", captureSinkAddr)
+		}
+		// Sandbox collectors run in their own containers and reach the
+		// backend through the Docker host
+		captureSinkEndpoint = "http://host.docker.internal:" + port
+	}
+
 	return &Config{
 		AnthropicAPIKey: apiKey,
+		Provider:        provider,
+		OpenAIBaseURL:   openAIBaseURL,
+		OpenAIAPIKey:    os.Getenv("OPENAI_API_KEY"),
+		OpenAIModel:     os.Getenv("OPENAI_MODEL"),
+		LLMProviders:    llmProviders,
+		CassettePath:    cassettePath,
+		CassetteMode:    cassetteMode,
 		Model:           model,
 		MaxTokens:       maxTokens,
 		LogLevel:        logLevel,
+		Streaming:       streaming,
+
+		HumanInputTimeout: humanInputTimeout,
+		HandoffTimeout:    handoffTimeout,
+		AgentsDir:         os.Getenv("AGENTS_DIR"),
+		MCPConfigPath:     os.Getenv("MCP_CONFIG"),
+		MCPServerAllow:    mcpServerAllow,
+		MCPServerToken:    os.Getenv("MCP_SERVER_TOKEN"),
+		MCPServerOrigins:  mcpServerOrigins,
+
+		CaptureSinkAddr:     captureSinkAddr,
+		CaptureSinkEndpoint: captureSinkEndpoint,
 	}, nil
 }
+
+// ParseAllowList parses a per-category tool allow list such as
+// "sandbox=*;otel=deploy_otel_collector,list_otel_agents". An empty string
+// returns nil, which allows no tools; "*=*" allows every tool.
+func ParseAllowList(s string) (map[string][]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	allowList := make(map[string][]string)
+	for _, entry := range strings.Split(s, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		category, names, ok := strings.Cut(entry, "=")
+		category = strings.TrimSpace(category)
+		if !ok || category == "" {
+			return nil, fmt.Errorf("entry %q must have the form category=tool1,tool2", entry)
+		}
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				allowList[category] = append(allowList[category], name)
+			}
+		}
+	}
+	return allowList, nil
+}
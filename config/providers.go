@@ -0,0 +1,68 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// NamedProvider is an LLM backend defined by the operator. Custom agents
+// select one by name, so API callers can never choose an endpoint or the
+// environment variable an API key is read from.
+type NamedProvider struct {
+	Type      string `json:"type"`                  // "anthropic" or "openai" (OpenAI-compatible chat completions)
+	BaseURL   string `json:"base_url,omitempty"`    // API endpoint, required for openai
+	Model     string `json:"model,omitempty"`       // Replaces the model name sent to an openai provider
+	APIKeyEnv string `json:"api_key_env,omitempty"` // Environment variable holding the API key
+}
+
+// providersFile is the JSON layout of LLM_PROVIDERS_CONFIG:
+//
+//	{"providers": {"gateway": {"type": "openai", "base_url": "https://gateway.internal/v1", "api_key_env": "GATEWAY_API_KEY"}}}
+type providersFile struct {
+	Providers map[string]NamedProvider `json:"providers"`
+}
+
+// LoadProviders reads the named LLM providers from a JSON file
+func LoadProviders(path string) (map[string]NamedProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read providers config: %w", err)
+	}
+
+	var file providersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse providers config %s: %w", path, err)
+	}
+
+	for name, provider := range file.Providers {
+		if err := provider.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid provider %q in %s: %w", name, path, err)
+		}
+	}
+
+	return file.Providers, nil
+}
+
+// Validate checks the provider type and that its API key is available.
+// A custom base URL always needs its own key, so the server's default
+// credentials are never sent to another endpoint.
+func (p NamedProvider) Validate() error {
+	switch p.Type {
+	case "anthropic":
+		if p.BaseURL != "" && p.APIKeyEnv == "" {
+			return fmt.Errorf("api_key_env is required with base_url")
+		}
+	case "openai":
+		if p.BaseURL == "" {
+			return fmt.Errorf("base_url is required for openai")
+		}
+	default:
+		return fmt.Errorf("unknown type %q (expected anthropic or openai)", p.Type)
+	}
+
+	if p.APIKeyEnv != "" && os.Getenv(p.APIKeyEnv) == "" {
+		return fmt.Errorf("environment variable %s is not set", p.APIKeyEnv)
+	}
+	return nil
+}
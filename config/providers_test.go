@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestNamedProviderValidate(t *testing.T) {
+	t.Setenv("TEST_GATEWAY_KEY", "gateway-secret")
+
+	tests := []struct {
+		name     string
+		provider NamedProvider
+		wantErr  bool
+	}{
+		{name: "openai", provider: NamedProvider{Type: "openai", BaseURL: "https://gateway.internal/v1"}},
+		{name: "openai without base_url", provider: NamedProvider{Type: "openai"}, wantErr: true},
+		{name: "anthropic default", provider: NamedProvider{Type: "anthropic"}},
+		{name: "anthropic base_url with key", provider: NamedProvider{Type: "anthropic", BaseURL: "https://proxy.internal", APIKeyEnv: "TEST_GATEWAY_KEY"}},
+		{name: "anthropic base_url without key", provider: NamedProvider{Type: "anthropic", BaseURL: "https://proxy.internal"}, wantErr: true},
+		{name: "unset key variable", provider: NamedProvider{Type: "openai", BaseURL: "https://gateway.internal/v1", APIKeyEnv: "TEST_UNSET_KEY"}, wantErr: true},
+		{name: "unknown type", provider: NamedProvider{Type: "bedrock"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.provider.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package grafanaclient
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -89,7 +90,7 @@ type AlertRule struct {
 }
 
 // CreateDatasource creates a new datasource in Grafana
-func (c *Client) CreateDatasource(datasource Datasource) (*Datasource, error) {
+func (c *Client) CreateDatasource(ctx context.Context, datasource Datasource) (*Datasource, error) {
 	url := fmt.Sprintf("%s/api/datasources", c.baseURL)
 
 	data, err := json.Marshal(datasource)
@@ -97,7 +98,7 @@ func (c *Client) CreateDatasource(datasource Datasource) (*Datasource, error) {
 		return nil, fmt.Errorf("failed to marshal datasource: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -125,10 +126,10 @@ func (c *Client) CreateDatasource(datasource Datasource) (*Datasource, error) {
 }
 
 // ListDatasources lists all datasources in Grafana
-func (c *Client) ListDatasources() ([]Datasource, error) {
+func (c *Client) ListDatasources(ctx context.Context) ([]Datasource, error) {
 	url := fmt.Sprintf("%s/api/datasources", c.baseURL)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -155,10 +156,10 @@ func (c *Client) ListDatasources() ([]Datasource, error) {
 }
 
 // GetDatasource gets a datasource by ID
-func (c *Client) GetDatasource(id int64) (*Datasource, error) {
+func (c *Client) GetDatasource(ctx context.Context, id int64) (*Datasource, error) {
 	url := fmt.Sprintf("%s/api/datasources/%d", c.baseURL, id)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -185,7 +186,7 @@ func (c *Client) GetDatasource(id int64) (*Datasource, error) {
 }
 
 // CreateDashboard creates a new dashboard in Grafana
-func (c *Client) CreateDashboard(dashboard Dashboard) (*Dashboard, error) {
+func (c *Client) CreateDashboard(ctx context.Context, dashboard Dashboard) (*Dashboard, error) {
 	url := fmt.Sprintf("%s/api/dashboards/db", c.baseURL)
 
 	data, err := json.Marshal(dashboard)
@@ -193,7 +194,7 @@ func (c *Client) CreateDashboard(dashboard Dashboard) (*Dashboard, error) {
 		return nil, fmt.Errorf("failed to marshal dashboard: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -221,7 +222,7 @@ func (c *Client) CreateDashboard(dashboard Dashboard) (*Dashboard, error) {
 }
 
 // CreateAlertRule creates a new alert rule in Grafana
-func (c *Client) CreateAlertRule(rule AlertRule) error {
+func (c *Client) CreateAlertRule(ctx context.Context, rule AlertRule) error {
 	url := fmt.Sprintf("%s/api/ruler/grafana/api/v1/rules", c.baseURL)
 
 	data, err := json.Marshal(rule)
@@ -229,7 +230,7 @@ func (c *Client) CreateAlertRule(rule AlertRule) error {
 		return fmt.Errorf("failed to marshal alert rule: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -252,10 +253,10 @@ func (c *Client) CreateAlertRule(rule AlertRule) error {
 }
 
 // GetHealth checks the health of the Grafana instance
-func (c *Client) GetHealth() (map[string]interface{}, error) {
+func (c *Client) GetHealth(ctx context.Context) (map[string]interface{}, error) {
 	url := fmt.Sprintf("%s/api/health", c.baseURL)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
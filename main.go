@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -13,21 +14,65 @@ import (
 	"github.com/mottibechhofer/otel-ai-engineer/agent"
 	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
 	"github.com/mottibechhofer/otel-ai-engineer/config"
+	"github.com/mottibechhofer/otel-ai-engineer/mcpclient"
 	"github.com/mottibechhofer/otel-ai-engineer/server"
 	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
 )
 
 func main() {
+	// "mcp" serves the tool catalog over MCP on stdin/stdout instead of
+	// starting the HTTP server. stdout carries the protocol, so everything
+	// else printed goes to stderr.
+	mcpStdio := len(os.Args) > 1 && os.Args[1] == "mcp"
+	protocolOut := os.Stdout
+	if mcpStdio {
+		os.Stdout = os.Stderr
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Create Anthropic client with API key from config
-	client := anthropic.NewClient(
-		option.WithAPIKey(cfg.AnthropicAPIKey),
-	)
+	// Create the default LLM provider
+	var provider agent.Provider
+	if cfg.Provider == agent.ProviderOpenAI {
+		provider = agent.NewOpenAIProvider(agent.OpenAIConfig{
+			BaseURL: cfg.OpenAIBaseURL,
+			APIKey:  cfg.OpenAIAPIKey,
+			Model:   cfg.OpenAIModel,
+		})
+	} else {
+		client := anthropic.NewClient(
+			option.WithAPIKey(cfg.AnthropicAPIKey),
+		)
+		provider = agent.NewAnthropicProvider(&client)
+	}
+
+	// Record or replay LLM calls and tool results through a cassette
+	if cfg.CassettePath != "" {
+		cassette, err := agent.NewCassetteProvider(agent.CassetteConfig{
+			Path:  cfg.CassettePath,
+			Mode:  cfg.CassetteMode,
+			Inner: provider,
+		})
+		if err != nil {
+			log.Fatalf("Failed to open cassette: %v", err)
+		}
+		log.Printf("Cassette %s mode: %s", cfg.CassetteMode, cfg.CassettePath)
+		provider = cassette
+	}
+	log.Printf("Using LLM provider: %s", provider.Name())
+
+	// Load the MCP servers whose tools are imported
+	var mcpServers []mcpclient.ServerConfig
+	if cfg.MCPConfigPath != "" {
+		mcpServers, err = mcpclient.LoadConfig(cfg.MCPConfigPath)
+		if err != nil {
+			log.Fatalf("Failed to load MCP config: %v", err)
+		}
+	}
 
 	// Parse port from command line arguments
 	port := 8080
@@ -54,17 +99,40 @@ func main() {
 
 	// Create agent registry
 	agentRegistry := agent.NewRegistry()
+	agentRegistry.SetProviders(cfg.LLMProviders)
 
 	// Create server
 	srv := server.New(server.Config{
-		Storage:         stor,
-		Port:            port,
-		AgentRegistry:   agentRegistry,
-		AnthropicClient: &client,
-		LogLevel:        cfg.LogLevel,
-		EventBridge:     bridge,
+		Storage:       stor,
+		Port:          port,
+		AgentRegistry: agentRegistry,
+		Provider:      provider,
+		LogLevel:      cfg.LogLevel,
+		EventBridge:   bridge,
+		Streaming:     cfg.Streaming,
+
+		HumanInputTimeout: cfg.HumanInputTimeout,
+		HandoffTimeout:    cfg.HandoffTimeout,
+		AgentsDir:         cfg.AgentsDir,
+		MCPServers:        mcpServers,
+		MCPServerAllow:    cfg.MCPServerAllow,
+		MCPServerToken:    cfg.MCPServerToken,
+		MCPServerOrigins:  cfg.MCPServerOrigins,
+
+		CaptureSinkAddr:     cfg.CaptureSinkAddr,
+		CaptureSinkEndpoint: cfg.CaptureSinkEndpoint,
 	})
 
+	if mcpStdio {
+		err := srv.ServeMCPStdio(context.Background(), os.Stdin, protocolOut)
+		bridge.Close() // Store the session's events before closing storage
+		srv.Close()
+		if err != nil {
+			log.Fatalf("MCP server failed: %v", err)
+		}
+		return
+	}
+
 	// Handle graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
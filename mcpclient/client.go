@@ -0,0 +1,282 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reconnectBackoff is the minimum time between attempts to reconnect to a
+// server that is down
+const reconnectBackoff = 5 * time.Second
+
+// handshakeTimeout bounds the initialization handshake with a server
+const handshakeTimeout = 30 * time.Second
+
+// transport carries JSON-RPC messages to and from an MCP server
+type transport interface {
+	// call sends a request and waits for its response
+	call(ctx context.Context, req *request) (*message, error)
+	// notify sends a notification
+	notify(ctx context.Context, req *request) error
+	close() error
+	// alive reports whether the connection can still carry messages
+	alive() bool
+}
+
+// Client is a connection to one MCP server. A server that exits or ends the
+// session is reconnected on next use.
+type Client struct {
+	name        string
+	dial        func() (transport, error) // opens a new connection; nil if it cannot be reopened
+	nextID      atomic.Int64
+	toolTimeout time.Duration
+
+	mu          sync.Mutex
+	transport   transport // nil while disconnected
+	serverInfo  Implementation
+	connections int64         // successful connections so far
+	lastAttempt time.Time     // last failed connection attempt
+	lastErr     error         // why it failed
+	connecting  chan struct{} // closed when the running reconnect ends; nil if none
+	closed      bool
+}
+
+// NewClient creates a client for the configured server without connecting
+// to it. It connects on first use, or when Connect is called.
+func NewClient(cfg ServerConfig) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	toolTimeout, _ := cfg.toolTimeout()
+
+	client := newClient(cfg.Name, nil, toolTimeout)
+	client.dial = func() (transport, error) {
+		if cfg.Command != "" {
+			return startStdioTransport(cfg)
+		}
+		return newHTTPTransport(cfg, &http.Client{}), nil
+	}
+	return client, nil
+}
+
+// Connect starts or dials the configured server and completes the MCP
+// initialization handshake
+func Connect(ctx context.Context, cfg ServerConfig) (*Client, error) {
+	client, err := NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func newClient(name string, t transport, toolTimeout time.Duration) *Client {
+	return &Client{
+		name:        name,
+		transport:   t,
+		toolTimeout: toolTimeout,
+	}
+}
+
+// Connect connects to the server unless the client is already connected.
+// Attempts are at least reconnectBackoff apart; in between, the last
+// failure is returned.
+func (c *Client) Connect(ctx context.Context) error {
+	_, err := c.connection(ctx)
+	return err
+}
+
+// Connections returns how many times the client has connected to the
+// server. It changes on every reconnect, when the server's tools may have
+// changed.
+func (c *Client) Connections() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connections
+}
+
+// connection returns the live transport, reconnecting if the server went away.
+// Reconnects run outside c.mu, one at a time; callers wait for the running
+// one or for their context to end.
+func (c *Client) connection(ctx context.Context) (transport, error) {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil, fmt.Errorf("MCP client %s is closed", c.name)
+		}
+		if c.transport != nil && c.transport.alive() {
+			t := c.transport
+			c.mu.Unlock()
+			return t, nil
+		}
+		if c.dial == nil {
+			c.mu.Unlock()
+			return nil, fmt.Errorf("MCP server %s disconnected", c.name)
+		}
+		if c.transport != nil {
+			c.transport.close()
+			c.transport = nil
+		}
+		if c.connecting == nil {
+			if c.lastErr != nil && time.Since(c.lastAttempt) < reconnectBackoff {
+				err := c.lastErr
+				c.mu.Unlock()
+				return nil, err
+			}
+			c.connecting = make(chan struct{})
+			go c.reconnect(ctx, c.connecting)
+		}
+		connecting := c.connecting
+		c.mu.Unlock()
+
+		select {
+		case <-connecting:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reconnect dials the server and runs the handshake, then installs the
+// transport and closes done. The handshake is bounded by handshakeTimeout
+// rather than ctx, since other callers may be waiting on it.
+func (c *Client) reconnect(ctx context.Context, done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), handshakeTimeout)
+	defer cancel()
+
+	var info Implementation
+	t, err := c.dial()
+	if err == nil {
+		if info, err = c.initialize(ctx, t); err != nil {
+			t.close()
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer close(done)
+	c.connecting = nil
+
+	switch {
+	case err != nil:
+		c.lastAttempt = time.Now()
+		c.lastErr = fmt.Errorf("failed to initialize MCP server %s: %w", c.name, err)
+	case c.closed:
+		t.close()
+	default:
+		c.transport = t
+		c.serverInfo = info
+		c.connections++
+		c.lastErr = nil
+	}
+}
+
+// initialize negotiates the protocol version over t, announces the client
+// and returns the server's name and version
+func (c *Client) initialize(ctx context.Context, t transport) (Implementation, error) {
+	var result initializeResult
+	err := c.callOn(ctx, t, "initialize", initializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      Implementation{Name: "otel-ai-engineer", Version: "1.0.0"},
+	}, &result)
+	if err != nil {
+		return Implementation{}, err
+	}
+
+	if ht, ok := t.(*httpTransport); ok {
+		ht.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	err = t.notify(ctx, &request{JSONRPC: "2.0", Method: "notifications/initialized"})
+	return result.ServerInfo, err
+}
+
+// call sends a request and decodes its result into result (if non-nil)
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	t, err := c.connection(ctx)
+	if err != nil {
+		return err
+	}
+	return c.callOn(ctx, t, method, params, result)
+}
+
+// callOn sends a request over t and decodes its result into result (if non-nil)
+func (c *Client) callOn(ctx context.Context, t transport, method string, params interface{}, result interface{}) error {
+	id := c.nextID.Add(1)
+	msg, err := t.call(ctx, &request{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// Name returns the configured name of the server
+func (c *Client) Name() string {
+	return c.name
+}
+
+// ServerInfo returns the name and version the server last reported
+func (c *Client) ServerInfo() Implementation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.serverInfo
+}
+
+// ListTools returns every tool the server offers, following pagination
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var all []Tool
+	cursor := ""
+	for {
+		var result listToolsResult
+		if err := c.call(ctx, "tools/list", listToolsParams{Cursor: cursor}, &result); err != nil {
+			return nil, fmt.Errorf("failed to list tools: %w", err)
+		}
+		all = append(all, result.Tools...)
+
+		if result.NextCursor == "" {
+			return all, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool calls a tool with JSON-encoded arguments. A result with IsError
+// set is returned without an error; err reports protocol failures.
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", callToolParams{Name: name, Arguments: arguments}, &result); err != nil {
+		return nil, fmt.Errorf("failed to call tool %s: %w", name, err)
+	}
+	return &result, nil
+}
+
+// Close disconnects from the server, stopping it if it is a subprocess
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.transport == nil {
+		return nil
+	}
+	err := c.transport.close()
+	c.transport = nil
+	return err
+}
@@ -0,0 +1,327 @@
+package mcpclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeServer answers MCP requests for a CMDB-like server with two pages of tools
+func fakeServer(msg *message) interface{} {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "cmdb", "version": "0.1.0"},
+		}
+	case "tools/list":
+		var params listToolsParams
+		json.Unmarshal(msg.Params, &params)
+		if params.Cursor == "" {
+			return map[string]interface{}{
+				"tools": []map[string]interface{}{{
+					"name":        "lookup.service",
+					"description": "Look up a service",
+					"inputSchema": map[string]interface{}{
+						"type":       "object",
+						"properties": map[string]interface{}{"name": map[string]string{"type": "string"}},
+						"required":   []string{"name"},
+					},
+				}},
+				"nextCursor": "page-2",
+			}
+		}
+		return map[string]interface{}{
+			"tools": []map[string]interface{}{{"name": "fail", "inputSchema": map[string]string{"type": "object"}}},
+		}
+	case "tools/call":
+		var params struct {
+			Name      string            `json:"name"`
+			Arguments map[string]string `json:"arguments"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		if params.Name == "fail" {
+			return map[string]interface{}{"content": []map[string]string{{"type": "text", "text": "CMDB is down"}}, "isError": true}
+		}
+		return map[string]interface{}{"content": []map[string]string{{"type": "text", "text": "owner of " + params.Arguments["name"] + ": team-a"}}}
+	}
+	return nil
+}
+
+func reply(msg *message) map[string]interface{} {
+	if result := fakeServer(msg); result != nil {
+		return map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID, "result": result}
+	}
+	return map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID, "error": RPCError{Code: codeMethodNotFound, Message: "unknown method"}}
+}
+
+// exerciseClient checks listing and calling tools through a connected client
+func exerciseClient(t *testing.T, client *Client) {
+	t.Helper()
+	ctx := context.Background()
+
+	if client.ServerInfo().Name != "cmdb" {
+		t.Errorf("ServerInfo() = %+v, want cmdb", client.ServerInfo())
+	}
+
+	wrapped, err := client.Tools(ctx)
+	if err != nil {
+		t.Fatalf("Tools() error = %v", err)
+	}
+	if len(wrapped) != 2 || wrapped[0].Name != "mcp__cmdb__lookup_service" || wrapped[1].Name != "mcp__cmdb__fail" {
+		t.Fatalf("Tools() = %+v, want both pages of tools", wrapped)
+	}
+	if len(wrapped[0].Schema.Required) != 1 || wrapped[0].Schema.Required[0] != "name" {
+		t.Errorf("Schema.Required = %v, want [name]", wrapped[0].Schema.Required)
+	}
+
+	result, err := wrapped[0].Execute(ctx, json.RawMessage(`{"name":"checkout"}`))
+	if err != nil || result != "owner of checkout: team-a" {
+		t.Errorf("Execute() = %v, %v, want the tool's text", result, err)
+	}
+
+	if _, err := wrapped[1].Execute(ctx, json.RawMessage(`{}`)); err == nil || err.Error() != "CMDB is down" {
+		t.Errorf("Execute() error = %v, want the tool's error text", err)
+	}
+}
+
+// startPipeServer runs the fake server over a pair of pipes. Closing the
+// returned transport, or calling exit, stops the server.
+func startPipeServer() (t *stdioTransport, exit func()) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	go func() {
+		defer serverWriter.Close()
+		scanner := bufio.NewScanner(serverReader)
+		for scanner.Scan() {
+			var msg message
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || len(msg.ID) == 0 {
+				continue
+			}
+			// Interleave a notification to check that it is skipped
+			fmt.Fprintln(serverWriter, `{"jsonrpc":"2.0","method":"notifications/message","params":{}}`)
+			data, _ := json.Marshal(reply(&msg))
+			fmt.Fprintln(serverWriter, string(data))
+		}
+	}()
+
+	return newStdioTransport(clientWriter, clientReader, nil), func() { serverWriter.Close() }
+}
+
+func TestStdioClient(t *testing.T) {
+	transport, _ := startPipeServer()
+	client := newClient("cmdb", transport, time.Minute)
+	defer client.Close()
+	info, err := client.initialize(context.Background(), client.transport)
+	if err != nil {
+		t.Fatalf("initialize() error = %v", err)
+	}
+	client.serverInfo = info
+
+	exerciseClient(t, client)
+}
+
+func TestClientReconnects(t *testing.T) {
+	var exit func()
+	client := newClient("cmdb", nil, time.Minute)
+	client.dial = func() (transport, error) {
+		var transport *stdioTransport
+		transport, exit = startPipeServer()
+		return transport, nil
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if _, err := client.ListTools(ctx); err != nil {
+		t.Fatalf("ListTools() error = %v", err)
+	}
+
+	// The server exits; the next call starts it again
+	exit()
+	for deadline := time.Now().Add(2 * time.Second); client.transport.alive() && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := client.ListTools(ctx); err != nil {
+		t.Fatalf("ListTools() after exit error = %v", err)
+	}
+	if got := client.Connections(); got != 2 {
+		t.Errorf("Connections() = %d, want 2", got)
+	}
+}
+
+func TestClientReconnectBackoff(t *testing.T) {
+	dials := 0
+	client := newClient("cmdb", nil, time.Minute)
+	client.dial = func() (transport, error) {
+		dials++
+		return nil, fmt.Errorf("connection refused")
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := client.Connect(context.Background()); err == nil || !strings.Contains(err.Error(), "connection refused") {
+			t.Fatalf("Connect() error = %v, want the dial error", err)
+		}
+	}
+	if dials != 1 {
+		t.Errorf("dialed %d times within the backoff, want 1", dials)
+	}
+}
+
+func TestClientSlowConnect(t *testing.T) {
+	var dials atomic.Int32
+	release := make(chan struct{})
+	client := newClient("cmdb", nil, time.Minute)
+	client.dial = func() (transport, error) {
+		dials.Add(1)
+		<-release
+		transport, _ := startPipeServer()
+		return transport, nil
+	}
+	defer client.Close()
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { errs <- client.Connect(context.Background()) }()
+	}
+
+	// A caller that gives up stops waiting for the running connect
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.Connect(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Connect() error = %v, want the context's error", err)
+	}
+	// The client can be inspected while connecting
+	if got := client.Connections(); got != 0 {
+		t.Errorf("Connections() while connecting = %d, want 0", got)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Connect() error = %v", err)
+		}
+	}
+	if got := dials.Load(); got != 1 {
+		t.Errorf("dialed %d times, want 1", got)
+	}
+	if got := client.Connections(); got != 1 {
+		t.Errorf("Connections() = %d, want 1", got)
+	}
+	if got := client.ServerInfo().Name; got != "cmdb" {
+		t.Errorf("ServerInfo().Name = %q, want cmdb", got)
+	}
+}
+
+func TestToolName(t *testing.T) {
+	if got := ToolName("cmdb", "lookup.service"); got != "mcp__cmdb__lookup_service" {
+		t.Errorf("ToolName() = %q", got)
+	}
+
+	prefix := strings.Repeat("x", 70)
+	a, b := ToolName("cmdb", prefix+"_a"), ToolName("cmdb", prefix+"_b")
+	if len(a) != maxToolNameLength || len(b) != maxToolNameLength {
+		t.Errorf("ToolName() lengths = %d, %d, want %d", len(a), len(b), maxToolNameLength)
+	}
+	if a == b {
+		t.Errorf("ToolName() = %q for two tools that differ after the length limit", a)
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	var sawSession, ended atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			ended.Store(r.Header.Get("Mcp-Session-Id") == "session-1")
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var msg message
+		json.NewDecoder(r.Body).Decode(&msg)
+		if msg.Method == "initialize" {
+			w.Header().Set("Mcp-Session-Id", "session-1")
+		} else if r.Header.Get("Mcp-Session-Id") == "session-1" && r.Header.Get("MCP-Protocol-Version") == ProtocolVersion {
+			sawSession.Store(true)
+		}
+		if len(msg.ID) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		data, _ := json.Marshal(reply(&msg))
+		if msg.Method == "tools/call" {
+			// Answer over SSE, after an unrelated event
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	client, err := Connect(context.Background(), ServerConfig{
+		Name:    "cmdb",
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	exerciseClient(t, client)
+	client.Close()
+
+	if !sawSession.Load() {
+		t.Error("requests after initialize did not carry the session and protocol version headers")
+	}
+	if !ended.Load() {
+		t.Error("Close() did not end the session")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("PAGING_TOKEN", "secret")
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	data := `{"mcpServers": {
+		"paging": {"url": "https://paging.internal/mcp", "headers": {"Authorization": "Bearer ${PAGING_TOKEN}"}},
+		"cmdb": {"command": "cmdb-mcp", "args": ["--readonly"], "timeout": "30s"}
+	}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	configs, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(configs) != 2 || configs[0].Name != "cmdb" || configs[1].Headers["Authorization"] != "Bearer secret" {
+		t.Errorf("LoadConfig() = %+v, want cmdb then paging with the token expanded", configs)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"mcpServers": {"both": {"command": "x", "url": "http://x"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "exactly one of command or url") {
+		t.Errorf("LoadConfig() error = %v, want a transport error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package mcpclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// DefaultToolTimeout bounds a single tool call when a server sets no timeout
+const DefaultToolTimeout = 2 * time.Minute
+
+// ServerConfig describes how to reach one MCP server. Set Command to launch
+// the server as a subprocess speaking stdio, or URL to use streamable HTTP.
+type ServerConfig struct {
+	// Name identifies the server; its tools are named mcp__<name>__<tool>
+	Name string `json:"-"`
+
+	// stdio transport
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+
+	// Streamable HTTP transport
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Timeout bounds each tool call, e.g. "30s" (empty uses DefaultToolTimeout)
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// configFile is the layout of an MCP configuration file:
+//
+//	{
+//	  "mcpServers": {
+//	    "cmdb":   {"command": "cmdb-mcp", "args": ["--readonly"], "env": {"CMDB_TOKEN": "${CMDB_TOKEN}"}},
+//	    "paging": {"url": "https://paging.internal/mcp", "headers": {"Authorization": "Bearer ${PAGING_TOKEN}"}}
+//	  }
+//	}
+type configFile struct {
+	MCPServers map[string]ServerConfig `json:"mcpServers"`
+}
+
+var serverNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// LoadConfig reads the MCP servers configured in a JSON file, sorted by name.
+// ${VAR} references in args, env, url and headers are expanded from the
+// environment so secrets can stay out of the file.
+func LoadConfig(path string) ([]ServerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MCP config: %w", err)
+	}
+
+	var file configFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse MCP config %s: %w", path, err)
+	}
+
+	configs := make([]ServerConfig, 0, len(file.MCPServers))
+	for name, cfg := range file.MCPServers {
+		cfg.Name = name
+		cfg.expandEnv()
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid MCP server %q in %s: %w", name, path, err)
+		}
+		configs = append(configs, cfg)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+
+	return configs, nil
+}
+
+// Validate checks that the server has a usable name and exactly one transport
+func (c *ServerConfig) Validate() error {
+	if !serverNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("name must only contain letters, digits, '_' and '-'")
+	}
+	if (c.Command == "") == (c.URL == "") {
+		return fmt.Errorf("exactly one of command or url is required")
+	}
+	if _, err := c.toolTimeout(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// toolTimeout parses Timeout, defaulting to DefaultToolTimeout
+func (c *ServerConfig) toolTimeout() (time.Duration, error) {
+	if c.Timeout == "" {
+		return DefaultToolTimeout, nil
+	}
+	d, err := time.ParseDuration(c.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q (expected a duration such as 30s)", c.Timeout)
+	}
+	return d, nil
+}
+
+// expandEnv replaces ${VAR} references with environment values
+func (c *ServerConfig) expandEnv() {
+	c.Command = os.ExpandEnv(c.Command)
+	c.URL = os.ExpandEnv(c.URL)
+	for i, arg := range c.Args {
+		c.Args[i] = os.ExpandEnv(arg)
+	}
+	for key, value := range c.Env {
+		c.Env[key] = os.ExpandEnv(value)
+	}
+	for key, value := range c.Headers {
+		c.Headers[key] = os.ExpandEnv(value)
+	}
+}
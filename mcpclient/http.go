@@ -0,0 +1,202 @@
+package mcpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// httpTransport speaks the MCP streamable HTTP transport: every message is
+// POSTed to the server's endpoint, which answers a request with either a JSON
+// body or an SSE stream that ends with the response
+type httpTransport struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+
+	mu              sync.Mutex
+	sessionID       string // Mcp-Session-Id assigned during initialization
+	protocolVersion string // negotiated version, sent once initialized
+	expired         bool   // the server no longer knows the session
+}
+
+func newHTTPTransport(cfg ServerConfig, httpClient *http.Client) *httpTransport {
+	return &httpTransport{
+		url:        cfg.URL,
+		headers:    cfg.Headers,
+		httpClient: httpClient,
+	}
+}
+
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocolVersion = version
+}
+
+func (t *httpTransport) call(ctx context.Context, req *request) (*message, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted {
+		return nil, fmt.Errorf("MCP server accepted %s without responding", req.Method)
+	}
+
+	id := strconv.FormatInt(*req.ID, 10)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		var msg message
+		if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+			return nil, fmt.Errorf("failed to decode MCP response: %w", err)
+		}
+		if !msg.isResponse() || string(msg.ID) != id {
+			return nil, fmt.Errorf("MCP server sent an unexpected message for %s", req.Method)
+		}
+		return &msg, nil
+	case "text/event-stream":
+		return readSSEResponse(resp.Body, id)
+	default:
+		return nil, fmt.Errorf("unexpected MCP response content type %q", resp.Header.Get("Content-Type"))
+	}
+}
+
+func (t *httpTransport) notify(ctx context.Context, req *request) error {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil
+}
+
+// post sends a message and returns the response if its status is 2xx
+func (t *httpTransport) post(ctx context.Context, req *request) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(httpReq)
+
+	resp, err := t.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach MCP server: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode == http.StatusNotFound && t.session() != "" {
+			t.mu.Lock()
+			t.expired = true
+			t.mu.Unlock()
+			return nil, fmt.Errorf("MCP session expired (HTTP 404)")
+		}
+		return nil, fmt.Errorf("MCP server error %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// setHeaders adds the configured headers and the session headers
+func (t *httpTransport) setHeaders(httpReq *http.Request) {
+	for key, value := range t.headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID != "" {
+		httpReq.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		httpReq.Header.Set("MCP-Protocol-Version", t.protocolVersion)
+	}
+}
+
+// alive reports whether the session is still valid; an expired session
+// needs a new initialization handshake
+func (t *httpTransport) alive() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return !t.expired
+}
+
+func (t *httpTransport) session() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID
+}
+
+// close ends the session, if the server assigned one
+func (t *httpTransport) close() error {
+	if t.session() == "" {
+		return nil
+	}
+
+	httpReq, err := http.NewRequest(http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	t.setHeaders(httpReq)
+
+	resp, err := t.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to end MCP session: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// readSSEResponse reads server-sent events until the response to the request
+// with the given ID arrives. Other messages on the stream are skipped.
+func readSSEResponse(r io.Reader, id string) (*message, error) {
+	reader := bufio.NewReader(r)
+	var data strings.Builder
+
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			data.WriteString("\n")
+		case line == "" && data.Len() > 0:
+			// A blank line ends the event
+			var msg message
+			if jsonErr := json.Unmarshal([]byte(data.String()), &msg); jsonErr == nil && msg.isResponse() && string(msg.ID) == id {
+				return &msg, nil
+			}
+			data.Reset()
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("MCP event stream ended without a response: %w", err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package mcpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// stdioTransport exchanges newline-delimited JSON-RPC messages with a server
+// over a pair of streams, normally the stdin and stdout of a subprocess
+type stdioTransport struct {
+	w       io.WriteCloser
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *message // request ID -> waiting caller
+
+	done    chan struct{} // closed when the read loop stops
+	readErr error         // why the read loop stopped
+
+	closeFn func() error
+}
+
+// startStdioTransport launches the server command and connects to its stdio
+func startStdioTransport(cfg ServerConfig) (*stdioTransport, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = os.Environ()
+	for key, value := range cfg.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	// Servers log to stderr
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", cfg.Command, err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	return newStdioTransport(stdin, stdout, func() error {
+		// Closing stdin asks the server to exit; kill it if it does not
+		stdin.Close()
+		select {
+		case <-exited:
+		case <-time.After(5 * time.Second):
+			cmd.Process.Kill()
+			<-exited
+		}
+		return nil
+	}), nil
+}
+
+// newStdioTransport starts reading messages from r. closeFn releases the
+// streams (and whatever is behind them) when the transport is closed.
+func newStdioTransport(w io.WriteCloser, r io.Reader, closeFn func() error) *stdioTransport {
+	t := &stdioTransport{
+		w:       w,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+		closeFn: closeFn,
+	}
+	go t.readLoop(r)
+	return t
+}
+
+func (t *stdioTransport) readLoop(r io.Reader) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var msg message
+			if jsonErr := json.Unmarshal(line, &msg); jsonErr == nil {
+				t.dispatch(&msg)
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("MCP server closed the connection")
+			}
+			t.mu.Lock()
+			t.readErr = err
+			t.mu.Unlock()
+			close(t.done)
+			return
+		}
+	}
+}
+
+// dispatch delivers responses to their callers and answers server requests
+func (t *stdioTransport) dispatch(msg *message) {
+	if msg.isResponse() {
+		t.mu.Lock()
+		ch, ok := t.pending[string(msg.ID)]
+		delete(t.pending, string(msg.ID))
+		t.mu.Unlock()
+		if ok {
+			ch <- msg
+		}
+		return
+	}
+
+	// Notifications need no answer
+	if len(msg.ID) == 0 {
+		return
+	}
+
+	reply := map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID}
+	if msg.Method == "ping" {
+		reply["result"] = struct{}{}
+	} else {
+		reply["error"] = RPCError{Code: codeMethodNotFound, Message: "method not supported by client: " + msg.Method}
+	}
+	t.write(reply)
+}
+
+func (t *stdioTransport) call(ctx context.Context, req *request) (*message, error) {
+	key := strconv.FormatInt(*req.ID, 10)
+	ch := make(chan *message, 1)
+
+	t.mu.Lock()
+	t.pending[key] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.done:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return nil, t.readErr
+	}
+}
+
+func (t *stdioTransport) notify(ctx context.Context, req *request) error {
+	return t.write(req)
+}
+
+func (t *stdioTransport) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to MCP server: %w", err)
+	}
+	return nil
+}
+
+// alive reports whether the server is still sending; a server that exits
+// closes its stdout
+func (t *stdioTransport) alive() bool {
+	select {
+	case <-t.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (t *stdioTransport) close() error {
+	if t.closeFn != nil {
+		return t.closeFn()
+	}
+	return t.w.Close()
+}
@@ -0,0 +1,148 @@
+package mcpclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+// maxToolNameLength is the longest tool name the Anthropic API accepts
+const maxToolNameLength = 64
+
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// ToolName returns the registry name of a server's tool, mcp__<server>__<tool>,
+// so tools from different servers cannot collide with each other or with
+// built-in tools. Names longer than the API allows are shortened and end
+// with a hash of the full name, so tools that share a long prefix stay apart.
+func ToolName(serverName, toolName string) string {
+	name := "mcp__" + serverName + "__" + invalidToolNameChars.ReplaceAllString(toolName, "_")
+	if len(name) > maxToolNameLength {
+		sum := sha256.Sum256([]byte("mcp__" + serverName + "__" + toolName))
+		suffix := "_" + hex.EncodeToString(sum[:4])
+		name = name[:maxToolNameLength-len(suffix)] + suffix
+	}
+	return name
+}
+
+// Tools lists the server's tools and wraps each one as a tools.Tool that
+// calls it on the server
+func (c *Client) Tools(ctx context.Context) ([]tools.Tool, error) {
+	mcpTools, err := c.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]tools.Tool, 0, len(mcpTools))
+	remoteNames := make(map[string]string, len(mcpTools))
+	for _, mcpTool := range mcpTools {
+		name := ToolName(c.name, mcpTool.Name)
+		if other, ok := remoteNames[name]; ok {
+			return nil, fmt.Errorf("tools %s and %s both map to the tool name %s", other, mcpTool.Name, name)
+		}
+		remoteNames[name] = mcpTool.Name
+
+		schema, err := inputSchema(mcpTool.InputSchema)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s has an invalid input schema: %w", mcpTool.Name, err)
+		}
+
+		description := mcpTool.Description
+		if description == "" {
+			description = mcpTool.Title
+		}
+
+		remoteName := mcpTool.Name
+		result = append(result, tools.Tool{
+			Name:        name,
+			Description: description,
+			Schema:      schema,
+			ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+				res, err := c.CallTool(ctx, remoteName, inputJSON)
+				if err != nil {
+					return nil, err
+				}
+				return toolResult(res)
+			},
+			Timeout: c.toolTimeout,
+		})
+	}
+
+	return result, nil
+}
+
+// inputSchema converts an MCP input schema (a JSON Schema object) to the
+// schema type used by the tool registry
+func inputSchema(raw json.RawMessage) (anthropic.ToolInputSchemaParam, error) {
+	var schema anthropic.ToolInputSchemaParam
+	if len(raw) == 0 {
+		schema.Properties = map[string]interface{}{}
+		return schema, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return schema, err
+	}
+
+	schema.Properties = fields["properties"]
+	if schema.Properties == nil {
+		schema.Properties = map[string]interface{}{}
+	}
+	if required, ok := fields["required"].([]interface{}); ok {
+		for _, name := range required {
+			if s, ok := name.(string); ok {
+				schema.Required = append(schema.Required, s)
+			}
+		}
+	}
+
+	// Keep the rest of the schema (e.g. $defs, additionalProperties) so
+	// references in the properties still resolve
+	for key, value := range fields {
+		if key == "type" || key == "properties" || key == "required" {
+			continue
+		}
+		if schema.ExtraFields == nil {
+			schema.ExtraFields = make(map[string]any)
+		}
+		schema.ExtraFields[key] = value
+	}
+
+	return schema, nil
+}
+
+// toolResult converts an MCP tool result to a registry result. Structured
+// content is returned as JSON when present, otherwise the text content.
+func toolResult(res *CallToolResult) (interface{}, error) {
+	var parts []string
+	for _, content := range res.Content {
+		switch content.Type {
+		case "text":
+			parts = append(parts, content.Text)
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource: %s]", content.URI))
+		default:
+			parts = append(parts, fmt.Sprintf("[%s content: %s]", content.Type, content.MimeType))
+		}
+	}
+	text := strings.Join(parts, "\n")
+
+	if res.IsError {
+		if text == "" {
+			text = "tool reported an error"
+		}
+		return nil, fmt.Errorf("%s", text)
+	}
+	if len(res.StructuredContent) > 0 {
+		return res.StructuredContent, nil
+	}
+	return text, nil
+}
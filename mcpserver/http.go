@@ -0,0 +1,158 @@
+package mcpserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+)
+
+// maxMessageSize limits the size of a POSTed message
+const maxMessageSize = 10 << 20
+
+// ServeHTTP implements the streamable HTTP transport. Clients POST each
+// message; initialize assigns an Mcp-Session-Id that must be sent with every
+// later message, and DELETE ends the session. Responses are always plain
+// JSON, since the server never sends requests or notifications of its own.
+// Every request must carry the bearer token and come from an allowed origin.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Browsers send Origin; checking it stops other sites (and DNS
+	// rebinding) from reaching the endpoint through a user's browser
+	if origin := r.Header.Get("Origin"); origin != "" && !slices.Contains(s.allowedOrigins, origin) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if s.authToken == "" {
+		http.Error(w, "MCP over HTTP is disabled: no token is configured", http.StatusForbidden)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.authToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.handlePost(w, r)
+	case http.MethodDelete:
+		session, ok := s.lookupSession(w, r)
+		if !ok {
+			return
+		}
+		s.removeSession(session.ID)
+		s.endSession(session, events.RunEndReasonCompleted)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		// No server-initiated event stream is offered
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		writeResponse(w, http.StatusBadRequest, errorResponse(nil, codeParseError, "invalid JSON: %v", err))
+		return
+	}
+
+	if msg.Method == "initialize" && r.Header.Get("Mcp-Session-Id") == "" {
+		session, resp := s.initialize(&msg)
+		if session != nil {
+			s.mu.Lock()
+			s.sessions[session.ID] = session
+			s.mu.Unlock()
+			w.Header().Set("Mcp-Session-Id", session.ID)
+		}
+		writeResponse(w, http.StatusOK, resp)
+		return
+	}
+
+	session, ok := s.lookupSession(w, r)
+	if !ok {
+		return
+	}
+
+	resp := s.handle(r.Context(), session, &msg)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
+
+// lookupSession finds the session named by the Mcp-Session-Id header,
+// writing an error response if there is none
+func (s *Server) lookupSession(w http.ResponseWriter, r *http.Request) (*Session, bool) {
+	sessionID := r.Header.Get("Mcp-Session-Id")
+	if sessionID == "" {
+		http.Error(w, "missing Mcp-Session-Id header", http.StatusBadRequest)
+		return nil, false
+	}
+
+	s.mu.Lock()
+	session, ok := s.sessions[sessionID]
+	s.mu.Unlock()
+	if !ok {
+		// 404 tells the client to start a new session
+		http.Error(w, "unknown or expired session", http.StatusNotFound)
+		return nil, false
+	}
+	return session, true
+}
+
+func (s *Server) removeSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionID)
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp *response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// WatchIdleSessions ends HTTP sessions that have not been used for idleTimeout,
+// checking every interval until ctx is done. Clients that disappear without
+// a DELETE would otherwise leave their runs open forever.
+func (s *Server) WatchIdleSessions(ctx context.Context, interval, idleTimeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var expired []*Session
+		s.mu.Lock()
+		for id, session := range s.sessions {
+			if time.Since(session.idleSince()) > idleTimeout {
+				expired = append(expired, session)
+				delete(s.sessions, id)
+			}
+		}
+		s.mu.Unlock()
+
+		for _, session := range expired {
+			s.endSession(session, events.RunEndReasonTimeout)
+		}
+	}
+}
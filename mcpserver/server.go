@@ -0,0 +1,363 @@
+package mcpserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+// supportedVersions are the MCP revisions the server accepts, newest first
+var supportedVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// AgentID is the agent ID of the runs that record MCP sessions
+const AgentID = "mcp"
+
+// Config configures an MCP server
+type Config struct {
+	// Name and Version identify the server to clients
+	Name    string
+	Version string
+
+	// Tools returns the tools to publish. It is called for every request so
+	// changes to the catalog are picked up.
+	Tools func() []tools.Tool
+
+	// Emitter receives a run for each session, with tool call and result
+	// events for each call, like an agent run (nil disables events)
+	Emitter events.EventEmitter
+
+	// AuthToken is the bearer token HTTP clients must send. The HTTP
+	// transport refuses every request while it is empty.
+	AuthToken string
+
+	// AllowedOrigins are the browser origins allowed to use the HTTP
+	// transport. Requests without an Origin header are not browser requests
+	// and are allowed.
+	AllowedOrigins []string
+}
+
+// Server publishes tools over the Model Context Protocol. Use ServeStdio for
+// a single client on a pair of streams, or the server as an http.Handler for
+// the streamable HTTP transport.
+type Server struct {
+	info           Implementation
+	tools          func() []tools.Tool
+	emitter        events.EventEmitter
+	authToken      string
+	allowedOrigins []string
+
+	serialMu sync.Mutex // held while a Serial tool runs
+	nextCall atomic.Int64
+
+	mu       sync.Mutex
+	sessions map[string]*Session // HTTP sessions by ID
+}
+
+// Implementation identifies an MCP client or server
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Session is an initialized connection from one client. Its ID is also the
+// ID of the run that records its tool calls.
+type Session struct {
+	ID        string
+	Client    Implementation
+	StartTime time.Time
+
+	mu        sync.Mutex
+	lastUsed  time.Time
+	toolCalls int
+}
+
+func (s *Session) touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastUsed = time.Now()
+}
+
+func (s *Session) idleSince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastUsed
+}
+
+// New creates an MCP server
+func New(cfg Config) *Server {
+	emitter := cfg.Emitter
+	if emitter == nil {
+		emitter = events.NewNoOpEmitter()
+	}
+
+	return &Server{
+		info:           Implementation{Name: cfg.Name, Version: cfg.Version},
+		tools:          cfg.Tools,
+		emitter:        emitter,
+		authToken:      cfg.AuthToken,
+		allowedOrigins: cfg.AllowedOrigins,
+		sessions:       make(map[string]*Session),
+	}
+}
+
+// message is a JSON-RPC 2.0 message received from a client
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// isRequest reports whether the message expects a response
+func (m *message) isRequest() bool {
+	return m.Method != "" && len(m.ID) > 0
+}
+
+// response is a JSON-RPC 2.0 response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+func resultResponse(id json.RawMessage, result interface{}) *response {
+	return &response{JSONRPC: "2.0", ID: id, Result: result}
+}
+
+func errorResponse(id json.RawMessage, code int, format string, args ...interface{}) *response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: fmt.Sprintf(format, args...)}}
+}
+
+type initializeParams struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	ClientInfo      Implementation `json:"clientInfo"`
+}
+
+// initialize starts a session for an initialize request
+func (s *Server) initialize(msg *message) (*Session, *response) {
+	var params initializeParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return nil, errorResponse(msg.ID, codeInvalidParams, "invalid initialize params: %v", err)
+	}
+
+	// Answer with the client's version if we support it, otherwise our latest
+	version := supportedVersions[0]
+	for _, supported := range supportedVersions {
+		if params.ProtocolVersion == supported {
+			version = supported
+		}
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, errorResponse(msg.ID, codeInvalidRequest, "failed to create session: %v", err)
+	}
+	now := time.Now()
+	session := &Session{
+		ID:        "mcp-" + hex.EncodeToString(idBytes),
+		Client:    params.ClientInfo,
+		StartTime: now,
+		lastUsed:  now,
+	}
+
+	if evt, err := events.NewRunStartEvent(session.ID, AgentID, session.agentName(), events.RunStartData{
+		Prompt: fmt.Sprintf("MCP session from %s %s", params.ClientInfo.Name, params.ClientInfo.Version),
+	}); err == nil {
+		s.emitter.Emit(evt)
+	}
+
+	return session, resultResponse(msg.ID, map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities":    map[string]interface{}{"tools": map[string]interface{}{"listChanged": false}},
+		"serverInfo":      s.info,
+	})
+}
+
+// agentName is the agent name of the session's run
+func (s *Session) agentName() string {
+	if s.Client.Name == "" {
+		return "MCP client"
+	}
+	return "MCP: " + s.Client.Name
+}
+
+// endSession records the end of a session's run
+func (s *Server) endSession(session *Session, reason events.RunEndReason) {
+	session.mu.Lock()
+	toolCalls := session.toolCalls
+	session.mu.Unlock()
+
+	if evt, err := events.NewRunEndEvent(session.ID, AgentID, session.agentName(), events.RunEndData{
+		Success:        true,
+		Reason:         reason,
+		TotalToolCalls: toolCalls,
+		Duration:       time.Since(session.StartTime).String(),
+	}); err == nil {
+		s.emitter.Emit(evt)
+	}
+}
+
+// handle answers a message from an initialized session. It returns nil for
+// notifications.
+func (s *Server) handle(ctx context.Context, session *Session, msg *message) *response {
+	session.touch()
+	if !msg.isRequest() {
+		return nil
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return errorResponse(msg.ID, codeInvalidRequest, "session is already initialized")
+	case "ping":
+		return resultResponse(msg.ID, struct{}{})
+	case "tools/list":
+		return resultResponse(msg.ID, map[string]interface{}{"tools": s.listTools()})
+	case "tools/call":
+		return s.callTool(ctx, session, msg)
+	default:
+		return errorResponse(msg.ID, codeMethodNotFound, "method not found: %s", msg.Method)
+	}
+}
+
+// toolDescription is a tool as listed to clients
+type toolDescription struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema interface{} `json:"inputSchema"`
+}
+
+func (s *Server) listTools() []toolDescription {
+	published := s.tools()
+	result := make([]toolDescription, 0, len(published))
+	for _, tool := range published {
+		result = append(result, toolDescription{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.Schema,
+		})
+	}
+	return result
+}
+
+type callToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type callToolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+// callTool runs a published tool, emitting the same tool call and result
+// events as an agent. Tool failures are returned as results with isError set.
+func (s *Server) callTool(ctx context.Context, session *Session, msg *message) *response {
+	var params callToolParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return errorResponse(msg.ID, codeInvalidParams, "invalid tools/call params: %v", err)
+	}
+
+	var tool *tools.Tool
+	for _, published := range s.tools() {
+		if published.Name == params.Name {
+			tool = &published
+			break
+		}
+	}
+	if tool == nil {
+		return errorResponse(msg.ID, codeInvalidParams, "unknown tool: %s", params.Name)
+	}
+
+	input := params.Arguments
+	if len(input) == 0 || string(input) == "null" {
+		input = json.RawMessage("{}")
+	}
+
+	callID := fmt.Sprintf("mcpcall_%d", s.nextCall.Add(1))
+	agentName := session.agentName()
+
+	if evt, err := events.NewToolCallEvent(session.ID, AgentID, agentName, events.ToolCallData{
+		ToolUseID: callID,
+		ToolName:  tool.Name,
+		Input:     input,
+	}); err == nil {
+		s.emitter.Emit(evt)
+	}
+
+	if tool.Serial {
+		s.serialMu.Lock()
+	}
+	toolCtx := tools.WithRunID(tools.WithToolUseID(ctx, callID), session.ID)
+	startTime := time.Now()
+	result, err := tool.Execute(toolCtx, input)
+	duration := time.Since(startTime)
+	if tool.Serial {
+		s.serialMu.Unlock()
+	}
+
+	session.mu.Lock()
+	session.toolCalls++
+	session.mu.Unlock()
+
+	var resultJSON json.RawMessage
+	if result != nil {
+		resultJSON, _ = json.Marshal(result)
+	}
+
+	resultData := events.ToolResultData{
+		ToolUseID: callID,
+		ToolName:  tool.Name,
+		Result:    resultJSON,
+		IsError:   err != nil,
+		Duration:  duration.String(),
+	}
+	if err != nil {
+		resultData.Error = err.Error()
+	}
+	if evt, evtErr := events.NewToolResultEvent(session.ID, AgentID, agentName, resultData); evtErr == nil {
+		s.emitter.Emit(evt)
+	}
+
+	if err != nil {
+		return resultResponse(msg.ID, callToolResult{
+			Content: []textContent{{Type: "text", Text: tools.ErrorContent(err)}},
+			IsError: true,
+		})
+	}
+
+	text := string(resultJSON)
+	if str, ok := result.(string); ok {
+		text = str
+	}
+	return resultResponse(msg.ID, callToolResult{Content: []textContent{{Type: "text", Text: text}}})
+}
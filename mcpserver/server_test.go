@@ -0,0 +1,225 @@
+package mcpserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+	"github.com/mottibechhofer/otel-ai-engineer/mcpclient"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+var testTools = []tools.Tool{
+	{
+		Name:        "validate_sandbox",
+		Description: "Validate a sandbox",
+		Schema: anthropic.ToolInputSchemaParam{
+			Properties: map[string]interface{}{"sandbox_id": map[string]string{"type": "string"}},
+			Required:   []string{"sandbox_id"},
+		},
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+			var input struct {
+				SandboxID string `json:"sandbox_id"`
+			}
+			json.Unmarshal(inputJSON, &input)
+			if input.SandboxID == "" {
+				return nil, errors.New("sandbox_id is required")
+			}
+			return map[string]string{"sandbox_id": input.SandboxID, "status": "valid", "run_id": tools.RunIDFromContext(ctx)}, nil
+		},
+	},
+}
+
+func newTestServer() (*Server, <-chan *events.AgentEvent, func()) {
+	emitter := events.NewEmitter()
+	eventChan, cleanup := emitter.SubscribeAll()
+	server := New(Config{
+		Name:    "test",
+		Version: "1.0.0",
+		Tools:   func() []tools.Tool { return testTools },
+		Emitter: emitter,
+
+		AuthToken:      "secret",
+		AllowedOrigins: []string{"http://localhost:3000"},
+	})
+	return server, eventChan, cleanup
+}
+
+// receiveEvents reads n events, failing the test if they do not arrive
+func receiveEvents(t *testing.T, eventChan <-chan *events.AgentEvent, n int) []*events.AgentEvent {
+	t.Helper()
+	var received []*events.AgentEvent
+	for len(received) < n {
+		select {
+		case evt := <-eventChan:
+			received = append(received, evt)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d events, want %d", len(received), n)
+		}
+	}
+	return received
+}
+
+func TestServeHTTP(t *testing.T) {
+	server, eventChan, cleanup := newTestServer()
+	defer cleanup()
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client, err := mcpclient.Connect(context.Background(), mcpclient.ServerConfig{
+		Name:    "otel",
+		URL:     httpServer.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	listed, err := client.ListTools(context.Background())
+	if err != nil || len(listed) != 1 || listed[0].Name != "validate_sandbox" {
+		t.Fatalf("ListTools() = %+v, %v, want validate_sandbox", listed, err)
+	}
+
+	result, err := client.CallTool(context.Background(), "validate_sandbox", json.RawMessage(`{"sandbox_id":"sb-1"}`))
+	if err != nil || result.IsError || !strings.Contains(result.Content[0].Text, `"status":"valid"`) {
+		t.Fatalf("CallTool() = %+v, %v, want a valid result", result, err)
+	}
+
+	result, err = client.CallTool(context.Background(), "validate_sandbox", nil)
+	if err != nil || !result.IsError || !strings.Contains(result.Content[0].Text, `"field":"sandbox_id"`) {
+		t.Fatalf("CallTool() = %+v, %v, want a validation error naming sandbox_id", result, err)
+	}
+
+	if _, err := client.CallTool(context.Background(), "deploy_everything", nil); err == nil {
+		t.Error("CallTool() of an unknown tool succeeded")
+	}
+
+	client.Close()
+
+	received := receiveEvents(t, eventChan, 6)
+	wantTypes := []events.EventType{events.EventRunStart, events.EventToolCall, events.EventToolResult, events.EventToolCall, events.EventToolResult, events.EventRunEnd}
+	runID := received[0].RunID
+	for i, evt := range received {
+		if evt.Type != wantTypes[i] || evt.RunID != runID || evt.AgentID != AgentID {
+			t.Errorf("event %d = %s for run %s, want %s for run %s", i, evt.Type, evt.RunID, wantTypes[i], runID)
+		}
+	}
+	if !strings.Contains(string(received[2].Data), runID) {
+		t.Errorf("tool result %s does not carry the session's run ID", received[2].Data)
+	}
+
+	// The session is gone after DELETE
+	req, _ := http.NewRequest(http.MethodPost, httpServer.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	req.Header.Set("Mcp-Session-Id", runID)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("request after DELETE returned %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestServeStdio(t *testing.T) {
+	server, eventChan, cleanup := newTestServer()
+	defer cleanup()
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- server.ServeStdio(context.Background(), serverReader, serverWriter)
+		serverWriter.Close()
+	}()
+
+	responses := bufio.NewScanner(clientReader)
+	exchange := func(line string) map[string]interface{} {
+		t.Helper()
+		fmt.Fprintln(clientWriter, line)
+		if !responses.Scan() {
+			t.Fatalf("no response to %s", line)
+		}
+		var resp map[string]interface{}
+		json.Unmarshal(responses.Bytes(), &resp)
+		return resp
+	}
+
+	if resp := exchange(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`); resp["error"] == nil {
+		t.Errorf("tools/list before initialize = %v, want an error", resp)
+	}
+
+	resp := exchange(`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"ide","version":"1"}}}`)
+	if result, _ := resp["result"].(map[string]interface{}); result["protocolVersion"] != "2025-03-26" {
+		t.Errorf("initialize = %v, want the client's protocol version", resp)
+	}
+	fmt.Fprintln(clientWriter, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	resp = exchange(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"validate_sandbox","arguments":{"sandbox_id":"sb-2"}}}`)
+	if result, _ := resp["result"].(map[string]interface{}); result == nil || result["isError"] == true {
+		t.Errorf("tools/call = %v, want a result", resp)
+	}
+
+	clientWriter.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("ServeStdio() error = %v", err)
+	}
+
+	received := receiveEvents(t, eventChan, 4)
+	if received[0].AgentName != "MCP: ide" || received[3].Type != events.EventRunEnd {
+		t.Errorf("events = %s (%s) ... %s, want a run for the ide session", received[0].Type, received[0].AgentName, received[3].Type)
+	}
+}
+
+func TestServeHTTPAccess(t *testing.T) {
+	server, _, cleanup := newTestServer()
+	defer cleanup()
+	noToken := New(Config{Name: "test", Version: "1.0.0", Tools: func() []tools.Tool { return testTools }})
+
+	tests := []struct {
+		name          string
+		server        *Server
+		authorization string
+		origin        string
+		want          int
+	}{
+		{name: "valid token", server: server, authorization: "Bearer secret", want: http.StatusOK},
+		{name: "allowed origin", server: server, authorization: "Bearer secret", origin: "http://localhost:3000", want: http.StatusOK},
+		{name: "missing token", server: server, want: http.StatusUnauthorized},
+		{name: "wrong token", server: server, authorization: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "other origin", server: server, authorization: "Bearer secret", origin: "https://evil.example", want: http.StatusForbidden},
+		{name: "no token configured", server: noToken, authorization: "Bearer ", want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"t","version":"1"}}}`
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json, text/event-stream")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+
+			rec := httptest.NewRecorder()
+			tt.server.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package mcpserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+)
+
+// ServeStdio serves one client that sends newline-delimited JSON-RPC
+// messages on r and reads responses from w, until r is closed. Requests
+// after initialization are handled concurrently, so a long tool call does
+// not block pings or other calls.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	var writeMu sync.Mutex
+	write := func(resp *response) {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+
+	var wg sync.WaitGroup
+	var session *Session
+	reader := bufio.NewReader(r)
+
+	var readErr error
+	for readErr == nil {
+		var line []byte
+		line, readErr = reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+
+		var msg message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			write(errorResponse(nil, codeParseError, "invalid JSON: %v", err))
+			continue
+		}
+
+		switch {
+		case msg.Method == "initialize" && session == nil:
+			var resp *response
+			session, resp = s.initialize(&msg)
+			write(resp)
+		case session == nil:
+			if msg.isRequest() {
+				write(errorResponse(msg.ID, codeInvalidRequest, "session is not initialized"))
+			}
+		default:
+			wg.Add(1)
+			go func(msg message) {
+				defer wg.Done()
+				if resp := s.handle(ctx, session, &msg); resp != nil {
+					write(resp)
+				}
+			}(msg)
+		}
+	}
+
+	wg.Wait()
+	if session != nil {
+		s.endSession(session, events.RunEndReasonCompleted)
+	}
+
+	if errors.Is(readErr, io.EOF) {
+		return nil
+	}
+	return fmt.Errorf("failed to read MCP messages: %w", readErr)
+}
@@ -0,0 +1,290 @@
+package otlpgen
+
+import (
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Export requests are encoded as TracesData, MetricsData and LogsData, which
+// share their wire format with the Export*ServiceRequest messages without
+// pulling in the gRPC service definitions.
+
+// attr is an attribute; value is a string, int64, float64, bool or []attr
+type attr struct {
+	key   string
+	value interface{}
+}
+
+type span struct {
+	traceID   [16]byte
+	spanID    [8]byte
+	parentID  [8]byte
+	hasParent bool
+	parent    *span
+	name      string
+	kind      int
+	start     time.Time
+	end       time.Time
+	attrs     []attr
+	failed    bool
+	message   string
+}
+
+type metricPoint struct {
+	name        string
+	description string
+	unit        string
+	metricType  string
+	attrs       []attr
+	start       time.Time
+	time        time.Time
+
+	// Counters and gauges
+	intValue   int64
+	floatValue float64
+
+	// Histograms and summaries
+	count     uint64
+	sum       float64
+	min, max  float64
+	bounds    []float64
+	buckets   []uint64
+	scale     int32
+	zeroCount uint64
+	offset    int32
+	quantiles [][2]float64 // quantile, value
+}
+
+type logRecord struct {
+	time     time.Time
+	severity int
+	text     string
+	body     interface{} // string or []attr
+	attrs    []attr
+	traceID  [16]byte
+	spanID   [8]byte
+	hasTrace bool
+}
+
+// resourceBatch is telemetry from one service
+type resourceBatch[T any] struct {
+	resource []attr
+	items    []T
+}
+
+// encodeTraces encodes an ExportTraceServiceRequest
+func encodeTraces(batches []resourceBatch[*span]) []byte {
+	req := &tracepb.TracesData{}
+	for _, batch := range batches {
+		spans := make([]*tracepb.Span, 0, len(batch.items))
+		for _, s := range batch.items {
+			spans = append(spans, s.proto())
+		}
+		req.ResourceSpans = append(req.ResourceSpans, &tracepb.ResourceSpans{
+			Resource:   resource(batch.resource),
+			ScopeSpans: []*tracepb.ScopeSpans{{Scope: scope(), Spans: spans}},
+		})
+	}
+	return marshal(req)
+}
+
+func (s *span) proto() *tracepb.Span {
+	pb := &tracepb.Span{
+		TraceId:           s.traceID[:],
+		SpanId:            s.spanID[:],
+		Name:              s.name,
+		Kind:              tracepb.Span_SpanKind(s.kind),
+		StartTimeUnixNano: unixNano(s.start),
+		EndTimeUnixNano:   unixNano(s.end),
+		Attributes:        keyValues(s.attrs),
+	}
+	if s.hasParent {
+		pb.ParentSpanId = s.parentID[:]
+	}
+	if s.failed {
+		pb.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: s.message}
+	}
+	return pb
+}
+
+// encodeMetrics encodes an ExportMetricsServiceRequest
+func encodeMetrics(batches []resourceBatch[*metricPoint]) []byte {
+	req := &metricspb.MetricsData{}
+	for _, batch := range batches {
+		metrics := make([]*metricspb.Metric, 0, len(batch.items))
+		for _, p := range batch.items {
+			metrics = append(metrics, p.proto())
+		}
+		req.ResourceMetrics = append(req.ResourceMetrics, &metricspb.ResourceMetrics{
+			Resource:     resource(batch.resource),
+			ScopeMetrics: []*metricspb.ScopeMetrics{{Scope: scope(), Metrics: metrics}},
+		})
+	}
+	return marshal(req)
+}
+
+func (p *metricPoint) proto() *metricspb.Metric {
+	pb := &metricspb.Metric{Name: p.name, Description: p.description, Unit: p.unit}
+
+	switch p.metricType {
+	case MetricCounter:
+		pb.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints: []*metricspb.NumberDataPoint{{
+				StartTimeUnixNano: unixNano(p.start),
+				TimeUnixNano:      unixNano(p.time),
+				Value:             &metricspb.NumberDataPoint_AsInt{AsInt: p.intValue},
+				Attributes:        keyValues(p.attrs),
+			}},
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}
+	case MetricGauge:
+		pb.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+			DataPoints: []*metricspb.NumberDataPoint{{
+				TimeUnixNano: unixNano(p.time),
+				Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: p.floatValue},
+				Attributes:   keyValues(p.attrs),
+			}},
+		}}
+	case MetricHistogram:
+		pb.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			DataPoints: []*metricspb.HistogramDataPoint{{
+				StartTimeUnixNano: unixNano(p.start),
+				TimeUnixNano:      unixNano(p.time),
+				Count:             p.count,
+				Sum:               proto.Float64(p.sum),
+				BucketCounts:      p.buckets,
+				ExplicitBounds:    p.bounds,
+				Attributes:        keyValues(p.attrs),
+				Min:               proto.Float64(p.min),
+				Max:               proto.Float64(p.max),
+			}},
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+		}}
+	case MetricExponentialHistogram:
+		pb.Data = &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+			DataPoints: []*metricspb.ExponentialHistogramDataPoint{{
+				Attributes:        keyValues(p.attrs),
+				StartTimeUnixNano: unixNano(p.start),
+				TimeUnixNano:      unixNano(p.time),
+				Count:             p.count,
+				Sum:               proto.Float64(p.sum),
+				Scale:             p.scale,
+				ZeroCount:         p.zeroCount,
+				Positive: &metricspb.ExponentialHistogramDataPoint_Buckets{
+					Offset:       p.offset,
+					BucketCounts: p.buckets,
+				},
+				Min: proto.Float64(p.min),
+				Max: proto.Float64(p.max),
+			}},
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+		}}
+	case MetricSummary:
+		quantiles := make([]*metricspb.SummaryDataPoint_ValueAtQuantile, 0, len(p.quantiles))
+		for _, q := range p.quantiles {
+			quantiles = append(quantiles, &metricspb.SummaryDataPoint_ValueAtQuantile{Quantile: q[0], Value: q[1]})
+		}
+		pb.Data = &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+			DataPoints: []*metricspb.SummaryDataPoint{{
+				StartTimeUnixNano: unixNano(p.start),
+				TimeUnixNano:      unixNano(p.time),
+				Count:             p.count,
+				Sum:               p.sum,
+				QuantileValues:    quantiles,
+				Attributes:        keyValues(p.attrs),
+			}},
+		}}
+	}
+	return pb
+}
+
+// encodeLogs encodes an ExportLogsServiceRequest
+func encodeLogs(batches []resourceBatch[*logRecord]) []byte {
+	req := &logspb.LogsData{}
+	for _, batch := range batches {
+		records := make([]*logspb.LogRecord, 0, len(batch.items))
+		for _, l := range batch.items {
+			records = append(records, l.proto())
+		}
+		req.ResourceLogs = append(req.ResourceLogs, &logspb.ResourceLogs{
+			Resource:  resource(batch.resource),
+			ScopeLogs: []*logspb.ScopeLogs{{Scope: scope(), LogRecords: records}},
+		})
+	}
+	return marshal(req)
+}
+
+func (l *logRecord) proto() *logspb.LogRecord {
+	pb := &logspb.LogRecord{
+		TimeUnixNano:         unixNano(l.time),
+		ObservedTimeUnixNano: unixNano(l.time),
+		SeverityNumber:       logspb.SeverityNumber(l.severity),
+		SeverityText:         l.text,
+		Body:                 anyValue(l.body),
+		Attributes:           keyValues(l.attrs),
+	}
+	if l.hasTrace {
+		pb.TraceId = l.traceID[:]
+		pb.SpanId = l.spanID[:]
+	}
+	return pb
+}
+
+func resource(attrs []attr) *resourcepb.Resource {
+	return &resourcepb.Resource{Attributes: keyValues(attrs)}
+}
+
+func scope() *commonpb.InstrumentationScope {
+	return &commonpb.InstrumentationScope{Name: scopeName, Version: scopeVersion}
+}
+
+func keyValues(attrs []attr) []*commonpb.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		kvs = append(kvs, &commonpb.KeyValue{Key: a.key, Value: anyValue(a.value)})
+	}
+	return kvs
+}
+
+func anyValue(value interface{}) *commonpb.AnyValue {
+	switch v := value.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []attr:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: keyValues(v)}}}
+	}
+	return &commonpb.AnyValue{}
+}
+
+// unixNano returns a timestamp as OTLP stores it, with the zero time unset
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+// marshal encodes a message built by this package, which cannot fail
+func marshal(m proto.Message) []byte {
+	data, err := proto.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
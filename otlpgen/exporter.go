@@ -0,0 +1,161 @@
+package otlpgen
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Signals
+const (
+	signalTraces  = "traces"
+	signalMetrics = "metrics"
+	signalLogs    = "logs"
+)
+
+// Content types of export requests
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// grpcMethods are the OTLP/gRPC export methods by signal
+var grpcMethods = map[string]string{
+	signalTraces:  "/opentelemetry.proto.collector.trace.v1.TraceService/Export",
+	signalMetrics: "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export",
+	signalLogs:    "/opentelemetry.proto.collector.logs.v1.LogsService/Export",
+}
+
+// exporter sends export requests over OTLP/gRPC, or OTLP/HTTP with protobuf
+// or JSON payloads, to one or more receivers
+type exporter struct {
+	client   *http.Client
+	baseURLs []string
+	protocol string
+	headers  map[string]string
+}
+
+func newExporter(endpoints []string, protocol string, headers map[string]string, insecureSkipVerify bool) *exporter {
+	baseURLs := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+		baseURLs[i] = strings.TrimSuffix(endpoint, "/")
+	}
+
+	// gRPC needs HTTP/2, which without TLS means h2c
+	protocols := new(http.Protocols)
+	switch {
+	case protocol == ProtocolGRPC:
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	}
+
+	return &exporter{
+		client: &http.Client{Transport: &http.Transport{
+			Protocols:       protocols,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify},
+		}},
+		baseURLs: baseURLs,
+		protocol: protocol,
+		headers:  headers,
+	}
+}
+
+// export sends an encoded Export*ServiceRequest to every receiver, returning
+// the first error
+func (e *exporter) export(ctx context.Context, signal string, message []byte, contentType string) error {
+	if e.protocol == ProtocolGRPC && contentType != contentTypeProtobuf {
+		return fmt.Errorf("failed to export %s: JSON can only be sent over OTLP/HTTP", signal)
+	}
+
+	var firstErr error
+	for _, baseURL := range e.baseURLs {
+		var err error
+		if e.protocol == ProtocolGRPC {
+			err = e.exportGRPC(ctx, baseURL, signal, message)
+		} else {
+			err = e.exportHTTP(ctx, baseURL, signal, message, contentType)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (e *exporter) exportHTTP(ctx context.Context, baseURL, signal string, message []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/v1/"+signal, bytes.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export %s: %w", signal, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export %s: HTTP %d: %s", signal, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (e *exporter) exportGRPC(ctx context.Context, baseURL, signal string, message []byte) error {
+	// A unary request is a single uncompressed, length-prefixed message
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	frame = append(frame, message...)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+grpcMethods[signal], bytes.NewReader(frame))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export %s: %w", signal, err)
+	}
+	defer resp.Body.Close()
+	// Trailers are only available once the body has been read
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to export %s: HTTP %d", signal, resp.StatusCode)
+	}
+
+	// Errors may come as trailers or, with no response message, as headers
+	status, detail := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, detail = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	if status != "0" {
+		return fmt.Errorf("failed to export %s: gRPC status %s: %s", signal, status, detail)
+	}
+	return nil
+}
+
+// close releases idle connections
+func (e *exporter) close() {
+	e.client.CloseIdleConnections()
+}
@@ -0,0 +1,340 @@
+package otlpgen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxFixtureSize bounds fixtures, which are held in memory for replay
+const maxFixtureSize = 256 << 20
+
+// Fixture is recorded telemetry to replay: a sequence of OTLP export requests
+type Fixture struct {
+	Name     string
+	requests []*fixtureRequest
+}
+
+// fixtureRequest is one recorded export request
+type fixtureRequest struct {
+	signal string
+	items  int // Spans, metric points or log records
+
+	// Requests are kept decoded, protobuf ones in message and JSON ones in
+	// doc
+	message proto.Message
+	doc     interface{}
+
+	// Earliest and latest timestamps, zero if the request has none
+	first, last time.Time
+}
+
+// requestTypes create the messages protobuf requests of each signal decode
+// into. TracesData, MetricsData and LogsData share their wire format with
+// the Export*ServiceRequest messages.
+var requestTypes = map[string]func() proto.Message{
+	signalTraces:  func() proto.Message { return &tracepb.TracesData{} },
+	signalMetrics: func() proto.Message { return &metricspb.MetricsData{} },
+	signalLogs:    func() proto.Message { return &logspb.LogsData{} },
+}
+
+// protoItems are the OTLP protobuf fields listing spans, metric points and
+// log records
+var protoItems = map[protoreflect.Name]bool{"spans": true, "data_points": true, "log_records": true}
+
+// jsonSignals maps the top-level fields of OTLP JSON requests to signals
+var jsonSignals = map[string]string{
+	"resourceSpans":   signalTraces,
+	"resourceMetrics": signalMetrics,
+	"resourceLogs":    signalLogs,
+}
+
+// jsonItems are the OTLP JSON fields listing spans, metric points and log
+// records
+var jsonItems = map[string]bool{"spans": true, "dataPoints": true, "logRecords": true}
+
+// ReadFixture reads a fixture from a file. signal is needed for protobuf
+// files; if empty, it is taken from a file name containing "traces",
+// "metrics" or "logs".
+func ReadFixture(path, signal string) (*Fixture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxFixtureSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if signal == "" {
+		name := strings.ToLower(filepath.Base(path))
+		for _, s := range []string{signalTraces, signalMetrics, signalLogs} {
+			if strings.Contains(name, strings.TrimSuffix(s, "s")) {
+				signal = s
+				break
+			}
+		}
+	}
+
+	fixture, err := LoadFixture(filepath.Base(path), data, signal)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return fixture, nil
+}
+
+// LoadFixture parses recorded telemetry, optionally gzipped, in the formats
+// the collector's file exporter writes: OTLP JSON with one request per line
+// (or concatenated documents), or OTLP protobuf with each request prefixed by
+// its length as a 4-byte big-endian integer. A single unprefixed protobuf
+// request, as sent over OTLP/HTTP, is also accepted. Protobuf data holds one
+// signal, which must be given; JSON requests name their own.
+func LoadFixture(name string, data []byte, signal string) (*Fixture, error) {
+	if len(data) > maxFixtureSize {
+		return nil, fmt.Errorf("fixture is larger than %d MiB", maxFixtureSize>>20)
+	}
+
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		data, err = io.ReadAll(io.LimitReader(reader, maxFixtureSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		if len(data) > maxFixtureSize {
+			return nil, fmt.Errorf("fixture is larger than %d MiB", maxFixtureSize>>20)
+		}
+	}
+
+	fixture := &Fixture{Name: name}
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		fixture.requests, err = loadJSON(trimmed)
+	} else {
+		fixture.requests, err = loadProtobuf(data, signal)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(fixture.requests) == 0 {
+		return nil, fmt.Errorf("fixture holds no telemetry")
+	}
+	return fixture, nil
+}
+
+// Items returns the number of spans, metric points and log records in the
+// fixture
+func (f *Fixture) Items() (spans, metricPoints, logs int) {
+	for _, req := range f.requests {
+		switch req.signal {
+		case signalTraces:
+			spans += req.items
+		case signalMetrics:
+			metricPoints += req.items
+		case signalLogs:
+			logs += req.items
+		}
+	}
+	return spans, metricPoints, logs
+}
+
+func loadProtobuf(data []byte, signal string) ([]*fixtureRequest, error) {
+	newMessage, ok := requestTypes[signal]
+	if !ok {
+		return nil, fmt.Errorf("the signal of protobuf data must be %s, %s or %s, got %q", signalTraces, signalMetrics, signalLogs, signal)
+	}
+
+	// Requests start with field 1, ResourceSpans etc., while a length prefix
+	// starts with a zero byte for any request under 16 MiB
+	var messages [][]byte
+	if len(data) > 0 && uint64(data[0]) == protowire.EncodeTag(1, protowire.BytesType) {
+		messages = [][]byte{data}
+	} else {
+		for len(data) > 0 {
+			if len(data) < 4 {
+				return nil, fmt.Errorf("truncated length prefix")
+			}
+			length := binary.BigEndian.Uint32(data)
+			if uint64(length) > uint64(len(data)-4) {
+				return nil, fmt.Errorf("truncated message of %d bytes", length)
+			}
+			messages = append(messages, data[4:4+length])
+			data = data[4+length:]
+		}
+	}
+
+	requests := make([]*fixtureRequest, 0, len(messages))
+	for i, body := range messages {
+		message := newMessage()
+		if err := proto.Unmarshal(body, message); err != nil {
+			return nil, fmt.Errorf("invalid %s request %d: %w", signal, i+1, err)
+		}
+		req := &fixtureRequest{signal: signal, message: message}
+		req.walkProto(message.ProtoReflect(), nil)
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+// walkProto counts the items in an OTLP protobuf message and observes its
+// timestamps, replacing them with shift(t) if shift is set. Timestamps are
+// the fixed64 fields ending in time_unix_nano.
+func (req *fixtureRequest) walkProto(m protoreflect.Message, shift func(uint64) uint64) {
+	shifted := make(map[protoreflect.FieldDescriptor]uint64)
+	m.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case field.IsList() && field.Message() != nil:
+			list := value.List()
+			if shift == nil && protoItems[field.Name()] {
+				req.items += list.Len()
+			}
+			for i := 0; i < list.Len(); i++ {
+				req.walkProto(list.Get(i).Message(), shift)
+			}
+		case field.Message() != nil && !field.IsMap():
+			req.walkProto(value.Message(), shift)
+		case field.Kind() == protoreflect.Fixed64Kind && !field.IsList() && strings.HasSuffix(string(field.Name()), "time_unix_nano"):
+			if shift == nil {
+				req.observe(value.Uint())
+			} else {
+				shifted[field] = shift(value.Uint())
+			}
+		}
+		return true
+	})
+
+	// Fields are set after ranging, which must not modify the message
+	for field, nanos := range shifted {
+		m.Set(field, protoreflect.ValueOfUint64(nanos))
+	}
+}
+
+func loadJSON(data []byte) ([]*fixtureRequest, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var requests []*fixtureRequest
+	for {
+		var doc map[string]interface{}
+		if err := decoder.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid JSON request %d: %w", len(requests)+1, err)
+		}
+
+		// The file exporter writes one signal per request
+		for field, signal := range jsonSignals {
+			if _, ok := doc[field]; !ok {
+				continue
+			}
+			req := &fixtureRequest{signal: signal, doc: doc}
+			req.walkJSON(doc, "", nil)
+			requests = append(requests, req)
+			break
+		}
+	}
+	return requests, nil
+}
+
+// walkJSON counts the items in an OTLP JSON value and observes its
+// timestamps, replacing them with shift(t) if shift is set. Timestamps are
+// the fields ending in UnixNano, as strings or numbers.
+func (req *fixtureRequest) walkJSON(value interface{}, key string, shift func(uint64) uint64) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := v
+		if shift != nil {
+			out = make(map[string]interface{}, len(v))
+		}
+		for k, child := range v {
+			out[k] = req.walkJSON(child, k, shift)
+		}
+		return out
+	case []interface{}:
+		if shift == nil && jsonItems[key] {
+			req.items += len(v)
+		}
+		out := v
+		if shift != nil {
+			out = make([]interface{}, len(v))
+		}
+		for i, child := range v {
+			out[i] = req.walkJSON(child, "", shift)
+		}
+		return out
+	case string, json.Number:
+		if !strings.HasSuffix(key, "UnixNano") {
+			return v
+		}
+		nanos, err := strconv.ParseUint(fmt.Sprint(v), 10, 64)
+		if err != nil || nanos == 0 {
+			return v
+		}
+		if shift == nil {
+			req.observe(nanos)
+			return v
+		}
+		shifted := strconv.FormatUint(shift(nanos), 10)
+		if _, ok := v.(json.Number); ok {
+			return json.Number(shifted)
+		}
+		return shifted
+	}
+	return value
+}
+
+// observe widens the request's time range to include a timestamp
+func (req *fixtureRequest) observe(nanos uint64) {
+	if nanos == 0 {
+		return
+	}
+	t := time.Unix(0, int64(nanos))
+	if req.first.IsZero() || t.Before(req.first) {
+		req.first = t
+	}
+	if t.After(req.last) {
+		req.last = t
+	}
+}
+
+// encode returns the request's body and content type, with its timestamps
+// moved by shift
+func (req *fixtureRequest) encode(shift time.Duration) ([]byte, string, error) {
+	move := func(nanos uint64) uint64 { return uint64(int64(nanos) + int64(shift)) }
+
+	if req.doc != nil {
+		doc := req.doc
+		if shift != 0 {
+			doc = req.walkJSON(doc, "", move)
+		}
+		body, err := json.Marshal(doc)
+		return body, contentTypeJSON, err
+	}
+
+	message := req.message
+	if shift != 0 {
+		message = proto.Clone(message)
+		req.walkProto(message.ProtoReflect(), move)
+	}
+	body, err := proto.Marshal(message)
+	return body, contentTypeProtobuf, err
+}
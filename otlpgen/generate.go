@@ -0,0 +1,389 @@
+package otlpgen
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Instrumentation scope of the generated telemetry
+const (
+	scopeName    = "otel-ai-engineer/otlpgen"
+	scopeVersion = "1.0.0"
+)
+
+// defaultHistogramBounds are the default explicit bounds of the OTel SDKs
+var defaultHistogramBounds = []float64{0, 5, 10, 25, 50, 75, 100, 250, 500, 750, 1000, 2500, 5000, 7500, 10000}
+
+// expHistogramScale gives exponential histogram buckets a base of 2^(1/8)
+const expHistogramScale = 3
+
+// samplesPerPoint is the number of measurements behind each histogram or
+// summary point
+const samplesPerPoint = 20
+
+var defaultLogMessages = []string{"request handled", "cache refreshed", "connection pool resized"}
+var defaultLogSeverities = []string{"info", "info", "info", "warn"}
+
+// series is the state of one cumulative or delta metric stream
+type series struct {
+	start time.Time
+	total int64
+}
+
+// generator turns a scenario into telemetry. It is not safe for concurrent
+// use; Generator serializes access.
+type generator struct {
+	scenario  *Scenario
+	rand      *rand.Rand
+	resources [][]attr // Resource attributes by service index
+	series    map[string]*series
+	metricSeq int
+
+	// The most recent span, so logs can be correlated with traces
+	lastTraceID [16]byte
+	lastSpanID  [8]byte
+	hasTrace    bool
+}
+
+func newGenerator(scenario *Scenario, seed uint64) *generator {
+	g := &generator{
+		scenario: scenario,
+		rand:     rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+		series:   make(map[string]*series),
+	}
+	for _, service := range scenario.Services {
+		resource := []attr{{"service.name", service.Name}}
+		if service.Version != "" {
+			resource = append(resource, attr{"service.version", service.Version})
+		}
+		resource = append(resource, sortedAttrs(scenario.Resource)...)
+		resource = append(resource, sortedAttrs(service.Resource)...)
+		g.resources = append(g.resources, resource)
+	}
+	if len(g.resources) == 0 {
+		// Metrics and logs still need a resource
+		g.resources = append(g.resources, append([]attr{{"service.name", "otlpgen"}}, sortedAttrs(scenario.Resource)...))
+	}
+	return g
+}
+
+// commonAttrs returns the scenario-wide attributes, with the
+// high-cardinality attribute if enabled
+func (g *generator) commonAttrs() []attr {
+	attrs := sortedAttrs(g.scenario.Attributes)
+	if g.scenario.Cardinality > 0 {
+		key := g.scenario.CardinalityKey
+		if key == "" {
+			key = DefaultCardinalityKey
+		}
+		attrs = append(attrs, attr{key, fmt.Sprintf("user-%d", g.rand.IntN(g.scenario.Cardinality))})
+	}
+	return attrs
+}
+
+// traces generates n traces, grouped by service
+func (g *generator) traces(n int, now time.Time) ([]resourceBatch[*span], int) {
+	byService := make([][]*span, len(g.scenario.Services))
+	spanCount := 0
+	for i := 0; i < n; i++ {
+		spans := g.trace(now)
+		for _, s := range spans {
+			byService[s.service] = append(byService[s.service], s.span)
+		}
+		spanCount += len(spans)
+	}
+	return batchesOf(g.resources, byService), spanCount
+}
+
+// serviceSpan is a span and the index of the service recording it
+type serviceSpan struct {
+	service int
+	span    *span
+}
+
+// trace generates one trace ending at now
+func (g *generator) trace(now time.Time) []serviceSpan {
+	if len(g.scenario.Services) == 0 || len(g.scenario.Services[0].Operations) == 0 {
+		return nil
+	}
+
+	var traceID [16]byte
+	g.fill(traceID[:])
+	b := &traceBuilder{g: g, traceID: traceID, common: g.commonAttrs()}
+	root := b.operation(0, 0, nil, now)
+
+	// Shift the trace so it ends now
+	shift := root.end.Sub(now)
+	for _, s := range b.spans {
+		s.span.start = s.span.start.Add(-shift)
+		s.span.end = s.span.end.Add(-shift)
+	}
+
+	// Fail a random span and every span waiting on it
+	if g.scenario.ErrorRatio > 0 && g.rand.Float64() < g.scenario.ErrorRatio {
+		failed := b.spans[g.rand.IntN(len(b.spans))].span
+		failed.attrs = append(failed.attrs, attr{"error.type", "SyntheticError"})
+		failed.message = "synthetic failure"
+		for s := failed; s != nil; s = s.parent {
+			s.failed = true
+		}
+	}
+
+	last := b.spans[len(b.spans)-1].span
+	g.lastTraceID, g.lastSpanID, g.hasTrace = last.traceID, last.spanID, true
+	return b.spans
+}
+
+// traceBuilder builds the spans of one trace
+type traceBuilder struct {
+	g       *generator
+	traceID [16]byte
+	common  []attr
+	spans   []serviceSpan
+}
+
+// newSpan starts a span of a service
+func (b *traceBuilder) newSpan(service int, name string, kind int, parent *span, start time.Time) *span {
+	s := &span{traceID: b.traceID, name: name, kind: kind, start: start, parent: parent}
+	b.g.fill(s.spanID[:])
+	if parent != nil {
+		s.parentID = parent.spanID
+		s.hasParent = true
+	}
+	b.spans = append(b.spans, serviceSpan{service: service, span: s})
+	return s
+}
+
+// operation records an operation and its calls starting at start. Validate
+// rejects call cycles, so the recursion ends.
+func (b *traceBuilder) operation(service, op int, parent *span, start time.Time) *span {
+	svc := &b.g.scenario.Services[service]
+	operation := &svc.Operations[op]
+
+	s := b.newSpan(service, operation.Name, spanKinds[kindOrDefault(operation.Kind)], parent, start)
+	s.attrs = append(sortedAttrs(operation.Attributes), b.common...)
+
+	// Spend a quarter of the operation's own time before its calls
+	own := b.g.jitter(operation.DurationMs)
+	cursor := start.Add(own / 4)
+
+	for _, call := range operation.Calls {
+		if call.Service == "" || call.Service == svc.Name {
+			child := b.operation(service, svc.operation(call.Operation), s, cursor)
+			cursor = child.end
+			continue
+		}
+
+		callee := b.g.serviceIndex(call.Service)
+		client := b.newSpan(service, call.Operation, spanKinds[KindClient], s, cursor)
+		client.attrs = append([]attr{{"peer.service", call.Service}}, b.common...)
+		server := b.operation(callee, b.g.scenario.Services[callee].operation(call.Operation), client, cursor.Add(time.Millisecond))
+		client.end = server.end.Add(time.Millisecond)
+		cursor = client.end
+	}
+
+	s.end = cursor.Add(own - own/4)
+	return s
+}
+
+// metrics generates n metric points, cycling through the scenario's metrics
+func (g *generator) metrics(n int, now time.Time) []resourceBatch[*metricPoint] {
+	byService := make([][]*metricPoint, len(g.resources))
+	if len(g.scenario.Metrics) == 0 {
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		spec := &g.scenario.Metrics[g.metricSeq%len(g.scenario.Metrics)]
+		g.metricSeq++
+		service := g.rand.IntN(len(g.resources))
+		byService[service] = append(byService[service], g.metricPoint(spec, service, now))
+	}
+	return batchesOf(g.resources, byService)
+}
+
+func (g *generator) metricPoint(spec *MetricSpec, service int, now time.Time) *metricPoint {
+	p := &metricPoint{
+		name:        spec.Name,
+		description: spec.Description,
+		unit:        spec.Unit,
+		metricType:  spec.Type,
+		attrs:       append(sortedAttrs(spec.Attributes), g.commonAttrs()...),
+		time:        now,
+	}
+
+	// Each service and attribute set is its own stream
+	key := fmt.Sprintf("%s|%d|%v", spec.Name, service, p.attrs)
+	state, ok := g.series[key]
+	if !ok {
+		state = &series{start: now}
+		g.series[key] = state
+	}
+	p.start = state.start
+
+	value := spec.Value
+	if value <= 0 {
+		value = 100
+	}
+
+	switch spec.Type {
+	case MetricCounter:
+		state.total += int64(math.Round(value * 2 * g.rand.Float64()))
+		p.intValue = state.total
+	case MetricGauge:
+		p.floatValue = value * (0.5 + g.rand.Float64())
+	case MetricHistogram, MetricExponentialHistogram, MetricSummary:
+		samples := make([]float64, samplesPerPoint)
+		for i := range samples {
+			samples[i] = value * g.rand.ExpFloat64()
+		}
+		p.summarize(samples, spec.Buckets)
+		if spec.Type != MetricSummary {
+			// Histograms are delta: each point covers the time since the last
+			state.start = now
+		}
+	}
+	return p
+}
+
+// summarize fills the histogram or summary fields from measurements
+func (p *metricPoint) summarize(samples []float64, bounds []float64) {
+	sort.Float64s(samples)
+	p.count = uint64(len(samples))
+	p.min, p.max = samples[0], samples[len(samples)-1]
+	for _, v := range samples {
+		p.sum += v
+	}
+
+	switch p.metricType {
+	case MetricHistogram:
+		p.bounds = bounds
+		if len(p.bounds) == 0 {
+			p.bounds = defaultHistogramBounds
+		}
+		p.buckets = make([]uint64, len(p.bounds)+1)
+		for _, v := range samples {
+			p.buckets[sort.SearchFloat64s(p.bounds, v)]++
+		}
+	case MetricExponentialHistogram:
+		// Bucket i holds values in (base^i, base^(i+1)] with base 2^(2^-scale)
+		p.scale = expHistogramScale
+		factor := math.Ldexp(1, expHistogramScale) / math.Ln2
+		var indexes []int32
+		for _, v := range samples {
+			if v == 0 {
+				p.zeroCount++
+				continue
+			}
+			indexes = append(indexes, int32(math.Ceil(math.Log(v)*factor))-1)
+		}
+		if len(indexes) > 0 {
+			p.offset = indexes[0]
+			p.buckets = make([]uint64, indexes[len(indexes)-1]-p.offset+1)
+			for _, index := range indexes {
+				p.buckets[index-p.offset]++
+			}
+		}
+	case MetricSummary:
+		for _, q := range []float64{0.5, 0.9, 0.99} {
+			p.quantiles = append(p.quantiles, [2]float64{q, samples[int(q*float64(len(samples)-1))]})
+		}
+	}
+}
+
+// logs generates n log records
+func (g *generator) logs(n int, now time.Time) []resourceBatch[*logRecord] {
+	spec := &g.scenario.Logs
+	messages := spec.Messages
+	if len(messages) == 0 {
+		messages = defaultLogMessages
+	}
+	severities := spec.Severities
+	if len(severities) == 0 {
+		severities = defaultLogSeverities
+	}
+
+	byService := make([][]*logRecord, len(g.resources))
+	for i := 0; i < n; i++ {
+		severity := strings.ToLower(severities[g.rand.IntN(len(severities))])
+		message := messages[g.rand.IntN(len(messages))]
+		if g.scenario.ErrorRatio > 0 && g.rand.Float64() < g.scenario.ErrorRatio {
+			severity = "error"
+			message = "failed: " + message
+		}
+
+		service := g.rand.IntN(len(g.resources))
+		record := &logRecord{
+			time:     now,
+			severity: severityNumbers[severity],
+			text:     strings.ToUpper(severity),
+			body:     message,
+			attrs:    append(sortedAttrs(spec.Attributes), g.commonAttrs()...),
+		}
+		if spec.Structured {
+			record.body = []attr{
+				{"message", message},
+				{"request.id", fmt.Sprintf("req-%08x", g.rand.Uint32())},
+				{"duration_ms", int64(g.jitter(20) / time.Millisecond)},
+			}
+		}
+		if g.hasTrace {
+			record.traceID, record.spanID, record.hasTrace = g.lastTraceID, g.lastSpanID, true
+		}
+		byService[service] = append(byService[service], record)
+	}
+	return batchesOf(g.resources, byService)
+}
+
+// batchesOf groups items by the resource of their service
+func batchesOf[T any](resources [][]attr, byService [][]T) []resourceBatch[T] {
+	var batches []resourceBatch[T]
+	for i, items := range byService {
+		if len(items) > 0 {
+			batches = append(batches, resourceBatch[T]{resource: resources[i], items: items})
+		}
+	}
+	return batches
+}
+
+func (g *generator) serviceIndex(name string) int {
+	for i, service := range g.scenario.Services {
+		if service.Name == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// jitter returns a duration within 25% of ms milliseconds (10 if unset)
+func (g *generator) jitter(ms int) time.Duration {
+	if ms <= 0 {
+		ms = 10
+	}
+	base := time.Duration(ms) * time.Millisecond
+	return base*3/4 + time.Duration(g.rand.Int64N(int64(base/2)+1))
+}
+
+// fill fills an ID with random bytes
+func (g *generator) fill(id []byte) {
+	for i := range id {
+		id[i] = byte(g.rand.Uint32())
+	}
+}
+
+// sortedAttrs converts attributes to string attributes in key order
+func sortedAttrs(m map[string]string) []attr {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	attrs := make([]attr, 0, len(keys))
+	for _, key := range keys {
+		attrs = append(attrs, attr{key, m[key]})
+	}
+	return attrs
+}
@@ -0,0 +1,274 @@
+package otlpgen
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Export protocols
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// tickInterval is how often the generator exports
+const tickInterval = 100 * time.Millisecond
+
+// exportTimeout bounds each export request
+const exportTimeout = 10 * time.Second
+
+// Config configures a Generator
+type Config struct {
+	// Endpoint is the OTLP receiver, e.g. localhost:4317 or
+	// http://localhost:4318. Without a scheme, plain text is used.
+	Endpoint string
+
+	// Protocol is ProtocolGRPC (default) or ProtocolHTTP
+	Protocol string
+
+	// Mirrors are further receivers sent every request Endpoint is, e.g. to
+	// compare collectors on identical input. A request counts as sent when
+	// every receiver accepts it.
+	Mirrors []string
+
+	// Headers are sent with every export request
+	Headers map[string]string
+
+	// Insecure skips TLS certificate verification for https endpoints
+	Insecure bool
+
+	// Scenario is the telemetry to generate (default: DefaultScenario)
+	Scenario *Scenario
+
+	// Traces, metric points and log records per second; 0 disables a signal
+	TraceRate  int
+	MetricRate int
+	LogRate    int
+
+	// Duration stops the generator after this long; 0 runs until the
+	// context is cancelled
+	Duration time.Duration
+
+	// Seed makes the generated telemetry reproducible; 0 picks a random seed
+	Seed uint64
+}
+
+// Stats counts what a Generator or Replay has sent
+type Stats struct {
+	Scenario      string     `json:"scenario,omitempty"`
+	Endpoint      string     `json:"endpoint"`
+	Protocol      string     `json:"protocol"`
+	Running       bool       `json:"running"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	Traces        int64      `json:"traces"`
+	Spans         int64      `json:"spans"`
+	MetricPoints  int64      `json:"metric_points"`
+	Logs          int64      `json:"logs"`
+	FailedExports int64      `json:"failed_exports"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// recorder keeps the Stats of a run
+type recorder struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+func (r *recorder) start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Running = true
+	r.stats.StartedAt = time.Now()
+}
+
+func (r *recorder) finish() {
+	finished := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Running = false
+	r.stats.FinishedAt = &finished
+}
+
+// record adds to the stats with fn
+func (r *recorder) record(fn func(stats *Stats)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&r.stats)
+}
+
+// Stats returns what has been sent so far
+func (r *recorder) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	if stats.FinishedAt != nil {
+		finished := *stats.FinishedAt
+		stats.FinishedAt = &finished
+	}
+	return stats
+}
+
+// export sends one request, recording failures
+func (r *recorder) export(ctx context.Context, e *exporter, signal string, message []byte, contentType string) error {
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+
+	err := e.export(ctx, signal, message, contentType)
+	if err != nil && ctx.Err() == nil {
+		r.record(func(stats *Stats) {
+			stats.FailedExports++
+			stats.LastError = err.Error()
+		})
+	}
+	return err
+}
+
+// checkEndpoint checks an endpoint and protocol, returning the protocol with
+// its default applied
+func checkEndpoint(endpoint, protocol string) (string, error) {
+	if endpoint == "" {
+		return "", fmt.Errorf("endpoint is required")
+	}
+	switch protocol {
+	case "":
+		return ProtocolGRPC, nil
+	case ProtocolGRPC, ProtocolHTTP:
+		return protocol, nil
+	}
+	return "", fmt.Errorf("unknown protocol %q (must be %s or %s)", protocol, ProtocolGRPC, ProtocolHTTP)
+}
+
+// Generator sends scenario telemetry to an OTLP receiver at fixed rates
+type Generator struct {
+	recorder
+	cfg      Config
+	exporter *exporter
+
+	mu  sync.Mutex // Guards gen
+	gen *generator
+}
+
+// New creates a generator
+func New(cfg Config) (*Generator, error) {
+	protocol, err := checkEndpoint(cfg.Endpoint, cfg.Protocol)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Protocol = protocol
+	if cfg.TraceRate < 0 || cfg.MetricRate < 0 || cfg.LogRate < 0 {
+		return nil, fmt.Errorf("rates must not be negative")
+	}
+	if cfg.Scenario == nil {
+		cfg.Scenario = DefaultScenario()
+	}
+	if err := cfg.Scenario.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+
+	return &Generator{
+		cfg:      cfg,
+		exporter: newExporter(append([]string{cfg.Endpoint}, cfg.Mirrors...), cfg.Protocol, cfg.Headers, cfg.Insecure),
+		gen:      newGenerator(cfg.Scenario, seed),
+		recorder: recorder{stats: Stats{
+			Scenario: cfg.Scenario.Name,
+			Endpoint: cfg.Endpoint,
+			Protocol: cfg.Protocol,
+		}},
+	}, nil
+}
+
+// Run generates telemetry until the configured duration elapses or ctx is
+// cancelled. Failed exports are counted in Stats rather than stopping the
+// run, since collectors commonly take a moment to start listening.
+func (g *Generator) Run(ctx context.Context) error {
+	if g.cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.cfg.Duration)
+		defer cancel()
+	}
+	defer g.exporter.close()
+
+	started := time.Now()
+	g.start()
+	defer g.finish()
+
+	// Keep each signal at its rate by sending whatever is due since start
+	var tracesSent, metricsSent, logsSent int
+	due := func(rate, sent int, elapsed time.Duration) int {
+		n := int(float64(rate)*elapsed.Seconds()) - sent
+		return min(n, rate)
+	}
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		elapsed := time.Since(started) + tickInterval
+		tracesSent += g.sendTraces(ctx, due(g.cfg.TraceRate, tracesSent, elapsed))
+		metricsSent += g.sendMetrics(ctx, due(g.cfg.MetricRate, metricsSent, elapsed))
+		logsSent += g.sendLogs(ctx, due(g.cfg.LogRate, logsSent, elapsed))
+
+		select {
+		case <-ctx.Done():
+			if g.cfg.Duration > 0 && ctx.Err() == context.DeadlineExceeded {
+				return nil
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *Generator) sendTraces(ctx context.Context, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	g.mu.Lock()
+	batches, spans := g.gen.traces(n, time.Now())
+	g.mu.Unlock()
+
+	if err := g.export(ctx, g.exporter, signalTraces, encodeTraces(batches), contentTypeProtobuf); err == nil {
+		g.record(func(stats *Stats) {
+			stats.Traces += int64(n)
+			stats.Spans += int64(spans)
+		})
+	}
+	return n
+}
+
+func (g *Generator) sendMetrics(ctx context.Context, n int) int {
+	if n <= 0 || len(g.cfg.Scenario.Metrics) == 0 {
+		return 0
+	}
+	g.mu.Lock()
+	batches := g.gen.metrics(n, time.Now())
+	g.mu.Unlock()
+
+	if err := g.export(ctx, g.exporter, signalMetrics, encodeMetrics(batches), contentTypeProtobuf); err == nil {
+		g.record(func(stats *Stats) { stats.MetricPoints += int64(n) })
+	}
+	return n
+}
+
+func (g *Generator) sendLogs(ctx context.Context, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	g.mu.Lock()
+	batches := g.gen.logs(n, time.Now())
+	g.mu.Unlock()
+
+	if err := g.export(ctx, g.exporter, signalLogs, encodeLogs(batches), contentTypeProtobuf); err == nil {
+		g.record(func(stats *Stats) { stats.Logs += int64(n) })
+	}
+	return n
+}
@@ -0,0 +1,264 @@
+package otlpgen
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
+)
+
+// newSink starts a capture sink accepting OTLP/gRPC and OTLP/HTTP
+func newSink(t *testing.T) (*otlpsink.Sink, *httptest.Server) {
+	sink := otlpsink.New(0)
+	server := httptest.NewUnstartedServer(sink)
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	t.Cleanup(server.Close)
+	return sink, server
+}
+
+// run generates telemetry for a short while and returns what the sink got
+func run(t *testing.T, protocol string, scenario *Scenario) (*otlpsink.Telemetry, Stats) {
+	sink, server := newSink(t)
+
+	endpoint := server.URL
+	if protocol == ProtocolGRPC {
+		endpoint = strings.TrimPrefix(endpoint, "http://")
+	}
+	gen, err := New(Config{
+		Endpoint:   endpoint,
+		Protocol:   protocol,
+		Headers:    map[string]string{otlpsink.SandboxHeader: "sb-1"},
+		Scenario:   scenario,
+		TraceRate:  20,
+		MetricRate: 20,
+		LogRate:    20,
+		Duration:   300 * time.Millisecond,
+		Seed:       42,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	stats := gen.Stats()
+	if stats.FailedExports > 0 {
+		t.Fatalf("%d exports failed: %s", stats.FailedExports, stats.LastError)
+	}
+	if stats.Running || stats.FinishedAt == nil {
+		t.Errorf("generator still running after Run: %+v", stats)
+	}
+	return sink.Telemetry("sb-1", "", 0), stats
+}
+
+func TestGenerateTraces(t *testing.T) {
+	for _, protocol := range []string{ProtocolGRPC, ProtocolHTTP} {
+		t.Run(protocol, func(t *testing.T) {
+			telemetry, stats := run(t, protocol, nil)
+			if stats.Traces == 0 || int(stats.Spans) != len(telemetry.Spans) {
+				t.Fatalf("stats %+v, sink got %d spans", stats, len(telemetry.Spans))
+			}
+
+			// Every span but the root has its parent in the same trace
+			spans := make(map[string]otlpsink.Span)
+			for _, s := range telemetry.Spans {
+				spans[s.TraceID+s.SpanID] = s
+			}
+			services := make(map[string]bool)
+			for _, s := range telemetry.Spans {
+				services[s.Resource["service.name"].(string)] = true
+				if s.ParentSpanID == "" {
+					if s.Name != "POST /checkout" {
+						t.Errorf("root span is %q", s.Name)
+					}
+					continue
+				}
+				parent, ok := spans[s.TraceID+s.ParentSpanID]
+				if !ok {
+					t.Fatalf("span %q has no parent", s.Name)
+				}
+				if s.StartTime.Before(parent.StartTime) || s.EndTime.After(parent.EndTime) {
+					t.Errorf("span %q is outside its parent %q", s.Name, parent.Name)
+				}
+			}
+			for _, name := range []string{"frontend", "checkout", "inventory", "payment"} {
+				if !services[name] {
+					t.Errorf("no spans from service %q", name)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateMetricsAndLogs(t *testing.T) {
+	telemetry, _ := run(t, ProtocolHTTP, nil)
+
+	types := make(map[string]bool)
+	for _, p := range telemetry.Metrics {
+		types[p.Type] = true
+	}
+	for _, want := range []string{"sum", "gauge", "histogram", "exponential_histogram", "summary"} {
+		if !types[want] {
+			t.Errorf("no %s metrics in %v", want, types)
+		}
+	}
+
+	if len(telemetry.Logs) == 0 {
+		t.Fatal("no logs")
+	}
+	for _, l := range telemetry.Logs {
+		body, ok := l.Body.(map[string]interface{})
+		if !ok || body["message"] == nil || body["request.id"] == nil {
+			t.Fatalf("log body is not structured: %#v", l.Body)
+		}
+		if l.TraceID == "" {
+			t.Errorf("log is not correlated with a trace")
+		}
+	}
+}
+
+func TestErrorRatioAndCardinality(t *testing.T) {
+	scenario := DefaultScenario()
+	scenario.ErrorRatio = 1
+	scenario.Cardinality = 1000
+	scenario.CardinalityKey = "customer.id"
+
+	telemetry, _ := run(t, ProtocolGRPC, scenario)
+
+	values := make(map[interface{}]bool)
+	for _, s := range telemetry.Spans {
+		if s.ParentSpanID == "" && s.StatusCode != "error" {
+			t.Errorf("root span status is %q, want error", s.StatusCode)
+		}
+		if s.Attributes["customer.id"] == nil {
+			t.Fatalf("span %q has no cardinality attribute", s.Name)
+		}
+		values[s.Attributes["customer.id"]] = true
+	}
+	if len(values) < 2 {
+		t.Errorf("cardinality attribute has %d distinct values", len(values))
+	}
+
+	for _, l := range telemetry.Logs {
+		if l.SeverityText != "ERROR" {
+			t.Errorf("log severity is %q, want ERROR", l.SeverityText)
+		}
+	}
+}
+
+func TestMirrors(t *testing.T) {
+	baseline, baselineServer := newSink(t)
+	candidate, candidateServer := newSink(t)
+
+	gen, err := New(Config{
+		Endpoint:  baselineServer.URL,
+		Protocol:  ProtocolHTTP,
+		Mirrors:   []string{candidateServer.URL},
+		Headers:   map[string]string{otlpsink.SandboxHeader: "sb-1"},
+		TraceRate: 20,
+		LogRate:   20,
+		Duration:  300 * time.Millisecond,
+		Seed:      42,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// Both receivers get the same requests
+	a, b := baseline.Telemetry("sb-1", "", 0), candidate.Telemetry("sb-1", "", 0)
+	if len(a.Spans) == 0 || len(a.Spans) != len(b.Spans) || len(a.Logs) != len(b.Logs) {
+		t.Fatalf("baseline got %d spans and %d logs, mirror %d and %d", len(a.Spans), len(a.Logs), len(b.Spans), len(b.Logs))
+	}
+	for i := range a.Spans {
+		if a.Spans[i].SpanID != b.Spans[i].SpanID {
+			t.Fatalf("span %d differs: %s and %s", i, a.Spans[i].SpanID, b.Spans[i].SpanID)
+		}
+	}
+
+	// A request fails if any receiver refuses it
+	candidateServer.Close()
+	gen, err = New(Config{
+		Endpoint:  baselineServer.URL,
+		Protocol:  ProtocolHTTP,
+		Mirrors:   []string{candidateServer.URL},
+		TraceRate: 20,
+		Duration:  200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen.Run(context.Background())
+	if stats := gen.Stats(); stats.FailedExports == 0 || stats.Spans != 0 {
+		t.Errorf("stats %+v, want every export to fail", stats)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *Scenario)
+		want   string
+	}{
+		{"valid", func(s *Scenario) {}, ""},
+		{"error ratio", func(s *Scenario) { s.ErrorRatio = 2 }, "error_ratio"},
+		{"unknown service", func(s *Scenario) {
+			s.Services[0].Operations[0].Calls = []Call{{Service: "billing", Operation: "Bill"}}
+		}, `unknown service "billing"`},
+		{"unknown operation", func(s *Scenario) {
+			s.Services[0].Operations[0].Calls = []Call{{Service: "payment", Operation: "Refund"}}
+		}, `unknown operation "Refund"`},
+		{"repeated call", func(s *Scenario) {
+			s.Services[0].Operations[0].Calls = append(s.Services[0].Operations[0].Calls, Call{Service: "checkout", Operation: "PlaceOrder"})
+		}, ""},
+		{"self call", func(s *Scenario) {
+			s.Services[0].Operations[0].Calls = []Call{{Operation: "POST /checkout"}}
+		}, "call cycle: frontend/POST /checkout -> frontend/POST /checkout"},
+		{"call cycle", func(s *Scenario) {
+			s.Services[1].Operations[1].Calls = []Call{{Service: "frontend", Operation: "POST /checkout"}}
+		}, "call cycle: frontend/POST /checkout -> checkout/PlaceOrder -> checkout/publish order.placed -> frontend/POST /checkout"},
+		{"too many spans", func(s *Scenario) {
+			// Each level calls the next ten times
+			fanout := Service{Name: "fanout"}
+			for level := 0; level < 4; level++ {
+				op := Operation{Name: fmt.Sprintf("level %d", level)}
+				for i := 0; level < 3 && i < 10; i++ {
+					op.Calls = append(op.Calls, Call{Operation: fmt.Sprintf("level %d", level+1)})
+				}
+				fanout.Operations = append(fanout.Operations, op)
+			}
+			s.Services = append(s.Services, fanout)
+			s.Services[0].Operations[0].Calls = []Call{{Service: "fanout", Operation: "level 0"}}
+		}, "more than 1000 spans"},
+		{"metric type", func(s *Scenario) { s.Metrics[0].Type = "timer" }, `unknown type "timer"`},
+		{"severity", func(s *Scenario) { s.Logs.Severities = []string{"loud"} }, `unknown log severity`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := DefaultScenario()
+			tt.modify(scenario)
+			err := scenario.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
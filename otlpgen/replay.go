@@ -0,0 +1,176 @@
+package otlpgen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ReplayConfig configures a Replay
+type ReplayConfig struct {
+	// Endpoint, Protocol, Mirrors, Headers and Insecure are as in Config.
+	// JSON fixtures can only be replayed over ProtocolHTTP.
+	Endpoint string
+	Protocol string
+	Mirrors  []string
+	Headers  map[string]string
+	Insecure bool
+
+	// Fixtures are replayed together, in the order they were recorded
+	Fixtures []*Fixture
+
+	// Speed scales the recorded pace: 2 replays twice as fast, 0.5 half as
+	// fast. 0 means 1.
+	Speed float64
+
+	// RebaseTimestamps moves the timestamps of each request so its latest is
+	// the time it is sent, as if the telemetry were recorded now.
+	// Otherwise they are sent as recorded.
+	RebaseTimestamps bool
+
+	// Duration stops the replay early; 0 replays every request
+	Duration time.Duration
+}
+
+// Replay sends recorded telemetry to an OTLP receiver
+type Replay struct {
+	recorder
+	cfg      ReplayConfig
+	exporter *exporter
+	requests []*fixtureRequest
+	at       []time.Time // When each request was recorded
+}
+
+// NewReplay creates a replay
+func NewReplay(cfg ReplayConfig) (*Replay, error) {
+	protocol, err := checkEndpoint(cfg.Endpoint, cfg.Protocol)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Protocol = protocol
+	if cfg.Speed < 0 {
+		return nil, fmt.Errorf("speed must not be negative")
+	}
+	if cfg.Speed == 0 {
+		cfg.Speed = 1
+	}
+	if len(cfg.Fixtures) == 0 {
+		return nil, fmt.Errorf("no fixtures to replay")
+	}
+
+	r := &Replay{
+		cfg:      cfg,
+		exporter: newExporter(append([]string{cfg.Endpoint}, cfg.Mirrors...), cfg.Protocol, cfg.Headers, cfg.Insecure),
+		recorder: recorder{stats: Stats{
+			Endpoint: cfg.Endpoint,
+			Protocol: cfg.Protocol,
+		}},
+	}
+
+	type timed struct {
+		req *fixtureRequest
+		at  time.Time
+	}
+	var requests []timed
+	for _, fixture := range cfg.Fixtures {
+		if r.stats.Scenario == "" {
+			r.stats.Scenario = "replay of " + fixture.Name
+		}
+
+		// Requests without timestamps are sent with the one before them
+		var at time.Time
+		for _, req := range fixture.requests {
+			if req.doc != nil && cfg.Protocol == ProtocolGRPC {
+				return nil, fmt.Errorf("fixture %s is JSON, which can only be replayed over OTLP/HTTP", fixture.Name)
+			}
+			if !req.first.IsZero() {
+				at = req.first
+			}
+			requests = append(requests, timed{req: req, at: at})
+		}
+	}
+	if len(cfg.Fixtures) > 1 {
+		r.stats.Scenario = fmt.Sprintf("replay of %d fixtures", len(cfg.Fixtures))
+	}
+
+	// Interleave the fixtures by recording time
+	sort.SliceStable(requests, func(i, j int) bool { return requests[i].at.Before(requests[j].at) })
+	for _, t := range requests {
+		r.requests = append(r.requests, t.req)
+		r.at = append(r.at, t.at)
+	}
+	return r, nil
+}
+
+// Run replays the fixtures until every request is sent, the configured
+// duration elapses or ctx is cancelled. Like Generator.Run, failed exports
+// are counted in Stats.
+func (r *Replay) Run(ctx context.Context) error {
+	if r.cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Duration)
+		defer cancel()
+	}
+	defer r.exporter.close()
+
+	started := time.Now()
+	r.start()
+	defer r.finish()
+
+	// The first recorded time; requests before it have no timestamps
+	var origin time.Time
+	for _, at := range r.at {
+		if !at.IsZero() {
+			origin = at
+			break
+		}
+	}
+
+	for i, req := range r.requests {
+		if !r.at[i].IsZero() {
+			wait := time.Until(started.Add(time.Duration(float64(r.at[i].Sub(origin)) / r.cfg.Speed)))
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+				case <-timer.C:
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			if r.cfg.Duration > 0 && err == context.DeadlineExceeded {
+				return nil
+			}
+			return err
+		}
+
+		var shift time.Duration
+		if r.cfg.RebaseTimestamps && !req.last.IsZero() {
+			shift = time.Since(req.last)
+		}
+		body, contentType, err := req.encode(shift)
+		if err != nil {
+			r.record(func(stats *Stats) {
+				stats.FailedExports++
+				stats.LastError = err.Error()
+			})
+			continue
+		}
+
+		if err := r.export(ctx, r.exporter, req.signal, body, contentType); err == nil {
+			r.record(func(stats *Stats) {
+				switch req.signal {
+				case signalTraces:
+					stats.Spans += int64(req.items)
+				case signalMetrics:
+					stats.MetricPoints += int64(req.items)
+				case signalLogs:
+					stats.Logs += int64(req.items)
+				}
+			})
+		}
+	}
+	return nil
+}
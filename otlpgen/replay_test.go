@@ -0,0 +1,209 @@
+package otlpgen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
+)
+
+// recordedTraces encodes traces recorded at each of times the way the file
+// exporter's proto format writes them, gzipped
+func recordedTraces(t *testing.T, times ...time.Time) []byte {
+	gen := newGenerator(DefaultScenario(), 1)
+	var data []byte
+	for _, at := range times {
+		batches, _ := gen.traces(2, at)
+		message := encodeTraces(batches)
+		data = binary.BigEndian.AppendUint32(data, uint32(len(message)))
+		data = append(data, message...)
+	}
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return gz.Bytes()
+}
+
+func replay(t *testing.T, cfg ReplayConfig) (*otlpsink.Telemetry, Stats) {
+	sink, server := newSink(t)
+
+	cfg.Endpoint = server.URL
+	if cfg.Protocol == ProtocolGRPC {
+		cfg.Endpoint = strings.TrimPrefix(server.URL, "http://")
+	}
+	cfg.Headers = map[string]string{otlpsink.SandboxHeader: "sb-1"}
+	r, err := NewReplay(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	stats := r.Stats()
+	if stats.FailedExports > 0 {
+		t.Fatalf("%d exports failed: %s", stats.FailedExports, stats.LastError)
+	}
+	return sink.Telemetry("sb-1", "", 0), stats
+}
+
+func TestReplayProtobuf(t *testing.T) {
+	recorded := time.Now().Add(-time.Hour)
+	path := filepath.Join(t.TempDir(), "traces.pb.gz")
+	if err := os.WriteFile(path, recordedTraces(t, recorded, recorded.Add(400*time.Millisecond)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signal comes from the file name
+	fixture, err := ReadFixture(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans, _, _ := fixture.Items()
+
+	started := time.Now()
+	telemetry, stats := replay(t, ReplayConfig{
+		Protocol:         ProtocolGRPC,
+		Fixtures:         []*Fixture{fixture},
+		Speed:            2,
+		RebaseTimestamps: true,
+	})
+
+	// 400ms recorded at twice the speed
+	if elapsed := time.Since(started); elapsed < 200*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("replay took %v, want about 200ms", elapsed)
+	}
+	if spans == 0 || int(stats.Spans) != spans || len(telemetry.Spans) != spans {
+		t.Fatalf("fixture has %d spans, stats say %d were sent, sink got %d", spans, stats.Spans, len(telemetry.Spans))
+	}
+	for _, s := range telemetry.Spans {
+		if s.EndTime.Before(started.Add(-time.Minute)) || s.EndTime.After(time.Now()) {
+			t.Fatalf("span %q ends at %v, want it rebased to the replay", s.Name, s.EndTime)
+		}
+		if s.EndTime.Before(s.StartTime) {
+			t.Fatalf("span %q ends before it starts", s.Name)
+		}
+	}
+}
+
+// fileExporterJSON is what the file exporter writes for a log and a metric
+const fileExporterJSON = `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]},"scopeLogs":[{"scope":{},"logRecords":[{"timeUnixNano":"1700000000000000000","severityNumber":17,"severityText":"ERROR","body":{"stringValue":"card declined"},"attributes":[{"key":"customer.tier","value":{"stringValue":"gold"}}]}]}]}]}
+{"resourceMetrics":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]},"scopeMetrics":[{"scope":{},"metrics":[{"name":"orders","sum":{"dataPoints":[{"startTimeUnixNano":"1700000000000000000","timeUnixNano":"1700000001000000000","asInt":"7"}],"aggregationTemporality":2,"isMonotonic":true}}]}]}]}
+`
+
+func TestReplayJSON(t *testing.T) {
+	fixture, err := LoadFixture("recorded.json", []byte(fileExporterJSON), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	telemetry, stats := replay(t, ReplayConfig{
+		Protocol: ProtocolHTTP,
+		Fixtures: []*Fixture{fixture},
+		Speed:    1000,
+	})
+
+	if stats.Logs != 1 || stats.MetricPoints != 1 {
+		t.Fatalf("stats %+v, want 1 log and 1 metric point", stats)
+	}
+	if len(telemetry.Logs) != 1 || telemetry.Logs[0].Attributes["customer.tier"] != "gold" {
+		t.Fatalf("sink got logs %+v", telemetry.Logs)
+	}
+	// Without rebasing, timestamps are sent as recorded
+	if got := telemetry.Logs[0].Time.UnixNano(); got != 1_700_000_000_000_000_000 {
+		t.Errorf("log time is %d", got)
+	}
+	if len(telemetry.Metrics) != 1 || telemetry.Metrics[0].Name != "orders" {
+		t.Fatalf("sink got metrics %+v", telemetry.Metrics)
+	}
+
+	// JSON can't be sent over gRPC
+	if _, err := NewReplay(ReplayConfig{Endpoint: "localhost:4317", Fixtures: []*Fixture{fixture}}); err == nil {
+		t.Error("expected an error replaying JSON over gRPC")
+	}
+}
+
+func TestLoadProtobufFixture(t *testing.T) {
+	at := time.Unix(1_700_000_000, 0)
+	gen := newGenerator(DefaultScenario(), 1)
+	tests := []struct {
+		signal  string
+		message []byte
+		items   int
+	}{
+		{signalMetrics, encodeMetrics(gen.metrics(10, at)), 10},
+		{signalLogs, encodeLogs(gen.logs(5, at)), 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.signal, func(t *testing.T) {
+			// A single request as sent over OTLP/HTTP
+			fixture, err := LoadFixture("fixture", tt.message, tt.signal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := fixture.requests[0]
+			if len(fixture.requests) != 1 || req.items != tt.items {
+				t.Fatalf("got %d requests, %d items, want 1 request of %d", len(fixture.requests), req.items, tt.items)
+			}
+			if !req.last.Equal(at) {
+				t.Errorf("latest timestamp is %v, want %v", req.last, at)
+			}
+
+			body, _, err := req.encode(time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			shifted, err := loadProtobuf(body, tt.signal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := shifted[0]; !got.first.Equal(req.first.Add(time.Hour)) || !got.last.Equal(at.Add(time.Hour)) {
+				t.Errorf("shifted time range is %v to %v, want an hour later than %v to %v", got.first, got.last, req.first, req.last)
+			}
+
+			// The recorded request is left as it was
+			body, _, err = req.encode(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if unshifted, err := loadProtobuf(body, tt.signal); err != nil || !unshifted[0].last.Equal(at) {
+				t.Errorf("encoding modified the recorded request")
+			}
+		})
+	}
+}
+
+func TestLoadFixtureErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		signal string
+		want   string
+	}{
+		{"no signal", "\x00\x00\x00\x00", "", "signal"},
+		{"truncated", "\x00\x00\x00\x09\x0a", signalTraces, "truncated"},
+		{"invalid JSON", `{"resourceSpans":`, "", "invalid JSON"},
+		{"empty", "", signalLogs, "no telemetry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFixture("fixture", []byte(tt.data), tt.signal)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got error %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
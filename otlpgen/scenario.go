@@ -0,0 +1,411 @@
+package otlpgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Span kinds an operation can have
+const (
+	KindServer   = "server"
+	KindClient   = "client"
+	KindInternal = "internal"
+	KindProducer = "producer"
+	KindConsumer = "consumer"
+)
+
+// Metric types a MetricSpec can have
+const (
+	MetricCounter              = "counter"
+	MetricGauge                = "gauge"
+	MetricHistogram            = "histogram"
+	MetricExponentialHistogram = "exponential_histogram"
+	MetricSummary              = "summary"
+)
+
+// MaxTraceSpans is the most spans a scenario's traces may have
+const MaxTraceSpans = 1000
+
+// DefaultCardinalityKey is the attribute high-cardinality mode varies
+const DefaultCardinalityKey = "user.id"
+
+// Scenario describes the telemetry a Generator produces: the services and
+// calls making up each trace, the metrics they report and the logs they write
+type Scenario struct {
+	Name string `json:"name,omitempty"`
+
+	// Services and the operations they serve. Every trace starts at the
+	// first operation of the first service and follows its calls, which
+	// must not form cycles.
+	Services []Service `json:"services"`
+
+	// Resource attributes added to every service
+	Resource map[string]string `json:"resource,omitempty"`
+
+	// Attributes added to every span, metric point and log record
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// ErrorRatio is the fraction of traces that fail, and of logs written
+	// at error severity, from 0 to 1
+	ErrorRatio float64 `json:"error_ratio,omitempty"`
+
+	// Cardinality adds CardinalityKey (DefaultCardinalityKey if empty) with
+	// this many distinct values to every span, metric point and log record,
+	// e.g. to exercise processors that must drop high-cardinality data
+	Cardinality    int    `json:"cardinality,omitempty"`
+	CardinalityKey string `json:"cardinality_key,omitempty"`
+
+	Metrics []MetricSpec `json:"metrics,omitempty"`
+	Logs    LogSpec      `json:"logs,omitempty"`
+}
+
+// Service is a service taking part in traces
+type Service struct {
+	Name       string            `json:"name"`
+	Version    string            `json:"version,omitempty"`
+	Resource   map[string]string `json:"resource,omitempty"`
+	Operations []Operation       `json:"operations"`
+}
+
+// Operation is a unit of work in a service, recorded as a span
+type Operation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind,omitempty"` // Default: server
+
+	// DurationMs is the time spent in the operation itself, excluding its
+	// calls (default 10)
+	DurationMs int `json:"duration_ms,omitempty"`
+
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// Calls are made in order. Calling another service records a client
+	// span in the caller with the callee's span beneath it.
+	Calls []Call `json:"calls,omitempty"`
+}
+
+// Call is a call from an operation to another operation
+type Call struct {
+	Service   string `json:"service,omitempty"` // Default: the calling service
+	Operation string `json:"operation"`
+}
+
+// MetricSpec describes a metric the services report
+type MetricSpec struct {
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	Unit        string            `json:"unit,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+
+	// Value is the typical gauge value, counter increment or histogram and
+	// summary sample (default 100)
+	Value float64 `json:"value,omitempty"`
+
+	// Buckets are the explicit bounds of a histogram (default: the SDK
+	// default bounds)
+	Buckets []float64 `json:"buckets,omitempty"`
+}
+
+// LogSpec describes the logs the services write
+type LogSpec struct {
+	// Messages are picked at random (default: a few generic messages)
+	Messages []string `json:"messages,omitempty"`
+
+	// Severities are picked at random for logs that are not errors
+	// (default: mostly info with some warn)
+	Severities []string `json:"severities,omitempty"`
+
+	// Structured writes bodies as maps holding the message and request
+	// fields instead of plain strings
+	Structured bool `json:"structured,omitempty"`
+
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// spanKinds maps span kinds to their OTLP values
+var spanKinds = map[string]int{
+	KindInternal: 1,
+	KindServer:   2,
+	KindClient:   3,
+	KindProducer: 4,
+	KindConsumer: 5,
+}
+
+// severityNumbers maps severity texts to their OTLP values
+var severityNumbers = map[string]int{
+	"trace": 1,
+	"debug": 5,
+	"info":  9,
+	"warn":  13,
+	"error": 17,
+	"fatal": 21,
+}
+
+// Validate checks that a scenario is well formed
+func (s *Scenario) Validate() error {
+	if s.ErrorRatio < 0 || s.ErrorRatio > 1 {
+		return fmt.Errorf("error_ratio must be between 0 and 1, got %v", s.ErrorRatio)
+	}
+	if s.Cardinality < 0 {
+		return fmt.Errorf("cardinality must not be negative")
+	}
+
+	services := make(map[string]*Service, len(s.Services))
+	for i := range s.Services {
+		service := &s.Services[i]
+		if service.Name == "" {
+			return fmt.Errorf("service %d has no name", i)
+		}
+		if _, ok := services[service.Name]; ok {
+			return fmt.Errorf("duplicate service %q", service.Name)
+		}
+		services[service.Name] = service
+	}
+
+	for _, service := range s.Services {
+		for _, op := range service.Operations {
+			if op.Name == "" {
+				return fmt.Errorf("service %q has an operation with no name", service.Name)
+			}
+			if _, ok := spanKinds[kindOrDefault(op.Kind)]; !ok {
+				return fmt.Errorf("operation %q of service %q has unknown kind %q", op.Name, service.Name, op.Kind)
+			}
+			for _, call := range op.Calls {
+				target := call.Service
+				if target == "" {
+					target = service.Name
+				}
+				callee, ok := services[target]
+				if !ok {
+					return fmt.Errorf("operation %q of service %q calls unknown service %q", op.Name, service.Name, target)
+				}
+				if callee.operation(call.Operation) < 0 {
+					return fmt.Errorf("operation %q of service %q calls unknown operation %q of service %q", op.Name, service.Name, call.Operation, target)
+				}
+			}
+		}
+	}
+
+	if err := s.checkCalls(); err != nil {
+		return err
+	}
+
+	for _, metric := range s.Metrics {
+		if metric.Name == "" {
+			return fmt.Errorf("metric with no name")
+		}
+		switch metric.Type {
+		case MetricCounter, MetricGauge, MetricHistogram, MetricExponentialHistogram, MetricSummary:
+		default:
+			return fmt.Errorf("metric %q has unknown type %q", metric.Name, metric.Type)
+		}
+	}
+
+	for _, severity := range s.Logs.Severities {
+		if _, ok := severityNumbers[strings.ToLower(severity)]; !ok {
+			return fmt.Errorf("unknown log severity %q", severity)
+		}
+	}
+	return nil
+}
+
+// checkCalls rejects call cycles, which would make traces infinite, and
+// traces with more than MaxTraceSpans spans. It expects calls to have been
+// checked to name known services and operations.
+func (s *Scenario) checkCalls() error {
+	type opKey struct{ service, op int }
+	services := make(map[string]int, len(s.Services))
+	for i, service := range s.Services {
+		services[service.Name] = i
+	}
+
+	// Spans recorded by each operation and its calls, capped at
+	// MaxTraceSpans+1 so fan-out cannot overflow
+	spans := make(map[opKey]int)
+	visiting := make(map[opKey]bool)
+	var path []string
+
+	var visit func(key opKey) (int, error)
+	visit = func(key opKey) (int, error) {
+		service := &s.Services[key.service]
+		op := &service.Operations[key.op]
+		name := service.Name + "/" + op.Name
+		if visiting[key] {
+			return 0, fmt.Errorf("call cycle: %s -> %s", strings.Join(path, " -> "), name)
+		}
+		if n, ok := spans[key]; ok {
+			return n, nil
+		}
+
+		visiting[key] = true
+		path = append(path, name)
+		n := 1
+		for _, call := range op.Calls {
+			callee := key.service
+			if call.Service != "" && call.Service != service.Name {
+				callee = services[call.Service]
+				n++ // The client span
+			}
+			calleeKey := opKey{callee, s.Services[callee].operation(call.Operation)}
+			calleeSpans, err := visit(calleeKey)
+			if err != nil {
+				return 0, err
+			}
+			n = min(n+calleeSpans, MaxTraceSpans+1)
+		}
+		path = path[:len(path)-1]
+		visiting[key] = false
+		spans[key] = n
+		return n, nil
+	}
+
+	for i, service := range s.Services {
+		for j := range service.Operations {
+			if _, err := visit(opKey{i, j}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(s.Services) > 0 && len(s.Services[0].Operations) > 0 && spans[opKey{0, 0}] > MaxTraceSpans {
+		return fmt.Errorf("traces would have more than %d spans", MaxTraceSpans)
+	}
+	return nil
+}
+
+// operation returns the index of the named operation, or -1
+func (s *Service) operation(name string) int {
+	for i, op := range s.Operations {
+		if op.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func kindOrDefault(kind string) string {
+	if kind == "" {
+		return KindServer
+	}
+	return strings.ToLower(kind)
+}
+
+// DefaultScenario is a small online shop: a frontend calling a checkout
+// service, which reserves stock and charges payments backed by a database
+func DefaultScenario() *Scenario {
+	return &Scenario{
+		Name: "shop",
+		Services: []Service{
+			{
+				Name:    "frontend",
+				Version: "1.4.2",
+				Operations: []Operation{
+					{
+						Name:       "POST /checkout",
+						DurationMs: 15,
+						Attributes: map[string]string{
+							"http.request.method":       "POST",
+							"http.route":                "/checkout",
+							"http.response.status_code": "200",
+							"user_agent.original":       "Mozilla/5.0",
+						},
+						Calls: []Call{{Service: "checkout", Operation: "PlaceOrder"}},
+					},
+				},
+			},
+			{
+				Name:    "checkout",
+				Version: "2.0.1",
+				Operations: []Operation{
+					{
+						Name:       "PlaceOrder",
+						DurationMs: 20,
+						Attributes: map[string]string{
+							"rpc.system":  "grpc",
+							"rpc.service": "CheckoutService",
+							"rpc.method":  "PlaceOrder",
+							"user.email":  "jane.doe@example.com",
+						},
+						Calls: []Call{
+							{Service: "inventory", Operation: "ReserveStock"},
+							{Service: "payment", Operation: "Charge"},
+							{Operation: "publish order.placed"},
+						},
+					},
+					{
+						Name:       "publish order.placed",
+						Kind:       KindProducer,
+						DurationMs: 3,
+						Attributes: map[string]string{
+							"messaging.system":           "kafka",
+							"messaging.destination.name": "orders",
+						},
+					},
+				},
+			},
+			{
+				Name:    "inventory",
+				Version: "1.1.0",
+				Operations: []Operation{
+					{
+						Name:       "ReserveStock",
+						DurationMs: 8,
+						Attributes: map[string]string{
+							"rpc.system":  "grpc",
+							"rpc.service": "InventoryService",
+							"rpc.method":  "ReserveStock",
+						},
+						Calls: []Call{{Operation: "UPDATE stock"}},
+					},
+					{
+						Name:       "UPDATE stock",
+						Kind:       KindClient,
+						DurationMs: 12,
+						Attributes: map[string]string{
+							"db.system":         "postgresql",
+							"db.operation.name": "UPDATE",
+							"db.query.text":     "UPDATE stock SET reserved = reserved + $1 WHERE sku = $2",
+						},
+					},
+				},
+			},
+			{
+				Name:    "payment",
+				Version: "3.2.0",
+				Operations: []Operation{
+					{
+						Name:       "Charge",
+						DurationMs: 40,
+						Attributes: map[string]string{
+							"rpc.system":          "grpc",
+							"rpc.service":         "PaymentService",
+							"rpc.method":          "Charge",
+							"payment.card_number": "4111111111111111",
+						},
+					},
+				},
+			},
+		},
+		Resource: map[string]string{
+			"deployment.environment.name": "sandbox",
+		},
+		ErrorRatio: 0.05,
+		Metrics: []MetricSpec{
+			{Name: "http.server.request.duration", Type: MetricHistogram, Unit: "ms", Value: 80,
+				Attributes: map[string]string{"http.request.method": "POST", "http.route": "/checkout"}},
+			{Name: "rpc.server.duration", Type: MetricExponentialHistogram, Unit: "ms", Value: 30},
+			{Name: "orders.placed", Type: MetricCounter, Unit: "{order}", Value: 3},
+			{Name: "process.memory.usage", Type: MetricGauge, Unit: "MiBy", Value: 256},
+			{Name: "db.query.duration", Type: MetricSummary, Unit: "ms", Value: 12},
+		},
+		Logs: LogSpec{
+			Messages: []string{
+				"order placed",
+				"stock reserved",
+				"payment authorized",
+				"cache miss for product",
+			},
+			Structured: true,
+		},
+	}
+}
@@ -0,0 +1,335 @@
+package otlpsink
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Assertion types
+const (
+	// AssertAttributePresent requires every item to have Key
+	AssertAttributePresent = "attribute_present"
+	// AssertAttributeAbsent requires no item to have Key
+	AssertAttributeAbsent = "attribute_absent"
+	// AssertAttributeEquals requires every item to have Key set to Value
+	AssertAttributeEquals = "attribute_equals"
+	// AssertAttributeMatches requires every item to have Key with a value
+	// matching Pattern
+	AssertAttributeMatches = "attribute_matches"
+	// AssertNoAttributeMatches requires that no attribute key or string value
+	// (or log body) matches Pattern, e.g. to check a redaction
+	AssertNoAttributeMatches = "no_attribute_matches"
+	// AssertMinCount requires at least Count items
+	AssertMinCount = "min_count"
+)
+
+// Assertion is a check on the telemetry a sandbox exported
+type Assertion struct {
+	// Signal is "traces", "metrics" or "logs"; empty checks all three
+	Signal string `json:"signal,omitempty"`
+	Type   string `json:"type"`
+
+	Key     string `json:"key,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Pattern string `json:"pattern,omitempty"` // Go regular expression
+	Count   int    `json:"count,omitempty"`
+
+	// Where limits attribute checks to "attributes" or "resource"; empty
+	// checks both
+	Where string `json:"where,omitempty"`
+
+	// Name restricts the check to spans, metrics or log bodies whose name
+	// matches this regular expression
+	Name string `json:"name,omitempty"`
+}
+
+// AssertionResult is the outcome of one assertion
+type AssertionResult struct {
+	Assertion Assertion `json:"assertion"`
+	Passed    bool      `json:"passed"`
+	Checked   int       `json:"checked"`  // Items the assertion looked at
+	Failures  int       `json:"failures"` // Items that broke it
+	Message   string    `json:"message"`
+	Examples  []string  `json:"examples,omitempty"` // A few offending items
+}
+
+// maxExamples is the number of offending items reported per assertion
+const maxExamples = 3
+
+// Validate checks that an assertion is well formed
+func (a Assertion) Validate() error {
+	switch a.Signal {
+	case "", SignalTraces, SignalMetrics, SignalLogs:
+	default:
+		return fmt.Errorf("unknown signal %q", a.Signal)
+	}
+	switch a.Where {
+	case "", "attributes", "resource":
+	default:
+		return fmt.Errorf("where must be attributes or resource, got %q", a.Where)
+	}
+
+	switch a.Type {
+	case AssertAttributePresent, AssertAttributeAbsent, AssertAttributeEquals:
+		if a.Key == "" {
+			return fmt.Errorf("%s requires a key", a.Type)
+		}
+	case AssertAttributeMatches:
+		if a.Key == "" || a.Pattern == "" {
+			return fmt.Errorf("%s requires a key and a pattern", a.Type)
+		}
+	case AssertNoAttributeMatches:
+		if a.Pattern == "" {
+			return fmt.Errorf("%s requires a pattern", a.Type)
+		}
+	case AssertMinCount:
+		if a.Count <= 0 {
+			return fmt.Errorf("%s requires a positive count", a.Type)
+		}
+	default:
+		return fmt.Errorf("unknown assertion type %q", a.Type)
+	}
+
+	if _, err := regexp.Compile(a.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if _, err := regexp.Compile(a.Name); err != nil {
+		return fmt.Errorf("invalid name pattern: %w", err)
+	}
+	return nil
+}
+
+// String describes the assertion, e.g. "every span has deployment.environment"
+func (a Assertion) String() string {
+	items := "item"
+	switch a.Signal {
+	case SignalTraces:
+		items = "span"
+	case SignalMetrics:
+		items = "metric point"
+	case SignalLogs:
+		items = "log record"
+	}
+	if a.Name != "" {
+		items += fmt.Sprintf(" named /%s/", a.Name)
+	}
+
+	switch a.Type {
+	case AssertAttributePresent:
+		return fmt.Sprintf("every %s has %s", items, a.Key)
+	case AssertAttributeAbsent:
+		return fmt.Sprintf("no %s has %s", items, a.Key)
+	case AssertAttributeEquals:
+		return fmt.Sprintf("every %s has %s=%s", items, a.Key, a.Value)
+	case AssertAttributeMatches:
+		return fmt.Sprintf("every %s has %s matching /%s/", items, a.Key, a.Pattern)
+	case AssertNoAttributeMatches:
+		return fmt.Sprintf("no %s attribute matches /%s/", items, a.Pattern)
+	case AssertMinCount:
+		return fmt.Sprintf("at least %d %ss", a.Count, items)
+	}
+	return a.Type
+}
+
+// item is a span, metric point or log record as seen by assertions
+type item struct {
+	description string
+	name        string
+	attributes  Attributes
+	resource    Attributes
+	body        interface{}
+}
+
+// items returns the captured items an assertion applies to
+func (t *Telemetry) items(signal string, name *regexp.Regexp) []item {
+	var items []item
+	add := func(it item) {
+		if name == nil || name.MatchString(it.name) {
+			items = append(items, it)
+		}
+	}
+
+	if signal == "" || signal == SignalTraces {
+		for _, span := range t.Spans {
+			add(item{
+				description: fmt.Sprintf("span %q (%s)", span.Name, span.SpanID),
+				name:        span.Name,
+				attributes:  span.Attributes,
+				resource:    span.Resource,
+			})
+		}
+	}
+	if signal == "" || signal == SignalMetrics {
+		for _, point := range t.Metrics {
+			add(item{
+				description: fmt.Sprintf("metric %q", point.Name),
+				name:        point.Name,
+				attributes:  point.Attributes,
+				resource:    point.Resource,
+			})
+		}
+	}
+	if signal == "" || signal == SignalLogs {
+		for _, record := range t.Logs {
+			body := fmt.Sprint(record.Body)
+			add(item{
+				description: fmt.Sprintf("log %q", truncate(body, 60)),
+				name:        body,
+				attributes:  record.Attributes,
+				resource:    record.Resource,
+				body:        record.Body,
+			})
+		}
+	}
+	return items
+}
+
+// Evaluate checks assertions against captured telemetry
+func (t *Telemetry) Evaluate(assertions []Assertion) []AssertionResult {
+	results := make([]AssertionResult, 0, len(assertions))
+	for _, assertion := range assertions {
+		results = append(results, t.evaluate(assertion))
+	}
+	return results
+}
+
+func (t *Telemetry) evaluate(a Assertion) AssertionResult {
+	result := AssertionResult{Assertion: a}
+	if err := a.Validate(); err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	var name *regexp.Regexp
+	if a.Name != "" {
+		name = regexp.MustCompile(a.Name)
+	}
+	var pattern *regexp.Regexp
+	if a.Pattern != "" {
+		pattern = regexp.MustCompile(a.Pattern)
+	}
+
+	items := t.items(a.Signal, name)
+	result.Checked = len(items)
+
+	if a.Type == AssertMinCount {
+		result.Passed = len(items) >= a.Count
+		result.Message = fmt.Sprintf("captured %d, want at least %d", len(items), a.Count)
+		return result
+	}
+
+	// An assertion about every (or no) item says nothing if nothing arrived
+	if len(items) == 0 {
+		result.Message = "no matching telemetry was captured"
+		return result
+	}
+
+	for _, it := range items {
+		ok, detail := a.check(it, pattern)
+		if ok {
+			continue
+		}
+		result.Failures++
+		if len(result.Examples) < maxExamples {
+			result.Examples = append(result.Examples, it.description+detail)
+		}
+	}
+
+	result.Passed = result.Failures == 0
+	if result.Passed {
+		result.Message = fmt.Sprintf("%d checked, all passed", result.Checked)
+	} else {
+		result.Message = fmt.Sprintf("%d of %d failed", result.Failures, result.Checked)
+	}
+	return result
+}
+
+// check reports whether an item satisfies the assertion, with a detail
+// describing the violation if not
+func (a Assertion) check(it item, pattern *regexp.Regexp) (bool, string) {
+	value, found := a.lookup(it)
+
+	switch a.Type {
+	case AssertAttributePresent:
+		return found, " is missing " + a.Key
+	case AssertAttributeAbsent:
+		return !found, fmt.Sprintf(" has %s=%v", a.Key, value)
+	case AssertAttributeEquals:
+		if !found {
+			return false, " is missing " + a.Key
+		}
+		return fmt.Sprint(value) == a.Value, fmt.Sprintf(" has %s=%v", a.Key, value)
+	case AssertAttributeMatches:
+		if !found {
+			return false, " is missing " + a.Key
+		}
+		return pattern.MatchString(fmt.Sprint(value)), fmt.Sprintf(" has %s=%v", a.Key, value)
+	case AssertNoAttributeMatches:
+		var matches []string
+		collect := func(prefix string, attrs Attributes) {
+			for key, v := range attrs {
+				if pattern.MatchString(key) || matchesValue(pattern, v) {
+					matches = append(matches, prefix+key)
+				}
+			}
+		}
+		if a.Where != "resource" {
+			collect("", it.attributes)
+			if it.body != nil && matchesValue(pattern, it.body) {
+				matches = append(matches, "body")
+			}
+		}
+		if a.Where != "attributes" {
+			collect("resource.", it.resource)
+		}
+		sort.Strings(matches)
+		return len(matches) == 0, " matches in " + strings.Join(matches, ", ")
+	}
+	return true, ""
+}
+
+// lookup finds the assertion's key on an item
+func (a Assertion) lookup(it item) (interface{}, bool) {
+	if a.Where != "resource" {
+		if value, ok := it.attributes[a.Key]; ok {
+			return value, true
+		}
+	}
+	if a.Where != "attributes" {
+		if value, ok := it.resource[a.Key]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// matchesValue reports whether a string anywhere in an attribute value
+// matches the pattern
+func matchesValue(pattern *regexp.Regexp, value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return pattern.MatchString(v)
+	case []interface{}:
+		for _, item := range v {
+			if matchesValue(pattern, item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			if pattern.MatchString(key) || matchesValue(pattern, item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
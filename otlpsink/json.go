@@ -0,0 +1,300 @@
+package otlpsink
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// OTLP/JSON uses the proto field names in lowerCamelCase, hex encoded trace
+// and span IDs, and strings for 64-bit integers
+
+type jsonKeyValue struct {
+	Key   string       `json:"key"`
+	Value jsonAnyValue `json:"value"`
+}
+
+type jsonAnyValue struct {
+	StringValue *string  `json:"stringValue"`
+	BoolValue   *bool    `json:"boolValue"`
+	IntValue    *flexInt `json:"intValue"`
+	DoubleValue *float64 `json:"doubleValue"`
+	ArrayValue  *struct {
+		Values []jsonAnyValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []jsonKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+	BytesValue *string `json:"bytesValue"`
+}
+
+type jsonResource struct {
+	Attributes []jsonKeyValue `json:"attributes"`
+}
+
+type jsonScope struct {
+	Name string `json:"name"`
+}
+
+// flexInt accepts an integer encoded as a JSON number or string
+type flexInt int64
+
+func (n *flexInt) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		// Enums may be sent by name, which the sink does not need
+		*n = 0
+		return nil
+	}
+	*n = flexInt(v)
+	return nil
+}
+
+type jsonTracesRequest struct {
+	ResourceSpans []struct {
+		Resource   jsonResource `json:"resource"`
+		ScopeSpans []struct {
+			Scope jsonScope `json:"scope"`
+			Spans []struct {
+				TraceID           string         `json:"traceId"`
+				SpanID            string         `json:"spanId"`
+				ParentSpanID      string         `json:"parentSpanId"`
+				Name              string         `json:"name"`
+				Kind              flexInt        `json:"kind"`
+				StartTimeUnixNano flexInt        `json:"startTimeUnixNano"`
+				EndTimeUnixNano   flexInt        `json:"endTimeUnixNano"`
+				Attributes        []jsonKeyValue `json:"attributes"`
+				Status            struct {
+					Code    flexInt `json:"code"`
+					Message string  `json:"message"`
+				} `json:"status"`
+			} `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+type jsonDataPoint struct {
+	Attributes   []jsonKeyValue `json:"attributes"`
+	TimeUnixNano flexInt        `json:"timeUnixNano"`
+	AsDouble     *float64       `json:"asDouble"`
+	AsInt        *flexInt       `json:"asInt"`
+	Count        flexInt        `json:"count"`
+	Sum          *float64       `json:"sum"`
+}
+
+type jsonDataPoints struct {
+	DataPoints []jsonDataPoint `json:"dataPoints"`
+}
+
+type jsonMetricsRequest struct {
+	ResourceMetrics []struct {
+		Resource     jsonResource `json:"resource"`
+		ScopeMetrics []struct {
+			Scope   jsonScope `json:"scope"`
+			Metrics []struct {
+				Name                 string          `json:"name"`
+				Description          string          `json:"description"`
+				Unit                 string          `json:"unit"`
+				Gauge                *jsonDataPoints `json:"gauge"`
+				Sum                  *jsonDataPoints `json:"sum"`
+				Histogram            *jsonDataPoints `json:"histogram"`
+				ExponentialHistogram *jsonDataPoints `json:"exponentialHistogram"`
+				Summary              *jsonDataPoints `json:"summary"`
+			} `json:"metrics"`
+		} `json:"scopeMetrics"`
+	} `json:"resourceMetrics"`
+}
+
+type jsonLogsRequest struct {
+	ResourceLogs []struct {
+		Resource  jsonResource `json:"resource"`
+		ScopeLogs []struct {
+			Scope      jsonScope `json:"scope"`
+			LogRecords []struct {
+				TimeUnixNano         flexInt        `json:"timeUnixNano"`
+				ObservedTimeUnixNano flexInt        `json:"observedTimeUnixNano"`
+				SeverityNumber       flexInt        `json:"severityNumber"`
+				SeverityText         string         `json:"severityText"`
+				Body                 *jsonAnyValue  `json:"body"`
+				Attributes           []jsonKeyValue `json:"attributes"`
+				TraceID              string         `json:"traceId"`
+				SpanID               string         `json:"spanId"`
+			} `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+// decodeTracesJSON decodes an ExportTraceServiceRequest in OTLP/JSON
+func decodeTracesJSON(data []byte) ([]Span, error) {
+	var req jsonTracesRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	var spans []Span
+	for _, rs := range req.ResourceSpans {
+		resource := jsonAttributes(rs.Resource.Attributes)
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				spans = append(spans, Span{
+					TraceID:       strings.ToLower(s.TraceID),
+					SpanID:        strings.ToLower(s.SpanID),
+					ParentSpanID:  strings.ToLower(s.ParentSpanID),
+					Name:          s.Name,
+					Kind:          spanKindName(int(s.Kind)),
+					StartTime:     unixNano(uint64(s.StartTimeUnixNano)),
+					EndTime:       unixNano(uint64(s.EndTimeUnixNano)),
+					StatusCode:    statusCodeName(int(s.Status.Code)),
+					StatusMessage: s.Status.Message,
+					Attributes:    jsonAttributes(s.Attributes),
+					Resource:      resource,
+					Scope:         ss.Scope.Name,
+				})
+			}
+		}
+	}
+	return spans, nil
+}
+
+// decodeMetricsJSON decodes an ExportMetricsServiceRequest in OTLP/JSON
+func decodeMetricsJSON(data []byte) ([]MetricPoint, error) {
+	var req jsonMetricsRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	var points []MetricPoint
+	for _, rm := range req.ResourceMetrics {
+		resource := jsonAttributes(rm.Resource.Attributes)
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				metricType, dataPoints := "", (*jsonDataPoints)(nil)
+				switch {
+				case m.Gauge != nil:
+					metricType, dataPoints = "gauge", m.Gauge
+				case m.Sum != nil:
+					metricType, dataPoints = "sum", m.Sum
+				case m.Histogram != nil:
+					metricType, dataPoints = "histogram", m.Histogram
+				case m.ExponentialHistogram != nil:
+					metricType, dataPoints = "exponential_histogram", m.ExponentialHistogram
+				case m.Summary != nil:
+					metricType, dataPoints = "summary", m.Summary
+				default:
+					continue
+				}
+
+				for _, dp := range dataPoints.DataPoints {
+					point := MetricPoint{
+						Name:        m.Name,
+						Description: m.Description,
+						Unit:        m.Unit,
+						Type:        metricType,
+						Time:        unixNano(uint64(dp.TimeUnixNano)),
+						Attributes:  jsonAttributes(dp.Attributes),
+						Resource:    resource,
+						Scope:       sm.Scope.Name,
+					}
+					if metricType == "gauge" || metricType == "sum" {
+						if dp.AsDouble != nil {
+							point.Value = dp.AsDouble
+						} else if dp.AsInt != nil {
+							value := float64(*dp.AsInt)
+							point.Value = &value
+						}
+					} else {
+						point.Count = uint64(dp.Count)
+						point.Sum = dp.Sum
+					}
+					points = append(points, point)
+				}
+			}
+		}
+	}
+	return points, nil
+}
+
+// decodeLogsJSON decodes an ExportLogsServiceRequest in OTLP/JSON
+func decodeLogsJSON(data []byte) ([]LogRecord, error) {
+	var req jsonLogsRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	var records []LogRecord
+	for _, rl := range req.ResourceLogs {
+		resource := jsonAttributes(rl.Resource.Attributes)
+		for _, sl := range rl.ScopeLogs {
+			for _, l := range sl.LogRecords {
+				record := LogRecord{
+					Time:           unixNano(uint64(l.TimeUnixNano)),
+					SeverityNumber: int(l.SeverityNumber),
+					SeverityText:   l.SeverityText,
+					TraceID:        strings.ToLower(l.TraceID),
+					SpanID:         strings.ToLower(l.SpanID),
+					Attributes:     jsonAttributes(l.Attributes),
+					Resource:       resource,
+					Scope:          sl.Scope.Name,
+				}
+				if record.Time.IsZero() {
+					record.Time = unixNano(uint64(l.ObservedTimeUnixNano))
+				}
+				if l.Body != nil {
+					record.Body = l.Body.value()
+				}
+				records = append(records, record)
+			}
+		}
+	}
+	return records, nil
+}
+
+func jsonAttributes(kvs []jsonKeyValue) Attributes {
+	if len(kvs) == 0 {
+		return nil
+	}
+	attrs := make(Attributes, len(kvs))
+	for _, kv := range kvs {
+		attrs[kv.Key] = kv.Value.value()
+	}
+	return attrs
+}
+
+func (v jsonAnyValue) value() interface{} {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != nil:
+		return int64(*v.IntValue)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.ArrayValue != nil:
+		values := make([]interface{}, 0, len(v.ArrayValue.Values))
+		for _, item := range v.ArrayValue.Values {
+			values = append(values, item.value())
+		}
+		return values
+	case v.KvlistValue != nil:
+		kvlist := make(map[string]interface{}, len(v.KvlistValue.Values))
+		for _, kv := range v.KvlistValue.Values {
+			kvlist[kv.Key] = kv.Value.value()
+		}
+		return kvlist
+	case v.BytesValue != nil:
+		// Bytes are base64 in OTLP/JSON; keep them hex encoded like protobuf
+		if data, err := base64.StdEncoding.DecodeString(*v.BytesValue); err == nil {
+			return hex.EncodeToString(data)
+		}
+		return *v.BytesValue
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package otlpsink
+
+import "time"
+
+// Signal names
+const (
+	SignalTraces  = "traces"
+	SignalMetrics = "metrics"
+	SignalLogs    = "logs"
+)
+
+// Attributes holds OTLP attributes. Values are strings, bools, int64s,
+// float64s, []interface{} for arrays and map[string]interface{} for kvlists.
+// Bytes values are hex encoded.
+type Attributes map[string]interface{}
+
+// Span is a captured span with its resource and scope flattened onto it
+type Span struct {
+	TraceID       string     `json:"trace_id"`
+	SpanID        string     `json:"span_id"`
+	ParentSpanID  string     `json:"parent_span_id,omitempty"`
+	Name          string     `json:"name"`
+	Kind          string     `json:"kind"`
+	StartTime     time.Time  `json:"start_time"`
+	EndTime       time.Time  `json:"end_time"`
+	StatusCode    string     `json:"status_code,omitempty"`
+	StatusMessage string     `json:"status_message,omitempty"`
+	Attributes    Attributes `json:"attributes,omitempty"`
+	Resource      Attributes `json:"resource,omitempty"`
+	Scope         string     `json:"scope,omitempty"`
+}
+
+// MetricPoint is one captured data point of a metric
+type MetricPoint struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Unit        string     `json:"unit,omitempty"`
+	Type        string     `json:"type"` // gauge, sum, histogram, exponential_histogram or summary
+	Time        time.Time  `json:"time"`
+	Value       *float64   `json:"value,omitempty"` // gauge and sum
+	Count       uint64     `json:"count,omitempty"` // histograms and summaries
+	Sum         *float64   `json:"sum,omitempty"`   // histograms and summaries
+	Attributes  Attributes `json:"attributes,omitempty"`
+	Resource    Attributes `json:"resource,omitempty"`
+	Scope       string     `json:"scope,omitempty"`
+}
+
+// LogRecord is a captured log record
+type LogRecord struct {
+	Time           time.Time   `json:"time"`
+	SeverityNumber int         `json:"severity_number,omitempty"`
+	SeverityText   string      `json:"severity_text,omitempty"`
+	Body           interface{} `json:"body,omitempty"`
+	TraceID        string      `json:"trace_id,omitempty"`
+	SpanID         string      `json:"span_id,omitempty"`
+	Attributes     Attributes  `json:"attributes,omitempty"`
+	Resource       Attributes  `json:"resource,omitempty"`
+	Scope          string      `json:"scope,omitempty"`
+}
+
+// Telemetry is the telemetry captured for one sandbox
+type Telemetry struct {
+	Spans   []Span        `json:"spans"`
+	Metrics []MetricPoint `json:"metrics"`
+	Logs    []LogRecord   `json:"logs"`
+
+	// Items discarded because the sandbox's buffer was full, oldest first
+	DroppedSpans   int `json:"dropped_spans,omitempty"`
+	DroppedMetrics int `json:"dropped_metrics,omitempty"`
+	DroppedLogs    int `json:"dropped_logs,omitempty"`
+}
+
+// spanKinds are the OTLP span kind names by enum value
+var spanKinds = []string{"unspecified", "internal", "server", "client", "producer", "consumer"}
+
+func spanKindName(kind int) string {
+	if kind >= 0 && kind < len(spanKinds) {
+		return spanKinds[kind]
+	}
+	return "unspecified"
+}
+
+// statusCodes are the OTLP status code names by enum value
+var statusCodes = []string{"", "ok", "error"}
+
+func statusCodeName(code int) string {
+	if code >= 0 && code < len(statusCodes) {
+		return statusCodes[code]
+	}
+	return ""
+}
+
+// unixNano converts an OTLP timestamp, leaving zero as the zero time
+func unixNano(nanos uint64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(nanos)).UTC()
+}
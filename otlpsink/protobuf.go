@@ -0,0 +1,208 @@
+package otlpsink
+
+import (
+	"encoding/hex"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Export requests are decoded as TracesData, MetricsData and LogsData, which
+// share their wire format with the Export*ServiceRequest messages without
+// pulling in the gRPC service definitions. Fields the sink does not keep,
+// such as span events and exemplars, are dropped.
+
+// decodeTracesProto decodes an ExportTraceServiceRequest
+func decodeTracesProto(data []byte) ([]Span, error) {
+	var req tracepb.TracesData
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	var spans []Span
+	for _, rs := range req.GetResourceSpans() {
+		resource := resourceAttributes(rs.GetResource())
+		for _, ss := range rs.GetScopeSpans() {
+			for _, s := range ss.GetSpans() {
+				spans = append(spans, Span{
+					TraceID:       hex.EncodeToString(s.GetTraceId()),
+					SpanID:        hex.EncodeToString(s.GetSpanId()),
+					ParentSpanID:  hex.EncodeToString(s.GetParentSpanId()),
+					Name:          s.GetName(),
+					Kind:          spanKindName(int(s.GetKind())),
+					StartTime:     unixNano(s.GetStartTimeUnixNano()),
+					EndTime:       unixNano(s.GetEndTimeUnixNano()),
+					StatusCode:    statusCodeName(int(s.GetStatus().GetCode())),
+					StatusMessage: s.GetStatus().GetMessage(),
+					Attributes:    protoAttributes(s.GetAttributes()),
+					Resource:      resource,
+					Scope:         ss.GetScope().GetName(),
+				})
+			}
+		}
+	}
+	return spans, nil
+}
+
+// decodeMetricsProto decodes an ExportMetricsServiceRequest
+func decodeMetricsProto(data []byte) ([]MetricPoint, error) {
+	var req metricspb.MetricsData
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	var points []MetricPoint
+	for _, rm := range req.GetResourceMetrics() {
+		resource := resourceAttributes(rm.GetResource())
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				base := MetricPoint{
+					Name:        m.GetName(),
+					Description: m.GetDescription(),
+					Unit:        m.GetUnit(),
+					Resource:    resource,
+					Scope:       sm.GetScope().GetName(),
+				}
+				points = append(points, metricPoints(base, m)...)
+			}
+		}
+	}
+	return points, nil
+}
+
+// metricPoints returns a metric's data points, each a copy of base
+func metricPoints(base MetricPoint, m *metricspb.Metric) []MetricPoint {
+	var points []MetricPoint
+	add := func(metricType string, timeUnixNano uint64, attrs []*commonpb.KeyValue) *MetricPoint {
+		point := base
+		point.Type = metricType
+		point.Time = unixNano(timeUnixNano)
+		point.Attributes = protoAttributes(attrs)
+		points = append(points, point)
+		return &points[len(points)-1]
+	}
+	number := func(metricType string, dataPoints []*metricspb.NumberDataPoint) {
+		for _, dp := range dataPoints {
+			point := add(metricType, dp.GetTimeUnixNano(), dp.GetAttributes())
+			var value float64
+			switch v := dp.GetValue().(type) {
+			case *metricspb.NumberDataPoint_AsDouble:
+				value = v.AsDouble
+			case *metricspb.NumberDataPoint_AsInt:
+				value = float64(v.AsInt)
+			default:
+				continue
+			}
+			point.Value = &value
+		}
+	}
+
+	switch data := m.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		number("gauge", data.Gauge.GetDataPoints())
+	case *metricspb.Metric_Sum:
+		number("sum", data.Sum.GetDataPoints())
+	case *metricspb.Metric_Histogram:
+		for _, dp := range data.Histogram.GetDataPoints() {
+			point := add("histogram", dp.GetTimeUnixNano(), dp.GetAttributes())
+			point.Count = dp.GetCount()
+			point.Sum = dp.Sum
+		}
+	case *metricspb.Metric_ExponentialHistogram:
+		for _, dp := range data.ExponentialHistogram.GetDataPoints() {
+			point := add("exponential_histogram", dp.GetTimeUnixNano(), dp.GetAttributes())
+			point.Count = dp.GetCount()
+			point.Sum = dp.Sum
+		}
+	case *metricspb.Metric_Summary:
+		for _, dp := range data.Summary.GetDataPoints() {
+			point := add("summary", dp.GetTimeUnixNano(), dp.GetAttributes())
+			sum := dp.GetSum()
+			point.Count = dp.GetCount()
+			point.Sum = &sum
+		}
+	}
+	return points
+}
+
+// decodeLogsProto decodes an ExportLogsServiceRequest
+func decodeLogsProto(data []byte) ([]LogRecord, error) {
+	var req logspb.LogsData
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	var records []LogRecord
+	for _, rl := range req.GetResourceLogs() {
+		resource := resourceAttributes(rl.GetResource())
+		for _, sl := range rl.GetScopeLogs() {
+			for _, l := range sl.GetLogRecords() {
+				record := LogRecord{
+					Time:           unixNano(l.GetTimeUnixNano()),
+					SeverityNumber: int(l.GetSeverityNumber()),
+					SeverityText:   l.GetSeverityText(),
+					TraceID:        hex.EncodeToString(l.GetTraceId()),
+					SpanID:         hex.EncodeToString(l.GetSpanId()),
+					Attributes:     protoAttributes(l.GetAttributes()),
+					Resource:       resource,
+					Scope:          sl.GetScope().GetName(),
+				}
+				if record.Time.IsZero() {
+					record.Time = unixNano(l.GetObservedTimeUnixNano())
+				}
+				if l.GetBody() != nil {
+					record.Body = anyValue(l.GetBody())
+				}
+				records = append(records, record)
+			}
+		}
+	}
+	return records, nil
+}
+
+func resourceAttributes(resource *resourcepb.Resource) Attributes {
+	return protoAttributes(resource.GetAttributes())
+}
+
+func protoAttributes(kvs []*commonpb.KeyValue) Attributes {
+	if len(kvs) == 0 {
+		return nil
+	}
+	attrs := make(Attributes, len(kvs))
+	for _, kv := range kvs {
+		attrs[kv.GetKey()] = anyValue(kv.GetValue())
+	}
+	return attrs
+}
+
+func anyValue(v *commonpb.AnyValue) interface{} {
+	switch value := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return value.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return value.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return value.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return value.DoubleValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]interface{}, 0, len(value.ArrayValue.GetValues()))
+		for _, item := range value.ArrayValue.GetValues() {
+			values = append(values, anyValue(item))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		kvlist := make(map[string]interface{}, len(value.KvlistValue.GetValues()))
+		for _, kv := range value.KvlistValue.GetValues() {
+			kvlist[kv.GetKey()] = anyValue(kv.GetValue())
+		}
+		return kvlist
+	case *commonpb.AnyValue_BytesValue:
+		return hex.EncodeToString(value.BytesValue)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package otlpsink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SandboxHeader names the sandbox exported telemetry belongs to. Exporters
+// pointed at the sink must send it, e.g. through the exporter's headers.
+const SandboxHeader = "X-Sandbox-Id"
+
+// DefaultMaxItems is the default number of spans, metric points and log
+// records kept per sandbox for each signal
+const DefaultMaxItems = 10000
+
+// maxRequestSize limits the size of an export request
+const maxRequestSize = 64 << 20
+
+// signalPaths maps the OTLP/HTTP paths and gRPC methods to their signals
+var signalPaths = map[string]string{
+	"/v1/traces":  SignalTraces,
+	"/v1/metrics": SignalMetrics,
+	"/v1/logs":    SignalLogs,
+	"/opentelemetry.proto.collector.trace.v1.TraceService/Export":     SignalTraces,
+	"/opentelemetry.proto.collector.metrics.v1.MetricsService/Export": SignalMetrics,
+	"/opentelemetry.proto.collector.logs.v1.LogsService/Export":       SignalLogs,
+}
+
+// Sink is an OTLP receiver that keeps what it receives in memory, per
+// sandbox, so tests can assert on what a collector actually exported. It
+// accepts OTLP/HTTP (protobuf or JSON) and OTLP/gRPC on the same port.
+type Sink struct {
+	maxItems int
+
+	mu       sync.RWMutex
+	captures map[string]*Telemetry
+}
+
+// New creates a sink keeping up to maxItems items per signal and sandbox
+// (DefaultMaxItems if maxItems is 0)
+func New(maxItems int) *Sink {
+	if maxItems <= 0 {
+		maxItems = DefaultMaxItems
+	}
+	return &Sink{
+		maxItems: maxItems,
+		captures: make(map[string]*Telemetry),
+	}
+}
+
+// ListenAndServe serves the sink on addr until ctx is done. gRPC clients
+// connect with cleartext HTTP/2 (h2c), as collector exporters do when TLS
+// is disabled.
+func (s *Sink) ListenAndServe(ctx context.Context, addr string) error {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		Protocols:         protocols,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP handles OTLP export requests
+func (s *Sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	signal, ok := signalPaths[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		s.serveGRPC(w, r, signal)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sandboxID := r.Header.Get(SandboxHeader)
+	if sandboxID == "" {
+		http.Error(w, "missing "+SandboxHeader+" header", http.StatusBadRequest)
+		return
+	}
+
+	body, err := readBody(r.Body, r.Header.Get("Content-Encoding"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	isJSON := strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+	if err := s.export(sandboxID, signal, body, isJSON); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// An empty Export*ServiceResponse means everything was accepted
+	if isJSON {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{}"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+// gRPC status codes
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcUnimplemented   = 12
+)
+
+// serveGRPC handles a unary OTLP/gRPC Export call
+func (s *Sink) serveGRPC(w http.ResponseWriter, r *http.Request, signal string) {
+	sandboxID := r.Header.Get(SandboxHeader)
+	if sandboxID == "" {
+		writeGRPCStatus(w, grpcInvalidArgument, "missing "+strings.ToLower(SandboxHeader)+" metadata")
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		writeGRPCStatus(w, grpcInvalidArgument, "failed to read request")
+		return
+	}
+
+	// A unary request is a single length-prefixed message
+	if len(data) < 5 {
+		writeGRPCStatus(w, grpcInvalidArgument, "malformed gRPC message")
+		return
+	}
+	compressed := data[0] == 1
+	length := binary.BigEndian.Uint32(data[1:5])
+	if uint64(length) > uint64(len(data)-5) {
+		writeGRPCStatus(w, grpcInvalidArgument, "truncated gRPC message")
+		return
+	}
+	message := data[5 : 5+length]
+
+	if compressed {
+		encoding := r.Header.Get("Grpc-Encoding")
+		if encoding != "gzip" {
+			writeGRPCStatus(w, grpcUnimplemented, "unsupported grpc-encoding "+encoding)
+			return
+		}
+		if message, err = readBody(io.NopCloser(bytes.NewReader(message)), "gzip"); err != nil {
+			writeGRPCStatus(w, grpcInvalidArgument, err.Error())
+			return
+		}
+	}
+
+	if err := s.export(sandboxID, signal, message, false); err != nil {
+		writeGRPCStatus(w, grpcInvalidArgument, err.Error())
+		return
+	}
+
+	// Reply with an empty response message and an OK status trailer
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte{0, 0, 0, 0, 0})
+	w.Header().Set("Grpc-Status", fmt.Sprint(grpcOK))
+	w.Header().Set("Grpc-Message", "")
+}
+
+// writeGRPCStatus writes a trailers-only gRPC error response
+func writeGRPCStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", fmt.Sprint(code))
+	w.Header().Set("Grpc-Message", message)
+	w.WriteHeader(http.StatusOK)
+}
+
+// readBody reads a request body, decompressing it if needed
+func readBody(body io.ReadCloser, encoding string) ([]byte, error) {
+	defer body.Close()
+
+	var reader io.Reader = body
+	switch encoding {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxRequestSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	return data, nil
+}
+
+// export decodes an export request and stores its items for the sandbox
+func (s *Sink) export(sandboxID, signal string, data []byte, isJSON bool) error {
+	var spans []Span
+	var metrics []MetricPoint
+	var logs []LogRecord
+	var err error
+
+	switch {
+	case signal == SignalTraces && isJSON:
+		spans, err = decodeTracesJSON(data)
+	case signal == SignalTraces:
+		spans, err = decodeTracesProto(data)
+	case signal == SignalMetrics && isJSON:
+		metrics, err = decodeMetricsJSON(data)
+	case signal == SignalMetrics:
+		metrics, err = decodeMetricsProto(data)
+	case signal == SignalLogs && isJSON:
+		logs, err = decodeLogsJSON(data)
+	default:
+		logs, err = decodeLogsProto(data)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s export request: %w", signal, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	captured, ok := s.captures[sandboxID]
+	if !ok {
+		captured = &Telemetry{}
+		s.captures[sandboxID] = captured
+	}
+
+	captured.Spans = append(captured.Spans, spans...)
+	if excess := len(captured.Spans) - s.maxItems; excess > 0 {
+		captured.Spans = append([]Span(nil), captured.Spans[excess:]...)
+		captured.DroppedSpans += excess
+	}
+	captured.Metrics = append(captured.Metrics, metrics...)
+	if excess := len(captured.Metrics) - s.maxItems; excess > 0 {
+		captured.Metrics = append([]MetricPoint(nil), captured.Metrics[excess:]...)
+		captured.DroppedMetrics += excess
+	}
+	captured.Logs = append(captured.Logs, logs...)
+	if excess := len(captured.Logs) - s.maxItems; excess > 0 {
+		captured.Logs = append([]LogRecord(nil), captured.Logs[excess:]...)
+		captured.DroppedLogs += excess
+	}
+
+	return nil
+}
+
+// Telemetry returns what has been captured for a sandbox. signal limits it
+// to one signal ("" for all), and limit keeps only the most recent items of
+// each signal (0 for all).
+func (s *Sink) Telemetry(sandboxID, signal string, limit int) *Telemetry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := &Telemetry{Spans: []Span{}, Metrics: []MetricPoint{}, Logs: []LogRecord{}}
+	captured, ok := s.captures[sandboxID]
+	if !ok {
+		return result
+	}
+
+	if signal == "" || signal == SignalTraces {
+		result.Spans = append(result.Spans, lastN(captured.Spans, limit)...)
+		result.DroppedSpans = captured.DroppedSpans
+	}
+	if signal == "" || signal == SignalMetrics {
+		result.Metrics = append(result.Metrics, lastN(captured.Metrics, limit)...)
+		result.DroppedMetrics = captured.DroppedMetrics
+	}
+	if signal == "" || signal == SignalLogs {
+		result.Logs = append(result.Logs, lastN(captured.Logs, limit)...)
+		result.DroppedLogs = captured.DroppedLogs
+	}
+	return result
+}
+
+// Reset discards everything captured for a sandbox
+func (s *Sink) Reset(sandboxID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.captures, sandboxID)
+}
+
+func lastN[T any](items []T, n int) []T {
+	if n > 0 && len(items) > n {
+		return items[len(items)-n:]
+	}
+	return items
+}
@@ -0,0 +1,278 @@
+package otlpsink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func mustMarshal(t *testing.T, m proto.Message) []byte {
+	t.Helper()
+	data, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// tracesRequest encodes an ExportTraceServiceRequest with one span
+func tracesRequest(t *testing.T, spanName string, attrs ...*commonpb.KeyValue) []byte {
+	return mustMarshal(t, &tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{stringAttr("service.name", "checkout")}},
+		ScopeSpans: []*tracepb.ScopeSpans{{
+			Scope: &commonpb.InstrumentationScope{Name: "otel-test"},
+			Spans: []*tracepb.Span{{
+				TraceId:           []byte{0x0a, 0x0b, 0x0c, 0x0d, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+				SpanId:            []byte{1, 2, 3, 4, 5, 6, 7, 8},
+				Name:              spanName,
+				Kind:              tracepb.Span_SPAN_KIND_SERVER,
+				StartTimeUnixNano: 1_700_000_000_000_000_000,
+				EndTimeUnixNano:   1_700_000_001_000_000_000,
+				Attributes:        attrs,
+			}},
+		}},
+	}}})
+}
+
+func TestExportProtobufOverHTTP(t *testing.T) {
+	sink := New(0)
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(tracesRequest(t, "GET /cart", stringAttr("http.method", "GET")))
+	w.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/traces", &gz)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set(SandboxHeader, "sb-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("export returned %d, want 200", resp.StatusCode)
+	}
+
+	captured := sink.Telemetry("sb-1", "", 0)
+	if len(captured.Spans) != 1 {
+		t.Fatalf("captured %d spans, want 1", len(captured.Spans))
+	}
+	span := captured.Spans[0]
+	if span.Name != "GET /cart" || span.Kind != "server" || span.SpanID != "0102030405060708" ||
+		span.Attributes["http.method"] != "GET" || span.Resource["service.name"] != "checkout" ||
+		span.Scope != "otel-test" || span.EndTime.Sub(span.StartTime).Seconds() != 1 {
+		t.Errorf("captured span = %+v", span)
+	}
+
+	if other := sink.Telemetry("sb-2", "", 0); len(other.Spans) != 0 {
+		t.Errorf("sb-2 captured %d spans, want telemetry kept per sandbox", len(other.Spans))
+	}
+}
+
+func TestDecodeMetricsProto(t *testing.T) {
+	sum := 12.5
+	data := mustMarshal(t, &metricspb.MetricsData{ResourceMetrics: []*metricspb.ResourceMetrics{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{stringAttr("service.name", "checkout")}},
+		ScopeMetrics: []*metricspb.ScopeMetrics{{
+			Scope: &commonpb.InstrumentationScope{Name: "otel-test"},
+			Metrics: []*metricspb.Metric{
+				{Name: "queue.size", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: []*metricspb.NumberDataPoint{
+					{TimeUnixNano: 1_700_000_000_000_000_000, Value: &metricspb.NumberDataPoint_AsInt{AsInt: 7}, Attributes: []*commonpb.KeyValue{stringAttr("queue", "orders")}},
+				}}}},
+				{Name: "request.duration", Unit: "ms", Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{DataPoints: []*metricspb.HistogramDataPoint{
+					{TimeUnixNano: 1_700_000_000_000_000_000, Count: 3, Sum: &sum},
+				}}}},
+			},
+		}},
+	}}})
+
+	points, err := decodeMetricsProto(data)
+	if err != nil {
+		t.Fatalf("decodeMetricsProto() error = %v", err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("decoded %d points, want 2", len(points))
+	}
+	gauge := points[0]
+	if gauge.Type != "gauge" || gauge.Value == nil || *gauge.Value != 7 || gauge.Attributes["queue"] != "orders" || gauge.Resource["service.name"] != "checkout" || gauge.Scope != "otel-test" {
+		t.Errorf("gauge point = %+v", gauge)
+	}
+	histogram := points[1]
+	if histogram.Type != "histogram" || histogram.Unit != "ms" || histogram.Count != 3 || histogram.Sum == nil || *histogram.Sum != 12.5 {
+		t.Errorf("histogram point = %+v", histogram)
+	}
+}
+
+func TestExportRequiresSandbox(t *testing.T) {
+	server := httptest.NewServer(New(0))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/v1/traces", "application/x-protobuf", bytes.NewReader(tracesRequest(t, "span")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("export without %s returned %d, want 400", SandboxHeader, resp.StatusCode)
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	sink := New(0)
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	body := `{"resourceMetrics":[{"resource":{"attributes":[{"key":"deployment.environment","value":{"stringValue":"dev"}}]},
+		"scopeMetrics":[{"metrics":[{"name":"requests","sum":{"dataPoints":[{"asInt":"42","timeUnixNano":"1700000000000000000",
+		"attributes":[{"key":"status","value":{"intValue":"200"}}]}]}}]}]}]}`
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/metrics", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SandboxHeader, "sb-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	captured := sink.Telemetry("sb-1", SignalMetrics, 0)
+	if len(captured.Metrics) != 1 {
+		t.Fatalf("captured %d metric points, want 1", len(captured.Metrics))
+	}
+	point := captured.Metrics[0]
+	if point.Type != "sum" || point.Value == nil || *point.Value != 42 || point.Attributes["status"] != int64(200) ||
+		point.Resource["deployment.environment"] != "dev" {
+		t.Errorf("captured point = %+v", point)
+	}
+}
+
+func TestExportGRPC(t *testing.T) {
+	sink := New(0)
+	server := httptest.NewUnstartedServer(sink)
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	defer server.Close()
+
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport}
+
+	message := mustMarshal(t, &logspb.LogsData{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{
+			LogRecords: []*logspb.LogRecord{{
+				TimeUnixNano:   1_700_000_000_000_000_000,
+				SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+				SeverityText:   "ERROR",
+				Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "payment failed for card 4111-1111"}},
+				Attributes: []*commonpb.KeyValue{{
+					Key:   "ratio",
+					Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 0.5}},
+				}},
+			}},
+		}},
+	}}})
+
+	frame := []byte{0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	frame = append(frame, message...)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/opentelemetry.proto.collector.logs.v1.LogsService/Export", bytes.NewReader(frame))
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	req.Header.Set(SandboxHeader, "sb-1")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var respBody bytes.Buffer
+	respBody.ReadFrom(resp.Body)
+	resp.Body.Close()
+
+	if resp.ProtoMajor != 2 || resp.Trailer.Get("Grpc-Status") != "0" {
+		t.Fatalf("export over HTTP/%d returned grpc-status %q, want 0 over HTTP/2", resp.ProtoMajor, resp.Trailer.Get("Grpc-Status"))
+	}
+
+	captured := sink.Telemetry("sb-1", SignalLogs, 0)
+	if len(captured.Logs) != 1 {
+		t.Fatalf("captured %d logs, want 1", len(captured.Logs))
+	}
+	record := captured.Logs[0]
+	if record.SeverityText != "ERROR" || record.SeverityNumber != 17 || record.Attributes["ratio"] != 0.5 {
+		t.Errorf("captured log = %+v", record)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	telemetry := &Telemetry{
+		Spans: []Span{
+			{Name: "GET /cart", SpanID: "01", Attributes: Attributes{"user.card": "4111-1111"}, Resource: Attributes{"deployment.environment": "dev"}},
+			{Name: "GET /health", SpanID: "02", Resource: Attributes{}},
+		},
+		Logs: []LogRecord{{Body: "card 4111-1111 declined"}},
+	}
+
+	tests := []struct {
+		assertion  Assertion
+		wantPassed bool
+		wantFailed int
+	}{
+		{Assertion{Signal: SignalTraces, Type: AssertAttributePresent, Key: "deployment.environment"}, false, 1},
+		{Assertion{Signal: SignalTraces, Type: AssertAttributePresent, Key: "deployment.environment", Name: "^GET /cart$"}, true, 0},
+		{Assertion{Signal: SignalTraces, Type: AssertAttributePresent, Key: "deployment.environment", Where: "attributes", Name: "cart"}, false, 1},
+		{Assertion{Type: AssertNoAttributeMatches, Pattern: `\d{4}-\d{4}`}, false, 2},
+		{Assertion{Signal: SignalTraces, Type: AssertNoAttributeMatches, Pattern: "credit_card"}, true, 0},
+		{Assertion{Signal: SignalTraces, Type: AssertAttributeEquals, Key: "deployment.environment", Value: "dev", Name: "cart"}, true, 0},
+		{Assertion{Signal: SignalMetrics, Type: AssertAttributeAbsent, Key: "user.card"}, false, 0},
+		{Assertion{Signal: SignalTraces, Type: AssertMinCount, Count: 2}, true, 0},
+		{Assertion{Signal: SignalTraces, Type: "every_span_is_fast"}, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.assertion.String(), func(t *testing.T) {
+			result := telemetry.Evaluate([]Assertion{tt.assertion})[0]
+			if result.Passed != tt.wantPassed || result.Failures != tt.wantFailed {
+				t.Errorf("Evaluate() = %+v, want passed=%v with %d failures", result, tt.wantPassed, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestMaxItems(t *testing.T) {
+	sink := New(2)
+	for i := 0; i < 3; i++ {
+		if err := sink.export("sb-1", SignalTraces, tracesRequest(t, "span"), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	captured := sink.Telemetry("sb-1", SignalTraces, 0)
+	if len(captured.Spans) != 2 || captured.DroppedSpans != 1 {
+		t.Errorf("captured %d spans with %d dropped, want 2 with 1 dropped", len(captured.Spans), captured.DroppedSpans)
+	}
+
+	sink.Reset("sb-1")
+	if captured := sink.Telemetry("sb-1", "", 0); len(captured.Spans) != 0 {
+		t.Errorf("Reset() kept %d spans", len(captured.Spans))
+	}
+}
@@ -0,0 +1,180 @@
+package sandbox
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
+	"gopkg.in/yaml.v2"
+)
+
+// captureExporterName is the exporter added to sandbox configs when
+// telemetry capture is enabled
+const captureExporterName = "otlphttp/capture"
+
+// metadataCaptureExporter marks sandboxes whose config exports to the sink
+const metadataCaptureExporter = "capture_exporter"
+
+// EnableCapture makes every sandbox created from now on also export its
+// telemetry to sink, which collectors reach at endpoint (an OTLP/HTTP base
+// URL such as http://host.docker.internal:4319)
+func (m *Manager) EnableCapture(sink *otlpsink.Sink, endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.capture = sink
+	m.captureEndpoint = endpoint
+	m.validator.capture = sink
+}
+
+// CapturedTelemetry returns the telemetry a sandbox exported to the capture
+// sink. signal is "traces", "metrics", "logs" or "" for all, and limit keeps
+// the most recent items of each signal (0 for all).
+func (m *Manager) CapturedTelemetry(sandboxID, signal string, limit int) (*otlpsink.Telemetry, error) {
+	sink, err := m.captureSink(sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	return sink.Telemetry(sandboxID, signal, limit), nil
+}
+
+// ClearCapturedTelemetry discards the telemetry captured for a sandbox, e.g.
+// before generating telemetry for a new set of assertions
+func (m *Manager) ClearCapturedTelemetry(sandboxID string) error {
+	sink, err := m.captureSink(sandboxID)
+	if err != nil {
+		return err
+	}
+	sink.Reset(sandboxID)
+	return nil
+}
+
+// captureSink returns the capture sink after checking the sandbox exists
+func (m *Manager) captureSink(sandboxID string) (*otlpsink.Sink, error) {
+	if _, err := m.GetSandbox(sandboxID); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.capture == nil {
+		return nil, fmt.Errorf("telemetry capture is not enabled")
+	}
+	return m.capture, nil
+}
+
+// withCaptureExporter returns config with an exporter sending to the capture
+// sink added to every pipeline. The exporter tags requests with the sandbox
+// ID so the sink can tell sandboxes apart.
+func withCaptureExporter(config, endpoint, sandboxID string) (string, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(config), &doc); err != nil {
+		return "", fmt.Errorf("invalid collector config: %w", err)
+	}
+
+	exporter := yaml.MapSlice{
+		{Key: "endpoint", Value: endpoint},
+		{Key: "headers", Value: yaml.MapSlice{{Key: otlpsink.SandboxHeader, Value: sandboxID}}},
+	}
+
+	exporters, _ := mapValue(doc, "exporters").(yaml.MapSlice)
+	doc = setMapValue(doc, "exporters", append(exporters, yaml.MapItem{Key: captureExporterName, Value: exporter}))
+
+	service, _ := mapValue(doc, "service").(yaml.MapSlice)
+	pipelines, _ := mapValue(service, "pipelines").(yaml.MapSlice)
+	if len(pipelines) == 0 {
+		return "", fmt.Errorf("collector config has no pipelines to capture")
+	}
+	for i, item := range pipelines {
+		pipeline, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		names, _ := mapValue(pipeline, "exporters").([]interface{})
+		pipelines[i].Value = setMapValue(pipeline, "exporters", append(names, captureExporterName))
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to render collector config: %w", err)
+	}
+	return string(out), nil
+}
+
+// mapValue returns the value of key in a YAML mapping
+func mapValue(m yaml.MapSlice, key string) interface{} {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// setMapValue sets key in a YAML mapping, keeping the order of existing keys
+func setMapValue(m yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range m {
+		if item.Key == key {
+			m[i].Value = value
+			return m
+		}
+	}
+	return append(m, yaml.MapItem{Key: key, Value: value})
+}
+
+// checkAssertions evaluates the request's assertions against the telemetry
+// the sandbox exported to the capture sink
+func (v *Validator) checkAssertions(sandbox *Sandbox, assertions []otlpsink.Assertion, result *ValidationResult) {
+	if len(assertions) == 0 {
+		return
+	}
+
+	if v.capture == nil || sandbox.Metadata[metadataCaptureExporter] == nil {
+		message := "Telemetry capture is not enabled"
+		if v.capture != nil {
+			message = "Sandbox was created without telemetry capture"
+		}
+		result.Checks = append(result.Checks, ValidationCheck{
+			Name:      "Telemetry Assertions",
+			Category:  "assertion",
+			Status:    "warning",
+			Severity:  "medium",
+			Message:   message,
+			Details:   fmt.Sprintf("%d assertion(s) were not evaluated", len(assertions)),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	result.Assertions = v.capture.Telemetry(sandbox.ID, "", 0).Evaluate(assertions)
+
+	for _, outcome := range result.Assertions {
+		check := ValidationCheck{
+			Name:      outcome.Assertion.String(),
+			Category:  "assertion",
+			Message:   outcome.Message,
+			Timestamp: time.Now(),
+		}
+		if outcome.Passed {
+			check.Status = "passed"
+			check.Severity = "info"
+		} else {
+			check.Status = "failed"
+			check.Severity = "high"
+			if len(outcome.Examples) > 0 {
+				check.Details = fmt.Sprintf("e.g. %v", outcome.Examples)
+			}
+
+			result.Issues = append(result.Issues, ValidationIssue{
+				Type:        "assertion",
+				Severity:    "high",
+				Component:   captureExporterName,
+				Message:     fmt.Sprintf("Assertion failed: %s", check.Name),
+				Description: outcome.Message,
+				Suggestion:  "Check the processors that should set or redact these attributes",
+				Timestamp:   time.Now(),
+			})
+		}
+		result.Checks = append(result.Checks, check)
+	}
+}
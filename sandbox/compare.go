@@ -0,0 +1,533 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mottibechhofer/otel-ai-engineer/otlpgen"
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
+)
+
+// componentComparison labels the collectors of a comparison, so Reconcile
+// removes rather than adopts them if the server stops mid-comparison
+const componentComparison = "comparison"
+
+// maxCardinality caps the distinct values counted for an attribute
+const maxCardinality = 10000
+
+// comparisonSide is one of the two collectors of a comparison
+type comparisonSide struct {
+	name        string // "baseline" or "candidate"
+	id          string // Names the container and config file, and tags captured telemetry
+	config      string
+	containerID string
+	run         *CollectorRun
+	telemetry   *otlpsink.Telemetry
+}
+
+// CompareConfigs runs a baseline and a candidate collector config side by
+// side in one network, sends both identical telemetry and reports how their
+// output and resource use differ. Comparing what they exported needs
+// telemetry capture. The collectors are removed afterwards.
+func (m *Manager) CompareConfigs(ctx context.Context, req CompareConfigsRequest) (*ComparisonReport, error) {
+	if strings.TrimSpace(req.BaselineConfig) == "" || strings.TrimSpace(req.CandidateConfig) == "" {
+		return nil, fmt.Errorf("both a baseline and a candidate config are required")
+	}
+	if req.CollectorVersion == "" {
+		req.CollectorVersion = "latest"
+	}
+	settle := req.SettleTime
+	if settle == 0 {
+		settle = 5 * time.Second
+	}
+
+	telemetryConfig := TelemetryConfig{
+		GenerateTraces:  true,
+		GenerateMetrics: true,
+		GenerateLogs:    true,
+		OTLPProtocol:    otlpgen.ProtocolGRPC,
+	}
+	if req.TelemetryConfig != nil {
+		telemetryConfig = *req.TelemetryConfig
+	}
+	// The telemetry must go to both collectors and nowhere else
+	telemetryConfig.OTLPEndpoint = ""
+	if telemetryConfig.OTLPProtocol == "" {
+		telemetryConfig.OTLPProtocol = otlpgen.ProtocolGRPC
+	}
+
+	m.mu.RLock()
+	sink, captureEndpoint := m.capture, m.captureEndpoint
+	m.mu.RUnlock()
+
+	comparisonID := uuid.New().String()
+	report := &ComparisonReport{ID: comparisonID, StartedAt: time.Now()}
+	sides := []*comparisonSide{
+		{name: "baseline", config: req.BaselineConfig, run: &report.Baseline},
+		{name: "candidate", config: req.CandidateConfig, run: &report.Candidate},
+	}
+	for _, side := range sides {
+		side.id = comparisonID + "-" + side.name
+	}
+
+	m.logger.Info("Comparing collector configs", map[string]interface{}{
+		"comparison_id": comparisonID,
+	})
+
+	networkName, _, err := m.dockerOrchestrator.CreateNetwork(ctx, comparisonID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create network: %w", err)
+	}
+	defer m.removeComparison(comparisonID, sides, sink)
+
+	for _, side := range sides {
+		config := side.config
+		if sink != nil {
+			config, err = withCaptureExporter(side.config, captureEndpoint, side.id)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s config: %w", side.name, err)
+			}
+		}
+
+		collectorInfo, err := m.dockerOrchestrator.DeployCollector(ctx, DeployCollectorConfig{
+			SandboxID:        comparisonID,
+			Name:             side.id,
+			NetworkAlias:     side.name,
+			Component:        componentComparison,
+			Config:           config,
+			CollectorVersion: req.CollectorVersion,
+			NetworkName:      networkName,
+			HostGateway:      sink != nil,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to deploy %s collector: %w", side.name, err)
+		}
+		side.containerID = collectorInfo.ContainerID
+
+		if collectorInfo.Status != "running" {
+			logs, _ := m.dockerOrchestrator.GetContainerLogs(ctx, side.containerID, 20)
+			messages := make([]string, 0, len(logs))
+			for _, log := range logs {
+				messages = append(messages, log.Message)
+			}
+			return nil, fmt.Errorf("%s collector is %s\nLogs: %s", side.name, collectorInfo.Status, strings.Join(messages, "\n"))
+		}
+	}
+
+	// Replay recorded telemetry, or generate it
+	var source telemetrySource
+	collectorIDs := []string{sides[0].containerID, sides[1].containerID}
+	duration := req.Duration
+	if req.Replay != nil {
+		source, err = m.newReplay(ctx, telemetryConfig, *req.Replay, duration, collectorIDs...)
+	} else {
+		if duration == 0 {
+			duration = 30 * time.Second
+		}
+		source, err = m.newTelemetryGenerator(ctx, telemetryConfig, duration, collectorIDs...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start telemetry: %w", err)
+	}
+
+	samplingCtx, stopSampling := context.WithCancel(ctx)
+	sampled := m.sampleResources(samplingCtx, sides)
+	err = source.Run(ctx)
+	if err == nil {
+		// Let batches and queues drain before measuring
+		timer := time.NewTimer(settle)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+		case <-timer.C:
+		}
+	}
+	stopSampling()
+	<-sampled
+	if err != nil {
+		return nil, fmt.Errorf("failed to send telemetry: %w", err)
+	}
+
+	report.Sent = source.Stats()
+	if report.Sent.Spans+report.Sent.MetricPoints+report.Sent.Logs == 0 {
+		return nil, fmt.Errorf("no telemetry reached the collectors: %s", report.Sent.LastError)
+	}
+	if report.Sent.FailedExports > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d export requests failed, so the collectors may not have received the same telemetry: %s", report.Sent.FailedExports, report.Sent.LastError))
+	}
+
+	var sending time.Duration
+	if report.Sent.FinishedAt != nil {
+		sending = report.Sent.FinishedAt.Sub(report.Sent.StartedAt)
+	}
+
+	for _, side := range sides {
+		metrics, err := m.dockerOrchestrator.GetCollectorMetrics(ctx, side.containerID)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("failed to get %s collector metrics: %v", side.name, err))
+		} else {
+			side.run.Metrics = *metrics
+		}
+
+		logs, err := m.dockerOrchestrator.GetContainerLogs(ctx, side.containerID, 1000)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("failed to get %s collector logs: %v", side.name, err))
+		}
+		for _, log := range logs {
+			if log.Level == "error" {
+				side.run.ErrorLogs++
+			}
+		}
+
+		if sink == nil {
+			continue
+		}
+		side.telemetry = sink.Telemetry(side.id, "", 0)
+		side.run.Exported = map[string]int{
+			otlpsink.SignalTraces:  len(side.telemetry.Spans) + side.telemetry.DroppedSpans,
+			otlpsink.SignalMetrics: len(side.telemetry.Metrics) + side.telemetry.DroppedMetrics,
+			otlpsink.SignalLogs:    len(side.telemetry.Logs) + side.telemetry.DroppedLogs,
+		}
+		if sending > 0 {
+			side.run.ExportedPerSecond = make(map[string]float64, len(side.run.Exported))
+			for signal, items := range side.run.Exported {
+				side.run.ExportedPerSecond[signal] = float64(items) / sending.Seconds()
+			}
+		}
+	}
+
+	if sink != nil {
+		report.Signals = compareTelemetry(sides[0].telemetry, sides[1].telemetry)
+		for _, signal := range report.Signals {
+			if warning := signal.truncation(); warning != "" {
+				report.Warnings = append(report.Warnings, warning)
+			}
+		}
+	} else {
+		report.Warnings = append(report.Warnings, "telemetry capture is not enabled, so exported attributes and cardinality were not compared")
+	}
+	report.Differences = report.differences()
+	report.CompletedAt = time.Now()
+
+	m.logger.Info("Compared collector configs", map[string]interface{}{
+		"comparison_id": comparisonID,
+		"differences":   len(report.Differences),
+	})
+
+	return report, nil
+}
+
+// sampleResources samples the CPU and memory use of each side's collector
+// until ctx is done. The returned channel is closed once the averages and
+// peaks are recorded.
+func (m *Manager) sampleResources(ctx context.Context, sides []*comparisonSide) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		cpuTotals := make([]float64, len(sides))
+		samples := make([]int, len(sides))
+		for ctx.Err() == nil {
+			for i, side := range sides {
+				cpu, memory, err := m.dockerOrchestrator.ContainerStats(ctx, side.containerID)
+				if err != nil {
+					continue
+				}
+				cpuTotals[i] += cpu
+				samples[i]++
+				side.run.PeakCPUPercent = max(side.run.PeakCPUPercent, cpu)
+				side.run.PeakMemoryMB = max(side.run.PeakMemoryMB, memory)
+			}
+
+			timer := time.NewTimer(time.Second)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+
+		for i, side := range sides {
+			if samples[i] > 0 {
+				side.run.AvgCPUPercent = cpuTotals[i] / float64(samples[i])
+			}
+		}
+	}()
+	return done
+}
+
+// removeComparison removes the collectors, network and config files of a
+// comparison, and the telemetry captured from it
+func (m *Manager) removeComparison(comparisonID string, sides []*comparisonSide, sink *otlpsink.Sink) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, side := range sides {
+		if side.containerID != "" {
+			if err := m.dockerOrchestrator.RemoveContainer(ctx, side.containerID); err != nil {
+				m.logger.Error("Failed to remove comparison collector", err, map[string]interface{}{
+					"comparison_id": comparisonID,
+					"container_id":  side.containerID,
+				})
+			}
+		}
+		if err := m.dockerOrchestrator.RemoveCollectorConfig(side.id); err != nil {
+			m.logger.Error("Failed to remove comparison collector config", err, map[string]interface{}{
+				"comparison_id": comparisonID,
+			})
+		}
+		if sink != nil {
+			sink.Reset(side.id)
+		}
+	}
+
+	if err := m.dockerOrchestrator.CleanupNetwork(ctx, comparisonID); err != nil {
+		m.logger.Error("Failed to remove comparison network", err, map[string]interface{}{
+			"comparison_id": comparisonID,
+		})
+	}
+}
+
+// attributeKey identifies an attribute of an item or of its resource
+type attributeKey struct {
+	key      string
+	resource bool
+}
+
+// exportSummary is the names and attribute values of the items of one
+// signal a collector exported
+type exportSummary struct {
+	items      int
+	dropped    int // Items the capture sink discarded
+	names      map[string]bool
+	attributes map[attributeKey]map[string]bool
+}
+
+// summarize summarizes the captured items of a signal
+func summarize(telemetry *otlpsink.Telemetry, signal string) exportSummary {
+	summary := exportSummary{
+		names:      make(map[string]bool),
+		attributes: make(map[attributeKey]map[string]bool),
+	}
+	add := func(name string, attributes, resource otlpsink.Attributes) {
+		if name != "" {
+			summary.names[name] = true
+		}
+		summary.addAttributes(attributes, false)
+		summary.addAttributes(resource, true)
+	}
+
+	switch signal {
+	case otlpsink.SignalTraces:
+		summary.items = len(telemetry.Spans) + telemetry.DroppedSpans
+		summary.dropped = telemetry.DroppedSpans
+		for _, span := range telemetry.Spans {
+			add(span.Name, span.Attributes, span.Resource)
+		}
+	case otlpsink.SignalMetrics:
+		summary.items = len(telemetry.Metrics) + telemetry.DroppedMetrics
+		summary.dropped = telemetry.DroppedMetrics
+		for _, point := range telemetry.Metrics {
+			add(point.Name, point.Attributes, point.Resource)
+		}
+	case otlpsink.SignalLogs:
+		summary.items = len(telemetry.Logs) + telemetry.DroppedLogs
+		summary.dropped = telemetry.DroppedLogs
+		for _, record := range telemetry.Logs {
+			add("", record.Attributes, record.Resource)
+		}
+	}
+	return summary
+}
+
+func (s exportSummary) addAttributes(attributes otlpsink.Attributes, resource bool) {
+	for key, value := range attributes {
+		k := attributeKey{key: key, resource: resource}
+		values := s.attributes[k]
+		if values == nil {
+			values = make(map[string]bool)
+			s.attributes[k] = values
+		}
+		if len(values) < maxCardinality {
+			values[fmt.Sprint(value)] = true
+		}
+	}
+}
+
+// compareTelemetry compares what two collectors exported, signal by signal
+func compareTelemetry(baseline, candidate *otlpsink.Telemetry) []SignalComparison {
+	var comparisons []SignalComparison
+	for _, signal := range []string{otlpsink.SignalTraces, otlpsink.SignalMetrics, otlpsink.SignalLogs} {
+		a, b := summarize(baseline, signal), summarize(candidate, signal)
+		if a.items == 0 && b.items == 0 {
+			continue
+		}
+
+		comparison := SignalComparison{
+			Signal:           signal,
+			BaselineItems:    a.items,
+			CandidateItems:   b.items,
+			BaselineDropped:  a.dropped,
+			CandidateDropped: b.dropped,
+			OnlyInBaseline:   missingNames(a.names, b.names),
+			OnlyInCandidate:  missingNames(b.names, a.names),
+		}
+
+		keys := make([]attributeKey, 0, len(a.attributes))
+		for key := range a.attributes {
+			keys = append(keys, key)
+		}
+		for key := range b.attributes {
+			if a.attributes[key] == nil {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].resource != keys[j].resource {
+				return keys[i].resource
+			}
+			return keys[i].key < keys[j].key
+		})
+
+		for _, key := range keys {
+			baselineValues, candidateValues := len(a.attributes[key]), len(b.attributes[key])
+			if baselineValues == candidateValues {
+				comparison.UnchangedAttributes++
+				continue
+			}
+			comparison.Attributes = append(comparison.Attributes, AttributeComparison{
+				Key:       key.key,
+				Resource:  key.resource,
+				Baseline:  baselineValues,
+				Candidate: candidateValues,
+			})
+		}
+
+		comparisons = append(comparisons, comparison)
+	}
+	return comparisons
+}
+
+// truncation describes the items the capture sink discarded, or returns ""
+// if it kept them all
+func (c SignalComparison) truncation() string {
+	if c.BaselineDropped == 0 && c.CandidateDropped == 0 {
+		return ""
+	}
+	noun := itemNouns[c.Signal]
+	return fmt.Sprintf("the capture sink kept only the latest %d of %d baseline and %d of %d candidate %s, so their names and attributes were compared on those",
+		c.BaselineItems-c.BaselineDropped, c.BaselineItems, c.CandidateItems-c.CandidateDropped, c.CandidateItems, noun)
+}
+
+// missingNames returns the names in names that aren't in others, sorted
+func missingNames(names, others map[string]bool) []string {
+	var missing []string
+	for name := range names {
+		if !others[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// itemNouns name the items of each signal
+var itemNouns = map[string]string{
+	otlpsink.SignalTraces:  "spans",
+	otlpsink.SignalMetrics: "metric points",
+	otlpsink.SignalLogs:    "log records",
+}
+
+// differences describes how the candidate differs from the baseline
+func (r *ComparisonReport) differences() []string {
+	var differences []string
+	baseline, candidate := r.Baseline, r.Candidate
+
+	counts := []struct {
+		what                string
+		baseline, candidate int64
+	}{
+		{"spans refused by receivers", baseline.Metrics.ReceiverRefusedSpans, candidate.Metrics.ReceiverRefusedSpans},
+		{"metric points refused by receivers", baseline.Metrics.ReceiverRefusedMetrics, candidate.Metrics.ReceiverRefusedMetrics},
+		{"log records refused by receivers", baseline.Metrics.ReceiverRefusedLogs, candidate.Metrics.ReceiverRefusedLogs},
+		{"spans dropped by processors", baseline.Metrics.ProcessorDroppedSpans, candidate.Metrics.ProcessorDroppedSpans},
+		{"metric points dropped by processors", baseline.Metrics.ProcessorDroppedMetrics, candidate.Metrics.ProcessorDroppedMetrics},
+		{"spans that failed to export", baseline.Metrics.ExporterFailedSpans, candidate.Metrics.ExporterFailedSpans},
+		{"metric points that failed to export", baseline.Metrics.ExporterFailedMetrics, candidate.Metrics.ExporterFailedMetrics},
+		{"log records that failed to export", baseline.Metrics.ExporterFailedLogs, candidate.Metrics.ExporterFailedLogs},
+	}
+	for _, count := range counts {
+		if count.baseline != count.candidate {
+			differences = append(differences, fmt.Sprintf("%s: %d in the candidate, %d in the baseline", count.what, count.candidate, count.baseline))
+		}
+	}
+
+	for _, signal := range r.Signals {
+		noun := itemNouns[signal.Signal]
+		if signal.CandidateItems != signal.BaselineItems {
+			differences = append(differences, fmt.Sprintf("the candidate exported %d %s, the baseline %d (%s)",
+				signal.CandidateItems, noun, signal.BaselineItems, percentChange(float64(signal.BaselineItems), float64(signal.CandidateItems))))
+		}
+		if len(signal.OnlyInBaseline) > 0 {
+			differences = append(differences, fmt.Sprintf("%s only the baseline exported: %s", noun, strings.Join(signal.OnlyInBaseline, ", ")))
+		}
+		if len(signal.OnlyInCandidate) > 0 {
+			differences = append(differences, fmt.Sprintf("%s only the candidate exported: %s", noun, strings.Join(signal.OnlyInCandidate, ", ")))
+		}
+
+		for _, attribute := range signal.Attributes {
+			what := fmt.Sprintf("attribute %q on %s", attribute.Key, noun)
+			if attribute.Resource {
+				what = fmt.Sprintf("resource attribute %q on %s", attribute.Key, noun)
+			}
+			switch {
+			case attribute.Candidate == 0:
+				differences = append(differences, fmt.Sprintf("%s was removed (%d distinct values in the baseline)", what, attribute.Baseline))
+			case attribute.Baseline == 0:
+				differences = append(differences, fmt.Sprintf("%s was added (%d distinct values)", what, attribute.Candidate))
+			default:
+				differences = append(differences, fmt.Sprintf("%s has %d distinct values in the candidate, %d in the baseline", what, attribute.Candidate, attribute.Baseline))
+			}
+		}
+	}
+
+	if significant(baseline.PeakMemoryMB, candidate.PeakMemoryMB) {
+		differences = append(differences, fmt.Sprintf("peak memory: %.1f MB in the candidate, %.1f MB in the baseline (%s)",
+			candidate.PeakMemoryMB, baseline.PeakMemoryMB, percentChange(baseline.PeakMemoryMB, candidate.PeakMemoryMB)))
+	}
+	if significant(baseline.AvgCPUPercent, candidate.AvgCPUPercent) {
+		differences = append(differences, fmt.Sprintf("average CPU: %.1f%% in the candidate, %.1f%% in the baseline",
+			candidate.AvgCPUPercent, baseline.AvgCPUPercent))
+	}
+	if candidate.ErrorLogs != baseline.ErrorLogs {
+		differences = append(differences, fmt.Sprintf("error log lines: %d in the candidate, %d in the baseline", candidate.ErrorLogs, baseline.ErrorLogs))
+	}
+
+	if len(differences) == 0 {
+		differences = append(differences, "no notable differences")
+	}
+	return differences
+}
+
+// significant reports whether a resource measurement changed by more than
+// 10% and by more than one unit, to ignore sampling noise
+func significant(baseline, candidate float64) bool {
+	diff := candidate - baseline
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff > 1 && diff > baseline/10
+}
+
+// percentChange formats the change from baseline to candidate
+func percentChange(baseline, candidate float64) string {
+	if baseline == 0 {
+		return "none in the baseline"
+	}
+	return fmt.Sprintf("%+.1f%%", (candidate-baseline)/baseline*100)
+}
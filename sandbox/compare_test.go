@@ -0,0 +1,230 @@
+package sandbox
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
+)
+
+func TestCompareTelemetry(t *testing.T) {
+	tests := []struct {
+		name                string
+		baseline, candidate *otlpsink.Telemetry
+		want                []SignalComparison
+	}{
+		{
+			name:      "nothing exported",
+			baseline:  &otlpsink.Telemetry{},
+			candidate: &otlpsink.Telemetry{},
+		},
+		{
+			name: "same spans",
+			baseline: &otlpsink.Telemetry{Spans: []otlpsink.Span{
+				{Name: "GET /", Attributes: otlpsink.Attributes{"http.route": "/"}, Resource: otlpsink.Attributes{"service.name": "frontend"}},
+			}},
+			candidate: &otlpsink.Telemetry{Spans: []otlpsink.Span{
+				{Name: "GET /", Attributes: otlpsink.Attributes{"http.route": "/"}, Resource: otlpsink.Attributes{"service.name": "frontend"}},
+			}},
+			want: []SignalComparison{{Signal: otlpsink.SignalTraces, BaselineItems: 1, CandidateItems: 1, UnchangedAttributes: 2}},
+		},
+		{
+			name: "sampled and attributes changed",
+			baseline: &otlpsink.Telemetry{Spans: []otlpsink.Span{
+				{Name: "GET /", Attributes: otlpsink.Attributes{"user.id": "1", "user.email": "a@example.com"}},
+				{Name: "GET /", Attributes: otlpsink.Attributes{"user.id": "2", "user.email": "b@example.com"}},
+				{Name: "health", Resource: otlpsink.Attributes{"host.name": "a"}},
+			}},
+			candidate: &otlpsink.Telemetry{Spans: []otlpsink.Span{
+				{Name: "GET /", Attributes: otlpsink.Attributes{"user.id": "redacted", "env": "prod"}, Resource: otlpsink.Attributes{"host.name": "a"}},
+			}},
+			want: []SignalComparison{{
+				Signal:         otlpsink.SignalTraces,
+				BaselineItems:  3,
+				CandidateItems: 1,
+				OnlyInBaseline: []string{"health"},
+				Attributes: []AttributeComparison{
+					{Key: "env", Baseline: 0, Candidate: 1},
+					{Key: "user.email", Baseline: 2, Candidate: 0},
+					{Key: "user.id", Baseline: 2, Candidate: 1},
+				},
+				UnchangedAttributes: 1,
+			}},
+		},
+		{
+			name: "metrics and logs",
+			baseline: &otlpsink.Telemetry{
+				Metrics: []otlpsink.MetricPoint{{Name: "requests"}, {Name: "latency"}},
+				Logs:    []otlpsink.LogRecord{{Attributes: otlpsink.Attributes{"level": "info"}}},
+			},
+			candidate: &otlpsink.Telemetry{
+				Metrics: []otlpsink.MetricPoint{{Name: "requests"}, {Name: "requests.total"}},
+			},
+			want: []SignalComparison{
+				{Signal: otlpsink.SignalMetrics, BaselineItems: 2, CandidateItems: 2, OnlyInBaseline: []string{"latency"}, OnlyInCandidate: []string{"requests.total"}},
+				{Signal: otlpsink.SignalLogs, BaselineItems: 1, Attributes: []AttributeComparison{{Key: "level", Baseline: 1}}},
+			},
+		},
+		{
+			name: "truncated by the sink",
+			baseline: &otlpsink.Telemetry{
+				Logs:        []otlpsink.LogRecord{{}, {}},
+				DroppedLogs: 8,
+			},
+			candidate: &otlpsink.Telemetry{Logs: []otlpsink.LogRecord{{}}},
+			want:      []SignalComparison{{Signal: otlpsink.SignalLogs, BaselineItems: 10, CandidateItems: 1, BaselineDropped: 8}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareTelemetry(tt.baseline, tt.candidate)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareTelemetry() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignalComparisonTruncation(t *testing.T) {
+	tests := []struct {
+		name       string
+		comparison SignalComparison
+		want       string
+	}{
+		{"kept everything", SignalComparison{Signal: otlpsink.SignalTraces, BaselineItems: 10, CandidateItems: 5}, ""},
+		{
+			"dropped",
+			SignalComparison{Signal: otlpsink.SignalTraces, BaselineItems: 15000, CandidateItems: 9000, BaselineDropped: 5000},
+			"the capture sink kept only the latest 10000 of 15000 baseline and 9000 of 9000 candidate spans, so their names and attributes were compared on those",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.comparison.truncation()
+			if got != tt.want {
+				t.Errorf("truncation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDifferences(t *testing.T) {
+	tests := []struct {
+		name   string
+		report ComparisonReport
+		want   []string
+	}{
+		{
+			name: "identical",
+			report: ComparisonReport{
+				Baseline:  CollectorRun{PeakMemoryMB: 100, AvgCPUPercent: 5},
+				Candidate: CollectorRun{PeakMemoryMB: 105, AvgCPUPercent: 5.5},
+				Signals:   []SignalComparison{{Signal: otlpsink.SignalTraces, BaselineItems: 10, CandidateItems: 10}},
+			},
+			want: []string{"no notable differences"},
+		},
+		{
+			name: "collector metrics",
+			report: ComparisonReport{
+				Baseline:  CollectorRun{Metrics: CollectorMetrics{ProcessorDroppedSpans: 0, ExporterFailedLogs: 3}},
+				Candidate: CollectorRun{Metrics: CollectorMetrics{ProcessorDroppedSpans: 40, ExporterFailedLogs: 3}, ErrorLogs: 2},
+			},
+			want: []string{
+				"spans dropped by processors: 40 in the candidate, 0 in the baseline",
+				"error log lines: 2 in the candidate, 0 in the baseline",
+			},
+		},
+		{
+			name: "signals",
+			report: ComparisonReport{Signals: []SignalComparison{
+				{
+					Signal:          otlpsink.SignalTraces,
+					BaselineItems:   200,
+					CandidateItems:  50,
+					OnlyInBaseline:  []string{"health"},
+					OnlyInCandidate: []string{"GET /v2"},
+					Attributes: []AttributeComparison{
+						{Key: "user.email", Baseline: 20},
+						{Key: "env", Candidate: 1},
+						{Key: "user.id", Baseline: 20, Candidate: 1},
+						{Key: "host.name", Resource: true, Baseline: 3},
+					},
+				},
+				{Signal: otlpsink.SignalLogs, CandidateItems: 4},
+			}},
+			want: []string{
+				"the candidate exported 50 spans, the baseline 200 (-75.0%)",
+				"spans only the baseline exported: health",
+				"spans only the candidate exported: GET /v2",
+				`attribute "user.email" on spans was removed (20 distinct values in the baseline)`,
+				`attribute "env" on spans was added (1 distinct values)`,
+				`attribute "user.id" on spans has 1 distinct values in the candidate, 20 in the baseline`,
+				`resource attribute "host.name" on spans was removed (3 distinct values in the baseline)`,
+				"the candidate exported 4 log records, the baseline 0 (none in the baseline)",
+			},
+		},
+		{
+			name: "resources",
+			report: ComparisonReport{
+				Baseline:  CollectorRun{PeakMemoryMB: 100, AvgCPUPercent: 10},
+				Candidate: CollectorRun{PeakMemoryMB: 150, AvgCPUPercent: 30},
+			},
+			want: []string{
+				"peak memory: 150.0 MB in the candidate, 100.0 MB in the baseline (+50.0%)",
+				"average CPU: 30.0% in the candidate, 10.0% in the baseline",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.report.differences()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("differences() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestSignificant(t *testing.T) {
+	tests := []struct {
+		baseline, candidate float64
+		want                bool
+	}{
+		{100, 100, false},
+		{100, 109, false}, // Under 10%
+		{100, 111, true},
+		{100, 80, true},
+		{5, 5.9, false}, // Under one unit
+		{5, 7, true},
+		{0, 0.5, false},
+		{0, 2, true},
+	}
+
+	for _, tt := range tests {
+		if got := significant(tt.baseline, tt.candidate); got != tt.want {
+			t.Errorf("significant(%v, %v) = %v, want %v", tt.baseline, tt.candidate, got, tt.want)
+		}
+	}
+}
+
+func TestPercentChange(t *testing.T) {
+	tests := []struct {
+		baseline, candidate float64
+		want                string
+	}{
+		{100, 150, "+50.0%"},
+		{200, 50, "-75.0%"},
+		{3, 3, "+0.0%"},
+		{0, 10, "none in the baseline"},
+	}
+
+	for _, tt := range tests {
+		if got := percentChange(tt.baseline, tt.candidate); got != tt.want {
+			t.Errorf("percentChange(%v, %v) = %q, want %q", tt.baseline, tt.candidate, got, tt.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -25,6 +26,18 @@ type DeployCollectorConfig struct {
 	Config           string
 	CollectorVersion string
 	NetworkName      string
+	// HostGateway makes the host reachable as host.docker.internal, e.g.
+	// for exporting to the capture sink
+	HostGateway bool
+
+	// Name names the container and config file (default: SandboxID), for
+	// sandboxes running more than one collector
+	Name string
+	// NetworkAlias is the collector's host name in the network (default:
+	// "collector")
+	NetworkAlias string
+	// Component is the sandbox.component label (default: "collector")
+	Component string
 }
 
 // CollectorInfo holds information about a deployed collector
@@ -34,14 +47,6 @@ type CollectorInfo struct {
 	Status        string
 }
 
-// StartTelemetryConfig holds telemetry generation configuration
-type StartTelemetryConfig struct {
-	SandboxID       string
-	NetworkName     string
-	TelemetryConfig TelemetryConfig
-	Duration        time.Duration
-}
-
 // NewDockerOrchestrator creates a new Docker orchestrator
 func NewDockerOrchestrator(logger Logger) (*DockerOrchestrator, error) {
 	dockerPath, err := exec.LookPath("docker")
@@ -85,20 +90,24 @@ func (d *DockerOrchestrator) CreateNetwork(ctx context.Context, sandboxID string
 
 // DeployCollector deploys an OpenTelemetry collector container
 func (d *DockerOrchestrator) DeployCollector(ctx context.Context, config DeployCollectorConfig) (*CollectorInfo, error) {
-	containerName := fmt.Sprintf("sandbox-collector-%s", config.SandboxID)
+	if config.Name == "" {
+		config.Name = config.SandboxID
+	}
+	if config.NetworkAlias == "" {
+		config.NetworkAlias = "collector"
+	}
+	if config.Component == "" {
+		config.Component = "collector"
+	}
+	containerName := fmt.Sprintf("sandbox-collector-%s", config.Name)
 
 	// Create config file in shared directory
-	// Use environment variable for config directory, default to /sandbox-configs
-	configDir := os.Getenv("SANDBOX_CONFIGS_DIR")
-	if configDir == "" {
-		configDir = "/sandbox-configs"
-	}
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	dir := configDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	configPath := filepath.Join(configDir, fmt.Sprintf("%s.yaml", config.SandboxID))
-	if err := os.WriteFile(configPath, []byte(config.Config), 0644); err != nil {
+	if err := os.WriteFile(configPath(config.Name), []byte(config.Config), 0644); err != nil {
 		return nil, fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -107,9 +116,9 @@ func (d *DockerOrchestrator) DeployCollector(ctx context.Context, config DeployC
 	configHostPath := os.Getenv("SANDBOX_CONFIGS_HOST_PATH")
 	if configHostPath == "" {
 		// If not set, assume we're not in Docker and use the same path
-		configHostPath = configDir
+		configHostPath = dir
 	}
-	configHostFilePath := filepath.Join(configHostPath, fmt.Sprintf("%s.yaml", config.SandboxID))
+	configHostFilePath := filepath.Join(configHostPath, fmt.Sprintf("%s.yaml", config.Name))
 
 	// Determine collector image
 	image := "otel/opentelemetry-collector-contrib"
@@ -125,15 +134,20 @@ func (d *DockerOrchestrator) DeployCollector(ctx context.Context, config DeployC
 		"-d",
 		"--name", containerName,
 		"--network", config.NetworkName,
-		"--network-alias", "collector", // Allow other containers to reach it via "collector"
+		"--network-alias", config.NetworkAlias, // Allow other containers to reach it via "collector"
 		"-v", fmt.Sprintf("%s:/etc/otelcol-contrib/config.yaml:ro", configHostFilePath),
 		"--label", fmt.Sprintf("sandbox.id=%s", config.SandboxID),
-		"--label", "sandbox.component=collector",
+		"--label", fmt.Sprintf("sandbox.component=%s", config.Component),
 		// Expose Prometheus metrics endpoint
 		"-p", "8888", // Prometheus metrics
-		image,
-		"--config=/etc/otelcol-contrib/config.yaml",
+		// Expose OTLP receivers to the telemetry generator
+		"-p", "4317", // OTLP gRPC
+		"-p", "4318", // OTLP HTTP
 	}
+	if config.HostGateway {
+		args = append(args, "--add-host", "host.docker.internal:host-gateway")
+	}
+	args = append(args, image, "--config=/etc/otelcol-contrib/config.yaml")
 
 	cmd := exec.CommandContext(ctx, d.dockerPath, args...)
 	output, err := cmd.CombinedOutput()
@@ -171,177 +185,6 @@ func (d *DockerOrchestrator) DeployCollector(ctx context.Context, config DeployC
 	}, nil
 }
 
-// StartTelemetryGeneration starts telemetrygen containers
-func (d *DockerOrchestrator) StartTelemetryGeneration(ctx context.Context, config StartTelemetryConfig) (TelemetryGeneratorContainerInfo, error) {
-	var info TelemetryGeneratorContainerInfo
-
-	// Determine OTLP endpoint format
-	otlpEndpoint := config.TelemetryConfig.OTLPEndpoint
-	if !strings.Contains(otlpEndpoint, ":") {
-		if config.TelemetryConfig.OTLPProtocol == "http" {
-			otlpEndpoint = otlpEndpoint + ":4318"
-		} else {
-			otlpEndpoint = otlpEndpoint + ":4317"
-		}
-	}
-
-	// Start traces generator
-	if config.TelemetryConfig.GenerateTraces {
-		containerID, err := d.startTracesGenerator(ctx, config.SandboxID, config.NetworkName, otlpEndpoint, config.TelemetryConfig, config.Duration)
-		if err != nil {
-			return info, fmt.Errorf("failed to start traces generator: %w", err)
-		}
-		info.TracesContainerID = containerID
-	}
-
-	// Start metrics generator
-	if config.TelemetryConfig.GenerateMetrics {
-		containerID, err := d.startMetricsGenerator(ctx, config.SandboxID, config.NetworkName, otlpEndpoint, config.TelemetryConfig, config.Duration)
-		if err != nil {
-			return info, fmt.Errorf("failed to start metrics generator: %w", err)
-		}
-		info.MetricsContainerID = containerID
-	}
-
-	// Start logs generator
-	if config.TelemetryConfig.GenerateLogs {
-		containerID, err := d.startLogsGenerator(ctx, config.SandboxID, config.NetworkName, otlpEndpoint, config.TelemetryConfig, config.Duration)
-		if err != nil {
-			return info, fmt.Errorf("failed to start logs generator: %w", err)
-		}
-		info.LogsContainerID = containerID
-	}
-
-	d.logger.Info("Started telemetry generation", map[string]interface{}{
-		"sandbox_id": config.SandboxID,
-		"duration":   config.Duration,
-	})
-
-	return info, nil
-}
-
-// startTracesGenerator starts a telemetrygen traces container
-func (d *DockerOrchestrator) startTracesGenerator(ctx context.Context, sandboxID, networkName, otlpEndpoint string, config TelemetryConfig, duration time.Duration) (string, error) {
-	containerName := fmt.Sprintf("sandbox-traces-%s", sandboxID)
-
-	rate := config.TraceRate
-	if rate == 0 {
-		rate = 1 // Default 1 trace per second
-	}
-
-	// Build args for telemetrygen
-	args := []string{
-		"run",
-		"--rm", // Auto-remove after completion
-		"--name", containerName,
-		"--network", networkName,
-		"--label", fmt.Sprintf("sandbox.id=%s", sandboxID),
-		"--label", "sandbox.component=telemetrygen",
-		"ghcr.io/open-telemetry/opentelemetry-collector-contrib/telemetrygen:latest",
-		"traces",
-		"--otlp-endpoint", otlpEndpoint,
-		"--otlp-insecure",
-		"--rate", strconv.Itoa(rate),
-		"--duration", duration.String(),
-	}
-
-	// Add custom attributes if provided
-	for key, value := range config.TraceAttributes {
-		args = append(args, "--traces-attribute", fmt.Sprintf("%s=%s", key, value))
-	}
-
-	cmd := exec.CommandContext(ctx, d.dockerPath, args...)
-
-	// Start container in background
-	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("failed to start traces generator: %w", err)
-	}
-
-	// Get container ID
-	containerID, err := d.getContainerIDByName(ctx, containerName)
-	if err != nil {
-		return "", fmt.Errorf("failed to get traces generator container ID: %w", err)
-	}
-
-	return containerID, nil
-}
-
-// startMetricsGenerator starts a telemetrygen metrics container
-func (d *DockerOrchestrator) startMetricsGenerator(ctx context.Context, sandboxID, networkName, otlpEndpoint string, config TelemetryConfig, duration time.Duration) (string, error) {
-	containerName := fmt.Sprintf("sandbox-metrics-%s", sandboxID)
-
-	rate := config.MetricRate
-	if rate == 0 {
-		rate = 1 // Default 1 metric per second
-	}
-
-	args := []string{
-		"run",
-		"--rm",
-		"--name", containerName,
-		"--network", networkName,
-		"--label", fmt.Sprintf("sandbox.id=%s", sandboxID),
-		"--label", "sandbox.component=telemetrygen",
-		"ghcr.io/open-telemetry/opentelemetry-collector-contrib/telemetrygen:latest",
-		"metrics",
-		"--otlp-endpoint", otlpEndpoint,
-		"--otlp-insecure",
-		"--rate", strconv.Itoa(rate),
-		"--duration", duration.String(),
-	}
-
-	cmd := exec.CommandContext(ctx, d.dockerPath, args...)
-
-	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("failed to start metrics generator: %w", err)
-	}
-
-	containerID, err := d.getContainerIDByName(ctx, containerName)
-	if err != nil {
-		return "", fmt.Errorf("failed to get metrics generator container ID: %w", err)
-	}
-
-	return containerID, nil
-}
-
-// startLogsGenerator starts a telemetrygen logs container
-func (d *DockerOrchestrator) startLogsGenerator(ctx context.Context, sandboxID, networkName, otlpEndpoint string, config TelemetryConfig, duration time.Duration) (string, error) {
-	containerName := fmt.Sprintf("sandbox-logs-%s", sandboxID)
-
-	rate := config.LogRate
-	if rate == 0 {
-		rate = 1 // Default 1 log per second
-	}
-
-	args := []string{
-		"run",
-		"--rm",
-		"--name", containerName,
-		"--network", networkName,
-		"--label", fmt.Sprintf("sandbox.id=%s", sandboxID),
-		"--label", "sandbox.component=telemetrygen",
-		"ghcr.io/open-telemetry/opentelemetry-collector-contrib/telemetrygen:latest",
-		"logs",
-		"--otlp-endpoint", otlpEndpoint,
-		"--otlp-insecure",
-		"--rate", strconv.Itoa(rate),
-		"--duration", duration.String(),
-	}
-
-	cmd := exec.CommandContext(ctx, d.dockerPath, args...)
-
-	if err := cmd.Start(); err != nil {
-		return "", fmt.Errorf("failed to start logs generator: %w", err)
-	}
-
-	containerID, err := d.getContainerIDByName(ctx, containerName)
-	if err != nil {
-		return "", fmt.Errorf("failed to get logs generator container ID: %w", err)
-	}
-
-	return containerID, nil
-}
-
 // GetContainerLogs retrieves logs from a container
 func (d *DockerOrchestrator) GetContainerLogs(ctx context.Context, containerID string, tailLines int) ([]LogEntry, error) {
 	args := []string{
@@ -448,10 +291,17 @@ func (d *DockerOrchestrator) GetCollectorMetrics(ctx context.Context, containerI
 	return metrics, nil
 }
 
-// parsePrometheusMetrics parses Prometheus format metrics
+// parsePrometheusMetrics parses Prometheus format metrics. Series of the
+// same metric, e.g. one per receiver, are summed.
 func (d *DockerOrchestrator) parsePrometheusMetrics(metricsOutput string) *CollectorMetrics {
 	metrics := &CollectorMetrics{}
 
+	// Newer collectors report processor item counts per signal rather than
+	// accepted/refused/dropped; items a processor lets in but not out were
+	// dropped
+	incoming := make(map[string]float64)
+	outgoing := make(map[string]float64)
+
 	// This is a simplified parser. A real implementation would use a Prometheus client library
 	lines := strings.Split(metricsOutput, "\n")
 	for _, line := range lines {
@@ -473,23 +323,128 @@ func (d *DockerOrchestrator) parsePrometheusMetrics(metricsOutput string) *Colle
 		// Map metrics to our structure
 		switch {
 		case strings.Contains(metricName, "receiver_accepted_spans"):
-			metrics.ReceiverAcceptedSpans = int64(value)
+			metrics.ReceiverAcceptedSpans += int64(value)
 		case strings.Contains(metricName, "receiver_refused_spans"):
-			metrics.ReceiverRefusedSpans = int64(value)
+			metrics.ReceiverRefusedSpans += int64(value)
+		case strings.Contains(metricName, "receiver_accepted_metric_points"):
+			metrics.ReceiverAcceptedMetrics += int64(value)
+		case strings.Contains(metricName, "receiver_refused_metric_points"):
+			metrics.ReceiverRefusedMetrics += int64(value)
+		case strings.Contains(metricName, "receiver_accepted_log_records"):
+			metrics.ReceiverAcceptedLogs += int64(value)
+		case strings.Contains(metricName, "receiver_refused_log_records"):
+			metrics.ReceiverRefusedLogs += int64(value)
+		case strings.Contains(metricName, "processor_accepted_spans"):
+			metrics.ProcessorAcceptedSpans += int64(value)
+		case strings.Contains(metricName, "processor_refused_spans"):
+			metrics.ProcessorRefusedSpans += int64(value)
+		case strings.Contains(metricName, "processor_dropped_spans"):
+			metrics.ProcessorDroppedSpans += int64(value)
+		case strings.Contains(metricName, "processor_accepted_metric_points"):
+			metrics.ProcessorAcceptedMetrics += int64(value)
+		case strings.Contains(metricName, "processor_refused_metric_points"):
+			metrics.ProcessorRefusedMetrics += int64(value)
+		case strings.Contains(metricName, "processor_dropped_metric_points"):
+			metrics.ProcessorDroppedMetrics += int64(value)
+		case strings.Contains(metricName, "processor_incoming_items"):
+			incoming[prometheusLabel(metricName, "otel_signal")] += value
+		case strings.Contains(metricName, "processor_outgoing_items"):
+			outgoing[prometheusLabel(metricName, "otel_signal")] += value
 		case strings.Contains(metricName, "exporter_sent_spans"):
-			metrics.ExporterSentSpans = int64(value)
+			metrics.ExporterSentSpans += int64(value)
 		case strings.Contains(metricName, "exporter_send_failed_spans"):
-			metrics.ExporterFailedSpans = int64(value)
+			metrics.ExporterFailedSpans += int64(value)
+		case strings.Contains(metricName, "exporter_sent_metric_points"):
+			metrics.ExporterSentMetrics += int64(value)
+		case strings.Contains(metricName, "exporter_send_failed_metric_points"):
+			metrics.ExporterFailedMetrics += int64(value)
+		case strings.Contains(metricName, "exporter_sent_log_records"):
+			metrics.ExporterSentLogs += int64(value)
+		case strings.Contains(metricName, "exporter_send_failed_log_records"):
+			metrics.ExporterFailedLogs += int64(value)
 		case strings.Contains(metricName, "queue_size"):
-			metrics.QueueSize = int64(value)
-		case strings.Contains(metricName, "process_resident_memory_bytes"):
+			metrics.QueueSize += int64(value)
+		case strings.Contains(metricName, "queue_capacity"):
+			metrics.QueueCapacity += int64(value)
+		case strings.Contains(metricName, "process_resident_memory_bytes"),
+			strings.Contains(metricName, "process_memory_rss"):
 			metrics.MemoryUsageMB = value / 1024 / 1024
 		}
 	}
 
+	if len(incoming) > 0 {
+		metrics.ProcessorDroppedSpans = int64(incoming["traces"] - outgoing["traces"])
+		metrics.ProcessorDroppedMetrics = int64(incoming["metrics"] - outgoing["metrics"])
+	}
+
 	return metrics
 }
 
+// prometheusLabel returns the value of a label in a series such as
+// name{label="value"}
+func prometheusLabel(series, label string) string {
+	start := strings.Index(series, label+`="`)
+	if start < 0 {
+		return ""
+	}
+	value := series[start+len(label)+2:]
+	if end := strings.IndexByte(value, '"'); end >= 0 {
+		value = value[:end]
+	}
+	return value
+}
+
+// ContainerStats returns a container's current CPU use, as a percentage of
+// one core, and memory use
+func (d *DockerOrchestrator) ContainerStats(ctx context.Context, containerID string) (cpuPercent, memoryMB float64, err error) {
+	cmd := exec.CommandContext(ctx, d.dockerPath, "stats", "--no-stream", "--format", "{{.CPUPerc}}\t{{.MemUsage}}", containerID)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get container stats: %s - %w", strings.TrimSpace(string(output)), err)
+	}
+
+	// e.g. "12.34%\t45.6MiB / 7.7GiB"
+	fields := strings.Split(strings.TrimSpace(string(output)), "\t")
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("unexpected container stats: %q", strings.TrimSpace(string(output)))
+	}
+	cpuPercent, err = strconv.ParseFloat(strings.TrimSuffix(fields[0], "%"), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected CPU usage %q", fields[0])
+	}
+	usage, _, _ := strings.Cut(fields[1], "/")
+	memoryMB, err = parseMemorySize(strings.TrimSpace(usage))
+	if err != nil {
+		return 0, 0, err
+	}
+	return cpuPercent, memoryMB, nil
+}
+
+// memoryUnits are the sizes of the units docker stats reports memory in, in MiB
+var memoryUnits = []struct {
+	suffix string
+	mb     float64
+}{
+	// "B" last, as every unit ends in it
+	{"KiB", 1.0 / 1024}, {"MiB", 1}, {"GiB", 1024}, {"TiB", 1024 * 1024},
+	{"kB", 1000.0 / 1024 / 1024}, {"MB", 1000 * 1000.0 / 1024 / 1024}, {"GB", 1000 * 1000 * 1000.0 / 1024 / 1024},
+	{"B", 1.0 / 1024 / 1024},
+}
+
+// parseMemorySize parses a size such as "45.6MiB" into MiB
+func parseMemorySize(size string) (float64, error) {
+	for _, unit := range memoryUnits {
+		if number, ok := strings.CutSuffix(size, unit.suffix); ok {
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				break
+			}
+			return value * unit.mb, nil
+		}
+	}
+	return 0, fmt.Errorf("unexpected memory usage %q", size)
+}
+
 // StopCollector stops a collector container
 func (d *DockerOrchestrator) StopCollector(ctx context.Context, containerID string) error {
 	cmd := exec.CommandContext(ctx, d.dockerPath, "stop", containerID)
@@ -510,21 +465,6 @@ func (d *DockerOrchestrator) StopCollector(ctx context.Context, containerID stri
 	return nil
 }
 
-// StopTelemetryGenerators stops telemetry generator containers
-func (d *DockerOrchestrator) StopTelemetryGenerators(ctx context.Context, info TelemetryGeneratorContainerInfo) error {
-	if info.TracesContainerID != "" {
-		_ = d.stopContainer(ctx, info.TracesContainerID)
-	}
-	if info.MetricsContainerID != "" {
-		_ = d.stopContainer(ctx, info.MetricsContainerID)
-	}
-	if info.LogsContainerID != "" {
-		_ = d.stopContainer(ctx, info.LogsContainerID)
-	}
-
-	return nil
-}
-
 // CleanupNetwork removes a sandbox network
 func (d *DockerOrchestrator) CleanupNetwork(ctx context.Context, sandboxID string) error {
 	networkName := fmt.Sprintf("sandbox-%s", sandboxID)
@@ -542,8 +482,123 @@ func (d *DockerOrchestrator) CleanupNetwork(ctx context.Context, sandboxID strin
 	return nil
 }
 
+// LabeledContainer is a container created for a sandbox, found by its labels
+type LabeledContainer struct {
+	ID        string
+	Name      string
+	Image     string
+	State     string // Docker state, e.g. "running" or "exited"
+	SandboxID string
+	Component string // "collector", or "telemetrygen" from older versions
+}
+
+// LabeledNetwork is a network created for a sandbox, found by its label
+type LabeledNetwork struct {
+	ID        string
+	Name      string
+	SandboxID string
+}
+
+// ListSandboxContainers lists all containers, running or not, that carry a
+// sandbox.id label
+func (d *DockerOrchestrator) ListSandboxContainers(ctx context.Context) ([]LabeledContainer, error) {
+	cmd := exec.CommandContext(ctx, d.dockerPath, "ps", "-a",
+		"--filter", "label=sandbox.id",
+		"--format", `{{.ID}}\t{{.Names}}\t{{.Image}}\t{{.State}}\t{{.Label "sandbox.id"}}\t{{.Label "sandbox.component"}}`)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sandbox containers: %w", err)
+	}
+
+	var containers []LabeledContainer
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 6 || fields[4] == "" {
+			continue
+		}
+		containers = append(containers, LabeledContainer{
+			ID:        fields[0],
+			Name:      fields[1],
+			Image:     fields[2],
+			State:     fields[3],
+			SandboxID: fields[4],
+			Component: fields[5],
+		})
+	}
+	return containers, nil
+}
+
+// ListSandboxNetworks lists all networks that carry a sandbox.id label
+func (d *DockerOrchestrator) ListSandboxNetworks(ctx context.Context) ([]LabeledNetwork, error) {
+	cmd := exec.CommandContext(ctx, d.dockerPath, "network", "ls",
+		"--filter", "label=sandbox.id",
+		"--format", `{{.ID}}\t{{.Name}}\t{{.Label "sandbox.id"}}`)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sandbox networks: %w", err)
+	}
+
+	var networks []LabeledNetwork
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || fields[2] == "" {
+			continue
+		}
+		networks = append(networks, LabeledNetwork{ID: fields[0], Name: fields[1], SandboxID: fields[2]})
+	}
+	return networks, nil
+}
+
+// RemoveNetwork removes a network by name or ID
+func (d *DockerOrchestrator) RemoveNetwork(ctx context.Context, network string) error {
+	cmd := exec.CommandContext(ctx, d.dockerPath, "network", "rm", network)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove network: %s - %w", strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// RemoveContainer stops and removes a container
+func (d *DockerOrchestrator) RemoveContainer(ctx context.Context, containerID string) error {
+	if err := d.stopContainer(ctx, containerID); err != nil {
+		return fmt.Errorf("failed to remove container: %w", err)
+	}
+	return nil
+}
+
+// ReadCollectorConfig reads the collector config file written for a sandbox
+func (d *DockerOrchestrator) ReadCollectorConfig(sandboxID string) (string, error) {
+	data, err := os.ReadFile(configPath(sandboxID))
+	if err != nil {
+		return "", fmt.Errorf("failed to read collector config: %w", err)
+	}
+	return string(data), nil
+}
+
+// RemoveCollectorConfig removes the collector config file written for a sandbox
+func (d *DockerOrchestrator) RemoveCollectorConfig(sandboxID string) error {
+	if err := os.Remove(configPath(sandboxID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove collector config: %w", err)
+	}
+	return nil
+}
+
 // Helper methods
 
+// configDir is the directory collector configs are written to, set by
+// SANDBOX_CONFIGS_DIR (default /sandbox-configs)
+func configDir() string {
+	if dir := os.Getenv("SANDBOX_CONFIGS_DIR"); dir != "" {
+		return dir
+	}
+	return "/sandbox-configs"
+}
+
+// configPath is the path of a sandbox's collector config file
+func configPath(sandboxID string) string {
+	return filepath.Join(configDir(), fmt.Sprintf("%s.yaml", sandboxID))
+}
+
 func (d *DockerOrchestrator) getContainerStatus(ctx context.Context, containerID string) (string, error) {
 	cmd := exec.CommandContext(ctx, d.dockerPath, "inspect", "-f", "{{.State.Status}}", containerID)
 	output, err := cmd.CombinedOutput()
@@ -562,16 +617,28 @@ func (d *DockerOrchestrator) getContainerIP(ctx context.Context, containerID str
 	return strings.TrimSpace(string(output)), nil
 }
 
-func (d *DockerOrchestrator) getContainerIDByName(ctx context.Context, containerName string) (string, error) {
-	// Give it a moment to register
-	time.Sleep(500 * time.Millisecond)
-
-	cmd := exec.CommandContext(ctx, d.dockerPath, "ps", "-aqf", fmt.Sprintf("name=%s", containerName))
+// PublishedAddress returns the host:port a container's port is published on.
+// The host is SANDBOX_PUBLISHED_HOST, e.g. host.docker.internal when running
+// in Docker, or localhost if not set.
+func (d *DockerOrchestrator) PublishedAddress(ctx context.Context, containerID, port string) (string, error) {
+	cmd := exec.CommandContext(ctx, d.dockerPath, "port", containerID, port+"/tcp")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%s - %w", strings.TrimSpace(string(output)), err)
 	}
-	return strings.TrimSpace(string(output)), nil
+
+	// One line per address, e.g. "0.0.0.0:49153" and "[::]:49153"
+	lines := strings.Fields(string(output))
+	if len(lines) == 0 {
+		return "", fmt.Errorf("port %s is not published", port)
+	}
+	hostPort := lines[0][strings.LastIndex(lines[0], ":")+1:]
+
+	host := os.Getenv("SANDBOX_PUBLISHED_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, hostPort), nil
 }
 
 func (d *DockerOrchestrator) stopContainer(ctx context.Context, containerID string) error {
@@ -0,0 +1,228 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/otlpgen"
+)
+
+// metadataTelemetryGenerator holds the stats of the last generator run
+const metadataTelemetryGenerator = "telemetry_generator"
+
+// telemetrySource sends telemetry to a sandbox: an otlpgen.Generator or
+// otlpgen.Replay
+type telemetrySource interface {
+	Run(ctx context.Context) error
+	Stats() otlpgen.Stats
+}
+
+// generatorRun is telemetry being sent to a sandbox
+type generatorRun struct {
+	source telemetrySource
+	cancel context.CancelFunc
+}
+
+// newTelemetryGenerator creates a generator sending telemetry described by
+// config to the given collector containers for duration. Every collector
+// gets the same requests.
+func (m *Manager) newTelemetryGenerator(ctx context.Context, config TelemetryConfig, duration time.Duration, collectorIDs ...string) (*otlpgen.Generator, error) {
+	endpoints, err := m.otlpEndpoints(ctx, config, collectorIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	generatorConfig := otlpgen.Config{
+		Endpoint: endpoints[0],
+		Mirrors:  endpoints[1:],
+		Protocol: config.OTLPProtocol,
+		Scenario: telemetryScenario(config),
+		Duration: duration,
+	}
+	if config.GenerateTraces {
+		generatorConfig.TraceRate = rateOrDefault(config.TraceRate)
+	}
+	if config.GenerateMetrics {
+		generatorConfig.MetricRate = rateOrDefault(config.MetricRate)
+	}
+	if config.GenerateLogs {
+		generatorConfig.LogRate = rateOrDefault(config.LogRate)
+	}
+
+	return otlpgen.New(generatorConfig)
+}
+
+// otlpEndpoints resolves the OTLP endpoint in config for each collector
+// container. An endpoint that doesn't name the collector is used as is, once.
+func (m *Manager) otlpEndpoints(ctx context.Context, config TelemetryConfig, collectorIDs []string) ([]string, error) {
+	var endpoints []string
+	for _, collectorID := range collectorIDs {
+		endpoint, err := m.otlpEndpoint(ctx, collectorID, config)
+		if err != nil {
+			return nil, err
+		}
+		if len(endpoints) > 0 && endpoint == endpoints[0] {
+			break
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+// otlpEndpoint resolves the OTLP endpoint in config. The collector is only
+// reachable as "collector" inside the sandbox network, so that host is
+// replaced with the collector's published port.
+func (m *Manager) otlpEndpoint(ctx context.Context, collectorID string, config TelemetryConfig) (string, error) {
+	endpoint := config.OTLPEndpoint
+	if endpoint == "" {
+		endpoint = "collector"
+	}
+	scheme := ""
+	if i := strings.Index(endpoint, "://"); i >= 0 {
+		scheme, endpoint = endpoint[:i+3], endpoint[i+3:]
+	}
+
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		// No port: use the protocol's default
+		host, port = endpoint, "4317"
+		if config.OTLPProtocol == otlpgen.ProtocolHTTP {
+			port = "4318"
+		}
+	}
+
+	if host == "collector" {
+		address, err := m.dockerOrchestrator.PublishedAddress(ctx, collectorID, port)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve collector OTLP port %s: %w", port, err)
+		}
+		return scheme + address, nil
+	}
+	return scheme + net.JoinHostPort(host, port), nil
+}
+
+// telemetryScenario returns the scenario to generate, narrowed by the
+// config's metric types, log severities and trace attributes
+func telemetryScenario(config TelemetryConfig) *otlpgen.Scenario {
+	var scenario otlpgen.Scenario
+	if config.Scenario != nil {
+		scenario = *config.Scenario
+	} else {
+		scenario = *otlpgen.DefaultScenario()
+	}
+
+	if len(config.TraceAttributes) > 0 {
+		attributes := make(map[string]string, len(scenario.Attributes)+len(config.TraceAttributes))
+		for key, value := range scenario.Attributes {
+			attributes[key] = value
+		}
+		for key, value := range config.TraceAttributes {
+			attributes[key] = value
+		}
+		scenario.Attributes = attributes
+	}
+
+	if len(config.MetricTypes) > 0 {
+		wanted := make(map[string]bool, len(config.MetricTypes))
+		for _, metricType := range config.MetricTypes {
+			metricType = strings.ToLower(metricType)
+			if metricType == "sum" {
+				metricType = otlpgen.MetricCounter
+			}
+			wanted[metricType] = true
+		}
+		var metrics []otlpgen.MetricSpec
+		for _, metric := range scenario.Metrics {
+			if wanted[metric.Type] {
+				metrics = append(metrics, metric)
+			}
+		}
+		scenario.Metrics = metrics
+	}
+
+	if len(config.LogSeverity) > 0 {
+		scenario.Logs.Severities = config.LogSeverity
+	}
+
+	return &scenario
+}
+
+func rateOrDefault(rate int) int {
+	if rate <= 0 {
+		return 1 // Default 1 per second
+	}
+	return rate
+}
+
+// runGenerator runs a telemetry source in the background, replacing any
+// already running for the sandbox. Its stats are recorded in the sandbox's
+// metadata when it starts and finishes. The returned channel is closed when
+// it finishes.
+func (m *Manager) runGenerator(sandbox *Sandbox, source telemetrySource) <-chan struct{} {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &generatorRun{source: source, cancel: cancel}
+	done := make(chan struct{})
+
+	m.mu.Lock()
+	if previous, ok := m.generators[sandbox.ID]; ok {
+		previous.cancel()
+	}
+	m.generators[sandbox.ID] = run
+
+	stats := source.Stats()
+	stats.Running = true
+	stats.StartedAt = time.Now()
+	sandbox.Metadata[metadataTelemetryGenerator] = stats
+	sandbox.UpdatedAt = time.Now()
+	m.saveLocked(sandbox)
+	m.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		err := source.Run(ctx)
+
+		stats := source.Stats()
+		fields := map[string]interface{}{
+			"sandbox_id":     sandbox.ID,
+			"traces":         stats.Traces,
+			"spans":          stats.Spans,
+			"metric_points":  stats.MetricPoints,
+			"logs":           stats.Logs,
+			"failed_exports": stats.FailedExports,
+		}
+		if err != nil && err != context.Canceled {
+			m.logger.Error("Telemetry generation failed", err, fields)
+		} else {
+			m.logger.Info("Telemetry generation finished", fields)
+		}
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.generators[sandbox.ID] == run {
+			delete(m.generators, sandbox.ID)
+		}
+		// Don't resurrect a deleted sandbox's record
+		if m.sandboxes[sandbox.ID] != sandbox {
+			return
+		}
+		sandbox.Metadata[metadataTelemetryGenerator] = stats
+		sandbox.UpdatedAt = time.Now()
+		m.saveLocked(sandbox)
+	}()
+	return done
+}
+
+// stopGenerator stops the generator running for a sandbox, if any
+func (m *Manager) stopGenerator(sandboxID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if run, ok := m.generators[sandboxID]; ok {
+		run.cancel()
+		delete(m.generators, sandboxID)
+	}
+}
@@ -2,6 +2,9 @@ package sandbox
 
 import (
 	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/otlpgen"
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
 )
 
 // SandboxStatus represents the current state of a sandbox
@@ -47,7 +50,7 @@ type Sandbox struct {
 
 // TelemetryConfig defines what kind of telemetry to generate
 type TelemetryConfig struct {
-	// Telemetrygen configuration
+	// Signals to generate
 	GenerateTraces  bool              `json:"generate_traces"`
 	GenerateMetrics bool              `json:"generate_metrics"`
 	GenerateLogs    bool              `json:"generate_logs"`
@@ -68,6 +71,10 @@ type TelemetryConfig struct {
 	// OTLP endpoint (within sandbox network)
 	OTLPEndpoint    string            `json:"otlp_endpoint"`     // e.g., "collector:4317"
 	OTLPProtocol    string            `json:"otlp_protocol"`     // "grpc" or "http"
+
+	// Scenario describes the services, metrics and logs to generate
+	// (default: otlpgen.DefaultScenario). The fields above narrow it.
+	Scenario        *otlpgen.Scenario `json:"scenario,omitempty"`
 }
 
 // ValidationResult contains the results of validating a sandbox
@@ -90,6 +97,9 @@ type ValidationResult struct {
 	// Issues found
 	Issues      []ValidationIssue   `json:"issues,omitempty"`
 
+	// Outcome of assertions on the telemetry captured by the OTLP sink
+	Assertions  []otlpsink.AssertionResult `json:"assertions,omitempty"`
+
 	// AI analysis
 	AIAnalysis  string              `json:"ai_analysis,omitempty"`
 	Recommendations []string         `json:"recommendations,omitempty"`
@@ -210,9 +220,27 @@ type CreateSandboxRequest struct {
 
 // StartSandboxRequest represents a request to start telemetry generation
 type StartSandboxRequest struct {
-	Duration         time.Duration   `json:"duration,omitempty"`      // How long to run (0 = run indefinitely)
+	Duration         time.Duration   `json:"duration,omitempty"`      // How long to run (0 = 30s, or all of a replay)
 	TelemetryConfig  *TelemetryConfig `json:"telemetry_config,omitempty"` // Override config
 	AutoValidate     bool            `json:"auto_validate"`           // Run validation after completion
+
+	// Replay sends recorded telemetry instead of generating it
+	Replay           *ReplaySource   `json:"replay,omitempty"`
+}
+
+// ReplaySource is recorded telemetry to replay into a sandbox
+type ReplaySource struct {
+	Fixtures         []ReplayFixture `json:"fixtures"`
+	Speed            float64         `json:"speed,omitempty"`             // 2 replays twice as fast as recorded (default: 1)
+	RebaseTimestamps bool            `json:"rebase_timestamps,omitempty"` // Move timestamps to the time of replay
+}
+
+// ReplayFixture is a file of OTLP protobuf or JSON requests, e.g. written by
+// a collector's file exporter
+type ReplayFixture struct {
+	Path   string `json:"path,omitempty"`   // File readable by the server
+	Data   []byte `json:"data,omitempty"`   // Or the file's contents
+	Signal string `json:"signal,omitempty"` // "traces", "metrics" or "logs"; needed for protobuf unless the path names it
 }
 
 // ValidateSandboxRequest represents a request to validate a sandbox
@@ -221,11 +249,79 @@ type ValidateSandboxRequest struct {
 	CollectLogs     bool     `json:"collect_logs"`                // Include collector logs
 	CollectMetrics  bool     `json:"collect_metrics"`             // Include collector metrics
 	AIAnalysis      bool     `json:"ai_analysis"`                 // Run AI-powered analysis
+
+	// Assertions on the telemetry the collector exported to the capture sink
+	Assertions      []otlpsink.Assertion `json:"assertions,omitempty"`
+}
+
+// CompareConfigsRequest represents a request to compare a candidate
+// collector config against a baseline on identical telemetry
+type CompareConfigsRequest struct {
+	BaselineConfig   string           `json:"baseline_config"`  // e.g. the config deployed now
+	CandidateConfig  string           `json:"candidate_config"` // The proposed change
+	CollectorVersion string           `json:"collector_version,omitempty"`
+	Duration         time.Duration    `json:"duration,omitempty"`         // How long to send telemetry (0 = 30s, or all of a replay)
+	SettleTime       time.Duration    `json:"settle_time,omitempty"`      // Wait for the collectors to flush before measuring (0 = 5s)
+	TelemetryConfig  *TelemetryConfig `json:"telemetry_config,omitempty"` // What to generate (default: every signal of the default scenario)
+
+	// Replay sends recorded telemetry instead of generating it
+	Replay *ReplaySource `json:"replay,omitempty"`
+}
+
+// ComparisonReport is how a candidate collector config's output and resource
+// use differ from a baseline's on identical telemetry
+type ComparisonReport struct {
+	ID          string        `json:"id"`
+	StartedAt   time.Time     `json:"started_at"`
+	CompletedAt time.Time     `json:"completed_at"`
+	Sent        otlpgen.Stats `json:"sent"` // Telemetry sent to each collector
+	Baseline    CollectorRun  `json:"baseline"`
+	Candidate   CollectorRun  `json:"candidate"`
+
+	// What each collector exported, by signal. Needs telemetry capture.
+	Signals []SignalComparison `json:"signals,omitempty"`
+
+	// Notable differences, in plain words
+	Differences []string `json:"differences"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// CollectorRun is what one collector of a comparison did
+type CollectorRun struct {
+	Metrics           CollectorMetrics   `json:"metrics"`
+	Exported          map[string]int     `json:"exported,omitempty"`            // Items captured by signal
+	ExportedPerSecond map[string]float64 `json:"exported_per_second,omitempty"` // Over the time telemetry was sent
+	AvgCPUPercent     float64            `json:"avg_cpu_percent"`
+	PeakCPUPercent    float64            `json:"peak_cpu_percent"`
+	PeakMemoryMB      float64            `json:"peak_memory_mb"`
+	ErrorLogs         int                `json:"error_logs"` // Error lines in the collector's log
+}
+
+// SignalComparison compares what two collectors exported of one signal
+type SignalComparison struct {
+	Signal         string `json:"signal"`
+	BaselineItems  int    `json:"baseline_items"`
+	CandidateItems int    `json:"candidate_items"`
+
+	// Items the capture sink discarded once its buffer was full. Names and
+	// attributes are compared over the items it kept.
+	BaselineDropped  int `json:"baseline_dropped,omitempty"`
+	CandidateDropped int `json:"candidate_dropped,omitempty"`
+
+	// Span or metric names only one collector exported
+	OnlyInBaseline  []string `json:"only_in_baseline,omitempty"`
+	OnlyInCandidate []string `json:"only_in_candidate,omitempty"`
+
+	// Attributes whose presence or cardinality differ
+	Attributes          []AttributeComparison `json:"attributes,omitempty"`
+	UnchangedAttributes int                   `json:"unchanged_attributes"`
 }
 
-// TelemetryGeneratorContainerInfo holds info about telemetrygen containers
-type TelemetryGeneratorContainerInfo struct {
-	TracesContainerID  string `json:"traces_container_id,omitempty"`
-	MetricsContainerID string `json:"metrics_container_id,omitempty"`
-	LogsContainerID    string `json:"logs_container_id,omitempty"`
+// AttributeComparison compares the distinct values of an attribute. 0 means
+// the collector exported no items with it.
+type AttributeComparison struct {
+	Key       string `json:"key"`
+	Resource  bool   `json:"resource,omitempty"` // A resource attribute
+	Baseline  int    `json:"baseline"`
+	Candidate int    `json:"candidate"`
 }
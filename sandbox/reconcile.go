@@ -0,0 +1,268 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReconcileReport summarizes what Reconcile found
+type ReconcileReport struct {
+	Restored int      `json:"restored"` // Sandboxes loaded from storage
+	Failed   []string `json:"failed"`   // Restored sandboxes whose collector is gone
+	Adopted  []string `json:"adopted"`  // Orphaned collectors rebuilt into sandboxes
+	Removed  []string `json:"removed"`  // Orphaned sandboxes whose resources were removed
+}
+
+// Reconcile restores sandboxes from storage and brings them in line with
+// Docker, using the sandbox.id labels on containers and networks. It runs at
+// startup, before the manager is used:
+//   - stored sandboxes take their status from their collector container, and
+//     are marked failed if it no longer exists
+//   - a running collector with no stored sandbox is adopted as a new sandbox
+//     if its config file is still present
+//   - the containers, network and config file of any other unknown sandbox
+//     are removed
+func (m *Manager) Reconcile(ctx context.Context) (*ReconcileReport, error) {
+	if m.store == nil {
+		return nil, fmt.Errorf("sandbox storage is not configured")
+	}
+
+	records, err := m.store.ListSandboxes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sandboxes: %w", err)
+	}
+
+	report := &ReconcileReport{}
+	restored := make(map[string]*Sandbox, len(records))
+	for _, record := range records {
+		var sandbox Sandbox
+		if err := json.Unmarshal(record.Data, &sandbox); err != nil {
+			m.logger.Error("Failed to decode stored sandbox", err, map[string]interface{}{
+				"sandbox_id": record.ID,
+			})
+			continue
+		}
+		if sandbox.Metadata == nil {
+			sandbox.Metadata = make(map[string]interface{})
+		}
+		restored[sandbox.ID] = &sandbox
+	}
+	report.Restored = len(restored)
+
+	containers, err := m.dockerOrchestrator.ListSandboxContainers(ctx)
+	if err != nil {
+		// Keep the stored sandboxes as they are rather than failing them all
+		m.restore(restored)
+		return report, err
+	}
+	networks, err := m.dockerOrchestrator.ListSandboxNetworks(ctx)
+	if err != nil {
+		m.restore(restored)
+		return report, err
+	}
+
+	containersBySandbox := make(map[string][]LabeledContainer)
+	for _, container := range containers {
+		containersBySandbox[container.SandboxID] = append(containersBySandbox[container.SandboxID], container)
+	}
+	networksBySandbox := make(map[string]LabeledNetwork)
+	for _, network := range networks {
+		networksBySandbox[network.SandboxID] = network
+	}
+
+	for id, sandbox := range restored {
+		collector, found := findCollector(containersBySandbox[id])
+		switch {
+		case found:
+			if !strings.HasPrefix(sandbox.CollectorContainerID, collector.ID) {
+				sandbox.CollectorContainerID = collector.ID
+				sandbox.CollectorContainerName = collector.Name
+			}
+			if status := statusForContainerState(collector.State); status != "" && status != sandbox.Status {
+				sandbox.Status = status
+				sandbox.UpdatedAt = time.Now()
+			}
+		case sandbox.Status != SandboxStatusStopped && sandbox.Status != SandboxStatusFailed:
+			sandbox.Status = SandboxStatusFailed
+			sandbox.UpdatedAt = time.Now()
+			sandbox.Metadata["error"] = "collector container no longer exists"
+			report.Failed = append(report.Failed, id)
+		}
+	}
+
+	// Sandboxes Docker knows about but storage does not
+	orphanIDs := make(map[string]bool)
+	for id := range containersBySandbox {
+		if restored[id] == nil {
+			orphanIDs[id] = true
+		}
+	}
+	for id := range networksBySandbox {
+		if restored[id] == nil {
+			orphanIDs[id] = true
+		}
+	}
+	orphans := make([]string, 0, len(orphanIDs))
+	for id := range orphanIDs {
+		orphans = append(orphans, id)
+	}
+	sort.Strings(orphans)
+
+	for _, id := range orphans {
+		network, hasNetwork := networksBySandbox[id]
+		if sandbox := m.adoptOrphan(id, containersBySandbox[id], network, hasNetwork); sandbox != nil {
+			restored[id] = sandbox
+			report.Adopted = append(report.Adopted, id)
+			continue
+		}
+
+		m.removeOrphan(ctx, id, containersBySandbox[id], network, hasNetwork)
+		report.Removed = append(report.Removed, id)
+	}
+
+	m.restore(restored)
+
+	m.logger.Info("Reconciled sandboxes", map[string]interface{}{
+		"restored": report.Restored,
+		"failed":   len(report.Failed),
+		"adopted":  len(report.Adopted),
+		"removed":  len(report.Removed),
+	})
+
+	return report, nil
+}
+
+// restore adds reconciled sandboxes to the manager and saves them. Sandboxes
+// created since the manager started are kept as they are.
+func (m *Manager) restore(sandboxes map[string]*Sandbox) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, sandbox := range sandboxes {
+		if _, exists := m.sandboxes[id]; exists {
+			continue
+		}
+		m.sandboxes[id] = sandbox
+		m.saveLocked(sandbox)
+	}
+}
+
+// adoptOrphan rebuilds a sandbox from a running collector that has no stored
+// sandbox, returning nil if it cannot be adopted
+func (m *Manager) adoptOrphan(sandboxID string, containers []LabeledContainer, network LabeledNetwork, hasNetwork bool) *Sandbox {
+	collector, found := findCollector(containers)
+	if !found || collector.State != "running" {
+		return nil
+	}
+
+	config, err := m.dockerOrchestrator.ReadCollectorConfig(sandboxID)
+	if err != nil {
+		return nil
+	}
+
+	version := "latest"
+	if i := strings.LastIndex(collector.Image, ":"); i >= 0 {
+		version = collector.Image[i+1:]
+	}
+
+	now := time.Now()
+	sandbox := &Sandbox{
+		ID:                     sandboxID,
+		Name:                   "recovered-" + shortID(sandboxID),
+		Description:            "Recovered from an orphaned collector container",
+		CollectorConfig:        config,
+		CollectorVersion:       version,
+		Status:                 SandboxStatusRunning,
+		CreatedAt:              now,
+		UpdatedAt:              now,
+		CollectorContainerID:   collector.ID,
+		CollectorContainerName: collector.Name,
+		TelemetryConfig: TelemetryConfig{
+			OTLPEndpoint: "collector:4317",
+			OTLPProtocol: "grpc",
+		},
+		Metadata: map[string]interface{}{"recovered": true},
+	}
+	if hasNetwork {
+		sandbox.NetworkID = network.ID
+		sandbox.NetworkName = network.Name
+	}
+
+	m.logger.Info("Adopted orphaned sandbox", map[string]interface{}{
+		"sandbox_id":   sandboxID,
+		"container_id": collector.ID,
+	})
+
+	return sandbox
+}
+
+// removeOrphan removes the containers, network and config file of a sandbox
+// that has no stored record
+func (m *Manager) removeOrphan(ctx context.Context, sandboxID string, containers []LabeledContainer, network LabeledNetwork, hasNetwork bool) {
+	for _, container := range containers {
+		if err := m.dockerOrchestrator.RemoveContainer(ctx, container.ID); err != nil {
+			m.logger.Error("Failed to remove orphaned container", err, map[string]interface{}{
+				"sandbox_id":   sandboxID,
+				"container_id": container.ID,
+			})
+		}
+	}
+
+	// The network can only be removed once its containers are gone
+	if hasNetwork {
+		if err := m.dockerOrchestrator.RemoveNetwork(ctx, network.ID); err != nil {
+			m.logger.Error("Failed to remove orphaned network", err, map[string]interface{}{
+				"sandbox_id": sandboxID,
+				"network":    network.Name,
+			})
+		}
+	}
+
+	if err := m.dockerOrchestrator.RemoveCollectorConfig(sandboxID); err != nil {
+		m.logger.Error("Failed to remove orphaned collector config", err, map[string]interface{}{
+			"sandbox_id": sandboxID,
+		})
+	}
+
+	m.logger.Info("Removed orphaned sandbox", map[string]interface{}{
+		"sandbox_id": sandboxID,
+		"containers": len(containers),
+	})
+}
+
+// findCollector returns the collector among a sandbox's containers
+func findCollector(containers []LabeledContainer) (LabeledContainer, bool) {
+	for _, container := range containers {
+		if container.Component == "collector" {
+			return container, true
+		}
+	}
+	return LabeledContainer{}, false
+}
+
+// statusForContainerState maps a collector's Docker state to a sandbox
+// status, returning "" for states that say nothing about the sandbox
+func statusForContainerState(state string) SandboxStatus {
+	switch state {
+	case "running":
+		return SandboxStatusRunning
+	case "exited", "dead":
+		return SandboxStatusFailed
+	case "created", "restarting":
+		return SandboxStatusCreating
+	case "paused":
+		return SandboxStatusStopped
+	}
+	return ""
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
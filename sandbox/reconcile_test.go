@@ -0,0 +1,168 @@
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+// saveRecord stores a sandbox as Reconcile finds it after a restart
+func saveRecord(t *testing.T, store storage.Storage, sandbox *Sandbox) {
+	t.Helper()
+	sandbox.CreatedAt = time.Now()
+	sandbox.UpdatedAt = sandbox.CreatedAt
+	data, err := json.Marshal(sandbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveSandbox(&storage.SandboxRecord{ID: sandbox.ID, Name: sandbox.Name, Status: string(sandbox.Status), Data: data}); err != nil {
+		t.Fatalf("SaveSandbox() error = %v", err)
+	}
+}
+
+// dockerCalls returns the arguments of each call to a fake docker
+func dockerCalls(t *testing.T, logPath string) []string {
+	t.Helper()
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestReconcile(t *testing.T) {
+	configs := t.TempDir()
+	t.Setenv("SANDBOX_CONFIGS_DIR", configs)
+	writeFile(t, filepath.Join(configs, "orphan-adopt.yaml"), "receivers: {}\n")
+	writeFile(t, filepath.Join(configs, "orphan-remove.yaml"), "receivers: {}\n")
+
+	dockerPath, logPath := fakeDocker(t,
+		"bbbbbbbbbbbb\tsandbox-sb-running\totel/opentelemetry-collector-contrib:0.98.0\trunning\tsb-running\tcollector\n"+
+			"cccccccccccc\tsandbox-orphan-adopt\totel/opentelemetry-collector-contrib:0.99.0\trunning\torphan-adopt\tcollector\n"+
+			"dddddddddddd\tsandbox-orphan-remove\totel/opentelemetry-collector-contrib:latest\texited\torphan-remove\tcollector\n"+
+			"eeeeeeeeeeee\ttelemetrygen-orphan-remove\ttelemetrygen:latest\trunning\torphan-remove\ttelemetrygen\n",
+		"n1\tsandbox-orphan-adopt\torphan-adopt\n"+
+			"n2\tsandbox-orphan-remove\torphan-remove\n"+
+			"n3\tsandbox-orphan-net\torphan-net\n")
+	m, store := newTestManager(t, dockerPath)
+
+	saveRecord(t, store, &Sandbox{ID: "sb-running", Name: "running", Status: SandboxStatusCreating, CollectorContainerID: "aaaaaaaaaaaa"})
+	saveRecord(t, store, &Sandbox{ID: "sb-gone", Name: "gone", Status: SandboxStatusRunning, CollectorContainerID: "ffffffffffff"})
+	saveRecord(t, store, &Sandbox{ID: "sb-stopped", Name: "stopped", Status: SandboxStatusStopped})
+
+	// A sandbox created since startup is kept as it is
+	live := &Sandbox{ID: "sb-stopped", Name: "live", Status: SandboxStatusRunning}
+	m.sandboxes["sb-stopped"] = live
+
+	report, err := m.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	want := &ReconcileReport{
+		Restored: 3,
+		Failed:   []string{"sb-gone"},
+		Adopted:  []string{"orphan-adopt"},
+		Removed:  []string{"orphan-net", "orphan-remove"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Reconcile() = %+v, want %+v", report, want)
+	}
+
+	running := m.sandboxes["sb-running"]
+	if running.Status != SandboxStatusRunning || running.CollectorContainerID != "bbbbbbbbbbbb" || running.CollectorContainerName != "sandbox-sb-running" {
+		t.Errorf("sb-running = %s %s, want running in its current container", running.Status, running.CollectorContainerID)
+	}
+	gone := m.sandboxes["sb-gone"]
+	if gone.Status != SandboxStatusFailed || gone.Metadata["error"] == nil {
+		t.Errorf("sb-gone = %s %v, want failed with an error", gone.Status, gone.Metadata)
+	}
+	if m.sandboxes["sb-stopped"] != live {
+		t.Error("Reconcile() replaced a sandbox created since startup")
+	}
+
+	adopted := m.sandboxes["orphan-adopt"]
+	if adopted == nil || adopted.CollectorVersion != "0.99.0" || adopted.NetworkID != "n1" || adopted.CollectorConfig != "receivers: {}\n" {
+		t.Fatalf("orphan-adopt = %+v, want a sandbox rebuilt from its collector", adopted)
+	}
+	if record, err := store.GetSandbox("orphan-adopt"); err != nil || record.Status != string(SandboxStatusRunning) {
+		t.Errorf("adopted sandbox record = %+v, %v, want it saved", record, err)
+	}
+	if record, _ := store.GetSandbox("sb-gone"); record == nil || record.Status != string(SandboxStatusFailed) {
+		t.Errorf("sb-gone record = %+v, want its failed status saved", record)
+	}
+
+	calls := dockerCalls(t, logPath)
+	for _, want := range []string{"stop dddddddddddd", "rm dddddddddddd", "stop eeeeeeeeeeee", "rm eeeeeeeeeeee", "network rm n2", "network rm n3"} {
+		if !slices.Contains(calls, want) {
+			t.Errorf("docker was not called with %q: %q", want, calls)
+		}
+	}
+	for _, unwanted := range []string{"rm bbbbbbbbbbbb", "rm cccccccccccc", "network rm n1"} {
+		if slices.Contains(calls, unwanted) {
+			t.Errorf("docker was called with %q", unwanted)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(configs, "orphan-remove.yaml")); !os.IsNotExist(err) {
+		t.Error("the removed sandbox's config file still exists")
+	}
+	if _, err := os.Stat(filepath.Join(configs, "orphan-adopt.yaml")); err != nil {
+		t.Error("the adopted sandbox's config file was removed")
+	}
+}
+
+func TestReconcileDockerUnavailable(t *testing.T) {
+	dockerPath, logPath := fakeDocker(t, "", "")
+	m, store := newTestManager(t, dockerPath)
+	saveRecord(t, store, &Sandbox{ID: "sb-1", Name: "first", Status: SandboxStatusRunning, CollectorContainerID: "aaaaaaaaaaaa"})
+
+	report, err := m.Reconcile(context.Background())
+	if err == nil {
+		t.Fatal("Reconcile() succeeded without Docker")
+	}
+
+	// Stored sandboxes are restored unchanged rather than failed
+	if report.Restored != 1 || len(report.Failed) != 0 {
+		t.Errorf("Reconcile() = %+v, want one sandbox restored", report)
+	}
+	if sandbox := m.sandboxes["sb-1"]; sandbox == nil || sandbox.Status != SandboxStatusRunning {
+		t.Errorf("sb-1 = %+v, want it restored as running", sandbox)
+	}
+	if calls := dockerCalls(t, logPath); len(calls) != 1 {
+		t.Errorf("docker calls = %q, want only the failed listing", calls)
+	}
+}
+
+func TestReconcileWithoutStore(t *testing.T) {
+	m, _ := newTestManager(t, "docker")
+	m.store = nil
+
+	if _, err := m.Reconcile(context.Background()); err == nil {
+		t.Error("Reconcile() succeeded without storage")
+	}
+}
+
+func TestStatusForContainerState(t *testing.T) {
+	tests := map[string]SandboxStatus{
+		"running":    SandboxStatusRunning,
+		"exited":     SandboxStatusFailed,
+		"dead":       SandboxStatusFailed,
+		"created":    SandboxStatusCreating,
+		"restarting": SandboxStatusCreating,
+		"paused":     SandboxStatusStopped,
+		"removing":   "",
+	}
+	for state, want := range tests {
+		if got := statusForContainerState(state); got != want {
+			t.Errorf("statusForContainerState(%q) = %q, want %q", state, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/otlpgen"
+)
+
+// newReplay creates a replay of recorded telemetry into the given collector
+// containers. Replays go to the collectors' OTLP/HTTP receivers, the only
+// ones accepting the JSON the file exporter writes by default, unless config
+// names another OTLP/HTTP endpoint.
+func (m *Manager) newReplay(ctx context.Context, config TelemetryConfig, source ReplaySource, duration time.Duration, collectorIDs ...string) (*otlpgen.Replay, error) {
+	fixtures, err := loadFixtures(source.Fixtures)
+	if err != nil {
+		return nil, err
+	}
+
+	replayConfig := TelemetryConfig{OTLPEndpoint: "collector:4318", OTLPProtocol: otlpgen.ProtocolHTTP}
+	if config.OTLPProtocol == otlpgen.ProtocolHTTP && config.OTLPEndpoint != "" {
+		replayConfig.OTLPEndpoint = config.OTLPEndpoint
+	}
+	endpoints, err := m.otlpEndpoints(ctx, replayConfig, collectorIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return otlpgen.NewReplay(otlpgen.ReplayConfig{
+		Endpoint:         endpoints[0],
+		Mirrors:          endpoints[1:],
+		Protocol:         otlpgen.ProtocolHTTP,
+		Fixtures:         fixtures,
+		Speed:            source.Speed,
+		RebaseTimestamps: source.RebaseTimestamps,
+		Duration:         duration,
+	})
+}
+
+// loadFixtures reads the fixtures of a replay
+func loadFixtures(sources []ReplayFixture) ([]*otlpgen.Fixture, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no fixtures to replay")
+	}
+
+	fixtures := make([]*otlpgen.Fixture, 0, len(sources))
+	for i, source := range sources {
+		var fixture *otlpgen.Fixture
+		var err error
+		switch {
+		case source.Path != "" && len(source.Data) > 0:
+			return nil, fmt.Errorf("fixture %d has both a path and data", i+1)
+		case source.Path != "":
+			fixture, err = otlpgen.ReadFixture(source.Path, source.Signal)
+		case len(source.Data) > 0:
+			fixture, err = otlpgen.LoadFixture(fmt.Sprintf("fixture %d", i+1), source.Data, source.Signal)
+		default:
+			return nil, fmt.Errorf("fixture %d has no path or data", i+1)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixture: %w", err)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures, nil
+}
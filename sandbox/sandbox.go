@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
 )
 
 // Manager manages sandbox lifecycles
@@ -16,6 +18,14 @@ type Manager struct {
 	sandboxes          map[string]*Sandbox
 	mu                 sync.RWMutex
 	logger             Logger
+	store              storage.Storage // nil keeps sandboxes in memory only
+
+	// Telemetry capture, see EnableCapture
+	capture         *otlpsink.Sink
+	captureEndpoint string
+
+	// Telemetry being generated, by sandbox ID
+	generators map[string]*generatorRun
 }
 
 // Logger interface for logging
@@ -25,8 +35,10 @@ type Logger interface {
 	Debug(msg string, fields map[string]interface{})
 }
 
-// NewManager creates a new sandbox manager
-func NewManager(logger Logger) (*Manager, error) {
+// NewManager creates a new sandbox manager. Sandboxes and their validations
+// are persisted to store; call Reconcile to restore them after a restart.
+// A nil store keeps sandboxes in memory only.
+func NewManager(logger Logger, store storage.Storage) (*Manager, error) {
 	orchestrator, err := NewDockerOrchestrator(logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker orchestrator: %w", err)
@@ -39,6 +51,8 @@ func NewManager(logger Logger) (*Manager, error) {
 		validator:          validator,
 		sandboxes:          make(map[string]*Sandbox),
 		logger:             logger,
+		store:              store,
+		generators:         make(map[string]*generatorRun),
 	}, nil
 }
 
@@ -83,27 +97,49 @@ func (m *Manager) CreateSandbox(ctx context.Context, req CreateSandboxRequest) (
 		"name":       req.Name,
 	})
 
+	// Record the sandbox before creating containers, so a restart while
+	// creating leaves a record for Reconcile to mark as failed
+	m.saveLocked(sandbox)
+
 	// Create Docker network for isolation
 	networkName, networkID, err := m.dockerOrchestrator.CreateNetwork(ctx, sandboxID)
 	if err != nil {
 		sandbox.Status = SandboxStatusFailed
+		m.deleteRecord(sandboxID)
 		return sandbox, fmt.Errorf("failed to create network: %w", err)
 	}
 
 	sandbox.NetworkName = networkName
 	sandbox.NetworkID = networkID
 
+	// Also export to the capture sink so validations can assert on what the
+	// collector actually sends. The sandbox keeps the config as given.
+	deployedConfig := req.CollectorConfig
+	if m.capture != nil {
+		captureConfig, err := withCaptureExporter(req.CollectorConfig, m.captureEndpoint, sandboxID)
+		if err != nil {
+			m.logger.Error("Failed to add capture exporter, deploying config as given", err, map[string]interface{}{
+				"sandbox_id": sandboxID,
+			})
+		} else {
+			deployedConfig = captureConfig
+			sandbox.Metadata[metadataCaptureExporter] = captureExporterName
+		}
+	}
+
 	// Deploy collector
 	collectorInfo, err := m.dockerOrchestrator.DeployCollector(ctx, DeployCollectorConfig{
 		SandboxID:        sandboxID,
-		Config:           req.CollectorConfig,
+		Config:           deployedConfig,
 		CollectorVersion: req.CollectorVersion,
 		NetworkName:      networkName,
+		HostGateway:      m.capture != nil,
 	})
 	if err != nil {
 		// Cleanup network
 		_ = m.dockerOrchestrator.CleanupNetwork(ctx, sandboxID)
 		sandbox.Status = SandboxStatusFailed
+		m.deleteRecord(sandboxID)
 		return sandbox, fmt.Errorf("failed to deploy collector: %w", err)
 	}
 
@@ -114,6 +150,7 @@ func (m *Manager) CreateSandbox(ctx context.Context, req CreateSandboxRequest) (
 
 	// Store sandbox
 	m.sandboxes[sandboxID] = sandbox
+	m.saveLocked(sandbox)
 
 	m.logger.Info("Sandbox created successfully", map[string]interface{}{
 		"sandbox_id":   sandboxID,
@@ -140,22 +177,41 @@ func (m *Manager) GetSandbox(sandboxID string) (*Sandbox, error) {
 	return sandbox, nil
 }
 
-// refreshSandboxStatus checks the actual container status and updates the sandbox
+// refreshSandboxStatus checks the actual container status and updates the
+// sandbox. Docker is queried without holding m.mu; the sandbox is updated and
+// saved under it.
 func (m *Manager) refreshSandboxStatus(ctx context.Context, sandbox *Sandbox) {
-	if sandbox.CollectorContainerID == "" {
+	m.mu.RLock()
+	containerID := sandbox.CollectorContainerID
+	m.mu.RUnlock()
+
+	if containerID == "" {
 		return
 	}
 
 	// Get actual container status
-	status, err := m.dockerOrchestrator.getContainerStatus(ctx, sandbox.CollectorContainerID)
+	status, err := m.dockerOrchestrator.getContainerStatus(ctx, containerID)
 	if err != nil {
 		m.logger.Error("Failed to get container status", err, map[string]interface{}{
 			"sandbox_id":   sandbox.ID,
-			"container_id": sandbox.CollectorContainerID,
+			"container_id": containerID,
 		})
 		return
 	}
 
+	// Capture error logs when container fails
+	var errorLogs []string
+	if status == "exited" || status == "dead" {
+		logs, err := m.dockerOrchestrator.GetContainerLogs(ctx, containerID, 50)
+		if err == nil {
+			for _, log := range logs {
+				if log.Level == "error" || containsError(log.Message) {
+					errorLogs = append(errorLogs, log.Message)
+				}
+			}
+		}
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -173,22 +229,12 @@ func (m *Manager) refreshSandboxStatus(ctx context.Context, sandbox *Sandbox) {
 			sandbox.Status = SandboxStatusFailed
 			sandbox.UpdatedAt = time.Now()
 
-			// Capture error logs when container fails
-			logs, err := m.dockerOrchestrator.GetContainerLogs(ctx, sandbox.CollectorContainerID, 50)
-			if err == nil && len(logs) > 0 {
-				// Store error logs in metadata
+			// Store error logs in metadata
+			if len(errorLogs) > 0 {
 				if sandbox.Metadata == nil {
 					sandbox.Metadata = make(map[string]interface{})
 				}
-				errorLogs := make([]string, 0)
-				for _, log := range logs {
-					if log.Level == "error" || containsError(log.Message) {
-						errorLogs = append(errorLogs, log.Message)
-					}
-				}
-				if len(errorLogs) > 0 {
-					sandbox.Metadata["error_logs"] = errorLogs
-				}
+				sandbox.Metadata["error_logs"] = errorLogs
 			}
 		}
 	case "created", "restarting":
@@ -209,6 +255,7 @@ func (m *Manager) refreshSandboxStatus(ctx context.Context, sandbox *Sandbox) {
 			"old_status": oldStatus,
 			"new_status": sandbox.Status,
 		})
+		m.saveLocked(sandbox)
 	}
 }
 
@@ -270,40 +317,43 @@ func (m *Manager) StartTelemetry(ctx context.Context, sandboxID string, req Star
 		telemetryConfig = *req.TelemetryConfig
 	}
 
-	// Set duration
+	// Replay recorded telemetry, or generate it
+	var source telemetrySource
 	duration := req.Duration
-	if duration == 0 {
-		// Default to 30 seconds if not specified
-		duration = 30 * time.Second
-	}
+	if req.Replay != nil {
+		m.logger.Info("Starting telemetry replay", map[string]interface{}{
+			"sandbox_id": sandboxID,
+			"fixtures":   len(req.Replay.Fixtures),
+		})
 
-	m.logger.Info("Starting telemetry generation", map[string]interface{}{
-		"sandbox_id": sandboxID,
-		"duration":   duration,
-	})
+		source, err = m.newReplay(ctx, telemetryConfig, *req.Replay, duration, sandbox.CollectorContainerID)
+		if err != nil {
+			return fmt.Errorf("failed to start telemetry replay: %w", err)
+		}
+	} else {
+		if duration == 0 {
+			// Default to 30 seconds if not specified
+			duration = 30 * time.Second
+		}
 
-	// Start telemetrygen containers
-	generatorInfo, err := m.dockerOrchestrator.StartTelemetryGeneration(ctx, StartTelemetryConfig{
-		SandboxID:       sandboxID,
-		NetworkName:     sandbox.NetworkName,
-		TelemetryConfig: telemetryConfig,
-		Duration:        duration,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to start telemetry generation: %w", err)
-	}
+		m.logger.Info("Starting telemetry generation", map[string]interface{}{
+			"sandbox_id": sandboxID,
+			"duration":   duration,
+		})
 
-	// Store generator info in metadata
-	m.mu.Lock()
-	sandbox.Metadata["telemetry_generator"] = generatorInfo
-	sandbox.UpdatedAt = time.Now()
-	m.mu.Unlock()
+		source, err = m.newTelemetryGenerator(ctx, telemetryConfig, duration, sandbox.CollectorContainerID)
+		if err != nil {
+			return fmt.Errorf("failed to start telemetry generation: %w", err)
+		}
+	}
+	done := m.runGenerator(sandbox, source)
 
-	// If auto-validate is enabled, wait for duration and then validate
+	// If auto-validate is enabled, wait for the telemetry to be sent and then validate
 	if req.AutoValidate {
 		go func() {
 			// Wait for telemetry generation to complete
-			time.Sleep(duration + 2*time.Second) // Extra buffer
+			<-done
+			time.Sleep(2 * time.Second) // Extra buffer
 
 			m.logger.Info("Auto-validating sandbox", map[string]interface{}{
 				"sandbox_id": sandboxID,
@@ -350,6 +400,7 @@ func (m *Manager) ValidateSandbox(ctx context.Context, sandboxID string, req Val
 	m.mu.Lock()
 	sandbox.Status = SandboxStatusValidating
 	sandbox.UpdatedAt = time.Now()
+	m.saveLocked(sandbox)
 	m.mu.Unlock()
 
 	// Run validation
@@ -357,6 +408,7 @@ func (m *Manager) ValidateSandbox(ctx context.Context, sandboxID string, req Val
 	if err != nil {
 		m.mu.Lock()
 		sandbox.Status = SandboxStatusFailed
+		m.saveLocked(sandbox)
 		m.mu.Unlock()
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
@@ -366,8 +418,11 @@ func (m *Manager) ValidateSandbox(ctx context.Context, sandboxID string, req Val
 	sandbox.LastValidation = result
 	sandbox.Status = SandboxStatusRunning
 	sandbox.UpdatedAt = time.Now()
+	m.saveLocked(sandbox)
 	m.mu.Unlock()
 
+	m.recordValidation(result)
+
 	m.logger.Info("Validation completed", map[string]interface{}{
 		"sandbox_id":   sandboxID,
 		"status":       result.Status,
@@ -389,10 +444,8 @@ func (m *Manager) StopSandbox(ctx context.Context, sandboxID string) error {
 		"sandbox_id": sandboxID,
 	})
 
-	// Stop telemetry generators if running
-	if generatorInfo, ok := sandbox.Metadata["telemetry_generator"].(TelemetryGeneratorContainerInfo); ok {
-		_ = m.dockerOrchestrator.StopTelemetryGenerators(ctx, generatorInfo)
-	}
+	// Stop telemetry generation if running
+	m.stopGenerator(sandboxID)
 
 	// Stop collector
 	if err := m.dockerOrchestrator.StopCollector(ctx, sandbox.CollectorContainerID); err != nil {
@@ -411,6 +464,7 @@ func (m *Manager) StopSandbox(ctx context.Context, sandboxID string) error {
 	m.mu.Lock()
 	sandbox.Status = SandboxStatusStopped
 	sandbox.UpdatedAt = time.Now()
+	m.saveLocked(sandbox)
 	m.mu.Unlock()
 
 	m.logger.Info("Sandbox stopped", map[string]interface{}{
@@ -429,10 +483,14 @@ func (m *Manager) DeleteSandbox(ctx context.Context, sandboxID string) error {
 		})
 	}
 
-	// Remove from memory
+	// Remove from memory and storage
 	m.mu.Lock()
 	delete(m.sandboxes, sandboxID)
+	if m.capture != nil {
+		m.capture.Reset(sandboxID)
+	}
 	m.mu.Unlock()
+	m.deleteRecord(sandboxID)
 
 	m.logger.Info("Sandbox deleted", map[string]interface{}{
 		"sandbox_id": sandboxID,
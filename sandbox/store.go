@@ -0,0 +1,117 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+// saveLocked persists a sandbox. The caller must hold m.mu. Failures are
+// logged rather than returned, since the sandbox itself is still usable.
+func (m *Manager) saveLocked(sandbox *Sandbox) {
+	if m.store == nil {
+		return
+	}
+
+	data, err := json.Marshal(sandbox)
+	if err != nil {
+		m.logger.Error("Failed to encode sandbox", err, map[string]interface{}{
+			"sandbox_id": sandbox.ID,
+		})
+		return
+	}
+
+	if err := m.store.SaveSandbox(&storage.SandboxRecord{
+		ID:        sandbox.ID,
+		Name:      sandbox.Name,
+		Status:    string(sandbox.Status),
+		Data:      data,
+		CreatedAt: sandbox.CreatedAt,
+		UpdatedAt: sandbox.UpdatedAt,
+	}); err != nil {
+		m.logger.Error("Failed to save sandbox", err, map[string]interface{}{
+			"sandbox_id": sandbox.ID,
+		})
+	}
+}
+
+// deleteRecord removes a sandbox and its validation history from storage
+func (m *Manager) deleteRecord(sandboxID string) {
+	if m.store == nil {
+		return
+	}
+
+	if err := m.store.DeleteSandbox(sandboxID); err != nil {
+		m.logger.Error("Failed to delete sandbox record", err, map[string]interface{}{
+			"sandbox_id": sandboxID,
+		})
+	}
+}
+
+// recordValidation appends a validation result to its sandbox's history
+func (m *Manager) recordValidation(result *ValidationResult) {
+	if m.store == nil {
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		m.logger.Error("Failed to encode validation result", err, map[string]interface{}{
+			"sandbox_id": result.SandboxID,
+		})
+		return
+	}
+
+	if err := m.store.AddSandboxValidation(&storage.SandboxValidationRecord{
+		ID:          result.ID,
+		SandboxID:   result.SandboxID,
+		Status:      string(result.Status),
+		Data:        data,
+		StartedAt:   result.StartedAt,
+		CompletedAt: result.CompletedAt,
+	}); err != nil {
+		m.logger.Error("Failed to save validation result", err, map[string]interface{}{
+			"sandbox_id":    result.SandboxID,
+			"validation_id": result.ID,
+		})
+	}
+}
+
+// ListValidations returns up to limit past validations of a sandbox, newest
+// first. Without storage only the last validation is kept.
+func (m *Manager) ListValidations(sandboxID string, limit int) ([]*ValidationResult, error) {
+	m.mu.RLock()
+	sandbox, exists := m.sandboxes[sandboxID]
+	var last *ValidationResult
+	if exists {
+		last = sandbox.LastValidation
+	}
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("sandbox not found: %s", sandboxID)
+	}
+
+	if m.store == nil {
+		if last == nil {
+			return []*ValidationResult{}, nil
+		}
+		return []*ValidationResult{last}, nil
+	}
+
+	records, err := m.store.ListSandboxValidations(sandboxID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list validations: %w", err)
+	}
+
+	results := make([]*ValidationResult, 0, len(records))
+	for _, record := range records {
+		var result ValidationResult
+		if err := json.Unmarshal(record.Data, &result); err != nil {
+			return nil, fmt.Errorf("failed to decode validation %s: %w", record.ID, err)
+		}
+		results = append(results, &result)
+	}
+	return results, nil
+}
@@ -0,0 +1,137 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+// fakeDocker writes a docker stand-in to a temp directory. It records each
+// invocation in the returned log and prints the given container and network
+// listings; with containers empty, listing containers fails.
+func fakeDocker(t *testing.T, containers, networks string) (dockerPath, logPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	dockerPath = filepath.Join(dir, "docker")
+	logPath = filepath.Join(dir, "calls.log")
+
+	containersPath := filepath.Join(dir, "containers")
+	if containers != "" {
+		writeFile(t, containersPath, containers)
+	}
+	networksPath := filepath.Join(dir, "networks")
+	writeFile(t, networksPath, networks)
+
+	script := `#!/bin/sh
+echo "$*" >> ` + logPath + `
+case "$1 $2" in
+"ps -a") cat ` + containersPath + ` ;;
+"network ls") cat ` + networksPath + ` ;;
+esac
+`
+	if err := os.WriteFile(dockerPath, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return dockerPath, logPath
+}
+
+// newTestManager creates a manager backed by a fresh SQLite database and the
+// given docker binary
+func newTestManager(t *testing.T, dockerPath string) (*Manager, storage.Storage) {
+	t.Helper()
+
+	store, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	logger := NewSimpleLogger("test")
+	return &Manager{
+		dockerOrchestrator: &DockerOrchestrator{dockerPath: dockerPath, logger: logger},
+		sandboxes:          make(map[string]*Sandbox),
+		logger:             logger,
+		store:              store,
+		generators:         make(map[string]*generatorRun),
+	}, store
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveAndDeleteRecord(t *testing.T) {
+	m, store := newTestManager(t, "docker")
+	sandbox := &Sandbox{ID: "sb-1", Name: "first", Status: SandboxStatusRunning, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+
+	m.mu.Lock()
+	m.saveLocked(sandbox)
+	m.mu.Unlock()
+
+	record, err := store.GetSandbox("sb-1")
+	if err != nil {
+		t.Fatalf("GetSandbox() error = %v", err)
+	}
+	if record.Name != "first" || record.Status != string(SandboxStatusRunning) || !strings.Contains(string(record.Data), `"id":"sb-1"`) {
+		t.Errorf("record = %+v, want the saved sandbox", record)
+	}
+
+	m.recordValidation(&ValidationResult{ID: "v-1", SandboxID: "sb-1", Status: ValidationStatusPassed})
+	m.deleteRecord("sb-1")
+
+	if _, err := store.GetSandbox("sb-1"); err == nil {
+		t.Error("GetSandbox() found a deleted sandbox")
+	}
+	if records, _ := store.ListSandboxValidations("sb-1", 0); len(records) != 0 {
+		t.Errorf("deleted sandbox still has %d validations", len(records))
+	}
+}
+
+func TestListValidations(t *testing.T) {
+	m, _ := newTestManager(t, "docker")
+	start := time.Now()
+	last := &ValidationResult{ID: "v-2", SandboxID: "sb-1", Status: ValidationStatusFailed, CompletedAt: start.Add(2 * time.Minute)}
+	m.sandboxes["sb-1"] = &Sandbox{ID: "sb-1", LastValidation: last}
+	m.saveLocked(m.sandboxes["sb-1"])
+
+	m.recordValidation(&ValidationResult{ID: "v-1", SandboxID: "sb-1", Status: ValidationStatusPassed, CompletedAt: start.Add(time.Minute)})
+	m.recordValidation(last)
+
+	results, err := m.ListValidations("sb-1", 0)
+	if err != nil {
+		t.Fatalf("ListValidations() error = %v", err)
+	}
+	if len(results) != 2 || results[0].ID != "v-2" || results[1].ID != "v-1" {
+		t.Errorf("ListValidations() = %+v, want v-2 then v-1", results)
+	}
+
+	if results, _ := m.ListValidations("sb-1", 1); len(results) != 1 || results[0].Status != ValidationStatusFailed {
+		t.Errorf("ListValidations(limit 1) = %+v, want only the newest", results)
+	}
+
+	if _, err := m.ListValidations("sb-unknown", 0); err == nil {
+		t.Error("ListValidations() of an unknown sandbox succeeded")
+	}
+}
+
+func TestListValidationsWithoutStore(t *testing.T) {
+	m, _ := newTestManager(t, "docker")
+	m.store = nil
+	m.sandboxes["sb-1"] = &Sandbox{ID: "sb-1"}
+
+	if results, err := m.ListValidations("sb-1", 0); err != nil || len(results) != 0 {
+		t.Errorf("ListValidations() = %+v, %v, want none", results, err)
+	}
+
+	m.sandboxes["sb-1"].LastValidation = &ValidationResult{ID: "v-1", SandboxID: "sb-1"}
+	if results, err := m.ListValidations("sb-1", 0); err != nil || len(results) != 1 || results[0].ID != "v-1" {
+		t.Errorf("ListValidations() = %+v, %v, want the last validation", results, err)
+	}
+}
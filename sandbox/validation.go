@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
 	"gopkg.in/yaml.v2"
 )
 
@@ -13,6 +15,7 @@ import (
 type Validator struct {
 	orchestrator *DockerOrchestrator
 	logger       Logger
+	capture      *otlpsink.Sink // nil when telemetry capture is disabled
 }
 
 // NewValidator creates a new validator
@@ -28,7 +31,7 @@ func (v *Validator) Validate(ctx context.Context, sandbox *Sandbox, req Validate
 	startedAt := time.Now()
 
 	result := &ValidationResult{
-		ID:        fmt.Sprintf("validation-%d", time.Now().Unix()),
+		ID:        "validation-" + uuid.New().String(),
 		SandboxID: sandbox.ID,
 		Status:    ValidationStatusRunning,
 		StartedAt: startedAt,
@@ -42,6 +45,7 @@ func (v *Validator) Validate(ctx context.Context, sandbox *Sandbox, req Validate
 	v.checkCollectorConfiguration(ctx, sandbox, result)
 	v.checkPipelineConfiguration(ctx, sandbox, result)
 	v.checkTelemetryFlow(ctx, sandbox, result)
+	v.checkAssertions(sandbox, req.Assertions, result)
 
 	// Collect logs if requested
 	if req.CollectLogs {
@@ -152,9 +152,10 @@ func (s *Server) HandleHealth(w http.ResponseWriter, r *http.Request) {
 
 // CreateRunRequest represents the request body for creating a new run
 type CreateRunRequest struct {
-	AgentID         string `json:"agent_id"`
-	Prompt          string `json:"prompt"`
-	ResumeFromRunID string `json:"resume_from_run_id,omitempty"`
+	AgentID         string             `json:"agent_id"`
+	Prompt          string             `json:"prompt"`
+	ResumeFromRunID string             `json:"resume_from_run_id,omitempty"`
+	Budget          *storage.RunBudget `json:"budget,omitempty"`
 }
 
 // AddInstructionRequest represents a request to add a custom instruction
@@ -182,6 +183,13 @@ func (s *Server) HandleCreateRun(w http.ResponseWriter, r *http.Request) {
 		Prompt:          req.Prompt,
 		ResumeFromRunID: req.ResumeFromRunID,
 	}
+	if req.Budget != nil {
+		validationReq.MaxInputTokens = req.Budget.MaxInputTokens
+		validationReq.MaxOutputTokens = req.Budget.MaxOutputTokens
+		validationReq.MaxCostUSD = req.Budget.MaxCostUSD
+		validationReq.MaxDuration = req.Budget.MaxDuration
+		validationReq.MaxIterations = req.Budget.MaxIterations
+	}
 	if err := validationReq.Validate(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -192,6 +200,7 @@ func (s *Server) HandleCreateRun(w http.ResponseWriter, r *http.Request) {
 		AgentID:         req.AgentID,
 		Prompt:          req.Prompt,
 		ResumeFromRunID: req.ResumeFromRunID,
+		Budget:          req.Budget,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
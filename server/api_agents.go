@@ -3,6 +3,7 @@ package server
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	agentService "github.com/mottibechhofer/otel-ai-engineer/server/service/agent"
@@ -190,8 +191,7 @@ func (s *Server) HandleCreateMetaAgent(w http.ResponseWriter, r *http.Request) {
 
 	response, err := s.agentService.CreateMetaAgent(r.Context(), req)
 	if err != nil {
-		if err.Error() == "name is required" || err.Error() == "description is required" ||
-			err.Error() == "at least one tool is required" {
+		if err.Error() == "goal is required" || strings.HasPrefix(err.Error(), "invalid tool names") {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
@@ -200,7 +200,7 @@ func (s *Server) HandleCreateMetaAgent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -37,14 +37,18 @@ func (s *Server) HandleExecutePlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create execution context
-	ctx := r.Context()
-
 	// Create a run with the observability agent to execute the plan
 	runID := fmt.Sprintf("plan-exec-%s-%d", planID, time.Now().UnixNano())
 
+	// The execution outlives this request; register it so it can be stopped
+	// like any other run, which also stops its handoff sub-runs
+	ctx, cancel := context.WithCancel(context.Background())
+	s.activeRunManager.Add(runID, ctx, cancel)
+
 	// Start the plan execution asynchronously
 	go func() {
+		defer cancel()
+		defer s.activeRunManager.Remove(runID)
 		s.executePlanAsync(ctx, plan, runID)
 	}()
 
@@ -60,35 +64,32 @@ func (s *Server) HandleExecutePlan(w http.ResponseWriter, r *http.Request) {
 
 // executePlanAsync executes the plan in the background
 func (s *Server) executePlanAsync(ctx context.Context, plan *storage.ObservabilityPlan, runID string) {
-	// Create ObservabilityAgent using the eventBridge's emitter
-	obsAgent, err := s.agentRegistry.Create("observability", s.anthropicClient, s.logLevel, s.eventBridge.emitter)
-	if err != nil {
-		log.Printf("Failed to create observability agent: %v", err)
-		return
-	}
-
-	// Register handoff tool to enable agent-to-agent delegation
-	handoffCtx := &agent.HandoffContext{
-		ParentRunID:   runID,
-		ParentAgentID: "observability",
-		Registry:      s.agentRegistry,
-		Client:        s.anthropicClient,
-		LogLevel:      s.logLevel,
-		EventEmitter:  s.eventBridge.emitter,
-		Storage:       s.storage,
-	}
-	handoffTool := agent.CreateHandoffTool(handoffCtx)
-	obsAgent.RegisterTool("handoff_task", "Delegate a task to another specialized agent", handoffTool.Schema, handoffTool.Handler)
-
 	// Create a prompt that includes the plan details
 	prompt := s.createPlanExecutionPrompt(plan)
 
-	// Run the agent
-	result := obsAgent.Run(ctx, prompt)
+	// Run the observability agent under the plan's run ID. With storage set,
+	// it gets the handoff tool to delegate to other agents.
+	result, err := s.agentRegistry.RunAgent(ctx, agent.RunnerConfig{
+		AgentID:      "observability",
+		Prompt:       prompt,
+		Provider:     s.provider,
+		LogLevel:     s.logLevel,
+		EventEmitter: s.eventBridge.emitter,
+		NewRunID:     runID,
+		Storage:      s.storage,
+		Streaming:    s.streaming,
+
+		HumanInputTimeout: s.humanInputTimeout,
+		HandoffTimeout:    s.handoffTimeout,
+		RunTracker:        s.activeRunManager,
+	})
+	if err != nil {
+		log.Printf("Failed to run observability agent: %v", err)
+	}
 
 	// Update plan status based on result
 	status := storage.PlanStatusFailed
-	if result.Success {
+	if result != nil && result.Success {
 		status = storage.PlanStatusSuccess
 	}
 
@@ -157,13 +158,24 @@ For each component in the plan, use the handoff_task tool to delegate to the app
 - For pipelines: Call handoff_task with to_agent_id='pipeline' and describe the pipeline configuration
 - For backends: Call handoff_task with to_agent_id='backend' and describe the backend connection
 
+Components of the same kind are usually independent. Use handoff_parallel to delegate them together
+instead of one handoff_task call at a time, e.g. instrument all services in one call:
+{
+  "tasks": [
+    {"to_agent_id": "instrumentation", "task_description": "Instrument service 'user-service' ..."},
+    {"to_agent_id": "instrumentation", "task_description": "Instrument service 'order-service' ..."}
+  ]
+}
+
 Example for service instrumentation:
 {
   "to_agent_id": "instrumentation",
   "task_description": "Instrument service 'user-service' located at '/path/to/service'. Language: Go, Framework: Gin. Install OpenTelemetry SDK, configure OTLP exporter, and add instrumentation to HTTP handlers."
 }
 
-Each handoff will create a sub-run that you can track. Report progress and update component statuses as you proceed.`
+Each handoff will create a sub-run that you can track. Its result includes the sub-agent's report with the
+artifacts it created (collector IDs, dashboard UIDs, files edited), the status of each component and any
+follow-ups. Use these reports to report progress and update component statuses as you proceed.`
 
 	return prompt
 }
@@ -105,6 +105,50 @@ func (s *Server) HandleValidateSandbox(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// HandleCompareConfigs handles POST /api/sandboxes/compare
+func (s *Server) HandleCompareConfigs(w http.ResponseWriter, r *http.Request) {
+	var req sandbox.CompareConfigsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.BaselineConfig == "" || req.CandidateConfig == "" {
+		http.Error(w, "baseline_config and candidate_config are required", http.StatusBadRequest)
+		return
+	}
+
+	response, err := s.sandboxService.CompareConfigs(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleListSandboxValidations handles GET /api/sandboxes/{id}/validations
+func (s *Server) HandleListSandboxValidations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sandboxID := vars["id"]
+
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil {
+			limit = l
+		}
+	}
+
+	response, err := s.sandboxService.ListValidations(r.Context(), sandboxID, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // HandleGetSandboxLogs handles GET /api/sandboxes/{id}/logs
 func (s *Server) HandleGetSandboxLogs(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -143,6 +187,43 @@ func (s *Server) HandleGetSandboxMetrics(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
+// HandleGetCapturedTelemetry handles GET /api/sandboxes/{id}/captured
+func (s *Server) HandleGetCapturedTelemetry(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sandboxID := vars["id"]
+
+	limit := 100
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil {
+			limit = l
+		}
+	}
+
+	response, err := s.sandboxService.GetCapturedTelemetry(r.Context(), sandboxID, r.URL.Query().Get("signal"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleClearCapturedTelemetry handles DELETE /api/sandboxes/{id}/captured
+func (s *Server) HandleClearCapturedTelemetry(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	sandboxID := vars["id"]
+
+	response, err := s.sandboxService.ClearCapturedTelemetry(r.Context(), sandboxID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // HandleStopSandbox handles POST /api/sandboxes/{id}/stop
 func (s *Server) HandleStopSandbox(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -14,6 +14,7 @@ type EventBridge struct {
 	storage storage.Storage
 	emitter events.EventEmitter
 	cleanup func()
+	done    chan struct{} // Closed once queued events are stored
 	runMap  map[string]bool // Track which runs have been created
 	runMapMu sync.RWMutex   // Protect runMap from concurrent access
 }
@@ -23,6 +24,7 @@ func NewEventBridge(stor storage.Storage, emitter events.EventEmitter) *EventBri
 	bridge := &EventBridge{
 		storage: stor,
 		emitter: emitter,
+		done:    make(chan struct{}),
 		runMap:  make(map[string]bool),
 	}
 
@@ -38,7 +40,15 @@ func NewEventBridge(stor storage.Storage, emitter events.EventEmitter) *EventBri
 
 // processEvents processes events from the emitter and stores them
 func (b *EventBridge) processEvents(eventChan <-chan *events.AgentEvent) {
+	defer close(b.done)
+
 	for event := range eventChan {
+		// Streaming deltas are forwarded to WebSocket clients directly;
+		// the final message event persists the same content
+		if events.IsStreamingEvent(event.Type) {
+			continue
+		}
+
 		// Handle run start - create run in storage
 		if event.Type == events.EventRunStart {
 			// Check if run already exists (thread-safe)
@@ -61,6 +71,11 @@ func (b *EventBridge) processEvents(eventChan <-chan *events.AgentEvent) {
 						TotalToolCalls:  0,
 						TotalTokens:     storage.TokenUsage{},
 					}
+					if data.ParentRunID != "" {
+						parentRunID := data.ParentRunID
+						run.ParentRunID = &parentRunID
+						run.IsHandoff = true
+					}
 
 					if err := b.storage.CreateRun(run); err != nil {
 						log.Printf("Failed to create run: %v", err)
@@ -79,7 +94,9 @@ func (b *EventBridge) processEvents(eventChan <-chan *events.AgentEvent) {
 			var data events.RunEndData
 			if err := json.Unmarshal(event.Data, &data); err == nil {
 				status := storage.RunStatusSuccess
-				if !data.Success {
+				if data.Reason == events.RunEndReasonCancelled {
+					status = storage.RunStatusCancelled
+				} else if !data.Success {
 					status = storage.RunStatusFailed
 				}
 
@@ -111,9 +128,12 @@ func (b *EventBridge) processEvents(eventChan <-chan *events.AgentEvent) {
 					run, err := b.storage.GetRun(event.RunID)
 					if err == nil {
 						newTokens := storage.TokenUsage{
-							InputTokens:  run.TotalTokens.InputTokens + data.Usage.InputTokens,
-							OutputTokens: run.TotalTokens.OutputTokens + data.Usage.OutputTokens,
-							TotalTokens:  run.TotalTokens.TotalTokens + data.Usage.InputTokens + data.Usage.OutputTokens,
+							InputTokens:              run.TotalTokens.InputTokens + data.Usage.InputTokens,
+							OutputTokens:             run.TotalTokens.OutputTokens + data.Usage.OutputTokens,
+							CacheCreationInputTokens: run.TotalTokens.CacheCreationInputTokens + data.Usage.CacheCreationInputTokens,
+							CacheReadInputTokens:     run.TotalTokens.CacheReadInputTokens + data.Usage.CacheReadInputTokens,
+							TotalTokens: run.TotalTokens.TotalTokens + data.Usage.InputTokens + data.Usage.OutputTokens +
+								data.Usage.CacheCreationInputTokens + data.Usage.CacheReadInputTokens,
 						}
 
 						update := &storage.RunUpdate{
@@ -140,9 +160,10 @@ func (b *EventBridge) GetEmitter() events.EventEmitter {
 	return b.emitter
 }
 
-// Close closes the bridge
+// Close closes the bridge after storing the events already emitted
 func (b *EventBridge) Close() {
 	if b.cleanup != nil {
 		b.cleanup()
 	}
+	<-b.done
 }
@@ -252,6 +252,10 @@ func (m *MockStorage) GetBackendsByPlan(planID string) ([]*storage.Backend, erro
 	return nil, fmt.Errorf("not implemented in MockStorage")
 }
 
+func (m *MockStorage) ListAllBackends() ([]*storage.Backend, error) {
+	return nil, fmt.Errorf("not implemented in MockStorage")
+}
+
 func (m *MockStorage) UpdateBackend(backendID string, backend *storage.Backend) error {
 	return fmt.Errorf("not implemented in MockStorage")
 }
@@ -326,6 +330,78 @@ func (m *MockStorage) DeleteCustomAgent(agentID string) error {
 	return fmt.Errorf("not implemented in MockStorage")
 }
 
+// Checkpoint methods
+func (m *MockStorage) SaveCheckpoint(checkpoint *storage.RunCheckpoint) error {
+	return fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) GetLatestCheckpoints(runID string, limit int) ([]*storage.RunCheckpoint, error) {
+	return nil, fmt.Errorf("not implemented in MockStorage")
+}
+
+// Tool scope methods
+func (m *MockStorage) SetRunToolScope(runID string, toolNames []string) error {
+	return fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) GetRunToolScope(runID string) ([]string, error) {
+	return nil, fmt.Errorf("not implemented in MockStorage")
+}
+
+// Human action methods
+func (m *MockStorage) CreateHumanAction(action *storage.HumanAction) error {
+	return fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) GetHumanAction(actionID string) (*storage.HumanAction, error) {
+	return nil, fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) ListHumanActions(opts storage.HumanActionListOptions) ([]*storage.HumanAction, error) {
+	return nil, fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) UpdateHumanAction(actionID string, update *storage.HumanActionUpdate) error {
+	return fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) DeleteHumanAction(actionID string) error {
+	return fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) GetHumanActionsByRun(runID string) ([]*storage.HumanAction, error) {
+	return nil, fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) GetPendingHumanActions() ([]*storage.HumanAction, error) {
+	return nil, fmt.Errorf("not implemented in MockStorage")
+}
+
+// Sandbox methods
+func (m *MockStorage) SaveSandbox(record *storage.SandboxRecord) error {
+	return fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) GetSandbox(sandboxID string) (*storage.SandboxRecord, error) {
+	return nil, fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) ListSandboxes() ([]*storage.SandboxRecord, error) {
+	return nil, fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) DeleteSandbox(sandboxID string) error {
+	return fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) AddSandboxValidation(record *storage.SandboxValidationRecord) error {
+	return fmt.Errorf("not implemented in MockStorage")
+}
+
+func (m *MockStorage) ListSandboxValidations(sandboxID string, limit int) ([]*storage.SandboxValidationRecord, error) {
+	return nil, fmt.Errorf("not implemented in MockStorage")
+}
+
 // TestEventBridgeCreation verifies EventBridge is created correctly
 func TestEventBridgeCreation(t *testing.T) {
 	stor := NewMockStorage()
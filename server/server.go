@@ -3,23 +3,28 @@ package server
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"sync"
 	"time"
 
-	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/gorilla/mux"
 	"github.com/mottibechhofer/otel-ai-engineer/agent"
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
 	"github.com/mottibechhofer/otel-ai-engineer/config"
+	"github.com/mottibechhofer/otel-ai-engineer/mcpclient"
+	"github.com/mottibechhofer/otel-ai-engineer/mcpserver"
 	"github.com/mottibechhofer/otel-ai-engineer/otelclient"
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
 	"github.com/mottibechhofer/otel-ai-engineer/server/service"
 	backendService "github.com/mottibechhofer/otel-ai-engineer/server/service/backend"
 	collectorService "github.com/mottibechhofer/otel-ai-engineer/server/service/collector"
 	humanActionService "github.com/mottibechhofer/otel-ai-engineer/server/service/humanaction"
 	sandboxService "github.com/mottibechhofer/otel-ai-engineer/server/service/sandbox"
 	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
 	agentService "github.com/mottibechhofer/otel-ai-engineer/server/service/agent"
 	toolService "github.com/mottibechhofer/otel-ai-engineer/server/service/tools"
 	sandboxTools "github.com/mottibechhofer/otel-ai-engineer/tools/sandbox"
@@ -41,8 +46,9 @@ type Server struct {
 	router             *mux.Router
 	port               int
 	storageCleanup     func()
+	emitterCleanup     func()
 	agentRegistry      *agent.Registry
-	anthropicClient    *anthropic.Client
+	provider           agent.Provider
 	logLevel           config.LogLevel
 	eventBridge        *EventBridge
 	activeRuns         map[string]*ActiveRun
@@ -59,16 +65,53 @@ type Server struct {
 	toolDiscoveryService *toolService.ToolDiscoveryService       // Service for tool discovery
 	agentService         *agentService.AgentService              // Service for agent management
 	otelClient           *otelclient.OtelClient                  // OTEL client for collector management
+	streaming            bool
+	humanInputTimeout    time.Duration
+	handoffTimeout       time.Duration
+	agentsDir            string
+	mcpClients           []*mcpclient.Client // Connected MCP servers, closed on shutdown
+	mcpServer            *mcpserver.Server   // Publishes the tool catalog over MCP
+	captureSink          *otlpsink.Sink      // Receives what sandbox collectors export
+	captureSinkAddr      string
 }
 
 // Config holds server configuration
 type Config struct {
-	Storage         storage.Storage
-	Port            int
-	AgentRegistry   *agent.Registry
-	AnthropicClient *anthropic.Client
-	LogLevel        config.LogLevel
-	EventBridge     *EventBridge
+	Storage       storage.Storage
+	Port          int
+	AgentRegistry *agent.Registry
+	Provider      agent.Provider // Default LLM provider for agents
+	LogLevel      config.LogLevel
+	EventBridge   *EventBridge
+	Streaming     bool
+
+	// HumanInputTimeout is how long request_human_input waits for a response
+	HumanInputTimeout time.Duration
+
+	// HandoffTimeout limits each handoff sub-run (0 is unlimited)
+	HandoffTimeout time.Duration
+
+	// AgentsDir holds YAML/Markdown agent definitions (empty disables them)
+	AgentsDir string
+
+	// MCPServers are MCP servers whose tools are offered to custom agents
+	MCPServers []mcpclient.ServerConfig
+
+	// MCPServerAllow lists the tools published over MCP, as category ->
+	// tool names ("*" for all). Nil publishes nothing.
+	MCPServerAllow map[string][]string
+
+	// MCPServerToken is the bearer token MCP clients must send over HTTP
+	// (empty disables the HTTP transport); MCPServerOrigins are the browser
+	// origins allowed to call it
+	MCPServerToken   string
+	MCPServerOrigins []string
+
+	// CaptureSinkAddr is where the OTLP sink capturing sandbox telemetry
+	// listens (empty disables capture); collectors reach it at
+	// CaptureSinkEndpoint
+	CaptureSinkAddr     string
+	CaptureSinkEndpoint string
 }
 
 // New creates a new server
@@ -85,12 +128,16 @@ func New(cfg Config) *Server {
 
 	// Create run service
 	runService := service.NewRunService(service.Config{
-		Storage:         cfg.Storage,
-		AgentRegistry:   cfg.AgentRegistry,
-		AnthropicClient: cfg.AnthropicClient,
-		LogLevel:        cfg.LogLevel,
-		EventBridge:     eventBridgeAdapter,
-		ActiveRuns:      activeRunManager,
+		Storage:       cfg.Storage,
+		AgentRegistry: cfg.AgentRegistry,
+		Provider:      cfg.Provider,
+		LogLevel:      cfg.LogLevel,
+		EventBridge:   eventBridgeAdapter,
+		ActiveRuns:    activeRunManager,
+		Streaming:     cfg.Streaming,
+
+		HumanInputTimeout: cfg.HumanInputTimeout,
+		HandoffTimeout:    cfg.HandoffTimeout,
 	})
 
 	// Create trace service
@@ -117,6 +164,16 @@ func New(cfg Config) *Server {
 	// Create collector service
 	collectorService := collectorService.NewCollectorService(cfg.Storage, agentWorkService, otelClient)
 
+	// Persist sandboxes so they survive restarts
+	sandboxTools.SetSandboxStorage(cfg.Storage)
+
+	// Capture what sandbox collectors export so validations can assert on it
+	var captureSink *otlpsink.Sink
+	if cfg.CaptureSinkAddr != "" {
+		captureSink = otlpsink.New(0)
+		sandboxTools.SetCaptureSink(captureSink, cfg.CaptureSinkEndpoint)
+	}
+
 	// Create sandbox service
 	sandboxService := sandboxService.NewSandboxService()
 
@@ -130,11 +187,30 @@ func New(cfg Config) *Server {
 		dockerClient = nil
 	}
 
+	// Connect to MCP servers so their tools can be discovered
+	mcpClients := connectMCPServers(cfg.MCPServers)
+
 	// Create tool discovery service
-	toolDiscoveryService := toolService.NewToolDiscoveryService(dockerClient, otelClient)
+	toolDiscoveryService := toolService.NewToolDiscoveryService(dockerClient, otelClient, mcpClients)
 
 	// Create agent service
-	agentService := agentService.NewAgentService(cfg.Storage, cfg.AgentRegistry, toolDiscoveryService)
+	agentService := agentService.NewAgentService(cfg.Storage, cfg.AgentRegistry, toolDiscoveryService, runService)
+
+	// Publish the tool catalog to MCP hosts, recording calls like agent tool calls
+	var mcpEmitter events.EventEmitter
+	if cfg.EventBridge != nil {
+		mcpEmitter = cfg.EventBridge.GetEmitter()
+	}
+	mcpServer := mcpserver.New(mcpserver.Config{
+		Name:    "otel-ai-engineer",
+		Version: "1.0.0",
+		Tools: func() []tools.Tool {
+			return toolDiscoveryService.GetAllowedTools(cfg.MCPServerAllow)
+		},
+		Emitter:        mcpEmitter,
+		AuthToken:      cfg.MCPServerToken,
+		AllowedOrigins: cfg.MCPServerOrigins,
+	})
 
 	s := &Server{
 		storage:            cfg.Storage,
@@ -142,7 +218,7 @@ func New(cfg Config) *Server {
 		router:             mux.NewRouter(),
 		port:               cfg.Port,
 		agentRegistry:      cfg.AgentRegistry,
-		anthropicClient:    cfg.AnthropicClient,
+		provider:           cfg.Provider,
 		logLevel:           cfg.LogLevel,
 		eventBridge:        cfg.EventBridge,
 		activeRuns:         make(map[string]*ActiveRun),
@@ -158,12 +234,45 @@ func New(cfg Config) *Server {
 		toolDiscoveryService: toolDiscoveryService,
 		agentService:         agentService,
 		otelClient:           otelClient,
+		streaming:            cfg.Streaming,
+		humanInputTimeout:    cfg.HumanInputTimeout,
+		handoffTimeout:       cfg.HandoffTimeout,
+		agentsDir:            cfg.AgentsDir,
+		mcpClients:           mcpClients,
+		captureSink:          captureSink,
+		captureSinkAddr:      cfg.CaptureSinkAddr,
+		mcpServer:            mcpServer,
 	}
 
 	s.setupRoutes()
 	return s
 }
 
+// connectMCPServers connects to each configured MCP server. Servers that
+// cannot be reached are logged and kept, so they can be reconnected later.
+func connectMCPServers(configs []mcpclient.ServerConfig) []*mcpclient.Client {
+	var clients []*mcpclient.Client
+	for _, serverCfg := range configs {
+		client, err := mcpclient.NewClient(serverCfg)
+		if err != nil {
+			log.Printf("Warning: Invalid MCP server %s: %v", serverCfg.Name, err)
+			continue
+		}
+		clients = append(clients, client)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err = client.Connect(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("Warning: Failed to connect to MCP server %s, will retry: %v", serverCfg.Name, err)
+			continue
+		}
+		info := client.ServerInfo()
+		log.Printf("Connected to MCP server %s (%s %s)", serverCfg.Name, info.Name, info.Version)
+	}
+	return clients
+}
+
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes() {
 	// API routes
@@ -184,6 +293,9 @@ func (s *Server) setupRoutes() {
 	// Tool endpoints
 	api.HandleFunc("/tools", s.HandleListTools).Methods("GET")
 
+	// MCP endpoint (streamable HTTP transport)
+	api.Handle("/mcp", s.mcpServer)
+
 	// Run endpoints
 	api.HandleFunc("/runs", s.HandleListRuns).Methods("GET")
 	api.HandleFunc("/runs", s.HandleCreateRun).Methods("POST")
@@ -236,12 +348,16 @@ func (s *Server) setupRoutes() {
 	// Sandbox endpoints
 	api.HandleFunc("/sandboxes", s.HandleListSandboxes).Methods("GET")
 	api.HandleFunc("/sandboxes", s.HandleCreateSandbox).Methods("POST")
+	api.HandleFunc("/sandboxes/compare", s.HandleCompareConfigs).Methods("POST")
 	api.HandleFunc("/sandboxes/{id}", s.HandleGetSandbox).Methods("GET")
 	api.HandleFunc("/sandboxes/{id}", s.HandleDeleteSandbox).Methods("DELETE")
 	api.HandleFunc("/sandboxes/{id}/telemetry", s.HandleStartTelemetry).Methods("POST")
 	api.HandleFunc("/sandboxes/{id}/validate", s.HandleValidateSandbox).Methods("POST")
+	api.HandleFunc("/sandboxes/{id}/validations", s.HandleListSandboxValidations).Methods("GET")
 	api.HandleFunc("/sandboxes/{id}/logs", s.HandleGetSandboxLogs).Methods("GET")
 	api.HandleFunc("/sandboxes/{id}/metrics", s.HandleGetSandboxMetrics).Methods("GET")
+	api.HandleFunc("/sandboxes/{id}/captured", s.HandleGetCapturedTelemetry).Methods("GET")
+	api.HandleFunc("/sandboxes/{id}/captured", s.HandleClearCapturedTelemetry).Methods("DELETE")
 	api.HandleFunc("/sandboxes/{id}/stop", s.HandleStopSandbox).Methods("POST")
 
 	// Agent work endpoints
@@ -369,11 +485,14 @@ func corsMiddleware(next http.Handler) http.Handler {
 
 // Start starts the server
 func (s *Server) Start() error {
-	// Initialize sandbox tools
+	// Initialize sandbox tools and restore the sandboxes from before a restart
 	if err := sandboxTools.InitializeSandboxTools(); err != nil {
 		log.Printf("Warning: Failed to initialize sandbox tools: %v", err)
 	} else {
 		log.Printf("Sandbox tools initialized successfully")
+		if _, err := sandboxTools.ReconcileSandboxes(context.Background()); err != nil {
+			log.Printf("Warning: Failed to reconcile sandboxes: %v", err)
+		}
 	}
 
 	// Start WebSocket hub
@@ -382,6 +501,36 @@ func (s *Server) Start() error {
 	// Subscribe to storage events
 	s.SubscribeToStorage()
 
+	// Subscribe to streaming events, which are not persisted
+	s.SubscribeToStreamingEvents()
+
+	// Expire human input requests whose runs stopped waiting, e.g. on restart
+	go s.humanActionService.WatchExpiry(context.Background(), time.Minute)
+
+	// Reconnect MCP servers that go down and pick up their tools again
+	go s.toolDiscoveryService.WatchMCPServers(context.Background(), 30*time.Second)
+
+	// End MCP sessions whose clients went away without closing them
+	go s.mcpServer.WatchIdleSessions(context.Background(), time.Minute, 30*time.Minute)
+
+	// Receive telemetry exported by sandbox collectors
+	if s.captureSink != nil {
+		go func() {
+			log.Printf("OTLP capture sink listening on %s", s.captureSinkAddr)
+			if err := s.captureSink.ListenAndServe(context.Background(), s.captureSinkAddr); err != nil {
+				log.Printf("Warning: OTLP capture sink failed: %v", err)
+			}
+		}()
+	}
+
+	// Load agent definitions and reload them when their files change
+	if s.agentsDir != "" {
+		if err := s.agentService.LoadAgentDefinitions(context.Background(), s.agentsDir); err != nil {
+			log.Printf("Warning: Failed to load agent definitions from %s: %v", s.agentsDir, err)
+		}
+		go s.agentService.WatchAgentDefinitions(context.Background(), s.agentsDir, 5*time.Second)
+	}
+
 	// Listen on all interfaces (0.0.0.0) for Docker compatibility
 	addr := fmt.Sprintf("0.0.0.0:%d", s.port)
 	log.Printf("Server starting on http://%s", addr)
@@ -391,14 +540,33 @@ func (s *Server) Start() error {
 	return http.ListenAndServe(addr, s.router)
 }
 
+// ServeMCPStdio serves the tool catalog over MCP to a single client on r and
+// w (normally stdin and stdout) instead of starting the HTTP server
+func (s *Server) ServeMCPStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	if err := sandboxTools.InitializeSandboxTools(); err != nil {
+		log.Printf("Warning: Failed to initialize sandbox tools: %v", err)
+	} else if _, err := sandboxTools.ReconcileSandboxes(ctx); err != nil {
+		log.Printf("Warning: Failed to reconcile sandboxes: %v", err)
+	}
+
+	return s.mcpServer.ServeStdio(ctx, r, w)
+}
+
 // Close gracefully shuts down the server
 func (s *Server) Close() error {
 	// Cancel all active runs using the manager
 	s.activeRunManager.CancelAll()
 
+	for _, client := range s.mcpClients {
+		client.Close()
+	}
+
 	if s.storageCleanup != nil {
 		s.storageCleanup()
 	}
+	if s.emitterCleanup != nil {
+		s.emitterCleanup()
+	}
 	return s.storage.Close()
 }
 
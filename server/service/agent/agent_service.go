@@ -3,10 +3,13 @@ package agent
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/mottibechhofer/otel-ai-engineer/agent"
 	"github.com/mottibechhofer/otel-ai-engineer/config"
+	"github.com/mottibechhofer/otel-ai-engineer/server/service"
 	toolService "github.com/mottibechhofer/otel-ai-engineer/server/service/tools"
 	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
 	"github.com/mottibechhofer/otel-ai-engineer/tools"
@@ -17,6 +20,11 @@ type AgentService struct {
 	storage       storage.Storage
 	agentRegistry *agent.Registry
 	toolDiscovery *toolService.ToolDiscoveryService
+	runService    *service.RunService
+
+	// Agents loaded from definition files, by ID
+	fileAgents   map[string]*agent.AgentDefinition
+	fileAgentsMu sync.Mutex
 }
 
 // NewAgentService creates a new agent service
@@ -24,17 +32,22 @@ func NewAgentService(
 	stor storage.Storage,
 	agentRegistry *agent.Registry,
 	toolDiscovery *toolService.ToolDiscoveryService,
+	runService *service.RunService,
 ) *AgentService {
-	service := &AgentService{
+	as := &AgentService{
 		storage:       stor,
 		agentRegistry: agentRegistry,
 		toolDiscovery: toolDiscovery,
+		runService:    runService,
+		fileAgents:    make(map[string]*agent.AgentDefinition),
 	}
 
+	as.registerMetaAgent()
+
 	// Load and register all custom agents from storage
-	service.LoadCustomAgents(context.Background())
+	as.LoadCustomAgents(context.Background())
 
-	return service
+	return as
 }
 
 // LoadCustomAgents loads all custom agents from storage and registers them
@@ -68,6 +81,9 @@ func (as *AgentService) ListAgents(ctx context.Context) ([]AgentResponse, error)
 			Model:       agentInfo.Model,
 			Type:        "built-in",
 		}
+		if def, ok := as.fileAgent(agentInfo.ID); ok {
+			applyAgentDefinition(&response, def)
+		}
 
 		// Get tools for built-in agent
 		if agentInstance, err := as.agentRegistry.Create(agentInfo.ID, nil, config.LogLevelInfo, nil); err == nil {
@@ -95,16 +111,19 @@ func (as *AgentService) ListAgents(ctx context.Context) ([]AgentResponse, error)
 
 	for _, customAgent := range customAgents {
 		response := AgentResponse{
-			ID:           customAgent.ID,
-			Name:         customAgent.Name,
-			Description:  customAgent.Description,
-			Model:        customAgent.Model,
-			SystemPrompt: customAgent.SystemPrompt,
-			MaxTokens:    customAgent.MaxTokens,
-			ToolNames:    customAgent.ToolNames,
-			Type:         "custom",
-			CreatedAt:    customAgent.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:    customAgent.UpdatedAt.Format(time.RFC3339),
+			ID:             customAgent.ID,
+			Name:           customAgent.Name,
+			Description:    customAgent.Description,
+			Model:          customAgent.Model,
+			SystemPrompt:   customAgent.SystemPrompt,
+			MaxTokens:      customAgent.MaxTokens,
+			ThinkingBudget: customAgent.ThinkingBudget,
+			ToolNames:      customAgent.ToolNames,
+			Budget:         customAgent.Budget,
+			Provider:       customAgent.Provider,
+			Type:           "custom",
+			CreatedAt:      customAgent.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:      customAgent.UpdatedAt.Format(time.RFC3339),
 		}
 
 		// Get tool details
@@ -133,6 +152,9 @@ func (as *AgentService) GetAgent(ctx context.Context, agentID string) (*AgentRes
 			Model:       agentInfo.Model,
 			Type:        "built-in",
 		}
+		if def, ok := as.fileAgent(agentInfo.ID); ok {
+			applyAgentDefinition(response, def)
+		}
 
 		// Get tools
 		if agentInstance, err := as.agentRegistry.Create(agentInfo.ID, nil, config.LogLevelInfo, nil); err == nil {
@@ -159,16 +181,19 @@ func (as *AgentService) GetAgent(ctx context.Context, agentID string) (*AgentRes
 	}
 
 	response := &AgentResponse{
-		ID:           customAgent.ID,
-		Name:         customAgent.Name,
-		Description:  customAgent.Description,
-		Model:        customAgent.Model,
-		SystemPrompt: customAgent.SystemPrompt,
-		MaxTokens:    customAgent.MaxTokens,
-		ToolNames:    customAgent.ToolNames,
-		Type:         "custom",
-		CreatedAt:    customAgent.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:    customAgent.UpdatedAt.Format(time.RFC3339),
+		ID:             customAgent.ID,
+		Name:           customAgent.Name,
+		Description:    customAgent.Description,
+		Model:          customAgent.Model,
+		SystemPrompt:   customAgent.SystemPrompt,
+		MaxTokens:      customAgent.MaxTokens,
+		ThinkingBudget: customAgent.ThinkingBudget,
+		ToolNames:      customAgent.ToolNames,
+		Budget:         customAgent.Budget,
+		Provider:       customAgent.Provider,
+		Type:           "custom",
+		CreatedAt:      customAgent.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      customAgent.UpdatedAt.Format(time.RFC3339),
 	}
 
 	// Get tool details
@@ -195,25 +220,8 @@ func (as *AgentService) GetAgentTools(ctx context.Context, agentID string) ([]to
 
 // CreateCustomAgent creates a new custom agent
 func (as *AgentService) CreateCustomAgent(ctx context.Context, req CreateCustomAgentRequest) (*AgentResponse, error) {
-	// Validate request
-	if req.Name == "" {
-		return nil, fmt.Errorf("name is required")
-	}
-	if req.Description == "" {
-		return nil, fmt.Errorf("description is required")
-	}
-	if len(req.ToolNames) == 0 {
-		return nil, fmt.Errorf("at least one tool is required")
-	}
-
-	// Validate tool names exist
-	if err := as.toolDiscovery.ValidateToolNames(ctx, req.ToolNames); err != nil {
-		return nil, fmt.Errorf("invalid tool names: %w", err)
-	}
-
-	// Check if built-in agent with same ID exists (using name as ID check)
-	if _, exists := as.agentRegistry.Get(req.Name); exists {
-		return nil, fmt.Errorf("an agent with name '%s' already exists as built-in agent", req.Name)
+	if err := as.validateCustomAgentRequest(ctx, req); err != nil {
+		return nil, err
 	}
 
 	// Generate ID
@@ -229,15 +237,18 @@ func (as *AgentService) CreateCustomAgent(ctx context.Context, req CreateCustomA
 
 	now := time.Now()
 	customAgent := &storage.CustomAgent{
-		ID:           agentID,
-		Name:         req.Name,
-		Description:  req.Description,
-		SystemPrompt: req.SystemPrompt,
-		Model:        req.Model,
-		MaxTokens:    req.MaxTokens,
-		ToolNames:    req.ToolNames,
-		CreatedAt:    now,
-		UpdatedAt:    now,
+		ID:             agentID,
+		Name:           req.Name,
+		Description:    req.Description,
+		SystemPrompt:   req.SystemPrompt,
+		Model:          req.Model,
+		MaxTokens:      req.MaxTokens,
+		ThinkingBudget: req.ThinkingBudget,
+		ToolNames:      req.ToolNames,
+		Budget:         req.Budget,
+		Provider:       req.Provider,
+		CreatedAt:      now,
+		UpdatedAt:      now,
 	}
 
 	if err := as.storage.CreateCustomAgent(customAgent); err != nil {
@@ -254,16 +265,19 @@ func (as *AgentService) CreateCustomAgent(ctx context.Context, req CreateCustomA
 
 	// Build response
 	response := &AgentResponse{
-		ID:           customAgent.ID,
-		Name:         customAgent.Name,
-		Description:  customAgent.Description,
-		Model:        customAgent.Model,
-		SystemPrompt: customAgent.SystemPrompt,
-		MaxTokens:    customAgent.MaxTokens,
-		ToolNames:    customAgent.ToolNames,
-		Type:         "custom",
-		CreatedAt:    customAgent.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:    customAgent.UpdatedAt.Format(time.RFC3339),
+		ID:             customAgent.ID,
+		Name:           customAgent.Name,
+		Description:    customAgent.Description,
+		Model:          customAgent.Model,
+		SystemPrompt:   customAgent.SystemPrompt,
+		MaxTokens:      customAgent.MaxTokens,
+		ThinkingBudget: customAgent.ThinkingBudget,
+		ToolNames:      customAgent.ToolNames,
+		Budget:         customAgent.Budget,
+		Provider:       customAgent.Provider,
+		Type:           "custom",
+		CreatedAt:      customAgent.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      customAgent.UpdatedAt.Format(time.RFC3339),
 	}
 
 	// Get tool details
@@ -278,6 +292,45 @@ func (as *AgentService) CreateCustomAgent(ctx context.Context, req CreateCustomA
 	return response, nil
 }
 
+// validateCustomAgentRequest checks a request to create a custom agent
+func (as *AgentService) validateCustomAgentRequest(ctx context.Context, req CreateCustomAgentRequest) error {
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if req.Description == "" {
+		return fmt.Errorf("description is required")
+	}
+	if len(req.ToolNames) == 0 {
+		return fmt.Errorf("at least one tool is required")
+	}
+
+	// Validate tool names exist
+	if err := as.toolDiscovery.ValidateToolNames(ctx, req.ToolNames); err != nil {
+		return fmt.Errorf("invalid tool names: %w", err)
+	}
+
+	// Validate budget
+	if _, err := agent.BudgetFromRunBudget(req.Budget); err != nil {
+		return fmt.Errorf("invalid budget: %w", err)
+	}
+
+	// Validate provider
+	if err := as.agentRegistry.ValidateProviderConfig(req.Provider); err != nil {
+		return fmt.Errorf("invalid provider: %w", err)
+	}
+
+	if err := validateThinkingBudget(req.ThinkingBudget); err != nil {
+		return err
+	}
+
+	// Check if built-in agent with same ID exists (using name as ID check)
+	if _, exists := as.agentRegistry.Get(req.Name); exists {
+		return fmt.Errorf("an agent with name '%s' already exists as built-in agent", req.Name)
+	}
+
+	return nil
+}
+
 // UpdateCustomAgent updates a custom agent
 func (as *AgentService) UpdateCustomAgent(ctx context.Context, agentID string, req UpdateCustomAgentRequest) (*AgentResponse, error) {
 	if agentID == "" {
@@ -296,14 +349,33 @@ func (as *AgentService) UpdateCustomAgent(ctx context.Context, agentID string, r
 		}
 	}
 
+	// Validate budget if provided
+	if _, err := agent.BudgetFromRunBudget(req.Budget); err != nil {
+		return nil, fmt.Errorf("invalid budget: %w", err)
+	}
+
+	// Validate provider if provided
+	if err := as.agentRegistry.ValidateProviderConfig(req.Provider); err != nil {
+		return nil, fmt.Errorf("invalid provider: %w", err)
+	}
+
+	if req.ThinkingBudget != nil {
+		if err := validateThinkingBudget(*req.ThinkingBudget); err != nil {
+			return nil, err
+		}
+	}
+
 	// Build update
 	update := &storage.CustomAgentUpdate{
-		Name:         req.Name,
-		Description:  req.Description,
-		SystemPrompt: req.SystemPrompt,
-		Model:        req.Model,
-		MaxTokens:    req.MaxTokens,
-		ToolNames:    req.ToolNames,
+		Name:           req.Name,
+		Description:    req.Description,
+		SystemPrompt:   req.SystemPrompt,
+		Model:          req.Model,
+		MaxTokens:      req.MaxTokens,
+		ThinkingBudget: req.ThinkingBudget,
+		ToolNames:      req.ToolNames,
+		Budget:         req.Budget,
+		Provider:       req.Provider,
 	}
 
 	if err := as.storage.UpdateCustomAgent(agentID, update); err != nil {
@@ -316,8 +388,8 @@ func (as *AgentService) UpdateCustomAgent(ctx context.Context, agentID string, r
 		return nil, fmt.Errorf("failed to get updated agent: %w", err)
 	}
 
-	// Re-register in registry if tool names changed
-	if req.ToolNames != nil {
+	// Re-register in registry if anything the factory captures changed
+	if req.ToolNames != nil || req.Budget != nil || req.Provider != nil || req.ThinkingBudget != nil {
 		if err := as.registerCustomAgentInRegistry(updated); err != nil {
 			fmt.Printf("Warning: Failed to re-register custom agent in registry: %v\n", err)
 		}
@@ -325,16 +397,19 @@ func (as *AgentService) UpdateCustomAgent(ctx context.Context, agentID string, r
 
 	// Build response
 	response := &AgentResponse{
-		ID:           updated.ID,
-		Name:         updated.Name,
-		Description:  updated.Description,
-		Model:        updated.Model,
-		SystemPrompt: updated.SystemPrompt,
-		MaxTokens:    updated.MaxTokens,
-		ToolNames:    updated.ToolNames,
-		Type:         "custom",
-		CreatedAt:    updated.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:    updated.UpdatedAt.Format(time.RFC3339),
+		ID:             updated.ID,
+		Name:           updated.Name,
+		Description:    updated.Description,
+		Model:          updated.Model,
+		SystemPrompt:   updated.SystemPrompt,
+		MaxTokens:      updated.MaxTokens,
+		ThinkingBudget: updated.ThinkingBudget,
+		ToolNames:      updated.ToolNames,
+		Budget:         updated.Budget,
+		Provider:       updated.Provider,
+		Type:           "custom",
+		CreatedAt:      updated.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      updated.UpdatedAt.Format(time.RFC3339),
 	}
 
 	// Get tool details
@@ -367,17 +442,81 @@ func (as *AgentService) DeleteCustomAgent(ctx context.Context, agentID string) e
 	return nil
 }
 
-// CreateMetaAgent creates a meta-agent (placeholder for now)
-func (as *AgentService) CreateMetaAgent(ctx context.Context, req CreateMetaAgentRequest) (*AgentResponse, error) {
-	// For now, treat meta-agent as a special type of custom agent
-	// TODO: Implement actual meta-agent logic
-	return as.CreateCustomAgent(ctx, CreateCustomAgentRequest{
-		Name:         req.Name,
-		Description:  req.Description,
-		SystemPrompt: req.SystemPrompt,
-		Model:        req.Model,
-		ToolNames:    req.AvailableToolNames,
+// CreateMetaAgent starts a meta-agent run that designs an agent for the goal,
+// dry-runs it and registers it once a human approves the proposal
+func (as *AgentService) CreateMetaAgent(ctx context.Context, req CreateMetaAgentRequest) (*CreateMetaAgentResponse, error) {
+	if strings.TrimSpace(req.Goal) == "" {
+		return nil, fmt.Errorf("goal is required")
+	}
+	if len(req.AvailableToolNames) > 0 {
+		if err := as.toolDiscovery.ValidateToolNames(ctx, req.AvailableToolNames); err != nil {
+			return nil, fmt.Errorf("invalid tool names: %w", err)
+		}
+	}
+
+	// Scope the run's tools before it starts, so its first draft is checked
+	runID := service.NewRunID()
+	if len(req.AvailableToolNames) > 0 {
+		if err := as.setMetaToolScope(runID, req.AvailableToolNames); err != nil {
+			return nil, fmt.Errorf("failed to scope meta-agent tools: %w", err)
+		}
+	}
+
+	run, err := as.runService.CreateRun(ctx, service.CreateRunRequest{
+		RunID:   runID,
+		AgentID: MetaAgentID,
+		Prompt:  metaAgentPrompt(req),
 	})
+	if err != nil {
+		if err := as.setMetaToolScope(runID, nil); err != nil {
+			fmt.Printf("Warning: Failed to remove tool scope of run %s: %v\n", runID, err)
+		}
+		return nil, fmt.Errorf("failed to start meta-agent: %w", err)
+	}
+
+	return &CreateMetaAgentResponse{
+		RunID:   run.RunID,
+		AgentID: MetaAgentID,
+		Status:  run.Status,
+	}, nil
+}
+
+// metaAgentPrompt turns a meta-agent request into the run's prompt
+func metaAgentPrompt(req CreateMetaAgentRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Design and register an agent for this goal:\n\n%s\n", req.Goal)
+
+	if req.Name != "" {
+		fmt.Fprintf(&b, "\nName the agent %q.\n", req.Name)
+	}
+	if req.Description != "" {
+		fmt.Fprintf(&b, "\nDescription: %s\n", req.Description)
+	}
+	if req.Model != "" {
+		fmt.Fprintf(&b, "\nUse the model %s.\n", req.Model)
+	}
+	if req.SystemPrompt != "" {
+		fmt.Fprintf(&b, "\nStart from this system prompt:\n%s\n", req.SystemPrompt)
+	}
+	if len(req.AvailableToolNames) > 0 {
+		fmt.Fprintf(&b, "\nOnly choose tools from: %s\n", strings.Join(req.AvailableToolNames, ", "))
+	}
+	if len(req.SampleTasks) > 0 {
+		b.WriteString("\nDry-run the draft on these sample tasks:\n")
+		for _, task := range req.SampleTasks {
+			fmt.Fprintf(&b, "- %s\n", task)
+		}
+	}
+
+	return b.String()
+}
+
+// validateThinkingBudget checks an extended thinking budget (0 disables it)
+func validateThinkingBudget(budget int64) error {
+	if budget != 0 && budget < 1024 {
+		return fmt.Errorf("thinking_budget must be 0 or at least 1024 tokens")
+	}
+	return nil
 }
 
 // registerCustomAgentInRegistry registers a custom agent in the agent registry
@@ -390,6 +529,15 @@ func (as *AgentService) registerCustomAgentInRegistry(customAgent *storage.Custo
 		model = "claude-sonnet-4-5-20250929"
 	}
 
+	budget, err := agent.BudgetFromRunBudget(customAgent.Budget)
+	if err != nil {
+		return fmt.Errorf("invalid budget: %w", err)
+	}
+
+	if err := as.agentRegistry.ValidateProviderConfig(customAgent.Provider); err != nil {
+		return fmt.Errorf("invalid provider: %w", err)
+	}
+
 	// Register custom agent in registry
 	as.agentRegistry.RegisterCustomAgent(
 		agent.AgentInfo{
@@ -401,6 +549,9 @@ func (as *AgentService) registerCustomAgentInRegistry(customAgent *storage.Custo
 		customAgent.ToolNames,
 		customAgent.SystemPrompt,
 		customAgent.MaxTokens,
+		customAgent.ThinkingBudget,
+		budget,
+		customAgent.Provider,
 		func(toolNames []string) []tools.Tool {
 			// Get actual Tool objects with handlers from tool discovery service
 			return as.toolDiscovery.GetToolsByName(toolNames)
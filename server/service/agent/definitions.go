@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/agent"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+// LoadAgentDefinitions registers the agents defined by the YAML and Markdown
+// files in dir. Agents whose files were removed since the last load are
+// unregistered. Invalid files are reported and skipped, keeping the last good
+// definition of a file that fails to parse on reload, and a file cannot
+// replace a built-in or custom agent with the same ID.
+func (as *AgentService) LoadAgentDefinitions(ctx context.Context, dir string) error {
+	defs, loadErr := agent.LoadAgentDefinitions(dir)
+	if defs == nil && loadErr != nil {
+		return loadErr
+	}
+	if loadErr != nil {
+		fmt.Printf("Warning: Some agent definitions were skipped: %v\n", loadErr)
+	}
+
+	as.fileAgentsMu.Lock()
+	defer as.fileAgentsMu.Unlock()
+
+	loaded := make(map[string]*agent.AgentDefinition, len(defs))
+	for _, def := range defs {
+		if _, fromFile := as.fileAgents[def.ID]; !fromFile && as.agentRegistry.Has(def.ID) {
+			fmt.Printf("Warning: Skipping agent definition %s: agent %s already exists\n", def.Path, def.ID)
+			continue
+		}
+
+		if err := as.registerAgentDefinition(ctx, def); err != nil {
+			fmt.Printf("Warning: Failed to register agent definition %s: %v\n", def.Path, err)
+			// Keep the previous version of a changed file registered
+			if previous, ok := as.fileAgents[def.ID]; ok {
+				loaded[def.ID] = previous
+			}
+			continue
+		}
+		loaded[def.ID] = def
+		fmt.Printf("Loaded agent definition: %s (%s) from %s\n", def.ID, def.Name, def.Path)
+	}
+
+	loadedPaths := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		loadedPaths[def.Path] = true
+	}
+
+	for id, previous := range as.fileAgents {
+		if _, ok := loaded[id]; ok {
+			continue
+		}
+		// A file that still exists but no longer loads keeps its last good
+		// definition, so a bad edit doesn't take the agent away
+		if _, err := os.Stat(previous.Path); err == nil && !loadedPaths[previous.Path] {
+			fmt.Printf("Warning: Keeping previous agent definition %s: %s failed to load\n", id, previous.Path)
+			loaded[id] = previous
+			continue
+		}
+		as.agentRegistry.Unregister(id)
+		fmt.Printf("Unloaded agent definition: %s\n", id)
+	}
+	as.fileAgents = loaded
+
+	return nil
+}
+
+// WatchAgentDefinitions reloads the definitions in dir whenever its files
+// change, checking every interval until ctx is done
+func (as *AgentService) WatchAgentDefinitions(ctx context.Context, dir string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, _ := agent.AgentDefinitionsFingerprint(dir)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := agent.AgentDefinitionsFingerprint(dir)
+		if err != nil || current == last {
+			continue
+		}
+		last = current
+
+		if err := as.LoadAgentDefinitions(ctx, dir); err != nil {
+			fmt.Printf("Warning: Failed to reload agent definitions: %v\n", err)
+		}
+	}
+}
+
+// fileAgent returns the definition of an agent loaded from a file
+func (as *AgentService) fileAgent(agentID string) (*agent.AgentDefinition, bool) {
+	as.fileAgentsMu.Lock()
+	defer as.fileAgentsMu.Unlock()
+
+	def, ok := as.fileAgents[agentID]
+	return def, ok
+}
+
+// registerAgentDefinition registers a file definition in the agent registry
+func (as *AgentService) registerAgentDefinition(ctx context.Context, def *agent.AgentDefinition) error {
+	if err := as.toolDiscovery.ValidateToolNames(ctx, def.Tools); err != nil {
+		return fmt.Errorf("invalid tool names: %w", err)
+	}
+
+	budget, err := agent.BudgetFromRunBudget(def.Budget)
+	if err != nil {
+		return fmt.Errorf("invalid budget: %w", err)
+	}
+
+	model := def.Model
+	if model == "" {
+		model = "claude-sonnet-4-5-20250929"
+	}
+
+	as.agentRegistry.RegisterCustomAgent(
+		agent.AgentInfo{
+			ID:             def.ID,
+			Name:           def.Name,
+			Description:    def.Description,
+			Model:          model,
+			HandoffTargets: def.HandoffTargets,
+		},
+		def.Tools,
+		def.SystemPrompt,
+		def.MaxTokens,
+		def.ThinkingBudget,
+		budget,
+		nil,
+		func(toolNames []string) []tools.Tool {
+			return as.toolDiscovery.GetToolsByName(toolNames)
+		},
+	)
+
+	return nil
+}
+
+// applyAgentDefinition fills a response with the fields of a file definition
+func applyAgentDefinition(response *AgentResponse, def *agent.AgentDefinition) {
+	response.Type = "file"
+	response.SystemPrompt = def.SystemPrompt
+	response.MaxTokens = def.MaxTokens
+	response.ThinkingBudget = def.ThinkingBudget
+	response.ToolNames = def.Tools
+	response.Budget = def.Budget
+	response.HandoffTargets = def.HandoffTargets
+	response.SourcePath = def.Path
+}
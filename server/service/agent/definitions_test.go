@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mottibechhofer/otel-ai-engineer/agent"
+	toolService "github.com/mottibechhofer/otel-ai-engineer/server/service/tools"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+// newTestAgentService creates an agent service backed by a fresh SQLite
+// database, with the tools that need no external clients
+func newTestAgentService(t *testing.T) *AgentService {
+	t.Helper()
+
+	stor, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { stor.Close() })
+
+	return NewAgentService(stor, agent.NewRegistry(), toolService.NewToolDiscoveryService(nil, nil, nil), nil)
+}
+
+func writeDefinition(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadAgentDefinitionsReload(t *testing.T) {
+	as := newTestAgentService(t)
+	dir := t.TempDir()
+	tuner := filepath.Join(dir, "tuner.yaml")
+	reviewer := filepath.Join(dir, "reviewer.yaml")
+	writeDefinition(t, tuner, "id: tuner\nsystem_prompt: first\n")
+	writeDefinition(t, reviewer, "id: reviewer\nsystem_prompt: review\n")
+
+	if err := as.LoadAgentDefinitions(context.Background(), dir); err != nil {
+		t.Fatalf("LoadAgentDefinitions() error = %v", err)
+	}
+	if !as.agentRegistry.Has("tuner") || !as.agentRegistry.Has("reviewer") {
+		t.Fatal("definitions were not registered")
+	}
+
+	// A bad edit keeps the last good definition; a removed file unloads its agent
+	writeDefinition(t, tuner, "id: [\n")
+	if err := os.Remove(reviewer); err != nil {
+		t.Fatal(err)
+	}
+	if err := as.LoadAgentDefinitions(context.Background(), dir); err != nil {
+		t.Fatalf("LoadAgentDefinitions() error = %v", err)
+	}
+	def, ok := as.fileAgent("tuner")
+	if !ok || !as.agentRegistry.Has("tuner") || def.SystemPrompt != "first" {
+		t.Errorf("tuner = %+v, want the last good definition kept", def)
+	}
+	if as.agentRegistry.Has("reviewer") {
+		t.Error("reviewer is still registered after its file was removed")
+	}
+
+	// Fixing the file replaces the kept definition
+	writeDefinition(t, tuner, "id: tuner\nsystem_prompt: second\n")
+	if err := as.LoadAgentDefinitions(context.Background(), dir); err != nil {
+		t.Fatalf("LoadAgentDefinitions() error = %v", err)
+	}
+	if def, _ := as.fileAgent("tuner"); def.SystemPrompt != "second" {
+		t.Errorf("tuner system prompt = %q, want second", def.SystemPrompt)
+	}
+}
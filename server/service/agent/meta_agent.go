@@ -0,0 +1,494 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/agent"
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+	"github.com/mottibechhofer/otel-ai-engineer/config"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+// MetaAgentID is the registry ID of the agent that designs other agents
+const MetaAgentID = "meta"
+
+const (
+	maxDryRunTasks           = 5  // Sample tasks per dry_run_agent call
+	defaultDryRunIterations  = 6  // Iterations per sample task when not set
+	maxDryRunIterations      = 10 // Upper bound for max_iterations
+	defaultCustomAgentModel  = "claude-sonnet-4-5-20250929"
+	approvedProposalResponse = "approve"
+)
+
+const metaAgentSystemPrompt = `You are a meta-agent that designs new agents for this platform.
+
+Given a goal, you produce an agent definition: a name, a description, a system prompt and a selection of tools, then get it approved and registered.
+
+Workflow:
+1. Call list_available_tools and pick the smallest set of tools that covers the goal. Only use tool names from that list.
+2. Draft a focused system prompt: the agent's role, how it should approach the work, which tools to use when, and what to report at the end.
+3. Call dry_run_agent with the draft and 2-3 realistic sample tasks. Tools are not executed in a dry run; check that the agent picks sensible tools in a sensible order and that its answers fit the goal. Revise and dry-run again if needed.
+4. Call propose_agent with the final draft and a short summary of the dry runs. A human approves or rejects every proposal.
+5. If the proposal is rejected, use the feedback to revise the draft, dry-run it again and propose it again.
+   If the result says it was approved but has no agent_id (e.g. the run was restarted while waiting), call propose_agent again.
+
+Finish with the registered agent's ID, or explain why no agent was registered.`
+
+// registerMetaAgent registers the meta-agent in the agent registry. Its tools
+// need the tool catalog and custom agent creation, so it is registered here
+// rather than with the other built-in agents.
+func (as *AgentService) registerMetaAgent() {
+	as.agentRegistry.Register(agent.AgentInfo{
+		ID:          MetaAgentID,
+		Name:        "Meta Agent",
+		Description: "Designs new agents from a goal: selects tools, drafts a system prompt, dry-runs it on sample tasks and registers it after human approval",
+		Model:       defaultCustomAgentModel,
+	}, func(provider agent.Provider, logLevel config.LogLevel, emitter events.EventEmitter) (*agent.Agent, error) {
+		return agent.NewAgent(agent.Config{
+			Name:         "Meta Agent",
+			Description:  "Designs and registers new agents",
+			Provider:     provider,
+			Model:        anthropic.Model(defaultCustomAgentModel),
+			MaxTokens:    8192,
+			SystemPrompt: metaAgentSystemPrompt,
+			LogLevel:     logLevel,
+			EventEmitter: emitter,
+			Tools: []tools.Tool{
+				as.listAvailableToolsTool(),
+				as.dryRunAgentTool(provider, logLevel),
+				as.proposeAgentTool(emitter),
+			},
+		}), nil
+	})
+}
+
+// agentDraft is an agent designed by the meta-agent. It lists every field a
+// draft can set; the custom agent keeps the defaults for the rest.
+type agentDraft struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	SystemPrompt string   `json:"system_prompt"`
+	ToolNames    []string `json:"tool_names"`
+	Model        string   `json:"model,omitempty"`
+	MaxTokens    int64    `json:"max_tokens,omitempty"`
+}
+
+// request returns the custom agent request for the draft, with the model and
+// max tokens defaults filled in
+func (d agentDraft) request() CreateCustomAgentRequest {
+	req := CreateCustomAgentRequest{
+		Name:         d.Name,
+		Description:  d.Description,
+		SystemPrompt: d.SystemPrompt,
+		Model:        d.Model,
+		MaxTokens:    d.MaxTokens,
+		ToolNames:    d.ToolNames,
+	}
+	if req.Model == "" {
+		req.Model = defaultCustomAgentModel
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = 4096
+	}
+	return req
+}
+
+// agentDraftSchema is the input schema for an agent draft
+func agentDraftSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"name": map[string]interface{}{
+			"type":        "string",
+			"description": "Agent name",
+		},
+		"description": map[string]interface{}{
+			"type":        "string",
+			"description": "One-sentence description of what the agent does",
+		},
+		"system_prompt": map[string]interface{}{
+			"type":        "string",
+			"description": "The agent's system prompt",
+		},
+		"tool_names": map[string]interface{}{
+			"type":        "array",
+			"description": "Tools the agent can use, from list_available_tools",
+			"items":       map[string]interface{}{"type": "string"},
+		},
+		"model": map[string]interface{}{
+			"type":        "string",
+			"description": "Optional: Model ID (default " + defaultCustomAgentModel + ")",
+		},
+		"max_tokens": map[string]interface{}{
+			"type":        "integer",
+			"description": "Optional: Max tokens per response (default 4096)",
+		},
+	}
+}
+
+// closedSchema is a tool input schema that rejects properties it doesn't list
+func closedSchema(properties map[string]interface{}, required ...string) anthropic.ToolInputSchemaParam {
+	return anthropic.ToolInputSchemaParam{
+		Properties:  properties,
+		Required:    required,
+		ExtraFields: map[string]any{"additionalProperties": false},
+	}
+}
+
+// setMetaToolScope limits the tools the meta-agent run may give a new agent.
+// The scope is stored with the run so it still applies once the run resumes,
+// e.g. after a restart while waiting for approval.
+func (as *AgentService) setMetaToolScope(runID string, toolNames []string) error {
+	return as.storage.SetRunToolScope(runID, toolNames)
+}
+
+// metaToolScope returns the tools the meta-agent run in ctx may give a new
+// agent, or nil if it may use any tool
+func (as *AgentService) metaToolScope(ctx context.Context) ([]string, error) {
+	runID := tools.RunIDFromContext(ctx)
+	if runID == "" {
+		return nil, nil
+	}
+	scope, err := as.storage.GetRunToolScope(runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the run's tool scope: %w", err)
+	}
+	return scope, nil
+}
+
+// checkMetaToolScope fails if a draft uses a tool outside the run's scope
+func (as *AgentService) checkMetaToolScope(ctx context.Context, toolNames []string) error {
+	scope, err := as.metaToolScope(ctx)
+	if err != nil {
+		return err
+	}
+	if scope == nil {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(scope))
+	for _, name := range scope {
+		allowed[name] = true
+	}
+	for _, name := range toolNames {
+		if !allowed[name] {
+			return fmt.Errorf("tool %s is not available for this agent; choose from: %s", name, strings.Join(scope, ", "))
+		}
+	}
+	return nil
+}
+
+// listAvailableToolsTool creates the list_available_tools tool
+func (as *AgentService) listAvailableToolsTool() tools.Tool {
+	return tools.Tool{
+		Name:        "list_available_tools",
+		Description: "List every tool an agent can be given, with its description and category.",
+		Schema:      closedSchema(map[string]interface{}{}),
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+			catalog, err := as.toolDiscovery.GetAllTools(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list tools: %w", err)
+			}
+			scope, err := as.metaToolScope(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			type toolSummary struct {
+				Name        string `json:"name"`
+				Description string `json:"description"`
+				Category    string `json:"category"`
+			}
+			summaries := make([]toolSummary, 0, len(catalog.Tools))
+			for _, tool := range catalog.Tools {
+				if scope != nil && !slices.Contains(scope, tool.Name) {
+					continue
+				}
+				summaries = append(summaries, toolSummary{Name: tool.Name, Description: tool.Description, Category: tool.Category})
+			}
+			return map[string]interface{}{"tools": summaries}, nil
+		},
+	}
+}
+
+// dryRunInput defines input for the dry_run_agent tool
+type dryRunInput struct {
+	agentDraft
+	SampleTasks   []string `json:"sample_tasks"`
+	MaxIterations int      `json:"max_iterations,omitempty"`
+}
+
+// dryRunToolCall is a tool call the draft agent made during a dry run
+type dryRunToolCall struct {
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// dryRunResult is the outcome of one sample task
+type dryRunResult struct {
+	Task       string           `json:"task"`
+	Success    bool             `json:"success"`
+	Response   string           `json:"response"`
+	ToolCalls  []dryRunToolCall `json:"tool_calls"`
+	Iterations int              `json:"iterations"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// dryRunAgentTool creates the dry_run_agent tool, which runs a draft agent on
+// sample tasks with its tools replaced by stand-ins that only record the calls
+func (as *AgentService) dryRunAgentTool(provider agent.Provider, logLevel config.LogLevel) tools.Tool {
+	properties := agentDraftSchema()
+	properties["sample_tasks"] = map[string]interface{}{
+		"type":        "array",
+		"description": fmt.Sprintf("Realistic tasks to try the draft on (at most %d)", maxDryRunTasks),
+		"items":       map[string]interface{}{"type": "string"},
+	}
+	properties["max_iterations"] = map[string]interface{}{
+		"type":        "integer",
+		"description": fmt.Sprintf("Optional: Iterations per task (default %d, max %d)", defaultDryRunIterations, maxDryRunIterations),
+	}
+
+	return tools.Tool{
+		Name:        "dry_run_agent",
+		Description: "Try a draft agent on sample tasks. Its tools are not executed: each call is recorded and answered with a placeholder. Returns the agent's response and tool calls for each task.",
+		Schema:      closedSchema(properties, "name", "system_prompt", "tool_names", "sample_tasks"),
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+			var input dryRunInput
+			if err := json.Unmarshal(inputJSON, &input); err != nil {
+				return nil, fmt.Errorf("failed to parse dry run input: %w", err)
+			}
+			return as.dryRunAgent(ctx, provider, logLevel, input)
+		},
+	}
+}
+
+// dryRunAgent runs the draft on each sample task in turn
+func (as *AgentService) dryRunAgent(ctx context.Context, provider agent.Provider, logLevel config.LogLevel, input dryRunInput) (interface{}, error) {
+	if len(input.SampleTasks) == 0 {
+		return nil, fmt.Errorf("at least one sample task is required")
+	}
+	if len(input.SampleTasks) > maxDryRunTasks {
+		return nil, fmt.Errorf("at most %d sample tasks are allowed", maxDryRunTasks)
+	}
+	if err := as.toolDiscovery.ValidateToolNames(ctx, input.ToolNames); err != nil {
+		return nil, fmt.Errorf("invalid tool names: %w", err)
+	}
+	if err := as.checkMetaToolScope(ctx, input.ToolNames); err != nil {
+		return nil, err
+	}
+
+	iterations := input.MaxIterations
+	if iterations <= 0 {
+		iterations = defaultDryRunIterations
+	}
+	if iterations > maxDryRunIterations {
+		iterations = maxDryRunIterations
+	}
+
+	req := input.request()
+
+	results := make([]dryRunResult, 0, len(input.SampleTasks))
+	for _, task := range input.SampleTasks {
+		var mu sync.Mutex
+		var calls []dryRunToolCall
+		record := func(name string, toolInput json.RawMessage) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, dryRunToolCall{Name: name, Input: toolInput})
+		}
+
+		// Dry runs use no event emitter, so they do not show up as runs
+		draft := agent.NewAgent(agent.Config{
+			Name:         input.Name,
+			Provider:     provider,
+			Model:        anthropic.Model(req.Model),
+			MaxTokens:    req.MaxTokens,
+			SystemPrompt: req.SystemPrompt,
+			LogLevel:     logLevel,
+			Tools:        as.dryRunTools(input.ToolNames, record),
+			Budget:       &agent.Budget{MaxIterations: iterations},
+		})
+
+		runResult := draft.Run(ctx, task)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		result := dryRunResult{
+			Task:       task,
+			Success:    runResult.Success,
+			Response:   finalText(runResult),
+			ToolCalls:  calls,
+			Iterations: runResult.Iterations,
+		}
+		if runResult.Error != nil {
+			result.Error = runResult.Error.Error()
+		}
+		results = append(results, result)
+	}
+
+	return map[string]interface{}{"results": results}, nil
+}
+
+// dryRunTools returns stand-ins for the named tools with the same schemas,
+// which record each call instead of running it
+func (as *AgentService) dryRunTools(toolNames []string, record func(name string, input json.RawMessage)) []tools.Tool {
+	catalogTools := as.toolDiscovery.GetToolsByName(toolNames)
+	standIns := make([]tools.Tool, 0, len(catalogTools))
+	for _, tool := range catalogTools {
+		name := tool.Name
+		standIns = append(standIns, tools.Tool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Schema:      tool.Schema,
+			Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+				record(name, inputJSON)
+				return map[string]interface{}{
+					"dry_run": true,
+					"message": "Dry run: the tool was not executed. Assume it succeeded and continue.",
+				}, nil
+			},
+		})
+	}
+	return standIns
+}
+
+// proposeInput defines input for the propose_agent tool
+type proposeInput struct {
+	agentDraft
+	DryRunSummary string `json:"dry_run_summary"`
+}
+
+// proposeAgentTool creates the propose_agent tool, which asks a human to
+// approve a draft and registers it as a custom agent if they do
+func (as *AgentService) proposeAgentTool(emitter events.EventEmitter) tools.Tool {
+	properties := agentDraftSchema()
+	properties["dry_run_summary"] = map[string]interface{}{
+		"type":        "string",
+		"description": "How the draft did in its dry runs, for the human reviewer",
+	}
+
+	return tools.Tool{
+		Name:        "propose_agent",
+		Serial:      true,
+		Description: "Propose a finished draft for registration. A human reviews the proposal; the agent is registered only if they approve. Blocks until the human responds or the request times out, and returns the new agent's ID or the human's feedback.",
+		Schema:      closedSchema(properties, "name", "description", "system_prompt", "tool_names", "dry_run_summary"),
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+			var input proposeInput
+			if err := json.Unmarshal(inputJSON, &input); err != nil {
+				return nil, fmt.Errorf("failed to parse proposal: %w", err)
+			}
+			return as.proposeAgent(ctx, emitter, input)
+		},
+	}
+}
+
+// proposeAgent validates the proposal, waits for a human to approve it and
+// registers the agent
+func (as *AgentService) proposeAgent(ctx context.Context, emitter events.EventEmitter, input proposeInput) (interface{}, error) {
+	req := input.request()
+	if err := as.validateCustomAgentRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	if err := as.checkMetaToolScope(ctx, req.ToolNames); err != nil {
+		return nil, err
+	}
+
+	runID := tools.RunIDFromContext(ctx)
+	if runID == "" {
+		return nil, fmt.Errorf("propose_agent needs a tracked run to request human approval")
+	}
+	if emitter == nil {
+		emitter = events.NewNoOpEmitter()
+	}
+
+	approval, err := agent.RequestHumanInput(ctx, &agent.HumanInputContext{
+		RunID:        runID,
+		AgentID:      MetaAgentID,
+		AgentName:    "Meta Agent",
+		Storage:      as.storage,
+		EventEmitter: emitter,
+	}, agent.HumanInputRequest{
+		RequestType: "approval",
+		Question:    fmt.Sprintf("Register the agent %q?", input.Name),
+		Context:     proposalContext(req, input.DryRunSummary),
+		Options:     []string{approvedProposalResponse, "reject"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, _ := approval["response"].(string)
+	if approval["status"] != "responded" || !strings.EqualFold(strings.TrimSpace(response), approvedProposalResponse) {
+		return map[string]interface{}{
+			"approved": false,
+			"response": response,
+			"message":  "The proposal was not approved. Revise the draft based on the response, or explain why no agent was registered.",
+		}, nil
+	}
+
+	created, err := as.CreateCustomAgent(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("proposal was approved but registering the agent failed: %w", err)
+	}
+
+	return map[string]interface{}{
+		"approved": true,
+		"agent_id": created.ID,
+		"message":  fmt.Sprintf("Agent %s registered.", created.Name),
+	}, nil
+}
+
+// proposalContext describes every field of the agent that approval registers,
+// for the human reviewer
+func proposalContext(req CreateCustomAgentRequest, dryRunSummary string) string {
+	thinking := "disabled"
+	if req.ThinkingBudget > 0 {
+		thinking = fmt.Sprintf("%d tokens", req.ThinkingBudget)
+	}
+	budget := "none"
+	if req.Budget != nil {
+		data, _ := json.Marshal(req.Budget)
+		budget = string(data)
+	}
+	provider := "default"
+	if req.Provider != nil {
+		provider = req.Provider.Name
+		if req.Provider.Model != "" {
+			provider += " (" + req.Provider.Model + ")"
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Name:** %s\n", req.Name)
+	fmt.Fprintf(&b, "**Description:** %s\n", req.Description)
+	fmt.Fprintf(&b, "**Model:** %s\n", req.Model)
+	fmt.Fprintf(&b, "**Max tokens:** %d\n", req.MaxTokens)
+	fmt.Fprintf(&b, "**Extended thinking:** %s\n", thinking)
+	fmt.Fprintf(&b, "**Budget:** %s\n", budget)
+	fmt.Fprintf(&b, "**Provider:** %s\n", provider)
+	fmt.Fprintf(&b, "**Tools:** %s\n\n", strings.Join(req.ToolNames, ", "))
+	fmt.Fprintf(&b, "**System prompt:**\n%s\n\n", req.SystemPrompt)
+	fmt.Fprintf(&b, "**Dry runs:**\n%s\n\n", dryRunSummary)
+	fmt.Fprintf(&b, "Respond %q to register the agent, or give feedback to revise it.", approvedProposalResponse)
+	return b.String()
+}
+
+// finalText returns the text of a run's final message
+func finalText(result *agent.RunResult) string {
+	if result == nil || result.FinalMessage == nil {
+		return ""
+	}
+
+	var parts []string
+	for _, block := range result.FinalMessage.Content {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,248 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/agent"
+	"github.com/mottibechhofer/otel-ai-engineer/config"
+	toolService "github.com/mottibechhofer/otel-ai-engineer/server/service/tools"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+	"github.com/mottibechhofer/otel-ai-engineer/tools"
+)
+
+// scriptedProvider returns canned responses in order
+type scriptedProvider struct {
+	responses []string
+	calls     int
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) CreateMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	var message anthropic.Message
+	if err := json.Unmarshal([]byte(p.responses[p.calls]), &message); err != nil {
+		return nil, err
+	}
+	p.calls++
+	return &message, nil
+}
+
+func TestAgentDraftSchemasRejectUnknownFields(t *testing.T) {
+	as := newTestAgentService(t)
+
+	tests := []struct {
+		tool  tools.Tool
+		valid string
+	}{
+		{
+			tool:  as.dryRunAgentTool(nil, config.LogLevelSilent),
+			valid: `{"name":"a","system_prompt":"p","tool_names":["read_file"],"sample_tasks":["t"]}`,
+		},
+		{
+			tool:  as.proposeAgentTool(nil),
+			valid: `{"name":"a","description":"d","system_prompt":"p","tool_names":["read_file"],"dry_run_summary":"ok"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool.Name, func(t *testing.T) {
+			schema, err := tools.CompileInputSchema(tt.tool.Schema)
+			if err != nil {
+				t.Fatalf("CompileInputSchema() error = %v", err)
+			}
+			if err := schema.Validate(tt.tool.Name, json.RawMessage(tt.valid)); err != nil {
+				t.Errorf("Validate(valid draft) error = %v", err)
+			}
+
+			withBudget := strings.TrimSuffix(tt.valid, "}") + `,"budget":{"max_cost_usd":1000}}`
+			if err := schema.Validate(tt.tool.Name, json.RawMessage(withBudget)); err == nil || !strings.Contains(err.Error(), "budget") {
+				t.Errorf("Validate(draft with budget) error = %v, want budget rejected", err)
+			}
+		})
+	}
+}
+
+func TestAgentDraftRequest(t *testing.T) {
+	// Unknown fields never reach the custom agent request
+	var input proposeInput
+	if err := json.Unmarshal([]byte(`{"name":"a","tool_names":["read_file"],"thinking_budget":5000,"provider":{"name":"other"}}`), &input); err != nil {
+		t.Fatal(err)
+	}
+
+	req := input.request()
+	if req.ThinkingBudget != 0 || req.Provider != nil || req.Budget != nil {
+		t.Errorf("request() = %+v, want only draft fields set", req)
+	}
+	if req.Model != defaultCustomAgentModel || req.MaxTokens != 4096 {
+		t.Errorf("request() model = %s, max tokens = %d, want defaults", req.Model, req.MaxTokens)
+	}
+}
+
+func TestProposalContext(t *testing.T) {
+	req := agentDraft{
+		Name:         "log-triage",
+		Description:  "Triages error logs",
+		SystemPrompt: "You triage logs.",
+		ToolNames:    []string{"read_file", "search_files"},
+	}.request()
+	req.Budget = &storage.RunBudget{MaxIterations: 5}
+	req.Provider = &storage.ProviderConfig{Name: "local", Model: "llama"}
+
+	got := proposalContext(req, "picked the right tools")
+	for _, want := range []string{
+		"**Name:** log-triage",
+		"**Description:** Triages error logs",
+		"**Model:** " + defaultCustomAgentModel,
+		"**Max tokens:** 4096",
+		"**Extended thinking:** disabled",
+		`**Budget:** {"max_iterations":5}`,
+		"**Provider:** local (llama)",
+		"**Tools:** read_file, search_files",
+		"You triage logs.",
+		"picked the right tools",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("proposal context is missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestMetaToolScope(t *testing.T) {
+	as := newTestAgentService(t)
+	if err := as.setMetaToolScope("run-1", []string{"read_file"}); err != nil {
+		t.Fatalf("setMetaToolScope() error = %v", err)
+	}
+	ctx := tools.WithRunID(context.Background(), "run-1")
+
+	draft := agentDraft{Name: "writer", Description: "Writes files", SystemPrompt: "Write.", ToolNames: []string{"write_file"}}
+
+	_, err := as.dryRunAgent(ctx, nil, config.LogLevelSilent, dryRunInput{agentDraft: draft, SampleTasks: []string{"write a file"}})
+	if err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("dryRunAgent() error = %v, want write_file rejected", err)
+	}
+	_, err = as.proposeAgent(ctx, nil, proposeInput{agentDraft: draft, DryRunSummary: "fine"})
+	if err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("proposeAgent() error = %v, want write_file rejected", err)
+	}
+
+	listed, err := as.listAvailableToolsTool().ContextHandler(ctx, nil)
+	if err != nil {
+		t.Fatalf("list_available_tools error = %v", err)
+	}
+	data, _ := json.Marshal(listed)
+	if !strings.Contains(string(data), `"read_file"`) || strings.Contains(string(data), `"write_file"`) {
+		t.Errorf("list_available_tools = %s, want only read_file", data)
+	}
+
+	// Other runs are not scoped
+	if err := as.checkMetaToolScope(tools.WithRunID(context.Background(), "run-2"), draft.ToolNames); err != nil {
+		t.Errorf("checkMetaToolScope(unscoped run) error = %v", err)
+	}
+}
+
+func TestMetaToolScopeAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	open := func() *AgentService {
+		stor, err := storage.NewSQLiteStorage(path)
+		if err != nil {
+			t.Fatalf("NewSQLiteStorage() error = %v", err)
+		}
+		t.Cleanup(func() { stor.Close() })
+		return NewAgentService(stor, agent.NewRegistry(), toolService.NewToolDiscoveryService(nil, nil, nil), nil)
+	}
+
+	before := open()
+	if err := before.setMetaToolScope("run-1", []string{"read_file"}); err != nil {
+		t.Fatalf("setMetaToolScope() error = %v", err)
+	}
+	before.storage.Close()
+
+	// The run resumes in a new process, e.g. once its proposal is approved
+	as := open()
+	ctx := tools.WithRunID(context.Background(), "run-1")
+	draft := agentDraft{Name: "writer", Description: "Writes files", SystemPrompt: "Write.", ToolNames: []string{"write_file"}}
+	if _, err := as.proposeAgent(ctx, nil, proposeInput{agentDraft: draft, DryRunSummary: "fine"}); err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("proposeAgent() error = %v, want write_file rejected", err)
+	}
+	listed, err := as.listAvailableToolsTool().ContextHandler(ctx, nil)
+	if err != nil {
+		t.Fatalf("list_available_tools error = %v", err)
+	}
+	if data, _ := json.Marshal(listed); strings.Contains(string(data), `"write_file"`) {
+		t.Errorf("list_available_tools = %s, want only read_file", data)
+	}
+
+	// Deleting the run deletes its scope
+	if err := as.storage.CreateRun(&storage.Run{ID: "run-1", AgentID: MetaAgentID, AgentName: "meta", Status: storage.RunStatusSuccess, StartTime: time.Now()}); err != nil {
+		t.Fatalf("CreateRun() error = %v", err)
+	}
+	if err := as.storage.DeleteRun("run-1"); err != nil {
+		t.Fatalf("DeleteRun() error = %v", err)
+	}
+	if scope, err := as.metaToolScope(ctx); err != nil || scope != nil {
+		t.Errorf("metaToolScope() of a deleted run = %v, %v, want none", scope, err)
+	}
+}
+
+func TestDryRunAgent(t *testing.T) {
+	as := newTestAgentService(t)
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	provider := &scriptedProvider{responses: []string{
+		`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"tool_use",
+		  "content":[{"type":"tool_use","id":"toolu_1","name":"write_file","input":{"path":` + jsonString(t, path) + `,"content":"hi"}}],
+		  "usage":{"input_tokens":10,"output_tokens":5}}`,
+		`{"id":"msg_2","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"end_turn",
+		  "content":[{"type":"text","text":"Wrote the notes."}],
+		  "usage":{"input_tokens":20,"output_tokens":6}}`,
+	}}
+
+	result, err := as.dryRunAgent(context.Background(), provider, config.LogLevelSilent, dryRunInput{
+		agentDraft:  agentDraft{Name: "writer", SystemPrompt: "Write notes.", ToolNames: []string{"write_file"}},
+		SampleTasks: []string{"write some notes"},
+	})
+	if err != nil {
+		t.Fatalf("dryRunAgent() error = %v", err)
+	}
+
+	results := result.(map[string]interface{})["results"].([]dryRunResult)
+	if len(results) != 1 || !results[0].Success || results[0].Response != "Wrote the notes." {
+		t.Fatalf("results = %+v, want one successful run", results)
+	}
+	if calls := results[0].ToolCalls; len(calls) != 1 || calls[0].Name != "write_file" {
+		t.Errorf("tool calls = %+v, want one write_file call", calls)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("dry run executed write_file")
+	}
+}
+
+func TestMetaAgentPrompt(t *testing.T) {
+	got := metaAgentPrompt(CreateMetaAgentRequest{
+		Goal:               "Triage error logs",
+		Name:               "log-triage",
+		AvailableToolNames: []string{"read_file", "search_files"},
+		SampleTasks:        []string{"triage today's errors"},
+	})
+
+	for _, want := range []string{"Triage error logs", `"log-triage"`, "Only choose tools from: read_file, search_files", "- triage today's errors"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt is missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func jsonString(t *testing.T, s string) string {
+	t.Helper()
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
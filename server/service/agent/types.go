@@ -2,49 +2,71 @@ package agent
 
 import (
 	"github.com/mottibechhofer/otel-ai-engineer/server/service/tools"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
 )
 
 // AgentResponse represents an agent with its tools
 type AgentResponse struct {
-	ID          string                    `json:"id"`
-	Name        string                    `json:"name"`
-	Description string                    `json:"description"`
-	Model       string                    `json:"model,omitempty"`
-	SystemPrompt string                   `json:"system_prompt,omitempty"`
-	MaxTokens   int64                     `json:"max_tokens,omitempty"`
-	Type        string                    `json:"type"` // "built-in" or "custom"
-	Tools       []tools.ToolInfo          `json:"tools,omitempty"`
-	ToolNames   []string                  `json:"tool_names,omitempty"` // For custom agents
-	CreatedAt   string                    `json:"created_at,omitempty"`
-	UpdatedAt   string                    `json:"updated_at,omitempty"`
+	ID             string                  `json:"id"`
+	Name           string                  `json:"name"`
+	Description    string                  `json:"description"`
+	Model          string                  `json:"model,omitempty"`
+	SystemPrompt   string                  `json:"system_prompt,omitempty"`
+	MaxTokens      int64                   `json:"max_tokens,omitempty"`
+	ThinkingBudget int64                   `json:"thinking_budget,omitempty"`
+	Type           string                  `json:"type"` // "built-in", "custom" or "file"
+	Tools          []tools.ToolInfo        `json:"tools,omitempty"`
+	ToolNames      []string                `json:"tool_names,omitempty"` // For custom agents
+	Budget         *storage.RunBudget      `json:"budget,omitempty"`     // For custom agents
+	Provider       *storage.ProviderConfig `json:"provider,omitempty"`   // For custom agents
+	HandoffTargets []string                `json:"handoff_targets,omitempty"`
+	SourcePath     string                  `json:"source_path,omitempty"` // Definition file of file agents
+	CreatedAt      string                  `json:"created_at,omitempty"`
+	UpdatedAt      string                  `json:"updated_at,omitempty"`
 }
 
 // CreateCustomAgentRequest represents the request to create a custom agent
 type CreateCustomAgentRequest struct {
-	Name         string   `json:"name"`
-	Description  string   `json:"description"`
-	SystemPrompt string   `json:"system_prompt,omitempty"`
-	Model        string   `json:"model,omitempty"`
-	MaxTokens    int64    `json:"max_tokens,omitempty"`
-	ToolNames    []string `json:"tool_names"`
+	Name           string                  `json:"name"`
+	Description    string                  `json:"description"`
+	SystemPrompt   string                  `json:"system_prompt,omitempty"`
+	Model          string                  `json:"model,omitempty"`
+	MaxTokens      int64                   `json:"max_tokens,omitempty"`
+	ThinkingBudget int64                   `json:"thinking_budget,omitempty"`
+	ToolNames      []string                `json:"tool_names"`
+	Budget         *storage.RunBudget      `json:"budget,omitempty"`
+	Provider       *storage.ProviderConfig `json:"provider,omitempty"`
 }
 
 // UpdateCustomAgentRequest represents the request to update a custom agent
 type UpdateCustomAgentRequest struct {
-	Name         *string   `json:"name,omitempty"`
-	Description  *string   `json:"description,omitempty"`
-	SystemPrompt *string   `json:"system_prompt,omitempty"`
-	Model        *string   `json:"model,omitempty"`
-	MaxTokens    *int64    `json:"max_tokens,omitempty"`
-	ToolNames    *[]string `json:"tool_names,omitempty"`
+	Name           *string                 `json:"name,omitempty"`
+	Description    *string                 `json:"description,omitempty"`
+	SystemPrompt   *string                 `json:"system_prompt,omitempty"`
+	Model          *string                 `json:"model,omitempty"`
+	MaxTokens      *int64                  `json:"max_tokens,omitempty"`
+	ThinkingBudget *int64                  `json:"thinking_budget,omitempty"` // 0 disables extended thinking
+	ToolNames      *[]string               `json:"tool_names,omitempty"`
+	Budget         *storage.RunBudget      `json:"budget,omitempty"`   // An empty budget removes it
+	Provider       *storage.ProviderConfig `json:"provider,omitempty"` // An empty config reverts to the default provider
 }
 
-// CreateMetaAgentRequest represents the request to create a meta-agent
+// CreateMetaAgentRequest represents the request to have the meta-agent
+// design a new agent. Everything except Goal is an optional hint.
 type CreateMetaAgentRequest struct {
-	Name              string   `json:"name"`
-	Description       string   `json:"description"`
-	SystemPrompt      string   `json:"system_prompt,omitempty"`
-	Model             string   `json:"model,omitempty"`
-	AvailableToolNames []string `json:"available_tool_names"` // Tools the meta-agent can use
+	Goal               string   `json:"goal"`                           // What the new agent should do
+	Name               string   `json:"name,omitempty"`                 // Name for the new agent
+	Description        string   `json:"description,omitempty"`          // Description for the new agent
+	SystemPrompt       string   `json:"system_prompt,omitempty"`        // Starting point for the system prompt
+	Model              string   `json:"model,omitempty"`                // Model for the new agent
+	AvailableToolNames []string `json:"available_tool_names,omitempty"` // Tools the new agent may be given
+	SampleTasks        []string `json:"sample_tasks,omitempty"`         // Tasks to dry-run the draft on
 }
 
+// CreateMetaAgentResponse identifies the meta-agent run designing the agent.
+// The proposal shows up as a pending human action on that run.
+type CreateMetaAgentResponse struct {
+	RunID   string `json:"run_id"`
+	AgentID string `json:"agent_id"`
+	Status  string `json:"status"`
+}
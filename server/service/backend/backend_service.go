@@ -279,14 +279,14 @@ func (bs *BackendService) TestConnection(ctx context.Context, backendID string,
 		client := grafanaclient.NewClientWithAuth(url, username, password)
 
 		// Try to get health and list datasources
-		health, err := client.GetHealth()
+		health, err := client.GetHealth(ctx)
 		if err != nil {
 			healthy = false
 			errorMsg = err.Error()
 		} else {
 			healthy = true
 			// Try to list datasources as additional validation
-			ds, err := client.ListDatasources()
+			ds, err := client.ListDatasources(ctx)
 			if err == nil {
 				// Convert to []interface{} for JSON serialization
 				datasources = make([]interface{}, len(ds))
@@ -397,7 +397,7 @@ func (bs *BackendService) ConfigureGrafanaDatasource(ctx context.Context, backen
 		return nil, fmt.Errorf("failed to marshal input: %w", err)
 	}
 
-	result, err := tool.Handler(inputJSON)
+	result, err := tool.Execute(ctx, inputJSON)
 	if err != nil {
 		return nil, fmt.Errorf("failed to configure datasource: %w", err)
 	}
@@ -47,7 +47,7 @@ func (cs *CollectorService) ListCollectors(ctx context.Context, targetType strin
 		inputJSON = json.RawMessage("{}")
 	}
 
-	result, err := tool.Handler(inputJSON)
+	result, err := tool.Execute(ctx, inputJSON)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list collectors: %w", err)
 	}
@@ -176,7 +176,7 @@ func (cs *CollectorService) GetCollector(ctx context.Context, collectorID string
 
 	// Try to find in deployed collectors
 	tool := otelTools.GetListDeployedCollectorsTool()
-	result, err := tool.Handler(json.RawMessage("{}"))
+	result, err := tool.Execute(ctx, json.RawMessage("{}"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to list deployed collectors: %w", err)
 	}
@@ -370,7 +370,7 @@ func (cs *CollectorService) DeployCollector(ctx context.Context, req DeployColle
 		return nil, fmt.Errorf("failed to marshal input: %w", err)
 	}
 
-	result, err := tool.Handler(inputJSON)
+	result, err := tool.Execute(ctx, inputJSON)
 	if err != nil {
 		return nil, fmt.Errorf("failed to deploy collector: %w", err)
 	}
@@ -408,7 +408,7 @@ func (cs *CollectorService) StopCollector(ctx context.Context, collectorID strin
 		return nil, fmt.Errorf("failed to marshal input: %w", err)
 	}
 
-	result, err := tool.Handler(inputJSON)
+	result, err := tool.Execute(ctx, inputJSON)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stop collector: %w", err)
 	}
@@ -2,9 +2,12 @@ package humanaction
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/mottibechhofer/otel-ai-engineer/agent"
 	"github.com/mottibechhofer/otel-ai-engineer/server/service"
 	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
 )
@@ -74,36 +77,34 @@ func (has *HumanActionService) RespondToHumanAction(ctx context.Context, actionI
 		return nil, fmt.Errorf("human action is not pending")
 	}
 
-	// Update action with response
+	// Update action with response, unless it expired in the meantime
 	now := time.Now()
 	status := storage.HumanActionStatusResponded
+	pending := storage.HumanActionStatusPending
 	update := &storage.HumanActionUpdate{
 		Status:      &status,
 		Response:    &req.Response,
 		RespondedAt: &now,
+		IfStatus:    &pending,
 	}
 
 	if err := has.storage.UpdateHumanAction(actionID, update); err != nil {
 		return nil, fmt.Errorf("failed to update human action: %w", err)
 	}
 
-	// If resume is requested, resume the run with the response
-	if req.Resume {
-		resumeStatus := storage.HumanActionStatusResumed
-		resumeUpdate := &storage.HumanActionUpdate{
-			Status:    &resumeStatus,
-			ResumedAt: &now,
-		}
-		_ = has.storage.UpdateHumanAction(actionID, resumeUpdate) // Log error but don't fail
+	action, err = has.storage.GetHumanAction(actionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated human action: %w", err)
+	}
+	if action.Status != status {
+		return nil, fmt.Errorf("human action is not pending")
+	}
 
-		// Resume the run with the human's response as a message
-		_, err := has.runService.ResumeRun(ctx, service.ResumeRunRequest{
-			RunID:   action.RunID,
-			Message: fmt.Sprintf("Human response to '%s': %s", action.Question, req.Response),
-		})
-		if err != nil {
-			// Log error but don't fail the request
-			_ = err
+	// A run still waiting on the request picks the response up itself.
+	// Otherwise, if resume is requested, resume the run with the response.
+	if req.Resume && !has.runService.IsRunActive(action.RunID) {
+		if err := has.resumeWithResponse(ctx, action); err != nil {
+			log.Printf("Failed to resume run %s from human action %s: %v", action.RunID, actionID, err)
 		}
 	}
 
@@ -136,24 +137,12 @@ func (has *HumanActionService) ResumeFromHumanAction(ctx context.Context, action
 		return nil, fmt.Errorf("no response found on human action")
 	}
 
-	// Mark as resumed
-	now := time.Now()
-	status := storage.HumanActionStatusResumed
-	update := &storage.HumanActionUpdate{
-		Status:    &status,
-		ResumedAt: &now,
-	}
-	if err := has.storage.UpdateHumanAction(actionID, update); err != nil {
-		return nil, fmt.Errorf("failed to update human action: %w", err)
+	if has.runService.IsRunActive(action.RunID) {
+		return nil, fmt.Errorf("run is still active and receives the response itself")
 	}
 
-	// Resume the run with the human's response
-	_, err = has.runService.ResumeRun(ctx, service.ResumeRunRequest{
-		RunID:   action.RunID,
-		Message: fmt.Sprintf("Human response to '%s': %s", action.Question, *action.Response),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to resume run: %w", err)
+	if err := has.resumeWithResponse(ctx, action); err != nil {
+		return nil, err
 	}
 
 	// Get updated action
@@ -165,6 +154,107 @@ func (has *HumanActionService) ResumeFromHumanAction(ctx context.Context, action
 	return updatedAction, nil
 }
 
+// resumeWithResponse marks an answered or expired action as resumed and
+// resumes its run. The response becomes the result of the request_human_input
+// call that created the action; actions without a recorded call pass the
+// response on as a message.
+func (has *HumanActionService) resumeWithResponse(ctx context.Context, action *storage.HumanAction) error {
+	resumeReq := service.ResumeRunRequest{RunID: action.RunID}
+	if action.ToolUseID != "" {
+		result, err := json.Marshal(agent.HumanInputResult(action))
+		if err != nil {
+			return fmt.Errorf("failed to marshal human input result: %w", err)
+		}
+		resumeReq.ToolResults = map[string]string{action.ToolUseID: string(result)}
+	} else if action.Response != nil {
+		resumeReq.Message = fmt.Sprintf("Human response to '%s': %s", action.Question, *action.Response)
+	} else {
+		resumeReq.Message = fmt.Sprintf("No human responded to '%s' in time.", action.Question)
+	}
+
+	// Expired actions keep their status; answered ones are marked resumed
+	now := time.Now()
+	update := &storage.HumanActionUpdate{ResumedAt: &now}
+	if action.Status != storage.HumanActionStatusExpired {
+		status := storage.HumanActionStatusResumed
+		update.Status = &status
+	}
+	if err := has.storage.UpdateHumanAction(action.ID, update); err != nil {
+		return fmt.Errorf("failed to update human action: %w", err)
+	}
+
+	if _, err := has.runService.ResumeRun(ctx, resumeReq); err != nil {
+		return fmt.Errorf("failed to resume run: %w", err)
+	}
+	return nil
+}
+
+// ExpireOverdueActions expires pending actions past their deadline whose runs
+// are no longer waiting in this process, and resumes runs that were
+// interrupted while waiting (e.g. by a server restart) with the default response
+func (has *HumanActionService) ExpireOverdueActions(ctx context.Context) error {
+	actions, err := has.storage.GetPendingHumanActions()
+	if err != nil {
+		return fmt.Errorf("failed to get pending human actions: %w", err)
+	}
+
+	now := time.Now()
+	for _, action := range actions {
+		if action.ExpiresAt == nil || action.ExpiresAt.After(now) || has.runService.IsRunActive(action.RunID) {
+			continue
+		}
+
+		// Expire the action unless a response arrived in the meantime
+		status := storage.HumanActionStatusExpired
+		pending := storage.HumanActionStatusPending
+		update := &storage.HumanActionUpdate{Status: &status, Response: action.DefaultResponse, IfStatus: &pending}
+		if err := has.storage.UpdateHumanAction(action.ID, update); err != nil {
+			log.Printf("Failed to expire human action %s: %v", action.ID, err)
+			continue
+		}
+		expired, err := has.storage.GetHumanAction(action.ID)
+		if err != nil {
+			log.Printf("Failed to get expired human action %s: %v", action.ID, err)
+			continue
+		}
+		if expired.Status != status {
+			continue
+		}
+		action = expired
+
+		// Only runs interrupted while waiting are resumed; stopped or failed
+		// runs stay as they are
+		run, err := has.storage.GetRun(action.RunID)
+		if err != nil || run.Status != storage.RunStatusRunning {
+			continue
+		}
+
+		if err := has.resumeWithResponse(ctx, action); err != nil {
+			log.Printf("Failed to resume run %s from expired human action %s: %v", action.RunID, action.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// WatchExpiry calls ExpireOverdueActions every interval until ctx is done
+func (has *HumanActionService) WatchExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := has.ExpireOverdueActions(ctx); err != nil {
+			log.Printf("Failed to expire human actions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // DeleteHumanAction deletes a human action
 func (has *HumanActionService) DeleteHumanAction(ctx context.Context, actionID string) error {
 	if actionID == "" {
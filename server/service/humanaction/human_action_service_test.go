@@ -0,0 +1,109 @@
+package humanaction
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mottibechhofer/otel-ai-engineer/server/service"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+// racingStorage runs afterPending once pending actions have been read, to
+// act between the expiry watcher's read and its write
+type racingStorage struct {
+	storage.Storage
+	afterPending func()
+}
+
+func (s *racingStorage) GetPendingHumanActions() ([]*storage.HumanAction, error) {
+	actions, err := s.Storage.GetPendingHumanActions()
+	if s.afterPending != nil {
+		s.afterPending()
+	}
+	return actions, err
+}
+
+// newTestService creates a service with an overdue action of a run that
+// was interrupted while waiting on it
+func newTestService(t *testing.T, runStatus storage.RunStatus) (*HumanActionService, *racingStorage, *storage.HumanAction) {
+	t.Helper()
+	stor, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { stor.Close() })
+
+	if err := stor.CreateRun(&storage.Run{ID: "run-1", AgentID: "agent", AgentName: "agent", Status: runStatus, StartTime: time.Now()}); err != nil {
+		t.Fatalf("CreateRun() error = %v", err)
+	}
+	expiresAt := time.Now().Add(-time.Minute)
+	fallback := "jaeger"
+	action := &storage.HumanAction{
+		ID:              "action-1",
+		RunID:           "run-1",
+		AgentID:         "agent",
+		AgentName:       "agent",
+		RequestType:     "decision",
+		Question:        "Which backend?",
+		ToolUseID:       "toolu_1",
+		DefaultResponse: &fallback,
+		ExpiresAt:       &expiresAt,
+		Status:          storage.HumanActionStatusPending,
+	}
+	if err := stor.CreateHumanAction(action); err != nil {
+		t.Fatalf("CreateHumanAction() error = %v", err)
+	}
+
+	racing := &racingStorage{Storage: stor}
+	runService := service.NewRunService(service.Config{Storage: racing, ActiveRuns: service.NewActiveRunManager()})
+	return NewHumanActionService(racing, runService), racing, action
+}
+
+func TestExpireOverdueActions(t *testing.T) {
+	has, stor, action := newTestService(t, storage.RunStatusCancelled)
+
+	if err := has.ExpireOverdueActions(context.Background()); err != nil {
+		t.Fatalf("ExpireOverdueActions() error = %v", err)
+	}
+
+	stored, err := stor.GetHumanAction(action.ID)
+	if err != nil {
+		t.Fatalf("GetHumanAction() error = %v", err)
+	}
+	if stored.Status != storage.HumanActionStatusExpired || stored.Response == nil || *stored.Response != "jaeger" {
+		t.Errorf("stored action = %s %v, want expired with the default response", stored.Status, stored.Response)
+	}
+	// Cancelled runs are not resumed
+	if stored.ResumedAt != nil {
+		t.Error("the run of an expired action was resumed")
+	}
+}
+
+func TestExpireOverdueActionsAfterResponse(t *testing.T) {
+	has, stor, action := newTestService(t, storage.RunStatusRunning)
+
+	// The human responds after the watcher read the action as pending
+	stor.afterPending = func() {
+		stor.afterPending = nil
+		if _, err := has.RespondToHumanAction(context.Background(), action.ID, RespondToHumanActionRequest{Response: "tempo"}); err != nil {
+			t.Fatalf("RespondToHumanAction() error = %v", err)
+		}
+	}
+
+	if err := has.ExpireOverdueActions(context.Background()); err != nil {
+		t.Fatalf("ExpireOverdueActions() error = %v", err)
+	}
+
+	stored, err := stor.GetHumanAction(action.ID)
+	if err != nil {
+		t.Fatalf("GetHumanAction() error = %v", err)
+	}
+	if stored.Status != storage.HumanActionStatusResponded || stored.Response == nil || *stored.Response != "tempo" {
+		t.Errorf("stored action = %s %v, want the human response kept", stored.Status, stored.Response)
+	}
+	if stored.ResumedAt != nil {
+		t.Error("the watcher resumed the run of an answered action")
+	}
+}
@@ -16,12 +16,16 @@ import (
 
 // RunService handles business logic for agent runs
 type RunService struct {
-	storage         storage.Storage
-	agentRegistry   *agent.Registry
-	anthropicClient *anthropic.Client
-	logLevel        config.LogLevel
-	eventBridge     EventBridge
-	activeRuns      ActiveRunManager
+	storage       storage.Storage
+	agentRegistry *agent.Registry
+	provider      agent.Provider
+	logLevel      config.LogLevel
+	eventBridge   EventBridge
+	activeRuns    ActiveRunManager
+	streaming     bool
+
+	humanInputTimeout time.Duration
+	handoffTimeout    time.Duration
 }
 
 // EventBridge interface for event emission
@@ -46,31 +50,45 @@ type ActiveRun struct {
 
 // Config holds configuration for the run service
 type Config struct {
-	Storage         storage.Storage
-	AgentRegistry   *agent.Registry
-	AnthropicClient *anthropic.Client
-	LogLevel        config.LogLevel
-	EventBridge     EventBridge
-	ActiveRuns      ActiveRunManager
+	Storage       storage.Storage
+	AgentRegistry *agent.Registry
+	Provider      agent.Provider // Default LLM provider for runs
+	LogLevel      config.LogLevel
+	EventBridge   EventBridge
+	ActiveRuns    ActiveRunManager
+	Streaming     bool
+
+	// HumanInputTimeout is how long request_human_input waits for a response
+	// (0 uses agent.DefaultHumanInputTimeout)
+	HumanInputTimeout time.Duration
+
+	// HandoffTimeout limits each handoff sub-run (0 is unlimited)
+	HandoffTimeout time.Duration
 }
 
 // NewRunService creates a new run service
 func NewRunService(cfg Config) *RunService {
 	return &RunService{
-		storage:         cfg.Storage,
-		agentRegistry:   cfg.AgentRegistry,
-		anthropicClient: cfg.AnthropicClient,
-		logLevel:        cfg.LogLevel,
-		eventBridge:     cfg.EventBridge,
-		activeRuns:      cfg.ActiveRuns,
+		storage:       cfg.Storage,
+		agentRegistry: cfg.AgentRegistry,
+		provider:      cfg.Provider,
+		logLevel:      cfg.LogLevel,
+		eventBridge:   cfg.EventBridge,
+		activeRuns:    cfg.ActiveRuns,
+		streaming:     cfg.Streaming,
+
+		humanInputTimeout: cfg.HumanInputTimeout,
+		handoffTimeout:    cfg.HandoffTimeout,
 	}
 }
 
 // CreateRunRequest represents a request to create a new run
 type CreateRunRequest struct {
+	RunID           string // Optional: ID for the new run (see NewRunID)
 	AgentID         string
 	Prompt          string
 	ResumeFromRunID string
+	Budget          *storage.RunBudget // Optional: overrides the agent's budget
 }
 
 // CreateRunResponse represents the response from creating a run
@@ -80,6 +98,11 @@ type CreateRunResponse struct {
 	Status string
 }
 
+// NewRunID generates an ID for a new run
+func NewRunID() string {
+	return fmt.Sprintf("run-%d", time.Now().UnixNano())
+}
+
 // CreateRun creates and starts a new agent run
 func (s *RunService) CreateRun(ctx context.Context, req CreateRunRequest) (*CreateRunResponse, error) {
 	// Validate agent exists
@@ -88,8 +111,15 @@ func (s *RunService) CreateRun(ctx context.Context, req CreateRunRequest) (*Crea
 		return nil, fmt.Errorf("agent not found: %s", req.AgentID)
 	}
 
-	// Generate run ID
-	runID := fmt.Sprintf("run-%d", time.Now().UnixNano())
+	budget, err := agent.BudgetFromRunBudget(req.Budget)
+	if err != nil {
+		return nil, err
+	}
+
+	runID := req.RunID
+	if runID == "" {
+		runID = NewRunID()
+	}
 
 	// Create cancellable context
 	runCtx, cancel := context.WithCancel(context.Background())
@@ -107,7 +137,7 @@ func (s *RunService) CreateRun(ctx context.Context, req CreateRunRequest) (*Crea
 	var history []anthropic.MessageParam
 	if req.ResumeFromRunID != "" {
 		var err error
-		history, err = s.buildHistoryFromEvents(req.ResumeFromRunID)
+		history, _, err = s.loadHistory(req.ResumeFromRunID, nil)
 		if err != nil {
 			log.Printf("Failed to build history: %v", err)
 			// Continue without history rather than failing
@@ -122,12 +152,19 @@ func (s *RunService) CreateRun(ctx context.Context, req CreateRunRequest) (*Crea
 		_, err := s.agentRegistry.RunAgent(runCtx, agent.RunnerConfig{
 			AgentID:         req.AgentID,
 			Prompt:          req.Prompt,
-			Client:          s.anthropicClient,
+			Provider:        s.provider,
 			LogLevel:        s.logLevel,
 			EventEmitter:    s.eventBridge.GetEmitter(),
 			PendingMessages: activeRun.PendingMessage,
 			History:         history,
+			NewRunID:        runID,
 			Storage:         s.storage,
+			Streaming:       s.streaming,
+			Budget:          budget,
+
+			HumanInputTimeout: s.humanInputTimeout,
+			HandoffTimeout:    s.handoffTimeout,
+			RunTracker:        s.activeRuns,
 		})
 		if err != nil {
 			log.Printf("Agent run failed: %v", err)
@@ -169,6 +206,10 @@ func (s *RunService) CreateRun(ctx context.Context, req CreateRunRequest) (*Crea
 type ResumeRunRequest struct {
 	RunID   string
 	Message string
+
+	// ToolResults answers tool calls that were waiting when the run stopped,
+	// keyed by tool_use ID. Message is optional when set.
+	ToolResults map[string]string
 }
 
 // ResumeRun resumes an existing run with a new message
@@ -188,34 +229,47 @@ func (s *RunService) ResumeRun(ctx context.Context, req ResumeRunRequest) (*stor
 		return nil, fmt.Errorf("agent not found: %s", agentID)
 	}
 
-	// Create user message event and store it immediately
-	// This ensures it's in storage regardless of WebSocket filtering
-	messageData := events.MessageData{
-		Role: "user",
-		Content: []events.ContentBlock{
-			{
-				Type: "text",
-				Text: req.Message,
-			},
-		},
+	if req.Message == "" && len(req.ToolResults) == 0 {
+		return nil, fmt.Errorf("message is required")
 	}
 
-	event, err := events.NewMessageEvent(req.RunID, run.AgentID, run.AgentName, messageData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create message event: %w", err)
-	}
+	if req.Message != "" {
+		// Create user message event and store it immediately
+		// This ensures it's in storage regardless of WebSocket filtering
+		messageData := events.MessageData{
+			Role: "user",
+			Content: []events.ContentBlock{
+				{
+					Type: "text",
+					Text: req.Message,
+				},
+			},
+		}
 
-	// Store event
-	if err := s.storage.AddEvent(req.RunID, event); err != nil {
-		return nil, fmt.Errorf("failed to add event: %w", err)
+		event, err := events.NewMessageEvent(req.RunID, run.AgentID, run.AgentName, messageData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create message event: %w", err)
+		}
+
+		// Store event
+		if err := s.storage.AddEvent(req.RunID, event); err != nil {
+			return nil, fmt.Errorf("failed to add event: %w", err)
+		}
 	}
 
-	// Build history from events (now includes the new user message)
-	history, err := s.buildHistoryFromEvents(req.RunID)
+	// Restore the conversation (now includes the new user message)
+	history, checkpoint, err := s.loadHistory(req.RunID, req.ToolResults)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build history: %w", err)
 	}
 
+	// Continue against the run's budget with the usage it already consumed.
+	// Without a saved budget the agent's own budget applies.
+	budgetTracker, err := agent.BudgetTrackerFromCheckpoint(checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore budget: %w", err)
+	}
+
 	// Update run status to running
 	status := storage.RunStatusRunning
 	update := &storage.RunUpdate{
@@ -242,13 +296,19 @@ func (s *RunService) ResumeRun(ctx context.Context, req ResumeRunRequest) (*stor
 		_, err := s.agentRegistry.RunAgent(runCtx, agent.RunnerConfig{
 			AgentID:         agentID,
 			Prompt:          "", // Empty prompt for resume - user message is in history
-			Client:          s.anthropicClient,
+			Provider:        s.provider,
 			LogLevel:        s.logLevel,
 			EventEmitter:    s.eventBridge.GetEmitter(),
 			PendingMessages: nil, // Don't use pending messages - message is already in history
 			History:         history,
 			RunID:           req.RunID, // Use existing run ID
 			Storage:         s.storage,
+			Streaming:       s.streaming,
+			BudgetTracker:   budgetTracker,
+
+			HumanInputTimeout: s.humanInputTimeout,
+			HandoffTimeout:    s.handoffTimeout,
+			RunTracker:        s.activeRuns,
 		})
 		if err != nil {
 			log.Printf("Agent run failed: %v", err)
@@ -268,14 +328,14 @@ func (s *RunService) ResumeRun(ctx context.Context, req ResumeRunRequest) (*stor
 	return updatedRun, nil
 }
 
-// StopRun stops an active run
+// StopRun stops an active run and all of its handoff sub-runs
 func (s *RunService) StopRun(ctx context.Context, runID string) error {
 	activeRun, exists := s.activeRuns.Get(runID)
 	if !exists {
 		return fmt.Errorf("run not found or not active")
 	}
 
-	// Cancel the run
+	// Cancel the run. Sub-runs share its context, so they stop too.
 	if activeRun.CancelFunc != nil {
 		activeRun.CancelFunc()
 	}
@@ -285,7 +345,36 @@ func (s *RunService) StopRun(ctx context.Context, runID string) error {
 	update := &storage.RunUpdate{
 		Status: &status,
 	}
-	return s.storage.UpdateRun(runID, update)
+	if err := s.storage.UpdateRun(runID, update); err != nil {
+		return err
+	}
+
+	s.cancelSubRuns(runID)
+	return nil
+}
+
+// cancelSubRuns cancels the unfinished descendants of a run and marks them cancelled
+func (s *RunService) cancelSubRuns(runID string) {
+	subRuns, err := s.storage.GetSubRuns(runID)
+	if err != nil {
+		log.Printf("Failed to get sub-runs of %s: %v", runID, err)
+		return
+	}
+
+	for _, subRun := range subRuns {
+		if activeRun, exists := s.activeRuns.Get(subRun.ID); exists && activeRun.CancelFunc != nil {
+			activeRun.CancelFunc()
+		}
+
+		if subRun.Status == storage.RunStatusRunning || subRun.Status == storage.RunStatusPaused {
+			status := storage.RunStatusCancelled
+			if err := s.storage.UpdateRun(subRun.ID, &storage.RunUpdate{Status: &status}); err != nil {
+				log.Printf("Failed to mark sub-run %s cancelled: %v", subRun.ID, err)
+			}
+		}
+
+		s.cancelSubRuns(subRun.ID)
+	}
 }
 
 // PauseRun pauses an active run
@@ -357,6 +446,12 @@ func (s *RunService) AddInstruction(ctx context.Context, runID string, instructi
 	return nil
 }
 
+// IsRunActive reports whether a run is executing in this process
+func (s *RunService) IsRunActive(runID string) bool {
+	_, exists := s.activeRuns.Get(runID)
+	return exists
+}
+
 // normalizeAgentID converts agent display names to registry IDs
 func (s *RunService) normalizeAgentID(agentID string) string {
 	// Map common display names to registry IDs
@@ -376,6 +471,45 @@ func (s *RunService) normalizeAgentID(agentID string) string {
 	}
 }
 
+// loadHistory restores a run's conversation from its latest checkpoint plus
+// any user messages sent after it, falling back to the user messages alone
+// for runs without checkpoints. toolResults replace the results of the given
+// tool calls. The checkpoint used, if any, is returned with the history.
+func (s *RunService) loadHistory(runID string, toolResults map[string]string) ([]anthropic.MessageParam, *storage.RunCheckpoint, error) {
+	history, checkpoint, err := agent.LoadCheckpoint(s.storage, runID)
+	if err != nil {
+		log.Printf("Failed to load checkpoint for run %s: %v", runID, err)
+	}
+	if checkpoint == nil {
+		history, err := s.buildHistoryFromEvents(runID)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Without the tool call to answer, pass the results on as text
+		for id, result := range toolResults {
+			history = append(history, anthropic.NewUserMessage(anthropic.NewTextBlock(fmt.Sprintf("Result of tool call %s: %s", id, result))))
+		}
+		return history, nil, nil
+	}
+	if len(toolResults) > 0 {
+		history = agent.SetToolResults(history, toolResults)
+	}
+
+	runEvents, err := s.storage.GetEvents(runID, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	newer := make([]*events.AgentEvent, 0)
+	for _, event := range runEvents {
+		if event.Timestamp.After(checkpoint.CreatedAt) {
+			newer = append(newer, event)
+		}
+	}
+
+	return append(history, userMessagesFromEvents(newer)...), checkpoint, nil
+}
+
 // buildHistoryFromEvents converts message events into conversation history
 func (s *RunService) buildHistoryFromEvents(runID string) ([]anthropic.MessageParam, error) {
 	runEvents, err := s.storage.GetEvents(runID, nil)
@@ -383,6 +517,11 @@ func (s *RunService) buildHistoryFromEvents(runID string) ([]anthropic.MessagePa
 		return nil, fmt.Errorf("failed to get events: %w", err)
 	}
 
+	return userMessagesFromEvents(runEvents), nil
+}
+
+// userMessagesFromEvents converts user message events into conversation messages
+func userMessagesFromEvents(runEvents []*events.AgentEvent) []anthropic.MessageParam {
 	history := []anthropic.MessageParam{}
 
 	for _, event := range runEvents {
@@ -426,5 +565,5 @@ func (s *RunService) buildHistoryFromEvents(runID string) ([]anthropic.MessagePa
 		history = append(history, msg)
 	}
 
-	return history, nil
+	return history
 }
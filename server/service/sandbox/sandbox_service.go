@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
 	"github.com/mottibechhofer/otel-ai-engineer/sandbox"
 	sandboxTools "github.com/mottibechhofer/otel-ai-engineer/tools/sandbox"
 )
@@ -132,6 +133,44 @@ func (ss *SandboxService) ValidateSandbox(ctx context.Context, sandboxID string,
 	}, nil
 }
 
+// CompareConfigs compares a candidate collector config against a baseline
+func (ss *SandboxService) CompareConfigs(ctx context.Context, req sandbox.CompareConfigsRequest) (*CompareConfigsResponse, error) {
+	if err := ss.ensureManager(); err != nil {
+		return nil, err
+	}
+
+	report, err := ss.manager.CompareConfigs(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare configs: %w", err)
+	}
+
+	return &CompareConfigsResponse{
+		Success: true,
+		Report:  report,
+	}, nil
+}
+
+// ListValidations retrieves the validation history of a sandbox, newest first
+func (ss *SandboxService) ListValidations(ctx context.Context, sandboxID string, limit int) (*ListValidationsResponse, error) {
+	if err := ss.ensureManager(); err != nil {
+		return nil, err
+	}
+
+	if sandboxID == "" {
+		return nil, fmt.Errorf("sandbox ID cannot be empty")
+	}
+
+	validations, err := ss.manager.ListValidations(sandboxID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list validations: %w", err)
+	}
+
+	return &ListValidationsResponse{
+		Validations: validations,
+		Count:       len(validations),
+	}, nil
+}
+
 // GetSandboxLogs retrieves logs for a sandbox collector
 func (ss *SandboxService) GetSandboxLogs(ctx context.Context, sandboxID string, tail int) (*GetSandboxLogsResponse, error) {
 	if err := ss.ensureManager(); err != nil {
@@ -185,6 +224,53 @@ func (ss *SandboxService) GetSandboxMetrics(ctx context.Context, sandboxID strin
 	}, nil
 }
 
+// GetCapturedTelemetry retrieves the telemetry a sandbox exported to the capture sink
+func (ss *SandboxService) GetCapturedTelemetry(ctx context.Context, sandboxID, signal string, limit int) (*GetCapturedTelemetryResponse, error) {
+	if err := ss.ensureManager(); err != nil {
+		return nil, err
+	}
+
+	if sandboxID == "" {
+		return nil, fmt.Errorf("sandbox ID cannot be empty")
+	}
+
+	switch signal {
+	case "", otlpsink.SignalTraces, otlpsink.SignalMetrics, otlpsink.SignalLogs:
+	default:
+		return nil, fmt.Errorf("signal must be traces, metrics or logs")
+	}
+
+	telemetry, err := ss.manager.CapturedTelemetry(sandboxID, signal, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get captured telemetry: %w", err)
+	}
+
+	return &GetCapturedTelemetryResponse{
+		Success:   true,
+		Telemetry: telemetry,
+	}, nil
+}
+
+// ClearCapturedTelemetry discards the telemetry captured for a sandbox
+func (ss *SandboxService) ClearCapturedTelemetry(ctx context.Context, sandboxID string) (*ClearCapturedTelemetryResponse, error) {
+	if err := ss.ensureManager(); err != nil {
+		return nil, err
+	}
+
+	if sandboxID == "" {
+		return nil, fmt.Errorf("sandbox ID cannot be empty")
+	}
+
+	if err := ss.manager.ClearCapturedTelemetry(sandboxID); err != nil {
+		return nil, fmt.Errorf("failed to clear captured telemetry: %w", err)
+	}
+
+	return &ClearCapturedTelemetryResponse{
+		Success: true,
+		Message: "Captured telemetry cleared",
+	}, nil
+}
+
 // StopSandbox stops a sandbox
 func (ss *SandboxService) StopSandbox(ctx context.Context, sandboxID string) (*StopSandboxResponse, error) {
 	if err := ss.ensureManager(); err != nil {
@@ -1,6 +1,7 @@
 package sandbox
 
 import (
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
 	"github.com/mottibechhofer/otel-ai-engineer/sandbox"
 )
 
@@ -29,6 +30,18 @@ type ValidateSandboxResponse struct {
 	Validation *sandbox.ValidationResult `json:"validation"`
 }
 
+// CompareConfigsResponse represents the response for comparing collector configs
+type CompareConfigsResponse struct {
+	Success bool                      `json:"success"`
+	Report  *sandbox.ComparisonReport `json:"report"`
+}
+
+// ListValidationsResponse represents the response for listing a sandbox's validations
+type ListValidationsResponse struct {
+	Validations []*sandbox.ValidationResult `json:"validations"`
+	Count       int                         `json:"count"`
+}
+
 // GetSandboxLogsResponse represents the response for getting sandbox logs
 type GetSandboxLogsResponse struct {
 	Success bool     `json:"success"`
@@ -42,6 +55,18 @@ type GetSandboxMetricsResponse struct {
 	Metrics *sandbox.CollectorMetrics `json:"metrics"`
 }
 
+// GetCapturedTelemetryResponse represents the response for getting the telemetry a sandbox exported
+type GetCapturedTelemetryResponse struct {
+	Success   bool                `json:"success"`
+	Telemetry *otlpsink.Telemetry `json:"telemetry"`
+}
+
+// ClearCapturedTelemetryResponse represents the response for clearing captured telemetry
+type ClearCapturedTelemetryResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
 // StopSandboxResponse represents the response for stopping a sandbox
 type StopSandboxResponse struct {
 	Success bool   `json:"success"`
@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/mcpclient"
 	"github.com/mottibechhofer/otel-ai-engineer/otelclient"
 	"github.com/mottibechhofer/otel-ai-engineer/tools"
 	dc "github.com/mottibechhofer/otel-ai-engineer/tools/dockerclient"
@@ -24,6 +27,10 @@ type ToolDiscoveryService struct {
 	mu           sync.RWMutex
 	dockerClient *dc.Client
 	otelClient   *otelclient.OtelClient
+	mcpClients   []*mcpclient.Client
+
+	// Connection count of each MCP server when its tools were last listed
+	mcpConnections map[string]int64
 }
 
 // ToolInfo represents information about a tool for API responses
@@ -40,14 +47,16 @@ type ListToolsResponse struct {
 	ByCategory map[string][]ToolInfo `json:"by_category"`
 }
 
-// NewToolDiscoveryService creates a new tool discovery service
-func NewToolDiscoveryService(dockerClient *dc.Client, otelClient *otelclient.OtelClient) *ToolDiscoveryService {
+// NewToolDiscoveryService creates a new tool discovery service. Tools of the
+// given MCP servers are listed under the category "mcp:<server name>".
+func NewToolDiscoveryService(dockerClient *dc.Client, otelClient *otelclient.OtelClient, mcpClients []*mcpclient.Client) *ToolDiscoveryService {
 	service := &ToolDiscoveryService{
 		tools:        make(map[string]ToolInfo),
 		toolObjects:  make(map[string]tools.Tool),
 		categories:   make(map[string][]string),
 		dockerClient: dockerClient,
 		otelClient:   otelClient,
+		mcpClients:   mcpClients,
 	}
 
 	// Initialize tools
@@ -58,9 +67,14 @@ func NewToolDiscoveryService(dockerClient *dc.Client, otelClient *otelclient.Ote
 
 // refreshTools collects all tools from all packages
 func (s *ToolDiscoveryService) refreshTools() {
+	// List MCP tools before locking, since it calls out to the servers
+	mcpTools, mcpConnections := s.listMCPTools()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.mcpConnections = mcpConnections
+
 	// Clear existing
 	s.tools = make(map[string]ToolInfo)
 	s.toolObjects = make(map[string]tools.Tool)
@@ -87,6 +101,83 @@ func (s *ToolDiscoveryService) refreshTools() {
 		otelToolsList := otelTools.GetOtelTools(s.otelClient)
 		s.collectToolsFromList(otelToolsList, "otel")
 	}
+
+	// Collect tools of the configured MCP servers
+	serverNames := make([]string, 0, len(mcpTools))
+	for name := range mcpTools {
+		serverNames = append(serverNames, name)
+	}
+	sort.Strings(serverNames)
+	for _, name := range serverNames {
+		s.collectToolsFromList(mcpTools[name], "mcp:"+name)
+	}
+}
+
+// listMCPTools lists the tools of each MCP server by server name, with the
+// connection count each list was taken on. Servers that fail to answer are
+// skipped.
+func (s *ToolDiscoveryService) listMCPTools() (map[string][]tools.Tool, map[string]int64) {
+	result := make(map[string][]tools.Tool, len(s.mcpClients))
+	connections := make(map[string]int64, len(s.mcpClients))
+	for _, client := range s.mcpClients {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		toolList, err := client.Tools(ctx)
+		cancel()
+		if err != nil {
+			fmt.Printf("Warning: Failed to list tools of MCP server %s: %v\n", client.Name(), err)
+			continue
+		}
+		result[client.Name()] = toolList
+		connections[client.Name()] = client.Connections()
+	}
+	return result, connections
+}
+
+// WatchMCPServers reconnects MCP servers that went down or never came up,
+// and lists the tools again whenever a server was reconnected since its
+// tools were last listed. It checks every interval until ctx is done.
+func (s *ToolDiscoveryService) WatchMCPServers(ctx context.Context, interval time.Duration) {
+	if len(s.mcpClients) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if s.reconnectMCPServers(ctx) {
+			s.refreshTools()
+		}
+	}
+}
+
+// reconnectMCPServers connects each MCP server that is down and reports
+// whether any server's tools need to be listed again
+func (s *ToolDiscoveryService) reconnectMCPServers(ctx context.Context) bool {
+	s.mu.RLock()
+	listed := s.mcpConnections
+	s.mu.RUnlock()
+
+	changed := false
+	for _, client := range s.mcpClients {
+		connectCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		err := client.Connect(connectCtx)
+		cancel()
+		if err != nil {
+			continue
+		}
+		if client.Connections() != listed[client.Name()] {
+			fmt.Printf("MCP server %s reconnected, listing its tools again\n", client.Name())
+			changed = true
+		}
+	}
+	return changed
 }
 
 // collectToolsFromList adds tools from a list to the catalog
@@ -221,3 +312,35 @@ func (s *ToolDiscoveryService) GetToolsByName(toolNames []string) []tools.Tool {
 
 	return result
 }
+
+// GetAllowedTools returns the tools permitted by an allow list, sorted by name.
+// The allow list maps a category to the names of its tools that are allowed,
+// or "*" for all of them; the category "*" applies to every category, and
+// categories it does not name are excluded. A nil allow list allows nothing.
+func (s *ToolDiscoveryService) GetAllowedTools(allowList map[string][]string) []tools.Tool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []tools.Tool
+	for category, toolNames := range s.categories {
+		for _, toolName := range toolNames {
+			if !allows(allowList[category], toolName) && !allows(allowList["*"], toolName) {
+				continue
+			}
+			result = append(result, s.toolObjects[toolName])
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result
+}
+
+// allows reports whether an allow list entry permits a tool
+func allows(allowed []string, toolName string) bool {
+	for _, name := range allowed {
+		if name == "*" || name == toolName {
+			return true
+		}
+	}
+	return false
+}
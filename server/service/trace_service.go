@@ -200,6 +200,9 @@ func (s *TraceService) buildSpanTree(eventList []*events.AgentEvent) []*storage.
 			apiSpan.Tags["model"] = apiReqData.Model
 			apiSpan.Tags["max_tokens"] = apiReqData.MaxTokens
 			apiSpan.Tags["tool_count"] = apiReqData.ToolCount
+			if apiReqData.ThinkingBudget > 0 {
+				apiSpan.Tags["thinking_budget"] = apiReqData.ThinkingBudget
+			}
 
 			if currentIteration != nil {
 				apiSpan.ParentSpanID = &currentIteration.ID
@@ -230,6 +233,8 @@ func (s *TraceService) buildSpanTree(eventList []*events.AgentEvent) []*storage.
 					if apiRespData.Usage != nil {
 						apiSpan.Tags["input_tokens"] = apiRespData.Usage.InputTokens
 						apiSpan.Tags["output_tokens"] = apiRespData.Usage.OutputTokens
+						apiSpan.Tags["cache_creation_input_tokens"] = apiRespData.Usage.CacheCreationInputTokens
+						apiSpan.Tags["cache_read_input_tokens"] = apiRespData.Usage.CacheReadInputTokens
 					}
 					apiSpan.Tags["content_count"] = apiRespData.ContentCount
 
@@ -268,6 +273,126 @@ func (s *TraceService) buildSpanTree(eventList []*events.AgentEvent) []*storage.
 			pendingSpans[handoffData.SubRunID] = handoffSpan
 			spanMap[handoffSpan.ID] = handoffSpan
 
+		case events.EventAPIRetry:
+			// Retries and fallbacks happen inside an API call, record them as point spans
+			var retryData events.APIRetryData
+			if err := json.Unmarshal(event.Data, &retryData); err != nil {
+				continue
+			}
+
+			name := fmt.Sprintf("API Retry %d/%d (%s)", retryData.Attempt, retryData.MaxRetries, retryData.ErrorKind)
+			if retryData.FallbackModel != "" {
+				name = fmt.Sprintf("Model Fallback to %s (%s)", retryData.FallbackModel, retryData.ErrorKind)
+			}
+
+			retrySpan := &storage.Span{
+				ID:        fmt.Sprintf("retry-%s", event.ID),
+				Type:      storage.SpanTypeAPIRetry,
+				Name:      name,
+				StartTime: event.Timestamp,
+				EndTime:   &event.Timestamp,
+				Duration:  "0s",
+				Error:     true,
+				ErrorMsg:  retryData.Error,
+				Tags:      make(map[string]interface{}),
+			}
+
+			retrySpan.Tags["attempt"] = retryData.Attempt
+			retrySpan.Tags["model"] = retryData.Model
+			retrySpan.Tags["error_kind"] = retryData.ErrorKind
+			if retryData.StatusCode != 0 {
+				retrySpan.Tags["status_code"] = retryData.StatusCode
+			}
+			if retryData.Delay != "" {
+				retrySpan.Tags["delay"] = retryData.Delay
+			}
+			if retryData.FallbackModel != "" {
+				retrySpan.Tags["fallback_model"] = retryData.FallbackModel
+			}
+
+			if currentIteration != nil {
+				retrySpan.ParentSpanID = &currentIteration.ID
+				currentIteration.Children = append(currentIteration.Children, retrySpan)
+			} else {
+				spans = append(spans, retrySpan)
+			}
+
+			spanMap[retrySpan.ID] = retrySpan
+
+		case events.EventCompaction:
+			// Compaction happens instantly before an API call, record it as a point span
+			var compactionData events.CompactionData
+			if err := json.Unmarshal(event.Data, &compactionData); err != nil {
+				continue
+			}
+
+			compactionSpan := &storage.Span{
+				ID:        fmt.Sprintf("compaction-%s", event.ID),
+				Type:      storage.SpanTypeCompaction,
+				Name:      fmt.Sprintf("History Compaction (%s)", compactionData.Strategy),
+				StartTime: event.Timestamp,
+				EndTime:   &event.Timestamp,
+				Duration:  "0s",
+				Tags:      make(map[string]interface{}),
+			}
+
+			compactionSpan.Tags["strategy"] = compactionData.Strategy
+			compactionSpan.Tags["messages_before"] = compactionData.MessagesBefore
+			compactionSpan.Tags["messages_after"] = compactionData.MessagesAfter
+			compactionSpan.Tags["estimated_tokens_before"] = compactionData.EstimatedTokensBefore
+			compactionSpan.Tags["estimated_tokens_after"] = compactionData.EstimatedTokensAfter
+			compactionSpan.Tags["truncated_results"] = compactionData.TruncatedResults
+			compactionSpan.Tags["summarized_messages"] = compactionData.SummarizedMessages
+
+			if currentIteration != nil {
+				compactionSpan.ParentSpanID = &currentIteration.ID
+				currentIteration.Children = append(currentIteration.Children, compactionSpan)
+			} else {
+				spans = append(spans, compactionSpan)
+			}
+
+			spanMap[compactionSpan.ID] = compactionSpan
+
+		case events.EventMessage:
+			// Extended thinking blocks become point spans at the time the response arrived
+			var messageData events.MessageData
+			if err := json.Unmarshal(event.Data, &messageData); err != nil {
+				continue
+			}
+
+			for i, block := range messageData.Content {
+				if block.Thinking == nil {
+					continue
+				}
+
+				thinkingSpan := &storage.Span{
+					ID:        fmt.Sprintf("thinking-%s-%d", event.ID, i),
+					Type:      storage.SpanTypeThinking,
+					Name:      "Thinking",
+					StartTime: event.Timestamp,
+					EndTime:   &event.Timestamp,
+					Duration:  "0s",
+					Tags:      make(map[string]interface{}),
+				}
+
+				if block.Type == "redacted_thinking" {
+					thinkingSpan.Name = "Thinking (redacted)"
+					thinkingSpan.Tags["redacted"] = true
+				} else {
+					thinkingSpan.Tags["thinking"] = block.Thinking.Thinking
+					thinkingSpan.Tags["thinking_chars"] = len(block.Thinking.Thinking)
+				}
+
+				if currentIteration != nil {
+					thinkingSpan.ParentSpanID = &currentIteration.ID
+					currentIteration.Children = append(currentIteration.Children, thinkingSpan)
+				} else {
+					spans = append(spans, thinkingSpan)
+				}
+
+				spanMap[thinkingSpan.ID] = thinkingSpan
+			}
+
 		case events.EventAgentHandoffComplete:
 			// Complete handoff span
 			var handoffCompleteData events.HandoffCompleteData
@@ -287,6 +412,9 @@ func (s *TraceService) buildSpanTree(eventList []*events.AgentEvent) []*storage.
 				}
 
 				handoffSpan.Tags["summary"] = handoffCompleteData.Summary
+				if handoffCompleteData.Report != nil {
+					handoffSpan.Tags["report"] = handoffCompleteData.Report
+				}
 
 				delete(pendingSpans, handoffCompleteData.SubRunID)
 			}
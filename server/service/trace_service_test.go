@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
+)
+
+func TestBuildSpanTreeThinking(t *testing.T) {
+	iteration, err := events.NewIterationEvent("run-1", "agent", "agent", events.IterationData{Iteration: 1})
+	if err != nil {
+		t.Fatalf("NewIterationEvent() error = %v", err)
+	}
+	apiRequest, err := events.NewAPIRequestEvent("run-1", "agent", "agent", events.APIRequestData{
+		Model:          "claude-sonnet-4-5",
+		MaxTokens:      6096,
+		ThinkingBudget: 2000,
+	})
+	if err != nil {
+		t.Fatalf("NewAPIRequestEvent() error = %v", err)
+	}
+	message, err := events.NewMessageEvent("run-1", "agent", "agent", events.MessageData{
+		Role: "assistant",
+		Content: []events.ContentBlock{
+			{Type: "thinking", Thinking: &events.ThinkingInfo{Thinking: "check the logs", Signature: "sig_1"}},
+			{Type: "redacted_thinking", Thinking: &events.ThinkingInfo{Data: "opaque_1"}},
+			{Type: "text", Text: "Done."},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewMessageEvent() error = %v", err)
+	}
+
+	spans := NewTraceService(nil).buildSpanTree([]*events.AgentEvent{iteration, apiRequest, message})
+	if len(spans) != 1 || spans[0].Type != storage.SpanTypeIteration {
+		t.Fatalf("got %d top-level spans, want one iteration", len(spans))
+	}
+	children := spans[0].Children
+	if len(children) != 3 {
+		t.Fatalf("iteration has %d children, want 3", len(children))
+	}
+
+	if got := children[0].Tags["thinking_budget"]; got != int64(2000) {
+		t.Errorf("api call thinking_budget tag = %v, want 2000", got)
+	}
+
+	thinking := children[1]
+	if thinking.Type != storage.SpanTypeThinking || thinking.ID != "thinking-"+message.ID+"-0" {
+		t.Errorf("thinking span = %s %s", thinking.Type, thinking.ID)
+	}
+	if thinking.Tags["thinking"] != "check the logs" || thinking.Tags["thinking_chars"] != len("check the logs") {
+		t.Errorf("thinking span tags = %v", thinking.Tags)
+	}
+	if thinking.ParentSpanID == nil || *thinking.ParentSpanID != spans[0].ID {
+		t.Error("thinking span is not parented to the iteration")
+	}
+
+	redacted := children[2]
+	if redacted.Name != "Thinking (redacted)" || redacted.Tags["redacted"] != true {
+		t.Errorf("redacted span = %s %v", redacted.Name, redacted.Tags)
+	}
+	if _, ok := redacted.Tags["thinking"]; ok {
+		t.Error("redacted span exposes thinking text")
+	}
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/mottibechhofer/otel-ai-engineer/agent/events"
@@ -19,30 +20,35 @@ const (
 
 // Run represents a single agent execution
 type Run struct {
-	ID              string               `json:"id"`
-	AgentID         string               `json:"agent_id"`
-	AgentName       string               `json:"agent_name"`
-	Status          RunStatus            `json:"status"`
-	Prompt          string               `json:"prompt"`
-	Model           string               `json:"model"`
-	StartTime       time.Time            `json:"start_time"`
-	EndTime         *time.Time           `json:"end_time,omitempty"`
-	Duration        string               `json:"duration,omitempty"`
-	TotalIterations int                  `json:"total_iterations"`
-	TotalToolCalls  int                  `json:"total_tool_calls"`
-	TotalTokens     TokenUsage           `json:"total_tokens"`
-	Error           string               `json:"error,omitempty"`
-	ParentRunID     *string              `json:"parent_run_id,omitempty"`
-	SubRunIDs       []string             `json:"sub_run_ids,omitempty"`
-	IsHandoff       bool                 `json:"is_handoff"`
-	Events          []*events.AgentEvent `json:"-"` // Not serialized, fetched separately
+	ID              string                `json:"id"`
+	AgentID         string                `json:"agent_id"`
+	AgentName       string                `json:"agent_name"`
+	Status          RunStatus             `json:"status"`
+	Prompt          string                `json:"prompt"`
+	Model           string                `json:"model"`
+	StartTime       time.Time             `json:"start_time"`
+	EndTime         *time.Time            `json:"end_time,omitempty"`
+	Duration        string                `json:"duration,omitempty"`
+	TotalIterations int                   `json:"total_iterations"`
+	TotalToolCalls  int                   `json:"total_tool_calls"`
+	TotalTokens     TokenUsage            `json:"total_tokens"`
+	Error           string                `json:"error,omitempty"`
+	ParentRunID     *string               `json:"parent_run_id,omitempty"`
+	SubRunIDs       []string              `json:"sub_run_ids,omitempty"`
+	IsHandoff       bool                  `json:"is_handoff"`
+	HandoffReport   *events.HandoffReport `json:"handoff_report,omitempty"` // Structured result of a handoff sub-run
+	Events          []*events.AgentEvent  `json:"-"`                        // Not serialized, fetched separately
 }
 
 // TokenUsage tracks token consumption
+// InputTokens excludes cached tokens, which are tracked separately;
+// TotalTokens counts all of them.
 type TokenUsage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
-	TotalTokens  int `json:"total_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	TotalTokens              int `json:"total_tokens"`
 }
 
 // RunUpdate contains fields that can be updated on a run
@@ -58,6 +64,7 @@ type RunUpdate struct {
 	ParentRunID     *string
 	SubRunIDs       *[]string
 	IsHandoff       *bool
+	HandoffReport   *events.HandoffReport
 }
 
 // RunListOptions contains options for listing runs
@@ -68,6 +75,31 @@ type RunListOptions struct {
 	Since  *time.Time
 }
 
+// RunCheckpoint is a snapshot of a run's conversation, saved every iteration
+// so a resumed run continues with its full assistant and tool history
+type RunCheckpoint struct {
+	ID        int64           `json:"id"`
+	RunID     string          `json:"run_id"`
+	Iteration int             `json:"iteration"`
+	Messages  json.RawMessage `json:"messages"` // JSON array of anthropic.MessageParam
+
+	// Budget and BudgetUsage let a resumed run continue against the same
+	// budget instead of starting over. Both are nil for unbudgeted runs.
+	Budget      *RunBudget   `json:"budget,omitempty"`
+	BudgetUsage *BudgetUsage `json:"budget_usage,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BudgetUsage is what a run, including its handoff sub-runs, has charged
+// against its budget
+type BudgetUsage struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	Elapsed      string  `json:"elapsed"` // Wall-clock time counted against MaxDuration, e.g. "4m10s"
+}
+
 // ApplyUpdate applies an update to a run
 func (r *Run) ApplyUpdate(update *RunUpdate) {
 	if update.Status != nil {
@@ -102,6 +134,9 @@ func (r *Run) ApplyUpdate(update *RunUpdate) {
 	if update.IsHandoff != nil {
 		r.IsHandoff = *update.IsHandoff
 	}
+	if update.HandoffReport != nil {
+		r.HandoffReport = update.HandoffReport
+	}
 }
 
 // CalculateDuration calculates and sets the duration field
@@ -120,6 +155,9 @@ const (
 	SpanTypeAPICall        SpanType = "api_call"
 	SpanTypeAgentHandoff   SpanType = "agent_handoff"
 	SpanTypeIteration      SpanType = "iteration"
+	SpanTypeCompaction     SpanType = "compaction"
+	SpanTypeAPIRetry       SpanType = "api_retry"
+	SpanTypeThinking       SpanType = "thinking"
 	SpanTypeTrace          SpanType = "trace"
 )
 
@@ -324,6 +362,7 @@ const (
 	HumanActionStatusResponded HumanActionStatus = "responded"
 	HumanActionStatusResumed   HumanActionStatus = "resumed"
 	HumanActionStatusCancelled HumanActionStatus = "cancelled"
+	HumanActionStatusExpired   HumanActionStatus = "expired" // Timed out; Response holds the default answer, if any
 )
 
 // HumanAction represents a request from an agent for human intervention
@@ -339,6 +378,9 @@ type HumanAction struct {
 	Question        string             `json:"question"`     // The question or request for the human
 	Context         string             `json:"context"`      // Additional context about the request
 	Options         []string           `json:"options,omitempty"` // Optional: predefined options/choices
+	ToolUseID       string             `json:"tool_use_id,omitempty"` // request_human_input call awaiting the response
+	DefaultResponse *string            `json:"default_response,omitempty"` // Answer used if no human responds in time
+	ExpiresAt       *time.Time         `json:"expires_at,omitempty"`
 	Status          HumanActionStatus  `json:"status"`
 	Response        *string            `json:"response,omitempty"` // Human's response
 	RespondedAt     *time.Time         `json:"responded_at,omitempty"`
@@ -353,6 +395,7 @@ type HumanActionUpdate struct {
 	Response    *string
 	RespondedAt *time.Time
 	ResumedAt   *time.Time
+	IfStatus    *HumanActionStatus // Only update if the action still has this status
 }
 
 // HumanActionListOptions contains options for listing human actions
@@ -366,23 +409,66 @@ type HumanActionListOptions struct {
 
 // CustomAgent represents a user-created agent with custom tool configuration
 type CustomAgent struct {
-	ID           string    `json:"id"`
-	Name         string    `json:"name"`
-	Description  string    `json:"description"`
-	SystemPrompt string    `json:"system_prompt,omitempty"`
-	Model        string    `json:"model,omitempty"`
-	MaxTokens    int64     `json:"max_tokens,omitempty"`
-	ToolNames    []string  `json:"tool_names"` // Array of tool name strings
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Description    string          `json:"description"`
+	SystemPrompt   string          `json:"system_prompt,omitempty"`
+	Model          string          `json:"model,omitempty"`
+	MaxTokens      int64           `json:"max_tokens,omitempty"`
+	ThinkingBudget int64           `json:"thinking_budget,omitempty"` // Extended thinking tokens, 0 disables
+	ToolNames      []string        `json:"tool_names"`                // Array of tool name strings
+	Budget         *RunBudget      `json:"budget,omitempty"`
+	Provider       *ProviderConfig `json:"provider,omitempty"` // nil uses the server's default provider
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// RunBudget limits what a run may consume. Zero values are unlimited.
+type RunBudget struct {
+	MaxInputTokens  int     `json:"max_input_tokens,omitempty" yaml:"max_input_tokens"`
+	MaxOutputTokens int     `json:"max_output_tokens,omitempty" yaml:"max_output_tokens"`
+	MaxCostUSD      float64 `json:"max_cost_usd,omitempty" yaml:"max_cost_usd"`
+	MaxDuration     string  `json:"max_duration,omitempty" yaml:"max_duration"` // Go duration, e.g. "30m"
+	MaxIterations   int     `json:"max_iterations,omitempty" yaml:"max_iterations"`
+}
+
+// ProviderConfig selects the LLM backend for an agent by the name of a
+// provider the operator configured (see config.NamedProvider)
+type ProviderConfig struct {
+	Name  string `json:"name"`            // Operator-defined provider name
+	Model string `json:"model,omitempty"` // Overrides the model name sent to an openai provider
 }
 
 // CustomAgentUpdate contains fields that can be updated on a custom agent
 type CustomAgentUpdate struct {
-	Name         *string
-	Description  *string
-	SystemPrompt *string
-	Model        *string
-	MaxTokens    *int64
-	ToolNames    *[]string
+	Name           *string
+	Description    *string
+	SystemPrompt   *string
+	Model          *string
+	MaxTokens      *int64
+	ThinkingBudget *int64 // 0 disables extended thinking
+	ToolNames      *[]string
+	Budget         *RunBudget      // An empty budget clears it
+	Provider       *ProviderConfig // An empty config reverts to the default provider
+}
+
+// SandboxRecord is a persisted sandbox. The sandbox itself is stored as JSON
+// so storage does not depend on the sandbox package.
+type SandboxRecord struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Status    string          `json:"status"`
+	Data      json.RawMessage `json:"data"` // JSON sandbox.Sandbox
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// SandboxValidationRecord is one persisted validation of a sandbox
+type SandboxValidationRecord struct {
+	ID          string          `json:"id"`
+	SandboxID   string          `json:"sandbox_id"`
+	Status      string          `json:"status"`
+	Data        json.RawMessage `json:"data"` // JSON sandbox.ValidationResult
+	StartedAt   time.Time       `json:"started_at"`
+	CompletedAt time.Time       `json:"completed_at"`
 }
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// initSandboxSchema creates tables for sandboxes and their validation history
+func (s *SQLiteStorage) initSandboxSchema() error {
+	sandboxTable := `
+	CREATE TABLE IF NOT EXISTS sandboxes (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		status TEXT NOT NULL,
+		data TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);`
+
+	validationTable := `
+	CREATE TABLE IF NOT EXISTS sandbox_validations (
+		id TEXT PRIMARY KEY,
+		sandbox_id TEXT NOT NULL,
+		status TEXT NOT NULL,
+		data TEXT NOT NULL,
+		started_at TIMESTAMP NOT NULL,
+		completed_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (sandbox_id) REFERENCES sandboxes(id) ON DELETE CASCADE
+	);`
+
+	if _, err := s.db.Exec(sandboxTable); err != nil {
+		return fmt.Errorf("failed to create sandboxes table: %w", err)
+	}
+	if _, err := s.db.Exec(validationTable); err != nil {
+		return fmt.Errorf("failed to create sandbox_validations table: %w", err)
+	}
+
+	if _, err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_sandbox_validations_sandbox_id ON sandbox_validations(sandbox_id, completed_at);"); err != nil {
+		return fmt.Errorf("failed to create sandbox_validations index: %w", err)
+	}
+
+	return nil
+}
+
+// SaveSandbox creates or replaces a sandbox record
+func (s *SQLiteStorage) SaveSandbox(record *SandboxRecord) error {
+	if record == nil {
+		return fmt.Errorf("sandbox record cannot be nil")
+	}
+	if record.ID == "" {
+		return fmt.Errorf("sandbox ID cannot be empty")
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	if record.UpdatedAt.IsZero() {
+		record.UpdatedAt = record.CreatedAt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		INSERT INTO sandboxes (id, name, status, data, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			status = excluded.status,
+			data = excluded.data,
+			updated_at = excluded.updated_at
+	`, record.ID, record.Name, record.Status, string(record.Data), record.CreatedAt, record.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save sandbox: %w", err)
+	}
+
+	return nil
+}
+
+// GetSandbox retrieves a sandbox record by ID
+func (s *SQLiteStorage) GetSandbox(sandboxID string) (*SandboxRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var record SandboxRecord
+	var data string
+	err := s.db.QueryRow(`
+		SELECT id, name, status, data, created_at, updated_at
+		FROM sandboxes WHERE id = ?`, sandboxID).
+		Scan(&record.ID, &record.Name, &record.Status, &data, &record.CreatedAt, &record.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("sandbox not found")
+		}
+		return nil, fmt.Errorf("failed to get sandbox: %w", err)
+	}
+	record.Data = []byte(data)
+
+	return &record, nil
+}
+
+// ListSandboxes retrieves all sandbox records, oldest first
+func (s *SQLiteStorage) ListSandboxes() ([]*SandboxRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+		SELECT id, name, status, data, created_at, updated_at
+		FROM sandboxes ORDER BY created_at ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sandboxes: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*SandboxRecord{}
+	for rows.Next() {
+		var record SandboxRecord
+		var data string
+		if err := rows.Scan(&record.ID, &record.Name, &record.Status, &data, &record.CreatedAt, &record.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sandbox: %w", err)
+		}
+		record.Data = []byte(data)
+		records = append(records, &record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sandboxes: %w", err)
+	}
+
+	return records, nil
+}
+
+// DeleteSandbox deletes a sandbox record and its validation history
+func (s *SQLiteStorage) DeleteSandbox(sandboxID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.Exec("DELETE FROM sandbox_validations WHERE sandbox_id = ?", sandboxID); err != nil {
+		return fmt.Errorf("failed to delete sandbox validations: %w", err)
+	}
+	if _, err := s.db.Exec("DELETE FROM sandboxes WHERE id = ?", sandboxID); err != nil {
+		return fmt.Errorf("failed to delete sandbox: %w", err)
+	}
+
+	return nil
+}
+
+// AddSandboxValidation records a validation of a sandbox
+func (s *SQLiteStorage) AddSandboxValidation(record *SandboxValidationRecord) error {
+	if record == nil {
+		return fmt.Errorf("validation record cannot be nil")
+	}
+	if record.ID == "" || record.SandboxID == "" {
+		return fmt.Errorf("validation ID and sandbox ID cannot be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		INSERT INTO sandbox_validations (id, sandbox_id, status, data, started_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, record.ID, record.SandboxID, record.Status, string(record.Data), record.StartedAt, record.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add sandbox validation: %w", err)
+	}
+
+	return nil
+}
+
+// ListSandboxValidations returns up to limit validations of a sandbox, newest
+// first. A limit of 0 returns them all.
+func (s *SQLiteStorage) ListSandboxValidations(sandboxID string, limit int) ([]*SandboxValidationRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := `
+		SELECT id, sandbox_id, status, data, started_at, completed_at
+		FROM sandbox_validations WHERE sandbox_id = ?
+		ORDER BY completed_at DESC`
+	args := []interface{}{sandboxID}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sandbox validations: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*SandboxValidationRecord{}
+	for rows.Next() {
+		var record SandboxValidationRecord
+		var data string
+		if err := rows.Scan(&record.ID, &record.SandboxID, &record.Status, &data, &record.StartedAt, &record.CompletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sandbox validation: %w", err)
+		}
+		record.Data = []byte(data)
+		records = append(records, &record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sandbox validations: %w", err)
+	}
+
+	return records, nil
+}
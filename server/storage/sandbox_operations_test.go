@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStorage(t *testing.T) *SQLiteStorage {
+	t.Helper()
+	stor, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { stor.Close() })
+	return stor
+}
+
+func TestSaveSandbox(t *testing.T) {
+	stor := newTestStorage(t)
+	created := time.Now().Add(-time.Hour)
+
+	if err := stor.SaveSandbox(&SandboxRecord{ID: "sb-1", Name: "first", Status: "creating", Data: []byte(`{"id":"sb-1"}`), CreatedAt: created}); err != nil {
+		t.Fatalf("SaveSandbox() error = %v", err)
+	}
+	if err := stor.SaveSandbox(&SandboxRecord{ID: "sb-2", Name: "second", Status: "running", Data: []byte(`{"id":"sb-2"}`)}); err != nil {
+		t.Fatalf("SaveSandbox() error = %v", err)
+	}
+
+	// Saving again updates the record but keeps when it was created
+	if err := stor.SaveSandbox(&SandboxRecord{ID: "sb-1", Name: "renamed", Status: "running", Data: []byte(`{"id":"sb-1","status":"running"}`)}); err != nil {
+		t.Fatalf("SaveSandbox() error = %v", err)
+	}
+
+	record, err := stor.GetSandbox("sb-1")
+	if err != nil {
+		t.Fatalf("GetSandbox() error = %v", err)
+	}
+	if record.Name != "renamed" || record.Status != "running" || string(record.Data) != `{"id":"sb-1","status":"running"}` {
+		t.Errorf("GetSandbox() = %+v, want the updated record", record)
+	}
+	if record.CreatedAt.Unix() != created.Unix() {
+		t.Errorf("created at = %v, want %v", record.CreatedAt, created)
+	}
+
+	records, err := stor.ListSandboxes()
+	if err != nil {
+		t.Fatalf("ListSandboxes() error = %v", err)
+	}
+	if len(records) != 2 || records[0].ID != "sb-1" || records[1].ID != "sb-2" {
+		t.Errorf("ListSandboxes() = %+v, want sb-1 then sb-2", records)
+	}
+
+	if _, err := stor.GetSandbox("sb-unknown"); err == nil {
+		t.Error("GetSandbox() of an unknown sandbox succeeded")
+	}
+	if err := stor.SaveSandbox(&SandboxRecord{Name: "no id"}); err == nil {
+		t.Error("SaveSandbox() accepted a record without an ID")
+	}
+}
+
+func TestSandboxValidations(t *testing.T) {
+	stor := newTestStorage(t)
+	if err := stor.SaveSandbox(&SandboxRecord{ID: "sb-1", Name: "first", Status: "running", Data: []byte(`{}`)}); err != nil {
+		t.Fatalf("SaveSandbox() error = %v", err)
+	}
+
+	start := time.Now()
+	for i, id := range []string{"v-1", "v-2", "v-3"} {
+		completed := start.Add(time.Duration(i) * time.Minute)
+		if err := stor.AddSandboxValidation(&SandboxValidationRecord{
+			ID: id, SandboxID: "sb-1", Status: "passed", Data: []byte(`{"id":"` + id + `"}`), StartedAt: completed, CompletedAt: completed,
+		}); err != nil {
+			t.Fatalf("AddSandboxValidation() error = %v", err)
+		}
+	}
+
+	records, err := stor.ListSandboxValidations("sb-1", 0)
+	if err != nil {
+		t.Fatalf("ListSandboxValidations() error = %v", err)
+	}
+	if len(records) != 3 || records[0].ID != "v-3" || records[2].ID != "v-1" || string(records[0].Data) != `{"id":"v-3"}` {
+		t.Errorf("ListSandboxValidations() = %+v, want v-3 to v-1", records)
+	}
+	if records, _ := stor.ListSandboxValidations("sb-1", 2); len(records) != 2 || records[0].ID != "v-3" {
+		t.Errorf("ListSandboxValidations(limit 2) = %+v, want the two newest", records)
+	}
+
+	if err := stor.AddSandboxValidation(&SandboxValidationRecord{ID: "v-4"}); err == nil {
+		t.Error("AddSandboxValidation() accepted a record without a sandbox ID")
+	}
+
+	// Deleting a sandbox deletes its validations
+	if err := stor.DeleteSandbox("sb-1"); err != nil {
+		t.Fatalf("DeleteSandbox() error = %v", err)
+	}
+	if _, err := stor.GetSandbox("sb-1"); err == nil {
+		t.Error("GetSandbox() found a deleted sandbox")
+	}
+	if records, _ := stor.ListSandboxValidations("sb-1", 0); len(records) != 0 {
+		t.Errorf("deleted sandbox still has %d validations", len(records))
+	}
+}
@@ -130,6 +130,21 @@ func (s *SQLiteStorage) initSchema() error {
 		return fmt.Errorf("failed to initialize custom agent schema: %w", err)
 	}
 
+	// Create conversation checkpoint tables
+	if err := s.initCheckpointSchema(); err != nil {
+		return fmt.Errorf("failed to initialize checkpoint schema: %w", err)
+	}
+
+	// Create run tool scope tables
+	if err := s.initToolScopeSchema(); err != nil {
+		return fmt.Errorf("failed to initialize tool scope schema: %w", err)
+	}
+
+	// Create sandbox tables
+	if err := s.initSandboxSchema(); err != nil {
+		return fmt.Errorf("failed to initialize sandbox schema: %w", err)
+	}
+
 	return nil
 }
 
@@ -319,6 +334,9 @@ func (s *SQLiteStorage) initHumanActionSchema() error {
 		question TEXT NOT NULL,
 		context TEXT NOT NULL,
 		options TEXT,
+		tool_use_id TEXT,
+		default_response TEXT,
+		expires_at TIMESTAMP,
 		status TEXT NOT NULL,
 		response TEXT,
 		responded_at TIMESTAMP,
@@ -345,6 +363,68 @@ func (s *SQLiteStorage) initHumanActionSchema() error {
 		}
 	}
 
+	// Add columns for blocking human input requests to existing tables
+	for _, column := range []struct{ name, ddl string }{
+		{"tool_use_id", "ALTER TABLE human_actions ADD COLUMN tool_use_id TEXT;"},
+		{"default_response", "ALTER TABLE human_actions ADD COLUMN default_response TEXT;"},
+		{"expires_at", "ALTER TABLE human_actions ADD COLUMN expires_at TIMESTAMP;"},
+	} {
+		var exists bool
+		err := s.db.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM pragma_table_info('human_actions') WHERE name = ?
+			)
+		`, column.name).Scan(&exists)
+
+		if err == nil && !exists {
+			if _, err := s.db.Exec(column.ddl); err != nil {
+				return fmt.Errorf("failed to add %s column: %w", column.name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// initCheckpointSchema creates the table for per-iteration conversation checkpoints
+func (s *SQLiteStorage) initCheckpointSchema() error {
+	checkpointTable := `
+	CREATE TABLE IF NOT EXISTS run_checkpoints (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		run_id TEXT NOT NULL,
+		iteration INTEGER NOT NULL,
+		messages TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (run_id) REFERENCES runs(id) ON DELETE CASCADE
+	);`
+
+	if _, err := s.db.Exec(checkpointTable); err != nil {
+		return fmt.Errorf("failed to create run_checkpoints table: %w", err)
+	}
+
+	if _, err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_run_checkpoints_run_id ON run_checkpoints(run_id, id);"); err != nil {
+		return fmt.Errorf("failed to create run_checkpoints index: %w", err)
+	}
+
+	// Add budget columns so resumed runs keep their budget
+	for _, column := range []struct{ name, ddl string }{
+		{"budget", "ALTER TABLE run_checkpoints ADD COLUMN budget TEXT;"},
+		{"budget_usage", "ALTER TABLE run_checkpoints ADD COLUMN budget_usage TEXT;"},
+	} {
+		var exists bool
+		err := s.db.QueryRow(`
+			SELECT EXISTS (
+				SELECT 1 FROM pragma_table_info('run_checkpoints') WHERE name = ?
+			)
+		`, column.name).Scan(&exists)
+
+		if err == nil && !exists {
+			if _, err := s.db.Exec(column.ddl); err != nil {
+				return fmt.Errorf("failed to add %s column: %w", column.name, err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -378,6 +458,22 @@ func (s *SQLiteStorage) migrateSchema() error {
 		}
 	}
 
+	// Add handoff_report column for structured handoff results
+	var handoffReportExists bool
+	err = s.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM pragma_table_info('runs') WHERE name = 'handoff_report'
+		)
+	`).Scan(&handoffReportExists)
+	if err != nil {
+		return fmt.Errorf("failed to check column existence: %w", err)
+	}
+	if !handoffReportExists {
+		if _, err := s.db.Exec("ALTER TABLE runs ADD COLUMN handoff_report TEXT;"); err != nil {
+			return fmt.Errorf("failed to run migration: %w", err)
+		}
+	}
+
 	// Check if backends table exists and if plan_id is NOT NULL (needs migration)
 	var planIDNotNullable bool
 	var backendsTableExists bool
@@ -516,13 +612,22 @@ func (s *SQLiteStorage) CreateRun(run *Run) error {
 		}
 	}
 
+	var handoffReportJSON sql.NullString
+	if run.HandoffReport != nil {
+		handoffReportJSONBytes, err := json.Marshal(run.HandoffReport)
+		if err == nil {
+			handoffReportJSON.String = string(handoffReportJSONBytes)
+			handoffReportJSON.Valid = true
+		}
+	}
+
 	_, err = s.db.Exec(
 		`INSERT INTO runs (id, agent_id, agent_name, status, prompt, model, start_time, end_time, duration, 
-			total_iterations, total_tool_calls, total_tokens, error, parent_run_id, sub_run_ids, is_handoff) 
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			total_iterations, total_tool_calls, total_tokens, error, parent_run_id, sub_run_ids, is_handoff, handoff_report) 
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		run.ID, run.AgentID, run.AgentName, run.Status, run.Prompt, run.Model, 
 		run.StartTime, endTime, duration, run.TotalIterations, run.TotalToolCalls, 
-		tokenUsageJSON, run.Error, parentRunID, subRunIDsJSON, run.IsHandoff)
+		tokenUsageJSON, run.Error, parentRunID, subRunIDsJSON, run.IsHandoff, handoffReportJSON)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert run: %w", err)
@@ -538,7 +643,7 @@ func (s *SQLiteStorage) GetRun(runID string) (*Run, error) {
 
 	row := s.db.QueryRow(
 		`SELECT id, agent_id, agent_name, status, prompt, model, start_time, end_time, duration,
-			total_iterations, total_tool_calls, total_tokens, error, parent_run_id, sub_run_ids, is_handoff
+			total_iterations, total_tool_calls, total_tokens, error, parent_run_id, sub_run_ids, is_handoff, handoff_report
 		 FROM runs WHERE id = ?`,
 		runID)
 
@@ -548,11 +653,12 @@ func (s *SQLiteStorage) GetRun(runID string) (*Run, error) {
 	var tokenUsageJSON string
 	var parentRunID sql.NullString
 	var subRunIDsJSON sql.NullString
+	var handoffReportJSON sql.NullString
 
 	err := row.Scan(
 		&run.ID, &run.AgentID, &run.AgentName, &run.Status, &run.Prompt, &run.Model,
 		&run.StartTime, &endTime, &duration, &run.TotalIterations, &run.TotalToolCalls,
-		&tokenUsageJSON, &run.Error, &parentRunID, &subRunIDsJSON, &run.IsHandoff)
+		&tokenUsageJSON, &run.Error, &parentRunID, &subRunIDsJSON, &run.IsHandoff, &handoffReportJSON)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("run with ID %s not found", runID)
@@ -584,6 +690,13 @@ func (s *SQLiteStorage) GetRun(runID string) (*Run, error) {
 		}
 	}
 
+	// Unmarshal handoff_report
+	if handoffReportJSON.Valid && handoffReportJSON.String != "" {
+		if err := json.Unmarshal([]byte(handoffReportJSON.String), &run.HandoffReport); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal handoff report: %w", err)
+		}
+	}
+
 	return &run, nil
 }
 
@@ -737,6 +850,14 @@ func (s *SQLiteStorage) UpdateRun(runID string, update *RunUpdate) error {
 		updates = append(updates, "is_handoff = ?")
 		args = append(args, *update.IsHandoff)
 	}
+	if update.HandoffReport != nil {
+		handoffReportJSON, err := json.Marshal(update.HandoffReport)
+		if err != nil {
+			return fmt.Errorf("failed to marshal handoff report: %w", err)
+		}
+		updates = append(updates, "handoff_report = ?")
+		args = append(args, string(handoffReportJSON))
+	}
 
 	if len(updates) == 0 {
 		return nil // Nothing to update
@@ -764,7 +885,11 @@ func (s *SQLiteStorage) DeleteRun(runID string) error {
 		return fmt.Errorf("failed to delete run: %w", err)
 	}
 
-	// Events are automatically deleted due to CASCADE
+	// Events and checkpoints are automatically deleted due to CASCADE; tool
+	// scopes don't reference the run
+	if _, err := s.db.Exec("DELETE FROM run_tool_scopes WHERE run_id = ?", runID); err != nil {
+		return fmt.Errorf("failed to delete run tool scope: %w", err)
+	}
 
 	return nil
 }
@@ -882,13 +1007,187 @@ func (s *SQLiteStorage) GetEventCount(runID string) (int, error) {
 	return count, nil
 }
 
+// checkpointsKept is how many checkpoints are kept per run. Older ones are
+// pruned; a few are kept in case the newest cannot be decoded.
+const checkpointsKept = 5
+
+// SaveCheckpoint stores a conversation checkpoint and prunes older ones for the run
+func (s *SQLiteStorage) SaveCheckpoint(checkpoint *RunCheckpoint) error {
+	if checkpoint == nil {
+		return fmt.Errorf("checkpoint cannot be nil")
+	}
+	if checkpoint.RunID == "" {
+		return fmt.Errorf("run ID cannot be empty")
+	}
+	if checkpoint.CreatedAt.IsZero() {
+		checkpoint.CreatedAt = time.Now()
+	}
+
+	var budgetJSON, budgetUsageJSON sql.NullString
+	if checkpoint.Budget != nil {
+		data, err := json.Marshal(checkpoint.Budget)
+		if err != nil {
+			return fmt.Errorf("failed to marshal budget: %w", err)
+		}
+		budgetJSON = sql.NullString{String: string(data), Valid: true}
+	}
+	if checkpoint.BudgetUsage != nil {
+		data, err := json.Marshal(checkpoint.BudgetUsage)
+		if err != nil {
+			return fmt.Errorf("failed to marshal budget usage: %w", err)
+		}
+		budgetUsageJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.Exec(`
+		INSERT INTO run_checkpoints (run_id, iteration, messages, budget, budget_usage, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, checkpoint.RunID, checkpoint.Iteration, string(checkpoint.Messages), budgetJSON, budgetUsageJSON, checkpoint.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		checkpoint.ID = id
+	}
+
+	_, err = s.db.Exec(`
+		DELETE FROM run_checkpoints
+		WHERE run_id = ? AND id NOT IN (
+			SELECT id FROM run_checkpoints WHERE run_id = ? ORDER BY id DESC LIMIT ?
+		)
+	`, checkpoint.RunID, checkpoint.RunID, checkpointsKept)
+	if err != nil {
+		return fmt.Errorf("failed to prune checkpoints: %w", err)
+	}
+
+	return nil
+}
+
+// GetLatestCheckpoints returns up to limit checkpoints for a run, newest first
+func (s *SQLiteStorage) GetLatestCheckpoints(runID string, limit int) ([]*RunCheckpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if limit <= 0 {
+		limit = checkpointsKept
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, run_id, iteration, messages, budget, budget_usage, created_at
+		FROM run_checkpoints WHERE run_id = ?
+		ORDER BY id DESC LIMIT ?
+	`, runID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	checkpoints := []*RunCheckpoint{}
+	for rows.Next() {
+		var checkpoint RunCheckpoint
+		var messages string
+		var budgetJSON, budgetUsageJSON sql.NullString
+		if err := rows.Scan(&checkpoint.ID, &checkpoint.RunID, &checkpoint.Iteration, &messages, &budgetJSON, &budgetUsageJSON, &checkpoint.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint: %w", err)
+		}
+		checkpoint.Messages = json.RawMessage(messages)
+		if budgetJSON.Valid && budgetJSON.String != "" {
+			if err := json.Unmarshal([]byte(budgetJSON.String), &checkpoint.Budget); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal budget: %w", err)
+			}
+		}
+		if budgetUsageJSON.Valid && budgetUsageJSON.String != "" {
+			if err := json.Unmarshal([]byte(budgetUsageJSON.String), &checkpoint.BudgetUsage); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal budget usage: %w", err)
+			}
+		}
+		checkpoints = append(checkpoints, &checkpoint)
+	}
+
+	return checkpoints, rows.Err()
+}
+
+// initToolScopeSchema creates the table for run tool scopes. Scopes are set
+// before their run is created, so they don't reference the runs table.
+func (s *SQLiteStorage) initToolScopeSchema() error {
+	toolScopeTable := `
+	CREATE TABLE IF NOT EXISTS run_tool_scopes (
+		run_id TEXT PRIMARY KEY,
+		tool_names TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	if _, err := s.db.Exec(toolScopeTable); err != nil {
+		return fmt.Errorf("failed to create run_tool_scopes table: %w", err)
+	}
+
+	return nil
+}
+
+// SetRunToolScope stores the tools a run may give the agent it designs, or
+// removes its scope if toolNames is nil
+func (s *SQLiteStorage) SetRunToolScope(runID string, toolNames []string) error {
+	if runID == "" {
+		return fmt.Errorf("run ID cannot be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if toolNames == nil {
+		if _, err := s.db.Exec("DELETE FROM run_tool_scopes WHERE run_id = ?", runID); err != nil {
+			return fmt.Errorf("failed to delete tool scope: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(toolNames)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tool names: %w", err)
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO run_tool_scopes (run_id, tool_names) VALUES (?, ?)
+		ON CONFLICT(run_id) DO UPDATE SET tool_names = excluded.tool_names
+	`, runID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save tool scope: %w", err)
+	}
+
+	return nil
+}
+
+// GetRunToolScope returns the tools a run may give the agent it designs, or
+// nil if it has no scope
+func (s *SQLiteStorage) GetRunToolScope(runID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var data string
+	err := s.db.QueryRow("SELECT tool_names FROM run_tool_scopes WHERE run_id = ?", runID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool scope: %w", err)
+	}
+
+	var toolNames []string
+	if err := json.Unmarshal([]byte(data), &toolNames); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tool names: %w", err)
+	}
+	return toolNames, nil
+}
+
 // GetSubRuns returns all sub-runs for a parent run
 func (s *SQLiteStorage) GetSubRuns(parentRunID string) ([]*Run, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	query := `SELECT id, agent_id, agent_name, status, prompt, model, start_time, end_time, duration,
-			  total_iterations, total_tool_calls, total_tokens, error, parent_run_id, sub_run_ids, is_handoff
+			  total_iterations, total_tool_calls, total_tokens, error, parent_run_id, sub_run_ids, is_handoff, handoff_report
 			  FROM runs WHERE parent_run_id = ? ORDER BY start_time ASC`
 
 	rows, err := s.db.Query(query, parentRunID)
@@ -905,11 +1204,12 @@ func (s *SQLiteStorage) GetSubRuns(parentRunID string) ([]*Run, error) {
 		var tokenUsageJSON string
 		var parentRunID sql.NullString
 		var subRunIDsJSON sql.NullString
+		var handoffReportJSON sql.NullString
 
 		err := rows.Scan(
 			&run.ID, &run.AgentID, &run.AgentName, &run.Status, &run.Prompt, &run.Model,
 			&run.StartTime, &endTime, &duration, &run.TotalIterations, &run.TotalToolCalls,
-			&tokenUsageJSON, &run.Error, &parentRunID, &subRunIDsJSON, &run.IsHandoff)
+			&tokenUsageJSON, &run.Error, &parentRunID, &subRunIDsJSON, &run.IsHandoff, &handoffReportJSON)
 
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan run: %w", err)
@@ -938,6 +1238,13 @@ func (s *SQLiteStorage) GetSubRuns(parentRunID string) ([]*Run, error) {
 			}
 		}
 
+		// Unmarshal handoff_report
+		if handoffReportJSON.Valid && handoffReportJSON.String != "" {
+			if err := json.Unmarshal([]byte(handoffReportJSON.String), &run.HandoffReport); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal handoff report: %w", err)
+			}
+		}
+
 		runs = append(runs, &run)
 	}
 
@@ -955,7 +1262,7 @@ func (s *SQLiteStorage) GetParentRun(subRunID string) (*Run, error) {
 
 	row := s.db.QueryRow(
 		`SELECT id, agent_id, agent_name, status, prompt, model, start_time, end_time, duration,
-			total_iterations, total_tool_calls, total_tokens, error, parent_run_id, sub_run_ids, is_handoff
+			total_iterations, total_tool_calls, total_tokens, error, parent_run_id, sub_run_ids, is_handoff, handoff_report
 		 FROM runs WHERE id = (
 			 SELECT parent_run_id FROM runs WHERE id = ?
 		 )`,
@@ -967,11 +1274,12 @@ func (s *SQLiteStorage) GetParentRun(subRunID string) (*Run, error) {
 	var tokenUsageJSON string
 	var parentRunID sql.NullString
 	var subRunIDsJSON sql.NullString
+	var handoffReportJSON sql.NullString
 
 	err := row.Scan(
 		&run.ID, &run.AgentID, &run.AgentName, &run.Status, &run.Prompt, &run.Model,
 		&run.StartTime, &endTime, &duration, &run.TotalIterations, &run.TotalToolCalls,
-		&tokenUsageJSON, &run.Error, &parentRunID, &subRunIDsJSON, &run.IsHandoff)
+		&tokenUsageJSON, &run.Error, &parentRunID, &subRunIDsJSON, &run.IsHandoff, &handoffReportJSON)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("parent run not found for sub-run %s", subRunID)
@@ -1003,6 +1311,13 @@ func (s *SQLiteStorage) GetParentRun(subRunID string) (*Run, error) {
 		}
 	}
 
+	// Unmarshal handoff_report
+	if handoffReportJSON.Valid && handoffReportJSON.String != "" {
+		if err := json.Unmarshal([]byte(handoffReportJSON.String), &run.HandoffReport); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal handoff report: %w", err)
+		}
+	}
+
 	return &run, nil
 }
 
@@ -1363,17 +1678,36 @@ func (s *SQLiteStorage) CreateHumanAction(action *HumanAction) error {
 		resumedAt.Valid = true
 	}
 
+	var toolUseID sql.NullString
+	if action.ToolUseID != "" {
+		toolUseID.String = action.ToolUseID
+		toolUseID.Valid = true
+	}
+
+	var defaultResponse sql.NullString
+	if action.DefaultResponse != nil {
+		defaultResponse.String = *action.DefaultResponse
+		defaultResponse.Valid = true
+	}
+
+	var expiresAt sql.NullTime
+	if action.ExpiresAt != nil {
+		expiresAt.Time = *action.ExpiresAt
+		expiresAt.Valid = true
+	}
+
 	now := time.Now()
 	action.CreatedAt = now
 	action.UpdatedAt = now
 
 	_, err := s.db.Exec(
 		`INSERT INTO human_actions (id, run_id, agent_id, agent_name, resource_type, resource_id, 
-		 agent_work_id, request_type, question, context, options, status, response, responded_at, 
-		 resumed_at, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		 agent_work_id, request_type, question, context, options, tool_use_id, default_response,
+		 expires_at, status, response, responded_at, resumed_at, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		action.ID, action.RunID, action.AgentID, action.AgentName, resourceType, resourceID,
 		agentWorkID, action.RequestType, action.Question, action.Context, optionsJSON,
+		toolUseID, defaultResponse, expiresAt,
 		action.Status, response, respondedAt, resumedAt, action.CreatedAt, action.UpdatedAt)
 
 	if err != nil {
@@ -1390,8 +1724,8 @@ func (s *SQLiteStorage) GetHumanAction(actionID string) (*HumanAction, error) {
 
 	row := s.db.QueryRow(
 		`SELECT id, run_id, agent_id, agent_name, resource_type, resource_id, agent_work_id,
-		 request_type, question, context, options, status, response, responded_at, resumed_at,
-		 created_at, updated_at
+		 request_type, question, context, options, tool_use_id, default_response, expires_at,
+		 status, response, responded_at, resumed_at, created_at, updated_at
 		 FROM human_actions WHERE id = ?`,
 		actionID)
 
@@ -1400,6 +1734,9 @@ func (s *SQLiteStorage) GetHumanAction(actionID string) (*HumanAction, error) {
 	var resourceID sql.NullString
 	var agentWorkID sql.NullString
 	var optionsJSON sql.NullString
+	var toolUseID sql.NullString
+	var defaultResponse sql.NullString
+	var expiresAt sql.NullTime
 	var response sql.NullString
 	var respondedAt sql.NullTime
 	var resumedAt sql.NullTime
@@ -1408,6 +1745,7 @@ func (s *SQLiteStorage) GetHumanAction(actionID string) (*HumanAction, error) {
 		&action.ID, &action.RunID, &action.AgentID, &action.AgentName,
 		&resourceType, &resourceID, &agentWorkID,
 		&action.RequestType, &action.Question, &action.Context, &optionsJSON,
+		&toolUseID, &defaultResponse, &expiresAt,
 		&action.Status, &response, &respondedAt, &resumedAt,
 		&action.CreatedAt, &action.UpdatedAt)
 
@@ -1433,6 +1771,15 @@ func (s *SQLiteStorage) GetHumanAction(actionID string) (*HumanAction, error) {
 			return nil, fmt.Errorf("failed to unmarshal options: %w", err)
 		}
 	}
+	if toolUseID.Valid {
+		action.ToolUseID = toolUseID.String
+	}
+	if defaultResponse.Valid {
+		action.DefaultResponse = &defaultResponse.String
+	}
+	if expiresAt.Valid {
+		action.ExpiresAt = &expiresAt.Time
+	}
 	if response.Valid {
 		action.Response = &response.String
 	}
@@ -1452,8 +1799,8 @@ func (s *SQLiteStorage) ListHumanActions(opts HumanActionListOptions) ([]*HumanA
 	defer s.mu.RUnlock()
 
 	query := `SELECT id, run_id, agent_id, agent_name, resource_type, resource_id, agent_work_id,
-			  request_type, question, context, options, status, response, responded_at, resumed_at,
-			  created_at, updated_at
+			  request_type, question, context, options, tool_use_id, default_response, expires_at,
+			  status, response, responded_at, resumed_at, created_at, updated_at
 			  FROM human_actions WHERE 1=1`
 	args := []interface{}{}
 
@@ -1496,6 +1843,9 @@ func (s *SQLiteStorage) ListHumanActions(opts HumanActionListOptions) ([]*HumanA
 		var resourceID sql.NullString
 		var agentWorkID sql.NullString
 		var optionsJSON sql.NullString
+		var toolUseID sql.NullString
+		var defaultResponse sql.NullString
+		var expiresAt sql.NullTime
 		var response sql.NullString
 		var respondedAt sql.NullTime
 		var resumedAt sql.NullTime
@@ -1504,6 +1854,7 @@ func (s *SQLiteStorage) ListHumanActions(opts HumanActionListOptions) ([]*HumanA
 			&action.ID, &action.RunID, &action.AgentID, &action.AgentName,
 			&resourceType, &resourceID, &agentWorkID,
 			&action.RequestType, &action.Question, &action.Context, &optionsJSON,
+			&toolUseID, &defaultResponse, &expiresAt,
 			&action.Status, &response, &respondedAt, &resumedAt,
 			&action.CreatedAt, &action.UpdatedAt)
 
@@ -1526,6 +1877,15 @@ func (s *SQLiteStorage) ListHumanActions(opts HumanActionListOptions) ([]*HumanA
 				// Ignore unmarshal errors for options
 			}
 		}
+		if toolUseID.Valid {
+			action.ToolUseID = toolUseID.String
+		}
+		if defaultResponse.Valid {
+			action.DefaultResponse = &defaultResponse.String
+		}
+		if expiresAt.Valid {
+			action.ExpiresAt = &expiresAt.Time
+		}
 		if response.Valid {
 			action.Response = &response.String
 		}
@@ -1594,6 +1954,10 @@ func (s *SQLiteStorage) UpdateHumanAction(actionID string, update *HumanActionUp
 	args = append(args, actionID)
 
 	query := fmt.Sprintf("UPDATE human_actions SET %s WHERE id = ?", strings.Join(updates, ", "))
+	if update.IfStatus != nil {
+		query += " AND status = ?"
+		args = append(args, *update.IfStatus)
+	}
 	_, err := s.db.Exec(query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update human action: %w", err)
@@ -1647,6 +2011,9 @@ func (s *SQLiteStorage) initCustomAgentSchema() error {
 		model TEXT,
 		max_tokens INTEGER,
 		tool_names TEXT NOT NULL,
+		budget TEXT,
+		provider TEXT,
+		thinking_budget INTEGER,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);`
@@ -1666,9 +2033,99 @@ func (s *SQLiteStorage) initCustomAgentSchema() error {
 		}
 	}
 
+	// Check if budget column exists in custom_agents
+	var budgetExists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM pragma_table_info('custom_agents') WHERE name = 'budget'
+		)
+	`).Scan(&budgetExists)
+
+	if err == nil && !budgetExists {
+		if _, err := s.db.Exec("ALTER TABLE custom_agents ADD COLUMN budget TEXT;"); err != nil {
+			return fmt.Errorf("failed to add budget column: %w", err)
+		}
+	}
+
+	// Check if provider column exists in custom_agents
+	var providerExists bool
+	err = s.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM pragma_table_info('custom_agents') WHERE name = 'provider'
+		)
+	`).Scan(&providerExists)
+
+	if err == nil && !providerExists {
+		if _, err := s.db.Exec("ALTER TABLE custom_agents ADD COLUMN provider TEXT;"); err != nil {
+			return fmt.Errorf("failed to add provider column: %w", err)
+		}
+	}
+
+	// Check if thinking_budget column exists in custom_agents
+	var thinkingBudgetExists bool
+	err = s.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM pragma_table_info('custom_agents') WHERE name = 'thinking_budget'
+		)
+	`).Scan(&thinkingBudgetExists)
+
+	if err == nil && !thinkingBudgetExists {
+		if _, err := s.db.Exec("ALTER TABLE custom_agents ADD COLUMN thinking_budget INTEGER;"); err != nil {
+			return fmt.Errorf("failed to add thinking_budget column: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// marshalBudget serializes a budget for storage, returning NULL for no budget
+func marshalBudget(budget *RunBudget) (sql.NullString, error) {
+	if budget == nil || *budget == (RunBudget{}) {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(budget)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to marshal budget: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// unmarshalBudget deserializes a stored budget
+func unmarshalBudget(data sql.NullString) (*RunBudget, error) {
+	if !data.Valid || data.String == "" {
+		return nil, nil
+	}
+	var budget RunBudget
+	if err := json.Unmarshal([]byte(data.String), &budget); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal budget: %w", err)
+	}
+	return &budget, nil
+}
+
+// marshalProvider serializes a provider config for storage, returning NULL for the default provider
+func marshalProvider(provider *ProviderConfig) (sql.NullString, error) {
+	if provider == nil || *provider == (ProviderConfig{}) {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(provider)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to marshal provider: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// unmarshalProvider deserializes a stored provider config
+func unmarshalProvider(data sql.NullString) (*ProviderConfig, error) {
+	if !data.Valid || data.String == "" {
+		return nil, nil
+	}
+	var provider ProviderConfig
+	if err := json.Unmarshal([]byte(data.String), &provider); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal provider: %w", err)
+	}
+	return &provider, nil
+}
+
 // CreateCustomAgent creates a new custom agent
 func (s *SQLiteStorage) CreateCustomAgent(agent *CustomAgent) error {
 	s.mu.Lock()
@@ -1680,11 +2137,21 @@ func (s *SQLiteStorage) CreateCustomAgent(agent *CustomAgent) error {
 		return fmt.Errorf("failed to marshal tool names: %w", err)
 	}
 
+	budgetJSON, err := marshalBudget(agent.Budget)
+	if err != nil {
+		return err
+	}
+
+	providerJSON, err := marshalProvider(agent.Provider)
+	if err != nil {
+		return err
+	}
+
 	_, err = s.db.Exec(`
-		INSERT INTO custom_agents (id, name, description, system_prompt, model, max_tokens, tool_names, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		agent.ID, agent.Name, agent.Description, agent.SystemPrompt, agent.Model, agent.MaxTokens,
-		string(toolNamesJSON), agent.CreatedAt, agent.UpdatedAt)
+		INSERT INTO custom_agents (id, name, description, system_prompt, model, max_tokens, thinking_budget, tool_names, budget, provider, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		agent.ID, agent.Name, agent.Description, agent.SystemPrompt, agent.Model, agent.MaxTokens, agent.ThinkingBudget,
+		string(toolNamesJSON), budgetJSON, providerJSON, agent.CreatedAt, agent.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create custom agent: %w", err)
 	}
@@ -1699,14 +2166,14 @@ func (s *SQLiteStorage) GetCustomAgent(agentID string) (*CustomAgent, error) {
 
 	var agent CustomAgent
 	var toolNamesJSON string
-	var systemPrompt, model sql.NullString
-	var maxTokens sql.NullInt64
+	var systemPrompt, model, budgetJSON, providerJSON sql.NullString
+	var maxTokens, thinkingBudget sql.NullInt64
 
 	err := s.db.QueryRow(`
-		SELECT id, name, description, system_prompt, model, max_tokens, tool_names, created_at, updated_at
+		SELECT id, name, description, system_prompt, model, max_tokens, thinking_budget, tool_names, budget, provider, created_at, updated_at
 		FROM custom_agents WHERE id = ?`, agentID).
-		Scan(&agent.ID, &agent.Name, &agent.Description, &systemPrompt, &model, &maxTokens,
-			&toolNamesJSON, &agent.CreatedAt, &agent.UpdatedAt)
+		Scan(&agent.ID, &agent.Name, &agent.Description, &systemPrompt, &model, &maxTokens, &thinkingBudget,
+			&toolNamesJSON, &budgetJSON, &providerJSON, &agent.CreatedAt, &agent.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("custom agent not found")
@@ -1723,12 +2190,22 @@ func (s *SQLiteStorage) GetCustomAgent(agentID string) (*CustomAgent, error) {
 	if maxTokens.Valid {
 		agent.MaxTokens = maxTokens.Int64
 	}
+	if thinkingBudget.Valid {
+		agent.ThinkingBudget = thinkingBudget.Int64
+	}
 
 	// Deserialize tool_names from JSON
 	if err := json.Unmarshal([]byte(toolNamesJSON), &agent.ToolNames); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal tool names: %w", err)
 	}
 
+	if agent.Budget, err = unmarshalBudget(budgetJSON); err != nil {
+		return nil, err
+	}
+	if agent.Provider, err = unmarshalProvider(providerJSON); err != nil {
+		return nil, err
+	}
+
 	return &agent, nil
 }
 
@@ -1738,7 +2215,7 @@ func (s *SQLiteStorage) ListCustomAgents() ([]*CustomAgent, error) {
 	defer s.mu.RUnlock()
 
 	rows, err := s.db.Query(`
-		SELECT id, name, description, system_prompt, model, max_tokens, tool_names, created_at, updated_at
+		SELECT id, name, description, system_prompt, model, max_tokens, thinking_budget, tool_names, budget, provider, created_at, updated_at
 		FROM custom_agents ORDER BY created_at DESC`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list custom agents: %w", err)
@@ -1749,11 +2226,11 @@ func (s *SQLiteStorage) ListCustomAgents() ([]*CustomAgent, error) {
 	for rows.Next() {
 		var agent CustomAgent
 		var toolNamesJSON string
-		var systemPrompt, model sql.NullString
-		var maxTokens sql.NullInt64
+		var systemPrompt, model, budgetJSON, providerJSON sql.NullString
+		var maxTokens, thinkingBudget sql.NullInt64
 
-		err := rows.Scan(&agent.ID, &agent.Name, &agent.Description, &systemPrompt, &model, &maxTokens,
-			&toolNamesJSON, &agent.CreatedAt, &agent.UpdatedAt)
+		err := rows.Scan(&agent.ID, &agent.Name, &agent.Description, &systemPrompt, &model, &maxTokens, &thinkingBudget,
+			&toolNamesJSON, &budgetJSON, &providerJSON, &agent.CreatedAt, &agent.UpdatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan custom agent: %w", err)
 		}
@@ -1767,12 +2244,22 @@ func (s *SQLiteStorage) ListCustomAgents() ([]*CustomAgent, error) {
 		if maxTokens.Valid {
 			agent.MaxTokens = maxTokens.Int64
 		}
+		if thinkingBudget.Valid {
+			agent.ThinkingBudget = thinkingBudget.Int64
+		}
 
 		// Deserialize tool_names from JSON
 		if err := json.Unmarshal([]byte(toolNamesJSON), &agent.ToolNames); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal tool names: %w", err)
 		}
 
+		if agent.Budget, err = unmarshalBudget(budgetJSON); err != nil {
+			return nil, err
+		}
+		if agent.Provider, err = unmarshalProvider(providerJSON); err != nil {
+			return nil, err
+		}
+
 		agents = append(agents, &agent)
 	}
 
@@ -1820,6 +2307,10 @@ func (s *SQLiteStorage) UpdateCustomAgent(agentID string, update *CustomAgentUpd
 		updates = append(updates, "max_tokens = ?")
 		args = append(args, *update.MaxTokens)
 	}
+	if update.ThinkingBudget != nil {
+		updates = append(updates, "thinking_budget = ?")
+		args = append(args, *update.ThinkingBudget)
+	}
 	if update.ToolNames != nil {
 		toolNamesJSON, err := json.Marshal(*update.ToolNames)
 		if err != nil {
@@ -1828,6 +2319,22 @@ func (s *SQLiteStorage) UpdateCustomAgent(agentID string, update *CustomAgentUpd
 		updates = append(updates, "tool_names = ?")
 		args = append(args, string(toolNamesJSON))
 	}
+	if update.Budget != nil {
+		budgetJSON, err := marshalBudget(update.Budget)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, "budget = ?")
+		args = append(args, budgetJSON)
+	}
+	if update.Provider != nil {
+		providerJSON, err := marshalProvider(update.Provider)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, "provider = ?")
+		args = append(args, providerJSON)
+	}
 
 	if len(updates) == 1 {
 		// Only updated_at, nothing to update
@@ -24,6 +24,15 @@ type Storage interface {
 	GetEvents(runID string, after *time.Time) ([]*events.AgentEvent, error)
 	GetEventCount(runID string) (int, error)
 
+	// Conversation checkpoints
+	SaveCheckpoint(checkpoint *RunCheckpoint) error
+	GetLatestCheckpoints(runID string, limit int) ([]*RunCheckpoint, error)
+
+	// Tool scopes limit the tools a meta-agent run may give the agent it
+	// designs. A nil scope allows every tool.
+	SetRunToolScope(runID string, toolNames []string) error
+	GetRunToolScope(runID string) ([]string, error)
+
 	// Stream support (for real-time updates)
 	Subscribe(runID string) (<-chan *events.AgentEvent, func())
 	SubscribeAll() (<-chan *events.AgentEvent, func())
@@ -97,4 +106,12 @@ type Storage interface {
 	ListCustomAgents() ([]*CustomAgent, error)
 	UpdateCustomAgent(agentID string, update *CustomAgentUpdate) error
 	DeleteCustomAgent(agentID string) error
+
+	// Sandbox management
+	SaveSandbox(record *SandboxRecord) error
+	GetSandbox(sandboxID string) (*SandboxRecord, error)
+	ListSandboxes() ([]*SandboxRecord, error)
+	DeleteSandbox(sandboxID string) error
+	AddSandboxValidation(record *SandboxValidationRecord) error
+	ListSandboxValidations(sandboxID string, limit int) ([]*SandboxValidationRecord, error)
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -68,6 +69,13 @@ type CreateRunRequest struct {
 	AgentID         string
 	Prompt          string
 	ResumeFromRunID string
+
+	// Optional budget limits (zero means unlimited)
+	MaxInputTokens  int
+	MaxOutputTokens int
+	MaxCostUSD      float64
+	MaxDuration     string
+	MaxIterations   int
 }
 
 func (r *CreateRunRequest) Validate() error {
@@ -97,6 +105,32 @@ func (r *CreateRunRequest) Validate() error {
 		}
 	}
 
+	return ValidateBudget(r.MaxInputTokens, r.MaxOutputTokens, r.MaxCostUSD, r.MaxDuration, r.MaxIterations)
+}
+
+// ValidateBudget checks that budget limits are non-negative and the duration parses
+func ValidateBudget(maxInputTokens, maxOutputTokens int, maxCostUSD float64, maxDuration string, maxIterations int) error {
+	if maxInputTokens < 0 {
+		return NewValidationError("budget.max_input_tokens", "must not be negative", ErrOutOfRange)
+	}
+	if maxOutputTokens < 0 {
+		return NewValidationError("budget.max_output_tokens", "must not be negative", ErrOutOfRange)
+	}
+	if maxCostUSD < 0 {
+		return NewValidationError("budget.max_cost_usd", "must not be negative", ErrOutOfRange)
+	}
+	if maxIterations < 0 {
+		return NewValidationError("budget.max_iterations", "must not be negative", ErrOutOfRange)
+	}
+	if maxDuration != "" {
+		d, err := time.ParseDuration(maxDuration)
+		if err != nil {
+			return NewValidationError("budget.max_duration", "must be a duration such as \"30m\"", ErrInvalidFormat)
+		}
+		if d < 0 {
+			return NewValidationError("budget.max_duration", "must not be negative", ErrOutOfRange)
+		}
+	}
 	return nil
 }
 
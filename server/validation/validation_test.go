@@ -293,6 +293,37 @@ func TestCreateRunRequestValidate(t *testing.T) {
 			expectErr: true,
 			errField:  "resume_from_run_id",
 		},
+		{
+			name: "valid with budget",
+			req: CreateRunRequest{
+				AgentID:         "coding",
+				Prompt:          "Some prompt",
+				MaxOutputTokens: 10000,
+				MaxCostUSD:      1.5,
+				MaxDuration:     "30m",
+			},
+			expectErr: false,
+		},
+		{
+			name: "negative budget",
+			req: CreateRunRequest{
+				AgentID:    "coding",
+				Prompt:     "Some prompt",
+				MaxCostUSD: -1,
+			},
+			expectErr: true,
+			errField:  "budget.max_cost_usd",
+		},
+		{
+			name: "invalid budget duration",
+			req: CreateRunRequest{
+				AgentID:     "coding",
+				Prompt:      "Some prompt",
+				MaxDuration: "ten minutes",
+			},
+			expectErr: true,
+			errField:  "budget.max_duration",
+		},
 	}
 
 	for _, tt := range tests {
@@ -262,6 +262,28 @@ func (c *WebSocketClient) readPump(hub *WebSocketHub) {
 	}
 }
 
+// SubscribeToStreamingEvents forwards streaming delta events from the agent
+// emitter to the hub. These events bypass storage, so they are not delivered
+// by SubscribeToStorage.
+func (s *Server) SubscribeToStreamingEvents() {
+	if s.eventBridge == nil {
+		return
+	}
+
+	eventChan, cleanup := s.eventBridge.GetEmitter().SubscribeAll()
+
+	// Store cleanup function
+	s.emitterCleanup = cleanup
+
+	go func() {
+		for event := range eventChan {
+			if events.IsStreamingEvent(event.Type) {
+				s.hub.BroadcastEvent(event)
+			}
+		}
+	}()
+}
+
 // SubscribeToStorage subscribes the hub to storage events
 func (s *Server) SubscribeToStorage() {
 	eventChan, cleanup := s.storage.SubscribeAll()
@@ -251,6 +251,7 @@ func GetFileSystemTools() []Tool {
 		},
 		{
 			Name:        "write_file",
+			Serial:      true,
 			Description: "Write content to a file (creates file if it doesn't exist)",
 			Schema: anthropic.ToolInputSchemaParam{
 				Properties: map[string]interface{}{
@@ -275,6 +276,7 @@ func GetFileSystemTools() []Tool {
 		},
 		{
 			Name:        "edit_file",
+			Serial:      true,
 			Description: "Edit a file by replacing old content with new content",
 			Schema: anthropic.ToolInputSchemaParam{
 				Properties: map[string]interface{}{
@@ -303,6 +305,7 @@ func GetFileSystemTools() []Tool {
 		},
 		{
 			Name:        "delete_file",
+			Serial:      true,
 			Description: "Delete a file",
 			Schema: anthropic.ToolInputSchemaParam{
 				Properties: map[string]interface{}{
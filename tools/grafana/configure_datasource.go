@@ -1,8 +1,10 @@
 package grafana
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/mottibechhofer/otel-ai-engineer/grafanaclient"
@@ -24,6 +26,7 @@ type ConfigureDatasourceInput struct {
 func GetConfigureDatasourceTool() tools.Tool {
 	return tools.Tool{
 		Name:        "configure_grafana_datasource",
+		Timeout:     30 * time.Second,
 		Description: "Configures a data source in Grafana. Supports OTLP (traces, metrics, logs), Prometheus, Loki, Tempo, and other Grafana-compatible data sources.",
 		Schema: anthropic.ToolInputSchemaParam{
 			Properties: map[string]interface{}{
@@ -59,7 +62,7 @@ func GetConfigureDatasourceTool() tools.Tool {
 			},
 			Required: []string{"grafana_url", "username", "password", "datasource_name", "datasource_type", "url"},
 		},
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 			var input ConfigureDatasourceInput
 			if err := json.Unmarshal(inputJSON, &input); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal input: %w", err)
@@ -79,7 +82,7 @@ func GetConfigureDatasourceTool() tools.Tool {
 				JSONData: input.JSONData,
 			}
 
-			result, err := client.CreateDatasource(datasource)
+			result, err := client.CreateDatasource(ctx, datasource)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create datasource: %w", err)
 			}
@@ -1,8 +1,10 @@
 package grafana
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/mottibechhofer/otel-ai-engineer/grafanaclient"
@@ -28,6 +30,7 @@ type CreateAlertRuleInput struct {
 func GetCreateAlertRuleTool() tools.Tool {
 	return tools.Tool{
 		Name:        "create_grafana_alert_rule",
+		Timeout:     30 * time.Second,
 		Description: "Creates a new alert rule in Grafana. Define conditions, thresholds, and notification channels for monitoring metrics and triggering alerts.",
 		Schema: anthropic.ToolInputSchemaParam{
 			Properties: map[string]interface{}{
@@ -78,7 +81,7 @@ func GetCreateAlertRuleTool() tools.Tool {
 			},
 			Required: []string{"grafana_url", "username", "password", "rule_name", "condition", "data"},
 		},
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 			var input CreateAlertRuleInput
 			if err := json.Unmarshal(inputJSON, &input); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal input: %w", err)
@@ -99,7 +102,7 @@ func GetCreateAlertRuleTool() tools.Tool {
 				Labels:       input.Labels,
 			}
 
-			err := client.CreateAlertRule(rule)
+			err := client.CreateAlertRule(ctx, rule)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create alert rule: %w", err)
 			}
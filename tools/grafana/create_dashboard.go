@@ -1,8 +1,10 @@
 package grafana
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/mottibechhofer/otel-ai-engineer/grafanaclient"
@@ -23,6 +25,7 @@ type CreateDashboardInput struct {
 func GetCreateDashboardTool() tools.Tool {
 	return tools.Tool{
 		Name:        "create_grafana_dashboard",
+		Timeout:     30 * time.Second,
 		Description: "Creates a new dashboard in Grafana from a JSON configuration. Supports both pre-built templates and custom dashboard JSON. The dashboard must follow Grafana's dashboard schema.",
 		Schema: anthropic.ToolInputSchemaParam{
 			Properties: map[string]interface{}{
@@ -53,7 +56,7 @@ func GetCreateDashboardTool() tools.Tool {
 			},
 			Required: []string{"grafana_url", "username", "password", "dashboard_json"},
 		},
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 			var input CreateDashboardInput
 			if err := json.Unmarshal(inputJSON, &input); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal input: %w", err)
@@ -67,7 +70,7 @@ func GetCreateDashboardTool() tools.Tool {
 				Dashboard: input.DashboardJSON,
 			}
 
-			result, err := client.CreateDashboard(dashboard)
+			result, err := client.CreateDashboard(ctx, dashboard)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create dashboard: %w", err)
 			}
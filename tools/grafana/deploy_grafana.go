@@ -1,8 +1,10 @@
 package grafana
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/mottibechhofer/otel-ai-engineer/tools"
@@ -34,6 +36,7 @@ func GetDeployGrafanaTool(dockerClient *dc.Client) tools.Tool {
 	sharedDockerClient = dockerClient
 	return tools.Tool{
 		Name:        "deploy_grafana",
+		Timeout:     5 * time.Minute,
 		Description: "Deploys a new Grafana instance to the specified target (docker, kubernetes, or remote). The Grafana instance can be configured to connect to OpenTelemetry collectors automatically.",
 		Schema: anthropic.ToolInputSchemaParam{
 			Properties: map[string]interface{}{
@@ -61,7 +64,7 @@ func GetDeployGrafanaTool(dockerClient *dc.Client) tools.Tool {
 			},
 			Required: []string{"target_type", "instance_name"},
 		},
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 			var input DeployGrafanaInput
 			if err := json.Unmarshal(inputJSON, &input); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal input: %w", err)
@@ -82,7 +85,7 @@ func GetDeployGrafanaTool(dockerClient *dc.Client) tools.Tool {
 				Parameters:    input.Parameters,
 			}
 
-			result, err := deployer.Deploy(config)
+			result, err := deployer.Deploy(ctx, config)
 			if err != nil {
 				return nil, fmt.Errorf("deployment failed: %w", err)
 			}
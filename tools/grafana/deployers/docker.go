@@ -36,9 +36,7 @@ func (d *DockerDeployer) GetTargetType() TargetType {
 }
 
 // Deploy deploys Grafana as a Docker container
-func (d *DockerDeployer) Deploy(config GrafanaDeploymentConfig) (*GrafanaDeploymentResult, error) {
-	ctx := context.Background()
-
+func (d *DockerDeployer) Deploy(ctx context.Context, config GrafanaDeploymentConfig) (*GrafanaDeploymentResult, error) {
 	// Generate unique instance ID
 	instanceID := fmt.Sprintf("%s-%d", config.InstanceName, time.Now().Unix())
 	containerName := fmt.Sprintf("grafana-%s", instanceID)
@@ -130,7 +128,13 @@ func (d *DockerDeployer) Deploy(config GrafanaDeploymentConfig) (*GrafanaDeploym
 	}
 
 	// Wait a moment for container to start
-	time.Sleep(2 * time.Second)
+	timer := time.NewTimer(2 * time.Second)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return nil, ctx.Err()
+	case <-timer.C:
+	}
 
 	// Check container status
 	status, err := d.dockerClient.GetContainerStatus(ctx, containerName)
@@ -157,8 +161,7 @@ func (d *DockerDeployer) Deploy(config GrafanaDeploymentConfig) (*GrafanaDeploym
 }
 
 // Stop stops and removes a Grafana container
-func (d *DockerDeployer) Stop(instanceID string, params map[string]interface{}) error {
-	ctx := context.Background()
+func (d *DockerDeployer) Stop(ctx context.Context, instanceID string, params map[string]interface{}) error {
 	containerName := fmt.Sprintf("grafana-%s", instanceID)
 	cli := d.dockerClient.GetClient()
 
@@ -187,8 +190,7 @@ func (d *DockerDeployer) Stop(instanceID string, params map[string]interface{})
 }
 
 // List lists all running Grafana containers
-func (d *DockerDeployer) List() ([]GrafanaInstanceInfo, error) {
-	ctx := context.Background()
+func (d *DockerDeployer) List(ctx context.Context) ([]GrafanaInstanceInfo, error) {
 	cli := d.dockerClient.GetClient()
 
 	filtersArgs := filters.NewArgs()
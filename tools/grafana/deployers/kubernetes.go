@@ -1,6 +1,7 @@
 package deployers
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -30,7 +31,7 @@ func (d *KubernetesDeployer) GetTargetType() TargetType {
 }
 
 // Deploy deploys Grafana to Kubernetes
-func (d *KubernetesDeployer) Deploy(config GrafanaDeploymentConfig) (*GrafanaDeploymentResult, error) {
+func (d *KubernetesDeployer) Deploy(ctx context.Context, config GrafanaDeploymentConfig) (*GrafanaDeploymentResult, error) {
 	// TODO: Implement Kubernetes deployment
 	// This would involve:
 	// 1. Generate Deployment manifest with proper configuration
@@ -45,7 +46,7 @@ func (d *KubernetesDeployer) Deploy(config GrafanaDeploymentConfig) (*GrafanaDep
 }
 
 // Stop stops and removes a Grafana deployment from Kubernetes
-func (d *KubernetesDeployer) Stop(instanceID string, params map[string]interface{}) error {
+func (d *KubernetesDeployer) Stop(ctx context.Context, instanceID string, params map[string]interface{}) error {
 	// TODO: Implement Kubernetes stop
 	// This would involve:
 	// 1. Delete the deployment
@@ -57,7 +58,7 @@ func (d *KubernetesDeployer) Stop(instanceID string, params map[string]interface
 }
 
 // List lists all Grafana instances in Kubernetes
-func (d *KubernetesDeployer) List() ([]GrafanaInstanceInfo, error) {
+func (d *KubernetesDeployer) List(ctx context.Context) ([]GrafanaInstanceInfo, error) {
 	// TODO: Implement Kubernetes list
 	// This would involve:
 	// 1. Query Kubernetes API for deployments with label selector
@@ -1,6 +1,7 @@
 package deployers
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -28,7 +29,7 @@ func (d *RemoteDeployer) GetTargetType() TargetType {
 }
 
 // Deploy configures a remote Grafana instance (no deployment, just configuration)
-func (d *RemoteDeployer) Deploy(config GrafanaDeploymentConfig) (*GrafanaDeploymentResult, error) {
+func (d *RemoteDeployer) Deploy(ctx context.Context, config GrafanaDeploymentConfig) (*GrafanaDeploymentResult, error) {
 	// TODO: Implement remote configuration
 	// This would involve:
 	// 1. Connect to remote Grafana instance
@@ -41,7 +42,7 @@ func (d *RemoteDeployer) Deploy(config GrafanaDeploymentConfig) (*GrafanaDeploym
 }
 
 // Stop stops configuration of a remote Grafana instance
-func (d *RemoteDeployer) Stop(instanceID string, params map[string]interface{}) error {
+func (d *RemoteDeployer) Stop(ctx context.Context, instanceID string, params map[string]interface{}) error {
 	// Note: For remote targets, "stop" means to remove configured resources
 	// This would involve:
 	// 1. Remove dashboards
@@ -53,7 +54,7 @@ func (d *RemoteDeployer) Stop(instanceID string, params map[string]interface{})
 }
 
 // List lists remote Grafana instances
-func (d *RemoteDeployer) List() ([]GrafanaInstanceInfo, error) {
+func (d *RemoteDeployer) List(ctx context.Context) ([]GrafanaInstanceInfo, error) {
 	// TODO: Implement remote list
 	// For remote instances, this would return:
 	// - List of configured/detected remote Grafana instances
@@ -1,6 +1,9 @@
 package deployers
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // TargetType represents the deployment target for Grafana
 type TargetType string
@@ -46,13 +49,13 @@ type GrafanaInstanceInfo struct {
 // GrafanaDeployer is the interface all deployment targets must implement
 type GrafanaDeployer interface {
 	// Deploy deploys a new Grafana instance
-	Deploy(config GrafanaDeploymentConfig) (*GrafanaDeploymentResult, error)
+	Deploy(ctx context.Context, config GrafanaDeploymentConfig) (*GrafanaDeploymentResult, error)
 
 	// Stop stops and removes a Grafana instance
-	Stop(instanceID string, params map[string]interface{}) error
+	Stop(ctx context.Context, instanceID string, params map[string]interface{}) error
 
 	// List lists all running Grafana instances for this target
-	List() ([]GrafanaInstanceInfo, error)
+	List(ctx context.Context) ([]GrafanaInstanceInfo, error)
 
 	// GetTargetType returns the target type this deployer handles
 	GetTargetType() TargetType
@@ -1,8 +1,10 @@
 package grafana
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/mottibechhofer/otel-ai-engineer/grafanaclient"
@@ -20,6 +22,7 @@ type ListDatasourcesInput struct {
 func GetListDatasourcesTool() tools.Tool {
 	return tools.Tool{
 		Name:        "list_grafana_datasources",
+		Timeout:     30 * time.Second,
 		Description: "Lists all configured data sources in a Grafana instance. Useful for checking existing data sources before adding new ones.",
 		Schema: anthropic.ToolInputSchemaParam{
 			Properties: map[string]interface{}{
@@ -38,7 +41,7 @@ func GetListDatasourcesTool() tools.Tool {
 			},
 			Required: []string{"grafana_url", "username", "password"},
 		},
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 			var input ListDatasourcesInput
 			if err := json.Unmarshal(inputJSON, &input); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal input: %w", err)
@@ -47,7 +50,7 @@ func GetListDatasourcesTool() tools.Tool {
 			// Create Grafana client
 			client := grafanaclient.NewClientWithAuth(input.GrafanaURL, input.Username, input.Password)
 
-			datasources, err := client.ListDatasources()
+			datasources, err := client.ListDatasources(ctx)
 			if err != nil {
 				return nil, fmt.Errorf("failed to list datasources: %w", err)
 			}
@@ -1,6 +1,7 @@
 package grafana
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -29,7 +30,7 @@ func GetListGrafanaInstancesTool() tools.Tool {
 			},
 			Required: []string{"target_type"},
 		},
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 			var input ListGrafanaInstancesInput
 			if err := json.Unmarshal(inputJSON, &input); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal input: %w", err)
@@ -42,7 +43,7 @@ func GetListGrafanaInstancesTool() tools.Tool {
 			}
 
 			// List instances
-			instances, err := deployer.List()
+			instances, err := deployer.List(ctx)
 			if err != nil {
 				return nil, fmt.Errorf("failed to list instances: %w", err)
 			}
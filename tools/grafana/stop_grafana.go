@@ -1,6 +1,7 @@
 package grafana
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -39,7 +40,7 @@ func GetStopGrafanaTool() tools.Tool {
 			},
 			Required: []string{"instance_id", "target_type"},
 		},
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 			var input StopGrafanaInput
 			if err := json.Unmarshal(inputJSON, &input); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal input: %w", err)
@@ -52,7 +53,7 @@ func GetStopGrafanaTool() tools.Tool {
 			}
 
 			// Stop the instance
-			err = deployer.Stop(input.InstanceID, input.Parameters)
+			err = deployer.Stop(ctx, input.InstanceID, input.Parameters)
 			if err != nil {
 				return nil, fmt.Errorf("failed to stop instance: %w", err)
 			}
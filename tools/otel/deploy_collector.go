@@ -1,8 +1,10 @@
 package otel
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/mottibechhofer/otel-ai-engineer/tools"
@@ -21,6 +23,7 @@ type DeployCollectorInput struct {
 func GetDeployCollectorTool() tools.Tool {
 	return tools.Tool{
 		Name:        "deploy_otel_collector",
+		Timeout:     5 * time.Minute,
 		Description: "Deploys a new OpenTelemetry collector instance to the specified target (docker, remote, kubernetes, or local). The collector will automatically connect to the Lawrence OpAMP server. Currently supports docker deployment.",
 		Schema: anthropic.ToolInputSchemaParam{
 			Properties: map[string]interface{}{
@@ -44,7 +47,7 @@ func GetDeployCollectorTool() tools.Tool {
 			},
 			Required: []string{"target_type", "collector_name", "yaml_config"},
 		},
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 			var input DeployCollectorInput
 			if err := json.Unmarshal(inputJSON, &input); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal input: %w", err)
@@ -64,7 +67,7 @@ func GetDeployCollectorTool() tools.Tool {
 				Parameters:     input.Parameters,
 			}
 
-			result, err := deployer.Deploy(config)
+			result, err := deployer.Deploy(ctx, config)
 			if err != nil {
 				return nil, fmt.Errorf("deployment failed: %w", err)
 			}
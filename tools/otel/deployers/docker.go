@@ -43,7 +43,7 @@ func (d *DockerDeployer) GetTargetType() TargetType {
 }
 
 // Deploy deploys a collector as a Docker container
-func (d *DockerDeployer) Deploy(config DeploymentConfig) (*DeploymentResult, error) {
+func (d *DockerDeployer) Deploy(ctx context.Context, config DeploymentConfig) (*DeploymentResult, error) {
 	// Generate unique collector ID
 	collectorID := fmt.Sprintf("%s-%d", config.CollectorName, time.Now().Unix())
 	containerName := fmt.Sprintf("otel-collector-%s", collectorID)
@@ -118,13 +118,14 @@ func (d *DockerDeployer) Deploy(config DeploymentConfig) (*DeploymentResult, err
 	}
 
 	// Ensure network exists before deploying
-	ctx := context.Background()
 	if err := d.dockerClient.EnsureNetwork(ctx, network); err != nil {
 		return nil, fmt.Errorf("failed to ensure network '%s': %w", network, err)
 	}
 
 	// Brief delay to ensure network is fully available for Docker CLI
-	time.Sleep(200 * time.Millisecond)
+	if err := sleep(ctx, 200*time.Millisecond); err != nil {
+		return nil, err
+	}
 
 	// Build docker run command
 	args := []string{
@@ -154,13 +155,15 @@ func (d *DockerDeployer) Deploy(config DeploymentConfig) (*DeploymentResult, err
 	}
 
 	// Wait a moment for container to start
-	time.Sleep(2 * time.Second)
+	if err := sleep(ctx, 2*time.Second); err != nil {
+		return nil, err
+	}
 
 	// Check container status
-	status, err := d.getContainerStatus(containerName)
+	status, err := d.getContainerStatus(ctx, containerName)
 	if err != nil {
 		// Container might have exited, check logs
-		logs, logErr := d.getContainerLogs(containerName)
+		logs, logErr := d.getContainerLogs(ctx, containerName)
 		if logErr != nil {
 			return nil, fmt.Errorf("container failed to start: %w (logs unavailable)", err)
 		}
@@ -178,17 +181,17 @@ func (d *DockerDeployer) Deploy(config DeploymentConfig) (*DeploymentResult, err
 }
 
 // Stop stops and removes a collector container
-func (d *DockerDeployer) Stop(collectorID string, params map[string]interface{}) error {
+func (d *DockerDeployer) Stop(ctx context.Context, collectorID string, params map[string]interface{}) error {
 	containerName := fmt.Sprintf("otel-collector-%s", collectorID)
 
 	// First, try to stop the container
-	stopCmd := exec.Command(d.dockerPath, "stop", containerName)
+	stopCmd := exec.CommandContext(ctx, d.dockerPath, "stop", containerName)
 	if err := stopCmd.Run(); err != nil && !strings.Contains(err.Error(), "No such container") {
 		// Log but continue to remove
 	}
 
 	// Remove the container
-	rmCmd := exec.Command(d.dockerPath, "rm", containerName)
+	rmCmd := exec.CommandContext(ctx, d.dockerPath, "rm", containerName)
 	if err := rmCmd.Run(); err != nil {
 		return fmt.Errorf("failed to remove container: %w", err)
 	}
@@ -205,7 +208,7 @@ func (d *DockerDeployer) Stop(collectorID string, params map[string]interface{})
 }
 
 // List lists all collector containers (including stopped/exited ones)
-func (d *DockerDeployer) List() ([]CollectorInfo, error) {
+func (d *DockerDeployer) List(ctx context.Context) ([]CollectorInfo, error) {
 	// List all otel-collector-* containers (including stopped ones with -a)
 	args := []string{
 		"ps",
@@ -214,7 +217,7 @@ func (d *DockerDeployer) List() ([]CollectorInfo, error) {
 		"--format", "{{.Names}},{{.Status}},{{.CreatedAt}}",
 	}
 
-	cmd := exec.Command(d.dockerPath, args...)
+	cmd := exec.CommandContext(ctx, d.dockerPath, args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list containers: %w", err)
@@ -272,8 +275,8 @@ func (d *DockerDeployer) List() ([]CollectorInfo, error) {
 }
 
 // getContainerStatus gets the status of a container
-func (d *DockerDeployer) getContainerStatus(containerName string) (string, error) {
-	cmd := exec.Command(d.dockerPath, "inspect", "-f", "{{.State.Status}}", containerName)
+func (d *DockerDeployer) getContainerStatus(ctx context.Context, containerName string) (string, error) {
+	cmd := exec.CommandContext(ctx, d.dockerPath, "inspect", "-f", "{{.State.Status}}", containerName)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", err
@@ -282,11 +285,23 @@ func (d *DockerDeployer) getContainerStatus(containerName string) (string, error
 }
 
 // getContainerLogs retrieves logs from a container
-func (d *DockerDeployer) getContainerLogs(containerName string) (string, error) {
-	cmd := exec.Command(d.dockerPath, "logs", "--tail", "50", containerName)
+func (d *DockerDeployer) getContainerLogs(ctx context.Context, containerName string) (string, error) {
+	cmd := exec.CommandContext(ctx, d.dockerPath, "logs", "--tail", "50", containerName)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", err
 	}
 	return string(output), nil
 }
+
+// sleep waits for d, returning early with ctx's error if it is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -1,6 +1,9 @@
 package deployers
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // TargetType represents the deployment target
 type TargetType string
@@ -44,13 +47,13 @@ type CollectorInfo struct {
 // Deployer is the interface all deployment targets must implement
 type Deployer interface {
 	// Deploy deploys a new collector instance
-	Deploy(config DeploymentConfig) (*DeploymentResult, error)
+	Deploy(ctx context.Context, config DeploymentConfig) (*DeploymentResult, error)
 	
 	// Stop stops a running collector
-	Stop(collectorID string, params map[string]interface{}) error
+	Stop(ctx context.Context, collectorID string, params map[string]interface{}) error
 	
 	// List lists all running collectors for this target
-	List() ([]CollectorInfo, error)
+	List(ctx context.Context) ([]CollectorInfo, error)
 	
 	// GetTargetType returns the target type this deployer handles
 	GetTargetType() TargetType
@@ -1,6 +1,7 @@
 package otel
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -29,7 +30,7 @@ func GetListDeployedCollectorsTool() tools.Tool {
 			},
 			Required: []string{},
 		},
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 			var input ListDeployedInput
 			if err := json.Unmarshal(inputJSON, &input); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal input: %w", err)
@@ -58,7 +59,7 @@ func GetListDeployedCollectorsTool() tools.Tool {
 					continue
 				}
 
-				collectors, err := deployer.List()
+				collectors, err := deployer.List(ctx)
 				if err != nil {
 					// Log error but continue with other targets
 					continue
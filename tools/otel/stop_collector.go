@@ -1,6 +1,7 @@
 package otel
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -39,7 +40,7 @@ func GetStopCollectorTool() tools.Tool {
 			},
 			Required: []string{"target_type", "collector_id"},
 		},
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 			var input StopCollectorInput
 			if err := json.Unmarshal(inputJSON, &input); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal input: %w", err)
@@ -52,7 +53,7 @@ func GetStopCollectorTool() tools.Tool {
 			}
 
 			// Stop the collector
-			err = deployer.Stop(input.CollectorID, input.Parameters)
+			err = deployer.Stop(ctx, input.CollectorID, input.Parameters)
 			if err != nil {
 				return nil, fmt.Errorf("failed to stop collector: %w", err)
 			}
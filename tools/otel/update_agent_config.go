@@ -19,6 +19,7 @@ type UpdateAgentConfigInput struct {
 func GetUpdateAgentConfigTool(client *otelclient.OtelClient) tools.Tool {
 	return tools.Tool{
 		Name:        "update_otel_agent_config",
+		Serial:      true,
 		Description: "Updates the configuration for a specific OpenTelemetry collector agent. The new configuration will be sent to the agent via OpAMP protocol. The agent must support remote configuration capability.",
 		Schema: anthropic.ToolInputSchemaParam{
 			Properties: map[string]interface{}{
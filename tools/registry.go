@@ -1,9 +1,12 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 )
@@ -11,12 +14,68 @@ import (
 // ToolHandler is a function that takes the raw JSON input and returns a result
 type ToolHandler func(inputJSON json.RawMessage) (interface{}, error)
 
+// ContextToolHandler is a tool handler that receives the run context.
+// Handlers should pass ctx to any blocking calls so they stop when the run
+// is cancelled or the tool's timeout expires.
+type ContextToolHandler func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error)
+
+// ToolInterceptor wraps every execution in a registry. It may call next to
+// run the tool, or return a result of its own (e.g. a recorded result when
+// replaying a run).
+type ToolInterceptor func(ctx context.Context, toolName string, inputJSON json.RawMessage, next ContextToolHandler) (interface{}, error)
+
+// toolUseIDKey is the context key for the ID of the tool_use block being executed
+type toolUseIDKey struct{}
+
+// WithToolUseID returns a context carrying the ID of the tool_use block being executed
+func WithToolUseID(ctx context.Context, toolUseID string) context.Context {
+	return context.WithValue(ctx, toolUseIDKey{}, toolUseID)
+}
+
+// ToolUseIDFromContext returns the tool_use ID set by WithToolUseID, or ""
+func ToolUseIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(toolUseIDKey{}).(string)
+	return id
+}
+
+// runIDKey is the context key for the ID of the run executing a tool
+type runIDKey struct{}
+
+// WithRunID returns a context carrying the ID of the run executing a tool
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// RunIDFromContext returns the run ID set by WithRunID, or ""
+func RunIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
 // ToolDefinition combines a tool's schema and handler
 type ToolDefinition struct {
 	Name        string
 	Description string
 	Schema      anthropic.ToolInputSchemaParam
-	Handler     ToolHandler
+	Handler     ContextToolHandler
+	Serial      bool
+	Timeout     time.Duration
+
+	inputSchema *InputSchema // compiled Schema, enforced before Handler runs
+	schemaErr   error        // why Schema could not be compiled
+}
+
+// compileSchema compiles the tool's schema so inputs can be validated
+func (d *ToolDefinition) compileSchema() {
+	d.inputSchema, d.schemaErr = CompileInputSchema(d.Schema)
+}
+
+// validateInput checks an input against the tool's schema
+func (d *ToolDefinition) validateInput(inputJSON json.RawMessage) error {
+	if d.schemaErr != nil {
+		return fmt.Errorf("tool %s has an invalid input schema: %w", d.Name, d.schemaErr)
+	}
+	return d.inputSchema.Validate(d.Name, inputJSON)
 }
 
 // Tool represents a single tool definition that can be registered
@@ -25,16 +84,104 @@ type Tool struct {
 	Description string
 	Schema      anthropic.ToolInputSchemaParam
 	Handler     ToolHandler
+	// ContextHandler is used instead of Handler when set
+	ContextHandler ContextToolHandler
+	// Serial marks tools that must not run concurrently with other tool
+	// calls from the same turn (e.g. tools that mutate shared state)
+	Serial bool
+	// Timeout bounds a single execution of the tool. Zero means the tool
+	// is only bounded by the run context.
+	Timeout time.Duration
+}
+
+// contextHandler returns the tool's handler as a ContextToolHandler,
+// adapting a plain Handler if no ContextHandler is set
+func (t Tool) contextHandler() ContextToolHandler {
+	if t.ContextHandler != nil {
+		return t.ContextHandler
+	}
+	if t.Handler != nil {
+		return WithContext(t.Handler)
+	}
+	return nil
+}
+
+// Execute runs the tool directly, outside of a registry. The input is
+// validated against the tool's schema first, as in a registry.
+func (t Tool) Execute(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+	handler := t.contextHandler()
+	if handler == nil {
+		return nil, fmt.Errorf("tool %s has no handler", t.Name)
+	}
+
+	def := &ToolDefinition{Name: t.Name, Schema: t.Schema}
+	def.compileSchema()
+	if err := def.validateInput(inputJSON); err != nil {
+		return nil, err
+	}
+
+	return runWithTimeout(ctx, t.Timeout, handler, inputJSON)
+}
+
+// WithContext adapts a ToolHandler that does not accept a context.
+// The adapter returns as soon as ctx is done; the handler keeps running in
+// the background and its result is discarded.
+func WithContext(handler ToolHandler) ContextToolHandler {
+	return func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+		type handlerResult struct {
+			value interface{}
+			err   error
+		}
+
+		done := make(chan handlerResult, 1)
+		go func() {
+			value, err := handler(inputJSON)
+			done <- handlerResult{value: value, err: err}
+		}()
+
+		select {
+		case res := <-done:
+			return res.value, res.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// runWithTimeout applies a tool timeout to ctx and invokes the handler
+func runWithTimeout(ctx context.Context, timeout time.Duration, handler ContextToolHandler, inputJSON json.RawMessage) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	result, err := handler(ctx, inputJSON)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("tool timed out after %s: %w", timeout, err)
+	}
+	return result, err
 }
 
 // RegisterTool registers a Tool into the registry
 func (r *ToolRegistry) RegisterTool(tool Tool) {
-	r.tools[tool.Name] = &ToolDefinition{
+	r.add(&ToolDefinition{
 		Name:        tool.Name,
 		Description: tool.Description,
 		Schema:      tool.Schema,
-		Handler:     tool.Handler,
-	}
+		Handler:     tool.contextHandler(),
+		Serial:      tool.Serial,
+		Timeout:     tool.Timeout,
+	})
+}
+
+// add compiles a tool's schema and stores the tool in the registry
+func (r *ToolRegistry) add(def *ToolDefinition) {
+	def.compileSchema()
+	r.tools[def.Name] = def
 }
 
 // RegisterTools registers multiple tools into the registry
@@ -46,7 +193,8 @@ func (r *ToolRegistry) RegisterTools(tools []Tool) {
 
 // ToolRegistry manages tool registrations and invocations
 type ToolRegistry struct {
-	tools map[string]*ToolDefinition
+	tools       map[string]*ToolDefinition
+	interceptor ToolInterceptor
 }
 
 // NewRegistry creates a new tool registry
@@ -59,22 +207,36 @@ func NewRegistry() *ToolRegistry {
 // Register adds a tool with its schema and handler to the registry
 // The handler function will be called with the parsed input struct
 func Register[T any](r *ToolRegistry, toolName string, description string, schema anthropic.ToolInputSchemaParam, handler func(T) (interface{}, error)) {
-	r.tools[toolName] = &ToolDefinition{
+	RegisterContext(r, toolName, description, schema, func(ctx context.Context, input T) (interface{}, error) {
+		return WithContext(func(json.RawMessage) (interface{}, error) {
+			return handler(input)
+		})(ctx, nil)
+	})
+}
+
+// RegisterContext adds a context-aware tool with its schema and handler to the registry
+// The handler function will be called with the run context and the parsed input struct
+func RegisterContext[T any](r *ToolRegistry, toolName string, description string, schema anthropic.ToolInputSchemaParam, handler func(context.Context, T) (interface{}, error)) {
+	r.add(&ToolDefinition{
 		Name:        toolName,
 		Description: description,
 		Schema:      schema,
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+		Handler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 			var input T
 			if err := json.Unmarshal(inputJSON, &input); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal input for tool %s: %w", toolName, err)
 			}
-			return handler(input)
+			return handler(ctx, input)
 		},
-	}
+	})
 }
 
+// contextType is the reflected type of context.Context
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
 // RegisterFunc is a convenience method for registering tools using function reflection
-// The function must accept a single struct parameter and return (interface{}, error)
+// The function must accept a single struct parameter, optionally preceded by a
+// context.Context, and return (interface{}, error)
 func RegisterFunc(r *ToolRegistry, toolName string, description string, schema anthropic.ToolInputSchemaParam, handlerFunc interface{}) error {
 	funcValue := reflect.ValueOf(handlerFunc)
 	funcType := funcValue.Type()
@@ -83,52 +245,93 @@ func RegisterFunc(r *ToolRegistry, toolName string, description string, schema a
 	if funcType.Kind() != reflect.Func {
 		return fmt.Errorf("handler must be a function")
 	}
-	if funcType.NumIn() != 1 {
-		return fmt.Errorf("handler must accept exactly one parameter")
+	takesContext := funcType.NumIn() == 2 && funcType.In(0) == contextType
+	if funcType.NumIn() != 1 && !takesContext {
+		return fmt.Errorf("handler must accept exactly one parameter, optionally preceded by a context.Context")
 	}
 	if funcType.NumOut() != 2 {
 		return fmt.Errorf("handler must return exactly two values (interface{}, error)")
 	}
 
-	inputType := funcType.In(0)
+	inputType := funcType.In(funcType.NumIn() - 1)
 
-	r.tools[toolName] = &ToolDefinition{
-		Name:        toolName,
-		Description: description,
-		Schema:      schema,
-		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
-			// Create new instance of input type
-			inputValue := reflect.New(inputType)
+	call := func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+		// Create new instance of input type
+		inputValue := reflect.New(inputType)
 
-			// Unmarshal JSON into the input struct
-			if err := json.Unmarshal(inputJSON, inputValue.Interface()); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal input for tool %s: %w", toolName, err)
-			}
+		// Unmarshal JSON into the input struct
+		if err := json.Unmarshal(inputJSON, inputValue.Interface()); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal input for tool %s: %w", toolName, err)
+		}
 
-			// Call the handler function
-			results := funcValue.Call([]reflect.Value{inputValue.Elem()})
+		// Call the handler function
+		args := []reflect.Value{inputValue.Elem()}
+		if takesContext {
+			args = append([]reflect.Value{reflect.ValueOf(ctx)}, args...)
+		}
+		results := funcValue.Call(args)
 
-			// Extract return values
-			var resultErr error
-			if !results[1].IsNil() {
-				resultErr = results[1].Interface().(error)
-			}
+		// Extract return values
+		var resultErr error
+		if !results[1].IsNil() {
+			resultErr = results[1].Interface().(error)
+		}
 
-			return results[0].Interface(), resultErr
-		},
+		return results[0].Interface(), resultErr
+	}
+
+	handler := call
+	if !takesContext {
+		// Handlers without a context are abandoned when the context is done
+		handler = func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+			return WithContext(func(inputJSON json.RawMessage) (interface{}, error) {
+				return call(ctx, inputJSON)
+			})(ctx, inputJSON)
+		}
 	}
 
+	r.add(&ToolDefinition{
+		Name:        toolName,
+		Description: description,
+		Schema:      schema,
+		Handler:     handler,
+	})
+
 	return nil
 }
 
 // Execute runs a tool by name with the given input
 func (r *ToolRegistry) Execute(toolName string, inputJSON json.RawMessage) (interface{}, error) {
+	return r.ExecuteContext(context.Background(), toolName, inputJSON)
+}
+
+// ExecuteContext runs a tool by name with the given input, bounded by ctx
+// and the tool's timeout. Inputs that do not match the tool's schema are
+// rejected with a *ValidationError before the handler runs.
+func (r *ToolRegistry) ExecuteContext(ctx context.Context, toolName string, inputJSON json.RawMessage) (interface{}, error) {
 	tool, exists := r.tools[toolName]
 	if !exists {
 		return nil, fmt.Errorf("unknown tool: %s", toolName)
 	}
 
-	return tool.Handler(inputJSON)
+	if err := tool.validateInput(inputJSON); err != nil {
+		return nil, err
+	}
+
+	handler := tool.Handler
+	if r.interceptor != nil {
+		handler = func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+			return r.interceptor(ctx, toolName, inputJSON, tool.Handler)
+		}
+	}
+
+	return runWithTimeout(ctx, tool.Timeout, handler, inputJSON)
+}
+
+// SetInterceptor routes all tool executions in the registry, including tools
+// registered later, through interceptor. A nil interceptor removes it.
+func (r *ToolRegistry) SetInterceptor(interceptor ToolInterceptor) {
+	r.interceptor = interceptor
 }
 
 // ExecuteToolUseBlock processes a ToolUseBlock and returns the result
@@ -142,6 +345,13 @@ func (r *ToolRegistry) HasTool(toolName string) bool {
 	return exists
 }
 
+// IsSerial checks if a tool must be executed serially
+// Unknown tools are not serial; executing them fails immediately
+func (r *ToolRegistry) IsSerial(toolName string) bool {
+	tool, exists := r.tools[toolName]
+	return exists && tool.Serial
+}
+
 // ListTools returns all registered tool names
 func (r *ToolRegistry) ListTools() []string {
 	toolNames := make([]string, 0, len(r.tools))
@@ -153,9 +363,17 @@ func (r *ToolRegistry) ListTools() []string {
 
 // GetToolParams returns Anthropic tool parameters for all registered tools
 // This is used to pass to the Messages API to inform Claude what tools are available
+// Tools are sorted by name so the list is identical across requests and can be prompt cached
 func (r *ToolRegistry) GetToolParams() []anthropic.ToolParam {
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
 	params := make([]anthropic.ToolParam, 0, len(r.tools))
-	for _, tool := range r.tools {
+	for _, name := range names {
+		tool := r.tools[name]
 		params = append(params, anthropic.ToolParam{
 			Name:        tool.Name,
 			Description: anthropic.String(tool.Description),
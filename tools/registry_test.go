@@ -1,9 +1,11 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 )
@@ -356,3 +358,102 @@ func TestRegisterFunc(t *testing.T) {
 		t.Errorf("Expected 42, got %v", result)
 	}
 }
+
+// TestIsSerial verifies the serial flag is carried through RegisterTool
+func TestIsSerial(t *testing.T) {
+	registry := NewRegistry()
+
+	handler := func(inputJSON json.RawMessage) (interface{}, error) { return nil, nil }
+	registry.RegisterTools([]Tool{
+		{Name: "read", Handler: handler},
+		{Name: "write", Handler: handler, Serial: true},
+	})
+
+	if registry.IsSerial("read") {
+		t.Error("IsSerial returned true for non-serial tool")
+	}
+	if !registry.IsSerial("write") {
+		t.Error("IsSerial returned false for serial tool")
+	}
+	if registry.IsSerial("nonexistent") {
+		t.Error("IsSerial returned true for nonexistent tool")
+	}
+}
+
+// TestExecuteContextTimeout verifies tool timeouts interrupt context-aware handlers
+func TestExecuteContextTimeout(t *testing.T) {
+	registry := NewRegistry()
+
+	registry.RegisterTool(Tool{
+		Name: "hang",
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		Timeout: 10 * time.Millisecond,
+	})
+
+	_, err := registry.ExecuteContext(context.Background(), "hang", json.RawMessage(`{}`))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+// TestExecuteContextCancelLegacyHandler verifies handlers without a context
+// are abandoned when the run context is cancelled
+func TestExecuteContextCancelLegacyHandler(t *testing.T) {
+	registry := NewRegistry()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	registry.RegisterTool(Tool{
+		Name: "blocking",
+		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+			<-release
+			return "done", nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := registry.ExecuteContext(ctx, "blocking", json.RawMessage(`{}`))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context canceled, got %v", err)
+	}
+}
+
+// TestRegisterFuncWithContext verifies reflection-based registration of context-aware handlers
+func TestRegisterFuncWithContext(t *testing.T) {
+	registry := NewRegistry()
+
+	type EchoInput struct {
+		Value string `json:"value"`
+	}
+
+	type ctxKey struct{}
+
+	echoFunc := func(ctx context.Context, input EchoInput) (interface{}, error) {
+		return ctx.Value(ctxKey{}).(string) + input.Value, nil
+	}
+
+	schema := anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"value": map[string]interface{}{"type": "string"},
+		},
+	}
+
+	if err := RegisterFunc(registry, "echo", "Echoes a value", schema, echoFunc); err != nil {
+		t.Fatalf("RegisterFunc failed: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "prefix-")
+	result, err := registry.ExecuteContext(ctx, "echo", json.RawMessage(`{"value": "x"}`))
+	if err != nil {
+		t.Fatalf("ExecuteContext failed: %v", err)
+	}
+	if result != "prefix-x" {
+		t.Errorf("Expected 'prefix-x', got %v", result)
+	}
+}
@@ -7,23 +7,69 @@ import (
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/mottibechhofer/otel-ai-engineer/otlpgen"
+	"github.com/mottibechhofer/otel-ai-engineer/otlpsink"
 	"github.com/mottibechhofer/otel-ai-engineer/sandbox"
+	"github.com/mottibechhofer/otel-ai-engineer/server/storage"
 	"github.com/mottibechhofer/otel-ai-engineer/tools"
 )
 
 var sandboxManager *sandbox.Manager
 
-// InitializeSandboxTools initializes the sandbox manager
+// Storage for sandboxes and their validations - this will be injected
+var sandboxStorage storage.Storage
+
+// SetSandboxStorage sets the storage sandboxes are persisted to. It must be
+// called before InitializeSandboxTools and ReconcileSandboxes for sandboxes
+// to survive a restart.
+func SetSandboxStorage(stor storage.Storage) {
+	sandboxStorage = stor
+}
+
+// Sink sandboxes export a copy of their telemetry to - this will be injected
+var (
+	captureSink     *otlpsink.Sink
+	captureEndpoint string
+)
+
+// SetCaptureSink sets the OTLP sink sandboxes export to, reachable from
+// collector containers at endpoint. It must be called before
+// InitializeSandboxTools for validations to assert on exported telemetry.
+func SetCaptureSink(sink *otlpsink.Sink, endpoint string) {
+	captureSink = sink
+	captureEndpoint = endpoint
+}
+
+// InitializeSandboxTools initializes the sandbox manager. Call
+// ReconcileSandboxes afterwards to restore stored sandboxes.
 func InitializeSandboxTools() error {
 	logger := sandbox.NewSimpleLogger("sandbox")
-	var err error
-	sandboxManager, err = sandbox.NewManager(logger)
+	manager, err := sandbox.NewManager(logger, sandboxStorage)
 	if err != nil {
 		return fmt.Errorf("failed to initialize sandbox manager: %w", err)
 	}
+
+	if captureSink != nil {
+		manager.EnableCapture(captureSink, captureEndpoint)
+	}
+
+	sandboxManager = manager
 	return nil
 }
 
+// ReconcileSandboxes restores stored sandboxes into the sandbox manager and
+// reconciles them with Docker. It runs once at startup, after
+// InitializeSandboxTools, and does nothing without storage.
+func ReconcileSandboxes(ctx context.Context) (*sandbox.ReconcileReport, error) {
+	if sandboxManager == nil {
+		return nil, fmt.Errorf("sandbox manager is not initialized")
+	}
+	if sandboxStorage == nil {
+		return &sandbox.ReconcileReport{}, nil
+	}
+	return sandboxManager.Reconcile(ctx)
+}
+
 // GetSandboxManager returns the global sandbox manager
 func GetSandboxManager() *sandbox.Manager {
 	return sandboxManager
@@ -42,6 +88,61 @@ func GetSandboxTools() []tools.Tool {
 	return getSandboxToolsList()
 }
 
+// Schemas of the telemetry options shared by create_sandbox, start_telemetry
+// and compare_collector_configs
+var (
+	scenarioSchema = map[string]interface{}{
+		"type":        "object",
+		"description": "Telemetry scenario (default: an online shop with frontend, checkout, inventory and payment services). Fields: services (name, version, resource, operations with name, kind, duration_ms, attributes and calls to {service, operation}), resource, attributes, error_ratio, cardinality, cardinality_key, metrics (name, type counter/gauge/histogram/exponential_histogram/summary, unit, attributes, value, buckets) and logs (messages, severities, structured, attributes)",
+	}
+	errorRatioSchema = map[string]interface{}{
+		"type":        "number",
+		"description": "Fraction of traces that fail and logs written at error severity, from 0 to 1 (default: the scenario's, 0.05 for the default scenario)",
+		"minimum":     0,
+		"maximum":     1,
+	}
+	cardinalitySchema = map[string]interface{}{
+		"type":        "number",
+		"description": "Add a user.id attribute with this many distinct values to all telemetry, to test handling of high-cardinality data (default: off)",
+		"minimum":     0,
+	}
+	replaySchema = map[string]interface{}{
+		"type":        "object",
+		"description": "Replay recorded telemetry instead of generating it, e.g. files a production collector's file exporter wrote, to check a config against real payloads. Sent over OTLP/HTTP.",
+		"properties": map[string]interface{}{
+			"fixtures": map[string]interface{}{
+				"type":        "array",
+				"description": "Files of OTLP JSON (one request per line) or length-prefixed OTLP protobuf, optionally gzipped",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "Path of the file",
+						},
+						"signal": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{otlpsink.SignalTraces, otlpsink.SignalMetrics, otlpsink.SignalLogs},
+							"description": "Signal of a protobuf file, if its name doesn't contain traces, metrics or logs",
+						},
+					},
+					"required": []string{"path"},
+				},
+			},
+			"speed": map[string]interface{}{
+				"type":        "number",
+				"description": "Replay speed relative to the recording, e.g. 10 for ten times faster (default: 1)",
+				"minimum":     0,
+			},
+			"rebase_timestamps": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Move timestamps to the time of replay, so processors that look at age, like tail sampling, treat the data as current (default: false)",
+			},
+		},
+		"required": []string{"fixtures"},
+	}
+)
+
 // getSandboxToolsList returns the sandbox tools array (internal helper)
 func getSandboxToolsList() []tools.Tool {
 	return []tools.Tool{
@@ -90,16 +191,20 @@ func getSandboxToolsList() []tools.Tool {
 						"type":        "number",
 						"description": "Logs per second to generate (default: 1)",
 					},
+					"scenario":    scenarioSchema,
+					"error_ratio": errorRatioSchema,
+					"cardinality": cardinalitySchema,
 				},
 				Required: []string{"name", "collector_config"},
 			},
-			Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+			ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 				var input CreateSandboxInput
 				if err := json.Unmarshal(inputJSON, &input); err != nil {
 					return nil, fmt.Errorf("failed to unmarshal input: %w", err)
 				}
-				return createSandboxHandler(input)
+				return createSandboxHandler(ctx, input)
 			},
+			Timeout: 5 * time.Minute,
 		},
 		{
 			Name:        "list_sandboxes",
@@ -137,7 +242,7 @@ func getSandboxToolsList() []tools.Tool {
 		},
 		{
 			Name:        "start_telemetry",
-			Description: "Start generating synthetic telemetry data in a sandbox using telemetrygen. This sends traces, metrics, and/or logs to the collector.",
+			Description: "Start generating synthetic telemetry data in a sandbox. This sends traces from a multi-service scenario, metrics of every type and structured logs to the collector, e.g. to exercise tail sampling or attribute processors. Scenario options given here replace the sandbox's for this run. Alternatively, replay recorded telemetry.",
 			Schema: anthropic.ToolInputSchemaParam{
 				Properties: map[string]interface{}{
 					"sandbox_id": map[string]interface{}{
@@ -146,26 +251,31 @@ func getSandboxToolsList() []tools.Tool {
 					},
 					"duration": map[string]interface{}{
 						"type":        "number",
-						"description": "How long to generate telemetry in seconds (default: 30)",
+						"description": "How long to generate telemetry in seconds (default: 30, or all of a replay)",
 					},
 					"auto_validate": map[string]interface{}{
 						"type":        "boolean",
 						"description": "Automatically run validation after telemetry generation completes (default: false)",
 					},
+					"scenario":    scenarioSchema,
+					"error_ratio": errorRatioSchema,
+					"cardinality": cardinalitySchema,
+					"replay":      replaySchema,
 				},
 				Required: []string{"sandbox_id"},
 			},
-			Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+			ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 				var input StartTelemetryInput
 				if err := json.Unmarshal(inputJSON, &input); err != nil {
 					return nil, fmt.Errorf("failed to unmarshal input: %w", err)
 				}
-				return startTelemetryHandler(input)
+				return startTelemetryHandler(ctx, input)
 			},
+			Timeout: 2 * time.Minute,
 		},
 		{
 			Name:        "validate_sandbox",
-			Description: "Validate a sandbox configuration and operation. This checks for configuration issues, pipeline problems, telemetry flow, and provides AI-powered recommendations.",
+			Description: "Validate a sandbox configuration and operation. This checks for configuration issues, pipeline problems, telemetry flow, and provides AI-powered recommendations. Assertions check the telemetry the collector actually exported, e.g. that every span has deployment.environment or that no attribute matches credit_card.",
 			Schema: anthropic.ToolInputSchemaParam{
 				Properties: map[string]interface{}{
 					"sandbox_id": map[string]interface{}{
@@ -184,16 +294,69 @@ func getSandboxToolsList() []tools.Tool {
 						"type":        "boolean",
 						"description": "Run AI-powered analysis and generate recommendations (default: true)",
 					},
+					"assertions": map[string]interface{}{
+						"type":        "array",
+						"description": "Assertions on the telemetry the collector exported since the sandbox was created or its captured telemetry was last cleared",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"type": map[string]interface{}{
+									"type": "string",
+									"enum": []string{
+										otlpsink.AssertAttributePresent,
+										otlpsink.AssertAttributeAbsent,
+										otlpsink.AssertAttributeEquals,
+										otlpsink.AssertAttributeMatches,
+										otlpsink.AssertNoAttributeMatches,
+										otlpsink.AssertMinCount,
+									},
+									"description": "attribute_present/attribute_absent/attribute_equals/attribute_matches check key on every item, no_attribute_matches checks that no attribute key or string value matches pattern, min_count checks at least count items arrived",
+								},
+								"signal": map[string]interface{}{
+									"type":        "string",
+									"enum":        []string{otlpsink.SignalTraces, otlpsink.SignalMetrics, otlpsink.SignalLogs},
+									"description": "Signal to check (default: all)",
+								},
+								"key": map[string]interface{}{
+									"type":        "string",
+									"description": "Attribute key, e.g. deployment.environment",
+								},
+								"value": map[string]interface{}{
+									"type":        "string",
+									"description": "Expected value for attribute_equals",
+								},
+								"pattern": map[string]interface{}{
+									"type":        "string",
+									"description": "Regular expression for attribute_matches and no_attribute_matches",
+								},
+								"count": map[string]interface{}{
+									"type":        "integer",
+									"description": "Minimum number of items for min_count",
+								},
+								"where": map[string]interface{}{
+									"type":        "string",
+									"enum":        []string{"attributes", "resource"},
+									"description": "Check only item or resource attributes (default: both)",
+								},
+								"name": map[string]interface{}{
+									"type":        "string",
+									"description": "Regular expression limiting the check to spans, metrics or log bodies with a matching name",
+								},
+							},
+							"required": []string{"type"},
+						},
+					},
 				},
 				Required: []string{"sandbox_id"},
 			},
-			Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+			ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 				var input ValidateSandboxInput
 				if err := json.Unmarshal(inputJSON, &input); err != nil {
 					return nil, fmt.Errorf("failed to unmarshal input: %w", err)
 				}
-				return validateSandboxHandler(input)
+				return validateSandboxHandler(ctx, input)
 			},
+			Timeout: 2 * time.Minute,
 		},
 		{
 			Name:        "get_sandbox_logs",
@@ -211,13 +374,14 @@ func getSandboxToolsList() []tools.Tool {
 				},
 				Required: []string{"sandbox_id"},
 			},
-			Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+			ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 				var input GetSandboxLogsInput
 				if err := json.Unmarshal(inputJSON, &input); err != nil {
 					return nil, fmt.Errorf("failed to unmarshal input: %w", err)
 				}
-				return getSandboxLogsHandler(input)
+				return getSandboxLogsHandler(ctx, input)
 			},
+			Timeout: 30 * time.Second,
 		},
 		{
 			Name:        "get_sandbox_metrics",
@@ -231,13 +395,48 @@ func getSandboxToolsList() []tools.Tool {
 				},
 				Required: []string{"sandbox_id"},
 			},
-			Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+			ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 				var input GetSandboxMetricsInput
 				if err := json.Unmarshal(inputJSON, &input); err != nil {
 					return nil, fmt.Errorf("failed to unmarshal input: %w", err)
 				}
-				return getSandboxMetricsHandler(input)
+				return getSandboxMetricsHandler(ctx, input)
+			},
+			Timeout: 30 * time.Second,
+		},
+		{
+			Name:        "get_captured_telemetry",
+			Description: "Retrieve the spans, metric points and log records a sandbox's collector exported, as received by the built-in OTLP capture sink. Use it to check what the configuration actually sends, e.g. which attributes survive processors.",
+			Schema: anthropic.ToolInputSchemaParam{
+				Properties: map[string]interface{}{
+					"sandbox_id": map[string]interface{}{
+						"type":        "string",
+						"description": "ID of the sandbox",
+					},
+					"signal": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{otlpsink.SignalTraces, otlpsink.SignalMetrics, otlpsink.SignalLogs},
+						"description": "Only return this signal (default: all)",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Number of most recent items to return per signal (default: 20)",
+					},
+					"clear": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Discard the captured telemetry after returning it, so later checks only see new telemetry",
+					},
+				},
+				Required: []string{"sandbox_id"},
+			},
+			ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+				var input GetCapturedTelemetryInput
+				if err := json.Unmarshal(inputJSON, &input); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal input: %w", err)
+				}
+				return getCapturedTelemetryHandler(ctx, input)
 			},
+			Timeout: 30 * time.Second,
 		},
 		{
 			Name:        "stop_sandbox",
@@ -251,13 +450,14 @@ func getSandboxToolsList() []tools.Tool {
 				},
 				Required: []string{"sandbox_id"},
 			},
-			Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+			ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 				var input StopSandboxInput
 				if err := json.Unmarshal(inputJSON, &input); err != nil {
 					return nil, fmt.Errorf("failed to unmarshal input: %w", err)
 				}
-				return stopSandboxHandler(input)
+				return stopSandboxHandler(ctx, input)
 			},
+			Timeout: time.Minute,
 		},
 		{
 			Name:        "delete_sandbox",
@@ -271,14 +471,91 @@ func getSandboxToolsList() []tools.Tool {
 				},
 				Required: []string{"sandbox_id"},
 			},
-			Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+			ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
 				var input DeleteSandboxInput
 				if err := json.Unmarshal(inputJSON, &input); err != nil {
 					return nil, fmt.Errorf("failed to unmarshal input: %w", err)
 				}
-				return deleteSandboxHandler(input)
+				return deleteSandboxHandler(ctx, input)
 			},
+			Timeout: time.Minute,
 		},
+		compareCollectorConfigsTool(),
+	}
+}
+
+// GetCompareTools returns the tool comparing collector configs, for agents
+// that change configs without managing sandboxes themselves. It uses the
+// sandbox manager the server initializes, and fails without one.
+func GetCompareTools() []tools.Tool {
+	return []tools.Tool{compareCollectorConfigsTool()}
+}
+
+func compareCollectorConfigsTool() tools.Tool {
+	return tools.Tool{
+		Name:        "compare_collector_configs",
+		Description: "Compare a candidate OpenTelemetry collector config against a baseline, e.g. the config deployed now, before rolling it out. Both run side by side in one sandbox network and receive identical telemetry. The report covers throughput, refused, dropped and failed counts, the attribute sets and cardinality of what each exported, and CPU and memory use. The collectors are removed afterwards.",
+		Schema: anthropic.ToolInputSchemaParam{
+			Properties: map[string]interface{}{
+				"baseline_config": map[string]interface{}{
+					"type":        "string",
+					"description": "Baseline collector configuration in YAML format, usually the current one",
+				},
+				"candidate_config": map[string]interface{}{
+					"type":        "string",
+					"description": "Candidate collector configuration in YAML format",
+				},
+				"collector_version": map[string]interface{}{
+					"type":        "string",
+					"description": "Collector version to use for both (e.g., '0.110.0', 'latest'). Defaults to 'latest'",
+				},
+				"duration": map[string]interface{}{
+					"type":        "number",
+					"description": "How long to send telemetry in seconds (default: 30, or all of a replay)",
+					"minimum":     0,
+					"maximum":     300,
+				},
+				"settle_time": map[string]interface{}{
+					"type":        "number",
+					"description": "Seconds to wait after sending before measuring, so batches and queues drain (default: 5). Raise it above decision_wait when comparing tail sampling.",
+					"minimum":     0,
+					"maximum":     120,
+				},
+				"signals": map[string]interface{}{
+					"type":        "array",
+					"description": "Signals to generate (default: all)",
+					"items": map[string]interface{}{
+						"type": "string",
+						"enum": []string{otlpsink.SignalTraces, otlpsink.SignalMetrics, otlpsink.SignalLogs},
+					},
+				},
+				"trace_rate": map[string]interface{}{
+					"type":        "number",
+					"description": "Traces per second to generate (default: 1)",
+				},
+				"metric_rate": map[string]interface{}{
+					"type":        "number",
+					"description": "Metrics per second to generate (default: 1)",
+				},
+				"log_rate": map[string]interface{}{
+					"type":        "number",
+					"description": "Logs per second to generate (default: 1)",
+				},
+				"scenario":    scenarioSchema,
+				"error_ratio": errorRatioSchema,
+				"cardinality": cardinalitySchema,
+				"replay":      replaySchema,
+			},
+			Required: []string{"baseline_config", "candidate_config"},
+		},
+		ContextHandler: func(ctx context.Context, inputJSON json.RawMessage) (interface{}, error) {
+			var input CompareCollectorConfigsInput
+			if err := json.Unmarshal(inputJSON, &input); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal input: %w", err)
+			}
+			return compareCollectorConfigsHandler(ctx, input)
+		},
+		Timeout: 10 * time.Minute,
 	}
 }
 
@@ -306,6 +583,43 @@ type CreateSandboxInput struct {
 	TraceRate        int    `json:"trace_rate"`
 	MetricRate       int    `json:"metric_rate"`
 	LogRate          int    `json:"log_rate"`
+	ScenarioInput
+}
+
+// ScenarioInput holds the telemetry scenario options
+type ScenarioInput struct {
+	Scenario    *otlpgen.Scenario `json:"scenario"`
+	ErrorRatio  *float64          `json:"error_ratio"`
+	Cardinality int               `json:"cardinality"`
+}
+
+// apply returns base with the options applied, or base itself if none are
+// set. A nil base means the default scenario.
+func (in ScenarioInput) apply(base *otlpgen.Scenario) (*otlpgen.Scenario, error) {
+	if in.Scenario == nil && in.ErrorRatio == nil && in.Cardinality == 0 {
+		return base, nil
+	}
+
+	var scenario otlpgen.Scenario
+	switch {
+	case in.Scenario != nil:
+		scenario = *in.Scenario
+	case base != nil:
+		scenario = *base
+	default:
+		scenario = *otlpgen.DefaultScenario()
+	}
+	if in.ErrorRatio != nil {
+		scenario.ErrorRatio = *in.ErrorRatio
+	}
+	if in.Cardinality > 0 {
+		scenario.Cardinality = in.Cardinality
+	}
+
+	if err := scenario.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	return &scenario, nil
 }
 
 type ListSandboxesInput struct{}
@@ -315,16 +629,19 @@ type GetSandboxInput struct {
 }
 
 type StartTelemetryInput struct {
-	SandboxID    string `json:"sandbox_id"`
-	Duration     int    `json:"duration"`
-	AutoValidate bool   `json:"auto_validate"`
+	SandboxID    string                `json:"sandbox_id"`
+	Duration     int                   `json:"duration"`
+	AutoValidate bool                  `json:"auto_validate"`
+	Replay       *sandbox.ReplaySource `json:"replay"`
+	ScenarioInput
 }
 
 type ValidateSandboxInput struct {
-	SandboxID      string `json:"sandbox_id"`
-	CollectLogs    bool   `json:"collect_logs"`
-	CollectMetrics bool   `json:"collect_metrics"`
-	AIAnalysis     bool   `json:"ai_analysis"`
+	SandboxID      string               `json:"sandbox_id"`
+	CollectLogs    bool                 `json:"collect_logs"`
+	CollectMetrics bool                 `json:"collect_metrics"`
+	AIAnalysis     bool                 `json:"ai_analysis"`
+	Assertions     []otlpsink.Assertion `json:"assertions"`
 }
 
 type GetSandboxLogsInput struct {
@@ -336,6 +653,27 @@ type GetSandboxMetricsInput struct {
 	SandboxID string `json:"sandbox_id"`
 }
 
+type GetCapturedTelemetryInput struct {
+	SandboxID string `json:"sandbox_id"`
+	Signal    string `json:"signal"`
+	Limit     int    `json:"limit"`
+	Clear     bool   `json:"clear"`
+}
+
+type CompareCollectorConfigsInput struct {
+	BaselineConfig   string                `json:"baseline_config"`
+	CandidateConfig  string                `json:"candidate_config"`
+	CollectorVersion string                `json:"collector_version"`
+	Duration         int                   `json:"duration"`
+	SettleTime       int                   `json:"settle_time"`
+	Signals          []string              `json:"signals"`
+	TraceRate        int                   `json:"trace_rate"`
+	MetricRate       int                   `json:"metric_rate"`
+	LogRate          int                   `json:"log_rate"`
+	Replay           *sandbox.ReplaySource `json:"replay"`
+	ScenarioInput
+}
+
 type StopSandboxInput struct {
 	SandboxID string `json:"sandbox_id"`
 }
@@ -346,8 +684,11 @@ type DeleteSandboxInput struct {
 
 // Tool handlers
 
-func createSandboxHandler(input CreateSandboxInput) (interface{}, error) {
-	ctx := context.Background()
+func createSandboxHandler(ctx context.Context, input CreateSandboxInput) (interface{}, error) {
+	scenario, err := input.ScenarioInput.apply(nil)
+	if err != nil {
+		return nil, err
+	}
 
 	req := sandbox.CreateSandboxRequest{
 		Name:             input.Name,
@@ -361,6 +702,7 @@ func createSandboxHandler(input CreateSandboxInput) (interface{}, error) {
 			TraceRate:       input.TraceRate,
 			MetricRate:      input.MetricRate,
 			LogRate:         input.LogRate,
+			Scenario:        scenario,
 		},
 	}
 
@@ -399,42 +741,64 @@ func getSandboxHandler(input GetSandboxInput) (interface{}, error) {
 	}, nil
 }
 
-func startTelemetryHandler(input StartTelemetryInput) (interface{}, error) {
-	ctx := context.Background()
-
+func startTelemetryHandler(ctx context.Context, input StartTelemetryInput) (interface{}, error) {
 	duration := time.Duration(input.Duration) * time.Second
-	if duration == 0 {
+	if duration == 0 && input.Replay == nil {
 		duration = 30 * time.Second
 	}
 
 	req := sandbox.StartSandboxRequest{
 		Duration:     duration,
 		AutoValidate: input.AutoValidate,
+		Replay:       input.Replay,
 	}
 
-	err := sandboxManager.StartTelemetry(ctx, input.SandboxID, req)
+	sb, err := sandboxManager.GetSandbox(input.SandboxID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start telemetry: %w", err)
 	}
+	scenario, err := input.ScenarioInput.apply(sb.TelemetryConfig.Scenario)
+	if err != nil {
+		return nil, err
+	}
+	if scenario != sb.TelemetryConfig.Scenario {
+		telemetryConfig := sb.TelemetryConfig
+		telemetryConfig.Scenario = scenario
+		req.TelemetryConfig = &telemetryConfig
+	}
 
+	err = sandboxManager.StartTelemetry(ctx, input.SandboxID, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start telemetry: %w", err)
+	}
+
+	message := fmt.Sprintf("Telemetry generation started for %v", duration)
+	if input.Replay != nil {
+		message = fmt.Sprintf("Replay of %d fixture(s) started", len(input.Replay.Fixtures))
+	}
 	return map[string]interface{}{
 		"success": true,
-		"message": fmt.Sprintf("Telemetry generation started for %v", duration),
+		"message": message,
 	}, nil
 }
 
-func validateSandboxHandler(input ValidateSandboxInput) (interface{}, error) {
-	ctx := context.Background()
-
+func validateSandboxHandler(ctx context.Context, input ValidateSandboxInput) (interface{}, error) {
 	// Default to true if not specified
 	collectLogs := true
 	collectMetrics := true
 	aiAnalysis := true
 
+	for _, assertion := range input.Assertions {
+		if err := assertion.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid assertion %q: %w", assertion.String(), err)
+		}
+	}
+
 	req := sandbox.ValidateSandboxRequest{
 		CollectLogs:    collectLogs,
 		CollectMetrics: collectMetrics,
 		AIAnalysis:     aiAnalysis,
+		Assertions:     input.Assertions,
 	}
 
 	result, err := sandboxManager.ValidateSandbox(ctx, input.SandboxID, req)
@@ -448,9 +812,7 @@ func validateSandboxHandler(input ValidateSandboxInput) (interface{}, error) {
 	}, nil
 }
 
-func getSandboxLogsHandler(input GetSandboxLogsInput) (interface{}, error) {
-	ctx := context.Background()
-
+func getSandboxLogsHandler(ctx context.Context, input GetSandboxLogsInput) (interface{}, error) {
 	tail := input.Tail
 	if tail == 0 {
 		tail = 100
@@ -468,9 +830,7 @@ func getSandboxLogsHandler(input GetSandboxLogsInput) (interface{}, error) {
 	}, nil
 }
 
-func getSandboxMetricsHandler(input GetSandboxMetricsInput) (interface{}, error) {
-	ctx := context.Background()
-
+func getSandboxMetricsHandler(ctx context.Context, input GetSandboxMetricsInput) (interface{}, error) {
 	metrics, err := sandboxManager.GetCollectorMetrics(ctx, input.SandboxID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get metrics: %w", err)
@@ -482,9 +842,31 @@ func getSandboxMetricsHandler(input GetSandboxMetricsInput) (interface{}, error)
 	}, nil
 }
 
-func stopSandboxHandler(input StopSandboxInput) (interface{}, error) {
-	ctx := context.Background()
+func getCapturedTelemetryHandler(ctx context.Context, input GetCapturedTelemetryInput) (interface{}, error) {
+	limit := input.Limit
+	if limit == 0 {
+		limit = 20
+	}
+
+	telemetry, err := sandboxManager.CapturedTelemetry(input.SandboxID, input.Signal, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get captured telemetry: %w", err)
+	}
+
+	if input.Clear {
+		if err := sandboxManager.ClearCapturedTelemetry(input.SandboxID); err != nil {
+			return nil, fmt.Errorf("failed to clear captured telemetry: %w", err)
+		}
+	}
 
+	return map[string]interface{}{
+		"success":   true,
+		"telemetry": telemetry,
+		"cleared":   input.Clear,
+	}, nil
+}
+
+func stopSandboxHandler(ctx context.Context, input StopSandboxInput) (interface{}, error) {
 	err := sandboxManager.StopSandbox(ctx, input.SandboxID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stop sandbox: %w", err)
@@ -496,9 +878,7 @@ func stopSandboxHandler(input StopSandboxInput) (interface{}, error) {
 	}, nil
 }
 
-func deleteSandboxHandler(input DeleteSandboxInput) (interface{}, error) {
-	ctx := context.Background()
-
+func deleteSandboxHandler(ctx context.Context, input DeleteSandboxInput) (interface{}, error) {
 	err := sandboxManager.DeleteSandbox(ctx, input.SandboxID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete sandbox: %w", err)
@@ -509,3 +889,55 @@ func deleteSandboxHandler(input DeleteSandboxInput) (interface{}, error) {
 		"message": "Sandbox deleted successfully",
 	}, nil
 }
+
+func compareCollectorConfigsHandler(ctx context.Context, input CompareCollectorConfigsInput) (interface{}, error) {
+	if sandboxManager == nil {
+		return nil, fmt.Errorf("sandbox manager is not initialized")
+	}
+
+	scenario, err := input.ScenarioInput.apply(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	signals := input.Signals
+	if len(signals) == 0 {
+		signals = []string{otlpsink.SignalTraces, otlpsink.SignalMetrics, otlpsink.SignalLogs}
+	}
+	telemetryConfig := sandbox.TelemetryConfig{
+		TraceRate:    input.TraceRate,
+		MetricRate:   input.MetricRate,
+		LogRate:      input.LogRate,
+		OTLPProtocol: otlpgen.ProtocolGRPC,
+		Scenario:     scenario,
+	}
+	for _, signal := range signals {
+		switch signal {
+		case otlpsink.SignalTraces:
+			telemetryConfig.GenerateTraces = true
+		case otlpsink.SignalMetrics:
+			telemetryConfig.GenerateMetrics = true
+		case otlpsink.SignalLogs:
+			telemetryConfig.GenerateLogs = true
+		}
+	}
+
+	report, err := sandboxManager.CompareConfigs(ctx, sandbox.CompareConfigsRequest{
+		BaselineConfig:   input.BaselineConfig,
+		CandidateConfig:  input.CandidateConfig,
+		CollectorVersion: input.CollectorVersion,
+		Duration:         time.Duration(input.Duration) * time.Second,
+		SettleTime:       time.Duration(input.SettleTime) * time.Second,
+		TelemetryConfig:  &telemetryConfig,
+		Replay:           input.Replay,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare configs: %w", err)
+	}
+
+	return map[string]interface{}{
+		"success":     true,
+		"differences": report.Differences,
+		"report":      report,
+	}, nil
+}
@@ -0,0 +1,371 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// Violation is one way a tool input fails its schema
+type Violation struct {
+	// Field is the path of the offending value, e.g. "config.receivers[0]",
+	// or "" for the input itself
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError reports a tool input that does not match the tool's
+// input schema. It is returned before the handler runs.
+type ValidationError struct {
+	ToolName   string      `json:"tool"`
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		if v.Field == "" {
+			parts = append(parts, v.Message)
+		} else {
+			parts = append(parts, v.Field+": "+v.Message)
+		}
+	}
+	return fmt.Sprintf("invalid input for tool %s: %s", e.ToolName, strings.Join(parts, "; "))
+}
+
+// InputSchema is a compiled tool input schema. It enforces the JSON Schema
+// keywords used by tool schemas: type, properties, required, enum, const,
+// pattern, items, additionalProperties, and the length and range bounds.
+// Other keywords (e.g. format, $ref), and patterns Go's regexp package can't
+// compile, are accepted and ignored.
+type InputSchema struct {
+	root *schemaNode
+}
+
+// schemaNode is one compiled (sub)schema
+type schemaNode struct {
+	types                []string
+	properties           map[string]*schemaNode
+	required             []string
+	additionalProperties *schemaNode // nil allows anything
+	noAdditional         bool        // additionalProperties: false
+	items                *schemaNode
+	enum                 []interface{}
+	constValue           *interface{}
+	pattern              *regexp.Regexp
+	minimum, maximum     *float64
+	minLength, maxLength *int
+	minItems, maxItems   *int
+}
+
+// CompileInputSchema compiles a tool's input schema for validation
+func CompileInputSchema(schema anthropic.ToolInputSchemaParam) (*InputSchema, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode schema: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode schema: %w", err)
+	}
+
+	root, err := compileNode(raw, "")
+	if err != nil {
+		return nil, err
+	}
+	return &InputSchema{root: root}, nil
+}
+
+func compileNode(raw map[string]interface{}, path string) (*schemaNode, error) {
+	node := &schemaNode{}
+
+	switch t := raw["type"].(type) {
+	case string:
+		node.types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				node.types = append(node.types, s)
+			}
+		}
+	}
+
+	if props, ok := raw["properties"].(map[string]interface{}); ok {
+		node.properties = make(map[string]*schemaNode, len(props))
+		for name, prop := range props {
+			propSchema, ok := prop.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			child, err := compileNode(propSchema, joinPath(path, name))
+			if err != nil {
+				return nil, err
+			}
+			node.properties[name] = child
+		}
+	}
+
+	if required, ok := raw["required"].([]interface{}); ok {
+		for _, v := range required {
+			if s, ok := v.(string); ok {
+				node.required = append(node.required, s)
+			}
+		}
+	}
+
+	switch additional := raw["additionalProperties"].(type) {
+	case bool:
+		node.noAdditional = !additional
+	case map[string]interface{}:
+		child, err := compileNode(additional, path+".*")
+		if err != nil {
+			return nil, err
+		}
+		node.additionalProperties = child
+	}
+
+	if items, ok := raw["items"].(map[string]interface{}); ok {
+		child, err := compileNode(items, path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		node.items = child
+	}
+
+	if enum, ok := raw["enum"].([]interface{}); ok {
+		node.enum = enum
+	}
+	if constValue, ok := raw["const"]; ok {
+		node.constValue = &constValue
+	}
+
+	// JSON Schema patterns are ECMA-262 regexes; one RE2 can't compile (e.g.
+	// a lookahead) goes unenforced rather than disabling the whole tool
+	if pattern, ok := raw["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err != nil {
+			log.Printf("Warning: ignoring pattern %q for %s: %v", pattern, displayPath(path), err)
+		} else {
+			node.pattern = re
+		}
+	}
+
+	node.minimum = numberKeyword(raw, "minimum")
+	node.maximum = numberKeyword(raw, "maximum")
+	node.minLength = intKeyword(raw, "minLength")
+	node.maxLength = intKeyword(raw, "maxLength")
+	node.minItems = intKeyword(raw, "minItems")
+	node.maxItems = intKeyword(raw, "maxItems")
+
+	return node, nil
+}
+
+func numberKeyword(raw map[string]interface{}, key string) *float64 {
+	if v, ok := raw[key].(float64); ok {
+		return &v
+	}
+	return nil
+}
+
+func intKeyword(raw map[string]interface{}, key string) *int {
+	if v, ok := raw[key].(float64); ok {
+		n := int(v)
+		return &n
+	}
+	return nil
+}
+
+// Validate checks a tool input against the schema, returning a
+// *ValidationError that lists every violation
+func (s *InputSchema) Validate(toolName string, inputJSON json.RawMessage) error {
+	if len(inputJSON) == 0 {
+		inputJSON = json.RawMessage("{}")
+	}
+
+	var input interface{}
+	if err := json.Unmarshal(inputJSON, &input); err != nil {
+		return &ValidationError{
+			ToolName:   toolName,
+			Violations: []Violation{{Message: fmt.Sprintf("input is not valid JSON: %v", err)}},
+		}
+	}
+
+	var violations []Violation
+	s.root.validate(input, "", &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{ToolName: toolName, Violations: violations}
+}
+
+func (n *schemaNode) validate(value interface{}, path string, violations *[]Violation) {
+	add := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(n.types) > 0 && !matchesAnyType(value, n.types) {
+		add("must be %s, got %s", strings.Join(n.types, " or "), jsonType(value))
+		return
+	}
+
+	if len(n.enum) > 0 && !containsValue(n.enum, value) {
+		add("must be one of %s", formatValues(n.enum))
+	}
+	if n.constValue != nil && !jsonEqual(*n.constValue, value) {
+		add("must be %s", formatValues([]interface{}{*n.constValue}))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range n.required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, Violation{Field: joinPath(path, name), Message: "is required"})
+			}
+		}
+
+		// Visit properties in order so violations are reported deterministically
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if child, ok := n.properties[name]; ok {
+				child.validate(v[name], joinPath(path, name), violations)
+			} else if n.additionalProperties != nil {
+				n.additionalProperties.validate(v[name], joinPath(path, name), violations)
+			} else if n.noAdditional {
+				*violations = append(*violations, Violation{Field: joinPath(path, name), Message: "is not an allowed field"})
+			}
+		}
+
+	case []interface{}:
+		if n.minItems != nil && len(v) < *n.minItems {
+			add("must have at least %d items", *n.minItems)
+		}
+		if n.maxItems != nil && len(v) > *n.maxItems {
+			add("must have at most %d items", *n.maxItems)
+		}
+		if n.items != nil {
+			for i, item := range v {
+				n.items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+
+	case string:
+		length := len([]rune(v))
+		if n.minLength != nil && length < *n.minLength {
+			add("must be at least %d characters", *n.minLength)
+		}
+		if n.maxLength != nil && length > *n.maxLength {
+			add("must be at most %d characters", *n.maxLength)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(v) {
+			add("must match pattern %s", n.pattern.String())
+		}
+
+	case float64:
+		if n.minimum != nil && v < *n.minimum {
+			add("must be at least %v", *n.minimum)
+		}
+		if n.maximum != nil && v > *n.maximum {
+			add("must be at most %v", *n.maximum)
+		}
+	}
+}
+
+// matchesAnyType reports whether a decoded JSON value has one of the types
+func matchesAnyType(value interface{}, types []string) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if jsonEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonEqual compares decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aJSON) == string(bJSON)
+}
+
+func formatValues(values []interface{}) string {
+	data, _ := json.Marshal(values)
+	if len(values) == 1 {
+		return strings.TrimSuffix(strings.TrimPrefix(string(data), "["), "]")
+	}
+	return string(data)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "input"
+	}
+	return path
+}
+
+// ErrorContent formats a tool error as the tool result sent to the model.
+// Validation errors are sent as JSON naming the offending fields, so the
+// model can correct its input.
+func ErrorContent(err error) string {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		data, marshalErr := json.Marshal(struct {
+			Error string `json:"error"`
+			*ValidationError
+		}{Error: "invalid_input", ValidationError: validationErr})
+		if marshalErr == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("Error: %v", err)
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+var deploySchema = anthropic.ToolInputSchemaParam{
+	Properties: map[string]interface{}{
+		"name": map[string]interface{}{
+			"type":    "string",
+			"pattern": "^[a-z][a-z0-9-]*$",
+		},
+		"type": map[string]interface{}{
+			"type": "string",
+			"enum": []string{"docker", "kubernetes"},
+		},
+		"replicas": map[string]interface{}{
+			"type":    "integer",
+			"minimum": 1,
+		},
+		"ports": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "integer"},
+		},
+		"labels": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		},
+	},
+	Required: []string{"name", "type"},
+}
+
+func TestInputSchemaValidate(t *testing.T) {
+	schema, err := CompileInputSchema(deploySchema)
+	if err != nil {
+		t.Fatalf("CompileInputSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		input      string
+		wantFields []string
+	}{
+		{name: "valid", input: `{"name":"otel-1","type":"docker","replicas":2,"ports":[4317],"labels":{"env":"dev"}}`},
+		{name: "missing required", input: `{"replicas":1}`, wantFields: []string{"name", "type"}},
+		{name: "wrong enum", input: `{"name":"otel","type":"vm"}`, wantFields: []string{"type"}},
+		{name: "pattern", input: `{"name":"Otel_1","type":"docker"}`, wantFields: []string{"name"}},
+		{name: "wrong types", input: `{"name":"otel","type":"docker","replicas":1.5,"ports":["4317"]}`, wantFields: []string{"ports[0]", "replicas"}},
+		{name: "minimum", input: `{"name":"otel","type":"docker","replicas":0}`, wantFields: []string{"replicas"}},
+		{name: "additional properties", input: `{"name":"otel","type":"docker","labels":{"env":1}}`, wantFields: []string{"labels.env"}},
+		{name: "not an object", input: `[]`, wantFields: []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate("deploy", json.RawMessage(tt.input))
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() error = %v, want a *ValidationError", err)
+			}
+			var fields []string
+			for _, v := range validationErr.Violations {
+				fields = append(fields, v.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("Validate() violations = %+v, want fields %v", validationErr.Violations, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestCompileInputSchemaUnsupportedPattern(t *testing.T) {
+	// Lookaheads are valid ECMA-262 but not RE2; only that pattern is skipped
+	schema, err := CompileInputSchema(anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"name": map[string]interface{}{"type": "string", "pattern": "^(?!tmp).*$"},
+			"id":   map[string]interface{}{"type": "string", "pattern": "^[0-9]+$"},
+		},
+		Required: []string{"name"},
+	})
+	if err != nil {
+		t.Fatalf("CompileInputSchema() error = %v", err)
+	}
+
+	if err := schema.Validate("test", json.RawMessage(`{"name":"tmp-1"}`)); err != nil {
+		t.Errorf("Validate() error = %v, want the unsupported pattern ignored", err)
+	}
+	err = schema.Validate("test", json.RawMessage(`{"name":"a","id":"x"}`))
+	if err == nil || !strings.Contains(err.Error(), "id") {
+		t.Errorf("Validate() error = %v, want the id pattern still enforced", err)
+	}
+	if err := schema.Validate("test", json.RawMessage(`{}`)); err == nil {
+		t.Error("Validate() accepted an input missing a required field")
+	}
+}
+
+// TestExecuteValidatesInput verifies that invalid inputs never reach the handler
+func TestExecuteValidatesInput(t *testing.T) {
+	registry := NewRegistry()
+
+	called := false
+	registry.RegisterTool(Tool{
+		Name:   "deploy",
+		Schema: deploySchema,
+		Handler: func(inputJSON json.RawMessage) (interface{}, error) {
+			called = true
+			return "deployed", nil
+		},
+	})
+
+	_, err := registry.Execute("deploy", json.RawMessage(`{"name":"otel","type":"vm"}`))
+	if called {
+		t.Error("handler ran for an invalid input")
+	}
+
+	content := ErrorContent(err)
+	var structured struct {
+		Error      string      `json:"error"`
+		Tool       string      `json:"tool"`
+		Violations []Violation `json:"violations"`
+	}
+	if jsonErr := json.Unmarshal([]byte(content), &structured); jsonErr != nil {
+		t.Fatalf("ErrorContent() = %s, want JSON: %v", content, jsonErr)
+	}
+	if structured.Error != "invalid_input" || structured.Tool != "deploy" || len(structured.Violations) != 1 || structured.Violations[0].Field != "type" {
+		t.Errorf("ErrorContent() = %s, want an invalid_input error naming type", content)
+	}
+
+	if result, err := registry.Execute("deploy", json.RawMessage(`{"name":"otel","type":"docker"}`)); err != nil || result != "deployed" {
+		t.Errorf("Execute() = %v, %v, want deployed", result, err)
+	}
+}
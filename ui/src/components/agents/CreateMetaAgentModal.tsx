@@ -26,17 +26,16 @@ export function CreateMetaAgentModal({
   onClose,
   onAgentCreated,
 }: CreateMetaAgentModalProps) {
+  const [goal, setGoal] = useState("");
   const [name, setName] = useState("");
-  const [description, setDescription] = useState("");
-  const [systemPrompt, setSystemPrompt] = useState(
-    "You are a meta-agent that can create other agents. You can analyze tasks and suggest appropriate tools, then create agents with those tools to complete tasks."
-  );
+  const [sampleTasks, setSampleTasks] = useState("");
   const [model, setModel] = useState("claude-sonnet-4-5-20250929");
   const [tools, setTools] = useState<ToolInfo[]>([]);
   const [selectedTools, setSelectedTools] = useState<string[]>([]);
   const [loading, setLoading] = useState(false);
   const [submitting, setSubmitting] = useState(false);
   const [error, setError] = useState<string | null>(null);
+  const [runId, setRunId] = useState<string | null>(null);
 
   useEffect(() => {
     if (isOpen) {
@@ -59,13 +58,8 @@ export function CreateMetaAgentModal({
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
 
-    if (!name.trim() || !description.trim()) {
-      setError("Name and description are required");
-      return;
-    }
-
-    if (selectedTools.length === 0) {
-      setError("At least one tool must be selected");
+    if (!goal.trim()) {
+      setError("Goal is required");
       return;
     }
 
@@ -73,31 +67,33 @@ export function CreateMetaAgentModal({
     setError(null);
 
     try {
-      await apiClient.createMetaAgent({
-        name: name.trim(),
-        description: description.trim(),
-        system_prompt: systemPrompt || undefined,
+      const response = await apiClient.createMetaAgent({
+        goal: goal.trim(),
+        name: name.trim() || undefined,
         model: model || undefined,
-        available_tool_names: selectedTools,
+        available_tool_names: selectedTools.length > 0 ? selectedTools : undefined,
+        sample_tasks: sampleTasks
+          .split("\n")
+          .map((task) => task.trim())
+          .filter((task) => task !== ""),
       });
+      setRunId(response.run_id);
       onAgentCreated();
-      handleClose();
     } catch (err) {
-      setError(err instanceof Error ? err.message : "Failed to create meta-agent");
+      setError(err instanceof Error ? err.message : "Failed to start meta-agent");
     } finally {
       setSubmitting(false);
     }
   };
 
   const handleClose = () => {
+    setGoal("");
     setName("");
-    setDescription("");
-    setSystemPrompt(
-      "You are a meta-agent that can create other agents. You can analyze tasks and suggest appropriate tools, then create agents with those tools to complete tasks."
-    );
+    setSampleTasks("");
     setModel("claude-sonnet-4-5-20250929");
     setSelectedTools([]);
     setError(null);
+    setRunId(null);
     onClose();
   };
 
@@ -113,31 +109,55 @@ export function CreateMetaAgentModal({
     <Dialog open={isOpen} onOpenChange={handleClose}>
       <DialogContent className="max-w-2xl max-h-[90vh] overflow-y-auto">
         <DialogHeader>
-          <DialogTitle>Create Meta-Agent</DialogTitle>
+          <DialogTitle>Design an Agent with the Meta-Agent</DialogTitle>
           <DialogDescription>
-            Create a meta-agent that can build other agents. Select tools the meta-agent will use to analyze tasks and create agents.
+            Describe a goal and the meta-agent designs an agent for it, dry-runs it on sample tasks and registers it once you approve the proposal.
           </DialogDescription>
         </DialogHeader>
 
+        {runId ? (
+          <div className="space-y-4">
+            <div className="text-sm">
+              The meta-agent is designing the agent in run <span className="font-mono">{runId}</span>.
+              It will ask for your approval in Human Actions before registering the agent.
+            </div>
+            <DialogFooter>
+              <Button type="button" onClick={handleClose}>
+                Close
+              </Button>
+            </DialogFooter>
+          </div>
+        ) : (
         <form onSubmit={handleSubmit} className="space-y-4">
           <div>
-            <Label htmlFor="name">Name *</Label>
+            <Label htmlFor="goal">Goal *</Label>
+            <Textarea
+              id="goal"
+              value={goal}
+              onChange={(e) => setGoal(e.target.value)}
+              placeholder="An agent that tunes collector sampling to stay under our trace ingest budget"
+              required
+              rows={3}
+            />
+          </div>
+
+          <div>
+            <Label htmlFor="name">Name</Label>
             <Input
               id="name"
               value={name}
               onChange={(e) => setName(e.target.value)}
-              required
+              placeholder="Let the meta-agent choose"
             />
           </div>
 
           <div>
-            <Label htmlFor="description">Description *</Label>
+            <Label htmlFor="sampleTasks">Sample Tasks (one per line, used for dry runs)</Label>
             <Textarea
-              id="description"
-              value={description}
-              onChange={(e) => setDescription(e.target.value)}
-              required
-              rows={2}
+              id="sampleTasks"
+              value={sampleTasks}
+              onChange={(e) => setSampleTasks(e.target.value)}
+              rows={3}
             />
           </div>
 
@@ -152,17 +172,7 @@ export function CreateMetaAgentModal({
           </div>
 
           <div>
-            <Label htmlFor="systemPrompt">System Prompt</Label>
-            <Textarea
-              id="systemPrompt"
-              value={systemPrompt}
-              onChange={(e) => setSystemPrompt(e.target.value)}
-              rows={4}
-            />
-          </div>
-
-          <div>
-            <Label>Available Tools * (Tools the meta-agent can use)</Label>
+            <Label>Allowed Tools (optional: limit the tools the new agent may use)</Label>
             {loading ? (
               <div className="text-sm text-muted-foreground">Loading tools...</div>
             ) : (
@@ -205,10 +215,11 @@ export function CreateMetaAgentModal({
             </Button>
             <Button type="submit" disabled={submitting}>
               {submitting && <Loader2 className="mr-2 h-4 w-4 animate-spin" />}
-              Create Meta-Agent
+              Start Meta-Agent
             </Button>
           </DialogFooter>
         </form>
+        )}
       </DialogContent>
     </Dialog>
   );
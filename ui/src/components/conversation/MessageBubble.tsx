@@ -145,6 +145,21 @@ export function MessageBubble({ data, timestamp }: MessageBubbleProps) {
               {block.type === "tool_result" && block.tool_result && (
                 <ToolResultInline toolResult={block.tool_result} />
               )}
+
+              {block.type === "thinking" && block.thinking?.thinking && (
+                <details className="text-xs text-muted-foreground">
+                  <summary className="cursor-pointer select-none">Thinking</summary>
+                  <div className="mt-1 whitespace-pre-wrap border-l-2 border-indigo-300 pl-2">
+                    {block.thinking.thinking}
+                  </div>
+                </details>
+              )}
+
+              {block.type === "redacted_thinking" && (
+                <div className="text-xs italic text-muted-foreground">
+                  Thinking redacted
+                </div>
+              )}
             </div>
           ))}
         </div>
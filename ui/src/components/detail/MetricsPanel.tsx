@@ -25,6 +25,11 @@ export function MetricsPanel({ run }: MetricsPanelProps) {
           (↓{formatNumber(run.total_tokens.input_tokens)} ↑
           {formatNumber(run.total_tokens.output_tokens)})
         </span>
+        {!!run.total_tokens.cache_read_input_tokens && (
+          <span className="text-muted-foreground/70 ml-1">
+            cached {formatNumber(run.total_tokens.cache_read_input_tokens)}
+          </span>
+        )}
       </div>
       <div>
         <span className="text-muted-foreground">Duration:</span>{" "}
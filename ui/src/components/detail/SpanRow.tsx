@@ -17,6 +17,9 @@ const SPAN_COLORS = {
   api_call: "bg-blue-500",
   agent_handoff: "bg-purple-500",
   iteration: "bg-green-500",
+  compaction: "bg-slate-500",
+  api_retry: "bg-orange-500",
+  thinking: "bg-indigo-500",
   trace: "bg-gray-500",
 };
 
@@ -25,6 +28,9 @@ const SPAN_TEXT_COLORS = {
   api_call: "text-blue-700",
   agent_handoff: "text-purple-700",
   iteration: "text-green-700",
+  compaction: "text-slate-700",
+  api_retry: "text-orange-700",
+  thinking: "text-indigo-700",
   trace: "text-gray-700",
 };
 
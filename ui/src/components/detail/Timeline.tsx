@@ -12,7 +12,9 @@ import {
   ArrowRight, 
   ArrowLeft, 
   AlertTriangle,
-  GitMerge
+  GitMerge,
+  Shrink,
+  Repeat
 } from "lucide-react";
 
 interface TimelineProps {
@@ -31,6 +33,8 @@ const EVENT_ICONS: Record<string, React.ComponentType<any>> = {
   error: AlertTriangle,
   agent_handoff: GitMerge,
   agent_handoff_complete: Check,
+  compaction: Shrink,
+  api_retry: Repeat,
 };
 
 const EVENT_COLORS: Record<string, string> = {
@@ -45,6 +49,8 @@ const EVENT_COLORS: Record<string, string> = {
   error: "text-red-600 dark:text-red-400",
   agent_handoff: "text-violet-600 dark:text-violet-400",
   agent_handoff_complete: "text-violet-600 dark:text-violet-400",
+  compaction: "text-slate-600 dark:text-slate-400",
+  api_retry: "text-orange-600 dark:text-orange-400",
 };
 
 const EVENT_VARIANTS: Record<string, "default" | "secondary" | "destructive" | "outline"> = {
@@ -59,6 +65,8 @@ const EVENT_VARIANTS: Record<string, "default" | "secondary" | "destructive" | "
   error: "destructive",
   agent_handoff: "default",
   agent_handoff_complete: "secondary",
+  compaction: "outline",
+  api_retry: "destructive",
 };
 
 export function Timeline({ events }: TimelineProps) {
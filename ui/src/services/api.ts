@@ -39,6 +39,7 @@ import type {
   CreateCustomAgentRequest,
   UpdateCustomAgentRequest,
   CreateMetaAgentRequest,
+  CreateMetaAgentResponse,
 } from "../types/agent";
 
 const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || "/api";
@@ -728,7 +729,7 @@ export class ApiClient {
     }
   }
 
-  async createMetaAgent(request: CreateMetaAgentRequest): Promise<Agent> {
+  async createMetaAgent(request: CreateMetaAgentRequest): Promise<CreateMetaAgentResponse> {
     const response = await fetch(`${this.baseUrl}/agents/meta`, {
       method: "POST",
       headers: { "Content-Type": "application/json" },
@@ -1,3 +1,5 @@
+import type { RunBudget } from "./models";
+
 export interface ToolInfo {
   name: string;
   description: string;
@@ -5,6 +7,11 @@ export interface ToolInfo {
   category: string;
 }
 
+export interface ProviderConfig {
+  name: string;
+  model?: string;
+}
+
 export interface Agent {
   id: string;
   name: string;
@@ -15,6 +22,8 @@ export interface Agent {
   type: "built-in" | "custom";
   tools?: ToolInfo[];
   tool_names?: string[];
+  budget?: RunBudget;
+  provider?: ProviderConfig;
   created_at?: string;
   updated_at?: string;
 }
@@ -31,6 +40,8 @@ export interface CreateCustomAgentRequest {
   model?: string;
   max_tokens?: number;
   tool_names: string[];
+  budget?: RunBudget;
+  provider?: ProviderConfig;
 }
 
 export interface UpdateCustomAgentRequest {
@@ -40,13 +51,23 @@ export interface UpdateCustomAgentRequest {
   model?: string;
   max_tokens?: number;
   tool_names?: string[];
+  budget?: RunBudget;
+  provider?: ProviderConfig;
 }
 
 export interface CreateMetaAgentRequest {
-  name: string;
-  description: string;
+  goal: string;
+  name?: string;
+  description?: string;
   system_prompt?: string;
   model?: string;
-  available_tool_names: string[];
+  available_tool_names?: string[];
+  sample_tasks?: string[];
+}
+
+export interface CreateMetaAgentResponse {
+  run_id: string;
+  agent_id: string;
+  status: string;
 }
 
@@ -9,7 +9,11 @@ export type EventType =
   | 'file_change'
   | 'error'
   | 'api_request'
-  | 'api_response';
+  | 'api_response'
+  | 'text_delta'
+  | 'tool_input_delta'
+  | 'compaction'
+  | 'api_retry';
 
 export interface AgentEvent {
   id: string;
@@ -26,10 +30,23 @@ export interface RunStartData {
   model: string;
   max_tokens: number;
   system_prompt?: string;
+  parent_run_id?: string;
 }
 
+export type RunEndReason =
+  | 'completed'
+  | 'cancelled'
+  | 'timeout'
+  | 'error'
+  | 'max_tokens'
+  | 'max_iterations'
+  | 'budget_exceeded'
+  | 'refusal';
+
 export interface RunEndData {
   success: boolean;
+  reason?: RunEndReason;
+  budget_limit?: 'input_tokens' | 'output_tokens' | 'cost_usd' | 'duration';
   error?: string;
   total_tool_calls: number;
   total_iterations: number;
@@ -44,10 +61,12 @@ export interface IterationData {
 export interface UsageInfo {
   input_tokens: number;
   output_tokens: number;
+  cache_creation_input_tokens?: number;
+  cache_read_input_tokens?: number;
 }
 
 export interface ContentBlock {
-  type: 'text' | 'tool_use' | 'tool_result';
+  type: 'text' | 'tool_use' | 'tool_result' | 'thinking' | 'redacted_thinking';
   text?: string;
   tool_use?: {
     id: string;
@@ -59,6 +78,11 @@ export interface ContentBlock {
     content: string;
     is_error: boolean;
   };
+  thinking?: {
+    thinking?: string;
+    signature?: string;
+    data?: string;
+  };
 }
 
 export interface MessageData {
@@ -101,3 +125,37 @@ export interface ErrorData {
   message: string;
   stack_trace?: string;
 }
+
+// Streaming delta events are delivered over WebSocket only and are not persisted
+export interface TextDeltaData {
+  index: number;
+  text: string;
+}
+
+export interface ToolInputDeltaData {
+  index: number;
+  tool_use_id: string;
+  tool_name: string;
+  partial_json: string;
+}
+
+export interface CompactionData {
+  strategy: string;
+  messages_before: number;
+  messages_after: number;
+  estimated_tokens_before: number;
+  estimated_tokens_after: number;
+  truncated_results?: number;
+  summarized_messages?: number;
+}
+
+export interface APIRetryData {
+  attempt: number;
+  max_retries: number;
+  model: string;
+  fallback_model?: string;
+  error_kind: string;
+  status_code?: number;
+  error: string;
+  delay?: string;
+}
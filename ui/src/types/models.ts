@@ -5,6 +5,8 @@ export type RunStatus = "running" | "paused" | "success" | "failed" | "cancelled
 export interface TokenUsage {
   input_tokens: number;
   output_tokens: number;
+  cache_creation_input_tokens?: number;
+  cache_read_input_tokens?: number;
   total_tokens: number;
 }
 
@@ -39,4 +41,13 @@ export interface StartRunRequest {
   agent_id: string;
   prompt: string;
   resume_from_run_id?: string;
+  budget?: RunBudget;
+}
+
+export interface RunBudget {
+  max_input_tokens?: number;
+  max_output_tokens?: number;
+  max_cost_usd?: number;
+  max_duration?: string; // Go duration, e.g. "30m"
+  max_iterations?: number;
 }
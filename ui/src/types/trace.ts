@@ -1,4 +1,4 @@
-export type SpanType = 'tool' | 'api_call' | 'agent_handoff' | 'iteration' | 'trace';
+export type SpanType = 'tool' | 'api_call' | 'agent_handoff' | 'iteration' | 'compaction' | 'api_retry' | 'thinking' | 'trace';
 
 export interface Span {
   id: string;